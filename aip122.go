@@ -0,0 +1,30 @@
+package aip
+
+import (
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/hxtk/aip/names"
+)
+
+// ValidateResourceReferences returns a validator checking that string fields
+// annotated with google.api.resource_reference hold a name of the referenced
+// resource type, or of the parent of the referenced child type, according to
+// the patterns in r (AIP-122). Add it to NewServerInterceptor with
+// WithFieldValidator to reject malformed references in Create and Update
+// requests, e.g.
+//
+//	aip.WithFieldValidator(aip.ValidateResourceReferences(names.NewRegistry(nil)))
+func ValidateResourceReferences(r *names.Registry) FieldValidator {
+	return func(fd protoreflect.FieldDescriptor, v protoreflect.Value) error {
+		if fd.Kind() != protoreflect.StringKind {
+			return nil
+		}
+		ref, ok := proto.GetExtension(fd.Options(), annotations.E_ResourceReference).(*annotations.ResourceReference)
+		if !ok || ref == nil {
+			return nil
+		}
+		return r.ValidateReference(ref, v.String())
+	}
+}
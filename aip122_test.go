@@ -0,0 +1,42 @@
+package aip_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/hxtk/aip"
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/names"
+)
+
+func TestValidateResourceReferences(t *testing.T) {
+	validate := aip.ValidateResourceReferences(names.NewRegistry(nil))
+	fields := (&testpb.ShelveBooksRequest{}).ProtoReflect().Descriptor().Fields()
+	bookFields := (&testpb.Book{}).ProtoReflect().Descriptor().Fields()
+	cases := []struct {
+		field protoreflect.FieldDescriptor
+		value string
+		valid bool
+	}{
+		{fields.ByName("shelf"), "shelves/1", true},
+		{fields.ByName("shelf"), "books/1", false},
+		{fields.ByName("books"), "publishers/acme/books/1", true},
+		{fields.ByName("books"), "publishers/acme", false},
+		{fields.ByName("parent"), "publishers/acme", true},
+		{fields.ByName("parent"), "shelves/1", false},
+		{fields.ByName("publisher"), "publishers/acme", true},
+		{fields.ByName("publisher"), "acme", false},
+		{fields.ByName("source"), "anything", true},
+		{bookFields.ByName("title"), "not/a/reference", true},
+	}
+	for _, tc := range cases {
+		err := validate(tc.field, protoreflect.ValueOfString(tc.value))
+		if tc.valid {
+			require.NoError(t, err, "%s = %q", tc.field.Name(), tc.value)
+		} else {
+			require.ErrorIs(t, err, names.ErrInvalidReference, "%s = %q", tc.field.Name(), tc.value)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package aip
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// FieldValidator checks the value of a field set on a request message,
+// returning an error describing why the value is invalid. Validators are
+// called for every set field of a request, including fields of nested
+// messages, and should return nil for fields they do not check. For
+// repeated fields, v holds a single element.
+type FieldValidator func(fd protoreflect.FieldDescriptor, v protoreflect.Value) error
+
+// ValidateLanguageCode checks that string fields named language_code, or
+// ending in _language_code, hold a well-formed BCP-47 language tag with
+// known subtags, such as "en-US" (AIP-143).
+func ValidateLanguageCode(fd protoreflect.FieldDescriptor, v protoreflect.Value) error {
+	if !isStandardCode(fd, "language_code") {
+		return nil
+	}
+	s := v.String()
+	if strings.Contains(s, "_") {
+		return fmt.Errorf("%q is not a BCP-47 language code, subtags are separated by '-'", s)
+	}
+	if _, err := language.Parse(s); err != nil {
+		return fmt.Errorf("%q is not a BCP-47 language code: %w", s, err)
+	}
+	return nil
+}
+
+// ValidateRegionCode checks that string fields named region_code, or ending
+// in _region_code, hold a Unicode CLDR region code: an ISO-3166-1 alpha-2
+// country code, such as "US", or a UN M.49 area code, such as "419"
+// (AIP-143).
+func ValidateRegionCode(fd protoreflect.FieldDescriptor, v protoreflect.Value) error {
+	if !isStandardCode(fd, "region_code") {
+		return nil
+	}
+	s := v.String()
+	if !isUpperAlpha(s, 2) && !isDigits(s, 3) {
+		return fmt.Errorf("%q is not a region code, expected two upper-case letters or three digits", s)
+	}
+	if _, err := language.ParseRegion(s); err != nil {
+		return fmt.Errorf("%q is not a region code: %w", s, err)
+	}
+	return nil
+}
+
+// ValidateCurrencyCode checks that string fields named currency_code, or
+// ending in _currency_code, hold an ISO-4217 currency code, such as "USD"
+// (AIP-143). This includes the currency_code of google.type.Money.
+func ValidateCurrencyCode(fd protoreflect.FieldDescriptor, v protoreflect.Value) error {
+	if !isStandardCode(fd, "currency_code") {
+		return nil
+	}
+	s := v.String()
+	if !isUpperAlpha(s, 3) {
+		return fmt.Errorf("%q is not a currency code, expected three upper-case letters", s)
+	}
+	if _, err := currency.ParseISO(s); err != nil {
+		return fmt.Errorf("%q is not an ISO-4217 currency code", s)
+	}
+	return nil
+}
+
+// isStandardCode reports whether fd is a string field named suffix, or
+// ending in "_" + suffix.
+func isStandardCode(fd protoreflect.FieldDescriptor, suffix string) bool {
+	name := string(fd.Name())
+	return fd.Kind() == protoreflect.StringKind && (name == suffix || strings.HasSuffix(name, "_"+suffix))
+}
+
+// isUpperAlpha reports whether s consists of n upper-case ASCII letters.
+func isUpperAlpha(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < 'A' || s[i] > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// isDigits reports whether s consists of n ASCII digits.
+func isDigits(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,51 @@
+package aip_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/hxtk/aip"
+	"github.com/hxtk/aip/aiptest/testpb"
+)
+
+func TestStandardCodeValidators(t *testing.T) {
+	fields := (&testpb.Book{}).ProtoReflect().Descriptor().Fields()
+	cases := []struct {
+		validate aip.FieldValidator
+		field    protoreflect.Name
+		value    string
+		valid    bool
+	}{
+		{aip.ValidateLanguageCode, "language_code", "en", true},
+		{aip.ValidateLanguageCode, "language_code", "en-US", true},
+		{aip.ValidateLanguageCode, "language_code", "zh-Hant-TW", true},
+		{aip.ValidateLanguageCode, "language_code", "en_US", false},
+		{aip.ValidateLanguageCode, "language_code", "english", false},
+		{aip.ValidateLanguageCode, "language_code", "xx", false},
+		{aip.ValidateLanguageCode, "title", "english", true},
+
+		{aip.ValidateRegionCode, "publication_region_code", "US", true},
+		{aip.ValidateRegionCode, "publication_region_code", "419", true},
+		{aip.ValidateRegionCode, "publication_region_code", "us", false},
+		{aip.ValidateRegionCode, "publication_region_code", "USA", false},
+		{aip.ValidateRegionCode, "publication_region_code", "JJ", false},
+		{aip.ValidateRegionCode, "language_code", "USA", true},
+
+		{aip.ValidateCurrencyCode, "price_currency_code", "USD", true},
+		{aip.ValidateCurrencyCode, "price_currency_code", "EUR", true},
+		{aip.ValidateCurrencyCode, "price_currency_code", "usd", false},
+		{aip.ValidateCurrencyCode, "price_currency_code", "ABC", false},
+		{aip.ValidateCurrencyCode, "price_currency_code", "$", false},
+	}
+	for _, c := range cases {
+		fd := fields.ByName(c.field)
+		err := c.validate(fd, protoreflect.ValueOfString(c.value))
+		if c.valid {
+			require.NoError(t, err, "%s = %q", c.field, c.value)
+		} else {
+			require.Error(t, err, "%s = %q", c.field, c.value)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+// Package aiptest provides test fixtures for implementations of AIP-compliant
+// APIs: the Book resource and BookService of package testpb, and a standard
+// corpus of filters, orders and field masks with their expected outcomes.
+//
+// Downstream adapters, such as custom SQL dialects or storage backends, can
+// load Books into their store and check that they return the same results
+// for the golden cases as the in-memory implementations of this module:
+//
+//	for _, c := range aiptest.FilterCases() {
+//	    got := store.List(ctx, c.Filter)
+//	    if c.Err != (err != nil) || !slices.Equal(names(got), c.Want) {
+//	        t.Errorf(...)
+//	    }
+//	}
+package aiptest
+
+//go:generate go tool bufisk generate
+
+import (
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+)
+
+// Books returns the corpus of books the golden cases are evaluated against,
+// ordered by name. Each call returns fresh copies, so callers may modify
+// them.
+func Books() []*testpb.Book {
+	return []*testpb.Book{
+		{
+			Name:       "books/1",
+			Title:      "Dune",
+			Author:     &testpb.Author{GivenName: "Frank", FamilyName: "Herbert"},
+			Format:     testpb.Format_PAPERBACK,
+			CreateTime: timestamppb.New(time.Date(1965, 8, 1, 0, 0, 0, 0, time.UTC)),
+			PageCount:  proto.Int32(412),
+			Reviews:    map[string]string{"alice": "A classic."},
+		},
+		{
+			Name:       "books/2",
+			Title:      "Emma",
+			Author:     &testpb.Author{GivenName: "Jane", FamilyName: "Austen"},
+			Format:     testpb.Format_HARDCOVER,
+			CreateTime: timestamppb.New(time.Date(1815, 12, 23, 0, 0, 0, 0, time.UTC)),
+		},
+		{
+			Name:       "books/3",
+			Title:      "Neuromancer",
+			Author:     &testpb.Author{GivenName: "William", FamilyName: "Gibson"},
+			Format:     testpb.Format_EBOOK,
+			CreateTime: timestamppb.New(time.Date(1984, 7, 1, 0, 0, 0, 0, time.UTC)),
+			PageCount:  proto.Int32(271),
+		},
+		{
+			Name:       "books/4",
+			Title:      "Persuasion",
+			Author:     &testpb.Author{GivenName: "Jane", FamilyName: "Austen"},
+			Format:     testpb.Format_PAPERBACK,
+			CreateTime: timestamppb.New(time.Date(1817, 12, 20, 0, 0, 0, 0, time.UTC)),
+		},
+		{
+			Name:  "books/5",
+			Title: "The Talisman",
+			Authors: []*testpb.Author{
+				{GivenName: "Stephen", FamilyName: "King"},
+				{GivenName: "Peter", FamilyName: "Straub"},
+			},
+		},
+	}
+}
+
+// FilterCase is an AIP-160 filter with the books it matches.
+type FilterCase struct {
+	// Filter is the filter text.
+	Filter string
+	// Want holds the names of the matching books, in the order of Books.
+	Want []string
+	// Err reports whether the filter is invalid.
+	Err bool
+}
+
+// FilterCases returns the golden AIP-160 filter cases over Books.
+func FilterCases() []FilterCase {
+	return []FilterCase{
+		{Filter: "", Want: []string{"books/1", "books/2", "books/3", "books/4", "books/5"}},
+		{Filter: "title = Dune", Want: []string{"books/1"}},
+		{Filter: `title = "The Talisman"`, Want: []string{"books/5"}},
+		{Filter: "title != Dune", Want: []string{"books/2", "books/3", "books/4", "books/5"}},
+		{Filter: "title:an", Want: []string{"books/3", "books/5"}},
+		{Filter: "title = Dune OR title = Emma", Want: []string{"books/1", "books/2"}},
+		{Filter: "author.family_name = Austen", Want: []string{"books/2", "books/4"}},
+		{Filter: "author.family_name = Austen AND title = Emma", Want: []string{"books/2"}},
+		{Filter: "NOT author.family_name = Austen", Want: []string{"books/1", "books/3", "books/5"}},
+		{Filter: "authors.family_name = Straub", Want: []string{"books/5"}},
+		{Filter: "create_time:*", Want: []string{"books/1", "books/2", "books/3", "books/4"}},
+		{Filter: "page_count:*", Want: []string{"books/1", "books/3"}},
+		{Filter: "-page_count:*", Want: []string{"books/2", "books/4", "books/5"}},
+		{Filter: "reviews.alice:*", Want: []string{"books/1"}},
+		{Filter: "Austen", Want: []string{"books/2", "books/4"}},
+		{Filter: "title = (", Err: true},
+		{Filter: "(title = Dune", Err: true},
+	}
+}
+
+// OrderCase is an AIP-132 order_by clause with the order of Books it
+// produces.
+type OrderCase struct {
+	// OrderBy is the order_by text.
+	OrderBy string
+	// Want holds the names of all books, sorted by OrderBy.
+	Want []string
+	// Err reports whether the order is invalid.
+	Err bool
+}
+
+// OrderCases returns the golden AIP-132 order_by cases over Books. Every
+// valid order ends with a unique field, so the expected order is total.
+//
+// Unset fields with explicit presence, including fields of missing
+// submessages, sort as NULL: first in ascending and last in descending
+// order.
+func OrderCases() []OrderCase {
+	return []OrderCase{
+		{OrderBy: "name", Want: []string{"books/1", "books/2", "books/3", "books/4", "books/5"}},
+		{OrderBy: "name desc", Want: []string{"books/5", "books/4", "books/3", "books/2", "books/1"}},
+		{OrderBy: "title", Want: []string{"books/1", "books/2", "books/3", "books/4", "books/5"}},
+		{OrderBy: "author.family_name, title", Want: []string{"books/5", "books/2", "books/4", "books/3", "books/1"}},
+		{OrderBy: "author.family_name desc, title desc", Want: []string{"books/1", "books/3", "books/4", "books/2", "books/5"}},
+		{OrderBy: "create_time", Want: []string{"books/5", "books/2", "books/4", "books/1", "books/3"}},
+		{OrderBy: "create_time desc", Want: []string{"books/3", "books/1", "books/4", "books/2", "books/5"}},
+		{OrderBy: "page_count desc, name", Want: []string{"books/1", "books/3", "books/2", "books/4", "books/5"}},
+		{OrderBy: "format, name", Want: []string{"books/5", "books/1", "books/4", "books/2", "books/3"}},
+		{OrderBy: "authors", Err: true},
+		{OrderBy: "reviews", Err: true},
+		{OrderBy: "author", Err: true},
+		{OrderBy: "no_such_field", Err: true},
+	}
+}
+
+// MaskCase is an AIP-157 read mask with the result of applying it to the
+// first of Books.
+type MaskCase struct {
+	// Paths are the field mask paths.
+	Paths []string
+	// Want is the first of Books, with fields outside the mask cleared.
+	Want *testpb.Book
+	// Err reports whether the mask is invalid.
+	Err bool
+}
+
+// MaskCases returns the golden AIP-157 read mask cases over the first of
+// Books.
+func MaskCases() []MaskCase {
+	dune := Books()[0]
+	return []MaskCase{
+		{Paths: []string{"title"}, Want: &testpb.Book{Title: "Dune"}},
+		{Paths: []string{"name", "title"}, Want: &testpb.Book{Name: "books/1", Title: "Dune"}},
+		{Paths: []string{"author.family_name"}, Want: &testpb.Book{Author: &testpb.Author{FamilyName: "Herbert"}}},
+		{Paths: []string{"author"}, Want: &testpb.Book{Author: dune.GetAuthor()}},
+		{Paths: []string{"page_count"}, Want: &testpb.Book{PageCount: proto.Int32(412)}},
+		{Paths: []string{"reviews"}, Want: &testpb.Book{Reviews: dune.GetReviews()}},
+		{Paths: []string{"reviews.alice"}, Want: &testpb.Book{Reviews: dune.GetReviews()}},
+		{Paths: []string{"*"}, Err: true},
+		{Paths: []string{"title.subtitle"}, Err: true},
+		{Paths: []string{""}, Err: true},
+	}
+}
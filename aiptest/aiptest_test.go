@@ -0,0 +1,105 @@
+package aiptest_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/hxtk/aip/aiptest"
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/masks"
+	"github.com/hxtk/aip/query"
+)
+
+func names(books []*testpb.Book) []string {
+	out := make([]string, len(books))
+	for i, b := range books {
+		out[i] = b.GetName()
+	}
+	return out
+}
+
+func TestFilterCases(t *testing.T) {
+	for _, c := range aiptest.FilterCases() {
+		t.Run(c.Filter, func(t *testing.T) {
+			f, err := query.ParseFilter(c.Filter)
+			var match func(*testpb.Book) bool
+			if err == nil {
+				match, err = query.ProtoFilter[testpb.Book](f)
+			}
+			if c.Err {
+				if err == nil {
+					t.Fatalf("filter %q: got nil error, want error", c.Filter)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("filter %q: %v", c.Filter, err)
+			}
+
+			var got []*testpb.Book
+			for _, b := range aiptest.Books() {
+				if match(b) {
+					got = append(got, b)
+				}
+			}
+			if !slices.Equal(names(got), c.Want) {
+				t.Errorf("filter %q matched %v, want %v", c.Filter, names(got), c.Want)
+			}
+		})
+	}
+}
+
+func TestOrderCases(t *testing.T) {
+	for _, c := range aiptest.OrderCases() {
+		t.Run(c.OrderBy, func(t *testing.T) {
+			order, err := query.ParseOrderBy(c.OrderBy)
+			var cmp func(a, b *testpb.Book) int
+			if err == nil {
+				cmp, err = query.Comparer[*testpb.Book](order)
+			}
+			if c.Err {
+				if err == nil {
+					t.Fatalf("order %q: got nil error, want error", c.OrderBy)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("order %q: %v", c.OrderBy, err)
+			}
+
+			books := aiptest.Books()
+			slices.Reverse(books)
+			slices.SortStableFunc(books, cmp)
+			if got := names(books); !slices.Equal(got, c.Want) {
+				t.Errorf("order %q sorted %v, want %v", c.OrderBy, got, c.Want)
+			}
+		})
+	}
+}
+
+func TestMaskCases(t *testing.T) {
+	for _, c := range aiptest.MaskCases() {
+		t.Run(strings.Join(c.Paths, ","), func(t *testing.T) {
+			book := aiptest.Books()[0]
+			mask, err := masks.New(book.ProtoReflect().Descriptor(), masks.ModeRead, c.Paths...)
+			if err == nil {
+				err = masks.PruneMessage(book, mask)
+			}
+			if c.Err {
+				if err == nil {
+					t.Fatalf("mask %v: got nil error, want error", c.Paths)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("mask %v: %v", c.Paths, err)
+			}
+			if !proto.Equal(book, c.Want) {
+				t.Errorf("mask %v: got %v, want %v", c.Paths, book, c.Want)
+			}
+		})
+	}
+}
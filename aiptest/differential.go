@@ -0,0 +1,191 @@
+package aiptest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/hxtk/aip/query"
+)
+
+// Workload is a List request run by Differential.
+type Workload struct {
+	// Filter is the AIP-160 filter text.
+	Filter string
+	// OrderBy is the AIP-132 order_by text. It should end with a unique
+	// field, so that the order is total and pages are well-defined.
+	OrderBy string
+	// PageSize is the maximum number of items per page, or zero to fetch all
+	// items in one page.
+	PageSize int
+}
+
+// SQLQuery is a query sent to a SQL backend by Differential.
+type SQLQuery struct {
+	// Where is the condition of the WHERE clause, without the keyword.
+	Where string
+	// OrderBy is the ORDER BY clause as returned by Table.OrderByClause,
+	// possibly empty.
+	OrderBy string
+	// Limit is the maximum number of rows to return, or zero for no limit.
+	Limit int
+	// Params are the query parameters of Where.
+	Params []query.QueryParameter
+}
+
+// Executor runs q against a SQL backend holding the same items passed to
+// Differential, and returns the selected resources in order.
+type Executor[M proto.Message] func(ctx context.Context, q SQLQuery) ([]M, error)
+
+// Divergence is a page of a workload on which the SQL backend and in-memory
+// evaluation disagree.
+type Divergence[M proto.Message] struct {
+	Workload Workload
+	// Page is the index of the first differing page.
+	Page int
+	// Memory and SQL are the items of the page from in-memory evaluation and
+	// from the SQL backend, respectively.
+	Memory []M
+	SQL    []M
+}
+
+// String describes d.
+func (d Divergence[M]) String() string {
+	return fmt.Sprintf("filter %q, order_by %q, page_size %d: page %d differs:\n  memory: %s\n  sql:    %s",
+		d.Workload.Filter, d.Workload.OrderBy, d.Workload.PageSize, d.Page, formatItems(d.Memory), formatItems(d.SQL))
+}
+
+// Differential runs each workload against items with the in-memory
+// evaluator (ProtoFilter and Comparer) and against a SQL backend through exec,
+// using the table's WhereClause, SeekClause and OrderByClause, and returns
+// the pages on which they diverge. This checks that queries pushed down to
+// the database return the same results, in the same order, as in-memory
+// evaluation.
+//
+// Pages after the first are fetched by seeking from the last item of the
+// previous page returned by exec. An error is returned if a workload is
+// invalid or cannot be translated to SQL by table, or if exec fails.
+func Differential[S any, M interface {
+	proto.Message
+	*S
+}](ctx context.Context, table *query.Table, items []M, exec Executor[M], workloads ...Workload) ([]Divergence[M], error) {
+	var out []Divergence[M]
+	for _, w := range workloads {
+		memory, err := memoryPages[S, M](table, items, w)
+		if err != nil {
+			return nil, fmt.Errorf("filter %q, order_by %q: %w", w.Filter, w.OrderBy, err)
+		}
+		d, err := compareSQLPages(ctx, table, exec, w, memory)
+		if err != nil {
+			return nil, fmt.Errorf("filter %q, order_by %q: %w", w.Filter, w.OrderBy, err)
+		}
+		if d != nil {
+			out = append(out, *d)
+		}
+	}
+	return out, nil
+}
+
+// memoryPages evaluates w against items in memory and splits the result into
+// pages.
+func memoryPages[S any, M interface {
+	proto.Message
+	*S
+}](table *query.Table, items []M, w Workload) ([][]M, error) {
+	f, err := query.ParseFilter(w.Filter)
+	if err != nil {
+		return nil, err
+	}
+	match, err := query.ProtoFilter[S, M](f)
+	if err != nil {
+		return nil, err
+	}
+	order, err := query.ParseOrderBy(w.OrderBy)
+	if err != nil {
+		return nil, err
+	}
+	if len(order) == 0 && w.PageSize > 0 {
+		return nil, errors.New("paginated workloads require an order")
+	}
+	cmp, err := query.Comparer[M](order, query.WithSortableColumns(table))
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []M
+	for _, m := range items {
+		if match(m) {
+			matched = append(matched, m)
+		}
+	}
+	slices.SortStableFunc(matched, cmp)
+
+	if w.PageSize <= 0 {
+		return [][]M{matched}, nil
+	}
+	pages := [][]M{}
+	for page := range slices.Chunk(matched, w.PageSize) {
+		pages = append(pages, page)
+	}
+	if len(pages) == 0 || len(pages[len(pages)-1]) == w.PageSize {
+		// A full last page is followed by an empty one.
+		pages = append(pages, nil)
+	}
+	return pages, nil
+}
+
+// compareSQLPages fetches the pages of w through exec and returns the first
+// one differing from memory, or nil if all pages agree.
+func compareSQLPages[M proto.Message](ctx context.Context, table *query.Table, exec Executor[M], w Workload, memory [][]M) (*Divergence[M], error) {
+	f, err := query.ParseFilter(w.Filter)
+	if err != nil {
+		return nil, err
+	}
+	where, params, err := table.WhereClause(f, "p_")
+	if err != nil {
+		return nil, err
+	}
+	order, err := query.ParseOrderBy(w.OrderBy)
+	if err != nil {
+		return nil, err
+	}
+	orderBy, err := table.OrderByClause(order)
+	if err != nil {
+		return nil, err
+	}
+
+	q := SQLQuery{Where: where, OrderBy: orderBy, Limit: max(w.PageSize, 0), Params: params}
+	for page, want := range memory {
+		got, err := exec(ctx, q)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", page, err)
+		}
+		if !slices.EqualFunc(got, want, func(a, b M) bool { return proto.Equal(a, b) }) {
+			return &Divergence[M]{Workload: w, Page: page, Memory: want, SQL: got}, nil
+		}
+		if len(got) == 0 {
+			break
+		}
+
+		seek, seekParams, err := table.SeekClause(got[len(got)-1], order, "s_")
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", page, err)
+		}
+		q.Where = where + " AND " + seek
+		q.Params = append(slices.Clip(params), seekParams...)
+	}
+	return nil, nil
+}
+
+// formatItems returns a compact description of items.
+func formatItems[M proto.Message](items []M) string {
+	parts := make([]string, len(items))
+	for i, m := range items {
+		parts[i] = "{" + strings.TrimSpace(fmt.Sprint(m)) + "}"
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
@@ -0,0 +1,132 @@
+package aiptest_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/hxtk/aip/aiptest"
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/query"
+)
+
+// fakeBackend stands in for a SQL database holding the Books. It serves each
+// workload by evaluating it in memory, or by a deliberately broken variant,
+// and seeks by skipping the rows it has already returned.
+type fakeBackend struct {
+	workload aiptest.Workload
+	// unordered makes the backend ignore ORDER BY.
+	unordered bool
+	offset    int
+	queries   []aiptest.SQLQuery
+}
+
+func (b *fakeBackend) exec(_ context.Context, q aiptest.SQLQuery) ([]*testpb.Book, error) {
+	b.queries = append(b.queries, q)
+	seeking := slices.ContainsFunc(q.Params, func(p query.QueryParameter) bool {
+		return strings.HasPrefix(p.Name, "s_")
+	})
+	if !seeking {
+		b.offset = 0
+	}
+
+	f, err := query.ParseFilter(b.workload.Filter)
+	if err != nil {
+		return nil, err
+	}
+	match, err := query.ProtoFilter[testpb.Book](f)
+	if err != nil {
+		return nil, err
+	}
+	var rows []*testpb.Book
+	for _, book := range aiptest.Books() {
+		if match(book) {
+			rows = append(rows, book)
+		}
+	}
+	if !b.unordered {
+		order, err := query.ParseOrderBy(b.workload.OrderBy)
+		if err != nil {
+			return nil, err
+		}
+		cmp, err := query.Comparer[*testpb.Book](order)
+		if err != nil {
+			return nil, err
+		}
+		slices.SortStableFunc(rows, cmp)
+	}
+
+	rows = rows[min(b.offset, len(rows)):]
+	if q.Limit > 0 {
+		rows = rows[:min(q.Limit, len(rows))]
+	}
+	b.offset += len(rows)
+	return rows, nil
+}
+
+func differentialTable() *query.Table {
+	return query.NewTable().WithColumns(
+		query.NewColumn().WithFieldPath("name").WithDatabaseName("db_name").Filterable().Sortable().Build(),
+		query.NewColumn().WithFieldPath("title").WithDatabaseName("db_title").Filterable().Sortable().Build(),
+		query.NewColumn().WithFieldPath("create_time").WithDatabaseName("db_create_time").Filterable().Sortable().Build(),
+	).Build()
+}
+
+func TestDifferential(t *testing.T) {
+	ctx := context.Background()
+	workload := aiptest.Workload{Filter: "create_time:*", OrderBy: "create_time desc, name", PageSize: 2}
+
+	backend := &fakeBackend{workload: workload}
+	divergences, err := aiptest.Differential(ctx, differentialTable(), aiptest.Books(), backend.exec, workload)
+	if err != nil {
+		t.Fatalf("Differential failed: %v", err)
+	}
+	if len(divergences) != 0 {
+		t.Fatalf("Differential reported %v for an agreeing backend", divergences)
+	}
+
+	// Four matching books take two full pages and an empty one.
+	if len(backend.queries) != 3 {
+		t.Fatalf("got %d queries, want 3", len(backend.queries))
+	}
+	first := backend.queries[0]
+	if first.Where != "(db_create_time IS NOT NULL)" || first.OrderBy != "ORDER BY db_create_time DESC, db_name\n" || first.Limit != 2 {
+		t.Errorf("first query = %+v", first)
+	}
+	if second := backend.queries[1]; !strings.Contains(second.Where, " AND ") || len(second.Params) != 2 {
+		t.Errorf("second query does not seek from the first page: %+v", second)
+	}
+}
+
+func TestDifferential_Divergence(t *testing.T) {
+	ctx := context.Background()
+	workload := aiptest.Workload{OrderBy: "title desc, name", PageSize: 3}
+
+	backend := &fakeBackend{workload: workload, unordered: true}
+	divergences, err := aiptest.Differential(ctx, differentialTable(), aiptest.Books(), backend.exec, workload)
+	if err != nil {
+		t.Fatalf("Differential failed: %v", err)
+	}
+	if len(divergences) != 1 {
+		t.Fatalf("got %d divergences, want 1", len(divergences))
+	}
+	d := divergences[0]
+	if d.Page != 0 || d.Memory[0].GetName() != "books/5" || d.SQL[0].GetName() != "books/1" {
+		t.Errorf("unexpected divergence: %s", d)
+	}
+}
+
+func TestDifferential_InvalidWorkload(t *testing.T) {
+	ctx := context.Background()
+	backend := &fakeBackend{}
+	for _, w := range []aiptest.Workload{
+		{Filter: "(title = Dune"},
+		{Filter: "page_count:*", OrderBy: "name"},
+		{Filter: "title = Dune", PageSize: 1},
+	} {
+		if _, err := aiptest.Differential(ctx, differentialTable(), aiptest.Books(), backend.exec, w); err == nil {
+			t.Errorf("Differential(%+v): got nil error, want error", w)
+		}
+	}
+}
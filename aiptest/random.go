@@ -0,0 +1,130 @@
+package aiptest
+
+import (
+	"math/rand/v2"
+	"strconv"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// maxRandomDepth bounds the nesting of expressions in RandomFilter.
+const maxRandomDepth = 3
+
+// randomAlphabet is the alphabet of string literals in RandomFilter. It is
+// small so that literals often equal or occur in generated field values.
+const randomAlphabet = "ab"
+
+// RandomFilter returns a random, valid AIP-160 filter over messages
+// described by desc, for property-based testing, e.g. that SQL translation
+// and in-memory evaluation agree:
+//
+//	rng := rand.New(rand.NewPCG(seed, 0))
+//	for range 1000 {
+//	    filter := aiptest.RandomFilter(desc, rng)
+//	    ...
+//	}
+//
+// Filters combine restrictions with AND, OR, NOT and parentheses. The
+// restrictions are comparisons of singular string fields with =, != and the
+// has operator, comparisons of singular bool fields with true and false, and
+// presence tests of singular fields with explicit presence. Only top-level
+// fields are referenced, as in columns of a query.Table; if desc has no
+// referenceable fields, the filter is empty.
+func RandomFilter(desc protoreflect.MessageDescriptor, rng *rand.Rand) string {
+	g := &filterGen{rng: rng}
+	g.collect(desc)
+	if len(g.strings)+len(g.bools)+len(g.present) == 0 {
+		return ""
+	}
+	return g.expression(maxRandomDepth)
+}
+
+// filterGen generates random filters from the referenceable field paths of
+// a message.
+type filterGen struct {
+	rng *rand.Rand
+
+	// Paths of singular string, bool, and explicit-presence fields.
+	strings []string
+	bools   []string
+	present []string
+}
+
+// collect adds the referenceable fields of desc to g.
+func (g *filterGen) collect(desc protoreflect.MessageDescriptor) {
+	fields := desc.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.Cardinality() == protoreflect.Repeated {
+			continue
+		}
+		name := string(fd.Name())
+		if fd.HasPresence() {
+			g.present = append(g.present, name)
+		}
+		switch fd.Kind() {
+		case protoreflect.StringKind:
+			g.strings = append(g.strings, name)
+		case protoreflect.BoolKind:
+			g.bools = append(g.bools, name)
+		}
+	}
+}
+
+// expression returns a random expression nested at most depth levels deep.
+func (g *filterGen) expression(depth int) string {
+	if depth == 0 {
+		return g.restriction()
+	}
+	switch g.rng.IntN(6) {
+	case 0:
+		return g.expression(depth-1) + " AND " + g.expression(depth-1)
+	case 1:
+		return g.expression(depth-1) + " " + g.expression(depth-1)
+	case 2:
+		return g.expression(depth-1) + " OR " + g.expression(depth-1)
+	case 3:
+		return "NOT (" + g.expression(depth-1) + ")"
+	case 4:
+		return "(" + g.expression(depth-1) + ")"
+	}
+	return g.restriction()
+}
+
+// restriction returns a random restriction.
+func (g *filterGen) restriction() string {
+	for {
+		switch g.rng.IntN(3) {
+		case 0:
+			if len(g.strings) == 0 {
+				continue
+			}
+			op := [...]string{"=", "!=", ":"}[g.rng.IntN(3)]
+			return g.pick(g.strings) + " " + op + " " + strconv.Quote(g.literal())
+		case 1:
+			if len(g.bools) == 0 {
+				continue
+			}
+			return g.pick(g.bools) + " = " + strconv.FormatBool(g.rng.IntN(2) == 0)
+		default:
+			if len(g.present) == 0 {
+				continue
+			}
+			return g.pick(g.present) + ":*"
+		}
+	}
+}
+
+// pick returns a random element of paths.
+func (g *filterGen) pick(paths []string) string {
+	return paths[g.rng.IntN(len(paths))]
+}
+
+// literal returns a random short string over randomAlphabet.
+func (g *filterGen) literal() string {
+	b := make([]byte, 1+g.rng.IntN(2))
+	for i := range b {
+		b[i] = randomAlphabet[g.rng.IntN(len(randomAlphabet))]
+	}
+	return string(b)
+}
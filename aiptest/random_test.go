@@ -0,0 +1,69 @@
+package aiptest_test
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/hxtk/aip/aiptest"
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/query"
+)
+
+func TestRandomFilter(t *testing.T) {
+	desc := (&testpb.Book{}).ProtoReflect().Descriptor()
+	table := query.NewTable().WithColumns(
+		query.NewColumn().WithFieldPath("title").WithDatabaseName("db_title").Filterable().Build(),
+		query.NewColumn().WithFieldPath("name").WithDatabaseName("db_name").Filterable().Build(),
+		query.NewColumn().WithFieldPath("author").WithDatabaseName("db_author").Filterable().Build(),
+		query.NewColumn().WithFieldPath("create_time").WithDatabaseName("db_create_time").Filterable().Build(),
+		query.NewColumn().WithFieldPath("page_count").WithDatabaseName("db_page_count").Filterable().Build(),
+		query.NewColumn().WithFieldPath("language_code").WithDatabaseName("db_language_code").Filterable().Build(),
+		query.NewColumn().WithFieldPath("publication_region_code").WithDatabaseName("db_publication_region_code").Filterable().Build(),
+		query.NewColumn().WithFieldPath("price_currency_code").WithDatabaseName("db_price_currency_code").Filterable().Build(),
+		query.NewColumn().WithFieldPath("reading_time").WithDatabaseName("db_reading_time").Filterable().Build(),
+		query.NewColumn().WithFieldPath("metadata").WithDatabaseName("db_metadata").Filterable().Build(),
+		query.NewColumn().WithFieldPath("details").WithDatabaseName("db_details").Filterable().Build(),
+		query.NewColumn().WithFieldPath("etag").WithDatabaseName("db_etag").Filterable().Build(),
+		query.NewColumn().WithFieldPath("edition_code").WithDatabaseName("db_edition_code").Filterable().Build(),
+		query.NewColumn().WithFieldPath("volume").WithDatabaseName("db_volume").Filterable().Build(),
+	).Build()
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	for range 1000 {
+		filter := aiptest.RandomFilter(desc, rng)
+		f, err := query.ParseFilter(filter)
+		if err != nil {
+			t.Fatalf("ParseFilter(%q) failed: %v", filter, err)
+		}
+		match, err := query.ProtoFilter[testpb.Book](f)
+		if err != nil {
+			t.Fatalf("ProtoFilter(%q) failed: %v", filter, err)
+		}
+		for _, b := range aiptest.Books() {
+			match(b)
+		}
+		if _, _, err := table.WhereClause(f, "p_"); err != nil {
+			t.Fatalf("WhereClause(%q) failed: %v", filter, err)
+		}
+	}
+}
+
+func TestRandomFilter_Deterministic(t *testing.T) {
+	desc := (&testpb.Book{}).ProtoReflect().Descriptor()
+	a := rand.New(rand.NewPCG(7, 7))
+	b := rand.New(rand.NewPCG(7, 7))
+	for range 100 {
+		if x, y := aiptest.RandomFilter(desc, a), aiptest.RandomFilter(desc, b); x != y {
+			t.Fatalf("same seed generated %q and %q", x, y)
+		}
+	}
+}
+
+func TestRandomFilter_NoFields(t *testing.T) {
+	desc := (&timestamppb.Timestamp{}).ProtoReflect().Descriptor()
+	if got := aiptest.RandomFilter(desc, rand.New(rand.NewPCG(1, 1))); got != "" {
+		t.Errorf("RandomFilter over a message without referenceable fields = %q, want empty", got)
+	}
+}
@@ -0,0 +1,1317 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        (unknown)
+// source: testpb/book.proto
+
+package testpb
+
+import (
+	_ "google.golang.org/genproto/googleapis/api/annotations"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	anypb "google.golang.org/protobuf/types/known/anypb"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	fieldmaskpb "google.golang.org/protobuf/types/known/fieldmaskpb"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Format int32
+
+const (
+	Format_FORMAT_UNSPECIFIED Format = 0
+	Format_PAPERBACK          Format = 1
+	Format_HARDCOVER          Format = 2
+	Format_EBOOK              Format = 3
+)
+
+// Enum value maps for Format.
+var (
+	Format_name = map[int32]string{
+		0: "FORMAT_UNSPECIFIED",
+		1: "PAPERBACK",
+		2: "HARDCOVER",
+		3: "EBOOK",
+	}
+	Format_value = map[string]int32{
+		"FORMAT_UNSPECIFIED": 0,
+		"PAPERBACK":          1,
+		"HARDCOVER":          2,
+		"EBOOK":              3,
+	}
+)
+
+func (x Format) Enum() *Format {
+	p := new(Format)
+	*p = x
+	return p
+}
+
+func (x Format) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Format) Descriptor() protoreflect.EnumDescriptor {
+	return file_testpb_book_proto_enumTypes[0].Descriptor()
+}
+
+func (Format) Type() protoreflect.EnumType {
+	return &file_testpb_book_proto_enumTypes[0]
+}
+
+func (x Format) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Format.Descriptor instead.
+func (Format) EnumDescriptor() ([]byte, []int) {
+	return file_testpb_book_proto_rawDescGZIP(), []int{0}
+}
+
+type Author struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GivenName     string                 `protobuf:"bytes,1,opt,name=given_name,json=givenName,proto3" json:"given_name,omitempty"`
+	FamilyName    string                 `protobuf:"bytes,2,opt,name=family_name,json=familyName,proto3" json:"family_name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Author) Reset() {
+	*x = Author{}
+	mi := &file_testpb_book_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Author) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Author) ProtoMessage() {}
+
+func (x *Author) ProtoReflect() protoreflect.Message {
+	mi := &file_testpb_book_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Author.ProtoReflect.Descriptor instead.
+func (*Author) Descriptor() ([]byte, []int) {
+	return file_testpb_book_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Author) GetGivenName() string {
+	if x != nil {
+		return x.GivenName
+	}
+	return ""
+}
+
+func (x *Author) GetFamilyName() string {
+	if x != nil {
+		return x.FamilyName
+	}
+	return ""
+}
+
+type Review struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rating        int32                  `protobuf:"varint,1,opt,name=rating,proto3" json:"rating,omitempty"`
+	Text          string                 `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Review) Reset() {
+	*x = Review{}
+	mi := &file_testpb_book_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Review) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Review) ProtoMessage() {}
+
+func (x *Review) ProtoReflect() protoreflect.Message {
+	mi := &file_testpb_book_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Review.ProtoReflect.Descriptor instead.
+func (*Review) Descriptor() ([]byte, []int) {
+	return file_testpb_book_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Review) GetRating() int32 {
+	if x != nil {
+		return x.Rating
+	}
+	return 0
+}
+
+func (x *Review) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+// A comment in a discussion thread, a self-recursive resource.
+type Comment struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Text          string                 `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	Replies       []*Comment             `protobuf:"bytes,3,rep,name=replies,proto3" json:"replies,omitempty"`
+	Parent        *Comment               `protobuf:"bytes,4,opt,name=parent,proto3" json:"parent,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Comment) Reset() {
+	*x = Comment{}
+	mi := &file_testpb_book_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Comment) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Comment) ProtoMessage() {}
+
+func (x *Comment) ProtoReflect() protoreflect.Message {
+	mi := &file_testpb_book_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Comment.ProtoReflect.Descriptor instead.
+func (*Comment) Descriptor() ([]byte, []int) {
+	return file_testpb_book_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Comment) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Comment) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *Comment) GetReplies() []*Comment {
+	if x != nil {
+		return x.Replies
+	}
+	return nil
+}
+
+func (x *Comment) GetParent() *Comment {
+	if x != nil {
+		return x.Parent
+	}
+	return nil
+}
+
+type Book struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Title   string                 `protobuf:"bytes,1,opt,name=title,proto3" json:"title,omitempty"`
+	Author  *Author                `protobuf:"bytes,2,opt,name=author,proto3" json:"author,omitempty"`
+	Authors []*Author              `protobuf:"bytes,3,rep,name=authors,proto3" json:"authors,omitempty"`
+	// Simple string map
+	Reviews map[string]string `protobuf:"bytes,4,rep,name=reviews,proto3" json:"reviews,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Int-keyed map
+	Items map[int32]string `protobuf:"bytes,5,rep,name=items,proto3" json:"items,omitempty" protobuf_key:"varint,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Example output-only field
+	Name       string                 `protobuf:"bytes,6,opt,name=name,proto3" json:"name,omitempty"`
+	Format     Format                 `protobuf:"varint,7,opt,name=format,proto3,enum=test.Format" json:"format,omitempty"`
+	CreateTime *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=create_time,json=createTime,proto3" json:"create_time,omitempty"`
+	PageCount  *int32                 `protobuf:"varint,9,opt,name=page_count,json=pageCount,proto3,oneof" json:"page_count,omitempty"`
+	// AIP-143 standard codes
+	LanguageCode          string `protobuf:"bytes,10,opt,name=language_code,json=languageCode,proto3" json:"language_code,omitempty"`
+	PublicationRegionCode string `protobuf:"bytes,11,opt,name=publication_region_code,json=publicationRegionCode,proto3" json:"publication_region_code,omitempty"`
+	PriceCurrencyCode     string `protobuf:"bytes,12,opt,name=price_currency_code,json=priceCurrencyCode,proto3" json:"price_currency_code,omitempty"`
+	// Message-valued map
+	DetailedReviews map[string]*Review `protobuf:"bytes,13,rep,name=detailed_reviews,json=detailedReviews,proto3" json:"detailed_reviews,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Floating point and bytes sort keys
+	AverageRating float64 `protobuf:"fixed64,14,opt,name=average_rating,json=averageRating,proto3" json:"average_rating,omitempty"`
+	WeightKg      float32 `protobuf:"fixed32,15,opt,name=weight_kg,json=weightKg,proto3" json:"weight_kg,omitempty"`
+	Checksum      []byte  `protobuf:"bytes,16,opt,name=checksum,proto3" json:"checksum,omitempty"`
+	// Repeated scalar
+	Tags []string `protobuf:"bytes,17,rep,name=tags,proto3" json:"tags,omitempty"`
+	// Well-known duration
+	ReadingTime *durationpb.Duration `protobuf:"bytes,18,opt,name=reading_time,json=readingTime,proto3" json:"reading_time,omitempty"`
+	// Free-form JSON metadata
+	Metadata *structpb.Struct `protobuf:"bytes,19,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	// Type-specific details
+	Details *anypb.Any `protobuf:"bytes,20,opt,name=details,proto3" json:"details,omitempty"`
+	// AIP-154 entity tag
+	Etag string `protobuf:"bytes,21,opt,name=etag,proto3" json:"etag,omitempty"`
+	// Identifies the edition by publisher code or by volume number
+	//
+	// Types that are valid to be assigned to Edition:
+	//
+	//	*Book_EditionCode
+	//	*Book_Volume
+	Edition       isBook_Edition `protobuf_oneof:"edition"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Book) Reset() {
+	*x = Book{}
+	mi := &file_testpb_book_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Book) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Book) ProtoMessage() {}
+
+func (x *Book) ProtoReflect() protoreflect.Message {
+	mi := &file_testpb_book_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Book.ProtoReflect.Descriptor instead.
+func (*Book) Descriptor() ([]byte, []int) {
+	return file_testpb_book_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Book) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Book) GetAuthor() *Author {
+	if x != nil {
+		return x.Author
+	}
+	return nil
+}
+
+func (x *Book) GetAuthors() []*Author {
+	if x != nil {
+		return x.Authors
+	}
+	return nil
+}
+
+func (x *Book) GetReviews() map[string]string {
+	if x != nil {
+		return x.Reviews
+	}
+	return nil
+}
+
+func (x *Book) GetItems() map[int32]string {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *Book) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Book) GetFormat() Format {
+	if x != nil {
+		return x.Format
+	}
+	return Format_FORMAT_UNSPECIFIED
+}
+
+func (x *Book) GetCreateTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreateTime
+	}
+	return nil
+}
+
+func (x *Book) GetPageCount() int32 {
+	if x != nil && x.PageCount != nil {
+		return *x.PageCount
+	}
+	return 0
+}
+
+func (x *Book) GetLanguageCode() string {
+	if x != nil {
+		return x.LanguageCode
+	}
+	return ""
+}
+
+func (x *Book) GetPublicationRegionCode() string {
+	if x != nil {
+		return x.PublicationRegionCode
+	}
+	return ""
+}
+
+func (x *Book) GetPriceCurrencyCode() string {
+	if x != nil {
+		return x.PriceCurrencyCode
+	}
+	return ""
+}
+
+func (x *Book) GetDetailedReviews() map[string]*Review {
+	if x != nil {
+		return x.DetailedReviews
+	}
+	return nil
+}
+
+func (x *Book) GetAverageRating() float64 {
+	if x != nil {
+		return x.AverageRating
+	}
+	return 0
+}
+
+func (x *Book) GetWeightKg() float32 {
+	if x != nil {
+		return x.WeightKg
+	}
+	return 0
+}
+
+func (x *Book) GetChecksum() []byte {
+	if x != nil {
+		return x.Checksum
+	}
+	return nil
+}
+
+func (x *Book) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *Book) GetReadingTime() *durationpb.Duration {
+	if x != nil {
+		return x.ReadingTime
+	}
+	return nil
+}
+
+func (x *Book) GetMetadata() *structpb.Struct {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *Book) GetDetails() *anypb.Any {
+	if x != nil {
+		return x.Details
+	}
+	return nil
+}
+
+func (x *Book) GetEtag() string {
+	if x != nil {
+		return x.Etag
+	}
+	return ""
+}
+
+func (x *Book) GetEdition() isBook_Edition {
+	if x != nil {
+		return x.Edition
+	}
+	return nil
+}
+
+func (x *Book) GetEditionCode() string {
+	if x != nil {
+		if x, ok := x.Edition.(*Book_EditionCode); ok {
+			return x.EditionCode
+		}
+	}
+	return ""
+}
+
+func (x *Book) GetVolume() int32 {
+	if x != nil {
+		if x, ok := x.Edition.(*Book_Volume); ok {
+			return x.Volume
+		}
+	}
+	return 0
+}
+
+type isBook_Edition interface {
+	isBook_Edition()
+}
+
+type Book_EditionCode struct {
+	EditionCode string `protobuf:"bytes,22,opt,name=edition_code,json=editionCode,proto3,oneof"`
+}
+
+type Book_Volume struct {
+	Volume int32 `protobuf:"varint,23,opt,name=volume,proto3,oneof"`
+}
+
+func (*Book_EditionCode) isBook_Edition() {}
+
+func (*Book_Volume) isBook_Edition() {}
+
+type GetBookRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBookRequest) Reset() {
+	*x = GetBookRequest{}
+	mi := &file_testpb_book_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBookRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBookRequest) ProtoMessage() {}
+
+func (x *GetBookRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_testpb_book_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBookRequest.ProtoReflect.Descriptor instead.
+func (*GetBookRequest) Descriptor() ([]byte, []int) {
+	return file_testpb_book_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetBookRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type UpdateBookRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Book          *Book                  `protobuf:"bytes,1,opt,name=book,proto3" json:"book,omitempty"`
+	UpdateMask    *fieldmaskpb.FieldMask `protobuf:"bytes,2,opt,name=update_mask,json=updateMask,proto3" json:"update_mask,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateBookRequest) Reset() {
+	*x = UpdateBookRequest{}
+	mi := &file_testpb_book_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateBookRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateBookRequest) ProtoMessage() {}
+
+func (x *UpdateBookRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_testpb_book_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateBookRequest.ProtoReflect.Descriptor instead.
+func (*UpdateBookRequest) Descriptor() ([]byte, []int) {
+	return file_testpb_book_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *UpdateBookRequest) GetBook() *Book {
+	if x != nil {
+		return x.Book
+	}
+	return nil
+}
+
+func (x *UpdateBookRequest) GetUpdateMask() *fieldmaskpb.FieldMask {
+	if x != nil {
+		return x.UpdateMask
+	}
+	return nil
+}
+
+type DeleteBookRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Etag          string                 `protobuf:"bytes,2,opt,name=etag,proto3" json:"etag,omitempty"`
+	AllowMissing  bool                   `protobuf:"varint,3,opt,name=allow_missing,json=allowMissing,proto3" json:"allow_missing,omitempty"`
+	Force         bool                   `protobuf:"varint,4,opt,name=force,proto3" json:"force,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteBookRequest) Reset() {
+	*x = DeleteBookRequest{}
+	mi := &file_testpb_book_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteBookRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteBookRequest) ProtoMessage() {}
+
+func (x *DeleteBookRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_testpb_book_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteBookRequest.ProtoReflect.Descriptor instead.
+func (*DeleteBookRequest) Descriptor() ([]byte, []int) {
+	return file_testpb_book_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DeleteBookRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *DeleteBookRequest) GetEtag() string {
+	if x != nil {
+		return x.Etag
+	}
+	return ""
+}
+
+func (x *DeleteBookRequest) GetAllowMissing() bool {
+	if x != nil {
+		return x.AllowMissing
+	}
+	return false
+}
+
+func (x *DeleteBookRequest) GetForce() bool {
+	if x != nil {
+		return x.Force
+	}
+	return false
+}
+
+type ListBooksRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PageSize      int32                  `protobuf:"varint,1,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	PageToken     string                 `protobuf:"bytes,2,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	Filter        string                 `protobuf:"bytes,3,opt,name=filter,proto3" json:"filter,omitempty"`
+	OrderBy       string                 `protobuf:"bytes,4,opt,name=order_by,json=orderBy,proto3" json:"order_by,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListBooksRequest) Reset() {
+	*x = ListBooksRequest{}
+	mi := &file_testpb_book_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListBooksRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListBooksRequest) ProtoMessage() {}
+
+func (x *ListBooksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_testpb_book_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListBooksRequest.ProtoReflect.Descriptor instead.
+func (*ListBooksRequest) Descriptor() ([]byte, []int) {
+	return file_testpb_book_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListBooksRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListBooksRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *ListBooksRequest) GetFilter() string {
+	if x != nil {
+		return x.Filter
+	}
+	return ""
+}
+
+func (x *ListBooksRequest) GetOrderBy() string {
+	if x != nil {
+		return x.OrderBy
+	}
+	return ""
+}
+
+type ListBooksResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Books         []*Book                `protobuf:"bytes,1,rep,name=books,proto3" json:"books,omitempty"`
+	NextPageToken string                 `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	TotalSize     int32                  `protobuf:"varint,3,opt,name=total_size,json=totalSize,proto3" json:"total_size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListBooksResponse) Reset() {
+	*x = ListBooksResponse{}
+	mi := &file_testpb_book_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListBooksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListBooksResponse) ProtoMessage() {}
+
+func (x *ListBooksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_testpb_book_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListBooksResponse.ProtoReflect.Descriptor instead.
+func (*ListBooksResponse) Descriptor() ([]byte, []int) {
+	return file_testpb_book_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ListBooksResponse) GetBooks() []*Book {
+	if x != nil {
+		return x.Books
+	}
+	return nil
+}
+
+func (x *ListBooksResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+func (x *ListBooksResponse) GetTotalSize() int32 {
+	if x != nil {
+		return x.TotalSize
+	}
+	return 0
+}
+
+// A resource with field behaviors, for tests of standard methods.
+type Shelf struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Theme         string                 `protobuf:"bytes,2,opt,name=theme,proto3" json:"theme,omitempty"`
+	Location      string                 `protobuf:"bytes,3,opt,name=location,proto3" json:"location,omitempty"`
+	Description   string                 `protobuf:"bytes,4,opt,name=description,proto3" json:"description,omitempty"`
+	Etag          string                 `protobuf:"bytes,5,opt,name=etag,proto3" json:"etag,omitempty"`
+	CreateTime    *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=create_time,json=createTime,proto3" json:"create_time,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Shelf) Reset() {
+	*x = Shelf{}
+	mi := &file_testpb_book_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Shelf) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Shelf) ProtoMessage() {}
+
+func (x *Shelf) ProtoReflect() protoreflect.Message {
+	mi := &file_testpb_book_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Shelf.ProtoReflect.Descriptor instead.
+func (*Shelf) Descriptor() ([]byte, []int) {
+	return file_testpb_book_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Shelf) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Shelf) GetTheme() string {
+	if x != nil {
+		return x.Theme
+	}
+	return ""
+}
+
+func (x *Shelf) GetLocation() string {
+	if x != nil {
+		return x.Location
+	}
+	return ""
+}
+
+func (x *Shelf) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Shelf) GetEtag() string {
+	if x != nil {
+		return x.Etag
+	}
+	return ""
+}
+
+func (x *Shelf) GetCreateTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreateTime
+	}
+	return nil
+}
+
+type UpdateShelfRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Shelf         *Shelf                 `protobuf:"bytes,1,opt,name=shelf,proto3" json:"shelf,omitempty"`
+	UpdateMask    *fieldmaskpb.FieldMask `protobuf:"bytes,2,opt,name=update_mask,json=updateMask,proto3" json:"update_mask,omitempty"`
+	AllowMissing  bool                   `protobuf:"varint,3,opt,name=allow_missing,json=allowMissing,proto3" json:"allow_missing,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateShelfRequest) Reset() {
+	*x = UpdateShelfRequest{}
+	mi := &file_testpb_book_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateShelfRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateShelfRequest) ProtoMessage() {}
+
+func (x *UpdateShelfRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_testpb_book_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateShelfRequest.ProtoReflect.Descriptor instead.
+func (*UpdateShelfRequest) Descriptor() ([]byte, []int) {
+	return file_testpb_book_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *UpdateShelfRequest) GetShelf() *Shelf {
+	if x != nil {
+		return x.Shelf
+	}
+	return nil
+}
+
+func (x *UpdateShelfRequest) GetUpdateMask() *fieldmaskpb.FieldMask {
+	if x != nil {
+		return x.UpdateMask
+	}
+	return nil
+}
+
+func (x *UpdateShelfRequest) GetAllowMissing() bool {
+	if x != nil {
+		return x.AllowMissing
+	}
+	return false
+}
+
+// A request referencing other resources, for tests of reference validation.
+type ShelveBooksRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Shelf         string                 `protobuf:"bytes,1,opt,name=shelf,proto3" json:"shelf,omitempty"`
+	Books         []string               `protobuf:"bytes,2,rep,name=books,proto3" json:"books,omitempty"`
+	Parent        string                 `protobuf:"bytes,3,opt,name=parent,proto3" json:"parent,omitempty"`
+	Publisher     string                 `protobuf:"bytes,4,opt,name=publisher,proto3" json:"publisher,omitempty"`
+	Source        string                 `protobuf:"bytes,5,opt,name=source,proto3" json:"source,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShelveBooksRequest) Reset() {
+	*x = ShelveBooksRequest{}
+	mi := &file_testpb_book_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShelveBooksRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShelveBooksRequest) ProtoMessage() {}
+
+func (x *ShelveBooksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_testpb_book_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShelveBooksRequest.ProtoReflect.Descriptor instead.
+func (*ShelveBooksRequest) Descriptor() ([]byte, []int) {
+	return file_testpb_book_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ShelveBooksRequest) GetShelf() string {
+	if x != nil {
+		return x.Shelf
+	}
+	return ""
+}
+
+func (x *ShelveBooksRequest) GetBooks() []string {
+	if x != nil {
+		return x.Books
+	}
+	return nil
+}
+
+func (x *ShelveBooksRequest) GetParent() string {
+	if x != nil {
+		return x.Parent
+	}
+	return ""
+}
+
+func (x *ShelveBooksRequest) GetPublisher() string {
+	if x != nil {
+		return x.Publisher
+	}
+	return ""
+}
+
+func (x *ShelveBooksRequest) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+type ImportBooksResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Books         []*Book                `protobuf:"bytes,1,rep,name=books,proto3" json:"books,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportBooksResponse) Reset() {
+	*x = ImportBooksResponse{}
+	mi := &file_testpb_book_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportBooksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportBooksResponse) ProtoMessage() {}
+
+func (x *ImportBooksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_testpb_book_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportBooksResponse.ProtoReflect.Descriptor instead.
+func (*ImportBooksResponse) Descriptor() ([]byte, []int) {
+	return file_testpb_book_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ImportBooksResponse) GetBooks() []*Book {
+	if x != nil {
+		return x.Books
+	}
+	return nil
+}
+
+var File_testpb_book_proto protoreflect.FileDescriptor
+
+const file_testpb_book_proto_rawDesc = "" +
+	"\n" +
+	"\x11testpb/book.proto\x12\x04test\x1a\x1fgoogle/api/field_behavior.proto\x1a\x19google/api/resource.proto\x1a\x19google/protobuf/any.proto\x1a\x1egoogle/protobuf/duration.proto\x1a google/protobuf/field_mask.proto\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"H\n" +
+	"\x06Author\x12\x1d\n" +
+	"\n" +
+	"given_name\x18\x01 \x01(\tR\tgivenName\x12\x1f\n" +
+	"\vfamily_name\x18\x02 \x01(\tR\n" +
+	"familyName\"4\n" +
+	"\x06Review\x12\x16\n" +
+	"\x06rating\x18\x01 \x01(\x05R\x06rating\x12\x12\n" +
+	"\x04text\x18\x02 \x01(\tR\x04text\"\x86\x01\n" +
+	"\aComment\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04text\x18\x02 \x01(\tR\x04text\x12'\n" +
+	"\areplies\x18\x03 \x03(\v2\r.test.CommentR\areplies\x12*\n" +
+	"\x06parent\x18\x04 \x01(\v2\r.test.CommentB\x03\xe0A\x03R\x06parent\"\xe1\t\n" +
+	"\x04Book\x12\x14\n" +
+	"\x05title\x18\x01 \x01(\tR\x05title\x12$\n" +
+	"\x06author\x18\x02 \x01(\v2\f.test.AuthorR\x06author\x12&\n" +
+	"\aauthors\x18\x03 \x03(\v2\f.test.AuthorR\aauthors\x121\n" +
+	"\areviews\x18\x04 \x03(\v2\x17.test.Book.ReviewsEntryR\areviews\x12+\n" +
+	"\x05items\x18\x05 \x03(\v2\x15.test.Book.ItemsEntryR\x05items\x12\x12\n" +
+	"\x04name\x18\x06 \x01(\tR\x04name\x12$\n" +
+	"\x06format\x18\a \x01(\x0e2\f.test.FormatR\x06format\x12@\n" +
+	"\vcreate_time\x18\b \x01(\v2\x1a.google.protobuf.TimestampB\x03\xe0A\x03R\n" +
+	"createTime\x12\"\n" +
+	"\n" +
+	"page_count\x18\t \x01(\x05H\x01R\tpageCount\x88\x01\x01\x12#\n" +
+	"\rlanguage_code\x18\n" +
+	" \x01(\tR\flanguageCode\x126\n" +
+	"\x17publication_region_code\x18\v \x01(\tR\x15publicationRegionCode\x12.\n" +
+	"\x13price_currency_code\x18\f \x01(\tR\x11priceCurrencyCode\x12J\n" +
+	"\x10detailed_reviews\x18\r \x03(\v2\x1f.test.Book.DetailedReviewsEntryR\x0fdetailedReviews\x12%\n" +
+	"\x0eaverage_rating\x18\x0e \x01(\x01R\raverageRating\x12\x1b\n" +
+	"\tweight_kg\x18\x0f \x01(\x02R\bweightKg\x12\x1a\n" +
+	"\bchecksum\x18\x10 \x01(\fR\bchecksum\x12\x12\n" +
+	"\x04tags\x18\x11 \x03(\tR\x04tags\x12<\n" +
+	"\freading_time\x18\x12 \x01(\v2\x19.google.protobuf.DurationR\vreadingTime\x123\n" +
+	"\bmetadata\x18\x13 \x01(\v2\x17.google.protobuf.StructR\bmetadata\x12.\n" +
+	"\adetails\x18\x14 \x01(\v2\x14.google.protobuf.AnyR\adetails\x12\x12\n" +
+	"\x04etag\x18\x15 \x01(\tR\x04etag\x12#\n" +
+	"\fedition_code\x18\x16 \x01(\tH\x00R\veditionCode\x12\x18\n" +
+	"\x06volume\x18\x17 \x01(\x05H\x00R\x06volume\x1a:\n" +
+	"\fReviewsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1a8\n" +
+	"\n" +
+	"ItemsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\x05R\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\x1aP\n" +
+	"\x14DetailedReviewsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\"\n" +
+	"\x05value\x18\x02 \x01(\v2\f.test.ReviewR\x05value:\x028\x01:P\xeaAM\n" +
+	"\x18library.example.com/Book\x12\fbooks/{book}\x12#publishers/{publisher}/books/{book}B\t\n" +
+	"\aeditionB\r\n" +
+	"\v_page_count\")\n" +
+	"\x0eGetBookRequest\x12\x17\n" +
+	"\x04name\x18\x01 \x01(\tB\x03\xe0A\x02R\x04name\"u\n" +
+	"\x11UpdateBookRequest\x12#\n" +
+	"\x04book\x18\x01 \x01(\v2\n" +
+	".test.BookB\x03\xe0A\x02R\x04book\x12;\n" +
+	"\vupdate_mask\x18\x02 \x01(\v2\x1a.google.protobuf.FieldMaskR\n" +
+	"updateMask\"{\n" +
+	"\x11DeleteBookRequest\x12\x17\n" +
+	"\x04name\x18\x01 \x01(\tB\x03\xe0A\x02R\x04name\x12\x12\n" +
+	"\x04etag\x18\x02 \x01(\tR\x04etag\x12#\n" +
+	"\rallow_missing\x18\x03 \x01(\bR\fallowMissing\x12\x14\n" +
+	"\x05force\x18\x04 \x01(\bR\x05force\"\x81\x01\n" +
+	"\x10ListBooksRequest\x12\x1b\n" +
+	"\tpage_size\x18\x01 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x02 \x01(\tR\tpageToken\x12\x16\n" +
+	"\x06filter\x18\x03 \x01(\tR\x06filter\x12\x19\n" +
+	"\border_by\x18\x04 \x01(\tR\aorderBy\"|\n" +
+	"\x11ListBooksResponse\x12 \n" +
+	"\x05books\x18\x01 \x03(\v2\n" +
+	".test.BookR\x05books\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\x12\x1d\n" +
+	"\n" +
+	"total_size\x18\x03 \x01(\x05R\ttotalSize\"\x80\x02\n" +
+	"\x05Shelf\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x19\n" +
+	"\x05theme\x18\x02 \x01(\tB\x03\xe0A\x02R\x05theme\x12\x1f\n" +
+	"\blocation\x18\x03 \x01(\tB\x03\xe0A\x05R\blocation\x12 \n" +
+	"\vdescription\x18\x04 \x01(\tR\vdescription\x12\x12\n" +
+	"\x04etag\x18\x05 \x01(\tR\x04etag\x12@\n" +
+	"\vcreate_time\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampB\x03\xe0A\x03R\n" +
+	"createTime:/\xeaA,\n" +
+	"\x19library.example.com/Shelf\x12\x0fshelves/{shelf}\"\x9e\x01\n" +
+	"\x12UpdateShelfRequest\x12&\n" +
+	"\x05shelf\x18\x01 \x01(\v2\v.test.ShelfB\x03\xe0A\x02R\x05shelf\x12;\n" +
+	"\vupdate_mask\x18\x02 \x01(\v2\x1a.google.protobuf.FieldMaskR\n" +
+	"updateMask\x12#\n" +
+	"\rallow_missing\x18\x03 \x01(\bR\fallowMissing\"\x98\x02\n" +
+	"\x12ShelveBooksRequest\x124\n" +
+	"\x05shelf\x18\x01 \x01(\tB\x1e\xfaA\x1b\n" +
+	"\x19library.example.com/ShelfR\x05shelf\x123\n" +
+	"\x05books\x18\x02 \x03(\tB\x1d\xfaA\x1a\n" +
+	"\x18library.example.com/BookR\x05books\x125\n" +
+	"\x06parent\x18\x03 \x01(\tB\x1d\xfaA\x1a\x12\x18library.example.com/BookR\x06parent\x12@\n" +
+	"\tpublisher\x18\x04 \x01(\tB\"\xfaA\x1f\n" +
+	"\x1dlibrary.example.com/PublisherR\tpublisher\x12\x1e\n" +
+	"\x06source\x18\x05 \x01(\tB\x06\xfaA\x03\n" +
+	"\x01*R\x06source\"7\n" +
+	"\x13ImportBooksResponse\x12 \n" +
+	"\x05books\x18\x01 \x03(\v2\n" +
+	".test.BookR\x05books*I\n" +
+	"\x06Format\x12\x16\n" +
+	"\x12FORMAT_UNSPECIFIED\x10\x00\x12\r\n" +
+	"\tPAPERBACK\x10\x01\x12\r\n" +
+	"\tHARDCOVER\x10\x02\x12\t\n" +
+	"\x05EBOOK\x10\x032\xfb\x02\n" +
+	"\vBookService\x120\n" +
+	"\aGetBook\x12\x14.test.GetBookRequest\x1a\n" +
+	".test.Book\"\x03\x90\x02\x01\x121\n" +
+	"\tListBooks\x12\x16.test.ListBooksRequest\x1a\n" +
+	".test.Book0\x01\x12@\n" +
+	"\rListBooksPage\x12\x16.test.ListBooksRequest\x1a\x17.test.ListBooksResponse\x121\n" +
+	"\n" +
+	"UpdateBook\x12\x17.test.UpdateBookRequest\x1a\n" +
+	".test.Book\x121\n" +
+	"\n" +
+	"DeleteBook\x12\x17.test.DeleteBookRequest\x1a\n" +
+	".test.Book\x126\n" +
+	"\vImportBooks\x12\n" +
+	".test.Book\x1a\x19.test.ImportBooksResponse(\x01\x12'\n" +
+	"\tSyncBooks\x12\n" +
+	".test.Book\x1a\n" +
+	".test.Book(\x010\x01B\xa3\x01\xeaA7\n" +
+	"\x1dlibrary.example.com/Publisher\x12\x16publishers/{publisher}\n" +
+	"\bcom.testB\tBookProtoP\x01Z\"github.com/hxtk/aip/aiptest/testpb\xa2\x02\x03TXX\xaa\x02\x04Test\xca\x02\x04Test\xe2\x02\x10Test\\GPBMetadata\xea\x02\x04Testb\x06proto3"
+
+var (
+	file_testpb_book_proto_rawDescOnce sync.Once
+	file_testpb_book_proto_rawDescData []byte
+)
+
+func file_testpb_book_proto_rawDescGZIP() []byte {
+	file_testpb_book_proto_rawDescOnce.Do(func() {
+		file_testpb_book_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_testpb_book_proto_rawDesc), len(file_testpb_book_proto_rawDesc)))
+	})
+	return file_testpb_book_proto_rawDescData
+}
+
+var file_testpb_book_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_testpb_book_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
+var file_testpb_book_proto_goTypes = []any{
+	(Format)(0),                   // 0: test.Format
+	(*Author)(nil),                // 1: test.Author
+	(*Review)(nil),                // 2: test.Review
+	(*Comment)(nil),               // 3: test.Comment
+	(*Book)(nil),                  // 4: test.Book
+	(*GetBookRequest)(nil),        // 5: test.GetBookRequest
+	(*UpdateBookRequest)(nil),     // 6: test.UpdateBookRequest
+	(*DeleteBookRequest)(nil),     // 7: test.DeleteBookRequest
+	(*ListBooksRequest)(nil),      // 8: test.ListBooksRequest
+	(*ListBooksResponse)(nil),     // 9: test.ListBooksResponse
+	(*Shelf)(nil),                 // 10: test.Shelf
+	(*UpdateShelfRequest)(nil),    // 11: test.UpdateShelfRequest
+	(*ShelveBooksRequest)(nil),    // 12: test.ShelveBooksRequest
+	(*ImportBooksResponse)(nil),   // 13: test.ImportBooksResponse
+	nil,                           // 14: test.Book.ReviewsEntry
+	nil,                           // 15: test.Book.ItemsEntry
+	nil,                           // 16: test.Book.DetailedReviewsEntry
+	(*timestamppb.Timestamp)(nil), // 17: google.protobuf.Timestamp
+	(*durationpb.Duration)(nil),   // 18: google.protobuf.Duration
+	(*structpb.Struct)(nil),       // 19: google.protobuf.Struct
+	(*anypb.Any)(nil),             // 20: google.protobuf.Any
+	(*fieldmaskpb.FieldMask)(nil), // 21: google.protobuf.FieldMask
+}
+var file_testpb_book_proto_depIdxs = []int32{
+	3,  // 0: test.Comment.replies:type_name -> test.Comment
+	3,  // 1: test.Comment.parent:type_name -> test.Comment
+	1,  // 2: test.Book.author:type_name -> test.Author
+	1,  // 3: test.Book.authors:type_name -> test.Author
+	14, // 4: test.Book.reviews:type_name -> test.Book.ReviewsEntry
+	15, // 5: test.Book.items:type_name -> test.Book.ItemsEntry
+	0,  // 6: test.Book.format:type_name -> test.Format
+	17, // 7: test.Book.create_time:type_name -> google.protobuf.Timestamp
+	16, // 8: test.Book.detailed_reviews:type_name -> test.Book.DetailedReviewsEntry
+	18, // 9: test.Book.reading_time:type_name -> google.protobuf.Duration
+	19, // 10: test.Book.metadata:type_name -> google.protobuf.Struct
+	20, // 11: test.Book.details:type_name -> google.protobuf.Any
+	4,  // 12: test.UpdateBookRequest.book:type_name -> test.Book
+	21, // 13: test.UpdateBookRequest.update_mask:type_name -> google.protobuf.FieldMask
+	4,  // 14: test.ListBooksResponse.books:type_name -> test.Book
+	17, // 15: test.Shelf.create_time:type_name -> google.protobuf.Timestamp
+	10, // 16: test.UpdateShelfRequest.shelf:type_name -> test.Shelf
+	21, // 17: test.UpdateShelfRequest.update_mask:type_name -> google.protobuf.FieldMask
+	4,  // 18: test.ImportBooksResponse.books:type_name -> test.Book
+	2,  // 19: test.Book.DetailedReviewsEntry.value:type_name -> test.Review
+	5,  // 20: test.BookService.GetBook:input_type -> test.GetBookRequest
+	8,  // 21: test.BookService.ListBooks:input_type -> test.ListBooksRequest
+	8,  // 22: test.BookService.ListBooksPage:input_type -> test.ListBooksRequest
+	6,  // 23: test.BookService.UpdateBook:input_type -> test.UpdateBookRequest
+	7,  // 24: test.BookService.DeleteBook:input_type -> test.DeleteBookRequest
+	4,  // 25: test.BookService.ImportBooks:input_type -> test.Book
+	4,  // 26: test.BookService.SyncBooks:input_type -> test.Book
+	4,  // 27: test.BookService.GetBook:output_type -> test.Book
+	4,  // 28: test.BookService.ListBooks:output_type -> test.Book
+	9,  // 29: test.BookService.ListBooksPage:output_type -> test.ListBooksResponse
+	4,  // 30: test.BookService.UpdateBook:output_type -> test.Book
+	4,  // 31: test.BookService.DeleteBook:output_type -> test.Book
+	13, // 32: test.BookService.ImportBooks:output_type -> test.ImportBooksResponse
+	4,  // 33: test.BookService.SyncBooks:output_type -> test.Book
+	27, // [27:34] is the sub-list for method output_type
+	20, // [20:27] is the sub-list for method input_type
+	20, // [20:20] is the sub-list for extension type_name
+	20, // [20:20] is the sub-list for extension extendee
+	0,  // [0:20] is the sub-list for field type_name
+}
+
+func init() { file_testpb_book_proto_init() }
+func file_testpb_book_proto_init() {
+	if File_testpb_book_proto != nil {
+		return
+	}
+	file_testpb_book_proto_msgTypes[3].OneofWrappers = []any{
+		(*Book_EditionCode)(nil),
+		(*Book_Volume)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_testpb_book_proto_rawDesc), len(file_testpb_book_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   16,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_testpb_book_proto_goTypes,
+		DependencyIndexes: file_testpb_book_proto_depIdxs,
+		EnumInfos:         file_testpb_book_proto_enumTypes,
+		MessageInfos:      file_testpb_book_proto_msgTypes,
+	}.Build()
+	File_testpb_book_proto = out.File
+	file_testpb_book_proto_goTypes = nil
+	file_testpb_book_proto_depIdxs = nil
+}
@@ -0,0 +1,279 @@
+// Code generated by protoc-gen-connect-go. DO NOT EDIT.
+//
+// Source: testpb/book.proto
+
+package testpbconnect
+
+import (
+	connect "connectrpc.com/connect"
+	context "context"
+	errors "errors"
+	testpb "github.com/hxtk/aip/aiptest/testpb"
+	http "net/http"
+	strings "strings"
+)
+
+// This is a compile-time assertion to ensure that this generated file and the connect package are
+// compatible. If you get a compiler error that this constant is not defined, this code was
+// generated with a version of connect newer than the one compiled into your binary. You can fix the
+// problem by either regenerating this code with an older version of connect or updating the connect
+// version compiled into your binary.
+const _ = connect.IsAtLeastVersion1_13_0
+
+const (
+	// BookServiceName is the fully-qualified name of the BookService service.
+	BookServiceName = "test.BookService"
+)
+
+// These constants are the fully-qualified names of the RPCs defined in this package. They're
+// exposed at runtime as Spec.Procedure and as the final two segments of the HTTP route.
+//
+// Note that these are different from the fully-qualified method names used by
+// google.golang.org/protobuf/reflect/protoreflect. To convert from these constants to
+// reflection-formatted method names, remove the leading slash and convert the remaining slash to a
+// period.
+const (
+	// BookServiceGetBookProcedure is the fully-qualified name of the BookService's GetBook RPC.
+	BookServiceGetBookProcedure = "/test.BookService/GetBook"
+	// BookServiceListBooksProcedure is the fully-qualified name of the BookService's ListBooks RPC.
+	BookServiceListBooksProcedure = "/test.BookService/ListBooks"
+	// BookServiceListBooksPageProcedure is the fully-qualified name of the BookService's ListBooksPage
+	// RPC.
+	BookServiceListBooksPageProcedure = "/test.BookService/ListBooksPage"
+	// BookServiceUpdateBookProcedure is the fully-qualified name of the BookService's UpdateBook RPC.
+	BookServiceUpdateBookProcedure = "/test.BookService/UpdateBook"
+	// BookServiceDeleteBookProcedure is the fully-qualified name of the BookService's DeleteBook RPC.
+	BookServiceDeleteBookProcedure = "/test.BookService/DeleteBook"
+	// BookServiceImportBooksProcedure is the fully-qualified name of the BookService's ImportBooks RPC.
+	BookServiceImportBooksProcedure = "/test.BookService/ImportBooks"
+	// BookServiceSyncBooksProcedure is the fully-qualified name of the BookService's SyncBooks RPC.
+	BookServiceSyncBooksProcedure = "/test.BookService/SyncBooks"
+)
+
+// BookServiceClient is a client for the test.BookService service.
+type BookServiceClient interface {
+	GetBook(context.Context, *connect.Request[testpb.GetBookRequest]) (*connect.Response[testpb.Book], error)
+	ListBooks(context.Context, *connect.Request[testpb.ListBooksRequest]) (*connect.ServerStreamForClient[testpb.Book], error)
+	ListBooksPage(context.Context, *connect.Request[testpb.ListBooksRequest]) (*connect.Response[testpb.ListBooksResponse], error)
+	UpdateBook(context.Context, *connect.Request[testpb.UpdateBookRequest]) (*connect.Response[testpb.Book], error)
+	DeleteBook(context.Context, *connect.Request[testpb.DeleteBookRequest]) (*connect.Response[testpb.Book], error)
+	ImportBooks(context.Context) *connect.ClientStreamForClient[testpb.Book, testpb.ImportBooksResponse]
+	SyncBooks(context.Context) *connect.BidiStreamForClient[testpb.Book, testpb.Book]
+}
+
+// NewBookServiceClient constructs a client for the test.BookService service. By default, it uses
+// the Connect protocol with the binary Protobuf Codec, asks for gzipped responses, and sends
+// uncompressed requests. To use the gRPC or gRPC-Web protocols, supply the connect.WithGRPC() or
+// connect.WithGRPCWeb() options.
+//
+// The URL supplied here should be the base URL for the Connect or gRPC server (for example,
+// http://api.acme.com or https://acme.com/grpc).
+func NewBookServiceClient(httpClient connect.HTTPClient, baseURL string, opts ...connect.ClientOption) BookServiceClient {
+	baseURL = strings.TrimRight(baseURL, "/")
+	bookServiceMethods := testpb.File_testpb_book_proto.Services().ByName("BookService").Methods()
+	return &bookServiceClient{
+		getBook: connect.NewClient[testpb.GetBookRequest, testpb.Book](
+			httpClient,
+			baseURL+BookServiceGetBookProcedure,
+			connect.WithSchema(bookServiceMethods.ByName("GetBook")),
+			connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+			connect.WithClientOptions(opts...),
+		),
+		listBooks: connect.NewClient[testpb.ListBooksRequest, testpb.Book](
+			httpClient,
+			baseURL+BookServiceListBooksProcedure,
+			connect.WithSchema(bookServiceMethods.ByName("ListBooks")),
+			connect.WithClientOptions(opts...),
+		),
+		listBooksPage: connect.NewClient[testpb.ListBooksRequest, testpb.ListBooksResponse](
+			httpClient,
+			baseURL+BookServiceListBooksPageProcedure,
+			connect.WithSchema(bookServiceMethods.ByName("ListBooksPage")),
+			connect.WithClientOptions(opts...),
+		),
+		updateBook: connect.NewClient[testpb.UpdateBookRequest, testpb.Book](
+			httpClient,
+			baseURL+BookServiceUpdateBookProcedure,
+			connect.WithSchema(bookServiceMethods.ByName("UpdateBook")),
+			connect.WithClientOptions(opts...),
+		),
+		deleteBook: connect.NewClient[testpb.DeleteBookRequest, testpb.Book](
+			httpClient,
+			baseURL+BookServiceDeleteBookProcedure,
+			connect.WithSchema(bookServiceMethods.ByName("DeleteBook")),
+			connect.WithClientOptions(opts...),
+		),
+		importBooks: connect.NewClient[testpb.Book, testpb.ImportBooksResponse](
+			httpClient,
+			baseURL+BookServiceImportBooksProcedure,
+			connect.WithSchema(bookServiceMethods.ByName("ImportBooks")),
+			connect.WithClientOptions(opts...),
+		),
+		syncBooks: connect.NewClient[testpb.Book, testpb.Book](
+			httpClient,
+			baseURL+BookServiceSyncBooksProcedure,
+			connect.WithSchema(bookServiceMethods.ByName("SyncBooks")),
+			connect.WithClientOptions(opts...),
+		),
+	}
+}
+
+// bookServiceClient implements BookServiceClient.
+type bookServiceClient struct {
+	getBook       *connect.Client[testpb.GetBookRequest, testpb.Book]
+	listBooks     *connect.Client[testpb.ListBooksRequest, testpb.Book]
+	listBooksPage *connect.Client[testpb.ListBooksRequest, testpb.ListBooksResponse]
+	updateBook    *connect.Client[testpb.UpdateBookRequest, testpb.Book]
+	deleteBook    *connect.Client[testpb.DeleteBookRequest, testpb.Book]
+	importBooks   *connect.Client[testpb.Book, testpb.ImportBooksResponse]
+	syncBooks     *connect.Client[testpb.Book, testpb.Book]
+}
+
+// GetBook calls test.BookService.GetBook.
+func (c *bookServiceClient) GetBook(ctx context.Context, req *connect.Request[testpb.GetBookRequest]) (*connect.Response[testpb.Book], error) {
+	return c.getBook.CallUnary(ctx, req)
+}
+
+// ListBooks calls test.BookService.ListBooks.
+func (c *bookServiceClient) ListBooks(ctx context.Context, req *connect.Request[testpb.ListBooksRequest]) (*connect.ServerStreamForClient[testpb.Book], error) {
+	return c.listBooks.CallServerStream(ctx, req)
+}
+
+// ListBooksPage calls test.BookService.ListBooksPage.
+func (c *bookServiceClient) ListBooksPage(ctx context.Context, req *connect.Request[testpb.ListBooksRequest]) (*connect.Response[testpb.ListBooksResponse], error) {
+	return c.listBooksPage.CallUnary(ctx, req)
+}
+
+// UpdateBook calls test.BookService.UpdateBook.
+func (c *bookServiceClient) UpdateBook(ctx context.Context, req *connect.Request[testpb.UpdateBookRequest]) (*connect.Response[testpb.Book], error) {
+	return c.updateBook.CallUnary(ctx, req)
+}
+
+// DeleteBook calls test.BookService.DeleteBook.
+func (c *bookServiceClient) DeleteBook(ctx context.Context, req *connect.Request[testpb.DeleteBookRequest]) (*connect.Response[testpb.Book], error) {
+	return c.deleteBook.CallUnary(ctx, req)
+}
+
+// ImportBooks calls test.BookService.ImportBooks.
+func (c *bookServiceClient) ImportBooks(ctx context.Context) *connect.ClientStreamForClient[testpb.Book, testpb.ImportBooksResponse] {
+	return c.importBooks.CallClientStream(ctx)
+}
+
+// SyncBooks calls test.BookService.SyncBooks.
+func (c *bookServiceClient) SyncBooks(ctx context.Context) *connect.BidiStreamForClient[testpb.Book, testpb.Book] {
+	return c.syncBooks.CallBidiStream(ctx)
+}
+
+// BookServiceHandler is an implementation of the test.BookService service.
+type BookServiceHandler interface {
+	GetBook(context.Context, *connect.Request[testpb.GetBookRequest]) (*connect.Response[testpb.Book], error)
+	ListBooks(context.Context, *connect.Request[testpb.ListBooksRequest], *connect.ServerStream[testpb.Book]) error
+	ListBooksPage(context.Context, *connect.Request[testpb.ListBooksRequest]) (*connect.Response[testpb.ListBooksResponse], error)
+	UpdateBook(context.Context, *connect.Request[testpb.UpdateBookRequest]) (*connect.Response[testpb.Book], error)
+	DeleteBook(context.Context, *connect.Request[testpb.DeleteBookRequest]) (*connect.Response[testpb.Book], error)
+	ImportBooks(context.Context, *connect.ClientStream[testpb.Book]) (*connect.Response[testpb.ImportBooksResponse], error)
+	SyncBooks(context.Context, *connect.BidiStream[testpb.Book, testpb.Book]) error
+}
+
+// NewBookServiceHandler builds an HTTP handler from the service implementation. It returns the path
+// on which to mount the handler and the handler itself.
+//
+// By default, handlers support the Connect, gRPC, and gRPC-Web protocols with the binary Protobuf
+// and JSON codecs. They also support gzip compression.
+func NewBookServiceHandler(svc BookServiceHandler, opts ...connect.HandlerOption) (string, http.Handler) {
+	bookServiceMethods := testpb.File_testpb_book_proto.Services().ByName("BookService").Methods()
+	bookServiceGetBookHandler := connect.NewUnaryHandler(
+		BookServiceGetBookProcedure,
+		svc.GetBook,
+		connect.WithSchema(bookServiceMethods.ByName("GetBook")),
+		connect.WithIdempotency(connect.IdempotencyNoSideEffects),
+		connect.WithHandlerOptions(opts...),
+	)
+	bookServiceListBooksHandler := connect.NewServerStreamHandler(
+		BookServiceListBooksProcedure,
+		svc.ListBooks,
+		connect.WithSchema(bookServiceMethods.ByName("ListBooks")),
+		connect.WithHandlerOptions(opts...),
+	)
+	bookServiceListBooksPageHandler := connect.NewUnaryHandler(
+		BookServiceListBooksPageProcedure,
+		svc.ListBooksPage,
+		connect.WithSchema(bookServiceMethods.ByName("ListBooksPage")),
+		connect.WithHandlerOptions(opts...),
+	)
+	bookServiceUpdateBookHandler := connect.NewUnaryHandler(
+		BookServiceUpdateBookProcedure,
+		svc.UpdateBook,
+		connect.WithSchema(bookServiceMethods.ByName("UpdateBook")),
+		connect.WithHandlerOptions(opts...),
+	)
+	bookServiceDeleteBookHandler := connect.NewUnaryHandler(
+		BookServiceDeleteBookProcedure,
+		svc.DeleteBook,
+		connect.WithSchema(bookServiceMethods.ByName("DeleteBook")),
+		connect.WithHandlerOptions(opts...),
+	)
+	bookServiceImportBooksHandler := connect.NewClientStreamHandler(
+		BookServiceImportBooksProcedure,
+		svc.ImportBooks,
+		connect.WithSchema(bookServiceMethods.ByName("ImportBooks")),
+		connect.WithHandlerOptions(opts...),
+	)
+	bookServiceSyncBooksHandler := connect.NewBidiStreamHandler(
+		BookServiceSyncBooksProcedure,
+		svc.SyncBooks,
+		connect.WithSchema(bookServiceMethods.ByName("SyncBooks")),
+		connect.WithHandlerOptions(opts...),
+	)
+	return "/test.BookService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case BookServiceGetBookProcedure:
+			bookServiceGetBookHandler.ServeHTTP(w, r)
+		case BookServiceListBooksProcedure:
+			bookServiceListBooksHandler.ServeHTTP(w, r)
+		case BookServiceListBooksPageProcedure:
+			bookServiceListBooksPageHandler.ServeHTTP(w, r)
+		case BookServiceUpdateBookProcedure:
+			bookServiceUpdateBookHandler.ServeHTTP(w, r)
+		case BookServiceDeleteBookProcedure:
+			bookServiceDeleteBookHandler.ServeHTTP(w, r)
+		case BookServiceImportBooksProcedure:
+			bookServiceImportBooksHandler.ServeHTTP(w, r)
+		case BookServiceSyncBooksProcedure:
+			bookServiceSyncBooksHandler.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// UnimplementedBookServiceHandler returns CodeUnimplemented from all methods.
+type UnimplementedBookServiceHandler struct{}
+
+func (UnimplementedBookServiceHandler) GetBook(context.Context, *connect.Request[testpb.GetBookRequest]) (*connect.Response[testpb.Book], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("test.BookService.GetBook is not implemented"))
+}
+
+func (UnimplementedBookServiceHandler) ListBooks(context.Context, *connect.Request[testpb.ListBooksRequest], *connect.ServerStream[testpb.Book]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("test.BookService.ListBooks is not implemented"))
+}
+
+func (UnimplementedBookServiceHandler) ListBooksPage(context.Context, *connect.Request[testpb.ListBooksRequest]) (*connect.Response[testpb.ListBooksResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("test.BookService.ListBooksPage is not implemented"))
+}
+
+func (UnimplementedBookServiceHandler) UpdateBook(context.Context, *connect.Request[testpb.UpdateBookRequest]) (*connect.Response[testpb.Book], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("test.BookService.UpdateBook is not implemented"))
+}
+
+func (UnimplementedBookServiceHandler) DeleteBook(context.Context, *connect.Request[testpb.DeleteBookRequest]) (*connect.Response[testpb.Book], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("test.BookService.DeleteBook is not implemented"))
+}
+
+func (UnimplementedBookServiceHandler) ImportBooks(context.Context, *connect.ClientStream[testpb.Book]) (*connect.Response[testpb.ImportBooksResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("test.BookService.ImportBooks is not implemented"))
+}
+
+func (UnimplementedBookServiceHandler) SyncBooks(context.Context, *connect.BidiStream[testpb.Book, testpb.Book]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("test.BookService.SyncBooks is not implemented"))
+}
@@ -0,0 +1,280 @@
+// Command aiprepl evaluates AIP-160 filters, AIP-132 orders and AIP-157 read
+// masks against a sample dataset, so that API designers can validate their
+// semantics before writing server code.
+//
+// Usage:
+//
+//	aiprepl -descriptor_set set.binpb -message pkg.Msg -data records.json
+//
+// The dataset holds messages of the given type, either as a JSON array of
+// messages in the protobuf JSON format (.json files) or as text format
+// messages separated by lines containing only "---" (.txtpb and .textproto
+// files).
+//
+// Commands are read from standard input, one per line:
+//
+//	filter EXPR    set the filter; an empty EXPR matches all records
+//	order_by EXPR  set the order; an empty EXPR keeps the dataset order
+//	mask PATHS     set the read mask, e.g. "title,author.family_name";
+//	               an empty PATHS selects all fields
+//	show           print the matching records
+//	help           print this list
+//	quit           exit
+//
+// Setting a filter, order or mask prints the matching records.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/hxtk/aip/masks"
+	"github.com/hxtk/aip/query"
+)
+
+const help = `commands:
+  filter EXPR    set the filter
+  order_by EXPR  set the order
+  mask PATHS     set the read mask, comma-separated
+  show           print the matching records
+  help           print this list
+  quit           exit
+`
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "aiprepl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("aiprepl", flag.ContinueOnError)
+	descriptorSet := fs.String("descriptor_set", "", "path to a binary FileDescriptorSet describing the records (required)")
+	messageName := fs.String("message", "", "full name of the record message type, e.g. library.v1.Book (required)")
+	dataPath := fs.String("data", "", "path to the dataset, a .json, .txtpb or .textproto file (required)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *descriptorSet == "" || *messageName == "" || *dataPath == "" {
+		return errors.New("-descriptor_set, -message and -data are required")
+	}
+
+	files, desc, err := loadDescriptor(*descriptorSet, *messageName)
+	if err != nil {
+		return err
+	}
+	records, err := loadRecords(*dataPath, files, desc)
+	if err != nil {
+		return err
+	}
+
+	s := &session{desc: desc, records: records, out: stdout}
+	fmt.Fprintf(stdout, "loaded %d %s records; type \"help\" for commands\n", len(records), desc.FullName())
+	scanner := bufio.NewScanner(stdin)
+	for {
+		fmt.Fprint(stdout, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(stdout)
+			return scanner.Err()
+		}
+		cmd, arg, _ := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		arg = strings.TrimSpace(arg)
+		switch cmd {
+		case "":
+		case "quit", "exit":
+			return nil
+		case "help":
+			fmt.Fprint(stdout, help)
+		default:
+			if err := s.exec(cmd, arg); err != nil {
+				fmt.Fprintln(stdout, "error:", err)
+			}
+		}
+	}
+}
+
+// session is the state of a REPL session.
+type session struct {
+	desc    protoreflect.MessageDescriptor
+	records []proto.Message
+	out     io.Writer
+
+	match func(proto.Message) bool
+	cmp   func(a, b proto.Message) int
+	mask  *masks.FieldMask
+}
+
+// exec runs the command cmd with argument arg.
+func (s *session) exec(cmd, arg string) error {
+	switch cmd {
+	case "filter":
+		f, err := query.ParseFilter(arg)
+		if err != nil {
+			return err
+		}
+		match, err := query.DynamicFilter(s.desc, f)
+		if err != nil {
+			return err
+		}
+		s.match = match
+	case "order_by":
+		order, err := query.ParseOrderBy(arg)
+		if err != nil {
+			return err
+		}
+		cmp, err := query.DynamicComparer(s.desc, order)
+		if err != nil {
+			return err
+		}
+		s.cmp = cmp
+	case "mask":
+		if arg == "" {
+			s.mask = nil
+			break
+		}
+		paths := strings.Split(arg, ",")
+		for i := range paths {
+			paths[i] = strings.TrimSpace(paths[i])
+		}
+		mask, err := masks.New(s.desc, masks.ModeRead, paths...)
+		if err != nil {
+			return err
+		}
+		s.mask = mask
+	case "show":
+	default:
+		return fmt.Errorf("unknown command %q; type \"help\" for commands", cmd)
+	}
+	return s.show()
+}
+
+// show prints the records matching the filter, in order, with the read mask
+// applied.
+func (s *session) show() error {
+	var selected []proto.Message
+	for _, r := range s.records {
+		if s.match == nil || s.match(r) {
+			selected = append(selected, r)
+		}
+	}
+	if s.cmp != nil {
+		slices.SortStableFunc(selected, s.cmp)
+	}
+
+	for _, r := range selected {
+		r = proto.Clone(r)
+		if err := masks.PruneMessage(r, s.mask); err != nil {
+			return err
+		}
+		b, err := protojson.Marshal(r)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(s.out, "%s\n", b)
+	}
+	fmt.Fprintf(s.out, "(%d of %d records)\n", len(selected), len(s.records))
+	return nil
+}
+
+// loadDescriptor reads the descriptor set at path and finds the message
+// named name in it.
+func loadDescriptor(path, name string) (*protoregistry.Files, protoreflect.MessageDescriptor, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(b, &set); err != nil {
+		return nil, nil, fmt.Errorf("reading descriptor set: %w", err)
+	}
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading descriptor set: %w", err)
+	}
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, nil, err
+	}
+	md, ok := desc.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s is not a message", name)
+	}
+	return files, md, nil
+}
+
+// loadRecords reads the dataset at path, holding messages of type desc.
+func loadRecords(path string, files *protoregistry.Files, desc protoreflect.MessageDescriptor) ([]proto.Message, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	types := dynamicpb.NewTypes(files)
+
+	var records []proto.Message
+	switch ext := filepath.Ext(path); ext {
+	case ".json":
+		var raw []json.RawMessage
+		if err := json.Unmarshal(b, &raw); err != nil {
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+		for i, r := range raw {
+			m := dynamicpb.NewMessage(desc)
+			if err := (protojson.UnmarshalOptions{Resolver: types}).Unmarshal(r, m); err != nil {
+				return nil, fmt.Errorf("reading record %d of %s: %w", i, path, err)
+			}
+			records = append(records, m)
+		}
+	case ".txtpb", ".textproto":
+		for i, text := range splitTextRecords(string(b)) {
+			m := dynamicpb.NewMessage(desc)
+			if err := (prototext.UnmarshalOptions{Resolver: types}).Unmarshal([]byte(text), m); err != nil {
+				return nil, fmt.Errorf("reading record %d of %s: %w", i, path, err)
+			}
+			records = append(records, m)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported dataset format %q", ext)
+	}
+	return records, nil
+}
+
+// splitTextRecords splits text format records separated by "---" lines.
+// Blank records are skipped.
+func splitTextRecords(text string) []string {
+	var records []string
+	var current strings.Builder
+	flush := func() {
+		if strings.TrimSpace(current.String()) != "" {
+			records = append(records, current.String())
+		}
+		current.Reset()
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "---" {
+			flush()
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+	}
+	flush()
+	return records
+}
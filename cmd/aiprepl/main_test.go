@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/hxtk/aip/aiptest"
+	"github.com/hxtk/aip/aiptest/testpb"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+
+	b, err := proto.Marshal(fileDescriptorSet(testpb.File_testpb_book_proto))
+	if err != nil {
+		t.Fatal(err)
+	}
+	setPath := filepath.Join(dir, "set.binpb")
+	if err := os.WriteFile(setPath, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var records []string
+	for _, book := range aiptest.Books() {
+		b, err := protojson.Marshal(book)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, string(b))
+	}
+	jsonPath := filepath.Join(dir, "books.json")
+	if err := os.WriteFile(jsonPath, []byte("["+strings.Join(records, ",")+"]"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	textPath := filepath.Join(dir, "books.txtpb")
+	text := "name: \"books/1\"\ntitle: \"Dune\"\n---\nname: \"books/2\"\ntitle: \"Emma\"\n---\n"
+	if err := os.WriteFile(textPath, []byte(text), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		stdin   string
+		want    []string
+		notWant []string
+		wantErr bool
+	}{
+		{
+			name:  "filter, order and mask",
+			args:  []string{"-descriptor_set", setPath, "-message", "test.Book", "-data", jsonPath},
+			stdin: "filter author.family_name = Austen\norder_by title desc\nmask title\n",
+			want: []string{
+				"loaded 5 test.Book records",
+				"(2 of 5 records)",
+				`{"title":"Persuasion"}` + "\n" + `{"title":"Emma"}` + "\n(2 of 5 records)",
+			},
+		},
+		{
+			name:    "invalid expressions",
+			args:    []string{"-descriptor_set", setPath, "-message", "test.Book", "-data", jsonPath},
+			stdin:   "filter title = (\norder_by authors\nmask title.subtitle\nfrobnicate\nquit\nshow\n",
+			want:    []string{"error: ", `unknown command "frobnicate"`},
+			notWant: []string{"records)"},
+		},
+		{
+			name:  "text format dataset",
+			args:  []string{"-descriptor_set", setPath, "-message", "test.Book", "-data", textPath},
+			stdin: "show\n",
+			want:  []string{"loaded 2 test.Book records", `"title":"Dune"`, "(2 of 2 records)"},
+		},
+		{
+			name:    "missing flags",
+			args:    []string{"-descriptor_set", setPath},
+			wantErr: true,
+		},
+		{
+			name:    "not a message",
+			args:    []string{"-descriptor_set", setPath, "-message", "test.Format", "-data", jsonPath},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := run(tt.args, strings.NewReader(tt.stdin), &out)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, w := range tt.want {
+				// protojson output spacing is unstable, so compare without
+				// spaces.
+				if !strings.Contains(strings.ReplaceAll(out.String(), " ", ""), strings.ReplaceAll(w, " ", "")) {
+					t.Errorf("output missing %q:\n%s", w, out.String())
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(out.String(), w) {
+					t.Errorf("output unexpectedly contains %q:\n%s", w, out.String())
+				}
+			}
+		})
+	}
+}
+
+// fileDescriptorSet returns a descriptor set holding fd and its transitive
+// imports.
+func fileDescriptorSet(fd protoreflect.FileDescriptor) *descriptorpb.FileDescriptorSet {
+	set := &descriptorpb.FileDescriptorSet{}
+	seen := map[string]bool{}
+	var add func(fd protoreflect.FileDescriptor)
+	add = func(fd protoreflect.FileDescriptor) {
+		if seen[fd.Path()] {
+			return
+		}
+		seen[fd.Path()] = true
+		imports := fd.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			add(imports.Get(i).FileDescriptor)
+		}
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(fd))
+	}
+	add(fd)
+	return set
+}
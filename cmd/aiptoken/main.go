@@ -0,0 +1,247 @@
+// Command aiptoken decrypts and prints the contents of a page token minted by
+// the query package.
+//
+// Usage:
+//
+//	aiptoken -keyset keyset.json [-aad ctx] [-descriptor_set set.binpb -message pkg.Msg] TOKEN
+//
+// The keyset must be a cleartext Tink keyset in JSON format. If no token is
+// given on the command line, it is read from standard input. Tokens copied
+// from URLs may be percent-encoded, and in either base64 alphabet.
+//
+// When a descriptor set and message name are given, the cursor is printed as
+// text format; otherwise its raw wire-format fields are printed, or the JSON
+// of cursors minted with query.CursorJSON as is.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tink-crypto/tink-go/v2/aead"
+	"github.com/tink-crypto/tink-go/v2/insecurecleartextkeyset"
+	"github.com/tink-crypto/tink-go/v2/keyset"
+	"github.com/tink-crypto/tink-go/v2/tink"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/hxtk/aip/query"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout, time.Now()); err != nil {
+		fmt.Fprintln(os.Stderr, "aiptoken:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer, now time.Time) error {
+	fs := flag.NewFlagSet("aiptoken", flag.ContinueOnError)
+	keysetPath := fs.String("keyset", "", "path to a cleartext JSON Tink keyset (required)")
+	aad := fs.String("aad", "", "associated data the token was minted with")
+	descriptorSet := fs.String("descriptor_set", "", "path to a binary FileDescriptorSet describing the cursor message")
+	messageName := fs.String("message", "", "full name of the cursor message type, e.g. library.v1.Book")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *keysetPath == "" {
+		return errors.New("-keyset is required")
+	}
+	if (*descriptorSet == "") != (*messageName == "") {
+		return errors.New("-descriptor_set and -message must be given together")
+	}
+
+	token, err := readToken(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+
+	primitive, err := loadAEAD(*keysetPath)
+	if err != nil {
+		return err
+	}
+
+	info, err := query.InspectToken(token, primitive, []byte(*aad), query.WithLenientDecoding())
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "version:   %d\n", info.Version)
+	fmt.Fprintf(stdout, "direction: %s\n", info.Direction)
+	fmt.Fprintf(stdout, "format:    %s\n", info.CursorFormat)
+	fmt.Fprintf(stdout, "issued:    %s (%s ago)\n", info.IssueTime.UTC().Format(time.RFC3339Nano), now.Sub(info.IssueTime).Round(time.Second))
+	fmt.Fprintf(stdout, "order:     %s\n", info.Order)
+	if info.Snapshot != "" {
+		fmt.Fprintf(stdout, "snapshot:  %s\n", info.Snapshot)
+	}
+	fmt.Fprintln(stdout, "cursor:")
+
+	if *messageName == "" {
+		if info.CursorFormat == query.CursorJSON {
+			fmt.Fprintf(stdout, "  %s\n", info.Cursor)
+			return nil
+		}
+		return printRaw(stdout, info.Cursor, "  ")
+	}
+	msg, err := newMessage(*descriptorSet, *messageName)
+	if err != nil {
+		return err
+	}
+	if info.CursorFormat == query.CursorJSON {
+		err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(info.Cursor, msg)
+	} else {
+		err = proto.Unmarshal(info.Cursor, msg)
+	}
+	if err != nil {
+		return fmt.Errorf("decoding cursor as %s: %w", *messageName, err)
+	}
+	text, err := prototext.MarshalOptions{Multiline: true, Indent: "  "}.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(strings.TrimRight(string(text), "\n"), "\n") {
+		fmt.Fprintf(stdout, "  %s\n", line)
+	}
+	return nil
+}
+
+func readToken(args []string, stdin io.Reader) (string, error) {
+	switch len(args) {
+	case 0:
+		b, err := io.ReadAll(stdin)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(b)), nil
+	case 1:
+		return args[0], nil
+	default:
+		return "", errors.New("expected at most one token argument")
+	}
+}
+
+func loadAEAD(path string) (tink.AEAD, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	handle, err := insecurecleartextkeyset.Read(keyset.NewJSONReader(f))
+	if err != nil {
+		return nil, fmt.Errorf("reading keyset: %w", err)
+	}
+	primitive, err := aead.New(handle)
+	if err != nil {
+		return nil, fmt.Errorf("creating AEAD: %w", err)
+	}
+	return primitive, nil
+}
+
+func newMessage(path, name string) (proto.Message, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(b, &set); err != nil {
+		return nil, fmt.Errorf("reading descriptor set: %w", err)
+	}
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, fmt.Errorf("reading descriptor set: %w", err)
+	}
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, err
+	}
+	md, ok := desc.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a message", name)
+	}
+	return dynamicpb.NewMessage(md), nil
+}
+
+// printRaw prints the wire-format fields of b, similar to protoc --decode_raw.
+// Length-delimited fields that parse as messages are printed recursively.
+func printRaw(w io.Writer, b []byte, indent string) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		switch typ {
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			fmt.Fprintf(w, "%s%d: %d\n", indent, num, v)
+			b = b[n:]
+		case protowire.Fixed32Type:
+			v, n := protowire.ConsumeFixed32(b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			fmt.Fprintf(w, "%s%d: 0x%08x\n", indent, num, v)
+			b = b[n:]
+		case protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			fmt.Fprintf(w, "%s%d: 0x%016x\n", indent, num, v)
+			b = b[n:]
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+			if len(v) > 0 && !isText(v) {
+				var nested bytes.Buffer
+				if err := printRaw(&nested, v, indent+"  "); err == nil {
+					fmt.Fprintf(w, "%s%d {\n%s%s}\n", indent, num, nested.String(), indent)
+					continue
+				}
+			}
+			fmt.Fprintf(w, "%s%d: %s\n", indent, num, strconv.Quote(string(v)))
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			fmt.Fprintf(w, "%s%d: <wire type %d>\n", indent, num, typ)
+			b = b[n:]
+		}
+	}
+	return nil
+}
+
+// isText reports whether b looks like printable UTF-8 text.
+func isText(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, r := range string(b) {
+		if r < ' ' && r != '\t' && r != '\n' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tink-crypto/tink-go/v2/aead"
+	"github.com/tink-crypto/tink-go/v2/insecurecleartextkeyset"
+	"github.com/tink-crypto/tink-go/v2/keyset"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/query"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+
+	handle, err := keyset.NewHandle(aead.AES256GCMKeyTemplate())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ks bytes.Buffer
+	if err := insecurecleartextkeyset.Write(handle, keyset.NewJSONWriter(&ks)); err != nil {
+		t.Fatal(err)
+	}
+	keysetPath := filepath.Join(dir, "keyset.json")
+	if err := os.WriteFile(keysetPath, ks.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	set := fileDescriptorSet(testpb.File_testpb_book_proto)
+	b, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setPath := filepath.Join(dir, "set.binpb")
+	if err := os.WriteFile(setPath, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	primitive, err := aead.New(handle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	order, _ := query.ParseOrderBy("author.family_name, title desc")
+	book := &testpb.Book{Title: "Dune", Author: &testpb.Author{FamilyName: "Herbert"}}
+	token, err := query.NewCursor(book, order, primitive, []byte("ctx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonToken, err := query.NewCursor(book, order, primitive, []byte("ctx"), query.WithCursorFormat(query.CursorJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		stdin   string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "typed cursor",
+			args: []string{"-keyset", keysetPath, "-aad", "ctx", "-descriptor_set", setPath, "-message", "test.Book", token},
+			want: []string{
+				"version:   1",
+				"direction: NEXT",
+				"format:    BINARY",
+				"order:     author.family_name:asc|title:desc",
+				`"Dune"`,
+				`"Herbert"`,
+			},
+		},
+		{
+			name:  "raw cursor from stdin",
+			args:  []string{"-keyset", keysetPath, "-aad", "ctx"},
+			stdin: token + "\n",
+			want:  []string{`1: "Dune"`, `2: "Herbert"`},
+		},
+		{
+			name: "typed JSON cursor",
+			args: []string{"-keyset", keysetPath, "-aad", "ctx", "-descriptor_set", setPath, "-message", "test.Book", jsonToken},
+			want: []string{
+				"format:    JSON",
+				`title: "Dune"`,
+				`family_name: "Herbert"`,
+			},
+		},
+		{
+			name: "raw JSON cursor",
+			args: []string{"-keyset", keysetPath, "-aad", "ctx", jsonToken},
+			want: []string{"format:    JSON", `"title":`, `"Dune"`, `"familyName":`, `"Herbert"`},
+		},
+		{
+			name:    "wrong aad",
+			args:    []string{"-keyset", keysetPath, "-aad", "other", token},
+			wantErr: true,
+		},
+		{
+			name:    "missing keyset",
+			args:    []string{token},
+			wantErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := run(tc.args, strings.NewReader(tc.stdin), &out, time.Now())
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("run() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			for _, w := range tc.want {
+				if !strings.Contains(out.String(), w) {
+					t.Errorf("output missing %q:\n%s", w, out.String())
+				}
+			}
+		})
+	}
+}
+
+// fileDescriptorSet returns fd and its transitive imports, as produced by
+// protoc --include_imports.
+func fileDescriptorSet(fd protoreflect.FileDescriptor) *descriptorpb.FileDescriptorSet {
+	set := &descriptorpb.FileDescriptorSet{}
+	seen := map[string]bool{}
+	var add func(fd protoreflect.FileDescriptor)
+	add = func(fd protoreflect.FileDescriptor) {
+		if seen[fd.Path()] {
+			return
+		}
+		seen[fd.Path()] = true
+		imports := fd.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			add(imports.Get(i).FileDescriptor)
+		}
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(fd))
+	}
+	add(fd)
+	return set
+}
@@ -0,0 +1,105 @@
+package aip
+
+import (
+	"context"
+	"fmt"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Deleter holds the storage operations of an AIP-135 Delete method, for
+// Delete to apply the standard semantics to.
+type Deleter[M proto.Message] struct {
+	// Get returns the resource named name. If it does not exist, Get must
+	// return an error with code connect.CodeNotFound.
+	Get func(ctx context.Context, name string) (M, error)
+
+	// HasChildren reports whether the resource named name has child
+	// resources. If nil, resources are assumed to have none.
+	HasChildren func(ctx context.Context, name string) (bool, error)
+
+	// Delete deletes the resource named name. force is the force field of
+	// the request: if set, Delete must also delete the children of the
+	// resource.
+	Delete func(ctx context.Context, name string, force bool) error
+}
+
+// Delete implements an AIP-135 Delete method for the resource named by the
+// name field of req, using the storage operations of d, and returns the
+// deleted resource. The request fields below are honored if req has them:
+//
+//   - etag (AIP-154): if set and not equal to the etag field of the
+//     resource, the request fails with connect.CodeAborted.
+//   - allow_missing: if set and the resource does not exist, the request
+//     succeeds without deleting anything, returning an empty resource.
+//     Otherwise it fails with connect.CodeNotFound.
+//   - force: unless set, deleting a resource with children fails with
+//     connect.CodeFailedPrecondition.
+//
+// Example:
+//
+//	func (s *server) DeleteBook(ctx context.Context, req *connect.Request[pb.DeleteBookRequest]) (*connect.Response[pb.Book], error) {
+//		book, err := aip.Delete(ctx, req.Msg, s.books)
+//		if err != nil {
+//			return nil, err
+//		}
+//		return connect.NewResponse(book), nil
+//	}
+//
+// Errors returned by d other than those from Get for a missing resource are
+// returned as is.
+func Delete[S any, M interface {
+	proto.Message
+	*S
+}](ctx context.Context, req proto.Message, d Deleter[M]) (M, error) {
+	r := req.ProtoReflect()
+	name, ok := stringValue(r, "name")
+	if !ok {
+		return nil, violationError(ctx, []Violation{{Field: "name", Reason: ReasonFieldRequired, Description: "required field is not set"}})
+	}
+
+	resource, err := d.Get(ctx, name)
+	if connect.CodeOf(err) == connect.CodeNotFound {
+		if boolField(r, "allow_missing") {
+			return M(new(S)), nil
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if etag, ok := stringValue(r, "etag"); ok {
+		if current, _ := stringValue(resource.ProtoReflect(), "etag"); etag != current {
+			return nil, connect.NewError(connect.CodeAborted, fmt.Errorf("etag %q does not match the current etag of %s", etag, name))
+		}
+	}
+
+	force := boolField(r, "force")
+	if d.HasChildren != nil && !force {
+		children, err := d.HasChildren(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if children {
+			return nil, connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("%s has child resources; set force to delete them too", name))
+		}
+	}
+
+	if err := d.Delete(ctx, name, force); err != nil {
+		return nil, err
+	}
+	return resource, nil
+}
+
+// boolField returns the value of the singular bool field of m named name, or
+// false if m has no such field.
+func boolField(m protoreflect.Message, name protoreflect.Name) bool {
+	fd := m.Descriptor().Fields().ByName(name)
+	if fd == nil || fd.IsList() || fd.Kind() != protoreflect.BoolKind {
+		return false
+	}
+	return m.Get(fd).Bool()
+}
@@ -0,0 +1,99 @@
+package aip_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/hxtk/aip"
+	"github.com/hxtk/aip/aiptest/testpb"
+)
+
+// bookStore is an in-memory store of books, by name, with the names of
+// their children.
+type bookStore struct {
+	books    map[string]*testpb.Book
+	children map[string]bool
+	deleted  []string
+}
+
+func (s *bookStore) deleter() aip.Deleter[*testpb.Book] {
+	return aip.Deleter[*testpb.Book]{
+		Get: func(_ context.Context, name string) (*testpb.Book, error) {
+			book, ok := s.books[name]
+			if !ok {
+				return nil, connect.NewError(connect.CodeNotFound, errors.New(name+" not found"))
+			}
+			return book, nil
+		},
+		HasChildren: func(_ context.Context, name string) (bool, error) {
+			return s.children[name], nil
+		},
+		Delete: func(_ context.Context, name string, _ bool) error {
+			delete(s.books, name)
+			s.deleted = append(s.deleted, name)
+			return nil
+		},
+	}
+}
+
+func newBookStore() *bookStore {
+	return &bookStore{
+		books: map[string]*testpb.Book{
+			"books/1": {Name: "books/1", Etag: "v1"},
+			"books/2": {Name: "books/2", Etag: "v7"},
+		},
+		children: map[string]bool{"books/2": true},
+	}
+}
+
+func TestDelete(t *testing.T) {
+	ctx := context.Background()
+	store := newBookStore()
+
+	book, err := aip.Delete(ctx, &testpb.DeleteBookRequest{Name: "books/1", Etag: "v1"}, store.deleter())
+	require.NoError(t, err)
+	require.Equal(t, "books/1", book.GetName())
+	require.Equal(t, []string{"books/1"}, store.deleted)
+
+	_, err = aip.Delete(ctx, &testpb.DeleteBookRequest{Name: "books/1"}, store.deleter())
+	require.Equal(t, connect.CodeNotFound, connect.CodeOf(err))
+
+	book, err = aip.Delete(ctx, &testpb.DeleteBookRequest{Name: "books/1", AllowMissing: true}, store.deleter())
+	require.NoError(t, err)
+	require.True(t, proto.Equal(&testpb.Book{}, book))
+	require.Equal(t, []string{"books/1"}, store.deleted)
+}
+
+func TestDelete_Preconditions(t *testing.T) {
+	ctx := context.Background()
+	store := newBookStore()
+
+	_, err := aip.Delete(ctx, &testpb.DeleteBookRequest{Name: "books/2", Etag: "v6", Force: true}, store.deleter())
+	require.Equal(t, connect.CodeAborted, connect.CodeOf(err))
+
+	_, err = aip.Delete(ctx, &testpb.DeleteBookRequest{Name: "books/2", Etag: "v7"}, store.deleter())
+	require.Equal(t, connect.CodeFailedPrecondition, connect.CodeOf(err))
+	require.Empty(t, store.deleted)
+
+	_, err = aip.Delete(ctx, &testpb.DeleteBookRequest{Name: "books/2", Etag: "v7", Force: true}, store.deleter())
+	require.NoError(t, err)
+	require.Equal(t, []string{"books/2"}, store.deleted)
+
+	_, err = aip.Delete(ctx, &testpb.DeleteBookRequest{}, store.deleter())
+	require.Equal(t, connect.CodeInvalidArgument, connect.CodeOf(err))
+}
+
+func TestDelete_StorageError(t *testing.T) {
+	store := newBookStore()
+	d := store.deleter()
+	failure := errors.New("connection reset")
+	d.Delete = func(context.Context, string, bool) error { return failure }
+
+	_, err := aip.Delete(context.Background(), &testpb.DeleteBookRequest{Name: "books/1"}, d)
+	require.ErrorIs(t, err, failure)
+}
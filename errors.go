@@ -0,0 +1,173 @@
+package aip
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"connectrpc.com/connect"
+	"golang.org/x/text/language"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+
+	"github.com/hxtk/aip/query"
+)
+
+// Reasons of field violations. They are stable and machine-readable, unlike
+// the descriptions of violations, and are sent as the reason of the
+// google.rpc.ErrorInfo detail of validation errors (AIP-193), so that
+// clients and alerting can key off them instead of error text.
+const (
+	ReasonFieldRequired   = "FIELD_REQUIRED"
+	ReasonFieldOutputOnly = "FIELD_OUTPUT_ONLY"
+	ReasonFieldImmutable  = "FIELD_IMMUTABLE"
+	ReasonFieldInvalid    = "FIELD_INVALID"
+	ReasonPageSizeInvalid = "PAGE_SIZE_INVALID"
+
+	// ReasonPageTokenInvalid is the reason of page tokens that are
+	// malformed, forged, or minted for another request.
+	ReasonPageTokenInvalid = "PAGE_TOKEN_INVALID"
+	// ReasonPageTokenExpired is the reason of page tokens older than the
+	// maximum age set with query.WithMaxAge; the client should restart
+	// iteration from the first page.
+	ReasonPageTokenExpired = "PAGE_TOKEN_EXPIRED"
+	// ReasonPageTokenStale is the reason of page tokens minted under an
+	// older schema (see query.ErrStaleCursorSchema); the client should
+	// restart iteration from the first page.
+	ReasonPageTokenStale = "PAGE_TOKEN_STALE"
+
+	ReasonFilterInvalid = "FILTER_INVALID"
+
+	// ReasonOrderByInvalid is the reason of malformed order_by clauses.
+	ReasonOrderByInvalid = "ORDER_BY_INVALID"
+	// ReasonOrderByUnsupportedField is the reason of order_by clauses
+	// naming a field that cannot be sorted on.
+	ReasonOrderByUnsupportedField = "ORDER_BY_UNSUPPORTED_FIELD"
+
+	ReasonFieldMaskInvalid = "FIELD_MASK_INVALID"
+)
+
+// Violation is a field violation of an invalid request.
+type Violation struct {
+	// Field is the path of the invalid field, e.g. "filter".
+	Field string
+	// Reason is one of the Reason constants.
+	Reason string
+	// Description is the description of the violation in English, which
+	// may include details such as the position of a syntax error.
+	Description string
+}
+
+// MessageCatalog returns the description of v in the given locale, one of
+// those the catalog was added with, or false if it has none, e.g. by
+// looking up a message template by v.Reason.
+type MessageCatalog func(locale language.Tag, v Violation) (string, bool)
+
+// WithMessageCatalog renders validation errors in the locale of the caller,
+// chosen among the supported locales by its Accept-Language request header.
+// The descriptions c returns are sent in a google.rpc.LocalizedMessage
+// detail, for display to end users; the message and BadRequest detail of
+// the error remain in English. Requests without an Accept-Language header,
+// or accepting none of the supported locales, are answered in English only.
+func WithMessageCatalog(c MessageCatalog, supported ...language.Tag) Option {
+	return func(o *options) {
+		o.catalog = c
+		o.locales = supported
+	}
+}
+
+// fieldViolations returns the violations described by fv.
+func fieldViolations(fv *query.FieldViolationError) []Violation {
+	var violations []Violation
+	for _, v := range fv.FieldViolations() {
+		reason := ReasonFieldInvalid
+		switch v.GetField() {
+		case query.FilterField:
+			reason = ReasonFilterInvalid
+		case query.OrderByField:
+			reason = ReasonOrderByInvalid
+			if errors.Is(fv, query.ErrUnsupportedField) {
+				reason = ReasonOrderByUnsupportedField
+			}
+		}
+		violations = append(violations, Violation{
+			Field:       v.GetField(),
+			Reason:      reason,
+			Description: v.GetDescription(),
+		})
+	}
+	return violations
+}
+
+// invalidArgument returns a CodeInvalidArgument error with a BadRequest
+// detail holding violations, an ErrorInfo detail with the reason of the
+// first, in the domain of the service of spec, and a LocalizedMessage
+// detail if the caller's locale is supported by the message catalog.
+func (o *options) invalidArgument(spec connect.Spec, header http.Header, violations []Violation) error {
+	descriptions := make([]string, len(violations))
+	fieldViolations := make([]*errdetails.BadRequest_FieldViolation, len(violations))
+	for i, v := range violations {
+		descriptions[i] = v.Field + ": " + v.Description
+		fieldViolations[i] = &errdetails.BadRequest_FieldViolation{
+			Field:       v.Field,
+			Description: v.Description,
+		}
+	}
+	err := connect.NewError(connect.CodeInvalidArgument, errors.New(strings.Join(descriptions, "; ")))
+	if detail, detailErr := connect.NewErrorDetail(&errdetails.BadRequest{FieldViolations: fieldViolations}); detailErr == nil {
+		err.AddDetail(detail)
+	}
+	if len(violations) > 0 {
+		service, _, _ := strings.Cut(strings.TrimPrefix(spec.Procedure, "/"), "/")
+		info := &errdetails.ErrorInfo{
+			Reason:   violations[0].Reason,
+			Domain:   service,
+			Metadata: map[string]string{"field": violations[0].Field},
+		}
+		if detail, detailErr := connect.NewErrorDetail(info); detailErr == nil {
+			err.AddDetail(detail)
+		}
+	}
+	if msg := o.localize(header, violations); msg != nil {
+		if detail, detailErr := connect.NewErrorDetail(msg); detailErr == nil {
+			err.AddDetail(detail)
+		}
+	}
+	return err
+}
+
+// localize returns the descriptions of violations in the locale accepted by
+// the request with the given header, or nil if there is none. Violations
+// missing from the catalog keep their English descriptions.
+func (o *options) localize(header http.Header, violations []Violation) *errdetails.LocalizedMessage {
+	accept := header.Get("Accept-Language")
+	if o.catalog == nil || len(o.locales) == 0 || accept == "" {
+		return nil
+	}
+	tags, _, err := language.ParseAcceptLanguage(accept)
+	if err != nil || len(tags) == 0 {
+		return nil
+	}
+	_, index, confidence := language.NewMatcher(o.locales).Match(tags...)
+	if confidence == language.No {
+		return nil
+	}
+	locale := o.locales[index]
+
+	descriptions := make([]string, len(violations))
+	translated := false
+	for i, v := range violations {
+		description, ok := o.catalog(locale, v)
+		if !ok {
+			description = v.Description
+		}
+		translated = translated || ok
+		descriptions[i] = v.Field + ": " + description
+	}
+	if !translated {
+		return nil
+	}
+	return &errdetails.LocalizedMessage{
+		Locale:  locale.String(),
+		Message: strings.Join(descriptions, "; "),
+	}
+}
@@ -0,0 +1,111 @@
+package aip_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/language"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/hxtk/aip"
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/query"
+)
+
+// errorDetails returns the ErrorInfo and LocalizedMessage details of err.
+func errorDetails(t *testing.T, err error) (*errdetails.ErrorInfo, *errdetails.LocalizedMessage) {
+	t.Helper()
+	var connectErr *connect.Error
+	require.ErrorAs(t, err, &connectErr)
+	var info *errdetails.ErrorInfo
+	var localized *errdetails.LocalizedMessage
+	for _, d := range connectErr.Details() {
+		msg, err := d.Value()
+		require.NoError(t, err)
+		switch msg := msg.(type) {
+		case *errdetails.ErrorInfo:
+			info = msg
+		case *errdetails.LocalizedMessage:
+			localized = msg
+		}
+	}
+	return info, localized
+}
+
+func TestErrorInfo(t *testing.T) {
+	client := newClient(t, &fakeBookService{})
+
+	_, err := client.UpdateBook(context.Background(), connect.NewRequest(&testpb.UpdateBookRequest{
+		Book:       &testpb.Book{Title: "Dune"},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"subtitle"}},
+	}))
+	info, localized := errorDetails(t, err)
+	require.Equal(t, aip.ReasonFieldMaskInvalid, info.GetReason())
+	require.Equal(t, "test.BookService", info.GetDomain())
+	require.Equal(t, map[string]string{"field": "update_mask"}, info.GetMetadata())
+	require.Nil(t, localized)
+
+	_, err = client.GetBook(context.Background(), connect.NewRequest(&testpb.GetBookRequest{}))
+	info, _ = errorDetails(t, err)
+	require.Equal(t, aip.ReasonFieldRequired, info.GetReason())
+}
+
+func TestMessageCatalog(t *testing.T) {
+	catalog := func(locale language.Tag, v aip.Violation) (string, bool) {
+		if locale == language.French && v.Reason == aip.ReasonFieldRequired {
+			return "champ obligatoire manquant", true
+		}
+		return "", false
+	}
+	client := newClient(t, &fakeBookService{}, aip.WithMessageCatalog(catalog, language.English, language.French))
+
+	getBook := func(acceptLanguage string) error {
+		req := connect.NewRequest(&testpb.GetBookRequest{})
+		if acceptLanguage != "" {
+			req.Header().Set("Accept-Language", acceptLanguage)
+		}
+		_, err := client.GetBook(context.Background(), req)
+		return err
+	}
+
+	err := getBook("fr-CA, en;q=0.5")
+	requireViolations(t, err, "name")
+	require.Equal(t, "name: required field is not set", err.(*connect.Error).Message())
+	info, localized := errorDetails(t, err)
+	require.Equal(t, aip.ReasonFieldRequired, info.GetReason())
+	require.Equal(t, "fr", localized.GetLocale())
+	require.Equal(t, "name: champ obligatoire manquant", localized.GetMessage())
+
+	for _, acceptLanguage := range []string{"", "en-US", "ja"} {
+		_, localized = errorDetails(t, getBook(acceptLanguage))
+		require.Nil(t, localized, "Accept-Language %q", acceptLanguage)
+	}
+}
+
+func TestErrorReasons(t *testing.T) {
+	_, malformed := query.ParseOrderBy("title,title")
+	order, err := query.ParseOrderBy("nickname")
+	require.NoError(t, err)
+	_, unsupported := query.Comparer[*testpb.Book](order)
+
+	tests := []struct {
+		err    error
+		reason string
+	}{
+		{malformed, aip.ReasonOrderByInvalid},
+		{unsupported, aip.ReasonOrderByUnsupportedField},
+		{query.ErrInvalidPageToken, aip.ReasonPageTokenInvalid},
+		{fmt.Errorf("%w: %w", query.ErrInvalidPageToken, query.ErrPageTokenExpired), aip.ReasonPageTokenExpired},
+		{fmt.Errorf("%w: %w", query.ErrInvalidPageToken, query.ErrStaleCursorSchema), aip.ReasonPageTokenStale},
+	}
+	for _, tc := range tests {
+		client := newClient(t, &fakeBookService{err: tc.err})
+		_, err := client.GetBook(context.Background(), connect.NewRequest(&testpb.GetBookRequest{Name: "books/1"}))
+		info, _ := errorDetails(t, err)
+		require.Equal(t, tc.reason, info.GetReason(), "error: %v", tc.err)
+	}
+}
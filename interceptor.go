@@ -0,0 +1,546 @@
+// Package aip bundles the utilities in this module into a single connect
+// interceptor, so services can adopt the whole AIP request handling stack at
+// once. See the masks and query packages for the individual pieces.
+package aip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"connectrpc.com/connect"
+	"golang.org/x/text/language"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/hxtk/aip/masks"
+	"github.com/hxtk/aip/query"
+)
+
+// DefaultReadMaskHeader is the request header carrying the AIP-157 read mask
+// unless WithReadMaskHeader is used.
+const DefaultReadMaskHeader = "X-Goog-FieldMask"
+
+// Option configures NewServerInterceptor.
+type Option func(*options)
+
+type options struct {
+	readMaskHeader    string
+	readMaskFallbacks []string
+	readMaskQuery     string
+	readMaskResolver  masks.MethodResolver
+	readMaskPruning   []masks.PruneOption
+	readMaskExclude   masks.MethodMatcher
+	errorMappers      []func(error) error
+	fieldValidators   []FieldValidator
+	policy            *Policy
+
+	nextPageTokenHeader string
+	totalSizeHeader     string
+	warningHeaders      bool
+	warn                func(context.Context, connect.Spec, Warning)
+	redactValues        bool
+	catalog             MessageCatalog
+	locales             []language.Tag
+}
+
+// WithReadMaskHeader sets the request header carrying the read mask, and
+// fallbacks consulted in order when it is absent, e.g. "X-Fields". An empty
+// header disables read mask pruning.
+func WithReadMaskHeader(header string, fallbacks ...string) Option {
+	return func(o *options) {
+		o.readMaskHeader = header
+		o.readMaskFallbacks = fallbacks
+	}
+}
+
+// WithReadMaskQueryParameter sets a query parameter carrying the read mask
+// of unary GET requests that send no read mask header.
+func WithReadMaskQueryParameter(name string) Option {
+	return func(o *options) {
+		o.readMaskQuery = name
+	}
+}
+
+// WithReadMaskResolver sets the resolver used to validate read masks sent to
+// handlers registered without a schema, such as those of a proxy. See
+// masks.NewReflectionResolver.
+func WithReadMaskResolver(r masks.MethodResolver) Option {
+	return func(o *options) {
+		o.readMaskResolver = r
+	}
+}
+
+// WithoutReadMask disables read mask pruning for the methods m matches, e.g.
+// masks.Procedures of streaming uploads or internal admin RPCs.
+func WithoutReadMask(m masks.MethodMatcher) Option {
+	return func(o *options) {
+		o.readMaskExclude = m
+	}
+}
+
+// WithReadMaskPruneOptions sets the options with which responses are pruned
+// to the read mask, e.g. masks.WithUnknownFields.
+func WithReadMaskPruneOptions(opts ...masks.PruneOption) Option {
+	return func(o *options) {
+		o.readMaskPruning = opts
+	}
+}
+
+// WithPaginationHeaders mirrors the next_page_token and total_size fields of
+// unary List responses (AIP-158) into the given response headers, so that
+// browser clients and proxies can read pagination state without parsing the
+// body. An empty header name disables mirroring of that field. Fields that
+// are unset or zero are not mirrored.
+//
+// Browser clients can only read the headers if they are listed in the
+// Access-Control-Expose-Headers of CORS responses.
+func WithPaginationHeaders(nextPageToken, totalSize string) Option {
+	return func(o *options) {
+		o.nextPageTokenHeader = nextPageToken
+		o.totalSizeHeader = totalSize
+	}
+}
+
+// WithFieldValidator adds a validator for the fields of request messages,
+// e.g. ValidateLanguageCode. Each value a validator rejects is reported as a
+// field violation.
+func WithFieldValidator(v FieldValidator) Option {
+	return func(o *options) {
+		o.fieldValidators = append(o.fieldValidators, v)
+	}
+}
+
+// WithPolicy sets a declarative request sanitization policy, which is
+// applied to requests before the other validations. See Policy.
+func WithPolicy(p *Policy) Option {
+	return func(o *options) {
+		o.policy = p
+	}
+}
+
+// WithErrorMapper adds a function mapping handler errors to connect errors,
+// e.g. for domain-specific errors. Mappers run in the order added, before the
+// default mapping, and should return errors they don't recognize unchanged.
+func WithErrorMapper(f func(error) error) Option {
+	return func(o *options) {
+		o.errorMappers = append(o.errorMappers, f)
+	}
+}
+
+// NewServerInterceptor returns a connect interceptor for handlers which, in
+// order:
+//
+//  1. maps handler errors to connect errors (see below);
+//  2. applies the request policy set with WithPolicy, if any;
+//  3. rejects requests missing fields annotated with the REQUIRED
+//     google.api.field_behavior (AIP-203);
+//  4. validates the paths of an update_mask against the resource being
+//     updated (AIP-134, AIP-161);
+//  5. validates the page_size, filter and order_by fields of List requests
+//     (AIP-132, AIP-158, AIP-160), and the fields checked by validators added
+//     with WithFieldValidator;
+//  6. applies the read mask from the request header, or query parameter set
+//     with WithReadMaskQueryParameter, to responses (AIP-157);
+//  7. if enabled with WithPaginationHeaders, mirrors pagination fields of
+//     responses into response headers;
+//  8. if enabled with WithWarningHeaders, sends the warnings handlers add
+//     with AddWarning in response headers.
+//
+// Invalid requests fail with CodeInvalidArgument, a google.rpc.BadRequest
+// detail listing every field violation, and a google.rpc.ErrorInfo detail
+// with the reason of the first (see Violation). Handler errors are mapped
+// as follows: *query.FieldViolationError and query.ErrInvalidPageToken
+// become CodeInvalidArgument with a BadRequest detail,
+// query.ErrBudgetExhausted becomes CodeResourceExhausted, and context
+// cancellation and deadline errors become CodeCanceled and
+// CodeDeadlineExceeded. Connect errors are returned unchanged.
+//
+// For streaming handlers, every received message is validated.
+func NewServerInterceptor(opts ...Option) connect.Interceptor {
+	o := &options{readMaskHeader: DefaultReadMaskHeader}
+	for _, opt := range opts {
+		opt(o)
+	}
+	i := &serverInterceptor{opts: o}
+	if o.readMaskHeader != "" {
+		maskOpts := []masks.InterceptorOption{
+			masks.WithMethodResolver(o.readMaskResolver),
+			masks.WithPruneOptions(o.readMaskPruning...),
+			masks.WithFallbackHeaders(o.readMaskFallbacks...),
+			masks.WithQueryParameter(o.readMaskQuery),
+			masks.WithoutMethods(o.readMaskExclude),
+		}
+		if o.warn != nil {
+			maskOpts = append(maskOpts, masks.WithWarningHandler(func(ctx context.Context, spec connect.Spec, w masks.Warning) {
+				o.warn(ctx, spec, readMaskWarning(w))
+			}))
+		}
+		i.readMask = masks.WithReadMaskInterceptor(o.readMaskHeader, maskOpts...)
+	}
+	return i
+}
+
+type serverInterceptor struct {
+	opts     *options
+	readMask connect.Interceptor
+}
+
+var _ connect.Interceptor = (*serverInterceptor)(nil)
+
+// WrapUnary implements connect.Interceptor.
+func (i *serverInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	if i.opts.nextPageTokenHeader != "" || i.opts.totalSizeHeader != "" {
+		next = i.wrapPagination(next)
+	}
+	if i.readMask != nil {
+		next = i.readMask.WrapUnary(next)
+	}
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if msg, ok := req.Any().(proto.Message); ok {
+			if err := i.opts.validateRequest(ctx, req.Spec(), req.Header(), msg); err != nil {
+				return nil, err
+			}
+		}
+		var w *warnings
+		if i.opts.warningHeaders {
+			w = &warnings{}
+			ctx = context.WithValue(ctx, warningsCtxKey{}, w)
+		}
+		rsp, err := next(ctx, req)
+		if err != nil {
+			msg, _ := req.Any().(proto.Message)
+			err = i.mapError(err, req.Spec(), req.Header(), msg)
+			var connectErr *connect.Error
+			if w != nil && errors.As(err, &connectErr) {
+				w.setHeaders(connectErr.Meta())
+			}
+			return nil, err
+		}
+		if w != nil {
+			w.setHeaders(rsp.Header())
+		}
+		return rsp, nil
+	}
+}
+
+// wrapPagination wraps next to set the configured pagination headers. It
+// runs inside the read mask, so fields the mask prunes are still mirrored.
+func (i *serverInterceptor) wrapPagination(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		rsp, err := next(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if msg, ok := rsp.Any().(proto.Message); ok {
+			i.setPaginationHeaders(rsp.Header(), msg.ProtoReflect())
+		}
+		return rsp, nil
+	}
+}
+
+// setPaginationHeaders sets the configured pagination headers from the
+// fields of the response m.
+func (i *serverInterceptor) setPaginationHeaders(header http.Header, m protoreflect.Message) {
+	if i.opts.nextPageTokenHeader != "" {
+		if token, ok := stringValue(m, "next_page_token"); ok {
+			header.Set(i.opts.nextPageTokenHeader, token)
+		}
+	}
+	if i.opts.totalSizeHeader != "" {
+		fd := m.Descriptor().Fields().ByName("total_size")
+		if fd != nil && !fd.IsList() && (fd.Kind() == protoreflect.Int32Kind || fd.Kind() == protoreflect.Int64Kind) && m.Has(fd) {
+			header.Set(i.opts.totalSizeHeader, strconv.FormatInt(m.Get(fd).Int(), 10))
+		}
+	}
+}
+
+// WrapStreamingClient implements connect.Interceptor.
+func (i *serverInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler implements connect.Interceptor.
+func (i *serverInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	if i.readMask != nil {
+		next = i.readMask.WrapStreamingHandler(next)
+	}
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		if i.opts.warningHeaders {
+			ctx = context.WithValue(ctx, warningsCtxKey{}, &warnings{header: conn.ResponseHeader()})
+		}
+		vc := &validatingConn{StreamingHandlerConn: conn, ctx: ctx, opts: i.opts}
+		if err := next(ctx, vc); err != nil {
+			return i.mapError(err, conn.Spec(), conn.RequestHeader(), vc.last)
+		}
+		return nil
+	}
+}
+
+// validatingConn validates each message received on a stream.
+type validatingConn struct {
+	connect.StreamingHandlerConn
+	ctx  context.Context
+	opts *options
+	// last is the last message received, whose values are redacted from
+	// the violations of the handler error.
+	last proto.Message
+}
+
+func (c *validatingConn) Receive(msg any) error {
+	if err := c.StreamingHandlerConn.Receive(msg); err != nil {
+		return err
+	}
+	if pm, ok := msg.(proto.Message); ok {
+		c.last = pm
+		return c.opts.validateRequest(c.ctx, c.Spec(), c.RequestHeader(), pm)
+	}
+	return nil
+}
+
+// mapError maps a handler error to a connect error. msg is the request the
+// handler failed on, if known.
+func (i *serverInterceptor) mapError(err error, spec connect.Spec, header http.Header, msg proto.Message) error {
+	for _, f := range i.opts.errorMappers {
+		err = f(err)
+	}
+
+	var connectErr *connect.Error
+	var fv *query.FieldViolationError
+	switch {
+	case errors.As(err, &connectErr):
+		return err
+	case errors.As(err, &fv):
+		violations := fieldViolations(fv)
+		i.opts.redact(msg, violations)
+		return i.opts.invalidArgument(spec, header, violations)
+	case errors.Is(err, query.ErrInvalidPageToken):
+		reason := ReasonPageTokenInvalid
+		switch {
+		case errors.Is(err, query.ErrPageTokenExpired):
+			reason = ReasonPageTokenExpired
+		case errors.Is(err, query.ErrStaleCursorSchema):
+			reason = ReasonPageTokenStale
+		}
+		return i.opts.invalidArgument(spec, header, []Violation{{
+			Field:       "page_token",
+			Reason:      reason,
+			Description: err.Error(),
+		}})
+	case errors.Is(err, query.ErrBudgetExhausted):
+		return connect.NewError(connect.CodeResourceExhausted, err)
+	case errors.Is(err, context.Canceled):
+		return connect.NewError(connect.CodeCanceled, err)
+	case errors.Is(err, context.DeadlineExceeded):
+		return connect.NewError(connect.CodeDeadlineExceeded, err)
+	}
+	return err
+}
+
+// validateRequest applies the policy of the procedure of spec to msg,
+// reporting its warnings to the warning handler, and returns a
+// CodeInvalidArgument error if msg is invalid.
+func (o *options) validateRequest(ctx context.Context, spec connect.Spec, header http.Header, msg proto.Message) error {
+	m := msg.ProtoReflect()
+	policy := o.policy.method(spec.Procedure)
+	warnings := policy.sanitize(m)
+	if o.warn != nil {
+		for _, w := range warnings {
+			o.warn(ctx, spec, w)
+		}
+	}
+	var violations []Violation
+	resource, partial := updateTarget(m)
+	violations = appendRequiredViolations(violations, m, "", resource, partial)
+	violations = appendUpdateMaskViolations(violations, m, resource)
+	violations = appendListViolations(violations, m)
+	violations = policy.appendViolations(violations, m, resource)
+	if len(o.fieldValidators) > 0 {
+		violations = appendFieldViolations(violations, m, "", o.fieldValidators)
+	}
+	if len(violations) > 0 {
+		o.redact(msg, violations)
+		return o.invalidArgument(spec, header, violations)
+	}
+	return nil
+}
+
+const fieldMaskName protoreflect.FullName = "google.protobuf.FieldMask"
+
+// updateTarget returns the resource field of an AIP-134 Update request,
+// i.e. the first singular message field other than update_mask, or nil if m
+// has no update_mask. partial reports whether the mask is set to anything
+// other than "*".
+func updateTarget(m protoreflect.Message) (resource protoreflect.FieldDescriptor, partial bool) {
+	fields := m.Descriptor().Fields()
+	mask := fields.ByName("update_mask")
+	if mask == nil || mask.Message() == nil || mask.Message().FullName() != fieldMaskName {
+		return nil, false
+	}
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd != mask && fd.Message() != nil && !fd.IsList() && !fd.IsMap() {
+			resource = fd
+			break
+		}
+	}
+	paths := maskPaths(m, mask)
+	return resource, len(paths) > 0 && !(len(paths) == 1 && paths[0] == "*")
+}
+
+// maskPaths returns the paths of the FieldMask in field fd of m.
+func maskPaths(m protoreflect.Message, fd protoreflect.FieldDescriptor) []string {
+	if !m.Has(fd) {
+		return nil
+	}
+	mask := m.Get(fd).Message()
+	list := mask.Get(mask.Descriptor().Fields().ByName("paths")).List()
+	paths := make([]string, list.Len())
+	for i := range paths {
+		paths[i] = list.Get(i).String()
+	}
+	return paths
+}
+
+// appendRequiredViolations appends a violation for each REQUIRED field of m
+// that is not set, recursing into set message fields. Fields beneath the
+// resource of a partial update are not checked, since only the masked fields
+// are being written.
+func appendRequiredViolations(violations []Violation, m protoreflect.Message, prefix string, resource protoreflect.FieldDescriptor, partial bool) []Violation {
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		path := prefix + string(fd.Name())
+		if !m.Has(fd) {
+			if isRequired(fd) {
+				violations = append(violations, Violation{
+					Field:       path,
+					Reason:      ReasonFieldRequired,
+					Description: "required field is not set",
+				})
+			}
+			continue
+		}
+		if fd.Message() == nil || fd.IsList() || fd.IsMap() || (fd == resource && partial) {
+			continue
+		}
+		violations = appendRequiredViolations(violations, m.Get(fd).Message(), path+".", nil, false)
+	}
+	return violations
+}
+
+func isRequired(fd protoreflect.FieldDescriptor) bool {
+	return hasBehavior(fd, annotations.FieldBehavior_REQUIRED)
+}
+
+// appendFieldViolations appends a violation for each value of a set field of
+// m rejected by one of validators, recursing into messages. Elements of
+// repeated fields are named by index and map values by key, as in
+// "books[0].language_code" and "labels[env]".
+func appendFieldViolations(violations []Violation, m protoreflect.Message, prefix string, validators []FieldValidator) []Violation {
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !m.Has(fd) {
+			continue
+		}
+		path := prefix + string(fd.Name())
+		switch {
+		case fd.IsList():
+			list := m.Get(fd).List()
+			for j := 0; j < list.Len(); j++ {
+				violations = appendValueViolations(violations, fd, list.Get(j), fmt.Sprintf("%s[%d]", path, j), validators)
+			}
+		case fd.IsMap():
+			for k, v := range m.Get(fd).Map().Range {
+				violations = appendValueViolations(violations, fd.MapValue(), v, fmt.Sprintf("%s[%s]", path, k.String()), validators)
+			}
+		default:
+			violations = appendValueViolations(violations, fd, m.Get(fd), path, validators)
+		}
+	}
+	return violations
+}
+
+// appendValueViolations appends a violation for each of validators
+// rejecting the value v of field fd, or recurses into v if it is a message.
+func appendValueViolations(violations []Violation, fd protoreflect.FieldDescriptor, v protoreflect.Value, path string, validators []FieldValidator) []Violation {
+	if fd.Message() != nil {
+		return appendFieldViolations(violations, v.Message(), path+".", validators)
+	}
+	for _, validate := range validators {
+		if err := validate(fd, v); err != nil {
+			violations = append(violations, Violation{
+				Field:       path,
+				Reason:      ReasonFieldInvalid,
+				Description: err.Error(),
+			})
+		}
+	}
+	return violations
+}
+
+// appendUpdateMaskViolations appends a violation if the update_mask of m
+// names fields that do not exist on resource.
+func appendUpdateMaskViolations(violations []Violation, m protoreflect.Message, resource protoreflect.FieldDescriptor) []Violation {
+	if resource == nil {
+		return violations
+	}
+	paths := maskPaths(m, m.Descriptor().Fields().ByName("update_mask"))
+	if len(paths) == 0 || (len(paths) == 1 && paths[0] == "*") {
+		return violations
+	}
+	if _, err := masks.New(resource.Message(), masks.ModeWrite, paths...); err != nil {
+		violations = append(violations, Violation{
+			Field:       "update_mask",
+			Reason:      ReasonFieldMaskInvalid,
+			Description: err.Error(),
+		})
+	}
+	return violations
+}
+
+// appendListViolations appends violations for the standard AIP-132 List
+// request fields of m that are present and invalid.
+func appendListViolations(violations []Violation, m protoreflect.Message) []Violation {
+	fields := m.Descriptor().Fields()
+	if fd := fields.ByName("page_size"); fd != nil && fd.Kind() == protoreflect.Int32Kind && m.Get(fd).Int() < 0 {
+		violations = append(violations, Violation{
+			Field:       "page_size",
+			Reason:      ReasonPageSizeInvalid,
+			Description: "must not be negative",
+		})
+	}
+	if text, ok := stringValue(m, "filter"); ok {
+		if _, err := query.ParseFilter(text); err != nil {
+			violations = append(violations, Violation{
+				Field:       "filter",
+				Reason:      ReasonFilterInvalid,
+				Description: fmt.Sprintf("invalid filter: %v", err),
+			})
+		}
+	}
+	if text, ok := stringValue(m, query.OrderByField); ok {
+		if _, err := query.ParseOrderBy(text); err != nil {
+			var fv *query.FieldViolationError
+			if errors.As(err, &fv) {
+				violations = append(violations, fieldViolations(fv)...)
+			}
+		}
+	}
+	return violations
+}
+
+// stringValue returns the value of the non-empty string field of m with the
+// given name.
+func stringValue(m protoreflect.Message, name protoreflect.Name) (string, bool) {
+	fd := m.Descriptor().Fields().ByName(name)
+	if fd == nil || fd.Kind() != protoreflect.StringKind || fd.IsList() {
+		return "", false
+	}
+	s := m.Get(fd).String()
+	return s, s != ""
+}
@@ -0,0 +1,265 @@
+package aip_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/hxtk/aip"
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/aiptest/testpb/testpbconnect"
+	"github.com/hxtk/aip/masks"
+	"github.com/hxtk/aip/query"
+)
+
+type fakeBookService struct {
+	testpbconnect.UnimplementedBookServiceHandler
+
+	err error
+}
+
+func (s *fakeBookService) GetBook(ctx context.Context, req *connect.Request[testpb.GetBookRequest]) (*connect.Response[testpb.Book], error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return connect.NewResponse(&testpb.Book{
+		Name:  req.Msg.GetName(),
+		Title: "The Pragmatic Programmer",
+	}), nil
+}
+
+func (s *fakeBookService) UpdateBook(ctx context.Context, req *connect.Request[testpb.UpdateBookRequest]) (*connect.Response[testpb.Book], error) {
+	return connect.NewResponse(req.Msg.GetBook()), nil
+}
+
+func (s *fakeBookService) ListBooks(ctx context.Context, req *connect.Request[testpb.ListBooksRequest], stream *connect.ServerStream[testpb.Book]) error {
+	return stream.Send(&testpb.Book{Name: "books/1", Title: "The Pragmatic Programmer"})
+}
+
+func (s *fakeBookService) ListBooksPage(ctx context.Context, req *connect.Request[testpb.ListBooksRequest]) (*connect.Response[testpb.ListBooksResponse], error) {
+	if req.Msg.GetPageToken() != "" {
+		return connect.NewResponse(&testpb.ListBooksResponse{
+			Books: []*testpb.Book{{Name: "books/2"}},
+		}), nil
+	}
+	return connect.NewResponse(&testpb.ListBooksResponse{
+		Books:         []*testpb.Book{{Name: "books/1"}},
+		NextPageToken: "page-2",
+		TotalSize:     2,
+	}), nil
+}
+
+func newClient(t *testing.T, svc testpbconnect.BookServiceHandler, opts ...aip.Option) testpbconnect.BookServiceClient {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.Handle(testpbconnect.NewBookServiceHandler(svc, connect.WithInterceptors(aip.NewServerInterceptor(opts...))))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return testpbconnect.NewBookServiceClient(server.Client(), server.URL)
+}
+
+// requireViolations asserts err is an InvalidArgument error with a BadRequest
+// detail naming exactly the given fields.
+func requireViolations(t *testing.T, err error, fields ...string) {
+	t.Helper()
+	require.Equal(t, connect.CodeInvalidArgument, connect.CodeOf(err), "error: %v", err)
+	var connectErr *connect.Error
+	require.ErrorAs(t, err, &connectErr)
+	var got []string
+	for _, d := range connectErr.Details() {
+		msg, err := d.Value()
+		require.NoError(t, err)
+		if br, ok := msg.(*errdetails.BadRequest); ok {
+			for _, v := range br.GetFieldViolations() {
+				got = append(got, v.GetField())
+			}
+		}
+	}
+	require.Equal(t, fields, got)
+}
+
+func TestRequiredFields(t *testing.T) {
+	client := newClient(t, &fakeBookService{})
+
+	_, err := client.GetBook(context.Background(), connect.NewRequest(&testpb.GetBookRequest{}))
+	requireViolations(t, err, "name")
+
+	_, err = client.UpdateBook(context.Background(), connect.NewRequest(&testpb.UpdateBookRequest{}))
+	requireViolations(t, err, "book")
+
+	rsp, err := client.GetBook(context.Background(), connect.NewRequest(&testpb.GetBookRequest{Name: "books/1"}))
+	require.NoError(t, err)
+	require.Equal(t, "books/1", rsp.Msg.GetName())
+}
+
+func TestUpdateMask(t *testing.T) {
+	client := newClient(t, &fakeBookService{})
+	book := &testpb.Book{Title: "Dune"}
+
+	for _, paths := range [][]string{{"title"}, {"*"}, {"author.given_name", "title"}} {
+		_, err := client.UpdateBook(context.Background(), connect.NewRequest(&testpb.UpdateBookRequest{
+			Book:       book,
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: paths},
+		}))
+		require.NoError(t, err, "paths %v", paths)
+	}
+
+	_, err := client.UpdateBook(context.Background(), connect.NewRequest(&testpb.UpdateBookRequest{
+		Book:       book,
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"subtitle"}},
+	}))
+	requireViolations(t, err, "update_mask")
+}
+
+func TestListRequestValidation(t *testing.T) {
+	client := newClient(t, &fakeBookService{})
+
+	list := func(req *testpb.ListBooksRequest) error {
+		stream, err := client.ListBooks(context.Background(), connect.NewRequest(req))
+		require.NoError(t, err)
+		defer stream.Close()
+		for stream.Receive() {
+		}
+		return stream.Err()
+	}
+
+	require.NoError(t, list(&testpb.ListBooksRequest{PageSize: 10, Filter: `title = "Dune"`, OrderBy: "title desc"}))
+	requireViolations(t, list(&testpb.ListBooksRequest{PageSize: -1}), "page_size")
+	requireViolations(t, list(&testpb.ListBooksRequest{Filter: `title = (`, OrderBy: "title,title"}), "filter", "order_by")
+}
+
+func TestFieldValidators(t *testing.T) {
+	client := newClient(t, &fakeBookService{},
+		aip.WithFieldValidator(aip.ValidateLanguageCode),
+		aip.WithFieldValidator(aip.ValidateRegionCode),
+		aip.WithFieldValidator(aip.ValidateCurrencyCode),
+	)
+	update := func(book *testpb.Book) error {
+		_, err := client.UpdateBook(context.Background(), connect.NewRequest(&testpb.UpdateBookRequest{Book: book}))
+		return err
+	}
+
+	require.NoError(t, update(&testpb.Book{LanguageCode: "en-GB", PublicationRegionCode: "GB", PriceCurrencyCode: "GBP"}))
+	requireViolations(t, update(&testpb.Book{LanguageCode: "en_GB", PublicationRegionCode: "UK1", PriceCurrencyCode: "GBP"}),
+		"book.language_code", "book.publication_region_code")
+	requireViolations(t, update(&testpb.Book{PriceCurrencyCode: "gbp"}), "book.price_currency_code")
+}
+
+func TestReadMask(t *testing.T) {
+	client := newClient(t, &fakeBookService{}, aip.WithReadMaskHeader("X-Read-Mask"))
+
+	req := connect.NewRequest(&testpb.GetBookRequest{Name: "books/1"})
+	req.Header().Set("X-Read-Mask", "title")
+	rsp, err := client.GetBook(context.Background(), req)
+	require.NoError(t, err)
+	require.Empty(t, rsp.Msg.GetName())
+	require.Equal(t, "The Pragmatic Programmer", rsp.Msg.GetTitle())
+
+	req = connect.NewRequest(&testpb.GetBookRequest{Name: "books/1"})
+	req.Header().Set("X-Read-Mask", "title.subtitle")
+	_, err = client.GetBook(context.Background(), req)
+	require.Equal(t, connect.CodeInvalidArgument, connect.CodeOf(err), "error: %v", err)
+
+	client = newClient(t, &fakeBookService{}, aip.WithReadMaskHeader("X-Read-Mask", "X-Fields"))
+	req = connect.NewRequest(&testpb.GetBookRequest{Name: "books/1"})
+	req.Header().Set("X-Fields", "name")
+	rsp, err = client.GetBook(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, "books/1", rsp.Msg.GetName())
+	require.Empty(t, rsp.Msg.GetTitle(), "pruned by the fallback header")
+
+	client = newClient(t, &fakeBookService{},
+		aip.WithReadMaskHeader("X-Read-Mask"),
+		aip.WithoutReadMask(masks.Procedures(testpbconnect.BookServiceGetBookProcedure)),
+	)
+	req = connect.NewRequest(&testpb.GetBookRequest{Name: "books/1"})
+	req.Header().Set("X-Read-Mask", "title")
+	rsp, err = client.GetBook(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, "books/1", rsp.Msg.GetName(), "read mask disabled for the method")
+}
+
+func TestPaginationHeaders(t *testing.T) {
+	client := newClient(t, &fakeBookService{},
+		aip.WithReadMaskHeader("X-Read-Mask"),
+		aip.WithPaginationHeaders("X-Next-Page-Token", "X-Total-Size"),
+	)
+
+	req := connect.NewRequest(&testpb.ListBooksRequest{})
+	req.Header().Set("X-Read-Mask", "books.name")
+	rsp, err := client.ListBooksPage(context.Background(), req)
+	require.NoError(t, err)
+	require.Empty(t, rsp.Msg.GetNextPageToken(), "pruned by the read mask")
+	require.Equal(t, "page-2", rsp.Header().Get("X-Next-Page-Token"))
+	require.Equal(t, "2", rsp.Header().Get("X-Total-Size"))
+
+	rsp, err = client.ListBooksPage(context.Background(), connect.NewRequest(&testpb.ListBooksRequest{PageToken: "page-2"}))
+	require.NoError(t, err)
+	require.NotContains(t, rsp.Header(), "X-Next-Page-Token", "the last page has no next page")
+	require.NotContains(t, rsp.Header(), "X-Total-Size")
+
+	client = newClient(t, &fakeBookService{})
+	rsp, err = client.ListBooksPage(context.Background(), connect.NewRequest(&testpb.ListBooksRequest{}))
+	require.NoError(t, err)
+	require.Empty(t, rsp.Header().Get("X-Next-Page-Token"), "headers are disabled by default")
+}
+
+func TestErrorMapping(t *testing.T) {
+	order, err := query.ParseOrderBy("title,title")
+	require.Nil(t, order)
+	require.Error(t, err)
+
+	errNotFound := errors.New("not found")
+	tests := []struct {
+		name string
+		err  error
+		code connect.Code
+	}{
+		{"field violation", err, connect.CodeInvalidArgument},
+		{"page token", query.ErrInvalidPageToken, connect.CodeInvalidArgument},
+		{"budget", fmt.Errorf("listing books: %w", query.ErrBudgetExhausted), connect.CodeResourceExhausted},
+		{"deadline", context.DeadlineExceeded, connect.CodeDeadlineExceeded},
+		{"connect error", connect.NewError(connect.CodeAborted, errors.New("aborted")), connect.CodeAborted},
+		{"custom mapping", errNotFound, connect.CodeNotFound},
+		{"unknown", errors.New("boom"), connect.CodeUnknown},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newClient(t, &fakeBookService{err: tc.err}, aip.WithErrorMapper(func(err error) error {
+				if errors.Is(err, errNotFound) {
+					return connect.NewError(connect.CodeNotFound, err)
+				}
+				return err
+			}))
+			_, err := client.GetBook(context.Background(), connect.NewRequest(&testpb.GetBookRequest{Name: "books/1"}))
+			require.Equal(t, tc.code, connect.CodeOf(err), "error: %v", err)
+		})
+	}
+}
+
+func TestRedactedValues(t *testing.T) {
+	update := func(client testpbconnect.BookServiceClient) *connect.Error {
+		_, err := client.UpdateBook(context.Background(), connect.NewRequest(&testpb.UpdateBookRequest{
+			Book:       &testpb.Book{Title: "Dune"},
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"detailed_reviews.alice.nope"}},
+		}))
+		requireViolations(t, err, "update_mask")
+		var connectErr *connect.Error
+		require.ErrorAs(t, err, &connectErr)
+		return connectErr
+	}
+
+	err := update(newClient(t, &fakeBookService{}))
+	require.Contains(t, err.Message(), "alice")
+
+	err = update(newClient(t, &fakeBookService{}, aip.WithRedactedValues()))
+	require.Equal(t, `update_mask: invalid field mask path "detailed_reviews.<redacted>.nope": field "nope" does not exist`, err.Message())
+}
@@ -1,4 +0,0 @@
-package internal
-
-//go:generate go tool bufisk generate
-
@@ -0,0 +1,194 @@
+package masks
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ApplyUpdateMask applies an AIP-134 update to dst, the stored resource:
+// the fields of src, the resource sent in the Update request, that paths
+// name are copied to dst, and those unset in src are cleared in dst. dst and
+// src must be of the same message type.
+//
+//   - No paths is an implied mask of the fields set in src, so only those
+//     are copied.
+//   - The single path "*" replaces dst with src.
+//   - Other paths must be valid under ModeWrite. A path may name a key of a
+//     map field, e.g. "labels.env", to set or delete only that entry.
+//     Repeated fields are replaced whole.
+//
+// Fields annotated OUTPUT_ONLY are never changed, as AIP-203 requires
+// servers to ignore them in requests. Values are copied, so src may be
+// modified afterwards without affecting dst.
+func ApplyUpdateMask(dst, src proto.Message, paths ...string) error {
+	d, s := dst.ProtoReflect(), src.ProtoReflect()
+	desc := d.Descriptor()
+	if s.Descriptor().FullName() != desc.FullName() {
+		return fmt.Errorf("cannot update %s from %s", desc.FullName(), s.Descriptor().FullName())
+	}
+
+	switch {
+	case len(paths) == 0:
+		fields := desc.Fields()
+		for i := 0; i < fields.Len(); i++ {
+			if fd := fields.Get(i); s.Has(fd) {
+				copyField(d, s, fd)
+			}
+		}
+		return nil
+	case len(paths) == 1 && paths[0] == "*":
+		fields := desc.Fields()
+		for i := 0; i < fields.Len(); i++ {
+			copyField(d, s, fields.Get(i))
+		}
+		return nil
+	}
+
+	mask, err := New(desc, ModeWrite, paths...)
+	if err != nil {
+		return err
+	}
+	return applyTrie(d, s, mask.trie)
+}
+
+// applyTrie applies the update of the paths in trie from src to dst.
+func applyTrie(dst, src protoreflect.Message, trie *maskTrie) error {
+	for seg, child := range trie.children {
+		fd := fieldBySegment(dst.Descriptor(), seg)
+		switch {
+		case len(child.children) == 0:
+			copyField(dst, src, fd)
+		case fd.IsMap():
+			if err := applyMapTrie(dst, src, fd, child); err != nil {
+				return err
+			}
+		case fd.IsList():
+			return fmt.Errorf("cannot update subfields of repeated field %q, which is replaced whole", fd.Name())
+		case isOutputOnly(fd) || (!src.Has(fd) && !dst.Has(fd)):
+		default:
+			if err := applyTrie(dst.Mutable(fd).Message(), src.Get(fd).Message(), child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// applyMapTrie applies the update of the entries of the map field fd that
+// trie selects by key.
+func applyMapTrie(dst, src protoreflect.Message, fd protoreflect.FieldDescriptor, trie *maskTrie) error {
+	if isOutputOnly(fd) {
+		return nil
+	}
+	if _, ok := trie.children["*"]; ok {
+		copyField(dst, src, fd)
+		return nil
+	}
+	srcMap := src.Get(fd).Map()
+	for seg, child := range trie.children {
+		key, err := mapKey(fd.MapKey(), seg)
+		if err != nil {
+			return err
+		}
+		if !srcMap.Has(key) && (!dst.Has(fd) || !dst.Get(fd).Map().Has(key)) {
+			continue
+		}
+		dstMap := dst.Mutable(fd).Map()
+		switch {
+		case !srcMap.Has(key) && len(child.children) == 0:
+			dstMap.Clear(key)
+		case len(child.children) == 0:
+			dstMap.Set(key, copyValue(fd.MapValue(), srcMap.Get(key)))
+		default:
+			value := dstMap.NewValue().Message()
+			if srcMap.Has(key) {
+				value = srcMap.Get(key).Message()
+			}
+			if err := applyTrie(dstMap.Mutable(key).Message(), value, child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mapKey parses seg, a path segment naming a key of a map whose keys are
+// described by fd.
+func mapKey(fd protoreflect.FieldDescriptor, seg string) (protoreflect.MapKey, error) {
+	if unquoted, ok := strings.CutPrefix(seg, "`"); ok {
+		seg = strings.TrimSuffix(unquoted, "`")
+	}
+	var v protoreflect.Value
+	switch kind := fd.Kind(); kind {
+	case protoreflect.StringKind:
+		v = protoreflect.ValueOfString(seg)
+	case protoreflect.BoolKind:
+		v = protoreflect.ValueOfBool(seg == "true")
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, err := strconv.ParseInt(seg, 10, 32)
+		if err != nil {
+			return protoreflect.MapKey{}, fmt.Errorf("invalid map key %q: %w", seg, err)
+		}
+		v = protoreflect.ValueOfInt32(int32(n))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := strconv.ParseInt(seg, 10, 64)
+		if err != nil {
+			return protoreflect.MapKey{}, fmt.Errorf("invalid map key %q: %w", seg, err)
+		}
+		v = protoreflect.ValueOfInt64(n)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		n, err := strconv.ParseUint(seg, 10, 32)
+		if err != nil {
+			return protoreflect.MapKey{}, fmt.Errorf("invalid map key %q: %w", seg, err)
+		}
+		v = protoreflect.ValueOfUint32(uint32(n))
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := strconv.ParseUint(seg, 10, 64)
+		if err != nil {
+			return protoreflect.MapKey{}, fmt.Errorf("invalid map key %q: %w", seg, err)
+		}
+		v = protoreflect.ValueOfUint64(n)
+	default:
+		return protoreflect.MapKey{}, fmt.Errorf("unsupported map key kind %s", kind)
+	}
+	return v.MapKey(), nil
+}
+
+// copyField sets fd in dst to a copy of its value in src, or clears it if it
+// is unset in src. Output only fields are left as they are.
+func copyField(dst, src protoreflect.Message, fd protoreflect.FieldDescriptor) {
+	switch {
+	case isOutputOnly(fd):
+	case !src.Has(fd):
+		dst.Clear(fd)
+	case fd.IsList():
+		from, to := src.Get(fd).List(), dst.NewField(fd).List()
+		for i := 0; i < from.Len(); i++ {
+			to.Append(copyValue(fd, from.Get(i)))
+		}
+		dst.Set(fd, protoreflect.ValueOfList(to))
+	case fd.IsMap():
+		to := dst.NewField(fd).Map()
+		for k, v := range src.Get(fd).Map().Range {
+			to.Set(k, copyValue(fd.MapValue(), v))
+		}
+		dst.Set(fd, protoreflect.ValueOfMap(to))
+	default:
+		dst.Set(fd, copyValue(fd, src.Get(fd)))
+	}
+}
+
+// copyValue returns a copy of v, a singular value of fd.
+func copyValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) protoreflect.Value {
+	switch {
+	case fd.Message() != nil:
+		return protoreflect.ValueOfMessage(proto.Clone(v.Message().Interface()).ProtoReflect())
+	case fd.Kind() == protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes(append([]byte(nil), v.Bytes()...))
+	}
+	return v
+}
@@ -0,0 +1,148 @@
+package masks_test
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/masks"
+)
+
+func storedBook() *testpb.Book {
+	return &testpb.Book{
+		Name:       "books/1",
+		Title:      "Dune",
+		Author:     &testpb.Author{GivenName: "Frank", FamilyName: "Herbert"},
+		Tags:       []string{"classic"},
+		Reviews:    map[string]string{"alice": "Great.", "bob": "Long."},
+		CreateTime: timestamppb.New(time.Date(1965, 8, 1, 0, 0, 0, 0, time.UTC)),
+		DetailedReviews: map[string]*testpb.Review{
+			"alice": {Rating: 5, Text: "Great."},
+		},
+	}
+}
+
+func TestApplyUpdateMask(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   *testpb.Book
+		paths []string
+		want  func(*testpb.Book)
+	}{
+		{
+			name:  "field",
+			src:   &testpb.Book{Title: "Dune Messiah", Tags: []string{"sequel"}},
+			paths: []string{"title"},
+			want:  func(b *testpb.Book) { b.Title = "Dune Messiah" },
+		},
+		{
+			name:  "cleared field",
+			src:   &testpb.Book{},
+			paths: []string{"title", "tags"},
+			want:  func(b *testpb.Book) { b.Title, b.Tags = "", nil },
+		},
+		{
+			name:  "nested field",
+			src:   &testpb.Book{Author: &testpb.Author{GivenName: "F."}},
+			paths: []string{"author.given_name"},
+			want:  func(b *testpb.Book) { b.Author.GivenName = "F." },
+		},
+		{
+			name:  "message",
+			src:   &testpb.Book{Author: &testpb.Author{GivenName: "F."}},
+			paths: []string{"author"},
+			want:  func(b *testpb.Book) { b.Author = &testpb.Author{GivenName: "F."} },
+		},
+		{
+			name:  "repeated field replaced whole",
+			src:   &testpb.Book{Tags: []string{"sequel", "sci-fi"}},
+			paths: []string{"tags"},
+			want:  func(b *testpb.Book) { b.Tags = []string{"sequel", "sci-fi"} },
+		},
+		{
+			name:  "map keys",
+			src:   &testpb.Book{Reviews: map[string]string{"carol": "Sandy."}},
+			paths: []string{"reviews.carol", "reviews.bob"},
+			want: func(b *testpb.Book) {
+				b.Reviews = map[string]string{"alice": "Great.", "carol": "Sandy."}
+			},
+		},
+		{
+			name:  "negative int32 map key",
+			src:   &testpb.Book{Items: map[int32]string{-1: "last"}},
+			paths: []string{"items.`-1`"},
+			want:  func(b *testpb.Book) { b.Items = map[int32]string{-1: "last"} },
+		},
+		{
+			name:  "map value field",
+			src:   &testpb.Book{DetailedReviews: map[string]*testpb.Review{"alice": {Rating: 4}}},
+			paths: []string{"detailed_reviews.alice.rating"},
+			want:  func(b *testpb.Book) { b.DetailedReviews["alice"].Rating = 4 },
+		},
+		{
+			name:  "implied mask",
+			src:   &testpb.Book{Title: "Dune Messiah"},
+			paths: nil,
+			want:  func(b *testpb.Book) { b.Title = "Dune Messiah" },
+		},
+		{
+			name:  "full replacement",
+			src:   &testpb.Book{Name: "books/1", Title: "Dune Messiah"},
+			paths: []string{"*"},
+			want: func(b *testpb.Book) {
+				*b = testpb.Book{Name: "books/1", Title: "Dune Messiah", CreateTime: b.CreateTime}
+			},
+		},
+		{
+			name:  "output only ignored",
+			src:   &testpb.Book{CreateTime: timestamppb.Now()},
+			paths: []string{"create_time"},
+			want:  func(*testpb.Book) {},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := storedBook()
+			if err := masks.ApplyUpdateMask(got, tc.src, tc.paths...); err != nil {
+				t.Fatalf("ApplyUpdateMask failed: %v", err)
+			}
+			want := storedBook()
+			tc.want(want)
+			if !proto.Equal(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestApplyUpdateMask_CopiesValues(t *testing.T) {
+	dst := &testpb.Book{}
+	src := &testpb.Book{Author: &testpb.Author{GivenName: "Jane"}}
+	if err := masks.ApplyUpdateMask(dst, src, "author"); err != nil {
+		t.Fatal(err)
+	}
+	src.Author.GivenName = "Emma"
+	if got := dst.GetAuthor().GetGivenName(); got != "Jane" {
+		t.Errorf("dst shares the author of src: given_name = %q", got)
+	}
+}
+
+func TestApplyUpdateMask_Errors(t *testing.T) {
+	for _, paths := range [][]string{
+		{"title.subtitle"},
+		{"isbn"},
+		{"authors.given_name"},
+		{"items.3000000000"},
+		{"items.`-3000000000`"},
+	} {
+		if err := masks.ApplyUpdateMask(storedBook(), &testpb.Book{}, paths...); err == nil {
+			t.Errorf("ApplyUpdateMask(%q): got nil error", paths)
+		}
+	}
+	if err := masks.ApplyUpdateMask(&testpb.Book{}, &testpb.Author{}, "name"); err == nil {
+		t.Error("ApplyUpdateMask of different types: got nil error")
+	}
+}
@@ -1,13 +1,102 @@
 package masks
 
 import (
+	"errors"
+	"maps"
+	"sync"
+
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
+// UnknownFields is how PruneMessage treats the unknown fields and
+// extensions of the messages it traverses, which a mask cannot name.
+type UnknownFields int
+
+const (
+	// PreserveUnknown keeps unknown fields and extensions, so that proxies
+	// forwarding messages of a newer or extended schema do not drop data
+	// they do not understand.
+	PreserveUnknown UnknownFields = iota
+	// ClearUnknown clears unknown fields and extensions, so that only the
+	// fields selected by the mask remain.
+	ClearUnknown
+)
+
+// PruneOption configures PruneMessage.
+type PruneOption func(*pruneOptions)
+
+type pruneOptions struct {
+	unknown     UnknownFields
+	stats       *PruneStats
+	parallelism int
+}
+
+// WithUnknownFields sets how unknown fields and extensions are treated. The
+// default is PreserveUnknown.
+func WithUnknownFields(u UnknownFields) PruneOption {
+	return func(o *pruneOptions) {
+		o.unknown = u
+	}
+}
+
+// WithParallelism sets the number of goroutines with which PruneAll and
+// PruneSlice prune messages. The default, 1, prunes them in the calling
+// goroutine.
+func WithParallelism(n int) PruneOption {
+	return func(o *pruneOptions) {
+		o.parallelism = n
+	}
+}
+
+// PruneStats reports what PruneMessage removed from messages, e.g. for
+// metrics verifying that read masks and field-level access policies reduce
+// response payloads.
+type PruneStats struct {
+	// Messages is the number of messages pruned.
+	Messages int
+	// FieldsKept is the number of set fields kept in the messages traversed.
+	// Fields of messages selected whole are not counted.
+	FieldsKept int
+	// FieldsCleared is the number of set fields cleared, including
+	// extensions cleared by ClearUnknown.
+	FieldsCleared int
+	// BytesSaved is the reduction in the wire size of the messages pruned.
+	BytesSaved int
+}
+
+// Add adds the counts of other to s.
+func (s *PruneStats) Add(other PruneStats) {
+	s.Messages += other.Messages
+	s.FieldsKept += other.FieldsKept
+	s.FieldsCleared += other.FieldsCleared
+	s.BytesSaved += other.BytesSaved
+}
+
+// WithStats adds the statistics of each message pruned to s, which must not
+// be shared by concurrent calls. Measuring BytesSaved computes the size of
+// the message before and after pruning.
+func WithStats(s *PruneStats) PruneOption {
+	return func(o *pruneOptions) {
+		o.stats = s
+	}
+}
+
 // PruneMessage traverses msg and clears fields that are not present in mask.
 // The mask must be valid under ModeRead for msg’s descriptor.
-func PruneMessage(msg proto.Message, mask *FieldMask) error {
+//
+// A wildcard selects every element of a repeated field or value of a map
+// field. Paths ending in one keep the whole field, so "tags.*" and
+// "authors.*" are equivalent to "tags" and "authors"; paths through one
+// select fields of each element, as "authors.*.given_name" and
+// "authors.given_name" both do.
+//
+// The unknown fields and extensions of msg and the messages the mask
+// descends into are treated as set by WithUnknownFields. Those of messages
+// selected whole, by a path ending at them, are always kept, as are the
+// extensions the mask names by their full name in parentheses, e.g.
+// "(google.api.http).get".
+func PruneMessage(msg proto.Message, mask *FieldMask, opts ...PruneOption) error {
 	if msg == nil {
 		return nil
 	}
@@ -15,101 +104,202 @@ func PruneMessage(msg proto.Message, mask *FieldMask) error {
 		return nil
 	}
 
-	return pruneMessage(msg.ProtoReflect(), mask.trie)
+	return newPruneOptions(opts).prune(msg, mask)
+}
+
+// PruneAll prunes each of msgs to mask, as PruneMessage does. Work is spread
+// over the goroutines set with WithParallelism. The error is the join of
+// those of each goroutine, which stops at the first message it fails to
+// prune.
+func PruneAll(msgs []proto.Message, mask *FieldMask, opts ...PruneOption) error {
+	return PruneSlice(msgs, mask, opts...)
+}
+
+// PruneSlice is PruneAll for a slice of a concrete message type, such as the
+// resources of a List response.
+func PruneSlice[M proto.Message](msgs []M, mask *FieldMask, opts ...PruneOption) error {
+	if mask == nil {
+		return nil
+	}
+	o := newPruneOptions(opts)
+	workers := min(o.parallelism, len(msgs))
+	if workers <= 1 {
+		for _, msg := range msgs {
+			if err := o.prune(msg, mask); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	errs := make([]error, workers)
+	stats := make([]PruneStats, workers)
+	var wg sync.WaitGroup
+	for w := range workers {
+		chunk := msgs[w*len(msgs)/workers : (w+1)*len(msgs)/workers]
+		wo := *o
+		if o.stats != nil {
+			wo.stats = &stats[w]
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, msg := range chunk {
+				if errs[w] = wo.prune(msg, mask); errs[w] != nil {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if o.stats != nil {
+		for _, s := range stats {
+			o.stats.Add(s)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func newPruneOptions(opts []PruneOption) *pruneOptions {
+	o := &pruneOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// prune prunes msg to mask, recording its statistics if requested.
+func (o *pruneOptions) prune(msg proto.Message, mask *FieldMask) error {
+	if msg == nil || !msg.ProtoReflect().IsValid() {
+		return nil
+	}
+	m := msg.ProtoReflect()
+	if o.stats == nil {
+		return o.pruneMessage(m, mask.trie)
+	}
+
+	before := proto.Size(msg)
+	err := o.pruneMessage(m, mask.trie)
+	o.stats.Messages++
+	o.stats.BytesSaved += before - proto.Size(msg)
+	return err
 }
 
 // pruneMessage applies pruning recursively.
-func pruneMessage(m protoreflect.Message, trie *maskTrie) error {
+func (o *pruneOptions) pruneMessage(m protoreflect.Message, trie *maskTrie) error {
+	if o.unknown == ClearUnknown && len(m.GetUnknown()) > 0 {
+		m.SetUnknown(nil)
+	}
+	if err := o.pruneExtensions(m, trie); err != nil {
+		return err
+	}
 	fields := m.Descriptor().Fields()
 	for i := 0; i < fields.Len(); i++ {
 		fd := fields.Get(i)
 		name := string(fd.Name())
 
+		// Wildcards are consumed by the repeated and map fields they
+		// follow, so never select the fields of a message.
 		subTrie := trie.children[name]
-		wildTrie := trie.children["*"]
+
+		if o.stats != nil && m.Has(fd) {
+			if subTrie == nil {
+				o.stats.FieldsCleared++
+			} else {
+				o.stats.FieldsKept++
+			}
+		}
 
 		switch {
 		case subTrie != nil:
 			// Field explicitly present in mask.
-			// Determine which trie should be used for the *element* or value:
-			// if subTrie contains a "*" child, that wildcard is consumed when
-			// descending into elements/values.
-			elementTrie := subTrie
-			if subTrie.children != nil {
-				if star := subTrie.children["*"]; star != nil {
-					elementTrie = star
-				}
+			if err := o.pruneSelected(m, fd, subTrie); err != nil {
+				return err
 			}
+		default:
+			// Not in mask at this level -> clear whole field
+			m.Clear(fd)
+		}
+	}
+	return nil
+}
 
-			if fd.Kind() == protoreflect.MessageKind {
-				// descend into message(s)
-				if fd.IsList() {
-					list := m.Mutable(fd).List()
-					for idx := 0; idx < list.Len(); idx++ {
-						pm := list.Get(idx).Message()
-						if pm.IsValid() {
-							if err := pruneMessage(pm, elementTrie); err != nil {
-								return err
-							}
-						}
-					}
-				} else if fd.IsMap() {
-					mapVal := m.Mutable(fd).Map()
-					for _, val := range mapVal.Range {
-						if fd.MapValue().Kind() == protoreflect.MessageKind {
-							pm := val.Message()
-							if pm.IsValid() {
-								if err := pruneMessage(pm, elementTrie); err != nil {
-									return err
-								}
-							}
-						}
-					}
-				} else {
-					sub := m.Mutable(fd).Message()
-					if sub.IsValid() {
-						if err := pruneMessage(sub, elementTrie); err != nil {
-							return err
-						}
+// pruneSelected prunes the subfields of fd in m, which subTrie selects.
+func (o *pruneOptions) pruneSelected(m protoreflect.Message, fd protoreflect.FieldDescriptor, subTrie *maskTrie) error {
+	if len(subTrie.children) == 0 {
+		// A path ending at fd selects all of its subfields.
+		return nil
+	}
+	elementTrie := subTrie.elementTrie()
+	if len(elementTrie.children) == 0 {
+		// A wildcard ending the path selects whole elements or values.
+		return nil
+	}
+
+	if fd.Kind() == protoreflect.MessageKind && m.Has(fd) {
+		// descend into message(s), without populating unset ones
+		if fd.IsList() {
+			list := m.Mutable(fd).List()
+			for idx := 0; idx < list.Len(); idx++ {
+				pm := list.Get(idx).Message()
+				if pm.IsValid() {
+					if err := o.pruneMessage(pm, elementTrie); err != nil {
+						return err
 					}
 				}
 			}
-			// scalar fields are kept as-is when explicitly listed
-
-		case wildTrie != nil:
-			// Wildcard present at THIS level of the trie:
-			// apply wildcard semantics (for messages we descend into each element/value
-			// using the wildcard's child trie).
-			// The wildcard node itself can have children (e.g. `authors.*.given_name`),
-			// so we should descend using wildTrie (which already corresponds to the '*'
-			// node's children).
-			if fd.IsList() && fd.Kind() == protoreflect.MessageKind {
-				list := m.Mutable(fd).List()
-				for idx := 0; idx < list.Len(); idx++ {
-					pm := list.Get(idx).Message()
+		} else if fd.IsMap() {
+			mapVal := m.Mutable(fd).Map()
+			for _, val := range mapVal.Range {
+				if fd.MapValue().Kind() == protoreflect.MessageKind {
+					pm := val.Message()
 					if pm.IsValid() {
-						if err := pruneMessage(pm, wildTrie); err != nil {
+						if err := o.pruneMessage(pm, elementTrie); err != nil {
 							return err
 						}
 					}
 				}
 			}
-			if fd.IsMap() && fd.MapValue().Kind() == protoreflect.MessageKind {
-				mapVal := m.Mutable(fd).Map()
-				for _, val := range mapVal.Range {
-					pm := val.Message()
-					if pm.IsValid() {
-						if err := pruneMessage(pm, wildTrie); err != nil {
-							return err
-						}
-					}
+		} else {
+			sub := m.Mutable(fd).Message()
+			if sub.IsValid() {
+				if err := o.pruneMessage(sub, elementTrie); err != nil {
+					return err
 				}
 			}
-			// For scalar lists/maps/scalars: wildcard keeps them entirely.
+		}
+	}
+	// scalar fields are kept as-is when explicitly listed
+	return nil
+}
 
-		default:
-			// Not in mask at this level -> clear whole field
+// pruneExtensions prunes the extensions set in m. Those the trie names, by
+// their full name in parentheses, are kept and pruned as fields are; others
+// are cleared with ClearUnknown.
+func (o *pruneOptions) pruneExtensions(m protoreflect.Message, trie *maskTrie) error {
+	var extensions []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		if fd.IsExtension() {
+			extensions = append(extensions, fd)
+		}
+		return true
+	})
+	for _, fd := range extensions {
+		subTrie := trie.children[extensionSegment(fd)]
+		if subTrie == nil && o.unknown == ClearUnknown {
+			if o.stats != nil {
+				o.stats.FieldsCleared++
+			}
 			m.Clear(fd)
+			continue
+		}
+		if o.stats != nil {
+			o.stats.FieldsKept++
+		}
+		if subTrie != nil {
+			if err := o.pruneSelected(m, fd, subTrie); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
@@ -119,6 +309,43 @@ type maskTrie struct {
 	children map[string]*maskTrie
 }
 
+// elementTrie returns the trie selecting the subfields of the elements or
+// values of a repeated or map field that t selects, consuming a wildcard
+// segment: "authors.*.given_name" and "authors.given_name" both select the
+// given_name of each element. A path ending in the wildcard selects whole
+// elements, as a path ending at the field does.
+func (t *maskTrie) elementTrie() *maskTrie {
+	star := t.children["*"]
+	if star == nil {
+		return t
+	}
+	if len(t.children) == 1 {
+		return star
+	}
+	rest := &maskTrie{children: make(map[string]*maskTrie, len(t.children)-1)}
+	for seg, child := range t.children {
+		if seg != "*" {
+			rest.children[seg] = child
+		}
+	}
+	return mergeTries(star, rest)
+}
+
+// mergeTries returns a trie selecting the paths selected by a or b.
+func mergeTries(a, b *maskTrie) *maskTrie {
+	if len(a.children) == 0 || len(b.children) == 0 {
+		return &maskTrie{}
+	}
+	out := &maskTrie{children: maps.Clone(a.children)}
+	for seg, child := range b.children {
+		if other, ok := out.children[seg]; ok {
+			child = mergeTries(other, child)
+		}
+		out.children[seg] = child
+	}
+	return out
+}
+
 func newMaskTrie(paths []string) *maskTrie {
 	root := &maskTrie{children: map[string]*maskTrie{}}
 	for _, p := range paths {
@@ -1,11 +1,15 @@
 package masks_test
 
 import (
+	"fmt"
 	"testing"
 
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
 
-	"github.com/hxtk/aip/internal/testpb"
+	"github.com/hxtk/aip/aiptest/testpb"
 	"github.com/hxtk/aip/masks"
 )
 
@@ -37,6 +41,30 @@ func TestPruneMessage_BasicScalars(t *testing.T) {
 	}
 }
 
+func TestPruneMessage_ExplicitPresence(t *testing.T) {
+	zero := int32(0)
+	book := &testpb.Book{
+		Title:     "drop me",
+		PageCount: &zero,
+	}
+
+	mask, err := masks.New(book.ProtoReflect().Descriptor(), masks.ModeRead, "page_count")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := masks.PruneMessage(book, mask); err != nil {
+		t.Fatal(err)
+	}
+
+	if book.PageCount == nil {
+		t.Errorf("expected PageCount set to zero to stay present")
+	}
+	if book.Title != "" {
+		t.Errorf("expected Title to be cleared, got %q", book.Title)
+	}
+}
+
 func TestPruneMessage_NestedMessage(t *testing.T) {
 	book := &testpb.Book{
 		Author: &testpb.Author{
@@ -62,6 +90,32 @@ func TestPruneMessage_NestedMessage(t *testing.T) {
 	}
 }
 
+func TestPruneMessage_WholeMessage(t *testing.T) {
+	book := &testpb.Book{
+		Title: "drop",
+		Author: &testpb.Author{
+			GivenName:  "keep",
+			FamilyName: "keep",
+		},
+	}
+
+	mask, err := masks.New(book.ProtoReflect().Descriptor(), masks.ModeRead, "author")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := masks.PruneMessage(book, mask); err != nil {
+		t.Fatal(err)
+	}
+
+	if book.Author.GivenName == "" || book.Author.FamilyName == "" {
+		t.Errorf("expected all Author fields to be kept, got %v", book.Author)
+	}
+	if book.Title != "" {
+		t.Errorf("expected Title cleared, got %q", book.Title)
+	}
+}
+
 func TestPruneMessage_RepeatedMessage(t *testing.T) {
 	book := &testpb.Book{
 		Authors: []*testpb.Author{
@@ -151,3 +205,265 @@ func TestPruneMessage_NilMaskNoop(t *testing.T) {
 		t.Errorf("expected no-op when mask=nil, got %v", book)
 	}
 }
+
+func TestPruneMessage_UnknownFields(t *testing.T) {
+	unknown := protowire.AppendTag(nil, 99, protowire.VarintType)
+	unknown = protowire.AppendVarint(unknown, 1)
+	newBook := func() *testpb.Book {
+		book := &testpb.Book{
+			Title:   "keep",
+			Author:  &testpb.Author{GivenName: "keep"},
+			Authors: []*testpb.Author{{GivenName: "keep"}},
+		}
+		book.ProtoReflect().SetUnknown(unknown)
+		book.Author.ProtoReflect().SetUnknown(unknown)
+		book.Authors[0].ProtoReflect().SetUnknown(unknown)
+		return book
+	}
+	mask, err := masks.New(newBook().ProtoReflect().Descriptor(), masks.ModeRead, "title", "author.given_name", "authors")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	book := newBook()
+	if err := masks.PruneMessage(book, mask); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(book, newBook()) {
+		t.Errorf("expected unknown fields to be preserved by default, got %v", book)
+	}
+
+	book = newBook()
+	if err := masks.PruneMessage(book, mask, masks.WithUnknownFields(masks.ClearUnknown)); err != nil {
+		t.Fatal(err)
+	}
+	if len(book.ProtoReflect().GetUnknown()) > 0 || len(book.Author.ProtoReflect().GetUnknown()) > 0 {
+		t.Errorf("expected unknown fields of traversed messages to be cleared, got %v", book)
+	}
+	if len(book.Authors[0].ProtoReflect().GetUnknown()) == 0 {
+		t.Errorf("expected unknown fields of messages selected whole to be kept")
+	}
+
+	newOptions := func() *descriptorpb.FieldOptions {
+		opts := &descriptorpb.FieldOptions{Deprecated: proto.Bool(true)}
+		proto.SetExtension(opts, annotations.E_FieldBehavior, []annotations.FieldBehavior{annotations.FieldBehavior_REQUIRED})
+		return opts
+	}
+	optsMask, err := masks.New(newOptions().ProtoReflect().Descriptor(), masks.ModeRead, "deprecated")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := newOptions()
+	if err := masks.PruneMessage(opts, optsMask); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.HasExtension(opts, annotations.E_FieldBehavior) {
+		t.Errorf("expected extensions to be preserved by default")
+	}
+	if err := masks.PruneMessage(opts, optsMask, masks.WithUnknownFields(masks.ClearUnknown)); err != nil {
+		t.Fatal(err)
+	}
+	if proto.HasExtension(opts, annotations.E_FieldBehavior) || !opts.GetDeprecated() {
+		t.Errorf("expected only extensions to be cleared, got %v", opts)
+	}
+}
+
+func TestPruneMessage_Stats(t *testing.T) {
+	book := &testpb.Book{
+		Title: "keep",
+		Name:  "drop",
+		Authors: []*testpb.Author{
+			{GivenName: "keep1", FamilyName: "drop1"},
+			{GivenName: "keep2"},
+		},
+	}
+	mask, err := masks.New(book.ProtoReflect().Descriptor(), masks.ModeRead, "title", "authors.given_name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stats masks.PruneStats
+	before := proto.Size(book)
+	if err := masks.PruneMessage(book, mask, masks.WithStats(&stats)); err != nil {
+		t.Fatal(err)
+	}
+	want := masks.PruneStats{
+		Messages:      1,
+		FieldsKept:    4, // title, authors and each given_name
+		FieldsCleared: 2, // name and one family_name
+		BytesSaved:    before - proto.Size(book),
+	}
+	if stats != want {
+		t.Errorf("got %+v, want %+v", stats, want)
+	}
+	if stats.BytesSaved <= 0 {
+		t.Errorf("expected bytes saved, got %d", stats.BytesSaved)
+	}
+
+	if err := masks.PruneMessage(book, mask, masks.WithStats(&stats)); err != nil {
+		t.Fatal(err)
+	}
+	want.Messages++
+	want.FieldsKept += 4
+	if stats != want {
+		t.Errorf("expected stats to accumulate: got %+v, want %+v", stats, want)
+	}
+}
+
+func TestPruneAll(t *testing.T) {
+	newBooks := func() []*testpb.Book {
+		books := make([]*testpb.Book, 100)
+		for i := range books {
+			books[i] = &testpb.Book{
+				Title:  fmt.Sprintf("keep %d", i),
+				Name:   fmt.Sprintf("books/%d", i),
+				Author: &testpb.Author{GivenName: "keep", FamilyName: "drop"},
+			}
+		}
+		return books
+	}
+	mask, err := masks.New((&testpb.Book{}).ProtoReflect().Descriptor(), masks.ModeRead, "title", "author.given_name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, parallelism := range []int{0, 1, 3, 200} {
+		t.Run(fmt.Sprintf("parallelism %d", parallelism), func(t *testing.T) {
+			books := newBooks()
+			var stats masks.PruneStats
+			err := masks.PruneSlice(books, mask, masks.WithParallelism(parallelism), masks.WithStats(&stats))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, book := range books {
+				want := &testpb.Book{Title: fmt.Sprintf("keep %d", i), Author: &testpb.Author{GivenName: "keep"}}
+				if !proto.Equal(book, want) {
+					t.Errorf("books[%d] = %v, want %v", i, book, want)
+				}
+			}
+			if stats.Messages != len(books) || stats.FieldsKept != 3*len(books) || stats.FieldsCleared != 2*len(books) {
+				t.Errorf("unexpected stats %+v", stats)
+			}
+		})
+	}
+
+	msgs := []proto.Message{&testpb.Book{Name: "drop", Title: "keep"}, nil, (*testpb.Book)(nil)}
+	if err := masks.PruneAll(msgs, mask, masks.WithParallelism(2)); err != nil {
+		t.Fatal(err)
+	}
+	if want := (&testpb.Book{Title: "keep"}); !proto.Equal(msgs[0], want) {
+		t.Errorf("got %v, want %v", msgs[0], want)
+	}
+}
+
+func TestPruneMessage_Extensions(t *testing.T) {
+	newOptions := func() *descriptorpb.MethodOptions {
+		opts := &descriptorpb.MethodOptions{Deprecated: proto.Bool(true)}
+		proto.SetExtension(opts, annotations.E_Http, &annotations.HttpRule{
+			Pattern: &annotations.HttpRule_Get{Get: "/v1/{name=books/*}"},
+			Body:    "*",
+		})
+		proto.SetExtension(opts, annotations.E_MethodSignature, []string{"name"})
+		return opts
+	}
+	mask, err := masks.New(newOptions().ProtoReflect().Descriptor(), masks.ModeRead, "(google.api.http).get")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := newOptions()
+	if err := masks.PruneMessage(opts, mask); err != nil {
+		t.Fatal(err)
+	}
+	rule := proto.GetExtension(opts, annotations.E_Http).(*annotations.HttpRule)
+	if rule.GetGet() == "" || rule.GetBody() != "" {
+		t.Errorf("expected only the get pattern of the http rule to be kept, got %v", rule)
+	}
+	if opts.Deprecated != nil {
+		t.Errorf("expected deprecated to be cleared")
+	}
+	if !proto.HasExtension(opts, annotations.E_MethodSignature) {
+		t.Errorf("expected extensions not named by the mask to be preserved by default")
+	}
+
+	opts = newOptions()
+	if err := masks.PruneMessage(opts, mask, masks.WithUnknownFields(masks.ClearUnknown)); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.HasExtension(opts, annotations.E_Http) || proto.HasExtension(opts, annotations.E_MethodSignature) {
+		t.Errorf("expected only extensions named by the mask to be kept, got %v", opts)
+	}
+}
+
+func TestPruneMessage_Wildcards(t *testing.T) {
+	newBook := func() *testpb.Book {
+		return &testpb.Book{
+			Title: "drop",
+			Tags:  []string{"sf", "classic"},
+			Authors: []*testpb.Author{
+				{GivenName: "given1", FamilyName: "family1"},
+				{GivenName: "given2", FamilyName: "family2"},
+			},
+			Reviews:         map[string]string{"smith": "good"},
+			DetailedReviews: map[string]*testpb.Review{"alice": {Rating: 5, Text: "great"}},
+		}
+	}
+
+	cases := []struct {
+		name  string
+		paths []string
+		want  *testpb.Book
+	}{
+		{
+			name:  "repeated scalar wildcard keeps the whole list",
+			paths: []string{"tags.*"},
+			want:  &testpb.Book{Tags: []string{"sf", "classic"}},
+		},
+		{
+			name:  "repeated message wildcard keeps whole elements",
+			paths: []string{"authors.*"},
+			want:  &testpb.Book{Authors: newBook().Authors},
+		},
+		{
+			name:  "scalar map wildcard keeps the whole map",
+			paths: []string{"reviews.*"},
+			want:  &testpb.Book{Reviews: map[string]string{"smith": "good"}},
+		},
+		{
+			name:  "message map wildcard keeps whole values",
+			paths: []string{"detailed_reviews.*"},
+			want:  &testpb.Book{DetailedReviews: newBook().DetailedReviews},
+		},
+		{
+			name:  "wildcard and element field paths merge",
+			paths: []string{"authors.*.given_name", "authors.family_name"},
+			want:  &testpb.Book{Authors: newBook().Authors},
+		},
+		{
+			name:  "whole elements subsume element fields",
+			paths: []string{"authors.*", "authors.given_name"},
+			want:  &testpb.Book{Authors: newBook().Authors},
+		},
+		{
+			name:  "map wildcard subselects values",
+			paths: []string{"detailed_reviews.*.rating"},
+			want:  &testpb.Book{DetailedReviews: map[string]*testpb.Review{"alice": {Rating: 5}}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			book := newBook()
+			mask, err := masks.New(book.ProtoReflect().Descriptor(), masks.ModeRead, tc.paths...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := masks.PruneMessage(book, mask); err != nil {
+				t.Fatal(err)
+			}
+			if !proto.Equal(book, tc.want) {
+				t.Errorf("got %v, want %v", book, tc.want)
+			}
+		})
+	}
+}
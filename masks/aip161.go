@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
 )
 
 // Mode represents whether the mask is being used for a read or write.
@@ -17,21 +18,45 @@ const (
 
 // New validates the given paths against the descriptor according to AIP-161
 // and returns a normalized FieldMask if valid.
+//
+// Under ModeRead, paths naming fields that do not exist are accepted and
+// select nothing; the FieldMask reports a Warning for each (see
+// FieldMask.Warnings).
 func New(desc protoreflect.MessageDescriptor, mode Mode, paths ...string) (*FieldMask, error) {
 	var validPaths []string
+	var warnings []Warning
 	for _, p := range paths {
-		if err := validatePath(desc, mode, p); err != nil {
+		ignore := func(seg string) {
+			warnings = append(warnings, Warning{Path: p, Field: seg})
+		}
+		if err := validatePath(desc, mode, p, ignore); err != nil {
 			return nil, fmt.Errorf("invalid field mask path %q: %w", p, err)
 		}
 		validPaths = append(validPaths, p)
 	}
 	return &FieldMask{
-		desc: desc,
-		trie: newMaskTrie(validPaths),
+		desc:     desc,
+		trie:     newMaskTrie(validPaths),
+		warnings: warnings,
 	}, nil
 }
 
-func validatePath(desc protoreflect.MessageDescriptor, mode Mode, path string) error {
+// Warning reports a path of a read mask that names a field that does not
+// exist, which New accepts under ModeRead rather than rejecting the mask.
+type Warning struct {
+	// Path is the path of the mask.
+	Path string
+	// Field is the segment of the path naming no field.
+	Field string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("field %q of read mask path %q does not exist and was ignored", w.Field, w.Path)
+}
+
+// validatePath checks path against desc, calling ignore with the segment
+// from which it is ignored under ModeRead, if any.
+func validatePath(desc protoreflect.MessageDescriptor, mode Mode, path string, ignore func(seg string)) error {
 	if path == "" {
 		return fmt.Errorf("empty path")
 	}
@@ -41,100 +66,160 @@ func validatePath(desc protoreflect.MessageDescriptor, mode Mode, path string) e
 	}
 
 	curr := desc
+	// container is the repeated or map field whose element or value the
+	// next segment addresses, if any.
+	var container protoreflect.FieldDescriptor
 	for i, seg := range segments {
 		isLast := i == len(segments)-1
 
+		if f := container; f != nil {
+			container = nil
+			if f.IsMap() {
+				if err := validateMapKey(f, seg); err != nil {
+					return err
+				}
+				if f.MapValue().Message() == nil {
+					if !isLast {
+						return fmt.Errorf("cannot traverse into scalar values of map field %q", f.Name())
+					}
+					continue
+				}
+				// Descend into the message values, which may be of the
+				// type being validated for self-recursive messages.
+				curr = f.MapValue().Message()
+				continue
+			}
+			if seg == "*" {
+				if f.Message() == nil && !isLast {
+					return fmt.Errorf("cannot traverse into scalar elements of repeated field %q", f.Name())
+				}
+				continue
+			}
+			if f.Message() == nil {
+				return fmt.Errorf("cannot traverse into scalar elements of repeated field %q", f.Name())
+			}
+			// Otherwise seg is a field of the elements, in curr.
+		}
+
 		switch {
 		case seg == "*":
-			// Wildcard must appear on repeated or map fields, not on scalars.
 			if i == 0 {
 				return fmt.Errorf("wildcard cannot be top-level")
 			}
-			// We already validated that the previous segment was a repeated/map field.
-			continue
-
-		case strings.HasPrefix(seg, "`") && strings.HasSuffix(seg, "`"):
-			// Backtick-quoted map key
-			key := seg[1 : len(seg)-1]
-			if strings.ContainsRune(key, '`') {
-				return fmt.Errorf("malformed backtick quoting in %q", seg)
-			}
-			// Must follow a map field
+			return fmt.Errorf("wildcard must follow a repeated or map field")
+		case strings.HasPrefix(seg, "`"):
+			return fmt.Errorf("map key %q without map field", seg)
+		case isAllDigits(seg):
 			if i == 0 {
-				return fmt.Errorf("map key %q without map field", seg)
+				return fmt.Errorf("numeric token %q cannot be top-level", seg)
 			}
-			continue
+			return fmt.Errorf("numeric index %q not allowed", seg)
+		}
 
-		default:
-			// Regular identifier or numeric literal
-			f := curr.Fields().ByName(protoreflect.Name(seg))
-			if f == nil {
-				// Not a field — check if it could be a map key
-				if isAllDigits(seg) {
-					// numeric token
-					if i == 0 {
-						return fmt.Errorf("numeric token %q cannot be top-level", seg)
-					}
-					// Validate parent field is a map with int key
-					parentField := findFieldBySegment(curr, segments[i-1])
-					if parentField == nil {
-						return fmt.Errorf("numeric key %q without parent field", seg)
-					}
-					if parentField.IsMap() && isIntegerKind(parentField.MapKey().Kind()) {
-						continue
-					}
-					return fmt.Errorf("numeric index %q not allowed", seg)
-				}
-				// If nonexistent field
-				if mode == ModeWrite {
-					return fmt.Errorf("field %q does not exist", seg)
-				}
-				// ModeRead: tolerate nonexistent field by stopping traversal.
-				return nil
+		f := fieldBySegment(curr, seg)
+		if f == nil {
+			if mode == ModeWrite {
+				return fmt.Errorf("field %q does not exist", seg)
 			}
-
-			// Found a real field
-			if f.IsList() || f.IsMap() {
-				// ok; subfield allowed (wildcard, map key, or subfield traversal)
-				if !isLast {
-					// must be followed by * or key or subfield
-					// we’ll check at next iteration
-				}
-			} else if f.Kind() == protoreflect.MessageKind {
-				// Embedded message — descend into it
+			// ModeRead: tolerate nonexistent field by stopping traversal.
+			ignore(seg)
+			return nil
+		}
+		switch {
+		case f.IsList() || f.IsMap():
+			container = f
+			if f.IsList() && f.Message() != nil {
 				curr = f.Message()
-			} else {
-				// Scalar field
-				if !isLast {
-					return fmt.Errorf("cannot traverse into scalar field %q", seg)
-				}
 			}
+		case f.Message() != nil:
+			// Embedded message — descend into it
+			curr = f.Message()
+		case !isLast:
+			return fmt.Errorf("cannot traverse into scalar field %q", seg)
 		}
 	}
 
 	return nil
 }
 
+// validateMapKey checks that seg addresses values of the map field f: a
+// wildcard, a backtick-quoted key, or a bare key of the key type of f.
+func validateMapKey(f protoreflect.FieldDescriptor, seg string) error {
+	switch kind := f.MapKey().Kind(); {
+	case seg == "*":
+	case strings.HasPrefix(seg, "`") && strings.HasSuffix(seg, "`") && len(seg) >= 2:
+		if strings.ContainsRune(seg[1:len(seg)-1], '`') {
+			return fmt.Errorf("malformed backtick quoting in %q", seg)
+		}
+	case isIntegerKind(kind):
+		if !isAllDigits(seg) {
+			return fmt.Errorf("invalid key %q of map field %q", seg, f.Name())
+		}
+	case kind == protoreflect.BoolKind:
+		if seg != "true" && seg != "false" {
+			return fmt.Errorf("invalid key %q of map field %q", seg, f.Name())
+		}
+	}
+	return nil
+}
+
+// fieldBySegment returns the field of desc that seg names, or nil if there
+// is none. Extensions of desc are named by their full name in parentheses
+// and must be registered in protoregistry.GlobalTypes.
+func fieldBySegment(desc protoreflect.MessageDescriptor, seg string) protoreflect.FieldDescriptor {
+	name, ok := strings.CutPrefix(seg, "(")
+	if !ok {
+		return desc.Fields().ByName(protoreflect.Name(seg))
+	}
+	name, ok = strings.CutSuffix(name, ")")
+	if !ok {
+		return nil
+	}
+	xt, err := protoregistry.GlobalTypes.FindExtensionByName(protoreflect.FullName(name))
+	if err != nil || xt.TypeDescriptor().ContainingMessage().FullName() != desc.FullName() {
+		return nil
+	}
+	return xt.TypeDescriptor()
+}
+
+// extensionSegment returns the path segment naming the extension fd.
+func extensionSegment(fd protoreflect.FieldDescriptor) string {
+	return "(" + string(fd.FullName()) + ")"
+}
+
 // tokenizePath splits a field mask path into segments,
-// handling backtick-quoted keys.
+// handling backtick-quoted keys and parenthesized extension names,
+// whose dots do not separate segments.
 func tokenizePath(path string) ([]string, error) {
 	var segs []string
 	var b strings.Builder
-	inQuote := false
+	inQuote, inParens := false, false
 
 	for i, r := range path {
-		switch r {
-		case '.':
-			if !inQuote {
-				if b.Len() == 0 {
-					return nil, fmt.Errorf("empty segment at %d", i)
-				}
-				segs = append(segs, b.String())
-				b.Reset()
-				continue
-			}
-		case '`':
+		switch {
+		case r == '`' && !inParens:
 			inQuote = !inQuote
+		case inQuote:
+		case r == '(':
+			if inParens || b.Len() > 0 {
+				return nil, fmt.Errorf("unexpected '(' at %d", i)
+			}
+			inParens = true
+		case r == ')':
+			if !inParens {
+				return nil, fmt.Errorf("unexpected ')' at %d", i)
+			}
+			inParens = false
+		case inParens:
+		case r == '.':
+			if b.Len() == 0 {
+				return nil, fmt.Errorf("empty segment at %d", i)
+			}
+			segs = append(segs, b.String())
+			b.Reset()
+			continue
+		case strings.HasPrefix(b.String(), "("):
+			return nil, fmt.Errorf("unexpected %q after extension name at %d", r, i)
 		}
 		b.WriteRune(r)
 	}
@@ -142,6 +227,9 @@ func tokenizePath(path string) ([]string, error) {
 	if inQuote {
 		return nil, fmt.Errorf("unclosed backtick")
 	}
+	if inParens {
+		return nil, fmt.Errorf("unclosed parenthesis")
+	}
 	if b.Len() > 0 {
 		segs = append(segs, b.String())
 	}
@@ -172,6 +260,60 @@ func isIntegerKind(k protoreflect.Kind) bool {
 	return false
 }
 
-func findFieldBySegment(desc protoreflect.MessageDescriptor, seg string) protoreflect.FieldDescriptor {
-	return desc.Fields().ByName(protoreflect.Name(seg))
+// MapKeys returns the segments of path that are keys of map fields of desc
+// rather than field names, such as "en" in "translations.en.title", e.g. to
+// redact them from logs when keys may hold personal data. Backtick-quoted
+// segments are returned both quoted and unquoted, and the segments past a
+// field desc does not have, or into a scalar, are returned as they may be
+// keys. A path that cannot be tokenized is returned whole.
+func MapKeys(desc protoreflect.MessageDescriptor, path string) []string {
+	segments, err := tokenizePath(path)
+	if err != nil {
+		return []string{path}
+	}
+	var keys []string
+	addKey := func(seg string) {
+		keys = append(keys, seg)
+		if unquoted, ok := strings.CutPrefix(seg, "`"); ok {
+			if unquoted, ok = strings.CutSuffix(unquoted, "`"); ok && unquoted != "" {
+				keys = append(keys, unquoted)
+			}
+		}
+	}
+
+	curr := desc
+	var container protoreflect.FieldDescriptor
+	for i, seg := range segments {
+		if f := container; f != nil {
+			container = nil
+			if f.IsMap() {
+				if seg != "*" {
+					addKey(seg)
+				}
+				curr = f.MapValue().Message()
+				continue
+			}
+			if seg == "*" {
+				continue
+			}
+		}
+		var f protoreflect.FieldDescriptor
+		if curr != nil && !strings.HasPrefix(seg, "`") {
+			f = fieldBySegment(curr, seg)
+		}
+		if f == nil {
+			if curr == nil || strings.HasPrefix(seg, "`") {
+				addKey(seg)
+			}
+			for _, rest := range segments[i+1:] {
+				addKey(rest)
+			}
+			return keys
+		}
+		if f.IsList() || f.IsMap() {
+			container = f
+		}
+		curr = f.Message()
+	}
+	return keys
 }
@@ -2,9 +2,13 @@
 package masks_test
 
 import (
+	"slices"
 	"testing"
 
-	"github.com/hxtk/aip/internal/testpb"
+	_ "google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/hxtk/aip/aiptest/testpb"
 	masks "github.com/hxtk/aip/masks"
 )
 
@@ -27,6 +31,18 @@ func TestNew_AIP161Semantics(t *testing.T) {
 		{"numeric index invalid", []string{"authors.0.given_name"}, masks.ModeRead, true},
 		{"nonexistent field read tolerated", []string{"does_not_exist"}, masks.ModeRead, false},
 		{"nonexistent field write invalid", []string{"does_not_exist"}, masks.ModeWrite, true},
+		{"repeated element field write", []string{"authors.given_name"}, masks.ModeWrite, false},
+		{"wildcard repeated write", []string{"authors.*.given_name"}, masks.ModeWrite, false},
+		{"nonexistent element field write invalid", []string{"authors.nope"}, masks.ModeWrite, true},
+		{"map value field write", []string{"detailed_reviews.alice.rating"}, masks.ModeWrite, false},
+		{"map wildcard value field write", []string{"detailed_reviews.*.rating"}, masks.ModeWrite, false},
+		{"nonexistent map value field write invalid", []string{"detailed_reviews.*.nope"}, masks.ModeWrite, true},
+		{"scalar map value traversal invalid", []string{"reviews.smith.name"}, masks.ModeRead, true},
+		{"non-integer key of integer map invalid", []string{"items.seven"}, masks.ModeRead, true},
+		{"wildcard on message invalid", []string{"author.*"}, masks.ModeRead, true},
+		{"wildcard on repeated scalar", []string{"tags.*"}, masks.ModeWrite, false},
+		{"traversal into repeated scalar invalid", []string{"tags.*.x"}, masks.ModeRead, true},
+		{"wildcard on repeated message", []string{"authors.*"}, masks.ModeWrite, false},
 	}
 
 	for _, tc := range cases {
@@ -38,3 +54,118 @@ func TestNew_AIP161Semantics(t *testing.T) {
 		})
 	}
 }
+
+func TestNew_Warnings(t *testing.T) {
+	desc := new(testpb.Book).ProtoReflect().Descriptor()
+
+	mask, err := masks.New(desc, masks.ModeRead, "title", "nope", "author.nickname.first", "detailed_reviews.*.stars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []masks.Warning{
+		{Path: "nope", Field: "nope"},
+		{Path: "author.nickname.first", Field: "nickname"},
+		{Path: "detailed_reviews.*.stars", Field: "stars"},
+	}
+	if got := mask.Warnings(); !slices.Equal(got, want) {
+		t.Errorf("Warnings() = %v, want %v", got, want)
+	}
+	if got, want := want[1].String(), `field "nickname" of read mask path "author.nickname.first" does not exist and was ignored`; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	mask, err = masks.New(desc, masks.ModeRead, "title", "author.given_name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mask.Warnings(); len(got) != 0 {
+		t.Errorf("Warnings() of a valid mask = %v, want none", got)
+	}
+	if got := (*masks.FieldMask)(nil).Warnings(); got != nil {
+		t.Errorf("Warnings() of a nil mask = %v, want nil", got)
+	}
+}
+
+func TestNew_Recursive(t *testing.T) {
+	desc := new(testpb.Comment).ProtoReflect().Descriptor()
+
+	for _, path := range []string{
+		"replies.*.replies.*.text",
+		"replies.replies.replies.text",
+		"parent.parent.parent.name",
+		"parent.replies.*.parent.text",
+	} {
+		if _, err := masks.New(desc, masks.ModeWrite, path); err != nil {
+			t.Errorf("path %q: %v", path, err)
+		}
+	}
+	for _, path := range []string{
+		"replies.*.replies.*.nope",
+		"parent.parent.text.name",
+	} {
+		if _, err := masks.New(desc, masks.ModeWrite, path); err == nil {
+			t.Errorf("path %q: expected error", path)
+		}
+	}
+}
+
+func TestNew_Extensions(t *testing.T) {
+	desc := new(descriptorpb.MethodOptions).ProtoReflect().Descriptor()
+
+	cases := []struct {
+		name    string
+		path    string
+		mode    masks.Mode
+		wantErr bool
+	}{
+		{"extension", "(google.api.http)", masks.ModeWrite, false},
+		{"extension subfield", "(google.api.http).get", masks.ModeWrite, false},
+		{"extension repeated subfield", "(google.api.http).additional_bindings.*.post", masks.ModeWrite, false},
+		{"nonexistent extension subfield write invalid", "(google.api.http).nope", masks.ModeWrite, true},
+		{"unregistered extension read tolerated", "(my.pkg.ext).field", masks.ModeRead, false},
+		{"unregistered extension write invalid", "(my.pkg.ext)", masks.ModeWrite, true},
+		{"extension of another message write invalid", "(google.api.resource)", masks.ModeWrite, true},
+		{"unclosed parenthesis invalid", "(google.api.http.get", masks.ModeRead, true},
+		{"text after extension invalid", "(google.api.http)get", masks.ModeRead, true},
+		{"parenthesis within segment invalid", "deprecated(x)", masks.ModeRead, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := masks.New(desc, tc.mode, tc.path)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got err=%v wantErr=%v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestMapKeys(t *testing.T) {
+	desc := new(testpb.Book).ProtoReflect().Descriptor()
+
+	cases := []struct {
+		path string
+		want []string
+	}{
+		{"title", nil},
+		{"author.given_name", nil},
+		{"reviews.smith", []string{"smith"}},
+		{"reviews.`John Smith`", []string{"`John Smith`", "John Smith"}},
+		{"reviews.*", nil},
+		{"items.123", []string{"123"}},
+		{"detailed_reviews.alice.rating", []string{"alice"}},
+		{"authors.*.given_name", nil},
+		{"does_not_exist.bob", []string{"bob"}},
+		{"reviews.smith.name", []string{"smith", "name"}},
+		{"reviews.`unclosed", []string{"reviews.`unclosed"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.path, func(t *testing.T) {
+			got := masks.MapKeys(desc, tc.path)
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("MapKeys(%q) = %q, want %q", tc.path, got, tc.want)
+			}
+		})
+	}
+}
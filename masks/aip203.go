@@ -0,0 +1,77 @@
+package masks
+
+import (
+	"fmt"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ClearOutputOnly clears the fields of msg annotated with the OUTPUT_ONLY
+// google.api.field_behavior, recursing into messages, lists and maps.
+//
+// AIP-203 requires servers to ignore output only fields supplied in
+// requests. ClearOutputOnly may be passed to WithRequestPruner to do so for
+// every request message, including those received on client and bidi
+// streams.
+//
+// Self-recursive messages are supported to the nesting depth protobuf
+// decoding allows. Deeper messages, such as those built in memory with a
+// cycle, are an error.
+func ClearOutputOnly(msg proto.Message) error {
+	if msg == nil {
+		return nil
+	}
+	return clearOutputOnly(msg.ProtoReflect(), protowire.DefaultRecursionLimit)
+}
+
+// clearOutputOnly applies ClearOutputOnly recursively, to messages nested at
+// most depth levels deep.
+func clearOutputOnly(m protoreflect.Message, depth int) error {
+	if depth == 0 {
+		return fmt.Errorf("message %s exceeds maximum nesting depth of %d", m.Descriptor().FullName(), protowire.DefaultRecursionLimit)
+	}
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !m.Has(fd) {
+			continue
+		}
+		if isOutputOnly(fd) {
+			m.Clear(fd)
+			continue
+		}
+		switch {
+		case fd.IsList() && fd.Message() != nil:
+			list := m.Mutable(fd).List()
+			for idx := 0; idx < list.Len(); idx++ {
+				if err := clearOutputOnly(list.Get(idx).Message(), depth-1); err != nil {
+					return err
+				}
+			}
+		case fd.IsMap() && fd.MapValue().Message() != nil:
+			for _, val := range m.Mutable(fd).Map().Range {
+				if err := clearOutputOnly(val.Message(), depth-1); err != nil {
+					return err
+				}
+			}
+		case fd.Message() != nil && !fd.IsList() && !fd.IsMap():
+			if err := clearOutputOnly(m.Mutable(fd).Message(), depth-1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func isOutputOnly(fd protoreflect.FieldDescriptor) bool {
+	behaviors, _ := proto.GetExtension(fd.Options(), annotations.E_FieldBehavior).([]annotations.FieldBehavior)
+	for _, b := range behaviors {
+		if b == annotations.FieldBehavior_OUTPUT_ONLY {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package masks_test
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/masks"
+)
+
+func TestClearOutputOnly(t *testing.T) {
+	req := &testpb.UpdateBookRequest{
+		Book: &testpb.Book{
+			Title:      "keep",
+			CreateTime: timestamppb.Now(),
+		},
+	}
+
+	if err := masks.ClearOutputOnly(req); err != nil {
+		t.Fatal(err)
+	}
+
+	want := &testpb.UpdateBookRequest{Book: &testpb.Book{Title: "keep"}}
+	if !proto.Equal(req, want) {
+		t.Errorf("got %v, want %v", req, want)
+	}
+}
+
+func TestClearOutputOnly_Repeated(t *testing.T) {
+	rsp := &testpb.ImportBooksResponse{
+		Books: []*testpb.Book{
+			{Title: "a", CreateTime: timestamppb.Now()},
+			{Title: "b", CreateTime: timestamppb.Now()},
+		},
+	}
+
+	if err := masks.ClearOutputOnly(rsp); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, b := range rsp.Books {
+		if b.CreateTime != nil {
+			t.Errorf("books[%d].CreateTime should be cleared, got %v", i, b.CreateTime)
+		}
+		if b.Title == "" {
+			t.Errorf("books[%d].Title should be kept", i)
+		}
+	}
+}
+
+func TestClearOutputOnly_Recursive(t *testing.T) {
+	thread := &testpb.Comment{
+		Text: "root",
+		Replies: []*testpb.Comment{{
+			Text:   "reply",
+			Parent: &testpb.Comment{Text: "root"},
+			Replies: []*testpb.Comment{{
+				Text:   "nested",
+				Parent: &testpb.Comment{Text: "reply"},
+			}},
+		}},
+	}
+
+	if err := masks.ClearOutputOnly(thread); err != nil {
+		t.Fatal(err)
+	}
+
+	want := &testpb.Comment{
+		Text: "root",
+		Replies: []*testpb.Comment{{
+			Text:    "reply",
+			Replies: []*testpb.Comment{{Text: "nested"}},
+		}},
+	}
+	if !proto.Equal(thread, want) {
+		t.Errorf("got %v, want %v", thread, want)
+	}
+
+	cycle := &testpb.Comment{Text: "cycle"}
+	cycle.Replies = []*testpb.Comment{cycle}
+	if err := masks.ClearOutputOnly(cycle); err == nil {
+		t.Error("expected an error for a cyclic message")
+	}
+}
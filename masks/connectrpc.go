@@ -2,17 +2,45 @@ package masks
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"slices"
 	"strings"
+	"sync"
 
 	"connectrpc.com/connect"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 )
 
 type masksCtxKey struct{}
 
 var ctxKey = masksCtxKey{}
 
+// maskState is the read mask of a call, stored in its context.
+type maskState struct {
+	mask *FieldMask
+
+	// prune prunes a response message to mask.
+	prune func(proto.Message) error
+
+	mu sync.Mutex
+	// applied holds the messages pruned by ApplyToResponse that the
+	// interceptor has not yet seen.
+	applied map[proto.Message]struct{}
+}
+
+// takeApplied reports whether msg was pruned by ApplyToResponse, forgetting
+// it.
+func (s *maskState) takeApplied(msg proto.Message) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.applied[msg]
+	delete(s.applied, msg)
+	return ok
+}
+
 // HasPath checks whether a given field path exists in the field mask in ctx.
 //
 // If the provided context has no mask embedded or has a nil mask embedded,
@@ -26,24 +54,303 @@ var ctxKey = masksCtxKey{}
 // If you're implementing an RPC, you may use this method to decide whether
 // your handler needs to do work to produce a non-trivial field on the response.
 func HasPath(ctx context.Context, path string) bool {
-	mask, ok := ctx.Value(ctxKey).(*FieldMask)
+	state, ok := ctx.Value(ctxKey).(*maskState)
 	if !ok {
 		return true
 	}
 
-	return mask.HasPath(path)
+	return state.mask.HasPath(path)
 }
 
+// Requested reports whether the read mask in ctx selects any of paths, or
+// any field within them, as HasPath does. Handlers use it to skip computing
+// expensive fields, such as aggregations or joins, that the mask would
+// discard:
+//
+//	if masks.Requested(ctx, "review_count", "average_rating") {
+//	    book.ReviewCount, book.AverageRating = s.reviewStats(ctx, book.Name)
+//	}
+//
+// If ctx has no mask, every path is requested.
+func Requested(ctx context.Context, paths ...string) bool {
+	state, ok := ctx.Value(ctxKey).(*maskState)
+	if !ok || state.mask == nil {
+		return true
+	}
+	for _, path := range paths {
+		if state.mask.HasPath(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyToResponse prunes msg to the read mask in ctx, as the read mask
+// interceptor would, and marks it so that the interceptor does not prune it
+// again when the handler returns or sends it. Handlers that assemble a
+// response themselves, e.g. to skip computing fields that HasPath reports
+// are not wanted, may use it to see exactly what the caller receives.
+//
+// Fields set on msg after ApplyToResponse are not pruned. If ctx has no
+// mask, ApplyToResponse does nothing.
+func ApplyToResponse(ctx context.Context, msg proto.Message) error {
+	state, ok := ctx.Value(ctxKey).(*maskState)
+	if !ok || state.mask == nil {
+		return nil
+	}
+	if err := state.prune(msg); err != nil {
+		return err
+	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.applied == nil {
+		state.applied = make(map[proto.Message]struct{})
+	}
+	state.applied[msg] = struct{}{}
+	return nil
+}
+
+// MaskContext returns a copy of ctx carrying mask, as seen by HasPath and
+// ApplyToResponse.
 func MaskContext(ctx context.Context, mask *FieldMask) context.Context {
-	return context.WithValue(ctx, ctxKey, mask)
+	return maskContext(ctx, &maskState{
+		mask: mask,
+		prune: func(msg proto.Message) error {
+			return PruneMessage(msg, mask)
+		},
+	})
+}
+
+// maskContext returns a copy of ctx carrying state.
+func maskContext(ctx context.Context, state *maskState) context.Context {
+	return context.WithValue(ctx, ctxKey, state)
+}
+
+// InterceptorOption configures WithReadMaskInterceptor.
+type InterceptorOption func(*connectInterceptor)
+
+// WithMethodResolver sets the resolver used to find the method descriptor of
+// handlers registered without a schema. Without a resolver, read masks sent
+// to such handlers are ignored.
+func WithMethodResolver(r MethodResolver) InterceptorOption {
+	return func(c *connectInterceptor) {
+		c.resolver = r
+	}
+}
+
+// WithRequestPruner sets a function applied to every request message before
+// the handler sees it, including each message received on client and bidi
+// streams, e.g. ClearOutputOnly. Errors other than *connect.Error fail the
+// request with CodeInvalidArgument.
+func WithRequestPruner(f func(proto.Message) error) InterceptorOption {
+	return func(c *connectInterceptor) {
+		c.requestPruner = f
+	}
+}
+
+// WithPruneOptions sets the options with which responses are pruned to the
+// read mask, e.g. WithUnknownFields.
+func WithPruneOptions(opts ...PruneOption) InterceptorOption {
+	return func(c *connectInterceptor) {
+		c.pruneOptions = opts
+	}
 }
 
-func WithReadMaskInterceptor(header string) connect.Interceptor {
-	return &connectInterceptor{header: header}
+// WithPruneStats sets a function called with the statistics of each response
+// message pruned to the read mask, e.g. to export metrics. It is called with
+// the context and spec of the call, once per message on server streams and
+// once per mask applied with WithItemMasks.
+func WithPruneStats(f func(ctx context.Context, spec connect.Spec, stats PruneStats)) InterceptorOption {
+	return func(c *connectInterceptor) {
+		c.statsFunc = f
+	}
+}
+
+// WithWarningHandler sets a function called with a Warning for each path of
+// a read mask that names a field that does not exist, which the interceptor
+// ignores rather than rejecting the request, e.g. to find clients that
+// would break if such masks were rejected.
+func WithWarningHandler(f func(ctx context.Context, spec connect.Spec, w Warning)) InterceptorOption {
+	return func(c *connectInterceptor) {
+		c.warn = f
+	}
+}
+
+// WithItemMasks sets a function returning the mask to prune each message
+// sent on a stream to, e.g. to apply per-item access policies to the
+// results of a server-streaming List. Messages are also pruned to the read
+// mask, if one was sent, keeping only the fields both select. A nil mask
+// leaves the message to the read mask alone. Masks not valid for the
+// message fail the call with CodeInternal.
+func WithItemMasks(maskFor func(ctx context.Context, msg proto.Message) *fieldmaskpb.FieldMask) InterceptorOption {
+	return func(c *connectInterceptor) {
+		c.maskFor = maskFor
+	}
+}
+
+// MethodMatcher reports whether the method of a call matches, given its spec.
+type MethodMatcher func(spec connect.Spec) bool
+
+// Procedures matches methods by procedure name, e.g. "/pkg.Service/Method".
+func Procedures(procedures ...string) MethodMatcher {
+	return func(spec connect.Spec) bool {
+		return slices.Contains(procedures, spec.Procedure)
+	}
+}
+
+// RequestTypes matches methods by the full name of their request message,
+// e.g. "pkg.UploadRequest". Methods of handlers registered without a schema
+// never match.
+func RequestTypes(names ...protoreflect.FullName) MethodMatcher {
+	return func(spec connect.Spec) bool {
+		meth, ok := spec.Schema.(protoreflect.MethodDescriptor)
+		return ok && slices.Contains(names, meth.Input().FullName())
+	}
+}
+
+// WithMethods limits the interceptor to the methods m matches. Calls of
+// other methods pass through it untouched.
+func WithMethods(m MethodMatcher) InterceptorOption {
+	return func(c *connectInterceptor) {
+		c.include = m
+	}
+}
+
+// WithoutMethods disables the interceptor for the methods m matches, e.g.
+// streaming uploads or internal admin RPCs.
+func WithoutMethods(m MethodMatcher) InterceptorOption {
+	return func(c *connectInterceptor) {
+		c.exclude = m
+	}
+}
+
+// WithFallbackHeaders sets headers consulted in order for the read mask when
+// the header given to WithReadMaskInterceptor is absent, e.g. "X-Fields".
+// The first present is used.
+func WithFallbackHeaders(headers ...string) InterceptorOption {
+	return func(c *connectInterceptor) {
+		c.headers = append(c.headers[:1:1], headers...)
+	}
+}
+
+// WithQueryParameter sets a query parameter consulted for the read mask of
+// unary GET requests, such as those of the Connect protocol for methods
+// without side effects, when no read mask header is present.
+func WithQueryParameter(name string) InterceptorOption {
+	return func(c *connectInterceptor) {
+		c.queryParameter = name
+	}
+}
+
+func WithReadMaskInterceptor(header string, opts ...InterceptorOption) connect.Interceptor {
+	c := &connectInterceptor{headers: []string{header}}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 type connectInterceptor struct {
-	header string
+	headers        []string
+	queryParameter string
+	resolver       MethodResolver
+	requestPruner  func(proto.Message) error
+	pruneOptions   []PruneOption
+	statsFunc      func(context.Context, connect.Spec, PruneStats)
+	warn           func(context.Context, connect.Spec, Warning)
+	maskFor        func(context.Context, proto.Message) *fieldmaskpb.FieldMask
+	include        MethodMatcher
+	exclude        MethodMatcher
+}
+
+// applies reports whether the interceptor applies to calls with spec.
+func (c *connectInterceptor) applies(spec connect.Spec) bool {
+	if c.include != nil && !c.include(spec) {
+		return false
+	}
+	return c.exclude == nil || !c.exclude(spec)
+}
+
+// readMask returns the read mask of a request: the value of the first of
+// the headers present, or of the query parameter for GET requests.
+func (c *connectInterceptor) readMask(header http.Header, peer connect.Peer, httpMethod string) string {
+	for _, h := range c.headers {
+		if v := header.Get(h); v != "" {
+			return v
+		}
+	}
+	if c.queryParameter != "" && httpMethod == http.MethodGet {
+		return peer.Query.Get(c.queryParameter)
+	}
+	return ""
+}
+
+// prune prunes msg to mask, reporting its statistics if requested.
+func (c *connectInterceptor) prune(ctx context.Context, spec connect.Spec, msg proto.Message, mask *FieldMask) error {
+	if c.statsFunc == nil {
+		return PruneMessage(msg, mask, c.pruneOptions...)
+	}
+	var stats PruneStats
+	opts := append(slices.Clip(c.pruneOptions), WithStats(&stats))
+	if err := PruneMessage(msg, mask, opts...); err != nil {
+		return err
+	}
+	c.statsFunc(ctx, spec, stats)
+	return nil
+}
+
+// reportWarnings passes the warnings of the read mask of a call to the
+// warning handler, if any.
+func (c *connectInterceptor) reportWarnings(ctx context.Context, spec connect.Spec, mask *FieldMask) {
+	if c.warn == nil {
+		return
+	}
+	for _, w := range mask.Warnings() {
+		c.warn(ctx, spec, w)
+	}
+}
+
+// maskState returns the state of a call with the read mask mask.
+func (c *connectInterceptor) maskState(ctx context.Context, spec connect.Spec, mask *FieldMask) *maskState {
+	return &maskState{
+		mask: mask,
+		prune: func(msg proto.Message) error {
+			return c.prune(ctx, spec, msg, mask)
+		},
+	}
+}
+
+// pruneRequest applies the request pruner, if any, to msg.
+func (c *connectInterceptor) pruneRequest(msg any) error {
+	pm, ok := msg.(proto.Message)
+	if !ok || c.requestPruner == nil {
+		return nil
+	}
+	err := c.requestPruner(pm)
+	if err == nil {
+		return nil
+	}
+	var connectErr *connect.Error
+	if errors.As(err, &connectErr) {
+		return err
+	}
+	return connect.NewError(connect.CodeInvalidArgument, err)
+}
+
+// method returns the descriptor of the method called, or nil if it is
+// unknown.
+func (c *connectInterceptor) method(ctx context.Context, spec connect.Spec) (protoreflect.MethodDescriptor, error) {
+	if meth, ok := spec.Schema.(protoreflect.MethodDescriptor); ok {
+		return meth, nil
+	}
+	if c.resolver == nil {
+		return nil, nil
+	}
+	meth, err := c.resolver.ResolveMethod(ctx, spec.Procedure)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeUnavailable, err)
+	}
+	return meth, nil
 }
 
 // WrapStreamingClient implements connect.Interceptor.
@@ -54,15 +361,27 @@ func (c *connectInterceptor) WrapStreamingClient(fn connect.StreamingClientFunc)
 // WrapStreamingHandler implements connect.Interceptor.
 func (c *connectInterceptor) WrapStreamingHandler(fn connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
 	return func(ctx context.Context, h connect.StreamingHandlerConn) error {
-		meth, ok := h.Spec().Schema.(protoreflect.MethodDescriptor)
-		if !ok {
+		if !c.applies(h.Spec()) {
 			return fn(ctx, h)
 		}
+		if c.requestPruner != nil {
+			h = &requestPruningConn{StreamingHandlerConn: h, c: c}
+		}
+		if c.maskFor != nil {
+			h = &itemPruningConn{StreamingHandlerConn: h, ctx: ctx, c: c}
+		}
 
-		headerVal := h.RequestHeader().Get(c.header)
+		headerVal := c.readMask(h.RequestHeader(), h.Peer(), "")
 		if headerVal == "" {
 			return fn(ctx, h)
 		}
+		meth, err := c.method(ctx, h.Spec())
+		if err != nil {
+			return err
+		}
+		if meth == nil {
+			return fn(ctx, h)
+		}
 		fields := splitComma(headerVal)
 
 		mask, err := New(meth.Output(), ModeRead, fields...)
@@ -73,29 +392,50 @@ func (c *connectInterceptor) WrapStreamingHandler(fn connect.StreamingHandlerFun
 			)
 		}
 
+		c.reportWarnings(ctx, h.Spec(), mask)
+		state := c.maskState(ctx, h.Spec(), mask)
 		return fn(
-			MaskContext(ctx, mask),
+			maskContext(ctx, state),
 			&pruningConn{
 				StreamingHandlerConn: h,
+				ctx:                  ctx,
+				c:                    c,
 				fm:                   mask,
+				state:                state,
 			},
 		)
 	}
 
 }
 
+// requestPruningConn applies the request pruner to each received message.
+type requestPruningConn struct {
+	connect.StreamingHandlerConn
+	c *connectInterceptor
+}
+
+func (r *requestPruningConn) Receive(msg any) error {
+	if err := r.StreamingHandlerConn.Receive(msg); err != nil {
+		return err
+	}
+	return r.c.pruneRequest(msg)
+}
+
 type pruningConn struct {
 	connect.StreamingHandlerConn
-	fm *FieldMask
+	ctx   context.Context
+	c     *connectInterceptor
+	fm    *FieldMask
+	state *maskState
 }
 
 func (c *pruningConn) Send(msg any) error {
 	pm, ok := msg.(proto.Message)
-	if !ok {
+	if !ok || c.state.takeApplied(pm) {
 		return c.StreamingHandlerConn.Send(msg)
 	}
 
-	err := PruneMessage(pm, c.fm)
+	err := c.c.prune(c.ctx, c.Spec(), pm, c.fm)
 	if err != nil {
 		return err
 	}
@@ -103,18 +443,53 @@ func (c *pruningConn) Send(msg any) error {
 	return c.StreamingHandlerConn.Send(msg)
 }
 
+// itemPruningConn prunes each message sent to the mask returned for it.
+type itemPruningConn struct {
+	connect.StreamingHandlerConn
+	ctx context.Context
+	c   *connectInterceptor
+}
+
+func (c *itemPruningConn) Send(msg any) error {
+	pm, ok := msg.(proto.Message)
+	if !ok {
+		return c.StreamingHandlerConn.Send(msg)
+	}
+	fm := c.c.maskFor(c.ctx, pm)
+	if fm == nil {
+		return c.StreamingHandlerConn.Send(msg)
+	}
+	mask, err := New(pm.ProtoReflect().Descriptor(), ModeRead, fm.GetPaths()...)
+	if err != nil {
+		return connect.NewError(connect.CodeInternal, err)
+	}
+	if err := c.c.prune(c.ctx, c.Spec(), pm, mask); err != nil {
+		return err
+	}
+	return c.StreamingHandlerConn.Send(msg)
+}
+
 // WrapUnary implements connect.Interceptor.
 func (c *connectInterceptor) WrapUnary(fn connect.UnaryFunc) connect.UnaryFunc {
 	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
-		meth, ok := req.Spec().Schema.(protoreflect.MethodDescriptor)
-		if !ok {
+		if !c.applies(req.Spec()) {
 			return fn(ctx, req)
 		}
+		if err := c.pruneRequest(req.Any()); err != nil {
+			return nil, err
+		}
 
-		headerVal := req.Header().Get(c.header)
+		headerVal := c.readMask(req.Header(), req.Peer(), req.HTTPMethod())
 		if headerVal == "" {
 			return fn(ctx, req)
 		}
+		meth, err := c.method(ctx, req.Spec())
+		if err != nil {
+			return nil, err
+		}
+		if meth == nil {
+			return fn(ctx, req)
+		}
 		fields := splitComma(headerVal)
 
 		mask, err := New(meth.Output(), ModeRead, fields...)
@@ -125,17 +500,19 @@ func (c *connectInterceptor) WrapUnary(fn connect.UnaryFunc) connect.UnaryFunc {
 			)
 		}
 
-		rsp, err := fn(MaskContext(ctx, mask), req)
+		c.reportWarnings(ctx, req.Spec(), mask)
+		state := c.maskState(ctx, req.Spec(), mask)
+		rsp, err := fn(maskContext(ctx, state), req)
 		if err != nil {
 			return nil, err
 		}
 
 		pm, ok := rsp.Any().(proto.Message)
-		if !ok {
+		if !ok || state.takeApplied(pm) {
 			return rsp, nil
 		}
 
-		err = PruneMessage(pm, mask)
+		err = c.prune(ctx, req.Spec(), pm, mask)
 		if err != nil {
 			return nil, connect.NewError(
 				connect.CodeInternal,
@@ -2,19 +2,29 @@ package masks_test
 
 import (
 	"context"
+	"errors"
+	"io"
+	"maps"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 
 	"connectrpc.com/connect"
-	"github.com/hxtk/aip/internal/testpb"
-	"github.com/hxtk/aip/internal/testpb/testpbconnect"
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/aiptest/testpb/testpbconnect"
 	"github.com/hxtk/aip/masks"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // fake service with both unary and streaming methods
 type fakeBookService struct {
+	testpbconnect.UnimplementedBookServiceHandler
+
 	testFunc func(ctx context.Context)
 }
 
@@ -49,6 +59,40 @@ func (s *fakeBookService) ListBooks(
 	})
 }
 
+// ImportBooks returns the books received.
+func (s *fakeBookService) ImportBooks(
+	ctx context.Context,
+	stream *connect.ClientStream[testpb.Book],
+) (*connect.Response[testpb.ImportBooksResponse], error) {
+	rsp := &testpb.ImportBooksResponse{}
+	for stream.Receive() {
+		rsp.Books = append(rsp.Books, stream.Msg())
+	}
+	if err := stream.Err(); err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(rsp), nil
+}
+
+// SyncBooks echoes the books received.
+func (s *fakeBookService) SyncBooks(
+	ctx context.Context,
+	stream *connect.BidiStream[testpb.Book, testpb.Book],
+) error {
+	for {
+		book, err := stream.Receive()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(book); err != nil {
+			return err
+		}
+	}
+}
+
 func newTestServer(fn func(ctx context.Context)) *httptest.Server {
 	svc := &fakeBookService{testFunc: fn}
 	mux := http.NewServeMux()
@@ -59,6 +103,25 @@ func newTestServer(fn func(ctx context.Context)) *httptest.Server {
 	return httptest.NewServer(mux)
 }
 
+// newPruningServer serves BookService over HTTP/2, clearing output only
+// fields from requests.
+func newPruningServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.Handle(testpbconnect.NewBookServiceHandler(
+		&fakeBookService{},
+		connect.WithInterceptors(masks.WithReadMaskInterceptor(
+			"x-goog-fieldmask",
+			masks.WithRequestPruner(masks.ClearOutputOnly),
+		)),
+	))
+	srv := httptest.NewUnstartedServer(mux)
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
 func TestUnaryInterceptorE2E(t *testing.T) {
 	srv := newTestServer(nil)
 	defer srv.Close()
@@ -124,6 +187,420 @@ func TestServerStreamInterceptorE2E(t *testing.T) {
 	}
 }
 
+func TestReadMaskSources(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle(testpbconnect.NewBookServiceHandler(
+		&fakeBookService{},
+		connect.WithInterceptors(masks.WithReadMaskInterceptor(
+			"X-Goog-FieldMask",
+			masks.WithFallbackHeaders("X-Fields", "X-Other"),
+			masks.WithQueryParameter("fields"),
+		)),
+	))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	// get sends GetBook as a Connect GET request with the given read mask
+	// headers and query, returning the book received.
+	get := func(t *testing.T, header http.Header, query url.Values) *testpb.Book {
+		t.Helper()
+		if query == nil {
+			query = url.Values{}
+		}
+		query.Set("encoding", "json")
+		query.Set("message", `{"name":"books/1"}`)
+		req, err := http.NewRequest(http.MethodGet, srv.URL+testpbconnect.BookServiceGetBookProcedure+"?"+query.Encode(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		maps.Copy(req.Header, header)
+		rsp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rsp.Body.Close()
+		body, err := io.ReadAll(rsp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rsp.StatusCode != http.StatusOK {
+			t.Fatalf("got status %d: %s", rsp.StatusCode, body)
+		}
+		book := &testpb.Book{}
+		if err := protojson.Unmarshal(body, book); err != nil {
+			t.Fatal(err)
+		}
+		return book
+	}
+
+	cases := []struct {
+		name   string
+		header http.Header
+		query  url.Values
+		want   *testpb.Book
+	}{
+		{
+			name:   "first header wins",
+			header: http.Header{"X-Goog-Fieldmask": {"title"}, "X-Fields": {"name"}},
+			query:  url.Values{"fields": {"name"}},
+			want:   &testpb.Book{Title: "keep"},
+		},
+		{
+			name:   "fallback header",
+			header: http.Header{"X-Other": {"title"}},
+			want:   &testpb.Book{Title: "keep"},
+		},
+		{
+			name:   "fallback headers in order",
+			header: http.Header{"X-Fields": {"name"}, "X-Other": {"title"}},
+			want:   &testpb.Book{Name: "drop"},
+		},
+		{
+			name:  "query parameter",
+			query: url.Values{"fields": {"title,author.given_name"}},
+			want:  &testpb.Book{Title: "keep", Author: &testpb.Author{GivenName: "keep"}},
+		},
+		{
+			name: "no read mask",
+			want: &testpb.Book{
+				Title:  "keep",
+				Name:   "drop",
+				Author: &testpb.Author{GivenName: "keep", FamilyName: "drop"},
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := get(t, tc.header, tc.query); !proto.Equal(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMethodMatchers(t *testing.T) {
+	cases := []struct {
+		name string
+		opts []masks.InterceptorOption
+		want bool
+	}{
+		{"all methods by default", nil, true},
+		{"allowed procedure", []masks.InterceptorOption{
+			masks.WithMethods(masks.Procedures(testpbconnect.BookServiceGetBookProcedure)),
+		}, true},
+		{"procedure not allowed", []masks.InterceptorOption{
+			masks.WithMethods(masks.Procedures(testpbconnect.BookServiceListBooksProcedure)),
+		}, false},
+		{"denied procedure", []masks.InterceptorOption{
+			masks.WithoutMethods(masks.Procedures(testpbconnect.BookServiceGetBookProcedure)),
+		}, false},
+		{"denied request type", []masks.InterceptorOption{
+			masks.WithoutMethods(masks.RequestTypes("test.GetBookRequest")),
+		}, false},
+		{"other request type denied", []masks.InterceptorOption{
+			masks.WithoutMethods(masks.RequestTypes("test.ListBooksRequest")),
+		}, true},
+		{"denylist overrides allowlist", []masks.InterceptorOption{
+			masks.WithMethods(masks.RequestTypes("test.GetBookRequest")),
+			masks.WithoutMethods(masks.Procedures(testpbconnect.BookServiceGetBookProcedure)),
+		}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.Handle(testpbconnect.NewBookServiceHandler(
+				&fakeBookService{},
+				connect.WithInterceptors(masks.WithReadMaskInterceptor("x-goog-fieldmask", tc.opts...)),
+			))
+			srv := httptest.NewServer(mux)
+			defer srv.Close()
+			client := testpbconnect.NewBookServiceClient(http.DefaultClient, srv.URL)
+
+			req := connect.NewRequest(&testpb.GetBookRequest{Name: "book1"})
+			req.Header().Set("x-goog-fieldmask", "title")
+			rsp, err := client.GetBook(context.Background(), req)
+			if err != nil {
+				t.Fatalf("GetBook failed: %v", err)
+			}
+			if pruned := rsp.Msg.Name == ""; pruned != tc.want {
+				t.Errorf("got pruned %v, want %v", pruned, tc.want)
+			}
+		})
+	}
+}
+
+func TestPruneStatsInterceptor(t *testing.T) {
+	reported := make(chan masks.PruneStats, 2)
+	mux := http.NewServeMux()
+	mux.Handle(testpbconnect.NewBookServiceHandler(
+		&fakeBookService{},
+		connect.WithInterceptors(masks.WithReadMaskInterceptor(
+			"x-goog-fieldmask",
+			masks.WithPruneStats(func(_ context.Context, _ connect.Spec, stats masks.PruneStats) {
+				reported <- stats
+			}),
+		)),
+	))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	client := testpbconnect.NewBookServiceClient(http.DefaultClient, srv.URL)
+
+	req := connect.NewRequest(&testpb.GetBookRequest{Name: "book1"})
+	req.Header().Set("x-goog-fieldmask", "title,author.given_name")
+	if _, err := client.GetBook(context.Background(), req); err != nil {
+		t.Fatalf("GetBook failed: %v", err)
+	}
+	streamReq := connect.NewRequest(&testpb.ListBooksRequest{})
+	streamReq.Header().Set("x-goog-fieldmask", "title,author.given_name")
+	stream, err := client.ListBooks(context.Background(), streamReq)
+	if err != nil {
+		t.Fatalf("ListBooks failed: %v", err)
+	}
+	for stream.Receive() {
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+
+	// title, author and author.given_name are kept; name and
+	// author.family_name are cleared.
+	for range 2 {
+		stats := <-reported
+		if stats.Messages != 1 || stats.FieldsKept != 3 || stats.FieldsCleared != 2 || stats.BytesSaved <= 0 {
+			t.Errorf("unexpected stats %+v", stats)
+		}
+	}
+}
+
+// applyingBookService prunes its responses itself with ApplyToResponse.
+type applyingBookService struct {
+	testpbconnect.UnimplementedBookServiceHandler
+
+	t *testing.T
+}
+
+// book returns a Book pruned to the read mask in ctx.
+func (s *applyingBookService) book(ctx context.Context) *testpb.Book {
+	book := &testpb.Book{
+		Title:  "keep",
+		Name:   "drop",
+		Author: &testpb.Author{GivenName: "keep", FamilyName: "drop"},
+	}
+	if err := masks.ApplyToResponse(ctx, book); err != nil {
+		s.t.Errorf("ApplyToResponse failed: %v", err)
+	}
+	if book.GetName() != "" || book.GetAuthor().GetFamilyName() != "" {
+		s.t.Errorf("expected handler to see pruned book, got %v", book)
+	}
+	return book
+}
+
+func (s *applyingBookService) GetBook(
+	ctx context.Context,
+	req *connect.Request[testpb.GetBookRequest],
+) (*connect.Response[testpb.Book], error) {
+	return connect.NewResponse(s.book(ctx)), nil
+}
+
+func (s *applyingBookService) ListBooks(
+	ctx context.Context,
+	req *connect.Request[testpb.ListBooksRequest],
+	stream *connect.ServerStream[testpb.Book],
+) error {
+	if err := stream.Send(s.book(ctx)); err != nil {
+		return err
+	}
+	// Messages not passed to ApplyToResponse are still pruned.
+	return stream.Send(&testpb.Book{Title: "keep", Name: "drop"})
+}
+
+func TestApplyToResponse(t *testing.T) {
+	reported := make(chan masks.PruneStats, 4)
+	mux := http.NewServeMux()
+	mux.Handle(testpbconnect.NewBookServiceHandler(
+		&applyingBookService{t: t},
+		connect.WithInterceptors(masks.WithReadMaskInterceptor(
+			"x-goog-fieldmask",
+			masks.WithPruneStats(func(_ context.Context, _ connect.Spec, stats masks.PruneStats) {
+				reported <- stats
+			}),
+		)),
+	))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	client := testpbconnect.NewBookServiceClient(http.DefaultClient, srv.URL)
+
+	req := connect.NewRequest(&testpb.GetBookRequest{Name: "book1"})
+	req.Header().Set("x-goog-fieldmask", "title,author.given_name")
+	res, err := client.GetBook(context.Background(), req)
+	if err != nil {
+		t.Fatalf("GetBook failed: %v", err)
+	}
+	if res.Msg.Title != "keep" || res.Msg.Name != "" {
+		t.Errorf("unexpected book %v", res.Msg)
+	}
+
+	streamReq := connect.NewRequest(&testpb.ListBooksRequest{})
+	streamReq.Header().Set("x-goog-fieldmask", "title")
+	stream, err := client.ListBooks(context.Background(), streamReq)
+	if err != nil {
+		t.Fatalf("ListBooks failed: %v", err)
+	}
+	for stream.Receive() {
+		if book := stream.Msg(); book.Title != "keep" || book.Name != "" || book.Author != nil {
+			t.Errorf("unexpected book %v", book)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+
+	// Each message is pruned exactly once: by the handler for the two
+	// passed to ApplyToResponse, by the interceptor for the other.
+	if n := len(reported); n != 3 {
+		t.Errorf("expected 3 messages pruned, got %d", n)
+	}
+
+	// Without a mask in the context, ApplyToResponse does nothing.
+	book := &testpb.Book{Name: "keep"}
+	if err := masks.ApplyToResponse(context.Background(), book); err != nil || book.Name != "keep" {
+		t.Errorf("ApplyToResponse without mask = %v, %v", err, book)
+	}
+}
+
+func TestItemMasks(t *testing.T) {
+	cases := []struct {
+		name     string
+		header   string
+		itemMask []string
+		want     *testpb.Book
+		code     connect.Code
+	}{
+		{
+			name:     "item mask only",
+			itemMask: []string{"title", "author"},
+			want: &testpb.Book{
+				Title:  "keep",
+				Author: &testpb.Author{GivenName: "keep", FamilyName: "drop"},
+			},
+		},
+		{
+			name:     "item and read masks",
+			header:   "title,author.given_name",
+			itemMask: []string{"name", "author"},
+			want:     &testpb.Book{Author: &testpb.Author{GivenName: "keep"}},
+		},
+		{
+			name:   "nil item mask",
+			header: "title",
+			want:   &testpb.Book{Title: "keep"},
+		},
+		{
+			name:     "invalid item mask",
+			itemMask: []string{"title.foo"},
+			code:     connect.CodeInternal,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mux := http.NewServeMux()
+			mux.Handle(testpbconnect.NewBookServiceHandler(
+				&fakeBookService{},
+				connect.WithInterceptors(masks.WithReadMaskInterceptor(
+					"x-goog-fieldmask",
+					masks.WithItemMasks(func(_ context.Context, msg proto.Message) *fieldmaskpb.FieldMask {
+						if tc.itemMask == nil {
+							return nil
+						}
+						return &fieldmaskpb.FieldMask{Paths: tc.itemMask}
+					}),
+				)),
+			))
+			srv := httptest.NewServer(mux)
+			defer srv.Close()
+			client := testpbconnect.NewBookServiceClient(http.DefaultClient, srv.URL)
+
+			req := connect.NewRequest(&testpb.ListBooksRequest{})
+			if tc.header != "" {
+				req.Header().Set("x-goog-fieldmask", tc.header)
+			}
+			stream, err := client.ListBooks(context.Background(), req)
+			if err != nil {
+				t.Fatalf("ListBooks failed: %v", err)
+			}
+			var got []*testpb.Book
+			for stream.Receive() {
+				got = append(got, stream.Msg())
+			}
+			if tc.code != 0 {
+				if code := connect.CodeOf(stream.Err()); code != tc.code {
+					t.Fatalf("got code %v, want %v", code, tc.code)
+				}
+				return
+			}
+			if err := stream.Err(); err != nil {
+				t.Fatalf("stream error: %v", err)
+			}
+			if len(got) != 1 || !proto.Equal(got[0], tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestClientStreamRequestPruning(t *testing.T) {
+	srv := newPruningServer(t)
+	client := testpbconnect.NewBookServiceClient(srv.Client(), srv.URL)
+
+	stream := client.ImportBooks(context.Background())
+	for _, title := range []string{"a", "b"} {
+		if err := stream.Send(&testpb.Book{Title: title, CreateTime: timestamppb.Now()}); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+	}
+	res, err := stream.CloseAndReceive()
+	if err != nil {
+		t.Fatalf("ImportBooks failed: %v", err)
+	}
+
+	if len(res.Msg.Books) != 2 {
+		t.Fatalf("expected 2 books received, got %d", len(res.Msg.Books))
+	}
+	for i, book := range res.Msg.Books {
+		if book.Title == "" {
+			t.Errorf("books[%d]: expected Title kept", i)
+		}
+		if book.CreateTime != nil {
+			t.Errorf("books[%d]: expected output only CreateTime cleared, got %v", i, book.CreateTime)
+		}
+	}
+}
+
+func TestBidiStreamRequestPruning(t *testing.T) {
+	srv := newPruningServer(t)
+	client := testpbconnect.NewBookServiceClient(srv.Client(), srv.URL)
+
+	stream := client.SyncBooks(context.Background())
+	req := &testpb.Book{Title: "keep", CreateTime: timestamppb.Now()}
+	if err := stream.Send(req); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	book, err := stream.Receive()
+	if err != nil {
+		t.Fatalf("Receive failed: %v", err)
+	}
+	if book.Title != "keep" || book.CreateTime != nil {
+		t.Errorf("expected output only CreateTime cleared, got %v", book)
+	}
+	if err := stream.CloseRequest(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Receive(); !errors.Is(err, io.EOF) {
+		t.Errorf("expected EOF, got %v", err)
+	}
+}
+
 func TestHasField(t *testing.T) {
 	cases := []struct {
 		name string
@@ -177,3 +654,31 @@ func TestHasField(t *testing.T) {
 		})
 	}
 }
+
+func TestRequested(t *testing.T) {
+	desc := (&testpb.Book{}).ProtoReflect().Descriptor()
+	mask, err := masks.New(desc, masks.ModeRead, "title", "author.given_name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := masks.MaskContext(context.Background(), mask)
+
+	cases := []struct {
+		ctx   context.Context
+		paths []string
+		want  bool
+	}{
+		{ctx, []string{"title"}, true},
+		{ctx, []string{"page_count"}, false},
+		{ctx, []string{"page_count", "author"}, true},
+		{ctx, []string{"author.family_name"}, false},
+		{ctx, nil, false},
+		{context.Background(), []string{"page_count"}, true},
+		{context.Background(), nil, true},
+	}
+	for _, tc := range cases {
+		if got := masks.Requested(tc.ctx, tc.paths...); got != tc.want {
+			t.Errorf("Requested(%q) = %v, want %v", tc.paths, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package masks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"connectrpc.com/grpcreflect"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// MethodResolver finds the descriptor of an RPC method by its procedure
+// name, e.g. "/library.v1.BookService/GetBook".
+//
+// The read mask interceptor uses a MethodResolver for handlers registered
+// without a schema, such as those of a generic proxy.
+type MethodResolver interface {
+	ResolveMethod(ctx context.Context, procedure string) (protoreflect.MethodDescriptor, error)
+}
+
+// ReflectionResolver is a MethodResolver which downloads descriptors from a
+// gRPC server reflection service, such as that of the upstream server of a
+// proxy. The descriptors of each service are downloaded once and cached.
+type ReflectionResolver struct {
+	client *grpcreflect.Client
+
+	mu       sync.Mutex
+	services map[protoreflect.FullName]protoreflect.ServiceDescriptor
+}
+
+var _ MethodResolver = (*ReflectionResolver)(nil)
+
+// NewReflectionResolver returns a ReflectionResolver using client.
+func NewReflectionResolver(client *grpcreflect.Client) *ReflectionResolver {
+	return &ReflectionResolver{
+		client:   client,
+		services: make(map[protoreflect.FullName]protoreflect.ServiceDescriptor),
+	}
+}
+
+// ResolveMethod implements MethodResolver.
+//
+// Failures to reach the reflection service are not cached, so a later call
+// retries the download.
+func (r *ReflectionResolver) ResolveMethod(ctx context.Context, procedure string) (protoreflect.MethodDescriptor, error) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(procedure, "/"), "/")
+	if !ok || service == "" || method == "" {
+		return nil, fmt.Errorf("malformed procedure %q", procedure)
+	}
+
+	sd, err := r.service(ctx, protoreflect.FullName(service))
+	if err != nil {
+		return nil, err
+	}
+	md := sd.Methods().ByName(protoreflect.Name(method))
+	if md == nil {
+		return nil, fmt.Errorf("method %s not found in service %s", method, service)
+	}
+	return md, nil
+}
+
+// service returns the descriptor of the named service, downloading it if it
+// is not cached.
+func (r *ReflectionResolver) service(ctx context.Context, name protoreflect.FullName) (protoreflect.ServiceDescriptor, error) {
+	r.mu.Lock()
+	sd, ok := r.services[name]
+	r.mu.Unlock()
+	if ok {
+		return sd, nil
+	}
+
+	stream := r.client.NewStream(ctx)
+	fdps, err := stream.FileContainingSymbol(name)
+	_, _ = stream.Close()
+	if err != nil {
+		return nil, fmt.Errorf("resolving %s by reflection: %w", name, err)
+	}
+	files, err := newFiles(fdps)
+	if err != nil {
+		return nil, fmt.Errorf("resolving %s by reflection: %w", name, err)
+	}
+	desc, err := files.FindDescriptorByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("resolving %s by reflection: %w", name, err)
+	}
+	sd, ok = desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", name)
+	}
+
+	r.mu.Lock()
+	r.services[name] = sd
+	r.mu.Unlock()
+	return sd, nil
+}
+
+// newFiles builds a registry of fdps. Dependencies the reflection service
+// omitted, typically well-known types, are taken from the global registry.
+func newFiles(fdps []*descriptorpb.FileDescriptorProto) (*protoregistry.Files, error) {
+	set := &descriptorpb.FileDescriptorSet{File: fdps}
+	have := make(map[string]bool, len(fdps))
+	for _, fdp := range fdps {
+		have[fdp.GetName()] = true
+	}
+	for i := 0; i < len(set.File); i++ {
+		for _, dep := range set.File[i].GetDependency() {
+			if have[dep] {
+				continue
+			}
+			fd, err := protoregistry.GlobalFiles.FindFileByPath(dep)
+			if err != nil {
+				return nil, fmt.Errorf("missing dependency %s of %s", dep, set.File[i].GetName())
+			}
+			set.File = append(set.File, protodesc.ToFileDescriptorProto(fd))
+			have[dep] = true
+		}
+	}
+	return protodesc.NewFiles(set)
+}
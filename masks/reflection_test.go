@@ -0,0 +1,137 @@
+package masks_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"connectrpc.com/connect"
+	"connectrpc.com/grpcreflect"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/aiptest/testpb/testpbconnect"
+	"github.com/hxtk/aip/masks"
+)
+
+// newReflectionServer serves the reflection service for BookService, and
+// counts the reflection streams opened.
+func newReflectionServer(t *testing.T, streams *atomic.Int32) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	path, handler := grpcreflect.NewHandlerV1(grpcreflect.NewStaticReflector(testpbconnect.BookServiceName))
+	mux.Handle(path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		streams.Add(1)
+		handler.ServeHTTP(w, r)
+	}))
+	srv := httptest.NewUnstartedServer(mux)
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newProxyServer serves GetBook without a schema, as a generic proxy would.
+func newProxyServer(t *testing.T, resolver masks.MethodResolver) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.Handle(testpbconnect.BookServiceGetBookProcedure, connect.NewUnaryHandler(
+		testpbconnect.BookServiceGetBookProcedure,
+		func(ctx context.Context, req *connect.Request[testpb.GetBookRequest]) (*connect.Response[testpb.Book], error) {
+			return connect.NewResponse(&testpb.Book{
+				Title:  "keep",
+				Name:   "drop",
+				Author: &testpb.Author{GivenName: "keep", FamilyName: "drop"},
+			}), nil
+		},
+		connect.WithInterceptors(masks.WithReadMaskInterceptor("x-goog-fieldmask", masks.WithMethodResolver(resolver))),
+	))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func getBook(t *testing.T, srv *httptest.Server, mask string) (*testpb.Book, error) {
+	t.Helper()
+	client := connect.NewClient[testpb.GetBookRequest, testpb.Book](
+		http.DefaultClient,
+		srv.URL+testpbconnect.BookServiceGetBookProcedure,
+	)
+	req := connect.NewRequest(&testpb.GetBookRequest{Name: "book1"})
+	req.Header().Set("x-goog-fieldmask", mask)
+	res, err := client.CallUnary(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+	return res.Msg, nil
+}
+
+func TestReflectionResolver(t *testing.T) {
+	var streams atomic.Int32
+	reflection := newReflectionServer(t, &streams)
+	resolver := masks.NewReflectionResolver(grpcreflect.NewClient(reflection.Client(), reflection.URL))
+	proxy := newProxyServer(t, resolver)
+
+	book, err := getBook(t, proxy, "title,author.given_name")
+	if err != nil {
+		t.Fatalf("GetBook failed: %v", err)
+	}
+	if book.Title == "" || book.Author.GivenName == "" {
+		t.Errorf("expected Title and Author.GivenName kept, got %v", book)
+	}
+	if book.Name != "" || book.Author.FamilyName != "" {
+		t.Errorf("expected Name and Author.FamilyName cleared, got %v", book)
+	}
+
+	_, err = getBook(t, proxy, "title.subtitle")
+	if got := connect.CodeOf(err); got != connect.CodeInvalidArgument {
+		t.Errorf("invalid mask: got code %v, want %v", got, connect.CodeInvalidArgument)
+	}
+
+	if got := streams.Load(); got != 1 {
+		t.Errorf("reflection streams = %d, want descriptors cached after 1", got)
+	}
+}
+
+func TestReflectionResolver_Errors(t *testing.T) {
+	var streams atomic.Int32
+	reflection := newReflectionServer(t, &streams)
+	resolver := masks.NewReflectionResolver(grpcreflect.NewClient(reflection.Client(), reflection.URL))
+	ctx := context.Background()
+
+	if _, err := resolver.ResolveMethod(ctx, "/test.BookService/PublishBook"); err == nil {
+		t.Error("expected error for unknown method")
+	}
+	if _, err := resolver.ResolveMethod(ctx, "/test.ShelfService/GetShelf"); err == nil {
+		t.Error("expected error for unknown service")
+	}
+	if _, err := resolver.ResolveMethod(ctx, "GetBook"); err == nil {
+		t.Error("expected error for malformed procedure")
+	}
+
+	md, err := resolver.ResolveMethod(ctx, testpbconnect.BookServiceListBooksProcedure)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := md.Output().FullName(); got != "test.Book" {
+		t.Errorf("ListBooks output = %s, want test.Book", got)
+	}
+}
+
+func TestReflectionResolver_Unavailable(t *testing.T) {
+	resolver := masks.NewReflectionResolver(grpcreflect.NewClient(http.DefaultClient, "http://127.0.0.1:1"))
+	proxy := newProxyServer(t, resolver)
+
+	_, err := getBook(t, proxy, "title")
+	var connectErr *connect.Error
+	if !errors.As(err, &connectErr) || connectErr.Code() != connect.CodeUnavailable {
+		t.Errorf("got %v, want CodeUnavailable", err)
+	}
+
+	// Requests without a read mask need no descriptor.
+	if _, err := getBook(t, proxy, ""); err != nil {
+		t.Errorf("GetBook without mask: %v", err)
+	}
+}
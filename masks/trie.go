@@ -1,10 +1,25 @@
 package masks
 
-import "google.golang.org/protobuf/reflect/protoreflect"
+import (
+	"slices"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
 
 type FieldMask struct {
 	desc protoreflect.MessageDescriptor
 	trie *maskTrie
+	// warnings are the paths ignored by New.
+	warnings []Warning
+}
+
+// Warnings returns a Warning for each path of the mask naming a field that
+// does not exist, which New ignored under ModeRead.
+func (t *FieldMask) Warnings() []Warning {
+	if t == nil {
+		return nil
+	}
+	return slices.Clone(t.warnings)
 }
 
 // HasPath optimistically checks to see if a path exists in a FieldMask.
@@ -30,31 +45,30 @@ func hasPath(desc protoreflect.MessageDescriptor, trie *maskTrie, parts []string
 	wildTrie := trie.children["*"]
 	switch {
 	case subTrie != nil:
-		fd := desc.Fields().ByName(protoreflect.Name(part))
-		elementTrie := subTrie
-		if subTrie.children != nil {
-			if star := subTrie.children["*"]; star != nil {
-				elementTrie = star
-			}
+		fd := fieldBySegment(desc, part)
+		if fd == nil {
+			// Read masks may name fields unknown to desc.
+			return true
+		}
+		elementTrie := subTrie.elementTrie()
+		if len(parts) < 2 || len(elementTrie.children) == 0 {
+			// The mask selects the whole field, or its whole elements.
+			return true
 		}
 
 		if fd.Kind() == protoreflect.MessageKind {
 			// descend into message(s)
-			if fd.IsList() {
-				if len(parts) < 2 {
-					return true
-				}
-				return hasPath(fd.Message(), elementTrie, parts[1:])
-			} else if fd.IsMap() {
+			if fd.IsMap() {
 				// TODO: false positives are okay, optimize them away later
 				return true
-			} else {
-				if len(parts) < 2 {
+			}
+			rest := parts[1:]
+			if fd.IsList() && rest[0] == "*" {
+				if rest = rest[1:]; len(rest) == 0 {
 					return true
 				}
-				sub := fd.Message()
-				return hasPath(sub, elementTrie, parts[1:])
 			}
+			return hasPath(fd.Message(), elementTrie, rest)
 		}
 
 	case wildTrie != nil:
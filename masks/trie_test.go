@@ -3,7 +3,7 @@ package masks_test
 import (
 	"testing"
 
-	"github.com/hxtk/aip/internal/testpb"
+	"github.com/hxtk/aip/aiptest/testpb"
 	"github.com/hxtk/aip/masks"
 )
 
@@ -16,7 +16,16 @@ func TestHasPath(t *testing.T) {
 	}
 
 	desc := new(testpb.Book).ProtoReflect().Descriptor()
-	tcs := []testCase{}
+	tcs := []testCase{
+		{"listed field", []string{"title"}, "title", true},
+		{"unlisted field", []string{"title"}, "name", false},
+		{"subfield of listed message", []string{"author"}, "author.given_name", true},
+		{"element field under wildcard", []string{"authors.*"}, "authors.given_name", true},
+		{"wildcard element field", []string{"authors.given_name"}, "authors.*.given_name", true},
+		{"unlisted element field", []string{"authors.*.given_name"}, "authors.family_name", false},
+		{"repeated scalar wildcard", []string{"tags.*"}, "tags", true},
+		{"unknown field", []string{"nope"}, "nope", true},
+	}
 	for _, tc := range tcs {
 		t.Run(tc.name, func(t *testing.T) {
 			mask, err := masks.New(desc, masks.ModeRead, tc.paths...)
@@ -26,6 +35,7 @@ func TestHasPath(t *testing.T) {
 
 			got := mask.HasPath(tc.path)
 			if tc.want != got {
+				t.Errorf("mask.HasPath(%q) = %v, want %v", tc.path, got, tc.want)
 			}
 		})
 	}
@@ -0,0 +1,152 @@
+// Package names implements helpers for the resource IDs of AIP-122 resource
+// names, as set by clients of AIP-133 Create methods.
+package names
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// DefaultMaxIDLength is the maximum length of a resource ID, unless changed
+// with WithMaxLength. It is the length limit of a DNS label (RFC-1034),
+// whose syntax resource IDs follow.
+const DefaultMaxIDLength = 63
+
+// ErrInvalidID is matched by errors.Is for the errors of ValidateResourceID.
+var ErrInvalidID = errors.New("invalid resource ID")
+
+// IDOption configures the validation and generation of resource IDs.
+type IDOption func(*idOptions)
+
+type idOptions struct {
+	maxLength int
+	uuid4     bool
+	generate  func() string
+}
+
+func newIDOptions(opts []IDOption) *idOptions {
+	o := &idOptions{maxLength: DefaultMaxIDLength, generate: NewUUID4}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithMaxLength sets the maximum length of resource IDs, which defaults to
+// DefaultMaxIDLength.
+func WithMaxLength(n int) IDOption {
+	return func(o *idOptions) {
+		o.maxLength = n
+	}
+}
+
+// WithUUID4 requires resource IDs to be UUID4s in canonical lower-case form,
+// e.g. "f47ac10b-58cc-4372-a567-0e02b2c3d479", for resources whose ID field
+// is annotated with (google.api.field_info).format = UUID4.
+func WithUUID4() IDOption {
+	return func(o *idOptions) {
+		o.uuid4 = true
+	}
+}
+
+// WithGenerator sets the function generating the IDs of resources created
+// without one by ResourceID, which defaults to NewUUID4.
+func WithGenerator(fn func() string) IDOption {
+	return func(o *idOptions) {
+		o.generate = fn
+	}
+}
+
+// ValidateResourceID checks that id is a valid user-settable resource ID
+// (AIP-122): it starts with a lower-case letter, holds only lower-case
+// letters, digits and hyphens, ends with a letter or digit, and is at most
+// DefaultMaxIDLength characters long. With WithUUID4, id must instead be a
+// UUID4.
+//
+// The error wraps ErrInvalidID; Create handlers should report it as an
+// invalid argument of the request's ID field, e.g. book_id.
+func ValidateResourceID(id string, opts ...IDOption) error {
+	o := newIDOptions(opts)
+	if o.uuid4 {
+		if !isUUID4(id) {
+			return fmt.Errorf("%w: %q is not a lower-case UUID4", ErrInvalidID, id)
+		}
+		return nil
+	}
+
+	switch {
+	case id == "":
+		return fmt.Errorf("%w: empty", ErrInvalidID)
+	case len(id) > o.maxLength:
+		return fmt.Errorf("%w: %q is longer than %d characters", ErrInvalidID, id, o.maxLength)
+	case !isLower(id[0]):
+		return fmt.Errorf("%w: %q must start with a lower-case letter", ErrInvalidID, id)
+	case id[len(id)-1] == '-':
+		return fmt.Errorf("%w: %q must end with a letter or digit", ErrInvalidID, id)
+	}
+	for i := 0; i < len(id); i++ {
+		if c := id[i]; !isLower(c) && !isDigit(c) && c != '-' {
+			return fmt.Errorf("%w: %q contains %q, only lower-case letters, digits and hyphens are allowed", ErrInvalidID, id, rune(c))
+		}
+	}
+	return nil
+}
+
+// ResourceID returns the ID of a resource to create: id if the client set
+// one, after checking it with ValidateResourceID, or else a new ID from the
+// generator, a UUID4 by default (AIP-133).
+//
+// Example:
+//
+//	id, err := names.ResourceID(req.GetBookId())
+//	if err != nil {
+//		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+//	}
+//	book.Name = "books/" + id
+func ResourceID(id string, opts ...IDOption) (string, error) {
+	if id == "" {
+		return newIDOptions(opts).generate(), nil
+	}
+	if err := ValidateResourceID(id, opts...); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// NewUUID4 returns a new random UUID4 in canonical lower-case form.
+func NewUUID4() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40 // Version 4.
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant.
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// isUUID4 reports whether s is a UUID4 in canonical lower-case form.
+func isUUID4(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if s[i] != '-' {
+				return false
+			}
+		default:
+			if !isDigit(s[i]) && (s[i] < 'a' || s[i] > 'f') {
+				return false
+			}
+		}
+	}
+	return s[14] == '4' && (s[19] == '8' || s[19] == '9' || s[19] == 'a' || s[19] == 'b')
+}
+
+func isLower(c byte) bool {
+	return c >= 'a' && c <= 'z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
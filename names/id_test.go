@@ -0,0 +1,89 @@
+package names_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hxtk/aip/names"
+)
+
+func TestValidateResourceID(t *testing.T) {
+	tests := []struct {
+		id    string
+		valid bool
+	}{
+		{"pride-and-prejudice", true},
+		{"b", true},
+		{"book42", true},
+		{strings.Repeat("a", 63), true},
+		{strings.Repeat("a", 64), false},
+		{"", false},
+		{"42books", false},
+		{"-book", false},
+		{"book-", false},
+		{"Book", false},
+		{"my_book", false},
+		{"my.book", false},
+		{"bücher", false},
+	}
+	for _, tc := range tests {
+		err := names.ValidateResourceID(tc.id)
+		if tc.valid && err != nil {
+			t.Errorf("ValidateResourceID(%q) = %v, want nil", tc.id, err)
+		}
+		if !tc.valid && !errors.Is(err, names.ErrInvalidID) {
+			t.Errorf("ValidateResourceID(%q) = %v, want ErrInvalidID", tc.id, err)
+		}
+	}
+
+	if err := names.ValidateResourceID("abcdef", names.WithMaxLength(5)); err == nil {
+		t.Error("ValidateResourceID longer than WithMaxLength: got nil error")
+	}
+}
+
+func TestValidateResourceID_UUID4(t *testing.T) {
+	tests := []struct {
+		id    string
+		valid bool
+	}{
+		{"f47ac10b-58cc-4372-a567-0e02b2c3d479", true},
+		{"F47AC10B-58CC-4372-A567-0E02B2C3D479", false},
+		{"f47ac10b-58cc-1372-a567-0e02b2c3d479", false},
+		{"f47ac10b-58cc-4372-c567-0e02b2c3d479", false},
+		{"f47ac10b58cc4372a5670e02b2c3d479", false},
+		{"pride-and-prejudice", false},
+	}
+	for _, tc := range tests {
+		err := names.ValidateResourceID(tc.id, names.WithUUID4())
+		if tc.valid != (err == nil) {
+			t.Errorf("ValidateResourceID(%q, WithUUID4()) = %v, want valid %t", tc.id, err, tc.valid)
+		}
+	}
+}
+
+func TestResourceID(t *testing.T) {
+	id, err := names.ResourceID("emma")
+	if err != nil || id != "emma" {
+		t.Errorf(`ResourceID("emma") = %q, %v, want "emma"`, id, err)
+	}
+	if _, err := names.ResourceID("Emma"); !errors.Is(err, names.ErrInvalidID) {
+		t.Errorf(`ResourceID("Emma") = %v, want ErrInvalidID`, err)
+	}
+
+	id, err = names.ResourceID("")
+	if err != nil {
+		t.Fatalf(`ResourceID("") failed: %v`, err)
+	}
+	if err := names.ValidateResourceID(id, names.WithUUID4()); err != nil {
+		t.Errorf(`ResourceID("") = %q, not a UUID4: %v`, id, err)
+	}
+	if other, _ := names.ResourceID(""); other == id {
+		t.Errorf(`ResourceID("") returned %q twice`, id)
+	}
+
+	id, _ = names.ResourceID("", names.WithGenerator(func() string { return "generated" }))
+	if id != "generated" {
+		t.Errorf("ResourceID with WithGenerator = %q, want %q", id, "generated")
+	}
+}
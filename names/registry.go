@@ -0,0 +1,132 @@
+package names
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// ErrInvalidReference is matched by errors.Is for the errors of
+// Registry.ValidateReference.
+var ErrInvalidReference = errors.New("invalid resource reference")
+
+// Registry holds the name patterns of resource types (AIP-123), as declared
+// with the google.api.resource option of messages and the
+// google.api.resource_definition option of files.
+type Registry struct {
+	patterns map[string][]string
+}
+
+// NewRegistry returns a registry of the resource types declared in files,
+// or in protoregistry.GlobalFiles if files is nil.
+func NewRegistry(files *protoregistry.Files) *Registry {
+	if files == nil {
+		files = protoregistry.GlobalFiles
+	}
+	r := &Registry{patterns: make(map[string][]string)}
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		defs, _ := proto.GetExtension(fd.Options(), annotations.E_ResourceDefinition).([]*annotations.ResourceDescriptor)
+		for _, def := range defs {
+			r.add(def)
+		}
+		r.addMessages(fd.Messages())
+		return true
+	})
+	return r
+}
+
+func (r *Registry) addMessages(msgs protoreflect.MessageDescriptors) {
+	for i := 0; i < msgs.Len(); i++ {
+		md := msgs.Get(i)
+		if def, ok := proto.GetExtension(md.Options(), annotations.E_Resource).(*annotations.ResourceDescriptor); ok && def != nil {
+			r.add(def)
+		}
+		r.addMessages(md.Messages())
+	}
+}
+
+func (r *Registry) add(def *annotations.ResourceDescriptor) {
+	if def.GetType() == "" {
+		return
+	}
+	r.patterns[def.GetType()] = append(r.patterns[def.GetType()], def.GetPattern()...)
+}
+
+// Patterns returns the name patterns of the resource type typ, e.g.
+// "publishers/{publisher}/books/{book}" for "library.example.com/Book", or
+// nil if the registry holds no such type.
+func (r *Registry) Patterns(typ string) []string {
+	return r.patterns[typ]
+}
+
+// ValidateReference checks that name is a resource name of the type ref
+// refers to: for a type reference, it must match one of the patterns of the
+// type, and for a child_type reference, the parent of one of the patterns of
+// the child type. References to any type ("*") and to types missing from the
+// registry, e.g. those of other services, accept any name.
+//
+// The error wraps ErrInvalidReference.
+func (r *Registry) ValidateReference(ref *annotations.ResourceReference, name string) error {
+	typ, child := ref.GetType(), false
+	if typ == "" {
+		typ, child = ref.GetChildType(), true
+	}
+	patterns, ok := r.patterns[typ]
+	if typ == "" || typ == "*" || !ok {
+		return nil
+	}
+	for _, pattern := range patterns {
+		if child {
+			pattern = ParentPattern(pattern)
+		}
+		if MatchPattern(pattern, name) {
+			return nil
+		}
+	}
+	if child {
+		return fmt.Errorf("%w: %q is not the parent of a resource of type %s", ErrInvalidReference, name, typ)
+	}
+	return fmt.Errorf("%w: %q is not the name of a resource of type %s, expected %s", ErrInvalidReference, name, typ, strings.Join(patterns, " or "))
+}
+
+// ParentPattern returns the pattern of the parents of resources named by
+// pattern, i.e. pattern without its final collection and ID, e.g.
+// "publishers/{publisher}" for "publishers/{publisher}/books/{book}". It is
+// empty for top-level resources.
+func ParentPattern(pattern string) string {
+	segments := strings.Split(pattern, "/")
+	if len(segments) < 2 {
+		return ""
+	}
+	return strings.Join(segments[:len(segments)-2], "/")
+}
+
+// MatchPattern reports whether name matches pattern, whose segments are
+// either literal collection identifiers or variables such as {book}, each
+// matching a single non-empty segment. An empty name matches no pattern.
+func MatchPattern(pattern, name string) bool {
+	if pattern == "" || name == "" {
+		return false
+	}
+	want, got := strings.Split(pattern, "/"), strings.Split(name, "/")
+	if len(want) != len(got) {
+		return false
+	}
+	for i, segment := range want {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if got[i] == "" {
+				return false
+			}
+			continue
+		}
+		if segment != got[i] {
+			return false
+		}
+	}
+	return true
+}
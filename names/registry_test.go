@@ -0,0 +1,79 @@
+package names_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/names"
+)
+
+func TestRegistry(t *testing.T) {
+	_ = testpb.File_testpb_book_proto
+	r := names.NewRegistry(nil)
+
+	if got, want := r.Patterns("library.example.com/Book"), []string{"books/{book}", "publishers/{publisher}/books/{book}"}; !slices.Equal(got, want) {
+		t.Errorf("Patterns(Book) = %q, want %q", got, want)
+	}
+	if got := r.Patterns("library.example.com/Publisher"); !slices.Equal(got, []string{"publishers/{publisher}"}) {
+		t.Errorf("Patterns(Publisher) = %q, want the file-level resource definition", got)
+	}
+	if got := r.Patterns("library.example.com/Magazine"); got != nil {
+		t.Errorf("Patterns(Magazine) = %q, want nil", got)
+	}
+
+	book := &annotations.ResourceReference{Type: "library.example.com/Book"}
+	bookParent := &annotations.ResourceReference{ChildType: "library.example.com/Book"}
+	tests := []struct {
+		ref   *annotations.ResourceReference
+		name  string
+		valid bool
+	}{
+		{book, "books/1", true},
+		{book, "publishers/acme/books/1", true},
+		{book, "books", false},
+		{book, "books/", false},
+		{book, "books/1/chapters/2", false},
+		{book, "shelves/1", false},
+		{book, "publishers//books/1", false},
+		{bookParent, "publishers/acme", true},
+		{bookParent, "publishers/acme/books/1", false},
+		{bookParent, "shelves/1", false},
+		{&annotations.ResourceReference{Type: "*"}, "anything/at/all", true},
+		{&annotations.ResourceReference{Type: "library.example.com/Magazine"}, "anything", true},
+	}
+	for _, tc := range tests {
+		err := r.ValidateReference(tc.ref, tc.name)
+		if tc.valid && err != nil {
+			t.Errorf("ValidateReference(%v, %q) = %v, want nil", tc.ref, tc.name, err)
+		}
+		if !tc.valid && !errors.Is(err, names.ErrInvalidReference) {
+			t.Errorf("ValidateReference(%v, %q) = %v, want ErrInvalidReference", tc.ref, tc.name, err)
+		}
+	}
+}
+
+func TestPatterns(t *testing.T) {
+	pattern := "publishers/{publisher}/books/{book}"
+	if got, want := names.ParentPattern(pattern), "publishers/{publisher}"; got != want {
+		t.Errorf("ParentPattern(%q) = %q, want %q", pattern, got, want)
+	}
+	if got := names.ParentPattern("books/{book}"); got != "" {
+		t.Errorf("ParentPattern of a top-level pattern = %q, want empty", got)
+	}
+	for name, want := range map[string]bool{
+		"publishers/acme/books/dune": true,
+		"publishers/-/books/dune":    true,
+		"publishers/acme/books/":     false,
+		"publishers/acme/shelves/1":  false,
+		"publishers/acme":            false,
+		"":                           false,
+	} {
+		if got := names.MatchPattern(pattern, name); got != want {
+			t.Errorf("MatchPattern(%q, %q) = %t, want %t", pattern, name, got, want)
+		}
+	}
+}
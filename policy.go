@@ -0,0 +1,246 @@
+package aip
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v3"
+
+	"github.com/hxtk/aip/masks"
+	"github.com/hxtk/aip/query"
+)
+
+// OutputOnlyHandling is how a MethodPolicy treats fields annotated with the
+// OUTPUT_ONLY google.api.field_behavior that are set on requests.
+type OutputOnlyHandling string
+
+const (
+	// OutputOnlyKeep passes output only fields to the handler unchanged.
+	OutputOnlyKeep OutputOnlyHandling = ""
+	// OutputOnlyClear clears output only fields before the handler sees
+	// them, as AIP-203 requires servers to ignore them.
+	OutputOnlyClear OutputOnlyHandling = "clear"
+	// OutputOnlyReject rejects requests setting output only fields.
+	OutputOnlyReject OutputOnlyHandling = "reject"
+)
+
+// Policy is a declarative request sanitization policy, so that the rules
+// enforced on an API can be reviewed as data. It may be built in code or
+// loaded from YAML with ParsePolicy or LoadPolicy:
+//
+//	methods:
+//	  /library.v1.BookService/UpdateBook:
+//	    required_fields: [book.title]
+//	    immutable_fields: [isbn]
+//	    output_only: clear
+//	  /library.v1.BookService/ListBooks:
+//	    max_page_size: 100
+//	    allowed_order_by: [title, create_time]
+//
+// Field paths are dot-separated field names. Paths that do not exist on a
+// request are treated as unset.
+type Policy struct {
+	// Methods holds the policy of each method, keyed by procedure name,
+	// e.g. "/library.v1.BookService/UpdateBook".
+	Methods map[string]*MethodPolicy `yaml:"methods"`
+}
+
+// MethodPolicy is the request sanitization policy of a method.
+type MethodPolicy struct {
+	// RequiredFields are the paths of request fields that must be set, in
+	// addition to those annotated REQUIRED.
+	RequiredFields []string `yaml:"required_fields"`
+	// ImmutableFields are the paths of resource fields that may not be
+	// named by the update_mask of an Update request (AIP-134). A mask of
+	// "*" is not checked, since immutable fields may be supplied unchanged
+	// on full replacement.
+	ImmutableFields []string `yaml:"immutable_fields"`
+	// OutputOnly is how output only fields set on requests are treated.
+	OutputOnly OutputOnlyHandling `yaml:"output_only"`
+	// MaxPageSize, if positive, is the largest page_size passed to the
+	// handler; larger values are coerced down to it (AIP-158).
+	MaxPageSize int32 `yaml:"max_page_size"`
+	// AllowedOrderBy, if not empty, holds the only field paths that may be
+	// referenced in order_by.
+	AllowedOrderBy []string `yaml:"allowed_order_by"`
+}
+
+// ParsePolicy parses a YAML policy. Unknown keys are an error, so that
+// misspelled rules are not silently ignored.
+func ParsePolicy(data []byte) (*Policy, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	p := &Policy{}
+	if err := dec.Decode(p); err != nil {
+		return nil, fmt.Errorf("parsing policy: %w", err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// LoadPolicy reads and parses the YAML policy at path.
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePolicy(data)
+}
+
+// Validate returns an error if p is malformed.
+func (p *Policy) Validate() error {
+	for procedure, mp := range p.Methods {
+		if !strings.HasPrefix(procedure, "/") || strings.Count(procedure, "/") != 2 {
+			return fmt.Errorf("policy: malformed procedure %q, expected /package.Service/Method", procedure)
+		}
+		if mp == nil {
+			continue
+		}
+		switch mp.OutputOnly {
+		case OutputOnlyKeep, OutputOnlyClear, OutputOnlyReject:
+		default:
+			return fmt.Errorf("policy: %s: unknown output_only handling %q", procedure, mp.OutputOnly)
+		}
+		if mp.MaxPageSize < 0 {
+			return fmt.Errorf("policy: %s: max_page_size must not be negative", procedure)
+		}
+	}
+	return nil
+}
+
+// method returns the policy of procedure, or nil if there is none.
+func (p *Policy) method(procedure string) *MethodPolicy {
+	if p == nil {
+		return nil
+	}
+	return p.Methods[procedure]
+}
+
+// sanitize applies the rewriting rules of the policy to m, returning a
+// Warning for each value it lowers.
+func (mp *MethodPolicy) sanitize(m protoreflect.Message) []Warning {
+	if mp == nil {
+		return nil
+	}
+	if mp.OutputOnly == OutputOnlyClear {
+		// Decoded requests are within the nesting depth it supports.
+		_ = masks.ClearOutputOnly(m.Interface())
+	}
+	if fd := m.Descriptor().Fields().ByName("page_size"); mp.MaxPageSize > 0 && fd != nil && fd.Kind() == protoreflect.Int32Kind {
+		if size := m.Get(fd).Int(); size > int64(mp.MaxPageSize) {
+			m.Set(fd, protoreflect.ValueOfInt32(mp.MaxPageSize))
+			return []Warning{{
+				Field:       "page_size",
+				Reason:      WarningPageSizeClamped,
+				Description: fmt.Sprintf("page_size %d exceeds the maximum of %d and was lowered to it", size, mp.MaxPageSize),
+			}}
+		}
+	}
+	return nil
+}
+
+// appendViolations appends the violations of the policy by m, the resource
+// of which is in field resource if m is an Update request.
+func (mp *MethodPolicy) appendViolations(violations []Violation, m protoreflect.Message, resource protoreflect.FieldDescriptor) []Violation {
+	if mp == nil {
+		return violations
+	}
+	for _, path := range mp.RequiredFields {
+		if !hasPath(m, strings.Split(path, ".")) {
+			violations = append(violations, Violation{
+				Field:       path,
+				Reason:      ReasonFieldRequired,
+				Description: "required field is not set",
+			})
+		}
+	}
+	if resource != nil && len(mp.ImmutableFields) > 0 {
+		for _, path := range maskPaths(m, m.Descriptor().Fields().ByName("update_mask")) {
+			for _, immutable := range mp.ImmutableFields {
+				if path == immutable || strings.HasPrefix(path, immutable+".") {
+					violations = append(violations, Violation{
+						Field:       "update_mask",
+						Reason:      ReasonFieldImmutable,
+						Description: fmt.Sprintf("field %s is immutable", immutable),
+					})
+				}
+			}
+		}
+	}
+	if mp.OutputOnly == OutputOnlyReject {
+		violations = appendOutputOnlyViolations(violations, m, "")
+	}
+	if text, ok := stringValue(m, query.OrderByField); ok && len(mp.AllowedOrderBy) > 0 {
+		// Malformed orders are reported by appendListViolations.
+		order, _ := query.ParseOrderBy(text)
+		for _, o := range order {
+			if !slices.Contains(mp.AllowedOrderBy, o.FieldPath.String()) {
+				violations = append(violations, Violation{
+					Field:       query.OrderByField,
+					Reason:      ReasonOrderByUnsupportedField,
+					Description: fmt.Sprintf("cannot order by %s, allowed fields are %s", o.FieldPath.String(), strings.Join(mp.AllowedOrderBy, ", ")),
+				})
+			}
+		}
+	}
+	return violations
+}
+
+// hasPath reports whether the field at the path given by segments is set
+// on m.
+func hasPath(m protoreflect.Message, segments []string) bool {
+	for i, seg := range segments {
+		fd := m.Descriptor().Fields().ByName(protoreflect.Name(seg))
+		if fd == nil || !m.Has(fd) {
+			return false
+		}
+		if i == len(segments)-1 {
+			return true
+		}
+		if fd.Message() == nil || fd.IsList() || fd.IsMap() {
+			return false
+		}
+		m = m.Get(fd).Message()
+	}
+	return false
+}
+
+// appendOutputOnlyViolations appends a violation for each field of m
+// annotated OUTPUT_ONLY that is set, recursing into set singular message
+// fields.
+func appendOutputOnlyViolations(violations []Violation, m protoreflect.Message, prefix string) []Violation {
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !m.Has(fd) {
+			continue
+		}
+		path := prefix + string(fd.Name())
+		if hasBehavior(fd, annotations.FieldBehavior_OUTPUT_ONLY) {
+			violations = append(violations, Violation{
+				Field:       path,
+				Reason:      ReasonFieldOutputOnly,
+				Description: "output only field must not be set",
+			})
+			continue
+		}
+		if fd.Message() != nil && !fd.IsList() && !fd.IsMap() {
+			violations = appendOutputOnlyViolations(violations, m.Get(fd).Message(), path+".")
+		}
+	}
+	return violations
+}
+
+// hasBehavior reports whether fd is annotated with the field behavior b.
+func hasBehavior(fd protoreflect.FieldDescriptor, b annotations.FieldBehavior) bool {
+	behaviors, _ := proto.GetExtension(fd.Options(), annotations.E_FieldBehavior).([]annotations.FieldBehavior)
+	return slices.Contains(behaviors, b)
+}
@@ -0,0 +1,118 @@
+package aip_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/hxtk/aip"
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/aiptest/testpb/testpbconnect"
+)
+
+const testPolicy = `
+methods:
+  /test.BookService/UpdateBook:
+    required_fields: [book.title]
+    immutable_fields: [language_code]
+    output_only: reject
+  /test.BookService/ListBooksPage:
+    max_page_size: 10
+    allowed_order_by: [title]
+`
+
+// pageSizeService records the page size of the last ListBooksPage request.
+type pageSizeService struct {
+	fakeBookService
+	pageSize int32
+}
+
+func (s *pageSizeService) ListBooksPage(ctx context.Context, req *connect.Request[testpb.ListBooksRequest]) (*connect.Response[testpb.ListBooksResponse], error) {
+	s.pageSize = req.Msg.GetPageSize()
+	return connect.NewResponse(&testpb.ListBooksResponse{}), nil
+}
+
+func TestPolicy(t *testing.T) {
+	policy, err := aip.ParsePolicy([]byte(testPolicy))
+	require.NoError(t, err)
+	client := newClient(t, &fakeBookService{}, aip.WithPolicy(policy))
+
+	update := func(req *testpb.UpdateBookRequest) error {
+		_, err := client.UpdateBook(context.Background(), connect.NewRequest(req))
+		return err
+	}
+	require.NoError(t, update(&testpb.UpdateBookRequest{Book: &testpb.Book{Title: "Dune"}}))
+	requireViolations(t, update(&testpb.UpdateBookRequest{Book: &testpb.Book{Name: "books/1"}}), "book.title")
+	requireViolations(t, update(&testpb.UpdateBookRequest{
+		Book:       &testpb.Book{Title: "Dune"},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"title", "language_code"}},
+	}), "update_mask")
+	require.NoError(t, update(&testpb.UpdateBookRequest{
+		Book:       &testpb.Book{Title: "Dune", LanguageCode: "en"},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"*"}},
+	}), "full replacement may supply immutable fields")
+	requireViolations(t, update(&testpb.UpdateBookRequest{Book: &testpb.Book{Title: "Dune", CreateTime: timestamppb.Now()}}), "book.create_time")
+}
+
+func TestPolicy_ListRequests(t *testing.T) {
+	policy, err := aip.ParsePolicy([]byte(testPolicy))
+	require.NoError(t, err)
+	svc := &pageSizeService{}
+	client := newClient(t, svc, aip.WithPolicy(policy))
+
+	list := func(req *testpb.ListBooksRequest) error {
+		_, err := client.ListBooksPage(context.Background(), connect.NewRequest(req))
+		return err
+	}
+	require.NoError(t, list(&testpb.ListBooksRequest{PageSize: 500, OrderBy: "title desc"}))
+	require.Equal(t, int32(10), svc.pageSize, "page_size is coerced to the maximum")
+	require.NoError(t, list(&testpb.ListBooksRequest{PageSize: 5}))
+	require.Equal(t, int32(5), svc.pageSize)
+	requireViolations(t, list(&testpb.ListBooksRequest{OrderBy: "title, create_time"}), "order_by")
+}
+
+func TestPolicy_Clear(t *testing.T) {
+	policy := &aip.Policy{Methods: map[string]*aip.MethodPolicy{
+		testpbconnect.BookServiceUpdateBookProcedure: {OutputOnly: aip.OutputOnlyClear},
+	}}
+	client := newClient(t, &fakeBookService{}, aip.WithPolicy(policy))
+
+	rsp, err := client.UpdateBook(context.Background(), connect.NewRequest(&testpb.UpdateBookRequest{
+		Book: &testpb.Book{Title: "Dune", CreateTime: timestamppb.Now()},
+	}))
+	require.NoError(t, err)
+	require.Equal(t, "Dune", rsp.Msg.GetTitle())
+	require.Nil(t, rsp.Msg.GetCreateTime(), "output only fields are cleared before the handler")
+}
+
+func TestParsePolicy_Errors(t *testing.T) {
+	_, err := aip.ParsePolicy([]byte("methods:\n  /a.B/C:\n    requird_fields: [x]\n"))
+	require.ErrorContains(t, err, "requird_fields")
+
+	_, err = aip.ParsePolicy([]byte("methods:\n  /a.B/C:\n    output_only: drop\n"))
+	require.ErrorContains(t, err, `unknown output_only handling "drop"`)
+
+	_, err = aip.ParsePolicy([]byte("methods:\n  a.B/C:\n    max_page_size: 1\n"))
+	require.ErrorContains(t, err, "malformed procedure")
+
+	_, err = aip.ParsePolicy([]byte("methods:\n  /a.B/C:\n    max_page_size: -1\n"))
+	require.ErrorContains(t, err, "must not be negative")
+}
+
+func TestLoadPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testPolicy), 0o600))
+	policy, err := aip.LoadPolicy(path)
+	require.NoError(t, err)
+	require.Equal(t, []string{"book.title"}, policy.Methods[testpbconnect.BookServiceUpdateBookProcedure].RequiredFields)
+	require.Equal(t, int32(10), policy.Methods[testpbconnect.BookServiceListBooksPageProcedure].MaxPageSize)
+
+	_, err = aip.LoadPolicy(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.Error(t, err)
+}
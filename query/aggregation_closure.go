@@ -0,0 +1,191 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// AggregateRow is one group of an in-memory Aggregation.
+type AggregateRow struct {
+	// GroupKeys holds the value of each group_by field for the group, in
+	// order, or nil where the field is unset.
+	GroupKeys []any
+
+	// Values holds the value of each measure, in order. Counts are int64.
+	// Sums are int64 for signed integer fields, uint64 for unsigned integer
+	// fields and float64 for floating point fields, or nil if no item in the
+	// group has the field set, as with SUM in SQL.
+	Values []any
+}
+
+// Aggregate computes the aggregation over items in memory, producing the
+// same groups and values as the SQL from AggregationClause. Rows are returned
+// in the order their groups first appear in items.
+//
+// group_by fields must be singular scalar or enum fields, and sum fields
+// singular numeric fields. Errors returned by Aggregate are of type
+// *FieldViolationError.
+//
+// Example:
+//
+//	agg, err := query.ParseAggregation("count group_by format")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	rows, err := query.Aggregate(books, agg)
+func Aggregate[M proto.Message](items []M, a *Aggregation) ([]AggregateRow, error) {
+	if a == nil || len(a.Measures) == 0 {
+		return nil, newFieldViolation(AggregationField, fmt.Errorf("at least one measure is required"))
+	}
+	var zero M
+	desc := zero.ProtoReflect().Descriptor()
+	for _, path := range a.GroupBy {
+		if err := validateAggregateField(desc, path, false); err != nil {
+			return nil, err
+		}
+	}
+	for _, m := range a.Measures {
+		switch m.Function {
+		case AggregateCount:
+		case AggregateSum:
+			if err := validateAggregateField(desc, m.FieldPath, true); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, newFieldViolation(AggregationField, fmt.Errorf("unsupported aggregate function %s", m.Function))
+		}
+	}
+
+	var groups []*aggregateGroup
+	groupByKey := make(map[string]*aggregateGroup)
+	for _, item := range items {
+		m := item.ProtoReflect()
+		keys := make([]any, len(a.GroupBy))
+		for i, path := range a.GroupBy {
+			if v, _ := getFieldPathValue(m, path.segments); v.IsValid() {
+				keys[i] = v.Interface()
+			}
+		}
+		key := groupKey(keys)
+		g := groupByKey[key]
+		if g == nil {
+			g = &aggregateGroup{keys: keys, sums: make([]sumAccumulator, len(a.Measures))}
+			groupByKey[key] = g
+			groups = append(groups, g)
+		}
+		g.count++
+		for i, measure := range a.Measures {
+			if measure.Function != AggregateSum {
+				continue
+			}
+			if v, _ := getFieldPathValue(m, measure.FieldPath.segments); v.IsValid() {
+				g.sums[i].add(v)
+			}
+		}
+	}
+
+	rows := make([]AggregateRow, 0, len(groups))
+	for _, g := range groups {
+		row := AggregateRow{
+			GroupKeys: g.keys,
+			Values:    make([]any, len(a.Measures)),
+		}
+		for i, measure := range a.Measures {
+			if measure.Function == AggregateCount {
+				row.Values[i] = g.count
+			} else {
+				row.Values[i] = g.sums[i].value()
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// validateAggregateField checks path addresses a field of desc that can be
+// grouped on or, if numeric is set, summed.
+func validateAggregateField(desc protoreflect.MessageDescriptor, path FieldPath, numeric bool) error {
+	if err := validateFieldPath(desc, path.segments); err != nil {
+		return newFieldViolation(AggregationField, fmt.Errorf("invalid field %s: %w", path.canonical, err))
+	}
+	fd := fieldPathDescriptor(desc, path.segments)
+	if numeric && !numericKind(fd.Kind()) {
+		return newFieldViolation(AggregationField, fmt.Errorf("cannot sum non-numeric field %s", path.canonical))
+	}
+	if fd.Message() != nil {
+		return newFieldViolation(AggregationField, fmt.Errorf("cannot group by message field %s", path.canonical))
+	}
+	return nil
+}
+
+func numericKind(k protoreflect.Kind) bool {
+	switch k {
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind,
+		protoreflect.FloatKind, protoreflect.DoubleKind:
+		return true
+	}
+	return false
+}
+
+// groupKey returns a string uniquely identifying a tuple of group keys.
+func groupKey(keys []any) string {
+	var b strings.Builder
+	for _, k := range keys {
+		// %#v quotes strings, so the separator cannot appear inside a key.
+		fmt.Fprintf(&b, "%#v,", k)
+	}
+	return b.String()
+}
+
+type aggregateGroup struct {
+	keys  []any
+	count int64
+	sums  []sumAccumulator
+}
+
+// sumAccumulator accumulates the values of a numeric field.
+type sumAccumulator struct {
+	set bool
+	i   int64
+	u   uint64
+	f   float64
+	// kind is the value type of the sum: 'i', 'u' or 'f'.
+	kind byte
+}
+
+func (s *sumAccumulator) add(v protoreflect.Value) {
+	s.set = true
+	switch n := v.Interface().(type) {
+	case int32:
+		s.kind, s.i = 'i', s.i+int64(n)
+	case int64:
+		s.kind, s.i = 'i', s.i+n
+	case uint32:
+		s.kind, s.u = 'u', s.u+uint64(n)
+	case uint64:
+		s.kind, s.u = 'u', s.u+n
+	case float32:
+		s.kind, s.f = 'f', s.f+float64(n)
+	case float64:
+		s.kind, s.f = 'f', s.f+n
+	}
+}
+
+func (s *sumAccumulator) value() any {
+	if !s.set {
+		return nil
+	}
+	switch s.kind {
+	case 'i':
+		return s.i
+	case 'u':
+		return s.u
+	}
+	return s.f
+}
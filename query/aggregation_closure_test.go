@@ -0,0 +1,75 @@
+package query_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/query"
+)
+
+func TestAggregate(t *testing.T) {
+	books := []*testpb.Book{
+		{Name: "books/1", Format: testpb.Format_PAPERBACK, PageCount: proto.Int32(100)},
+		{Name: "books/2", Format: testpb.Format_EBOOK, PageCount: proto.Int32(50)},
+		{Name: "books/3", Format: testpb.Format_PAPERBACK, PageCount: proto.Int32(20)},
+		{Name: "books/4", Format: testpb.Format_EBOOK},
+		{Name: "books/5", Format: testpb.Format_HARDCOVER},
+	}
+
+	agg, err := query.ParseAggregation("count, sum(page_count) group_by format")
+	require.NoError(t, err)
+	rows, err := query.Aggregate(books, agg)
+	require.NoError(t, err)
+	require.Equal(t, []query.AggregateRow{
+		{GroupKeys: []any{testpb.Format_PAPERBACK.Number()}, Values: []any{int64(2), int64(120)}},
+		{GroupKeys: []any{testpb.Format_EBOOK.Number()}, Values: []any{int64(2), int64(50)}},
+		{GroupKeys: []any{testpb.Format_HARDCOVER.Number()}, Values: []any{int64(1), nil}},
+	}, rows)
+
+	agg, err = query.ParseAggregation("count group_by author.family_name, page_count")
+	require.NoError(t, err)
+	rows, err = query.Aggregate([]*testpb.Book{
+		{Author: &testpb.Author{FamilyName: "Hunt"}},
+		{Author: &testpb.Author{FamilyName: "Hunt"}, PageCount: proto.Int32(0)},
+		{},
+		{Author: &testpb.Author{FamilyName: "Hunt"}},
+	}, agg)
+	require.NoError(t, err)
+	require.Equal(t, []query.AggregateRow{
+		{GroupKeys: []any{"Hunt", nil}, Values: []any{int64(2)}},
+		{GroupKeys: []any{"Hunt", int32(0)}, Values: []any{int64(1)}},
+		{GroupKeys: []any{nil, nil}, Values: []any{int64(1)}},
+	}, rows)
+
+	agg, err = query.ParseAggregation("count")
+	require.NoError(t, err)
+	rows, err = query.Aggregate(books, agg)
+	require.NoError(t, err)
+	require.Equal(t, []query.AggregateRow{{GroupKeys: []any{}, Values: []any{int64(5)}}}, rows)
+}
+
+func TestAggregateInvalidFields(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		want string
+	}{
+		{"sum of string", "sum(title)", "cannot sum non-numeric field title"},
+		{"group by message", "count group_by create_time", "cannot group by message field create_time"},
+		{"group by nested message", "count group_by author", "invalid field author"},
+		{"unknown field", "count group_by missing", "invalid field missing"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			agg, err := query.ParseAggregation(tc.spec)
+			require.NoError(t, err)
+			_, err = query.Aggregate[*testpb.Book](nil, agg)
+			require.ErrorContains(t, err, tc.want)
+			var fv *query.FieldViolationError
+			require.ErrorAs(t, err, &fv)
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AggregationClause returns Standard SQL SELECT and GROUP BY clauses
+// equivalent to the given aggregation. The GROUP BY clause is empty if the
+// aggregation has no group_by fields.
+//
+// The selected columns are the group_by columns, in order, followed by the
+// measures, in order. For example, the aggregation
+// `count, sum(page_count) group_by format` yields:
+//
+//	SELECT db_format, COUNT(*), SUM(db_page_count)
+//	GROUP BY db_format
+//
+// Every field referenced by the aggregation must be an aggregatable column
+// of the table. Errors returned by AggregationClause are of type
+// *FieldViolationError.
+func (t *Table) AggregationClause(a *Aggregation) (selectClause string, groupByClause string, err error) {
+	if a == nil || len(a.Measures) == 0 {
+		return "", "", newFieldViolation(AggregationField, fmt.Errorf("at least one measure is required"))
+	}
+
+	groups := make([]string, 0, len(a.GroupBy))
+	for _, path := range a.GroupBy {
+		column, err := t.AggregatableColumnByFieldPath(path)
+		if err != nil {
+			return "", "", newFieldViolation(AggregationField, err)
+		}
+		groups = append(groups, column.databaseName)
+	}
+
+	selected := append([]string{}, groups...)
+	for _, m := range a.Measures {
+		switch m.Function {
+		case AggregateCount:
+			selected = append(selected, "COUNT(*)")
+		case AggregateSum:
+			column, err := t.AggregatableColumnByFieldPath(m.FieldPath)
+			if err != nil {
+				return "", "", newFieldViolation(AggregationField, err)
+			}
+			selected = append(selected, fmt.Sprintf("SUM(%s)", column.databaseName))
+		default:
+			return "", "", newFieldViolation(AggregationField, fmt.Errorf("unsupported aggregate function %s", m.Function))
+		}
+	}
+
+	selectClause = "SELECT " + strings.Join(selected, ", ") + "\n"
+	if len(groups) > 0 {
+		groupByClause = "GROUP BY " + strings.Join(groups, ", ") + "\n"
+	}
+	return selectClause, groupByClause, nil
+}
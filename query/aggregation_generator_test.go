@@ -0,0 +1,47 @@
+package query
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	. "github.com/hxtk/aip/query/internal/assertions"
+)
+
+func TestAggregationClause(t *testing.T) {
+	Convey("AggregationClause", t, func() {
+		table := NewTable().WithColumns(
+			NewColumn().WithFieldPath("format").WithDatabaseName("db_format").Aggregatable().Build(),
+			NewColumn().WithFieldPath("page_count").WithDatabaseName("db_page_count").Aggregatable().Build(),
+			NewColumn().WithFieldPath("title").WithDatabaseName("db_title").Sortable().Build(),
+		).Build()
+
+		Convey("Without grouping", func() {
+			agg, err := ParseAggregation("count, sum(page_count)")
+			So(err, ShouldBeNil)
+			selectClause, groupByClause, err := table.AggregationClause(agg)
+			So(err, ShouldBeNil)
+			So(selectClause, ShouldEqual, "SELECT COUNT(*), SUM(db_page_count)\n")
+			So(groupByClause, ShouldEqual, "")
+		})
+		Convey("With grouping", func() {
+			agg, err := ParseAggregation("sum(page_count), count group_by format")
+			So(err, ShouldBeNil)
+			selectClause, groupByClause, err := table.AggregationClause(agg)
+			So(err, ShouldBeNil)
+			So(selectClause, ShouldEqual, "SELECT db_format, SUM(db_page_count), COUNT(*)\n")
+			So(groupByClause, ShouldEqual, "GROUP BY db_format\n")
+		})
+		Convey("Non-aggregatable column", func() {
+			agg, err := ParseAggregation("count group_by title")
+			So(err, ShouldBeNil)
+			_, _, err = table.AggregationClause(agg)
+			So(err, ShouldErrLike, `no aggregatable field named "title", valid fields are format, page_count`)
+			So(err, ShouldHaveSameTypeAs, &FieldViolationError{})
+		})
+		Convey("No measures", func() {
+			_, _, err := table.AggregationClause(&Aggregation{})
+			So(err, ShouldErrLike, "at least one measure is required")
+		})
+	})
+}
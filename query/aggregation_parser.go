@@ -0,0 +1,136 @@
+// This file provides a parser for aggregation specs, an opt-in extension for
+// APIs exposing simple analytics over a collection alongside an AIP-132 List
+// method (see AIP-236 for the related guidance on such methods).
+//
+// aggregation = measure {[spaces] "," [spaces] measure} [group_by] [spaces]
+// measure = [spaces] ("count" | "sum" [spaces] "(" field_path [spaces] ")")
+// group_by = spaces "group_by" field_path {[spaces] "," field_path}
+//
+// field_path is as described for AIP-132 order by clauses. For example:
+//
+//	count, sum(page_count) group_by author.family_name, format
+//
+// No validation is performed to test that the field paths are valid for
+// a particular protocol buffer message.
+
+package query
+
+import (
+	"strings"
+
+	participle "github.com/alecthomas/participle/v2"
+	"github.com/alecthomas/participle/v2/lexer"
+
+	"go.chromium.org/luci/common/errors"
+)
+
+var (
+	aggregationLexer = lexer.MustSimple([]lexer.SimpleRule{
+		{Name: "Spaces", Pattern: `[ ]+`},
+		{Name: "String", Pattern: `[a-zA-Z_][a-zA-Z_0-9]*`},
+		{Name: "QuotedString", Pattern: "`(``|[^`])*`"},
+		{Name: "Operators", Pattern: "[.,()]"},
+	})
+
+	aggregationParser = participle.MustBuild[aggregationSpec](
+		participle.Lexer(aggregationLexer),
+		participle.UseLookahead(3),
+	)
+)
+
+// AggregateFunction is an aggregate function of an Aggregation.
+type AggregateFunction int32
+
+const (
+	// AggregateCount counts the items in each group.
+	AggregateCount AggregateFunction = iota
+	// AggregateSum sums a numeric field over the items in each group,
+	// ignoring items where the field is unset.
+	AggregateSum
+)
+
+func (f AggregateFunction) String() string {
+	switch f {
+	case AggregateCount:
+		return "count"
+	case AggregateSum:
+		return "sum"
+	default:
+		return "unknown"
+	}
+}
+
+// Measure is a single aggregate computed for each group.
+type Measure struct {
+	// The aggregate function.
+	Function AggregateFunction
+	// The field the function is applied to. Empty for AggregateCount.
+	FieldPath FieldPath
+}
+
+// String returns the measure as written in an aggregation spec.
+func (m Measure) String() string {
+	if m.Function == AggregateCount {
+		return m.Function.String()
+	}
+	return m.Function.String() + "(" + m.FieldPath.String() + ")"
+}
+
+// Aggregation is a parsed aggregation spec.
+type Aggregation struct {
+	// The aggregates computed for each group, in the order requested.
+	Measures []Measure
+	// The fields the items are grouped by. If empty, all items form a
+	// single group.
+	GroupBy []FieldPath
+}
+
+// ParseAggregation parses an aggregation spec. The method validates the
+// syntax is correct and each group_by field appears at most once, but it does
+// not validate the identifiers themselves are valid.
+//
+// Errors returned by ParseAggregation are of type *FieldViolationError.
+func ParseAggregation(text string) (*Aggregation, error) {
+	if strings.Trim(text, " ") == "" {
+		return nil, newFieldViolation(AggregationField, errors.Reason("at least one measure is required").Err())
+	}
+
+	expr, err := aggregationParser.ParseString("", text)
+	if err != nil {
+		return nil, newFieldViolation(AggregationField, errors.Annotate(err, "syntax error").Err())
+	}
+
+	result := &Aggregation{}
+	for _, m := range expr.Measures {
+		if m.Count {
+			result.Measures = append(result.Measures, Measure{Function: AggregateCount})
+			continue
+		}
+		result.Measures = append(result.Measures, Measure{
+			Function:  AggregateSum,
+			FieldPath: NewFieldPath(m.Sum.Path()...),
+		})
+	}
+
+	uniqueFieldPaths := make(map[string]struct{})
+	for _, path := range expr.GroupBy {
+		fp := NewFieldPath(path.Path()...)
+		if _, ok := uniqueFieldPaths[fp.String()]; ok {
+			return nil, newFieldViolation(AggregationField, errors.Reason("group_by field appears multiple times: %q", fp).Err())
+		}
+		uniqueFieldPaths[fp.String()] = struct{}{}
+		result.GroupBy = append(result.GroupBy, fp)
+	}
+
+	return result, nil
+}
+
+type aggregationSpec struct {
+	Measures []*measure   `parser:"Spaces? @@ ( Spaces? ',' Spaces? @@ )*"`
+	GroupBy  []*fieldPath `parser:"( Spaces 'group_by' @@ ( Spaces? ',' @@ )* )? Spaces?"`
+}
+
+type measure struct {
+	Count bool       `parser:"  @'count'"`
+	Sum   *fieldPath `parser:"| 'sum' Spaces? '(' @@ Spaces? ')'"`
+}
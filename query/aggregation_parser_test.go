@@ -0,0 +1,64 @@
+package query
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	. "github.com/hxtk/aip/query/internal/assertions"
+)
+
+func TestParseAggregation(t *testing.T) {
+	Convey("ParseAggregation", t, func() {
+		Convey("Measures without grouping", func() {
+			result, err := ParseAggregation("count")
+			So(err, ShouldBeNil)
+			So(result, ShouldResemble, &Aggregation{
+				Measures: []Measure{{Function: AggregateCount}},
+			})
+		})
+		Convey("Measures with grouping", func() {
+			expected := &Aggregation{
+				Measures: []Measure{
+					{Function: AggregateCount},
+					{Function: AggregateSum, FieldPath: NewFieldPath("page_count")},
+				},
+				GroupBy: []FieldPath{
+					NewFieldPath("author", "family_name"),
+					NewFieldPath("format"),
+				},
+			}
+			result, err := ParseAggregation("count, sum(page_count) group_by author.family_name, format")
+			So(err, ShouldBeNil)
+			So(result, ShouldResemble, expected)
+
+			result, err = ParseAggregation("  count ,sum ( page_count )  group_by  author.family_name ,format  ")
+			So(err, ShouldBeNil)
+			So(result, ShouldResemble, expected)
+		})
+		Convey("Quoted field paths", func() {
+			result, err := ParseAggregation("sum(metrics.`some-metric`)")
+			So(err, ShouldBeNil)
+			So(result.Measures, ShouldResemble, []Measure{
+				{Function: AggregateSum, FieldPath: NewFieldPath("metrics", "some-metric")},
+			})
+			So(result.Measures[0].String(), ShouldEqual, "sum(metrics.`some-metric`)")
+		})
+		Convey("Empty spec", func() {
+			_, err := ParseAggregation("  ")
+			So(err, ShouldErrLike, "at least one measure is required")
+		})
+		Convey("Group by without measures", func() {
+			_, err := ParseAggregation("group_by format")
+			So(err, ShouldErrLike, "syntax error")
+		})
+		Convey("Sum without a field", func() {
+			_, err := ParseAggregation("sum()")
+			So(err, ShouldErrLike, "syntax error")
+		})
+		Convey("Duplicate group by field", func() {
+			_, err := ParseAggregation("count group_by format, format")
+			So(err, ShouldErrLike, `group_by field appears multiple times: "format"`)
+		})
+	})
+}
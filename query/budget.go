@@ -0,0 +1,66 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ErrBudgetExhausted is returned by FilterSlice, FilterSliceParallel and
+// Index.List when evaluating a filter exceeds the budget set with
+// WithBudget.
+var ErrBudgetExhausted = errors.New("evaluation budget exhausted")
+
+// Budget bounds the work of evaluating a filter over a collection for a
+// single request, so that a pathological query fails instead of occupying a
+// worker indefinitely.
+type Budget struct {
+	// MaxMessages is the maximum number of messages the filter is
+	// evaluated on, or zero for no limit.
+	MaxMessages int
+
+	// MaxDuration is the maximum wall time spent evaluating the filter, or
+	// zero for no limit.
+	MaxDuration time.Duration
+}
+
+// WithBudget sets the budget of each call of FilterSlice,
+// FilterSliceParallel or Index.List, which fail with an error wrapping
+// ErrBudgetExhausted once it is spent. It has no effect on the predicates
+// returned by ProtoFilter and ProtoFilterCtx.
+func WithBudget(b Budget) FilterOption {
+	return func(o *filterOptions) {
+		o.budget = b
+	}
+}
+
+// budgetMeter tracks the spending of a Budget. It is safe for concurrent
+// use.
+type budgetMeter struct {
+	budget   Budget
+	deadline time.Time
+	messages atomic.Int64
+}
+
+// start returns a meter for a call starting now.
+func (b Budget) start() *budgetMeter {
+	m := &budgetMeter{budget: b}
+	if b.MaxDuration > 0 {
+		m.deadline = time.Now().Add(b.MaxDuration)
+	}
+	return m
+}
+
+// spend records the evaluation of one message, returning an error wrapping
+// ErrBudgetExhausted if the budget does not allow it.
+func (m *budgetMeter) spend() error {
+	n := m.messages.Add(1)
+	if m.budget.MaxMessages > 0 && n > int64(m.budget.MaxMessages) {
+		return fmt.Errorf("%w: more than %d messages evaluated", ErrBudgetExhausted, m.budget.MaxMessages)
+	}
+	if !m.deadline.IsZero() && time.Now().After(m.deadline) {
+		return fmt.Errorf("%w: evaluation exceeded %s", ErrBudgetExhausted, m.budget.MaxDuration)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package query_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/query"
+)
+
+func TestBudget(t *testing.T) {
+	books := makeBooks(100)
+	f := mustParse(t, `author.family_name = "Thomas"`)
+
+	got, err := query.FilterSlice(books, f, query.WithBudget(query.Budget{MaxMessages: 100}))
+	require.NoError(t, err)
+	require.Len(t, got, 34)
+
+	for _, workers := range []int{1, 4} {
+		_, err = query.FilterSliceParallel(books, f, workers, query.WithBudget(query.Budget{MaxMessages: 99}))
+		require.ErrorIs(t, err, query.ErrBudgetExhausted, "workers=%d", workers)
+	}
+
+	_, err = query.FilterSlice(books, f, query.WithBudget(query.Budget{MaxDuration: time.Nanosecond}))
+	require.ErrorIs(t, err, query.ErrBudgetExhausted)
+
+	index, err := query.NewIndex[testpb.Book]()
+	require.NoError(t, err)
+	for _, b := range books {
+		index.Put(b)
+	}
+	// A page of the index is read until full, so a budget only bounds the
+	// messages read for the page requested.
+	budget := query.WithBudget(query.Budget{MaxMessages: 10})
+	page, more, err := index.List(f, nil, nil, 2, budget)
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	require.True(t, more)
+	_, _, err = index.List(f, nil, nil, 0, budget)
+	require.ErrorIs(t, err, query.ErrBudgetExhausted)
+}
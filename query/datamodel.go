@@ -21,6 +21,8 @@ package query
 import (
 	"fmt"
 	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
 const (
@@ -30,6 +32,18 @@ const (
 	ColumnTypeBool = iota
 )
 
+// EnumOrder controls how the values of an enum field are ordered when
+// sorting.
+type EnumOrder int32
+
+const (
+	// EnumOrderByNumber orders enum values by their declared number.
+	EnumOrderByNumber EnumOrder = iota
+	// EnumOrderByName orders enum values lexicographically by their symbolic
+	// name. Values unknown to the enum descriptor sort after all known values.
+	EnumOrderByName
+)
+
 // ColumnType is an enum for the type of a column.  Valid values are in the const block above.
 type ColumnType int32
 
@@ -63,6 +77,9 @@ type Column struct {
 	// Whether this column can be filtered on.
 	filterable bool
 
+	// Whether this column can be grouped on and aggregated.
+	aggregatable bool
+
 	// ImplicitFilter controls whether this field is searched implicitly
 	// in AIP-160 filter expressions.
 	implicitFilter bool
@@ -73,11 +90,46 @@ type Column struct {
 	// Whether this column is an array.
 	array bool
 
+	// Whether this column may hold NULL, e.g. for a missing submessage.
+	nullable bool
+
+	// The SQL literal of the zero value of the field, if the column holds
+	// NULL for it. Important: as with databaseName, only assign safe
+	// constants.
+	nullZero string
+
 	// The type of the column, defaults to ColumnType_STRING.
 	columnType ColumnType
 
 	// The function which is applied to the filter arguments.
 	argSubstitute func(sub string) string
+
+	// The enum stored in this column by number, if any.
+	enumDesc protoreflect.EnumDescriptor
+
+	// How values of enumDesc are ordered.
+	enumOrder EnumOrder
+
+	// The position of the column among the key columns of each composite
+	// index including it, by index name.
+	indexes map[string]int
+
+	// The custom comparison the column is sorted with in memory, if any.
+	comparator FieldComparator
+
+	// The SQL expression the column is sorted on, if not its database name.
+	// Important: as with databaseName, only assign safe constants.
+	orderExpr string
+
+	// Whether the column is deprecated, and the field path replacing it.
+	deprecated  bool
+	replacement string
+
+	// Whether the column is a synthetic field, computed by the SQL
+	// expression in databaseName rather than stored in a field of the
+	// resource, and the filter comparators it may be compared with.
+	synthetic   bool
+	comparators []string
 }
 
 // Table represents the schema of a Database table, view or query.
@@ -88,10 +140,33 @@ type Table struct {
 	// A mapping from externally-visible field path to the column
 	// definition. The column name used as a key is in lowercase.
 	columnByFieldPath map[string]*Column
+
+	// Limits on the size of generated SQL.
+	limits SQLLimits
+
+	// The key columns of each declared index, in order, by index name.
+	indexes map[string][]*Column
+
+	// The handler of warnings about generated queries, if any.
+	warn func(Warning)
+
+	// The functions filters may call, if any.
+	functions *FunctionRegistry
+
+	// The name patterns of the resources in the table.
+	patterns []string
+
+	// The order completing the order of every request, if any.
+	defaultOrder []OrderBy
+
+	// The maximum number of fields in the order of a request, or
+	// non-positive for no limit.
+	maxOrderFields int
 }
 
 // FilterableColumnByFieldPath returns the database name of the filterable column
-// with the given field path.
+// with the given field path. If there is none, the error matches
+// ErrUnsupportedField.
 func (t *Table) FilterableColumnByFieldPath(path FieldPath) (*Column, error) {
 	col := t.columnByFieldPath[path.String()]
 	if col != nil && col.filterable {
@@ -104,11 +179,12 @@ func (t *Table) FilterableColumnByFieldPath(path FieldPath) (*Column, error) {
 			columnNames = append(columnNames, column.fieldPath.String())
 		}
 	}
-	return nil, fmt.Errorf("no filterable field %q, valid fields are %s", path.String(), strings.Join(columnNames, ", "))
+	return nil, unsupportedFieldError{fmt.Errorf("no filterable field %q, valid fields are %s", path.String(), strings.Join(columnNames, ", "))}
 }
 
 // SortableColumnByFieldPath returns the sortable database column
-// with the given externally-visible field path.
+// with the given externally-visible field path. If there is none, the error
+// matches ErrUnsupportedField.
 func (t *Table) SortableColumnByFieldPath(path FieldPath) (*Column, error) {
 	col := t.columnByFieldPath[path.String()]
 	if col != nil && col.sortable {
@@ -121,5 +197,23 @@ func (t *Table) SortableColumnByFieldPath(path FieldPath) (*Column, error) {
 			columnNames = append(columnNames, column.fieldPath.String())
 		}
 	}
-	return nil, fmt.Errorf("no sortable field named %q, valid fields are %s", path.String(), strings.Join(columnNames, ", "))
+	return nil, unsupportedFieldError{fmt.Errorf("no sortable field named %q, valid fields are %s", path.String(), strings.Join(columnNames, ", "))}
+}
+
+// AggregatableColumnByFieldPath returns the aggregatable database column
+// with the given externally-visible field path. If there is none, the error
+// matches ErrUnsupportedField.
+func (t *Table) AggregatableColumnByFieldPath(path FieldPath) (*Column, error) {
+	col := t.columnByFieldPath[path.String()]
+	if col != nil && col.aggregatable {
+		return col, nil
+	}
+
+	columnNames := []string{}
+	for _, column := range t.columns {
+		if column.aggregatable {
+			columnNames = append(columnNames, column.fieldPath.String())
+		}
+	}
+	return nil, unsupportedFieldError{fmt.Errorf("no aggregatable field named %q, valid fields are %s", path.String(), strings.Join(columnNames, ", "))}
 }
@@ -14,6 +14,14 @@
 
 package query
 
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
 type ColumnBuilder struct {
 	column Column
 }
@@ -62,12 +70,53 @@ func (c *ColumnBuilder) Array() *ColumnBuilder {
 	return c
 }
 
+// Nullable specifies this column may hold NULL where the resource has no
+// value, e.g. a field of a submessage stored in a LEFT JOINed table or
+// extracted from a JSON column.
+//
+// OrderByClause then places NULLs explicitly, first in ascending and last in
+// descending order, as Comparer does for missing submessages. This keeps
+// the order consistent on databases whose default differs, like Postgres.
+func (c *ColumnBuilder) Nullable() *ColumnBuilder {
+	c.column.nullable = true
+	return c
+}
+
+// NullIsZero specifies this column may hold NULL where the resource has the
+// zero value of its field, as when NULLs are read into fields with implicit
+// presence, rather than where the field is unset as with Nullable. The zero
+// value is given as a SQL literal, e.g. "0", "FALSE", or two single quotes
+// for the empty string.
+//
+// WhereClause, OrderByClause and SeekClause then compare the column as
+// COALESCE(column, zero), so that NULLs match filters, sort and page like
+// the zero value, as Comparer and ProtoFilter treat it. NullIsZero takes
+// precedence over Nullable.
+// Important: Only pass safe constants as zero. It is used directly in SQL
+// statements.
+func (c *ColumnBuilder) NullIsZero(zero string) *ColumnBuilder {
+	c.column.nullZero = zero
+	return c
+}
+
 // Bool specifies this column has bool type in the database.
 func (c *ColumnBuilder) Bool() *ColumnBuilder {
 	c.column.columnType = ColumnTypeBool
 	return c
 }
 
+// Enum specifies this column stores values of the given enum by number, and
+// how those values are ordered when sorting on this column.
+//
+// With EnumOrderByName, OrderByClause sorts on a CASE expression mapping
+// each number to the rank of its name, so the database ordering matches
+// in-memory sorting with the same EnumOrder.
+func (c *ColumnBuilder) Enum(desc protoreflect.EnumDescriptor, order EnumOrder) *ColumnBuilder {
+	c.column.enumDesc = desc
+	c.column.enumOrder = order
+	return c
+}
+
 // Sortable specifies this column can be sorted on.
 func (c *ColumnBuilder) Sortable() *ColumnBuilder {
 	c.column.sortable = true
@@ -80,6 +129,13 @@ func (c *ColumnBuilder) Filterable() *ColumnBuilder {
 	return c
 }
 
+// Aggregatable specifies this column can be grouped on and used as the
+// argument of aggregate functions in an Aggregation.
+func (c *ColumnBuilder) Aggregatable() *ColumnBuilder {
+	c.column.aggregatable = true
+	return c
+}
+
 // FilterableImplicitly specifies this column can be filtered on implicitly.
 // This means that AIP-160 filter expressions not referencing any
 // particular field will try to search in this column.
@@ -97,15 +153,91 @@ func (c *ColumnBuilder) WithArgumentSubstitutor(f func(sub string) string) *Colu
 	return c
 }
 
+// WithComparator specifies the column is sorted with fn rather than by the
+// natural order of its field, e.g. to compare semantic versions. Comparers
+// built WithSortableColumns use fn.
+//
+// OrderByClause reports ordering on the column as unsupported unless the
+// database can sort it the same way, as declared with WithOrderExpression.
+// SeekClause always does, since the cursor holds the field value rather than
+// the value of the expression.
+func (c *ColumnBuilder) WithComparator(fn FieldComparator) *ColumnBuilder {
+	c.column.comparator = fn
+	return c
+}
+
+// WithOrderExpression specifies the SQL expression OrderByClause sorts the
+// column on instead of its database name, e.g. `COLLATE(db_name, "und:ci")`
+// or a generated sort key column.
+// Important: Only pass safe constants. The expression is used directly in
+// SQL statements.
+func (c *ColumnBuilder) WithOrderExpression(expr string) *ColumnBuilder {
+	c.column.orderExpr = expr
+	return c
+}
+
+// Deprecated marks the column deprecated, naming the field path to use
+// instead, or empty if there is none. Queries on the column still work, but
+// WhereClause and OrderByClause report a Warning for them to the handler set
+// with TableBuilder.WithWarningHandler, and Table.Warnings lists them, so
+// that clients can be steered off the field before it is removed.
+func (c *ColumnBuilder) Deprecated(replacement string) *ColumnBuilder {
+	c.column.deprecated = true
+	c.column.replacement = replacement
+	return c
+}
+
+// Synthetic declares the column is a synthetic field: one with no field in
+// the resource, computed by the SQL expression expr instead, e.g.
+// "RANDOM()" to sort in random order or
+// "ts_rank(search_vector, query)" to sort by relevance. It replaces the
+// database name of the column.
+//
+// Filters may compare the field only with the given comparators, of "=",
+// "!=", "<", "<=", ">", ">=", ":" and ":*" for presence tests. Integer and
+// float arguments are bound as numbers, others as for the column type.
+//
+// Synthetic fields only exist in SQL: SeekClause rejects orders on them,
+// since the cursor has no value for them, and CheckAgainst and RowValues
+// skip them.
+// Important: Only pass safe constants as expr, as with WithDatabaseName.
+func (c *ColumnBuilder) Synthetic(expr string, comparators ...string) *ColumnBuilder {
+	c.column.databaseName = expr
+	c.column.synthetic = true
+	c.column.comparators = comparators
+	return c
+}
+
+// InIndex declares the column is the key column at the given position,
+// counting from zero, of the composite database index with the given name.
+// A column may be in several indexes. Table.AnalyzeIndexes uses the
+// declarations to report whether queries are served by an index.
+func (c *ColumnBuilder) InIndex(name string, position int) *ColumnBuilder {
+	if c.column.indexes == nil {
+		c.column.indexes = make(map[string]int)
+	}
+	c.column.indexes[name] = position
+	return c
+}
+
 // Build returns the built column.
 func (c *ColumnBuilder) Build() *Column {
 	result := &Column{}
 	*result = c.column
+	result.indexes = maps.Clone(c.column.indexes)
+	result.comparators = slices.Clone(c.column.comparators)
 	return result
 }
 
 type TableBuilder struct {
-	columns []*Column
+	columns   []*Column
+	limits    SQLLimits
+	warn      func(Warning)
+	functions *FunctionRegistry
+	patterns  []string
+
+	defaultOrder   string
+	maxOrderFields int
 }
 
 // NewTable starts building a new table.
@@ -119,18 +251,106 @@ func (t *TableBuilder) WithColumns(columns ...*Column) *TableBuilder {
 	return t
 }
 
+// WithLimits specifies limits on the size of the SQL clauses generated for
+// the table. By default, the size is not limited.
+func (t *TableBuilder) WithLimits(limits SQLLimits) *TableBuilder {
+	t.limits = limits
+	return t
+}
+
+// WithWarningHandler specifies a function called with a Warning for each
+// deprecated field referenced by the queries WhereClause and OrderByClause
+// generate, e.g. to log or count uses of fields being migrated.
+func (t *TableBuilder) WithWarningHandler(h func(Warning)) *TableBuilder {
+	t.warn = h
+	return t
+}
+
+// WithFunctions specifies the functions filters may call, which must all
+// have a SQL implementation.
+func (t *TableBuilder) WithFunctions(r *FunctionRegistry) *TableBuilder {
+	t.functions = r
+	return t
+}
+
+// WithResourcePatterns specifies the AIP-122 name patterns of the resources
+// stored in the table, e.g. "publishers/{publisher}/books/{book}", which
+// ParentClause and ScopedWhereClause scope queries by.
+func (t *TableBuilder) WithResourcePatterns(patterns ...string) *TableBuilder {
+	t.patterns = patterns
+	return t
+}
+
+// WithDefaultOrder specifies the AIP-132 order_by text, e.g.
+// "create_time desc, name", that ParseOrder completes the order of every
+// request with. Every field of the order must be a sortable column.
+func (t *TableBuilder) WithDefaultOrder(orderBy string) *TableBuilder {
+	t.defaultOrder = orderBy
+	return t
+}
+
+// WithMaxOrderFields limits the number of fields the order_by of a request
+// may name, not counting those of the default order, since each sort key
+// adds to the cost of a query. By default, the number is not limited.
+func (t *TableBuilder) WithMaxOrderFields(n int) *TableBuilder {
+	t.maxOrderFields = n
+	return t
+}
+
 // Build returns the built table.
 func (t *TableBuilder) Build() *Table {
 	columnByFieldPath := make(map[string]*Column)
 	for _, c := range t.columns {
+		for _, op := range c.comparators {
+			if !slices.Contains(restrictionComparators, op) {
+				panic(fmt.Sprintf("invalid comparator %q of synthetic field %s", op, c.fieldPath.String()))
+			}
+		}
 		if _, ok := columnByFieldPath[c.fieldPath.String()]; ok {
 			panic("multiple columns with the same field path: " + c.fieldPath.String())
 		}
 		columnByFieldPath[c.fieldPath.String()] = c
 	}
 
-	return &Table{
+	indexes := make(map[string][]*Column)
+	for _, c := range t.columns {
+		for name, pos := range c.indexes {
+			keys := indexes[name]
+			for len(keys) <= pos {
+				keys = append(keys, nil)
+			}
+			if keys[pos] != nil {
+				panic(fmt.Sprintf("multiple columns at position %d of index %s", pos, name))
+			}
+			keys[pos] = c
+			indexes[name] = keys
+		}
+	}
+	for name, keys := range indexes {
+		if i := slices.Index(keys, nil); i >= 0 {
+			panic(fmt.Sprintf("no column at position %d of index %s", i, name))
+		}
+	}
+
+	table := &Table{
 		columns:           t.columns,
 		columnByFieldPath: columnByFieldPath,
+		limits:            t.limits,
+		indexes:           indexes,
+		warn:              t.warn,
+		functions:         t.functions,
+		patterns:          t.patterns,
+		maxOrderFields:    t.maxOrderFields,
+	}
+	defaultOrder, err := ParseOrderBy(t.defaultOrder)
+	if err != nil {
+		panic(fmt.Sprintf("invalid default order %q: %v", t.defaultOrder, err))
+	}
+	for _, o := range defaultOrder {
+		if _, err := table.SortableColumnByFieldPath(o.FieldPath); err != nil {
+			panic(fmt.Sprintf("invalid default order %q: %v", t.defaultOrder, err))
+		}
 	}
+	table.defaultOrder = defaultOrder
+	return table
 }
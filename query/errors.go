@@ -0,0 +1,91 @@
+package query
+
+import (
+	"errors"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+// OrderByField is the name of the AIP-132 request field holding the
+// order_by clause. It is used as the field in order_by field violations.
+const OrderByField = "order_by"
+
+// FilterField is the name of the AIP-160 request field holding the filter.
+// It is used as the field in filter field violations.
+const FilterField = "filter"
+
+// ParentField is the name of the AIP-132 request field holding the parent of
+// the listed collection. It is used as the field in parent field violations.
+const ParentField = "parent"
+
+// AggregationField is the name of the request field holding the aggregation
+// spec parsed by ParseAggregation. It is used as the field in aggregation
+// field violations.
+const AggregationField = "aggregation"
+
+// ViewField is the name of the request field naming a saved query of a
+// QueryRegistry. It is used as the field in view field violations.
+const ViewField = "view"
+
+// ErrUnsupportedField is matched by errors.Is for field violations naming a
+// field that cannot be used where it is, such as an order_by field that is
+// not sortable, as opposed to malformed syntax.
+var ErrUnsupportedField = errors.New("unsupported field")
+
+// unsupportedFieldError marks err as naming an unsupported field without
+// changing its text.
+type unsupportedFieldError struct {
+	error
+}
+
+// Unwrap returns the underlying error.
+func (e unsupportedFieldError) Unwrap() error {
+	return e.error
+}
+
+// Is reports whether target is ErrUnsupportedField.
+func (e unsupportedFieldError) Is(target error) bool {
+	return target == ErrUnsupportedField
+}
+
+// FieldViolationError is an error caused by one or more invalid fields of
+// a request.
+//
+// The violations are suitable for attaching to a google.rpc.BadRequest error
+// detail as described in AIP-193, e.g.:
+//
+//	var fv *query.FieldViolationError
+//	if errors.As(err, &fv) {
+//	    detail, _ := connect.NewErrorDetail(&errdetails.BadRequest{
+//	        FieldViolations: fv.FieldViolations(),
+//	    })
+//	    ...
+//	}
+type FieldViolationError struct {
+	field string
+	err   error
+}
+
+// newFieldViolation returns an error describing err as a violation of the
+// request field named field.
+func newFieldViolation(field string, err error) *FieldViolationError {
+	return &FieldViolationError{field: field, err: err}
+}
+
+// Error implements error.
+func (e *FieldViolationError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *FieldViolationError) Unwrap() error {
+	return e.err
+}
+
+// FieldViolations returns the request field violations described by e.
+func (e *FieldViolationError) FieldViolations() []*errdetails.BadRequest_FieldViolation {
+	return []*errdetails.BadRequest_FieldViolation{{
+		Field:       e.field,
+		Description: e.err.Error(),
+	}}
+}
@@ -0,0 +1,162 @@
+package query
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// ParseFieldPath parses the text of a field path as returned by
+// FieldPath.String, e.g. "author.given_name" or "reviews.`a.b`". Segments
+// may be quoted in backticks, with a doubled backtick standing for one. The
+// paths of field masks are written the same way.
+//
+// ParseFieldPath does not check that the path is valid for any message; see
+// FieldPath.Descriptor.
+func ParseFieldPath(text string) (FieldPath, error) {
+	if text == "" {
+		return FieldPath{}, fmt.Errorf("empty field path")
+	}
+	var segments []string
+	for i := 0; ; {
+		start := i
+		var seg string
+		if text[i] == '`' {
+			var b strings.Builder
+			for i++; ; i++ {
+				n := strings.IndexByte(text[i:], '`')
+				if n < 0 {
+					return FieldPath{}, fmt.Errorf("unclosed backtick at %d in field path %q", start, text)
+				}
+				b.WriteString(text[i : i+n])
+				i += n + 1
+				if i == len(text) || text[i] != '`' {
+					break
+				}
+				b.WriteByte('`')
+			}
+			seg = b.String()
+		} else {
+			n := strings.IndexAny(text[i:], ".`")
+			if n < 0 {
+				n = len(text) - i
+			}
+			seg, i = text[i:i+n], i+n
+			if seg == "" {
+				return FieldPath{}, fmt.Errorf("empty segment at %d in field path %q", start, text)
+			}
+		}
+		segments = append(segments, seg)
+		if i == len(text) {
+			return NewFieldPath(segments...), nil
+		}
+		if text[i] != '.' {
+			return FieldPath{}, fmt.Errorf("unexpected %q at %d in field path %q", text[i], i, text)
+		}
+		if i++; i == len(text) {
+			return FieldPath{}, fmt.Errorf("empty segment at %d in field path %q", i, text)
+		}
+	}
+}
+
+// FieldPathsFromMask returns the field paths of the paths of mask, in
+// order. A nil mask has none.
+func FieldPathsFromMask(mask *fieldmaskpb.FieldMask) ([]FieldPath, error) {
+	paths := make([]FieldPath, 0, len(mask.GetPaths()))
+	for _, p := range mask.GetPaths() {
+		path, err := ParseFieldPath(p)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// FieldPathFromJSON returns the field path of messages of type desc written
+// with the JSON names of its fields, e.g. "author.givenName" for
+// "author.given_name", as in the JSON encoding of a field mask. Proto names
+// are accepted too, and map keys are kept as they are.
+func FieldPathFromJSON(desc protoreflect.MessageDescriptor, text string) (FieldPath, error) {
+	path, err := ParseFieldPath(text)
+	if err != nil {
+		return FieldPath{}, err
+	}
+	names, _, err := resolveFieldPath(desc, path.segments, func(fields protoreflect.FieldDescriptors, seg string) protoreflect.FieldDescriptor {
+		if fd := fields.ByJSONName(seg); fd != nil {
+			return fd
+		}
+		return fields.ByName(protoreflect.Name(seg))
+	})
+	if err != nil {
+		return FieldPath{}, err
+	}
+	return NewFieldPath(names...), nil
+}
+
+// Segments returns the segments of the field path: the names of its fields
+// and the keys of its map fields, unquoted.
+func (f FieldPath) Segments() []string {
+	return slices.Clone(f.segments)
+}
+
+// Descriptor returns the descriptor of the field that the path addresses in
+// messages of type desc. For a path ending in the key of a map field, e.g.
+// "detailed_reviews.alice", it is the descriptor of the values of the map.
+//
+// The path may not descend into repeated fields, whose elements have no
+// path, and map keys must be valid for the key type of their map.
+func (f FieldPath) Descriptor(desc protoreflect.MessageDescriptor) (protoreflect.FieldDescriptor, error) {
+	_, fd, err := resolveFieldPath(desc, f.segments, func(fields protoreflect.FieldDescriptors, seg string) protoreflect.FieldDescriptor {
+		return fields.ByName(protoreflect.Name(seg))
+	})
+	return fd, err
+}
+
+// resolveFieldPath walks segments through messages of type desc, looking up
+// each field with lookup. It returns the proto names of the segments, with
+// map keys as they are, and the descriptor of the field addressed.
+func resolveFieldPath(
+	desc protoreflect.MessageDescriptor,
+	segments []string,
+	lookup func(protoreflect.FieldDescriptors, string) protoreflect.FieldDescriptor,
+) ([]string, protoreflect.FieldDescriptor, error) {
+	if len(segments) == 0 {
+		return nil, nil, fmt.Errorf("empty field path")
+	}
+	names := make([]string, 0, len(segments))
+	var fd protoreflect.FieldDescriptor
+	for i := 0; i < len(segments); i++ {
+		if desc == nil {
+			return nil, nil, fmt.Errorf("cannot traverse into non-message %s", segments[i-1])
+		}
+		fd = lookup(desc.Fields(), segments[i])
+		if fd == nil {
+			return nil, nil, fmt.Errorf("field %s not found on %s", segments[i], desc.FullName())
+		}
+		names = append(names, string(fd.Name()))
+		switch {
+		case fd.IsMap():
+			if i == len(segments)-1 {
+				break
+			}
+			i++
+			if _, err := parseMapKey(fd.MapKey(), segments[i]); err != nil {
+				return nil, nil, err
+			}
+			names = append(names, segments[i])
+			fd = fd.MapValue()
+			desc = fd.Message()
+		case fd.IsList():
+			if i < len(segments)-1 {
+				return nil, nil, fmt.Errorf("cannot traverse into repeated field %s", fd.Name())
+			}
+		default:
+			desc = fd.Message()
+		}
+	}
+	return names, fd, nil
+}
@@ -0,0 +1,129 @@
+package query_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+	aip "github.com/hxtk/aip/query"
+)
+
+func TestParseFieldPath(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"title", []string{"title"}},
+		{"author.given_name", []string{"author", "given_name"}},
+		{"reviews.`a.b`", []string{"reviews", "a.b"}},
+		{"reviews.`a``b`.rating", []string{"reviews", "a`b", "rating"}},
+		{"items.5", []string{"items", "5"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.text, func(t *testing.T) {
+			path, err := aip.ParseFieldPath(tc.text)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, path.Segments())
+			require.True(t, path.Equals(aip.NewFieldPath(tc.want...)))
+
+			// The canonical text parses to the same path.
+			again, err := aip.ParseFieldPath(path.String())
+			require.NoError(t, err)
+			require.True(t, again.Equals(path))
+		})
+	}
+
+	for _, text := range []string{"", ".", "title.", ".title", "a..b", "`a", "`a`b", "a`b`"} {
+		_, err := aip.ParseFieldPath(text)
+		require.Error(t, err, text)
+	}
+}
+
+func TestFieldPath_SegmentsAreCopied(t *testing.T) {
+	path := aip.NewFieldPath("author", "given_name")
+	path.Segments()[0] = "authors"
+	require.Equal(t, []string{"author", "given_name"}, path.Segments())
+}
+
+func TestFieldPathsFromMask(t *testing.T) {
+	paths, err := aip.FieldPathsFromMask(&fieldmaskpb.FieldMask{Paths: []string{"title", "detailed_reviews.`bob`.rating"}})
+	require.NoError(t, err)
+	require.Len(t, paths, 2)
+	require.Equal(t, "title", paths[0].String())
+	require.Equal(t, []string{"detailed_reviews", "bob", "rating"}, paths[1].Segments())
+
+	paths, err = aip.FieldPathsFromMask(nil)
+	require.NoError(t, err)
+	require.Empty(t, paths)
+
+	_, err = aip.FieldPathsFromMask(&fieldmaskpb.FieldMask{Paths: []string{"title", "a..b"}})
+	require.Error(t, err)
+}
+
+func TestFieldPathFromJSON(t *testing.T) {
+	desc := (&testpb.Book{}).ProtoReflect().Descriptor()
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"title", "title"},
+		{"author.givenName", "author.given_name"},
+		{"author.given_name", "author.given_name"},
+		{"pageCount", "page_count"},
+		{"detailedReviews.aliceSmith.rating", "detailed_reviews.aliceSmith.rating"},
+		{"items.5", "items.`5`"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.text, func(t *testing.T) {
+			path, err := aip.FieldPathFromJSON(desc, tc.text)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, path.String())
+		})
+	}
+
+	for _, text := range []string{"nope", "author.nope", "title.length", "authors.givenName", "items.x"} {
+		_, err := aip.FieldPathFromJSON(desc, text)
+		require.Error(t, err, text)
+	}
+}
+
+func TestFieldPath_Descriptor(t *testing.T) {
+	desc := (&testpb.Book{}).ProtoReflect().Descriptor()
+	tests := []struct {
+		path aip.FieldPath
+		name protoreflect.FullName
+		kind protoreflect.Kind
+	}{
+		{aip.NewFieldPath("title"), "test.Book.title", protoreflect.StringKind},
+		{aip.NewFieldPath("author", "given_name"), "test.Author.given_name", protoreflect.StringKind},
+		{aip.NewFieldPath("authors"), "test.Book.authors", protoreflect.MessageKind},
+		{aip.NewFieldPath("reviews"), "test.Book.reviews", protoreflect.MessageKind},
+		{aip.NewFieldPath("reviews", "alice"), "test.Book.ReviewsEntry.value", protoreflect.StringKind},
+		{aip.NewFieldPath("detailed_reviews", "alice", "rating"), "test.Review.rating", protoreflect.Int32Kind},
+		{aip.NewFieldPath("items", "5"), "test.Book.ItemsEntry.value", protoreflect.StringKind},
+	}
+	for _, tc := range tests {
+		t.Run(tc.path.String(), func(t *testing.T) {
+			fd, err := tc.path.Descriptor(desc)
+			require.NoError(t, err)
+			require.Equal(t, tc.name, fd.FullName())
+			require.Equal(t, tc.kind, fd.Kind())
+		})
+	}
+
+	for _, path := range []aip.FieldPath{
+		{},
+		aip.NewFieldPath("nope"),
+		aip.NewFieldPath("title", "length"),
+		aip.NewFieldPath("authors", "given_name"),
+		aip.NewFieldPath("items", "x"),
+		aip.NewFieldPath("reviews", "alice", "text"),
+		aip.NewFieldPath("author", "givenName"),
+	} {
+		_, err := path.Descriptor(desc)
+		require.Error(t, err, path.String())
+	}
+}
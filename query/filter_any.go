@@ -0,0 +1,71 @@
+package query
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// anyName is the full name of google.protobuf.Any.
+const anyName protoreflect.FullName = "google.protobuf.Any"
+
+// anyTypeField is the pseudo-field of a google.protobuf.Any holding its
+// type URL, as in the JSON mapping of Any.
+const anyTypeField = "@type"
+
+// resolveAnyPath resolves fields in m, a google.protobuf.Any. The @type
+// pseudo-field resolves to the type URL, e.g.
+// "type.googleapis.com/google.protobuf.Duration". Other fields are resolved
+// in the packed message if its type is in protoregistry.GlobalTypes and has
+// them; otherwise they resolve to nil, so that a filter may mention the
+// fields of several types packed in the same field.
+func resolveAnyPath(m protoreflect.Message, fields []string) (any, error) {
+	if fields[0] == anyTypeField {
+		if len(fields) > 1 {
+			return nil, fmt.Errorf("cannot descend into %s", anyTypeField)
+		}
+		return m.Get(m.Descriptor().Fields().ByName("type_url")).String(), nil
+	}
+	packed := unpackAny(m)
+	if packed == nil || checkSubfields(packed.Descriptor(), fields) != nil {
+		return nil, nil
+	}
+	return resolveMemberValueFromMessage(packed, fields)
+}
+
+// hasAnyPath reports whether path is present in m, a google.protobuf.Any, as
+// resolved by resolveAnyPath.
+func hasAnyPath(m protoreflect.Message, path []string) (bool, error) {
+	if path[0] == anyTypeField {
+		if len(path) > 1 {
+			return false, fmt.Errorf("cannot descend into %s", anyTypeField)
+		}
+		return m.Get(m.Descriptor().Fields().ByName("type_url")).String() != "", nil
+	}
+	packed := unpackAny(m)
+	if packed == nil || checkSubfields(packed.Descriptor(), path) != nil {
+		return false, nil
+	}
+	return hasFieldPath(packed, path)
+}
+
+// unpackAny returns the message packed in m, a google.protobuf.Any, or nil
+// if m is empty, its type is not registered, or its value is malformed.
+func unpackAny(m protoreflect.Message) protoreflect.Message {
+	fields := m.Descriptor().Fields()
+	url := m.Get(fields.ByName("type_url")).String()
+	if url == "" {
+		return nil
+	}
+	mt, err := protoregistry.GlobalTypes.FindMessageByURL(url)
+	if err != nil {
+		return nil
+	}
+	packed := mt.New()
+	if err := proto.Unmarshal(m.Get(fields.ByName("value")).Bytes(), packed.Interface()); err != nil {
+		return nil
+	}
+	return packed
+}
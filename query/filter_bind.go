@@ -0,0 +1,173 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BindFilter returns a copy of f with each placeholder replaced by its value
+// in bindings, so that servers can ship canned filters such as
+// `owner = @me` and fill them in per request:
+//
+//	f, err := query.BindFilter(mineFilter, map[string]string{"me": caller})
+//
+// A placeholder is an unquoted argument, or a bare value searched for, of
+// the form @name; quoted values such as "@name" are literals. Values are substituted as literals after parsing, so unlike
+// interpolating them into the filter text they cannot change the structure
+// of the filter. A value of `*` cannot be bound to the has operator, where
+// it would turn the restriction into a presence test.
+//
+// BindFilter returns an error if a placeholder has no binding, or is used as
+// a field, e.g. `@me = owner` or `@me.name = "x"`. f is not modified; a
+// filter without placeholders is returned as is.
+func BindFilter(f *Filter, bindings map[string]string) (*Filter, error) {
+	if f == nil || f.Expression == nil || !hasPlaceholder(f.Expression) {
+		return f, nil
+	}
+	e := cloneExpression(f.Expression)
+	if err := bindExpression(e, bindings); err != nil {
+		return nil, err
+	}
+	return &Filter{Expression: e}, nil
+}
+
+// WithBindings binds the placeholders of the filter compiled by ProtoFilter,
+// ProtoFilterCtx, WireFilter or DynamicFilter to bindings, as by
+// BindFilter. Without it, values beginning with @ are not placeholders.
+func WithBindings(bindings map[string]string) FilterOption {
+	return func(o *filterOptions) {
+		o.bindings = bindings
+	}
+}
+
+// bind binds the placeholders of f if bindings were given with
+// WithBindings. Without them, values beginning with @ are plain literals.
+func (o *filterOptions) bind(f *Filter) (*Filter, error) {
+	if o.bindings == nil {
+		return f, nil
+	}
+	return BindFilter(f, o.bindings)
+}
+
+// placeholder returns the name of the placeholder m is, if it is one.
+func placeholder(m *Member) (string, bool) {
+	if m == nil || m.Kind != LiteralText || len(m.Value) < 2 || m.Value[0] != '@' {
+		return "", false
+	}
+	return m.Value[1:], true
+}
+
+// hasPlaceholder reports whether e mentions a placeholder anywhere.
+func hasPlaceholder(e *Expression) bool {
+	found := false
+	walkRestrictions(e, func(r *Restriction) {
+		if r.Comparable != nil {
+			_, ok := placeholder(r.Comparable.Member)
+			found = found || ok
+		}
+		if r.Arg != nil && r.Arg.Comparable != nil {
+			_, ok := placeholder(r.Arg.Comparable.Member)
+			found = found || ok
+		}
+	})
+	return found
+}
+
+// walkRestrictions calls fn with each restriction of e, including those in
+// composite arguments.
+func walkRestrictions(e *Expression, fn func(*Restriction)) {
+	for _, seq := range e.Sequences {
+		for _, f := range seq.Factors {
+			for _, t := range f.Terms {
+				if r := t.Simple.Restriction; r != nil {
+					fn(r)
+					if r.Arg != nil && r.Arg.Composite != nil {
+						walkRestrictions(r.Arg.Composite, fn)
+					}
+				}
+				if t.Simple.Composite != nil {
+					walkRestrictions(t.Simple.Composite, fn)
+				}
+			}
+		}
+	}
+}
+
+// bindExpression replaces the placeholders of e in place.
+func bindExpression(e *Expression, bindings map[string]string) error {
+	var err error
+	walkRestrictions(e, func(r *Restriction) {
+		if err == nil {
+			err = bindRestriction(r, bindings)
+		}
+	})
+	return err
+}
+
+// bindRestriction replaces the placeholders of r in place.
+func bindRestriction(r *Restriction, bindings map[string]string) error {
+	if r.Comparable == nil {
+		return nil
+	}
+	if r.Comparable.Function != nil {
+		if err := bindFunction(r.Comparable.Function, bindings); err != nil {
+			return err
+		}
+	}
+	if r.Comparator == "" {
+		// A bare value is searched for, so it may be bound.
+		return bindMember(r.Comparable.Member, bindings)
+	}
+	if name, ok := placeholder(r.Comparable.Member); ok {
+		return fmt.Errorf("placeholder @%s cannot be used as a field", name)
+	}
+	if r.Arg == nil || r.Arg.Comparable == nil {
+		return nil
+	}
+	if r.Arg.Comparable.Function != nil {
+		return bindFunction(r.Arg.Comparable.Function, bindings)
+	}
+	m := r.Arg.Comparable.Member
+	name, ok := placeholder(m)
+	if ok && r.Comparator == ":" && bindings[name] == "*" && len(m.Fields) == 0 {
+		return fmt.Errorf("placeholder @%s cannot be bound to %q for the has operator", name, "*")
+	}
+	return bindMember(m, bindings)
+}
+
+// bindFunction replaces the placeholders among the arguments of fn in place.
+func bindFunction(fn *Function, bindings map[string]string) error {
+	for _, a := range fn.Args {
+		if a.Comparable == nil {
+			continue
+		}
+		if a.Comparable.Function != nil {
+			if err := bindFunction(a.Comparable.Function, bindings); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := bindMember(a.Comparable.Member, bindings); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bindMember replaces m with its binding if it is a placeholder.
+func bindMember(m *Member, bindings map[string]string) error {
+	name, ok := placeholder(m)
+	if !ok {
+		return nil
+	}
+	if len(m.Fields) > 0 {
+		return fmt.Errorf("placeholder @%s cannot be used as a field", name+"."+strings.Join(m.Fields, "."))
+	}
+	value, ok := bindings[name]
+	if !ok {
+		return fmt.Errorf("unbound placeholder @%s", name)
+	}
+	m.Value = value
+	m.Kind, m.Literal = LiteralString, nil
+	return nil
+}
@@ -0,0 +1,85 @@
+package query_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/query"
+)
+
+func TestBindFilter(t *testing.T) {
+	bindings := map[string]string{
+		"me":     "users/alice",
+		"title":  `Dune" OR title = "x`,
+		"star":   "*",
+		"search": "Herbert",
+	}
+	bind := func(filter string) (*query.Filter, error) {
+		return query.BindFilter(mustParse(t, filter), bindings)
+	}
+
+	f, err := bind(`owner = @me AND (title = @title OR Herbert)`)
+	require.NoError(t, err)
+	require.Equal(t, mustParse(t, `owner = "users/alice" AND (title = "Dune\" OR title = \"x" OR Herbert)`).String(), f.String())
+
+	f, err = bind(`@search`)
+	require.NoError(t, err)
+	require.Equal(t, mustParse(t, `"Herbert"`).String(), f.String())
+
+	// Quoted values are literals.
+	f, err = bind(`title:"@home" AND owner = @me`)
+	require.NoError(t, err)
+	require.Equal(t, mustParse(t, `title:"@home" AND owner = "users/alice"`).String(), f.String())
+
+	f, err = bind(`title = @star`)
+	require.NoError(t, err)
+	require.Equal(t, mustParse(t, `title = "*"`).String(), f.String())
+
+	// The parsed filter is left alone, and filters without placeholders are
+	// returned as is.
+	parsed := mustParse(t, `owner = @me`)
+	_, err = query.BindFilter(parsed, bindings)
+	require.NoError(t, err)
+	require.Equal(t, mustParse(t, `owner = @me`).String(), parsed.String())
+	parsed = mustParse(t, `owner = bob`)
+	f, err = query.BindFilter(parsed, bindings)
+	require.NoError(t, err)
+	require.Same(t, parsed, f)
+
+	for filter, want := range map[string]string{
+		`owner = @you`:       "unbound placeholder @you",
+		`@me = owner`:        "placeholder @me cannot be used as a field",
+		`owner = @me.name`:   "placeholder @me.name cannot be used as a field",
+		`tags:@star`:         `placeholder @star cannot be bound to "*"`,
+		`-(owner = (@nope))`: "unbound placeholder @nope",
+	} {
+		_, err := bind(filter)
+		require.ErrorContains(t, err, want, filter)
+	}
+}
+
+func TestWithBindings(t *testing.T) {
+	books := []*testpb.Book{{Title: "Dune"}, {Title: "@title"}}
+	f := mustParse(t, `title = @title`)
+
+	pred, err := query.ProtoFilter[testpb.Book](f, query.WithBindings(map[string]string{"title": "Dune"}))
+	require.NoError(t, err)
+	require.True(t, pred(books[0]))
+	require.False(t, pred(books[1]))
+
+	_, err = query.ProtoFilter[testpb.Book](f, query.WithBindings(map[string]string{}))
+	require.ErrorContains(t, err, "unbound placeholder @title")
+
+	pred, err = query.ProtoFilter[testpb.Book](mustParse(t, `title:"@home"`), query.WithBindings(map[string]string{"title": "Dune"}))
+	require.NoError(t, err)
+	require.True(t, pred(&testpb.Book{Title: "@home"}))
+	require.False(t, pred(books[0]))
+
+	// Without bindings, values beginning with @ are literals.
+	pred, err = query.ProtoFilter[testpb.Book](f)
+	require.NoError(t, err)
+	require.False(t, pred(books[0]))
+	require.True(t, pred(books[1]))
+}
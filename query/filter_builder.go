@@ -0,0 +1,225 @@
+package query
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Eq returns the filter `field = value`, with the AST ParseFilter returns
+// for it, e.g. to add mandatory restrictions to the filter of a request
+// without concatenating filter text:
+//
+//	f = f.And(query.Eq("owner", user))
+//
+// The field is a path of field names separated by dots, e.g.
+// "author.given_name". The value is written as a literal of its type:
+// strings as quoted strings, integers, floats and bools as such, nil as
+// null, enums by name, time.Time values as RFC 3339 timestamps and
+// time.Duration values as durations in seconds, e.g. "1.5s". Eq panics
+// for values of other types, and for infinite and NaN floats, which have no
+// literal.
+func Eq(field string, value any) *Filter {
+	return restrictionFilter(field, "=", value)
+}
+
+// Ne returns the filter `field != value`. See Eq.
+func Ne(field string, value any) *Filter {
+	return restrictionFilter(field, "!=", value)
+}
+
+// Lt returns the filter `field < value`. See Eq.
+func Lt(field string, value any) *Filter {
+	return restrictionFilter(field, "<", value)
+}
+
+// Le returns the filter `field <= value`. See Eq.
+func Le(field string, value any) *Filter {
+	return restrictionFilter(field, "<=", value)
+}
+
+// Gt returns the filter `field > value`. See Eq.
+func Gt(field string, value any) *Filter {
+	return restrictionFilter(field, ">", value)
+}
+
+// Ge returns the filter `field >= value`. See Eq.
+func Ge(field string, value any) *Filter {
+	return restrictionFilter(field, ">=", value)
+}
+
+// Has returns the filter `field:value`, e.g. Has("tags", "sci-fi"). See Eq.
+func Has(field string, value any) *Filter {
+	return restrictionFilter(field, ":", value)
+}
+
+// Present returns the presence test `field:*`.
+func Present(field string) *Filter {
+	return termFilter(&Term{Simple: &Simple{Restriction: &Restriction{
+		Comparable: &Comparable{Member: fieldMember(field)},
+		Comparator: ":",
+		Arg:        &Arg{Comparable: &Comparable{Member: &Member{Value: "*"}}},
+	}}})
+}
+
+// Not returns the negation of f, `NOT f`, parenthesizing f unless it is a
+// single restriction. Not panics if f is empty, since no filter matches
+// nothing.
+func Not(f *Filter) *Filter {
+	if f == nil || f.Expression == nil {
+		panic("cannot negate an empty filter")
+	}
+	if t := singleTerm(f); t != nil && !t.Negated {
+		return termFilter(&Term{Negated: true, Simple: t.Simple})
+	}
+	return termFilter(&Term{Negated: true, Simple: &Simple{Composite: f.Expression}})
+}
+
+// And returns a filter matching what f and all of others match, `f AND
+// others...`. Empty filters, which match everything, are left out. The
+// result shares nodes with f and others.
+func (f *Filter) And(others ...*Filter) *Filter {
+	result := f
+	for _, o := range others {
+		result = andFilters(result, o)
+	}
+	if result == nil {
+		return &Filter{}
+	}
+	return result
+}
+
+// Or returns a filter matching what f or any of others match, `f OR
+// others...`, parenthesizing conjunctions. If any of them is empty, it
+// matches everything and so is the result. The result shares nodes with f
+// and others.
+func (f *Filter) Or(others ...*Filter) *Filter {
+	factor := &Factor{}
+	for _, o := range append([]*Filter{f}, others...) {
+		if o == nil || o.Expression == nil {
+			return &Filter{}
+		}
+		if seq := o.Expression.Sequences; len(seq) == 1 && len(seq[0].Factors) == 1 {
+			factor.Terms = append(factor.Terms, seq[0].Factors[0].Terms...)
+			continue
+		}
+		factor.Terms = append(factor.Terms, &Term{Simple: &Simple{Composite: o.Expression}})
+	}
+	return &Filter{Expression: &Expression{Sequences: []*Sequence{{Factors: []*Factor{factor}}}}}
+}
+
+// restrictionFilter returns the filter `field <comparator> value`.
+func restrictionFilter(field, comparator string, value any) *Filter {
+	return termFilter(&Term{Simple: &Simple{Restriction: &Restriction{
+		Comparable: &Comparable{Member: fieldMember(field)},
+		Comparator: comparator,
+		Arg:        &Arg{Comparable: &Comparable{Member: literalMember(value)}},
+	}}})
+}
+
+// termFilter returns the filter of the single term t.
+func termFilter(t *Term) *Filter {
+	return &Filter{Expression: &Expression{Sequences: []*Sequence{{
+		Factors: []*Factor{{Terms: []*Term{t}}},
+	}}}}
+}
+
+// singleTerm returns the only term of f, or nil if it has several.
+func singleTerm(f *Filter) *Term {
+	seq := f.Expression.Sequences
+	if len(seq) != 1 || len(seq[0].Factors) != 1 || len(seq[0].Factors[0].Terms) != 1 {
+		return nil
+	}
+	return seq[0].Factors[0].Terms[0]
+}
+
+// fieldMember returns the member naming the field at the dot-separated path.
+func fieldMember(path string) *Member {
+	value, rest, ok := strings.Cut(path, ".")
+	m := &Member{Value: value, Kind: LiteralText}
+	if ok {
+		m.Fields = strings.Split(rest, ".")
+	}
+	return m
+}
+
+// literalMember returns the member of the literal value, as ParseFilter
+// would parse it.
+func literalMember(value any) *Member {
+	switch v := value.(type) {
+	case nil:
+		return &Member{Value: "null", Kind: LiteralNull}
+	case string:
+		return &Member{Value: v, Kind: LiteralString}
+	case bool:
+		return &Member{Value: strconv.FormatBool(v), Kind: LiteralBool, Literal: v}
+	case int:
+		return intMember(int64(v))
+	case int8:
+		return intMember(int64(v))
+	case int16:
+		return intMember(int64(v))
+	case int32:
+		return intMember(int64(v))
+	case int64:
+		return intMember(v)
+	case uint:
+		return uintMember(uint64(v))
+	case uint8:
+		return intMember(int64(v))
+	case uint16:
+		return intMember(int64(v))
+	case uint32:
+		return intMember(int64(v))
+	case uint64:
+		return uintMember(v)
+	case float32:
+		return floatMember(float64(v))
+	case float64:
+		return floatMember(v)
+	case time.Time:
+		return &Member{Value: v.Format(time.RFC3339Nano), Kind: LiteralString}
+	case time.Duration:
+		return &Member{Value: strconv.FormatFloat(v.Seconds(), 'f', -1, 64) + "s", Kind: LiteralString}
+	case protoreflect.Enum:
+		if ev := v.Descriptor().Values().ByNumber(v.Number()); ev != nil {
+			return &Member{Value: string(ev.Name()), Kind: LiteralString}
+		}
+		return intMember(int64(v.Number()))
+	}
+	panic(fmt.Sprintf("unsupported filter value %v of type %T", value, value))
+}
+
+// intMember returns the member of the integer literal n.
+func intMember(n int64) *Member {
+	return &Member{Value: strconv.FormatInt(n, 10), Kind: LiteralInt, Literal: n}
+}
+
+// uintMember returns the member of the unsigned integer literal n. Those too
+// large for an int64 are written in full but parsed as floats, as
+// ParseFilter parses them.
+func uintMember(n uint64) *Member {
+	if n <= math.MaxInt64 {
+		return intMember(int64(n))
+	}
+	s := strconv.FormatUint(n, 10)
+	kind, literal := classifyLiteral(s)
+	return &Member{Value: s, Kind: kind, Literal: literal}
+}
+
+// floatMember returns the member of the float literal f.
+func floatMember(f float64) *Member {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		panic(fmt.Sprintf("unsupported filter value %v", f))
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if intLiteral.MatchString(s) {
+		// Whole floats are written with a fraction, as they are parsed.
+		s += ".0"
+	}
+	return &Member{Value: s, Kind: LiteralFloat, Literal: f}
+}
@@ -0,0 +1,71 @@
+package query_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+	aip "github.com/hxtk/aip/query"
+)
+
+func TestFilterBuilder(t *testing.T) {
+	tests := []struct {
+		name string
+		got  *aip.Filter
+		want string
+	}{
+		{"Eq", aip.Eq("title", "Dune"), `title = "Dune"`},
+		{"Ne", aip.Ne("author.given_name", "Frank"), `author.given_name != "Frank"`},
+		{"Lt", aip.Lt("page_count", 100), `page_count < 100`},
+		{"Le", aip.Le("page_count", int64(-5)), `page_count <= -5`},
+		{"Gt", aip.Gt("average_rating", 4.5), `average_rating > 4.5`},
+		{"Ge", aip.Ge("average_rating", 4.0), `average_rating >= 4.0`},
+		{"Has", aip.Has("tags", "sci-fi"), `tags:"sci-fi"`},
+		{"Present", aip.Present("reviews.alice"), `reviews.alice:*`},
+		{"Int8", aip.Eq("a", int8(-8)), `a = -8`},
+		{"Int16", aip.Eq("a", int16(-16)), `a = -16`},
+		{"Uint", aip.Eq("a", uint(7)), `a = 7`},
+		{"Uint8", aip.Eq("a", uint8(8)), `a = 8`},
+		{"Uint16", aip.Eq("a", uint16(16)), `a = 16`},
+		{"Uint64", aip.Eq("a", uint64(64)), `a = 64`},
+		{"Uint64Max", aip.Eq("a", uint64(math.MaxUint64)), `a = 18446744073709551615`},
+		{"Bool", aip.Eq("available", true), `available = true`},
+		{"Null", aip.Eq("author", nil), `author = null`},
+		{"Enum", aip.Eq("format", testpb.Format_HARDCOVER), `format = "HARDCOVER"`},
+		{"Time", aip.Gt("create_time", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)), `create_time > "2024-06-01T00:00:00Z"`},
+		{"Duration", aip.Lt("ttl", 1500*time.Millisecond), `ttl < "1.5s"`},
+		{"Not", aip.Not(aip.Eq("title", "Dune")), `NOT title = "Dune"`},
+		{"NotGroup", aip.Not(aip.Eq("a", 1).Or(aip.Eq("b", 2))), `NOT (a = 1 OR b = 2)`},
+		{"NotNot", aip.Not(aip.Not(aip.Eq("a", 1))), `NOT (NOT a = 1)`},
+		{"And", aip.Eq("a", 1).And(aip.Eq("b", 2), aip.Eq("c", 3)), `a = 1 AND b = 2 AND c = 3`},
+		{"Or", aip.Eq("a", 1).Or(aip.Eq("b", 2), aip.Eq("c", 3)), `a = 1 OR b = 2 OR c = 3`},
+		{"AndOr", aip.Eq("a", 1).Or(aip.Eq("b", 2)).And(aip.Eq("c", 3)), `a = 1 OR b = 2 AND c = 3`},
+		{"OrAnd", aip.Eq("a", 1).And(aip.Eq("b", 2)).Or(aip.Eq("c", 3)), `(a = 1 AND b = 2) OR c = 3`},
+		{"AndEmpty", (&aip.Filter{}).And(aip.Eq("a", 1), nil), `a = 1`},
+		{"OrEmpty", aip.Eq("a", 1).Or(&aip.Filter{}), ``},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, mustParse(t, tc.want), tc.got)
+			require.Equal(t, tc.want, tc.got.String())
+		})
+	}
+
+	require.Panics(t, func() { aip.Eq("title", struct{}{}) })
+	require.Panics(t, func() { aip.Eq("average_rating", math.Inf(1)) })
+	require.Panics(t, func() { aip.Eq("average_rating", math.NaN()) })
+	require.Panics(t, func() { aip.Not(&aip.Filter{}) })
+}
+
+func TestFilterBuilder_MandatoryRestriction(t *testing.T) {
+	// A restriction added to the filter of a request applies whatever the
+	// request filter is, e.g. one ending in OR.
+	f := mustParse(t, `title = Dune OR title = Emma`).And(aip.Eq("author.given_name", "Frank"))
+	filter, err := aip.ProtoFilter[testpb.Book](f)
+	require.NoError(t, err)
+	require.True(t, filter(&testpb.Book{Title: "Dune", Author: &testpb.Author{GivenName: "Frank"}}))
+	require.False(t, filter(&testpb.Book{Title: "Emma", Author: &testpb.Author{GivenName: "Jane"}}))
+}
@@ -0,0 +1,175 @@
+package query
+
+import (
+	"slices"
+	"strings"
+)
+
+// Canonicalize returns a filter equivalent to f in a canonical form, so that
+// semantically equal filters have equal ASTs and equal String() values, e.g.
+// for use as cache keys, in authorization decisions, or in audit logs.
+//
+// The canonical form:
+//   - joins all top-level conjuncts with a single implicit AND, flattening
+//     parenthesized conjunctions and disjunctions into their parent;
+//   - sorts the operands of AND and OR and drops duplicates;
+//   - removes redundant parentheses around single terms and folds double
+//     negations, e.g. NOT (NOT a = 1) becomes a = 1.
+//
+// String() quotes the text arguments of restrictions, so `title = Dune`
+// and `title = "Dune"` canonicalize to equal String() values. The quoting
+// of function arguments, which may name fields, is kept.
+//
+// Canonicalize does not modify f, and the result does not share nodes with
+// it. A nil or empty filter canonicalizes to an empty filter.
+func Canonicalize(f *Filter) *Filter {
+	if f == nil || f.Expression == nil {
+		return &Filter{}
+	}
+	canonical := factorFilter(canonicalConjuncts(f.Expression))
+	if canonical == nil {
+		return &Filter{}
+	}
+	return canonical
+}
+
+// canonicalConjuncts returns the canonical factors AND-ed together by e.
+func canonicalConjuncts(e *Expression) []*Factor {
+	var out []*Factor
+	for _, seq := range e.Sequences {
+		for _, f := range seq.Factors {
+			terms := canonicalDisjuncts(f)
+			if len(terms) == 1 && !terms[0].Negated && terms[0].Simple.Composite != nil {
+				// The composite is canonical, so it is one sequence of
+				// factors.
+				for _, seq := range terms[0].Simple.Composite.Sequences {
+					out = append(out, seq.Factors...)
+				}
+				continue
+			}
+			out = append(out, &Factor{Terms: terms})
+		}
+	}
+	return sortUnique(out)
+}
+
+// canonicalDisjuncts returns the canonical terms OR-ed together by f.
+func canonicalDisjuncts(f *Factor) []*Term {
+	var out []*Term
+	for _, t := range f.Terms {
+		t = canonicalTerm(t)
+		if c := t.Simple.Composite; !t.Negated && c != nil && len(c.Sequences) == 1 && len(c.Sequences[0].Factors) == 1 {
+			out = append(out, c.Sequences[0].Factors[0].Terms...)
+			continue
+		}
+		out = append(out, t)
+	}
+	return sortUnique(out)
+}
+
+// canonicalTerm returns the canonical form of t.
+func canonicalTerm(t *Term) *Term {
+	if t.Simple.Composite == nil {
+		return &Term{
+			Negated: t.Negated,
+			Simple:  &Simple{Restriction: cloneRestriction(t.Simple.Restriction)},
+		}
+	}
+
+	factors := canonicalConjuncts(t.Simple.Composite)
+	if len(factors) == 1 && len(factors[0].Terms) == 1 {
+		// Drop the parentheses around a single term, folding negations.
+		inner := factors[0].Terms[0]
+		return &Term{
+			Negated: t.Negated != inner.Negated,
+			Simple:  inner.Simple,
+		}
+	}
+	composite := &Expression{}
+	if len(factors) > 0 {
+		composite.Sequences = []*Sequence{{Factors: factors}}
+	}
+	return &Term{
+		Negated: t.Negated,
+		Simple:  &Simple{Composite: composite},
+	}
+}
+
+// cloneRestriction returns a deep copy of r.
+func cloneRestriction(r *Restriction) *Restriction {
+	if r == nil {
+		return nil
+	}
+	clone := &Restriction{
+		Comparable: cloneComparable(r.Comparable),
+		Comparator: r.Comparator,
+	}
+	if r.Arg != nil {
+		clone.Arg = &Arg{Comparable: cloneComparable(r.Arg.Comparable)}
+		if r.Arg.Composite != nil {
+			clone.Arg.Composite = cloneExpression(r.Arg.Composite)
+		}
+	}
+	return clone
+}
+
+// cloneComparable returns a deep copy of c.
+func cloneComparable(c *Comparable) *Comparable {
+	if c == nil {
+		return nil
+	}
+	if c.Function != nil {
+		fn := &Function{Name: c.Function.Name}
+		for _, a := range c.Function.Args {
+			arg := &Arg{Comparable: cloneComparable(a.Comparable)}
+			if a.Composite != nil {
+				arg.Composite = cloneExpression(a.Composite)
+			}
+			fn.Args = append(fn.Args, arg)
+		}
+		return &Comparable{Function: fn}
+	}
+	if c.Member == nil {
+		return &Comparable{}
+	}
+	return &Comparable{Member: &Member{
+		Value:   c.Member.Value,
+		Fields:  slices.Clone(c.Member.Fields),
+		Kind:    c.Member.Kind,
+		Literal: c.Member.Literal,
+	}}
+}
+
+// cloneExpression returns a deep copy of e.
+func cloneExpression(e *Expression) *Expression {
+	clone := &Expression{}
+	for _, seq := range e.Sequences {
+		s := &Sequence{}
+		for _, f := range seq.Factors {
+			factor := &Factor{}
+			for _, t := range f.Terms {
+				term := &Term{Negated: t.Negated, Simple: &Simple{}}
+				if t.Simple.Restriction != nil {
+					term.Simple.Restriction = cloneRestriction(t.Simple.Restriction)
+				}
+				if t.Simple.Composite != nil {
+					term.Simple.Composite = cloneExpression(t.Simple.Composite)
+				}
+				factor.Terms = append(factor.Terms, term)
+			}
+			s.Factors = append(s.Factors, factor)
+		}
+		clone.Sequences = append(clone.Sequences, s)
+	}
+	return clone
+}
+
+// sortUnique sorts nodes by their String() value and removes duplicates.
+func sortUnique[T interface{ String() string }](nodes []T) []T {
+	slices.SortFunc(nodes, func(a, b T) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	return slices.CompactFunc(nodes, func(a, b T) bool {
+		return a.String() == b.String()
+	})
+}
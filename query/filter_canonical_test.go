@@ -0,0 +1,81 @@
+package query
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCanonicalize(t *testing.T) {
+	Convey("Canonicalize", t, func() {
+		canonical := func(filter string) string {
+			f, err := ParseFilter(filter)
+			So(err, ShouldBeNil)
+			return Canonicalize(f).String()
+		}
+
+		Convey("Empty filter", func() {
+			So(Canonicalize(nil), ShouldResemble, &Filter{})
+			So(canonical(""), ShouldEqual, "")
+		})
+		Convey("Conjunctions are commutative", func() {
+			So(canonical("a = 1 AND b = 2"), ShouldEqual, canonical("b = 2 AND a = 1"))
+			So(canonical("a = 1 b = 2 c = 3"), ShouldEqual, canonical("c = 3 AND (b = 2 AND a = 1)"))
+		})
+		Convey("Disjunctions are commutative", func() {
+			So(canonical("a = 1 OR b = 2"), ShouldEqual, canonical("b = 2 OR a = 1"))
+			So(canonical("a = 1 OR (b = 2 OR c = 3)"), ShouldEqual, canonical("(c = 3 OR a = 1) OR b = 2"))
+		})
+		Convey("Duplicates are removed", func() {
+			So(canonical("a = 1 AND a = 1"), ShouldEqual, canonical("a = 1"))
+			So(canonical("a = 1 OR a = 1"), ShouldEqual, canonical("a = 1"))
+		})
+		Convey("Quoting is normalized", func() {
+			So(canonical(`title = "Dune"`), ShouldEqual, canonical("title = Dune"))
+		})
+		Convey("Double negations are folded", func() {
+			So(canonical("NOT (NOT a = 1)"), ShouldEqual, canonical("a = 1"))
+			So(canonical("-(NOT a = 1)"), ShouldEqual, canonical("a = 1"))
+			So(canonical("NOT (a = 1)"), ShouldEqual, canonical("-a = 1"))
+		})
+		Convey("Negated groups are kept", func() {
+			So(canonical("NOT (a = 1 OR b = 2)"), ShouldEqual, canonical("NOT (b = 2 OR a = 1)"))
+			So(canonical("NOT (a = 1 OR b = 2)"), ShouldNotEqual, canonical("a = 1 OR b = 2"))
+		})
+		Convey("Function calls are kept", func() {
+			So(canonical(`f(a, "b") AND c = 1`), ShouldEqual, canonical(`c = 1 AND f(a, "b")`))
+			So(canonical(`f(a, "b")`), ShouldNotEqual, canonical(`f(a, b)`))
+			So(canonical("f(a, b)"), ShouldNotEqual, canonical("f(b, a)"))
+			So(canonical("f(a)"), ShouldNotEqual, canonical("f a"))
+		})
+		Convey("Different filters stay different", func() {
+			So(canonical("a = 1"), ShouldNotEqual, canonical("a != 1"))
+			So(canonical("(a = 1 OR b = 2) AND c = 3"), ShouldNotEqual, canonical("a = 1 OR (b = 2 AND c = 3)"))
+		})
+		Convey("Canonicalization is idempotent", func() {
+			f, err := ParseFilter("c = 3 AND NOT (b = 2 OR NOT (a = 1)) AND (x OR y)")
+			So(err, ShouldBeNil)
+			once := Canonicalize(f)
+			So(Canonicalize(once), ShouldResemble, once)
+		})
+		Convey("The input is not modified", func() {
+			f, err := ParseFilter("b = 2 AND a = 1")
+			So(err, ShouldBeNil)
+			before := f.String()
+			Canonicalize(f).Expression.Sequences[0].Factors[0].Terms[0].Simple.Restriction.Comparable.Member.Value = "z"
+			So(f.String(), ShouldEqual, before)
+		})
+		Convey("Canonical filters evaluate the same", func() {
+			table := NewTable().WithColumns(
+				NewColumn().WithFieldPath("a").WithDatabaseName("db_a").Filterable().Build(),
+				NewColumn().WithFieldPath("b").WithDatabaseName("db_b").Filterable().Build(),
+			).Build()
+			f, err := ParseFilter("b = y AND (a = x)")
+			So(err, ShouldBeNil)
+			where, params, err := table.WhereClause(Canonicalize(f), "p_")
+			So(err, ShouldBeNil)
+			So(where, ShouldEqual, "((db_a = @p_0) AND (db_b = @p_1))")
+			So(params, ShouldHaveLength, 2)
+		})
+	})
+}
@@ -1,12 +1,17 @@
 package query
 
 import (
+	"cmp"
+	"context"
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
 )
 
 // ProtoFilter compiles a parsed AIP-160 Filter into a type-safe predicate function.
@@ -23,7 +28,7 @@ import (
 func ProtoFilter[S any, M interface {
 	proto.Message
 	*S
-}](f *Filter) (func(M) bool, error) {
+}](f *Filter, opts ...FilterOption) (func(M) bool, error) {
 	if f == nil {
 		// empty filter always true
 		return func(M) bool { return true }, nil
@@ -34,70 +39,272 @@ func ProtoFilter[S any, M interface {
 	var zeroRaw S
 	var zero M = &zeroRaw
 
+	o := newFilterOptions(opts)
+	f, err := o.bind(f)
+	if err != nil {
+		return nil, err
+	}
+
 	// Perform validation once; discard result.
-	if _, err := matchesFilter(zero, f); err != nil {
+	if _, err := matchesFilter(nil, zero, f, o); err != nil {
 		return nil, err
 	}
 
 	// Return a pure boolean predicate closure.
 	return func(m M) bool {
-		ok, _ := matchesFilter(m, f)
+		ok, _ := matchesFilter(context.Background(), m, f, o)
+		return ok
+	}, nil
+}
+
+// ProtoFilterCtx is like ProtoFilter, but the returned predicate takes a
+// context, which is passed to any Matcher or Resolver (see WithMatcher and
+// WithResolver).
+//
+// Evaluation stops early with the context's error once it is canceled or its
+// deadline passes, so long scans over large messages honor request deadlines.
+// Errors returned by a Matcher or Resolver are returned by the predicate.
+//
+// Example:
+//
+//	f, err := query.ProtoFilterCtx[testpb.Book](filter,
+//	    query.WithMatcher("shared_with_me", func(ctx context.Context, msg proto.Message, comparator, arg string) (bool, error) {
+//	        return isSharedWith(msg.(*testpb.Book), callerFromContext(ctx)) == (arg == "true"), nil
+//	    }),
+//	)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	ok, err := f(ctx, book)
+func ProtoFilterCtx[S any, M interface {
+	proto.Message
+	*S
+}](f *Filter, opts ...FilterOption) (func(context.Context, M) (bool, error), error) {
+	if f == nil {
+		return func(context.Context, M) (bool, error) { return true, nil }, nil
+	}
+
+	var zeroRaw S
+	var zero M = &zeroRaw
+
+	o := newFilterOptions(opts)
+	f, err := o.bind(f)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := matchesFilter(nil, zero, f, o); err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, m M) (bool, error) {
+		return matchesFilter(ctx, m, f, o)
+	}, nil
+}
+
+// DynamicFilter is like ProtoFilter, but for messages whose type is only
+// known at runtime from desc, e.g. dynamicpb messages built from a
+// descriptor set. The returned closure must only be called with messages of
+// type desc.
+func DynamicFilter(desc protoreflect.MessageDescriptor, f *Filter, opts ...FilterOption) (func(proto.Message) bool, error) {
+	if f == nil {
+		return func(proto.Message) bool { return true }, nil
+	}
+
+	o := newFilterOptions(opts)
+	f, err := o.bind(f)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := matchesFilter(nil, dynamicpb.NewMessage(desc), f, o); err != nil {
+		return nil, err
+	}
+
+	return func(m proto.Message) bool {
+		ok, _ := matchesFilter(context.Background(), m, f, o)
 		return ok
 	}, nil
 }
 
+const (
+	// DefaultMaxSearchDepth is the default maximum depth of nested messages
+	// examined by a global restriction.
+	DefaultMaxSearchDepth = 32
+
+	// DefaultMaxSearchFields is the default maximum number of field values
+	// examined by a single global restriction on a single message.
+	DefaultMaxSearchFields = 10000
+)
+
+// FilterOption configures how a compiled filter evaluates messages.
+type FilterOption func(*filterOptions)
+
+type filterOptions struct {
+	maxSearchDepth  int
+	maxSearchFields int
+	matchers        map[string]Matcher
+	resolvers       map[string]Resolver
+	functions       *FunctionRegistry
+	budget          Budget
+	bindings        map[string]string
+
+	// normalize normalizes strings before they are compared, or is nil;
+	// see WithNormalization.
+	normalize func(string) string
+
+	// strictFields rejects unknown fields rather than comparing them as
+	// literals; see WithStrictFields.
+	strictFields bool
+
+	// warn is called with warnings about the filter, or is nil; see
+	// WithWarningHandler.
+	warn func(Warning)
+}
+
+func newFilterOptions(opts []FilterOption) *filterOptions {
+	o := &filterOptions{
+		maxSearchDepth:  DefaultMaxSearchDepth,
+		maxSearchFields: DefaultMaxSearchFields,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithMaxSearchDepth limits how many levels of nested messages a global
+// restriction (e.g. `Pragmatic`) descends into. Strings in messages nested
+// deeper than n levels below the root are not searched. A value of zero or
+// less means no limit.
+func WithMaxSearchDepth(n int) FilterOption {
+	return func(o *filterOptions) {
+		o.maxSearchDepth = n
+	}
+}
+
+// WithMaxSearchFields limits how many field values (including individual list
+// elements and map entries) a global restriction examines in one message
+// before giving up and reporting no match. A value of zero or less means no
+// limit.
+func WithMaxSearchFields(n int) FilterOption {
+	return func(o *filterOptions) {
+		o.maxSearchFields = n
+	}
+}
+
+// WithStrictFields rejects filters whose restrictions compare a name that is
+// neither a field of the message, a matcher nor a resolver, e.g. `tittle = "Dune"`,
+// with an error naming it. By default such names are compared as string
+// literals, which hides typos. Arguments, e.g. `Dune` in `title = Dune`, and
+// global restrictions are literals as usual.
+func WithStrictFields() FilterOption {
+	return func(o *filterOptions) {
+		o.strictFields = true
+	}
+}
+
+// Matcher evaluates a restriction on a virtual field, such as
+// `shared_with_me = true`, that cannot be answered from the message alone.
+//
+// comparator is the restriction's operator and arg its literal argument.
+// ctx is the context passed to the predicate returned by ProtoFilterCtx, or
+// context.Background() for ProtoFilter.
+type Matcher func(ctx context.Context, msg proto.Message, comparator string, arg string) (bool, error)
+
+// WithMatcher registers fn to evaluate restrictions whose comparable is the
+// bare name, e.g. `name = value` or `name:value`. A matcher takes precedence
+// over a message field with the same name. The argument of such restrictions
+// must be a literal.
+func WithMatcher(name string, fn Matcher) FilterOption {
+	return func(o *filterOptions) {
+		if o.matchers == nil {
+			o.matchers = make(map[string]Matcher)
+		}
+		o.matchers[name] = fn
+	}
+}
+
 // matchesFilter returns true if msg satisfies the filter expression.
 // Empty filter matches everything.
-func matchesFilter(msg proto.Message, f *Filter) (bool, error) {
+//
+// A nil ctx validates the filter without invoking any Matcher.
+func matchesFilter(ctx context.Context, msg proto.Message, f *Filter, o *filterOptions) (bool, error) {
 	if f == nil || f.Expression == nil {
 		return true, nil
 	}
-	return evalExpression(msg.ProtoReflect(), f.Expression)
+	ev := &evaluator{ctx: ctx, opts: o}
+	return ev.evalExpression(msg.ProtoReflect(), f.Expression)
+}
+
+// evaluator holds the configuration used while evaluating a filter against
+// a single message.
+type evaluator struct {
+	// ctx is the evaluation context, or nil when validating the filter.
+	ctx  context.Context
+	opts *filterOptions
 }
 
 // ---- AST evaluation (AND/OR/NOT/parentheses) ----
 
-func evalExpression(m protoreflect.Message, e *Expression) (bool, error) {
+// validating reports whether the filter is being validated rather than
+// evaluated. Validation evaluates every restriction rather than stopping
+// once the result is known, so that all of them are checked.
+func (ev *evaluator) validating() bool {
+	return ev.ctx == nil
+}
+
+func (ev *evaluator) evalExpression(m protoreflect.Message, e *Expression) (bool, error) {
+	result := true
 	for _, seq := range e.Sequences {
-		ok, err := evalSequence(m, seq)
+		ok, err := ev.evalSequence(m, seq)
 		if err != nil {
 			return false, err
 		}
 		if !ok {
-			return false, nil
+			if !ev.validating() {
+				return false, nil
+			}
+			result = false
 		}
 	}
-	return true, nil
+	return result, nil
 }
 
-func evalSequence(m protoreflect.Message, s *Sequence) (bool, error) {
+func (ev *evaluator) evalSequence(m protoreflect.Message, s *Sequence) (bool, error) {
+	result := true
 	for _, f := range s.Factors {
-		ok, err := evalFactor(m, f)
+		ok, err := ev.evalFactor(m, f)
 		if err != nil {
 			return false, err
 		}
 		if !ok {
-			return false, nil
+			if !ev.validating() {
+				return false, nil
+			}
+			result = false
 		}
 	}
-	return true, nil
+	return result, nil
 }
 
-func evalFactor(m protoreflect.Message, f *Factor) (bool, error) {
+func (ev *evaluator) evalFactor(m protoreflect.Message, f *Factor) (bool, error) {
+	result := false
 	for _, t := range f.Terms {
-		ok, err := evalTerm(m, t)
+		ok, err := ev.evalTerm(m, t)
 		if err != nil {
 			return false, err
 		}
 		if ok {
-			return true, nil
+			if !ev.validating() {
+				return true, nil
+			}
+			result = true
 		}
 	}
-	return false, nil
+	return result, nil
 }
 
-func evalTerm(m protoreflect.Message, t *Term) (bool, error) {
-	ok, err := evalSimple(m, t.Simple)
+func (ev *evaluator) evalTerm(m protoreflect.Message, t *Term) (bool, error) {
+	ok, err := ev.evalSimple(m, t.Simple)
 	if err != nil {
 		return false, err
 	}
@@ -107,26 +314,74 @@ func evalTerm(m protoreflect.Message, t *Term) (bool, error) {
 	return ok, nil
 }
 
-func evalSimple(m protoreflect.Message, s *Simple) (bool, error) {
+func (ev *evaluator) evalSimple(m protoreflect.Message, s *Simple) (bool, error) {
 	if s.Restriction != nil {
-		return evalRestriction(m, s.Restriction)
+		return ev.evalRestriction(m, s.Restriction)
 	}
 	if s.Composite != nil {
-		return evalExpression(m, s.Composite)
+		return ev.evalExpression(m, s.Composite)
 	}
 	return false, fmt.Errorf("invalid simple node")
 }
 
 // ---- restriction evaluation ----
 
-func evalRestriction(m protoreflect.Message, r *Restriction) (bool, error) {
-	// Case 1: global restriction — no comparator.
+func (ev *evaluator) evalRestriction(m protoreflect.Message, r *Restriction) (bool, error) {
+	if ev.ctx != nil {
+		if err := ev.ctx.Err(); err != nil {
+			return false, err
+		}
+	}
+
+	// Case 1: function call, e.g. `regex(title, "^The")`.
+	if r.Comparable.Function != nil {
+		return ev.evalFunctionRestriction(m, r)
+	}
+
+	// Case 2: global restriction — no comparator.
 	if r.Comparator == "" {
-		term := r.Comparable.Member.Value
-		return searchMessageStrings(m, term), nil
+		search := &stringSearch{
+			ctx:        ev.ctx,
+			term:       strings.ToLower(ev.opts.normalizeValue(r.Comparable.Member.Value).(string)),
+			normalize:  ev.opts.normalize,
+			maxDepth:   ev.opts.maxSearchDepth,
+			fieldsLeft: ev.opts.maxSearchFields,
+			unlimited:  ev.opts.maxSearchFields <= 0,
+		}
+		found := search.message(m, 0)
+		return found, search.err
+	}
+
+	// Case 3: custom matcher, e.g. `shared_with_me = true`.
+	if fn, ok := ev.opts.matchers[r.Comparable.Member.Value]; ok && len(r.Comparable.Member.Fields) == 0 {
+		return ev.evalMatcher(m, r, fn)
+	}
+
+	// Case 4: virtual field, e.g. `display_name = "Frank Herbert"`.
+	if _, ok := ev.resolver(r.Comparable.Member); ok {
+		return ev.evalResolved(m, r)
+	}
+
+	// Case 5: presence test, e.g. `author.given_name:*`.
+	if isPresenceTest(r) {
+		return hasMember(m, r.Comparable.Member)
+	}
+
+	if err := checkKnownMember(m.Descriptor(), r.Comparable.Member); err != nil {
+		if ev.opts.strictFields {
+			return false, err
+		}
+		if ev.opts.warn != nil && ev.validating() {
+			ev.opts.warn(Warning{Kind: WarningUnknownField, FieldPath: r.Comparable.Member.Value})
+		}
+	}
+
+	// Case 6: key presence test of a map field, e.g. `reviews:alice`.
+	if fd := mapFieldOf(m.Descriptor(), r.Comparable.Member); fd != nil && r.Comparator == ":" {
+		return ev.evalMapKey(m, fd, r)
 	}
 
-	// Case 2: normal comparator-based restriction.
+	// Case 7: normal comparator-based restriction.
 	lhs, err := resolveMemberValue(m, r.Comparable.Member)
 	if err != nil {
 		return false, err
@@ -134,27 +389,241 @@ func evalRestriction(m protoreflect.Message, r *Restriction) (bool, error) {
 	if r.Arg == nil {
 		return false, fmt.Errorf("missing arg in restriction")
 	}
-	rhs, err := resolveMemberValue(m, r.Arg.Comparable.Member)
+	if err := checkRestrictionLiteral(m.Descriptor(), r); err != nil {
+		return false, err
+	}
+	rhs, err := ev.resolveComparable(m, r.Arg.Comparable)
+	if err != nil {
+		return false, err
+	}
+	ok, err := compareAny(ev.opts.normalizeValue(lhs), ev.opts.normalizeValue(rhs), r.Comparator)
 	if err != nil {
 		return false, err
 	}
-	return compareAny(lhs, rhs, r.Comparator)
+	// Only the set member of a oneof has a value, so any comparison of
+	// another one, even !=, is false.
+	return ok && !unsetOneofMember(m, r.Comparable.Member), nil
+}
+
+// checkKnownMember returns an error if mem is unquoted text that does not
+// name a field of desc; see WithStrictFields.
+func checkKnownMember(desc protoreflect.MessageDescriptor, mem *Member) error {
+	if mem.Kind != LiteralText || desc.Fields().ByName(protoreflect.Name(mem.Value)) != nil {
+		return nil
+	}
+	return fmt.Errorf("unknown field %q", mem.Value)
+}
+
+// checkRestrictionLiteral checks the literal argument of r, if any, against
+// the field r compares when it is a singular scalar field of desc; see
+// checkLiteral.
+func checkRestrictionLiteral(desc protoreflect.MessageDescriptor, r *Restriction) error {
+	if r.Arg.Comparable == nil {
+		return nil
+	}
+	lhs, arg := r.Comparable.Member, r.Arg.Comparable.Member
+	if len(lhs.Fields) > 0 || arg == nil || arg.Kind == LiteralText {
+		return nil
+	}
+	fd := desc.Fields().ByName(protoreflect.Name(lhs.Value))
+	if fd == nil || fd.IsList() || fd.IsMap() {
+		return nil
+	}
+	return checkLiteral(fd, arg)
+}
+
+// evalMatcher evaluates r using fn.
+func (ev *evaluator) evalMatcher(m protoreflect.Message, r *Restriction, fn Matcher) (bool, error) {
+	if r.Arg == nil || r.Arg.Comparable == nil || r.Arg.Comparable.Member == nil || len(r.Arg.Comparable.Member.Fields) > 0 {
+		return false, fmt.Errorf("%s requires a literal argument", r.Comparable.Member.Value)
+	}
+	if ev.ctx == nil {
+		return false, nil
+	}
+	return fn(ev.ctx, m.Interface(), r.Comparator, r.Arg.Comparable.Member.Value)
+}
+
+// isPresenceTest reports whether r is an AIP-160 presence test, i.e. the has
+// operator with a bare `*` argument. A quoted "*" is a literal asterisk.
+func isPresenceTest(r *Restriction) bool {
+	if r.Comparator != ":" || r.Arg == nil || r.Arg.Comparable == nil {
+		return false
+	}
+	arg := r.Arg.Comparable.Member
+	return arg != nil && arg.Kind == LiteralText && arg.Value == "*" && len(arg.Fields) == 0
+}
+
+// hasMember reports whether the field addressed by mem is present in m.
+//
+// Fields with explicit presence (messages, proto3 optional and editions
+// fields with explicit field presence) are present when set, even to their
+// zero value. Fields with implicit presence are present when non-zero, and
+// repeated and map fields when non-empty. A trailing segment after a map
+// field tests for the presence of that key, and a path through a repeated
+// message field, or through the values of a map with the key *, is present
+// if it is present in any element. Segments after
+// a google.protobuf.Struct field are keys, present even when set to null.
+// Segments after a google.protobuf.Any field test the packed message, or its
+// type URL for @type. The name of a oneof, e.g. `edition:*`, is present if
+// any of its members is set.
+func hasMember(m protoreflect.Message, mem *Member) (bool, error) {
+	return hasFieldPath(m, append([]string{mem.Value}, mem.Fields...))
+}
+
+// unsetOneofMember reports whether the field addressed by mem is, or is
+// beneath, a member of a oneof other than the one set. Paths through maps
+// and repeated fields are not examined past them.
+func unsetOneofMember(m protoreflect.Message, mem *Member) bool {
+	path := append([]string{mem.Value}, mem.Fields...)
+	for _, name := range path {
+		fd := m.Descriptor().Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			return false
+		}
+		if od := fd.ContainingOneof(); od != nil && !od.IsSynthetic() && !m.Has(fd) {
+			return true
+		}
+		if fd.IsList() || fd.IsMap() || fd.Message() == nil {
+			return false
+		}
+		m = m.Get(fd).Message()
+	}
+	return false
+}
+
+func hasFieldPath(m protoreflect.Message, path []string) (bool, error) {
+	if isJSONMessage(m.Descriptor()) {
+		return hasJSONPath(m, path), nil
+	}
+	if m.Descriptor().FullName() == anyName {
+		return hasAnyPath(m, path)
+	}
+	fd := m.Descriptor().Fields().ByName(protoreflect.Name(path[0]))
+	if od := m.Descriptor().Oneofs().ByName(protoreflect.Name(path[0])); fd == nil && od != nil && !od.IsSynthetic() {
+		// A oneof is present if any of its members is set.
+		if len(path) > 1 {
+			return false, fmt.Errorf("cannot descend into oneof %q", path[0])
+		}
+		return m.WhichOneof(od) != nil, nil
+	}
+	if fd == nil {
+		return false, fmt.Errorf("unknown field %q in presence test", path[0])
+	}
+	if len(path) == 1 || !m.Has(fd) {
+		return m.Has(fd), nil
+	}
+
+	switch {
+	case fd.IsMap():
+		mp := m.Get(fd).Map()
+		if path[1] == mapWildcard {
+			return hasMapWildcardPath(fd, mp, path[2:])
+		}
+		key, err := parseMapKey(fd.MapKey(), path[1])
+		if err != nil {
+			return false, err
+		}
+		if len(path) == 2 || !mp.Has(key) {
+			return mp.Has(key), nil
+		}
+		if fd.MapValue().Message() == nil {
+			return false, fmt.Errorf("cannot descend into non-message values of map field %q", path[0])
+		}
+		return hasFieldPath(mp.Get(key).Message(), path[2:])
+	case fd.Message() == nil:
+		return false, fmt.Errorf("cannot descend into non-message field %q", path[0])
+	case fd.IsList():
+		l := m.Get(fd).List()
+		for i := 0; i < l.Len(); i++ {
+			ok, err := hasFieldPath(l.Get(i).Message(), path[1:])
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	}
+	return hasFieldPath(m.Get(fd).Message(), path[1:])
+}
+
+// stringSearch performs a case-insensitive substring search for term over
+// the string fields of a message and its submessages, as required by global
+// restrictions.
+//
+// Only known string fields are searched: bytes fields, unknown fields and
+// extensions are skipped.
+type stringSearch struct {
+	// ctx is checked periodically so long searches can be abandoned. It may
+	// be nil.
+	ctx context.Context
+	// err is the context error that ended the search, if any.
+	err error
+
+	term string
+
+	// normalize normalizes the strings searched, or is nil; see
+	// WithNormalization.
+	normalize func(string) string
+
+	// maxDepth is the deepest level of message nesting that is searched, or
+	// non-positive for no limit.
+	maxDepth int
+
+	// fieldsLeft is the remaining number of field values that may be
+	// examined, unless unlimited is set.
+	fieldsLeft int
+	unlimited  bool
+
+	// visited counts the field values examined.
+	visited int
+}
+
+// searchCheckInterval is the number of field values a global restriction
+// examines between checks of its context.
+const searchCheckInterval = 256
+
+// visit consumes one unit of the field budget, reporting false once the
+// budget is exhausted or the context is done.
+func (s *stringSearch) visit() bool {
+	if s.ctx != nil {
+		s.visited++
+		if s.visited%searchCheckInterval == 0 {
+			if s.err = s.ctx.Err(); s.err != nil {
+				return false
+			}
+		}
+	}
+	if s.unlimited {
+		return true
+	}
+	if s.fieldsLeft <= 0 {
+		return false
+	}
+	s.fieldsLeft--
+	return true
 }
 
-func searchMessageStrings(m protoreflect.Message, term string) bool {
-	term = strings.ToLower(term)
+func (s *stringSearch) message(m protoreflect.Message, depth int) bool {
+	if s.maxDepth > 0 && depth > s.maxDepth {
+		return false
+	}
 	desc := m.Descriptor()
 	fields := desc.Fields()
 
 	for i := 0; i < fields.Len(); i++ {
 		fd := fields.Get(i)
+		if !searchableKind(fd) || !m.Has(fd) {
+			continue
+		}
 		val := m.Get(fd)
 
 		switch {
 		case fd.IsList():
 			l := val.List()
 			for j := 0; j < l.Len(); j++ {
-				if fieldContains(fd, l.Get(j), term) {
+				if !s.visit() {
+					return false
+				}
+				if s.field(fd, l.Get(j), depth) {
 					return true
 				}
 			}
@@ -163,8 +632,11 @@ func searchMessageStrings(m protoreflect.Message, term string) bool {
 			mp := val.Map()
 			found := false
 			mp.Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
-				if fieldContains(fd.MapKey(), protoreflect.ValueOf(k.Interface()), term) ||
-					fieldContains(fd.MapValue(), v, term) {
+				if !s.visit() {
+					return false
+				}
+				if s.field(fd.MapKey(), protoreflect.ValueOf(k.Interface()), depth) ||
+					s.field(fd.MapValue(), v, depth) {
 					found = true
 					return false
 				}
@@ -175,7 +647,10 @@ func searchMessageStrings(m protoreflect.Message, term string) bool {
 			}
 
 		default:
-			if fieldContains(fd, val, term) {
+			if !s.visit() {
+				return false
+			}
+			if s.field(fd, val, depth) {
 				return true
 			}
 		}
@@ -183,18 +658,34 @@ func searchMessageStrings(m protoreflect.Message, term string) bool {
 	return false
 }
 
-func fieldContains(fd protoreflect.FieldDescriptor, v protoreflect.Value, term string) bool {
+func (s *stringSearch) field(fd protoreflect.FieldDescriptor, v protoreflect.Value, depth int) bool {
 	switch fd.Kind() {
 	case protoreflect.StringKind:
-		return strings.Contains(strings.ToLower(v.String()), term)
+		if s.normalize != nil {
+			return strings.Contains(strings.ToLower(s.normalize(v.String())), s.term)
+		}
+		return strings.Contains(strings.ToLower(v.String()), s.term)
 	case protoreflect.MessageKind:
 		if v.Message().IsValid() {
-			return searchMessageStrings(v.Message(), term)
+			return s.message(v.Message(), depth+1)
 		}
 	}
 	return false
 }
 
+// searchableKind reports whether fd may contain strings reachable by a
+// global restriction.
+func searchableKind(fd protoreflect.FieldDescriptor) bool {
+	if fd.IsMap() {
+		return searchableKind(fd.MapKey()) || searchableKind(fd.MapValue())
+	}
+	switch fd.Kind() {
+	case protoreflect.StringKind, protoreflect.MessageKind:
+		return true
+	}
+	return false
+}
+
 // ---- resolving member -> runtime value ----
 //
 // Behavior notes:
@@ -204,7 +695,23 @@ func fieldContains(fd protoreflect.FieldDescriptor, v protoreflect.Value, term s
 //  * Repeated message fields can be descended into: e.g. `authors.family_name`
 //    returns a []any of that subfield for each element. Comparison
 //    semantics treat slices as "any element matches" for =, :, !=, etc.
-//  * Maps are returned as map[any]any for simple membership tests.
+//    Each repeated field along the path nests another []any, e.g.
+//    `replies.replies.text`, so that the comparison is true if it is true
+//    of any element at every level.
+//  * Maps are returned as map[any]any for simple membership tests. A field
+//    after a map field is a key, e.g. `reviews.alice`, and resolves to the
+//    value at that key, or nil if it is absent. Fields after the key descend
+//    into message values, e.g. `detailed_reviews.alice.rating`. The key `*`
+//    resolves to a []any of every value, e.g. `reviews.*`, so that
+//    comparisons are true if they are true of any value.
+//  * Fields after a google.protobuf.Struct or Value field are keys of JSON
+//    objects, e.g. `metadata.labels.env`, and resolve to the JSON value as a
+//    float64, string, bool, map[any]any or []any, or nil for null and
+//    absent keys.
+//  * The @type pseudo-field of a google.protobuf.Any field resolves to its
+//    type URL, e.g. `details.@type`. Other fields after an Any field descend
+//    into the packed message if its type is registered, e.g.
+//    `details.rating`, and resolve to nil otherwise.
 
 func resolveMemberValue(m protoreflect.Message, mem *Member) (any, error) {
 	// Try to find the top-level field descriptor by name.
@@ -230,7 +737,7 @@ func resolveMemberValue(m protoreflect.Message, mem *Member) (any, error) {
 		if len(mem.Fields) == 0 {
 			return mp, nil
 		}
-		return nil, fmt.Errorf("cannot descend into map field %q", mem.Value)
+		return resolveMapValue(fd, mv, mem.Fields)
 	}
 
 	// Repeated (list)
@@ -249,6 +756,10 @@ func resolveMemberValue(m protoreflect.Message, mem *Member) (any, error) {
 		if fd.Message() == nil {
 			return nil, fmt.Errorf("cannot descend into repeated non-message field %q", mem.Value)
 		}
+		if l.Len() == 0 {
+			// No element, so no comparison is true, not even !=.
+			return []any{}, checkSubfields(fd.Message(), mem.Fields)
+		}
 		var results []any
 		for i := 0; i < l.Len(); i++ {
 			elemMsg := l.Get(i).Message()
@@ -267,16 +778,22 @@ func resolveMemberValue(m protoreflect.Message, mem *Member) (any, error) {
 
 	// Singular message/primitive
 	if len(mem.Fields) == 0 {
-		return val.Interface(), nil
+		return fieldValue(fd, val), nil
 	}
 	// Descend into submessage fields.
 	if fd.Message() == nil {
 		return nil, fmt.Errorf("cannot descend into non-message field %q", mem.Value)
 	}
 	subMsg := val.Message()
+	if isJSONMessage(fd.Message()) {
+		return resolveJSONPath(subMsg, mem.Fields), nil
+	}
+	if fd.Message().FullName() == anyName {
+		return resolveAnyPath(subMsg, mem.Fields)
+	}
 	if !subMsg.IsValid() {
 		// missing message -> treat as nil
-		return nil, nil
+		return nil, checkSubfields(fd.Message(), mem.Fields)
 	}
 	return resolveMemberValueFromMessage(subMsg, mem.Fields)
 }
@@ -284,6 +801,13 @@ func resolveMemberValue(m protoreflect.Message, mem *Member) (any, error) {
 func resolveMemberValueFromMessage(m protoreflect.Message, fields []string) (any, error) {
 	cur := m
 	for i, fname := range fields {
+		if isJSONMessage(cur.Descriptor()) {
+			// The remaining fields are keys of a JSON object.
+			return resolveJSONPath(cur, fields[i:]), nil
+		}
+		if cur.Descriptor().FullName() == anyName {
+			return resolveAnyPath(cur, fields[i:])
+		}
 		fd := cur.Descriptor().Fields().ByName(protoreflect.Name(fname))
 		if fd == nil {
 			return nil, fmt.Errorf("unknown subfield %q", fname)
@@ -307,21 +831,111 @@ func resolveMemberValueFromMessage(m protoreflect.Message, fields []string) (any
 				}
 				return out, nil
 			}
-			return v.Interface(), nil
+			return fieldValue(fd, v), nil
+		}
+		// Not final -> a map key follows, or must be a message to descend
+		if fd.IsMap() {
+			return resolveMapValue(fd, v.Map(), fields[i+1:])
 		}
-		// Not final -> must be a message to descend
 		if fd.Message() == nil {
 			return nil, fmt.Errorf("cannot descend into non-message subfield %q", fname)
 		}
+		if fd.IsList() {
+			// Resolve the rest of the path in each element, as for
+			// top-level repeated fields.
+			l := v.List()
+			if l.Len() == 0 {
+				return []any{}, checkSubfields(fd.Message(), fields[i+1:])
+			}
+			results := make([]any, l.Len())
+			for j := 0; j < l.Len(); j++ {
+				sub, err := resolveMemberValueFromMessage(l.Get(j).Message(), fields[i+1:])
+				if err != nil {
+					return nil, err
+				}
+				results[j] = sub
+			}
+			return results, nil
+		}
 		cur = v.Message()
 		if !cur.IsValid() {
 			// intermediate nil message
-			return nil, nil
+			return nil, checkSubfields(fd.Message(), fields[i+1:])
 		}
 	}
 	return nil, fmt.Errorf("unreachable")
 }
 
+// resolveMapValue resolves the value at the key fields[0] of the map field
+// fd, descending into it with the remaining fields. An absent key resolves to
+// nil. The key * resolves the remaining fields in every value; see
+// resolveMapWildcard.
+func resolveMapValue(fd protoreflect.FieldDescriptor, mp protoreflect.Map, fields []string) (any, error) {
+	if fields[0] == mapWildcard {
+		return resolveMapWildcard(fd, mp, fields[1:])
+	}
+	key, err := parseMapKey(fd.MapKey(), fields[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 1 {
+		if !mp.Has(key) {
+			return nil, nil
+		}
+		return fieldValue(fd.MapValue(), mp.Get(key)), nil
+	}
+	md := fd.MapValue().Message()
+	if md == nil {
+		return nil, fmt.Errorf("cannot descend into non-message values of map field %q", fd.Name())
+	}
+	if !mp.Has(key) {
+		return nil, checkSubfields(md, fields[1:])
+	}
+	return resolveMemberValueFromMessage(mp.Get(key).Message(), fields[1:])
+}
+
+// checkSubfields returns the error resolving fields in a message of type desc
+// would, so that filters are validated when they descend through absent
+// messages, empty lists or absent map keys, e.g. against the zero message.
+// It walks descriptors only, so it terminates on self-recursive messages.
+func checkSubfields(desc protoreflect.MessageDescriptor, fields []string) error {
+	for i := 0; i < len(fields); i++ {
+		if isJSONMessage(desc) || desc.FullName() == anyName {
+			// Any key of a JSON object is valid, as is any field of the
+			// message packed in an Any.
+			return nil
+		}
+		fd := desc.Fields().ByName(protoreflect.Name(fields[i]))
+		if fd == nil {
+			return fmt.Errorf("unknown subfield %q", fields[i])
+		}
+		if i == len(fields)-1 {
+			return nil
+		}
+		switch {
+		case fd.IsMap():
+			i++
+			if fields[i] != mapWildcard {
+				if _, err := parseMapKey(fd.MapKey(), fields[i]); err != nil {
+					return err
+				}
+			}
+			if i == len(fields)-1 {
+				return nil
+			}
+			if fd.MapValue().Message() == nil {
+				return fmt.Errorf("cannot descend into non-message values of map field %q", fd.Name())
+			}
+			desc = fd.MapValue().Message()
+		case fd.Message() == nil:
+			return fmt.Errorf("cannot descend into non-message subfield %q", fields[i])
+		default:
+			desc = fd.Message()
+		}
+	}
+	return nil
+}
+
 func asFloat64(v any) (float64, bool) {
 	switch n := v.(type) {
 	case int:
@@ -345,12 +959,28 @@ func asFloat64(v any) (float64, bool) {
 	}
 }
 
+// literalFloat64 is like asFloat64, but also parses string literals, as the
+// arguments of restrictions are always resolved as strings.
+func literalFloat64(v any) (float64, bool) {
+	if s, ok := v.(string); ok {
+		n, err := strconv.ParseFloat(s, 64)
+		return n, err == nil
+	}
+	return asFloat64(v)
+}
+
 // compareAny implements =, !=, >, <, >=, <=, :
 // Notes:
 // If lhs is a slice, comparisons are true if any element compares true
 //
 //	 For ":" on strings, lhs contains rhs is used.
 //	- For "=" we attempt number/string/bool/message/reflection comparisons.
+//	- Numeric and bool lhs values are compared with string literals parsed
+//	  as numbers and bools.
+//	- A nil lhs, e.g. an absent map key, orders false against anything.
+//	- google.protobuf.Duration lhs values are compared with string literals
+//	  parsed as durations, e.g. "30s" or "1h30m", or with other durations.
+//	  An unset duration is zero.
 func compareAny(lhs, rhs any, op string) (bool, error) {
 	// If lhs is a slice -> "any element matches" semantics.
 	if isSlice(lhs) {
@@ -382,6 +1012,11 @@ func compareAny(lhs, rhs any, op string) (bool, error) {
 		return false, nil
 	}
 
+	// google.protobuf.Duration fields compare with duration literals.
+	if ld, ok := durationValue(lhs); ok {
+		return compareDuration(ld, rhs, op)
+	}
+
 	// ":" operator (has)
 	if op == ":" {
 		ls, lok := lhs.(string)
@@ -418,7 +1053,7 @@ func compareAny(lhs, rhs any, op string) (bool, error) {
 		var eq bool
 		// numbers
 		if ln, lok := asFloat64(lhs); lok {
-			if rn, rok := asFloat64(rhs); rok {
+			if rn, rok := literalFloat64(rhs); rok {
 				eq = ln == rn
 			} else {
 				eq = false
@@ -432,6 +1067,9 @@ func compareAny(lhs, rhs any, op string) (bool, error) {
 		} else if lb, lok := lhs.(bool); lok {
 			if rb, rok := rhs.(bool); rok {
 				eq = lb == rb
+			} else if rs, rok := rhs.(string); rok {
+				rb, err := strconv.ParseBool(rs)
+				eq = err == nil && lb == rb
 			} else {
 				eq = false
 			}
@@ -451,8 +1089,11 @@ func compareAny(lhs, rhs any, op string) (bool, error) {
 	}
 
 	// Ordering operators: try numeric, else try string.
+	if lhs == nil {
+		return false, nil
+	}
 	if ln, lok := asFloat64(lhs); lok {
-		if rn, rok := asFloat64(rhs); rok {
+		if rn, rok := literalFloat64(rhs); rok {
 			switch op {
 			case ">":
 				return ln > rn, nil
@@ -485,6 +1126,77 @@ func compareAny(lhs, rhs any, op string) (bool, error) {
 	return false, fmt.Errorf("unsupported comparator %q for types %T vs %T", op, lhs, rhs)
 }
 
+// protoDuration is the value of a google.protobuf.Duration, kept as seconds
+// and nanoseconds so that its whole range compares correctly.
+type protoDuration struct {
+	seconds int64
+	nanos   int32
+}
+
+// compare returns -1, 0 or +1 as d is less than, equal to or greater than
+// other.
+func (d protoDuration) compare(other protoDuration) int {
+	if c := cmp.Compare(d.seconds, other.seconds); c != 0 {
+		return c
+	}
+	return cmp.Compare(d.nanos, other.nanos)
+}
+
+// durationValue returns the value of v if it is a google.protobuf.Duration
+// message, including one of a dynamic type.
+func durationValue(v any) (protoDuration, bool) {
+	var m protoreflect.Message
+	switch v := v.(type) {
+	case protoreflect.Message:
+		m = v
+	case proto.Message:
+		m = v.ProtoReflect()
+	default:
+		return protoDuration{}, false
+	}
+	desc := m.Descriptor()
+	if desc.FullName() != "google.protobuf.Duration" {
+		return protoDuration{}, false
+	}
+	return protoDuration{
+		seconds: m.Get(desc.Fields().ByName("seconds")).Int(),
+		nanos:   int32(m.Get(desc.Fields().ByName("nanos")).Int()),
+	}, true
+}
+
+// compareDuration compares lhs with rhs, a duration or a literal parsed by
+// time.ParseDuration. The has operator tests for equality.
+func compareDuration(lhs protoDuration, rhs any, op string) (bool, error) {
+	r, ok := durationValue(rhs)
+	if !ok {
+		s, isString := rhs.(string)
+		if !isString {
+			return false, fmt.Errorf("rhs is not a duration for operator %q", op)
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return false, fmt.Errorf("invalid duration %q: %w", s, err)
+		}
+		r = protoDuration{seconds: int64(d / time.Second), nanos: int32(d % time.Second)}
+	}
+	c := lhs.compare(r)
+	switch op {
+	case "=", ":":
+		return c == 0, nil
+	case "!=":
+		return c != 0, nil
+	case ">":
+		return c > 0, nil
+	case "<":
+		return c < 0, nil
+	case ">=":
+		return c >= 0, nil
+	case "<=":
+		return c <= 0, nil
+	}
+	return false, fmt.Errorf("unsupported comparator %q for durations", op)
+}
+
 func isSlice(v any) bool {
 	if v == nil {
 		return false
@@ -1,11 +1,19 @@
 package query_test
 
 import (
+	"context"
+	"fmt"
 	"testing"
+	"time"
 
-	"github.com/hxtk/aip/internal/testpb"
+	"github.com/hxtk/aip/aiptest/testpb"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
 
 	aip "github.com/hxtk/aip/query"
 )
@@ -238,3 +246,658 @@ func TestMatchesFilter_GlobalAndExplicit(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchesFilter_GlobalRestrictionLimits(t *testing.T) {
+	file := &descriptorpb.FileDescriptorProto{
+		Name: proto.String("outer.proto"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Outer"),
+			NestedType: []*descriptorpb.DescriptorProto{{
+				Name: proto.String("Inner"),
+			}},
+		}},
+	}
+	book := &testpb.Book{
+		Title: "Dune",
+		Author: &testpb.Author{
+			FamilyName: "Herbert",
+		},
+		Name: "books/needle",
+	}
+
+	tests := []struct {
+		name     string
+		filter   string
+		msg      proto.Message
+		opts     []aip.FilterOption
+		expected bool
+	}{
+		{"default depth reaches nested", "Inner", file, nil, true},
+		{"depth limit excludes nested", "Inner", file, []aip.FilterOption{aip.WithMaxSearchDepth(1)}, false},
+		{"depth limit includes shallower", "Outer", file, []aip.FilterOption{aip.WithMaxSearchDepth(1)}, true},
+		{"unlimited depth", "Inner", file, []aip.FilterOption{aip.WithMaxSearchDepth(0)}, true},
+		{"field limit stops scan", "needle", book, []aip.FilterOption{aip.WithMaxSearchFields(2)}, false},
+		{"field limit allows early fields", "Herbert", book, []aip.FilterOption{aip.WithMaxSearchFields(3)}, true},
+		{"unlimited fields", "needle", book, []aip.FilterOption{aip.WithMaxSearchFields(0)}, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := aip.ParseFilter(tc.filter)
+			require.NoError(t, err, "parse filter")
+
+			var ok bool
+			switch msg := tc.msg.(type) {
+			case *testpb.Book:
+				filter, err := aip.ProtoFilter[testpb.Book](f, tc.opts...)
+				require.NoError(t, err, "evaluate filter")
+				ok = filter(msg)
+			case *descriptorpb.FileDescriptorProto:
+				filter, err := aip.ProtoFilter[descriptorpb.FileDescriptorProto](f, tc.opts...)
+				require.NoError(t, err, "evaluate filter")
+				ok = filter(msg)
+			}
+			require.Equal(t, tc.expected, ok)
+		})
+	}
+}
+
+func TestMatchesFilter_Presence(t *testing.T) {
+	zero := int32(0)
+	book := &testpb.Book{
+		Title:     "The Pragmatic Programmer",
+		Author:    &testpb.Author{},
+		PageCount: &zero,
+		Reviews:   map[string]string{"alice": "great"},
+		Items:     map[int32]string{7: "seven"},
+		Authors:   []*testpb.Author{{GivenName: "Dave"}},
+		Tags:      []string{"classic"},
+	}
+
+	tests := []struct {
+		name     string
+		filter   string
+		expected bool
+	}{
+		{"optional set to zero", `page_count:*`, true},
+		{"non-empty repeated message", `authors:*`, true},
+		{"non-empty repeated scalar", `tags:*`, true},
+		{"non-empty map", `reviews:*`, true},
+		{"empty map", `detailed_reviews:*`, false},
+		{"quoted star is a literal", `title:"*"`, false},
+		{"implicit presence set", `title:*`, true},
+		{"implicit presence empty", `name:*`, false},
+		{"empty message is present", `author:*`, true},
+		{"field of empty message", `author.given_name:*`, false},
+		{"unset message", `create_time:*`, false},
+		{"map key present", `reviews.alice:*`, true},
+		{"map key missing", `reviews.bob:*`, false},
+		{"integer map key", `items.7:*`, true},
+		{"repeated element field", `authors.given_name:*`, true},
+		{"repeated element missing field", `authors.family_name:*`, false},
+		{"negated presence", `NOT create_time:*`, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := aip.ParseFilter(tc.filter)
+			require.NoError(t, err, "parse filter")
+
+			filter, err := aip.ProtoFilter[testpb.Book](f)
+			require.NoError(t, err, "evaluate filter")
+
+			require.Equal(t, tc.expected, filter(book))
+		})
+	}
+
+	f, err := aip.ParseFilter(`page_count:*`)
+	require.NoError(t, err)
+	filter, err := aip.ProtoFilter[testpb.Book](f)
+	require.NoError(t, err)
+	require.False(t, filter(&testpb.Book{}), "unset optional field should not be present")
+
+	filter, err = aip.ProtoFilter[testpb.Book](mustParse(t, `title:"*"`))
+	require.NoError(t, err)
+	require.True(t, filter(&testpb.Book{Title: "M*A*S*H"}), "a quoted star matches a literal asterisk")
+
+	for _, field := range []string{"authors", "tags", "reviews", "items"} {
+		filter, err := aip.ProtoFilter[testpb.Book](mustParse(t, field+`:*`))
+		require.NoError(t, err)
+		require.False(t, filter(&testpb.Book{Authors: []*testpb.Author{}, Reviews: map[string]string{}}), "empty %s should not be present", field)
+	}
+}
+
+func TestMatchesFilter_MapValues(t *testing.T) {
+	book := &testpb.Book{
+		Reviews: map[string]string{"alice": "great"},
+		Items:   map[int32]string{5: "hardcover"},
+		DetailedReviews: map[string]*testpb.Review{
+			"alice": {Rating: 5, Text: "A classic."},
+			"bob":   {Rating: 2},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		filter   string
+		expected bool
+	}{
+		{"scalar value at key", `reviews.alice = great`, true},
+		{"scalar value at missing key", `reviews.carol = great`, false},
+		{"integer key", `items.5 = "hardcover"`, true},
+		{"integer key not matching", `items.5 != "hardcover"`, false},
+		{"missing integer key", `items.6 = "hardcover"`, false},
+		{"message value field", `detailed_reviews.alice.rating > 3`, true},
+		{"message value field not matching", `detailed_reviews.bob.rating > 3`, false},
+		{"message value field equality", `detailed_reviews.bob.rating = 2`, true},
+		{"message value string field", `detailed_reviews.alice.text:classic`, true},
+		{"message value at missing key", `detailed_reviews.carol.rating < 3`, false},
+		{"negated missing key", `NOT detailed_reviews.carol.rating > 3`, true},
+		{"message value field present", `detailed_reviews.alice.text:*`, true},
+		{"message value field absent", `detailed_reviews.bob.text:*`, false},
+		{"message value at missing key absent", `detailed_reviews.carol.text:*`, false},
+		{"key present", `reviews:alice`, true},
+		{"quoted key present", `reviews:"alice"`, true},
+		{"key absent", `reviews:carol`, false},
+		{"key is not a value", `reviews:great`, false},
+		{"key is not a substring", `reviews:ali`, false},
+		{"negated key absent", `NOT reviews:carol`, true},
+		{"integer key present", `items:5`, true},
+		{"quoted integer key present", `items:"5"`, true},
+		{"integer key absent", `items:6`, false},
+		{"message map key present", `detailed_reviews:bob`, true},
+		{"backtick quoted key", "reviews.`alice` = great", true},
+		{"wildcard value", `reviews.*:"great"`, true},
+		{"wildcard value substring", `reviews.*:gre`, true},
+		{"wildcard value not matching", `reviews.* = awful`, false},
+		{"wildcard integer key", `items.* = hardcover`, true},
+		{"wildcard message value field", `detailed_reviews.*.rating = 2`, true},
+		{"wildcard message value field any", `detailed_reviews.*.rating > 4`, true},
+		{"wildcard message value field none", `detailed_reviews.*.rating > 5`, false},
+		{"wildcard message value text", `detailed_reviews.*.text:classic`, true},
+		{"wildcard present", `detailed_reviews.*:*`, true},
+		{"wildcard field present", `detailed_reviews.*.text:*`, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := aip.ParseFilter(tc.filter)
+			require.NoError(t, err, "parse filter")
+
+			filter, err := aip.ProtoFilter[testpb.Book](f)
+			require.NoError(t, err, "evaluate filter")
+
+			require.Equal(t, tc.expected, filter(book))
+		})
+	}
+
+	invalid := map[string]string{
+		`detailed_reviews.alice.stars > 3`: "unknown subfield",
+		`reviews.alice.text = great`:       "non-message values",
+		`items.seven = x`:                  "invalid int32 map key",
+		`items:seven`:                      "invalid int32 map key",
+		`items:+5`:                         "invalid int32 map key",
+		`items:5.5`:                        "invalid int32 map key",
+		`reviews:title.text`:               "must be a key",
+		`detailed_reviews.*.stars > 3`:     "unknown subfield",
+		`reviews.*.text = great`:           "non-message values",
+		`page_count > x`:                   "rhs is not numeric",
+	}
+	for filter, want := range invalid {
+		f, err := aip.ParseFilter(filter)
+		require.NoError(t, err, filter)
+		_, err = aip.ProtoFilter[testpb.Book](f)
+		require.ErrorContains(t, err, want, filter)
+	}
+}
+
+func TestMatchesFilter_Recursive(t *testing.T) {
+	thread := &testpb.Comment{
+		Text: "root",
+		Replies: []*testpb.Comment{
+			{Text: "first", Replies: []*testpb.Comment{{Text: "nested"}}},
+			{Text: "second", Parent: &testpb.Comment{Text: "root"}},
+			{Text: "third", Replies: []*testpb.Comment{
+				{Text: "shallow"},
+				{Text: "deep", Replies: []*testpb.Comment{{Text: "deepest"}}},
+			}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		filter   string
+		expected bool
+	}{
+		{"field of replies", `replies.text = second`, true},
+		{"field of replies of replies", `replies.replies.text = nested`, true},
+		{"field of replies of replies not matching", `replies.replies.text = second`, false},
+		{"field of a later element of replies of replies", `replies.replies.text = deep`, true},
+		{"three repeated levels", `replies.replies.replies.text = deepest`, true},
+		{"three repeated levels not matching", `replies.replies.replies.text = nested`, false},
+		{"three repeated levels inequality", `replies.replies.replies.text != deepest`, false},
+		{"inequality of any nested element", `replies.replies.text != nested`, true},
+		{"negated nested match", `NOT replies.replies.text = shallow`, false},
+		{"has through nested replies", `replies.replies.text:deep`, true},
+		{"presence through nested replies", `replies.replies.replies:*`, true},
+		{"presence of empty nested replies", `replies.replies.replies.replies:*`, false},
+		{"four repeated levels through empty lists", `replies.replies.replies.replies.text = x`, false},
+		{"field of parent of replies", `replies.parent.text = root`, true},
+		{"presence through replies", `replies.parent:*`, true},
+		{"absent parent", `parent.parent.text = root`, false},
+		{"global search of replies", `nested`, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := aip.ParseFilter(tc.filter)
+			require.NoError(t, err, "parse filter")
+
+			filter, err := aip.ProtoFilter[testpb.Comment](f)
+			require.NoError(t, err, "evaluate filter")
+
+			require.Equal(t, tc.expected, filter(thread))
+		})
+	}
+
+	// A repeated field without elements has no element for which a
+	// comparison is true, not even !=.
+	for _, filter := range []string{`replies.text != x`, `replies.replies.text != x`} {
+		f, err := aip.ProtoFilter[testpb.Comment](mustParse(t, filter))
+		require.NoError(t, err)
+		require.False(t, f(&testpb.Comment{}), filter)
+	}
+	empty, err := aip.ProtoFilter[testpb.Comment](mustParse(t, `replies.replies.text != x`))
+	require.NoError(t, err)
+	require.False(t, empty(&testpb.Comment{Replies: []*testpb.Comment{{}}}))
+
+	for _, filter := range []string{
+		`replies.nope = x`,
+		`parent.parent.parent.nope = x`,
+		`replies.replies.text.nope = x`,
+	} {
+		f, err := aip.ParseFilter(filter)
+		require.NoError(t, err, filter)
+		_, err = aip.ProtoFilter[testpb.Comment](f)
+		require.Error(t, err, "subfields of absent recursive fields are validated: %s", filter)
+	}
+
+	// Global restrictions stop at the maximum search depth, even on
+	// messages built in memory with a cycle.
+	deep := &testpb.Comment{Text: "needle"}
+	for range 2 * aip.DefaultMaxSearchDepth {
+		deep = &testpb.Comment{Parent: deep}
+	}
+	cycle := &testpb.Comment{}
+	cycle.Replies = []*testpb.Comment{cycle}
+	f, err := aip.ParseFilter(`needle`)
+	require.NoError(t, err)
+	filter, err := aip.ProtoFilter[testpb.Comment](f)
+	require.NoError(t, err)
+	require.False(t, filter(deep))
+	require.False(t, filter(cycle))
+	filter, err = aip.ProtoFilter[testpb.Comment](f, aip.WithMaxSearchDepth(4*aip.DefaultMaxSearchDepth))
+	require.NoError(t, err)
+	require.True(t, filter(deep))
+}
+
+type callerKey struct{}
+
+func TestProtoFilterCtx_Matcher(t *testing.T) {
+	sharedWithMe := func(ctx context.Context, msg proto.Message, comparator, arg string) (bool, error) {
+		if comparator != "=" {
+			return false, fmt.Errorf("shared_with_me only supports =")
+		}
+		caller, _ := ctx.Value(callerKey{}).(string)
+		shared := msg.(*testpb.Book).GetReviews()[caller] != ""
+		return shared == (arg == "true"), nil
+	}
+
+	f, err := aip.ParseFilter(`shared_with_me = true AND title:Pragmatic`)
+	require.NoError(t, err)
+	filter, err := aip.ProtoFilterCtx[testpb.Book](f, aip.WithMatcher("shared_with_me", sharedWithMe))
+	require.NoError(t, err)
+
+	book := &testpb.Book{
+		Title:   "The Pragmatic Programmer",
+		Reviews: map[string]string{"alice": "great"},
+	}
+	alice := context.WithValue(context.Background(), callerKey{}, "alice")
+	bob := context.WithValue(context.Background(), callerKey{}, "bob")
+
+	ok, err := filter(alice, book)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = filter(bob, book)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	f, err = aip.ParseFilter(`shared_with_me:true`)
+	require.NoError(t, err)
+	filter, err = aip.ProtoFilterCtx[testpb.Book](f, aip.WithMatcher("shared_with_me", sharedWithMe))
+	require.NoError(t, err, "matchers are not invoked during validation")
+	_, err = filter(alice, book)
+	require.ErrorContains(t, err, "only supports =")
+
+	f, err = aip.ParseFilter(`shared_with_me = author.given_name`)
+	require.NoError(t, err)
+	_, err = aip.ProtoFilterCtx[testpb.Book](f, aip.WithMatcher("shared_with_me", sharedWithMe))
+	require.ErrorContains(t, err, "requires a literal argument")
+
+	wire, err := aip.WireFilter[testpb.Book](mustParse(t, `shared_with_me = true`),
+		aip.WithMatcher("shared_with_me", func(ctx context.Context, msg proto.Message, _, _ string) (bool, error) {
+			return msg.(*testpb.Book).GetReviews()["alice"] != "", nil
+		}))
+	require.NoError(t, err)
+	raw, err := proto.Marshal(book)
+	require.NoError(t, err)
+	ok, err = wire(raw)
+	require.NoError(t, err)
+	require.True(t, ok, "matcher should see fields not referenced by the filter")
+}
+
+func TestProtoFilterCtx_Cancellation(t *testing.T) {
+	f, err := aip.ParseFilter(`needle`)
+	require.NoError(t, err)
+	filter, err := aip.ProtoFilterCtx[testpb.Book](f, aip.WithMaxSearchFields(0))
+	require.NoError(t, err)
+
+	book := &testpb.Book{}
+	for range 1000 {
+		book.Authors = append(book.Authors, &testpb.Author{GivenName: "hay"})
+	}
+	book.Authors = append(book.Authors, &testpb.Author{GivenName: "needle"})
+
+	ok, err := filter(context.Background(), book)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = filter(ctx, book)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestDynamicFilter(t *testing.T) {
+	desc := (&testpb.Book{}).ProtoReflect().Descriptor()
+	match, err := aip.DynamicFilter(desc, mustParse(t, `author.family_name = "Hunt" AND page_count:*`))
+	require.NoError(t, err)
+
+	raw, err := proto.Marshal(&testpb.Book{
+		Author:    &testpb.Author{FamilyName: "Hunt"},
+		PageCount: proto.Int32(0),
+	})
+	require.NoError(t, err)
+	book := dynamicpb.NewMessage(desc)
+	require.NoError(t, proto.Unmarshal(raw, book))
+	require.True(t, match(book))
+	require.False(t, match(dynamicpb.NewMessage(desc)))
+}
+
+func TestMatchesFilter_Duration(t *testing.T) {
+	book := &testpb.Book{ReadingTime: durationpb.New(90 * time.Minute)}
+
+	tests := []struct {
+		filter   string
+		expected bool
+	}{
+		{`reading_time > "30s"`, true},
+		{`reading_time > 2h`, false},
+		{`reading_time >= "1h30m"`, true},
+		{`reading_time <= "5400s"`, true},
+		{`reading_time < "5400.5s"`, true},
+		{`reading_time = "1.5h"`, true},
+		{`reading_time != "1.5h"`, false},
+		{`reading_time : "90m"`, true},
+		{`reading_time > "-1s"`, true},
+		{`reading_time = "0s"`, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.filter, func(t *testing.T) {
+			filter, err := aip.ProtoFilter[testpb.Book](mustParse(t, tc.filter))
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, filter(book))
+		})
+	}
+
+	// An unset duration is zero.
+	filter, err := aip.ProtoFilter[testpb.Book](mustParse(t, `reading_time = "0s"`))
+	require.NoError(t, err)
+	require.True(t, filter(&testpb.Book{}))
+
+	_, err = aip.ProtoFilter[testpb.Book](mustParse(t, `reading_time > "soon"`))
+	require.ErrorContains(t, err, `invalid duration "soon"`)
+}
+
+func TestMatchesFilter_Struct(t *testing.T) {
+	metadata, err := structpb.NewStruct(map[string]any{
+		"labels":   map[string]any{"env": "prod", "tier": "gold"},
+		"priority": 3,
+		"archived": false,
+		"owner":    nil,
+		"tags":     []any{"new", "sale"},
+	})
+	require.NoError(t, err)
+	book := &testpb.Book{Metadata: metadata}
+
+	tests := []struct {
+		filter   string
+		expected bool
+	}{
+		{`metadata.labels.env = "prod"`, true},
+		{`metadata.labels.env = "dev"`, false},
+		{`metadata.labels.env != "dev"`, true},
+		{`metadata.labels.env : "pro"`, true},
+		{`metadata.labels : "gold"`, true},
+		{`metadata.priority = 3`, true},
+		{`metadata.priority >= "2.5"`, true},
+		{`metadata.priority < 3`, false},
+		{`metadata.archived = false`, true},
+		{`metadata.tags : "sale"`, true},
+		{`metadata.tags = "old"`, false},
+		{`metadata.labels.region = "eu"`, false},
+		{`metadata.priority.level = 1`, false},
+		{`metadata.labels.env:*`, true},
+		{`metadata.owner:*`, true},
+		{`metadata.labels.region:*`, false},
+		{`metadata.priority.level:*`, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.filter, func(t *testing.T) {
+			filter, err := aip.ProtoFilter[testpb.Book](mustParse(t, tc.filter))
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, filter(book))
+		})
+	}
+
+	// Keys of an unset struct are absent.
+	filter, err := aip.ProtoFilter[testpb.Book](mustParse(t, `metadata.labels.env = "prod"`))
+	require.NoError(t, err)
+	require.False(t, filter(&testpb.Book{}))
+}
+
+func TestMatchesFilter_Any(t *testing.T) {
+	details, err := anypb.New(&testpb.Review{Rating: 4, Text: "Gripping."})
+	require.NoError(t, err)
+	book := &testpb.Book{Details: details}
+
+	tests := []struct {
+		filter   string
+		expected bool
+	}{
+		{`details.@type = "type.googleapis.com/test.Review"`, true},
+		{`details.@type = "type.googleapis.com/test.Author"`, false},
+		{`details.@type : "test.Review"`, true},
+		{`details.rating > 3`, true},
+		{`details.rating = 5`, false},
+		{`details.text : "Grip"`, true},
+		{`details.@type:*`, true},
+		{`details.text:*`, true},
+		// Fields the packed type lacks resolve to nil.
+		{`details.family_name = "Austen"`, false},
+		{`details.family_name:*`, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.filter, func(t *testing.T) {
+			filter, err := aip.ProtoFilter[testpb.Book](mustParse(t, tc.filter))
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, filter(book))
+		})
+	}
+
+	// Unregistered types are not unpacked.
+	unknown := &testpb.Book{Details: &anypb.Any{TypeUrl: "type.googleapis.com/test.Unknown", Value: details.Value}}
+	filter, err := aip.ProtoFilter[testpb.Book](mustParse(t, `details.rating > 3`))
+	require.NoError(t, err)
+	require.False(t, filter(unknown))
+	require.False(t, filter(&testpb.Book{}))
+
+	_, err = aip.ProtoFilter[testpb.Book](mustParse(t, `details.@type.name = x`))
+	require.ErrorContains(t, err, "cannot descend into @type")
+}
+
+func TestMatchesFilter_Oneof(t *testing.T) {
+	byCode := &testpb.Book{Edition: &testpb.Book_EditionCode{EditionCode: "first"}}
+	byVolume := &testpb.Book{Edition: &testpb.Book_Volume{Volume: 0}}
+	neither := &testpb.Book{}
+
+	tests := []struct {
+		filter string
+		want   []bool // byCode, byVolume, neither
+	}{
+		{`edition_code = "first"`, []bool{true, false, false}},
+		{`edition_code != "second"`, []bool{true, false, false}},
+		{`edition_code = ""`, []bool{false, false, false}},
+		{`volume = 0`, []bool{false, true, false}},
+		{`volume != 1`, []bool{false, true, false}},
+		{`NOT volume = 1`, []bool{true, true, true}},
+		{`edition:*`, []bool{true, true, false}},
+		{`volume:*`, []bool{false, true, false}},
+		{`NOT edition:*`, []bool{false, false, true}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.filter, func(t *testing.T) {
+			filter, err := aip.ProtoFilter[testpb.Book](mustParse(t, tc.filter))
+			require.NoError(t, err)
+			require.Equal(t, tc.want, []bool{filter(byCode), filter(byVolume), filter(neither)})
+		})
+	}
+
+	_, err := aip.ProtoFilter[testpb.Book](mustParse(t, `edition.volume:*`))
+	require.ErrorContains(t, err, `cannot descend into oneof "edition"`)
+}
+
+func mustParse(t *testing.T, filter string) *aip.Filter {
+	t.Helper()
+	f, err := aip.ParseFilter(filter)
+	require.NoError(t, err)
+	return f
+}
+
+func TestMatchesFilter_TypedLiterals(t *testing.T) {
+	book := &testpb.Book{
+		Title:         "1984",
+		AverageRating: 4.25,
+		WeightKg:      0.5,
+	}
+
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{`average_rating > 4.2`, true},
+		{`average_rating < 4.3`, true},
+		{`average_rating = 4.25`, true},
+		{`average_rating >= 4.5e0`, false},
+		{`weight_kg = 5e-1`, true},
+		{`title = 1984`, true},
+		{`title = "1984"`, true},
+		{`title = 1984.0`, false},
+		{`average_rating >= -1`, true},
+		{`average_rating < -1.5e3`, false},
+		{`average_rating > -4.25E0`, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.filter, func(t *testing.T) {
+			filter, err := aip.ProtoFilter[testpb.Book](mustParse(t, tc.filter))
+			require.NoError(t, err)
+			require.Equal(t, tc.want, filter(book))
+		})
+	}
+
+	// Literals of a type that cannot compare with the field are rejected
+	// when the filter is compiled.
+	_, err := aip.ProtoFilter[testpb.Book](mustParse(t, `average_rating = true`))
+	require.ErrorContains(t, err, `cannot compare double field "average_rating" with BOOL literal true`)
+	_, err = aip.ProtoFilter[testpb.Book](mustParse(t, `average_rating = "true"`))
+	require.NoError(t, err, "quoted strings are parsed as the type of the field")
+}
+
+func TestMatchesFilter_EscapedStrings(t *testing.T) {
+	book := &testpb.Book{
+		Title: `The "Quoted" Title`,
+		Tags:  []string{`C:\Books`, "two\nlines"},
+	}
+
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{`title = "The \"Quoted\" Title"`, true},
+		{`title:"\"Quoted\""`, true},
+		{`title = "The \u0022Quoted\u0022 Title"`, true},
+		{`tags = "C:\\Books"`, true},
+		{`tags = "two\nlines"`, true},
+		{`tags = "two\\nlines"`, false},
+		{`"\"Quoted\""`, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.filter, func(t *testing.T) {
+			filter, err := aip.ProtoFilter[testpb.Book](mustParse(t, tc.filter))
+			require.NoError(t, err)
+			require.Equal(t, tc.want, filter(book))
+		})
+	}
+}
+
+func TestProtoFilter_StrictFields(t *testing.T) {
+	book := &testpb.Book{Title: "Dune", PageCount: proto.Int32(412)}
+
+	valid := map[string]bool{
+		`title = Dune`:         true,
+		`title = "Dune"`:       true,
+		`"Dune" = title`:       true,
+		`page_count > 400`:     true,
+		`42 = 42`:              true,
+		`Dune`:                 true,
+		`edition:*`:            false,
+		`shared_with_me = yes`: true,
+	}
+	for filter, want := range valid {
+		t.Run(filter, func(t *testing.T) {
+			f, err := aip.ProtoFilter[testpb.Book](mustParse(t, filter),
+				aip.WithStrictFields(),
+				aip.WithMatcher("shared_with_me", func(context.Context, proto.Message, string, string) (bool, error) {
+					return true, nil
+				}),
+			)
+			require.NoError(t, err)
+			require.Equal(t, want, f(book))
+		})
+	}
+
+	invalid := map[string]string{
+		`tittle = "Dune"`:          `unknown field "tittle"`,
+		`NOT (tittle:Dune)`:        `unknown field "tittle"`,
+		`title = Dune OR pages>1`:  `unknown field "pages"`,
+		`authors:* AND tittle < 3`: `unknown field "tittle"`,
+	}
+	for filter, want := range invalid {
+		_, err := aip.ProtoFilter[testpb.Book](mustParse(t, filter), aip.WithStrictFields())
+		require.ErrorContains(t, err, want, filter)
+
+		// Without strict fields, the unknown name is a literal.
+		_, err = aip.ProtoFilter[testpb.Book](mustParse(t, filter))
+		require.NoError(t, err, filter)
+	}
+}
@@ -0,0 +1,63 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// dateLayout is the layout of the dates returned by the date function, as
+// CAST(DATE AS STRING) writes them in Standard SQL.
+const dateLayout = "2006-01-02"
+
+// locations caches the time zones loaded by the date function, since
+// loading one reads the time zone database.
+var locations sync.Map // map[string]*time.Location
+
+// DateFunction returns the definition of the filter function
+// `date(timestamp, time_zone)`, which returns the calendar date of a
+// timestamp in an IANA time zone as a string in the format "YYYY-MM-DD",
+// e.g.
+//
+//	date(create_time, "America/New_York") = "2024-06-01"
+//
+// Since the dates sort as strings, they may be compared with < and > too.
+// Register it with NewFunctionRegistry; the in-memory filters and
+// Table.WhereClause, where it is DATE(timestamp, time_zone), agree on every
+// time zone the database knows. An unknown time zone is an error.
+func DateFunction() FunctionDef {
+	return FunctionDef{
+		Name:   "date",
+		Args:   []ValueType{ValueTimestamp, ValueString},
+		Result: ValueString,
+		Eval: func(_ context.Context, args []any) (any, error) {
+			loc, err := loadLocation(args[1].(string))
+			if err != nil {
+				return nil, err
+			}
+			return args[0].(time.Time).In(loc).Format(dateLayout), nil
+		},
+		SQL: func(args []string) string {
+			return "CAST(DATE(" + args[0] + ", " + args[1] + ") AS STRING)"
+		},
+	}
+}
+
+// loadLocation returns the time zone with the given IANA name.
+func loadLocation(name string) (*time.Location, error) {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	// time.LoadLocation takes "" to mean UTC and "Local" to mean the time
+	// zone of the server, which the database does not know.
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
@@ -0,0 +1,58 @@
+package query_test
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+	aip "github.com/hxtk/aip/query"
+)
+
+func TestDateFunction(t *testing.T) {
+	functions, err := aip.NewFunctionRegistry(aip.DateFunction())
+	require.NoError(t, err)
+	// Late on May 31st in New York, but June 1st in UTC.
+	book := &testpb.Book{CreateTime: timestamppb.New(time.Date(2024, 6, 1, 2, 30, 0, 0, time.UTC))}
+
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{`date(create_time, "UTC") = "2024-06-01"`, true},
+		{`date(create_time, "America/New_York") = "2024-06-01"`, false},
+		{`date(create_time, "America/New_York") = "2024-05-31"`, true},
+		{`date(create_time, "Asia/Tokyo") > "2024-05-31"`, true},
+		{`date(create_time, "America/New_York") < "2024-06-01"`, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.filter, func(t *testing.T) {
+			match, err := aip.ProtoFilter[testpb.Book](mustParse(t, tc.filter), aip.WithFunctions(functions))
+			require.NoError(t, err)
+			require.Equal(t, tc.want, match(book))
+			// An unset timestamp has no date.
+			require.False(t, match(&testpb.Book{}))
+		})
+	}
+
+	for _, zone := range []string{"Mars/Olympus_Mons", "", "Local"} {
+		match, err := aip.ProtoFilterCtx[testpb.Book](mustParse(t, `date(create_time, "`+zone+`") = "2024-06-01"`), aip.WithFunctions(functions))
+		require.NoError(t, err)
+		_, err = match(t.Context(), book)
+		require.ErrorContains(t, err, "unknown time zone", zone)
+	}
+
+	table := aip.NewTable().WithColumns(
+		aip.NewColumn().WithFieldPath("create_time").WithDatabaseName("db_create_time").Filterable().Build(),
+	).WithFunctions(functions).Build()
+	sql, params, err := table.WhereClause(mustParse(t, `date(create_time, "America/New_York") = "2024-06-01"`), "p_")
+	require.NoError(t, err)
+	require.Equal(t, "((CAST(DATE(db_create_time, @p_0) AS STRING)) = @p_1)", sql)
+	require.Equal(t, []aip.QueryParameter{
+		{Name: "p_0", Value: "America/New_York"},
+		{Name: "p_1", Value: "2024-06-01"},
+	}, params)
+}
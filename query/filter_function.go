@@ -0,0 +1,452 @@
+package query
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ValueType is the type of an argument or of the result of a filter
+// function.
+type ValueType int32
+
+const (
+	// ValueString is a string, a Go string.
+	ValueString ValueType = iota
+	// ValueInt is an integer, a Go int64.
+	ValueInt
+	// ValueFloat is a floating point number, a Go float64.
+	ValueFloat
+	// ValueBool is a boolean, a Go bool.
+	ValueBool
+	// ValueTimestamp is a point in time, a Go time.Time. Literals are
+	// written in RFC 3339 format, e.g. "2024-01-02T15:04:05Z".
+	ValueTimestamp
+)
+
+func (t ValueType) String() string {
+	switch t {
+	case ValueString:
+		return "STRING"
+	case ValueInt:
+		return "INT"
+	case ValueFloat:
+		return "FLOAT"
+	case ValueBool:
+		return "BOOL"
+	case ValueTimestamp:
+		return "TIMESTAMP"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// FunctionDef defines a function that filters may call, e.g.
+// `regex(title, "^The")`.
+//
+// The arguments of a call are fields, calls of other functions, or literals,
+// which are converted to the declared argument types when the filter is
+// compiled. A call whose result is a ValueBool may be used as a restriction
+// on its own; the results of other functions must be compared, e.g.
+// `lower(title) = "dune"`.
+type FunctionDef struct {
+	// Name is the name filters call the function by. It may be qualified
+	// with dots, e.g. "math.abs".
+	Name string
+
+	// Args are the types of the arguments of the function.
+	Args []ValueType
+
+	// Result is the type of the result of the function.
+	Result ValueType
+
+	// Eval evaluates the function for ProtoFilter and the other in-memory
+	// filters. args hold the Go values of the types in Args, and the result
+	// must be of the Go type of Result. ctx is the context of the predicate
+	// (see ProtoFilterCtx).
+	//
+	// Eval is not called if an argument is an unset field with explicit
+	// presence, or an absent map key, and the call evaluates to false.
+	Eval func(ctx context.Context, args []any) (any, error)
+
+	// SQL returns the Standard SQL expression of a call for
+	// Table.WhereClause, given the SQL expressions of its arguments, e.g.
+	// "REGEXP_CONTAINS(" + args[0] + ", " + args[1] + ")". The arguments
+	// are injection-safe column names, bound query parameters or calls; the
+	// expression must not contain anything else derived from user input.
+	SQL func(args []string) string
+}
+
+// FunctionRegistry holds the functions filters may call. Register it with
+// WithFunctions for in-memory filters and TableBuilder.WithFunctions for
+// SQL generation; calls of functions missing from the registry are errors.
+type FunctionRegistry struct {
+	byName map[string]*FunctionDef
+}
+
+// NewFunctionRegistry returns a registry of the given functions. It returns
+// an error if a function has no name, a duplicate name, or neither an Eval
+// nor a SQL implementation.
+func NewFunctionRegistry(defs ...FunctionDef) (*FunctionRegistry, error) {
+	r := &FunctionRegistry{byName: make(map[string]*FunctionDef, len(defs))}
+	for _, def := range defs {
+		if def.Name == "" {
+			return nil, fmt.Errorf("function has no name")
+		}
+		if _, ok := r.byName[def.Name]; ok {
+			return nil, fmt.Errorf("duplicate function %q", def.Name)
+		}
+		if def.Eval == nil && def.SQL == nil {
+			return nil, fmt.Errorf("function %q has no implementation", def.Name)
+		}
+		r.byName[def.Name] = &def
+	}
+	return r, nil
+}
+
+// WithFunctions registers the functions that filters may call.
+func WithFunctions(r *FunctionRegistry) FilterOption {
+	return func(o *filterOptions) {
+		o.functions = r
+	}
+}
+
+// lookup returns the definition of the function fn calls, checking the number
+// of its arguments.
+func (r *FunctionRegistry) lookup(fn *Function) (*FunctionDef, error) {
+	var def *FunctionDef
+	if r != nil {
+		def = r.byName[fn.Name]
+	}
+	if def == nil {
+		return nil, fmt.Errorf("unknown function %q", fn.Name)
+	}
+	if len(fn.Args) != len(def.Args) {
+		return nil, fmt.Errorf("function %s takes %d arguments, got %d", fn.Name, len(def.Args), len(fn.Args))
+	}
+	for i, a := range fn.Args {
+		if a.Composite != nil || a.Comparable == nil {
+			return nil, fmt.Errorf("argument %d of function %s must be a field, literal or function call", i+1, fn.Name)
+		}
+	}
+	return def, nil
+}
+
+// ---- in-memory evaluation ----
+
+// resolveComparable returns the value of c in m, as resolveMember, or the
+// result of the function c calls.
+func (ev *evaluator) resolveComparable(m protoreflect.Message, c *Comparable) (any, error) {
+	if c.Function != nil {
+		v, _, err := ev.evalFunction(m, c.Function)
+		return v, err
+	}
+	return ev.resolveMember(m, c.Member)
+}
+
+// evalFunction returns the result of the call fn on m, or nil if an argument
+// is null or the filter is being validated, and the definition of the
+// function.
+func (ev *evaluator) evalFunction(m protoreflect.Message, fn *Function) (any, *FunctionDef, error) {
+	def, err := ev.opts.functions.lookup(fn)
+	if err != nil {
+		return nil, nil, err
+	}
+	if def.Eval == nil {
+		return nil, nil, fmt.Errorf("function %s cannot be evaluated in memory", fn.Name)
+	}
+	args := make([]any, len(fn.Args))
+	null := false
+	for i, a := range fn.Args {
+		v, err := ev.resolveComparable(m, a.Comparable)
+		if err != nil {
+			return nil, nil, err
+		}
+		if isSlice(v) {
+			return nil, nil, fmt.Errorf("argument %d of function %s must not be a repeated field", i+1, fn.Name)
+		}
+		if v, ok := v.(protoreflect.Message); ok && !v.IsValid() {
+			null = true
+			continue
+		}
+		if v == nil {
+			null = true
+			continue
+		}
+		if args[i], err = convertValue(v, def.Args[i]); err != nil {
+			return nil, nil, fmt.Errorf("argument %d of function %s: %w", i+1, fn.Name, err)
+		}
+	}
+	if null || ev.ctx == nil {
+		return nil, def, nil
+	}
+	result, err := def.Eval(ev.ctx, args)
+	if err != nil {
+		return nil, nil, err
+	}
+	if result, err = convertValue(result, def.Result); err != nil {
+		return nil, nil, fmt.Errorf("result of function %s: %w", fn.Name, err)
+	}
+	return result, def, nil
+}
+
+// evalFunctionRestriction evaluates r, whose comparable is a function call.
+func (ev *evaluator) evalFunctionRestriction(m protoreflect.Message, r *Restriction) (bool, error) {
+	fn := r.Comparable.Function
+	lhs, def, err := ev.evalFunction(m, fn)
+	if err != nil {
+		return false, err
+	}
+	if r.Comparator == "" {
+		if def.Result != ValueBool {
+			return false, fmt.Errorf("function %s returns %s, so its result must be compared", fn.Name, def.Result)
+		}
+		b, _ := lhs.(bool)
+		return b, nil
+	}
+	if err := checkFunctionComparator(fn, def, r); err != nil {
+		return false, err
+	}
+	rhs, err := ev.resolveComparable(m, r.Arg.Comparable)
+	if err != nil {
+		return false, err
+	}
+	if rhs == nil || isSlice(rhs) {
+		return false, nil
+	}
+	if rhs, err = convertValue(rhs, def.Result); err != nil {
+		return false, fmt.Errorf("comparing the result of function %s: %w", fn.Name, err)
+	}
+	if lhs == nil {
+		return false, nil
+	}
+	return compareValue(ev.opts.normalizeValue(lhs), ev.opts.normalizeValue(rhs), r.Comparator)
+}
+
+// checkFunctionComparator checks that the result of fn, defined by def, may
+// be compared with the argument of r.
+func checkFunctionComparator(fn *Function, def *FunctionDef, r *Restriction) error {
+	if r.Arg == nil || r.Arg.Comparable == nil {
+		return fmt.Errorf("the result of function %s can only be compared with a value", fn.Name)
+	}
+	switch r.Comparator {
+	case "=", "!=", ":":
+	default:
+		if def.Result == ValueBool {
+			return fmt.Errorf("unsupported comparator %q for the BOOL result of function %s", r.Comparator, fn.Name)
+		}
+	}
+	return nil
+}
+
+// compareValue applies op to two values of the same ValueType. The has
+// operator tests strings for substrings and other values for equality.
+func compareValue(lhs, rhs any, op string) (bool, error) {
+	var c int
+	switch l := lhs.(type) {
+	case string:
+		if op == ":" {
+			return strings.Contains(l, rhs.(string)), nil
+		}
+		c = strings.Compare(l, rhs.(string))
+	case int64:
+		c = cmp.Compare(l, rhs.(int64))
+	case float64:
+		c = cmp.Compare(l, rhs.(float64))
+	case time.Time:
+		c = l.Compare(rhs.(time.Time))
+	case bool:
+		if l != rhs.(bool) {
+			c = 1
+		}
+	}
+	switch op {
+	case "=", ":":
+		return c == 0, nil
+	case "!=":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	case ">=":
+		return c >= 0, nil
+	}
+	return false, fmt.Errorf("unsupported comparator %q", op)
+}
+
+// convertValue converts v, the value of a field, a literal string or the
+// result of a function, to the Go type of t.
+func convertValue(v any, t ValueType) (any, error) {
+	s, literal := v.(string)
+	switch t {
+	case ValueString:
+		if literal {
+			return s, nil
+		}
+	case ValueInt:
+		switch n := v.(type) {
+		case int32:
+			return int64(n), nil
+		case int64:
+			return n, nil
+		case uint32:
+			return int64(n), nil
+		case uint64:
+			return int64(n), nil
+		case int:
+			return int64(n), nil
+		case protoreflect.EnumNumber:
+			return int64(n), nil
+		case string:
+			n64, err := strconv.ParseInt(n, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not an integer", n)
+			}
+			return n64, nil
+		}
+	case ValueFloat:
+		if literal {
+			f, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a number", s)
+			}
+			return f, nil
+		}
+		if f, ok := asFloat64(v); ok {
+			return f, nil
+		}
+	case ValueBool:
+		if literal {
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a boolean", s)
+			}
+			return b, nil
+		}
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case ValueTimestamp:
+		switch ts := v.(type) {
+		case time.Time:
+			return ts, nil
+		case string:
+			parsed, err := time.Parse(time.RFC3339Nano, ts)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not an RFC 3339 timestamp", ts)
+			}
+			return parsed, nil
+		case protoreflect.Message:
+			if ts.Descriptor().FullName() == timestampName {
+				seconds, nanos := timestampParts(ts)
+				return time.Unix(seconds, int64(nanos)).UTC(), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("cannot use a value of type %T as %s", v, t)
+}
+
+// ---- SQL generation ----
+
+// functionQuery returns the SQL expression of the call fn, and the definition
+// of the function.
+// The returned string is an injection-safe SQL expression.
+func (w *whereClause) functionQuery(fn *Function) (string, *FunctionDef, error) {
+	def, err := w.table.functions.lookup(fn)
+	if err != nil {
+		return "", nil, err
+	}
+	if def.SQL == nil {
+		return "", nil, fmt.Errorf("function %s cannot be used in SQL", fn.Name)
+	}
+	args := make([]string, len(fn.Args))
+	for i, a := range fn.Args {
+		if args[i], err = w.functionArg(a.Comparable, def.Args[i]); err != nil {
+			return "", nil, fmt.Errorf("argument %d of function %s: %w", i+1, fn.Name, err)
+		}
+	}
+	return "(" + def.SQL(args) + ")", def, nil
+}
+
+// functionArg returns the SQL expression of c as a value of type t: the
+// column of a filterable field, a function call, or a literal bound as a
+// query parameter.
+// The returned string is an injection-safe SQL expression.
+func (w *whereClause) functionArg(c *Comparable, t ValueType) (string, error) {
+	if c.Function != nil {
+		sql, def, err := w.functionQuery(c.Function)
+		if err != nil {
+			return "", err
+		}
+		if def.Result != t {
+			return "", fmt.Errorf("function %s returns %s, not %s", c.Function.Name, def.Result, t)
+		}
+		return sql, nil
+	}
+	column, err := w.table.FilterableColumnByFieldPath(NewFieldPath(append([]string{c.Member.Value}, c.Member.Fields...)...))
+	if err == nil {
+		return column.valueExpression(), nil
+	}
+	if len(c.Member.Fields) > 0 {
+		return "", err
+	}
+	v, err := convertValue(c.Member.Value, t)
+	if err != nil {
+		return "", err
+	}
+	if t == ValueBool {
+		if v.(bool) {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	}
+	// Bind unsanitised user input to a parameter to protect against SQL injection.
+	return w.bind(v), nil
+}
+
+// functionRestrictionQuery returns the SQL expression equivalent to r, whose
+// comparable is a function call.
+// The returned string is an injection-safe SQL expression.
+func (w *whereClause) functionRestrictionQuery(r *Restriction) (string, error) {
+	fn := r.Comparable.Function
+	lhs, def, err := w.functionQuery(fn)
+	if err != nil {
+		return "", err
+	}
+	if r.Comparator == "" {
+		if def.Result != ValueBool {
+			return "", fmt.Errorf("function %s returns %s, so its result must be compared", fn.Name, def.Result)
+		}
+		return lhs, nil
+	}
+	if err := checkFunctionComparator(fn, def, r); err != nil {
+		return "", err
+	}
+	if r.Comparator == ":" && def.Result == ValueString {
+		arg, err := w.likeComparableValue(r.Arg.Comparable)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("(%s LIKE %s)", lhs, arg), nil
+	}
+	rhs, err := w.functionArg(r.Arg.Comparable, def.Result)
+	if err != nil {
+		return "", fmt.Errorf("comparing the result of function %s: %w", fn.Name, err)
+	}
+	op := r.Comparator
+	switch op {
+	case ":":
+		op = "="
+	case "!=":
+		op = "<>"
+	}
+	return fmt.Sprintf("(%s %s %s)", lhs, op, rhs), nil
+}
@@ -0,0 +1,213 @@
+package query_test
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+	aip "github.com/hxtk/aip/query"
+)
+
+func testFunctions(t *testing.T) *aip.FunctionRegistry {
+	t.Helper()
+	r, err := aip.NewFunctionRegistry(
+		aip.FunctionDef{
+			Name:   "regex",
+			Args:   []aip.ValueType{aip.ValueString, aip.ValueString},
+			Result: aip.ValueBool,
+			Eval: func(_ context.Context, args []any) (any, error) {
+				return regexp.MatchString(args[1].(string), args[0].(string))
+			},
+			SQL: func(args []string) string {
+				return "REGEXP_CONTAINS(" + args[0] + ", " + args[1] + ")"
+			},
+		},
+		aip.FunctionDef{
+			Name:   "lower",
+			Args:   []aip.ValueType{aip.ValueString},
+			Result: aip.ValueString,
+			Eval: func(_ context.Context, args []any) (any, error) {
+				return strings.ToLower(args[0].(string)), nil
+			},
+			SQL: func(args []string) string {
+				return "LOWER(" + args[0] + ")"
+			},
+		},
+		aip.FunctionDef{
+			Name:   "math.double",
+			Args:   []aip.ValueType{aip.ValueInt},
+			Result: aip.ValueInt,
+			Eval: func(_ context.Context, args []any) (any, error) {
+				return 2 * args[0].(int64), nil
+			},
+		},
+		aip.FunctionDef{
+			Name:   "year",
+			Args:   []aip.ValueType{aip.ValueTimestamp},
+			Result: aip.ValueInt,
+			Eval: func(_ context.Context, args []any) (any, error) {
+				return int64(args[0].(time.Time).Year()), nil
+			},
+			SQL: func(args []string) string {
+				return "EXTRACT(YEAR FROM " + args[0] + ")"
+			},
+		},
+	)
+	require.NoError(t, err)
+	return r
+}
+
+func TestMatchesFilter_Functions(t *testing.T) {
+	pages := int32(352)
+	book := &testpb.Book{
+		Title:      "The Pragmatic Programmer",
+		PageCount:  &pages,
+		CreateTime: timestamppb.New(time.Date(1999, 10, 20, 0, 0, 0, 0, time.UTC)),
+	}
+	functions := testFunctions(t)
+
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{`regex(title, "^The")`, true},
+		{`regex(title, "^A")`, false},
+		{`NOT regex(title, "^A")`, true},
+		{`lower(title) = "the pragmatic programmer"`, true},
+		{`lower(title) != "the pragmatic programmer"`, false},
+		{`lower(title):pragmatic`, true},
+		{`lower(title) > "s"`, true},
+		{`regex(lower(title), "^the")`, true},
+		{`math.double(page_count) = 704`, true},
+		{`math.double(page_count) < 704`, false},
+		{`math.double(3) = page_count`, false},
+		{`math.double(-176) = -352`, true},
+		{`year(create_time) = 1999`, true},
+		{`year(create_time) >= 2000`, false},
+		{`year("2024-01-02T00:00:00Z") = 2024`, true},
+		{`title = lower("The Pragmatic Programmer")`, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.filter, func(t *testing.T) {
+			filter, err := aip.ProtoFilter[testpb.Book](mustParse(t, tc.filter), aip.WithFunctions(functions))
+			require.NoError(t, err)
+			require.Equal(t, tc.want, filter(book))
+		})
+	}
+
+	// Functions are not called with unset fields.
+	filter, err := aip.ProtoFilter[testpb.Book](mustParse(t, `year(create_time) = 1970`), aip.WithFunctions(functions))
+	require.NoError(t, err)
+	require.False(t, filter(&testpb.Book{}))
+
+	invalid := map[string]string{
+		`unknown(title)`:                `unknown function "unknown"`,
+		`regex(title)`:                  "takes 2 arguments, got 1",
+		`lower(title)`:                  "its result must be compared",
+		`regex(title, "x") < true`:      `unsupported comparator "<"`,
+		`math.double(title) = 2`:        `"" is not an integer`,
+		`math.double(page_count) = two`: `"two" is not an integer`,
+		`year("yesterday") = 2024`:      "not an RFC 3339 timestamp",
+		`lower(authors) = x`:            "must not be a repeated field",
+		`regex((title), "x")`:           "must be a field, literal or function call",
+	}
+	for filter, want := range invalid {
+		_, err := aip.ProtoFilter[testpb.Book](mustParse(t, filter), aip.WithFunctions(functions))
+		require.ErrorContains(t, err, want, filter)
+	}
+
+	// The fields passed to functions are decoded by wire filters.
+	raw, err := proto.Marshal(book)
+	require.NoError(t, err)
+	wire, err := aip.WireFilter[testpb.Book](mustParse(t, `year(create_time) = 1999 AND regex(lower(title), "^the")`), aip.WithFunctions(functions))
+	require.NoError(t, err)
+	ok, err := wire(raw)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	_, err = aip.ProtoFilter[testpb.Book](mustParse(t, `regex(title, "x")`))
+	require.ErrorContains(t, err, `unknown function "regex"`, "functions must be registered")
+}
+
+func TestNewFunctionRegistry(t *testing.T) {
+	eval := func(context.Context, []any) (any, error) { return true, nil }
+	_, err := aip.NewFunctionRegistry(aip.FunctionDef{Eval: eval})
+	require.ErrorContains(t, err, "no name")
+	_, err = aip.NewFunctionRegistry(aip.FunctionDef{Name: "f", Eval: eval}, aip.FunctionDef{Name: "f", Eval: eval})
+	require.ErrorContains(t, err, "duplicate")
+	_, err = aip.NewFunctionRegistry(aip.FunctionDef{Name: "f"})
+	require.ErrorContains(t, err, "no implementation")
+}
+
+func TestWhereClause_Functions(t *testing.T) {
+	table := aip.NewTable().WithColumns(
+		aip.NewColumn().WithFieldPath("title").WithDatabaseName("db_title").Filterable().Build(),
+		aip.NewColumn().WithFieldPath("create_time").WithDatabaseName("db_create_time").Filterable().Build(),
+	).WithFunctions(testFunctions(t)).Build()
+
+	tests := []struct {
+		filter string
+		sql    string
+		params []aip.QueryParameter
+	}{
+		{
+			filter: `regex(title, "^The")`,
+			sql:    "(REGEXP_CONTAINS(db_title, @p_0))",
+			params: []aip.QueryParameter{{Name: "p_0", Value: "^The"}},
+		},
+		{
+			filter: `NOT regex(lower(title), "^the")`,
+			sql:    "(NOT (REGEXP_CONTAINS((LOWER(db_title)), @p_0)))",
+			params: []aip.QueryParameter{{Name: "p_0", Value: "^the"}},
+		},
+		{
+			filter: `lower(title) != "dune"`,
+			sql:    "((LOWER(db_title)) <> @p_0)",
+			params: []aip.QueryParameter{{Name: "p_0", Value: "dune"}},
+		},
+		{
+			filter: `lower(title):dune`,
+			sql:    "((LOWER(db_title)) LIKE @p_0)",
+			params: []aip.QueryParameter{{Name: "p_0", Value: "%dune%"}},
+		},
+		{
+			filter: `year(create_time) >= 2000`,
+			sql:    "((EXTRACT(YEAR FROM db_create_time)) >= @p_0)",
+			params: []aip.QueryParameter{{Name: "p_0", Value: int64(2000)}},
+		},
+		{
+			filter: `year("2024-01-02T00:00:00Z") = 2024`,
+			sql:    "((EXTRACT(YEAR FROM @p_0)) = @p_1)",
+			params: []aip.QueryParameter{
+				{Name: "p_0", Value: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+				{Name: "p_1", Value: int64(2024)},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.filter, func(t *testing.T) {
+			sql, params, err := table.WhereClause(mustParse(t, tc.filter), "p_")
+			require.NoError(t, err)
+			require.Equal(t, tc.sql, sql)
+			require.Equal(t, tc.params, params)
+		})
+	}
+
+	invalid := map[string]string{
+		`math.double(2) = 4`:  "cannot be used in SQL",
+		`regex(title, 1) < 2`: `unsupported comparator "<"`,
+		`year(x) = 2024`:      "not an RFC 3339 timestamp",
+		`lower(title)`:        "its result must be compared",
+	}
+	for filter, want := range invalid {
+		_, _, err := table.WhereClause(mustParse(t, filter), "p_")
+		require.ErrorContains(t, err, want, filter)
+	}
+}
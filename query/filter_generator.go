@@ -33,8 +33,11 @@ type whereClause struct {
 
 // QueryParameter represents a query parameter.
 type QueryParameter struct {
-	Name  string
-	Value string
+	Name string
+	// Value is the value to bind. Filter arguments are bound as strings;
+	// cursor values (see SeekClause) are bound using the Go type matching
+	// the field, e.g. time.Time for google.protobuf.Timestamp fields.
+	Value any
 }
 
 // WhereClause creates a Standard SQL WHERE clause fragment for the given filter.
@@ -45,8 +48,11 @@ type QueryParameter struct {
 //
 // All field names are replaced with the safe database column names from the specified table.
 // All user input strings are passed via query parameters, so the returned query is SQL injection safe.
+//
+// If the clause exceeds the limits of the table, the error is a
+// *FieldViolationError wrapping ErrSQLTooLarge.
 func (t *Table) WhereClause(filter *Filter, parameterPrefix string) (string, []QueryParameter, error) {
-	if filter.Expression == nil {
+	if filter == nil || filter.Expression == nil {
 		return "(TRUE)", []QueryParameter{}, nil
 	}
 
@@ -59,6 +65,10 @@ func (t *Table) WhereClause(filter *Filter, parameterPrefix string) (string, []Q
 	if err != nil {
 		return "", []QueryParameter{}, err
 	}
+	if err := t.limits.Check(clause, q.parameters); err != nil {
+		return "", []QueryParameter{}, newFieldViolation(FilterField, fmt.Errorf("filter is too complex: %w", err))
+	}
+	t.reportWarnings(filter, nil)
 	return clause, q.parameters, nil
 }
 
@@ -140,6 +150,9 @@ func (w *whereClause) simpleQuery(simple *Simple) (string, error) {
 // restriction.
 // The returned string is an injection-safe SQL expression.
 func (w *whereClause) restrictionQuery(restriction *Restriction) (string, error) {
+	if restriction.Comparable.Function != nil {
+		return w.functionRestrictionQuery(restriction)
+	}
 	if restriction.Comparable.Member == nil {
 		return "", fmt.Errorf("invalid comparable")
 	}
@@ -158,7 +171,7 @@ func (w *whereClause) restrictionQuery(restriction *Restriction) (string, error)
 		// marked for implicit matching.
 		for _, column := range w.table.columns {
 			if column.implicitFilter {
-				clauses = append(clauses, fmt.Sprintf("%s LIKE %s", column.databaseName, arg))
+				clauses = append(clauses, fmt.Sprintf("%s LIKE %s", column.valueExpression(), arg))
 			}
 		}
 		return "(" + strings.Join(clauses, " OR ") + ")", nil
@@ -167,6 +180,12 @@ func (w *whereClause) restrictionQuery(restriction *Restriction) (string, error)
 	if err != nil {
 		return "", err
 	}
+	if column.synthetic {
+		return w.syntheticQuery(column, restriction)
+	}
+	if isPresenceTest(restriction) {
+		return w.presenceQuery(column, restriction.Comparable.Member.Fields)
+	}
 	if len(restriction.Comparable.Member.Fields) > 0 {
 		if !column.keyValue {
 			return "", fmt.Errorf("fields are only supported for key value columns.  Try removing the '.' from after your column named %q", column.fieldPath.String())
@@ -211,24 +230,48 @@ func (w *whereClause) restrictionQuery(restriction *Restriction) (string, error)
 		if err != nil {
 			return "", errors.Annotate(err, "argument for field %s", column.fieldPath.String()).Err()
 		}
-		return fmt.Sprintf("(%s = %s)", column.databaseName, arg), nil
+		return fmt.Sprintf("(%s = %s)", column.valueExpression(), arg), nil
 	} else if restriction.Comparator == "!=" {
 		arg, err := w.argValue(restriction.Arg, column)
 		if err != nil {
 			return "", errors.Annotate(err, "argument for field %s", column.fieldPath.String()).Err()
 		}
-		return fmt.Sprintf("(%s <> %s)", column.databaseName, arg), nil
+		return fmt.Sprintf("(%s <> %s)", column.valueExpression(), arg), nil
 	} else if restriction.Comparator == ":" {
 		arg, err := w.likeArgValue(restriction.Arg, column)
 		if err != nil {
 			return "", errors.Annotate(err, "argument for field %s", column.fieldPath.String()).Err()
 		}
-		return fmt.Sprintf("(%s LIKE %s)", column.databaseName, arg), nil
+		return fmt.Sprintf("(%s LIKE %s)", column.valueExpression(), arg), nil
 	} else {
 		return "", fmt.Errorf("comparator operator not implemented yet")
 	}
 }
 
+// presenceQuery returns the SQL expression testing for the presence of the
+// given column, or of the given key of a key value column.
+// The returned string is an injection-safe SQL expression.
+func (w *whereClause) presenceQuery(column *Column, fields []string) (string, error) {
+	if len(fields) > 0 {
+		if !column.keyValue {
+			return "", fmt.Errorf("fields are only supported for key value columns.  Try removing the '.' from after your column named %q", column.fieldPath.String())
+		}
+		if len(fields) > 1 {
+			return "", fmt.Errorf("expected only a single '.' in keyvalue column named %q", column.fieldPath.String())
+		}
+		key := w.bind(fields[0])
+		return fmt.Sprintf("(EXISTS (SELECT key FROM UNNEST(%s) WHERE key = %s))", column.databaseName, key), nil
+	}
+	if column.keyValue || column.array {
+		return fmt.Sprintf("(ARRAY_LENGTH(%s) > 0)", column.databaseName), nil
+	}
+	if column.nullZero != "" {
+		// Fields without presence are present when they are not zero.
+		return fmt.Sprintf("(%s <> %s)", column.valueExpression(), column.nullZero), nil
+	}
+	return fmt.Sprintf("(%s IS NOT NULL)", column.databaseName), nil
+}
+
 // argValue returns a SQL expression representing the value of the specified
 // arg.
 // The returned string is an injection-safe SQL expression.
@@ -246,6 +289,10 @@ func (w *whereClause) argValue(arg *Arg, column *Column) (string, error) {
 // comparable.
 // The returned string is an injection-safe SQL expression.
 func (w *whereClause) comparableValue(comparable *Comparable, column *Column) (string, error) {
+	if comparable.Function != nil {
+		sql, _, err := w.functionQuery(comparable.Function)
+		return sql, err
+	}
 	if comparable.Member == nil {
 		return "", fmt.Errorf("invalid comparable")
 	}
@@ -309,7 +356,7 @@ func (w *whereClause) likeComparableValue(comparable *Comparable) (string, error
 // bind binds a new query parameter with the given value, and returns
 // the name of the parameter (including '@').
 // The returned string is an injection-safe SQL expression.
-func (w *whereClause) bind(value string) string {
+func (w *whereClause) bind(value any) string {
 	name := w.namePrefix + strconv.Itoa(w.nextValueName)
 	w.nextValueName += 1
 	w.parameters = append(w.parameters, QueryParameter{Name: name, Value: value})
@@ -198,6 +198,37 @@ func TestWhereClause(t *testing.T) {
 				})
 				So(result, ShouldEqual, "(EXISTS (SELECT value FROM UNNEST(db_array) as value WHERE value LIKE @p_0))")
 			})
+			Convey("presence test", func() {
+				filter, err := ParseFilter("baz:*")
+				So(err, ShouldEqual, nil)
+
+				result, pars, err := table.WhereClause(filter, "p_")
+				So(err, ShouldBeNil)
+				So(pars, ShouldHaveLength, 0)
+				So(result, ShouldEqual, "(db_baz IS NOT NULL)")
+			})
+			Convey("presence test on array", func() {
+				filter, err := ParseFilter("array:*")
+				So(err, ShouldEqual, nil)
+
+				result, _, err := table.WhereClause(filter, "p_")
+				So(err, ShouldBeNil)
+				So(result, ShouldEqual, "(ARRAY_LENGTH(db_array) > 0)")
+			})
+			Convey("key value presence test", func() {
+				filter, err := ParseFilter("kv.key:*")
+				So(err, ShouldEqual, nil)
+
+				result, pars, err := table.WhereClause(filter, "p_")
+				So(err, ShouldBeNil)
+				So(pars, ShouldResemble, []QueryParameter{
+					{
+						Name:  "p_0",
+						Value: "key",
+					},
+				})
+				So(result, ShouldEqual, "(EXISTS (SELECT key FROM UNNEST(db_kv) WHERE key = @p_0))")
+			})
 			Convey("unsupported composite to LIKE", func() {
 				filter, err := ParseFilter("foo:(somevalue)")
 				So(err, ShouldEqual, nil)
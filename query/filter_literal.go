@@ -0,0 +1,207 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// LiteralKind is the kind of literal a Member is written as.
+type LiteralKind int32
+
+const (
+	// LiteralText is unquoted text, which names a field if the message has
+	// one by that name and is a string otherwise, e.g. `title` or `Dune`.
+	LiteralText LiteralKind = iota
+	// LiteralString is a quoted string, e.g. `"Dune"`.
+	LiteralString
+	// LiteralInt is an integer, e.g. `42` or `-1`. Its value is an int64.
+	LiteralInt
+	// LiteralFloat is a floating point number, e.g. `3.5`, `-0.5` or
+	// `1.5e3`. Its value is a float64.
+	LiteralFloat
+	// LiteralBool is `true` or `false`. Its value is a bool.
+	LiteralBool
+	// LiteralNull is `null`. Its value is nil.
+	LiteralNull
+)
+
+func (k LiteralKind) String() string {
+	switch k {
+	case LiteralText:
+		return "TEXT"
+	case LiteralString:
+		return "STRING"
+	case LiteralInt:
+		return "INT"
+	case LiteralFloat:
+		return "FLOAT"
+	case LiteralBool:
+		return "BOOL"
+	case LiteralNull:
+		return "NULL"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+var (
+	intLiteral   = regexp.MustCompile(`^-?[0-9]+$`)
+	floatLiteral = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+	// fractionLiteral matches the part of a float literal after its dot,
+	// which the lexer reads as a field name.
+	fractionLiteral = regexp.MustCompile(`^[0-9]+([eE][+-]?[0-9]+)?$`)
+)
+
+// classifyLiteral returns the kind and typed value of the unquoted text s.
+// Integers too large for an int64 are floats.
+func classifyLiteral(s string) (LiteralKind, any) {
+	switch {
+	case s == "true":
+		return LiteralBool, true
+	case s == "false":
+		return LiteralBool, false
+	case s == "null":
+		return LiteralNull, nil
+	case intLiteral.MatchString(s):
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return LiteralInt, n
+		}
+		fallthrough
+	case floatLiteral.MatchString(s):
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			return LiteralFloat, n
+		}
+	}
+	return LiteralText, nil
+}
+
+// checkLiteral returns an error if the literal m can never compare equal to
+// the singular scalar field fd, e.g. a boolean literal with an integer
+// field. Text and string literals, which are parsed as the type of the
+// field when compared, are not checked.
+func checkLiteral(fd protoreflect.FieldDescriptor, m *Member) error {
+	numeric := false
+	switch fd.Kind() {
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind,
+		protoreflect.FloatKind, protoreflect.DoubleKind:
+		numeric = true
+	case protoreflect.BoolKind:
+	default:
+		return nil
+	}
+	// Integers remain comparable with bool fields, as 0 and 1.
+	switch {
+	case numeric && m.Kind == LiteralBool,
+		!numeric && m.Kind == LiteralFloat:
+		return fmt.Errorf("cannot compare %s field %q with %s literal %s", fd.Kind(), fd.Name(), m.Kind, m.Value)
+	}
+	return nil
+}
+
+// unquote returns the value of the quoted string literal s, e.g.
+// `"say \"hi\""`. It supports the escape sequences of Go string literals
+// other than octal ones, as well as \' and \/, and UTF-16 surrogate pairs
+// written as two \u escapes, e.g. `"\ud83d\ude00"`, as produced by JSON
+// encoders.
+func unquote(s string) (string, error) {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return "", fmt.Errorf("string %s is not quoted", s)
+	}
+	s = s[1 : len(s)-1]
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+		if i+1 == len(s) {
+			return "", fmt.Errorf("unterminated escape sequence at offset %d", i)
+		}
+		switch c := s[i+1]; c {
+		case '"', '\\', '\'', '/':
+			b.WriteByte(c)
+			i += 2
+		case 'a', 'b', 'f', 'n', 'r', 't', 'v':
+			b.WriteByte(simpleEscapes[c])
+			i += 2
+		case 'x':
+			n, err := parseHexEscape(s, i, 2)
+			if err != nil {
+				return "", err
+			}
+			b.WriteByte(byte(n))
+			i += 4
+		case 'u', 'U':
+			digits := 4
+			if c == 'U' {
+				digits = 8
+			}
+			n, err := parseHexEscape(s, i, digits)
+			if err != nil {
+				return "", err
+			}
+			start := i
+			i += 2 + digits
+			r := rune(n)
+			if utf16.IsSurrogate(r) {
+				// The low surrogate must follow as another \u escape.
+				low, err := parseHexEscape(s, i, 4)
+				if err != nil || !strings.HasPrefix(s[i:], `\u`) {
+					return "", fmt.Errorf("unpaired surrogate %s at offset %d", s[start:i], start)
+				}
+				if r = utf16.DecodeRune(r, rune(low)); r == utf8.RuneError {
+					return "", fmt.Errorf("invalid surrogate pair %s at offset %d", s[start:i+6], start)
+				}
+				i += 6
+			}
+			if !utf8.ValidRune(r) {
+				return "", fmt.Errorf("invalid code point %s at offset %d", s[start:i], start)
+			}
+			b.WriteRune(r)
+		default:
+			return "", fmt.Errorf("invalid escape sequence \\%c at offset %d", c, i)
+		}
+	}
+	return b.String(), nil
+}
+
+// simpleEscapes maps the letters of single-character escape sequences to
+// the bytes they stand for.
+var simpleEscapes = map[byte]byte{
+	'a': '\a',
+	'b': '\b',
+	'f': '\f',
+	'n': '\n',
+	'r': '\r',
+	't': '\t',
+	'v': '\v',
+}
+
+// parseHexEscape parses the digits hexadecimal digits of the escape
+// sequence at offset i of s, e.g. \x41 or \u00e9.
+func parseHexEscape(s string, i, digits int) (uint64, error) {
+	end := i + 2 + digits
+	if end > len(s) {
+		return 0, fmt.Errorf("escape sequence at offset %d needs %d hexadecimal digits", i, digits)
+	}
+	n, err := strconv.ParseUint(s[i+2:end], 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid escape sequence %s at offset %d", s[i:end], i)
+	}
+	return n, nil
+}
@@ -0,0 +1,177 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// mapWildcard is the map key that addresses every value of a map, e.g.
+// `reviews.*:"Classic"`.
+const mapWildcard = "*"
+
+// parseMapKey converts the text of a map key in a filter into a MapKey of
+// the kind described by fd. The rules are those of map keys in field masks:
+// a key may be quoted in backticks, e.g. `*` for a literal asterisk,
+// integer keys are decimal and bool keys are true or false.
+func parseMapKey(fd protoreflect.FieldDescriptor, s string) (protoreflect.MapKey, error) {
+	if unquoted, ok := strings.CutPrefix(s, "`"); ok && len(s) >= 2 {
+		if unquoted, ok = strings.CutSuffix(unquoted, "`"); ok {
+			s = unquoted
+		}
+	}
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(s).MapKey(), nil
+	case protoreflect.BoolKind:
+		if s != "true" && s != "false" {
+			return protoreflect.MapKey{}, fmt.Errorf("invalid bool map key %q", s)
+		}
+		return protoreflect.ValueOfBool(s == "true").MapKey(), nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, err := strconv.ParseInt(s, 10, 32)
+		if err != nil || !intLiteral.MatchString(s) {
+			return protoreflect.MapKey{}, fmt.Errorf("invalid int32 map key %q", s)
+		}
+		return protoreflect.ValueOfInt32(int32(n)).MapKey(), nil
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || !intLiteral.MatchString(s) {
+			return protoreflect.MapKey{}, fmt.Errorf("invalid int64 map key %q", s)
+		}
+		return protoreflect.ValueOfInt64(n).MapKey(), nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		n, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return protoreflect.MapKey{}, fmt.Errorf("invalid uint32 map key %q", s)
+		}
+		return protoreflect.ValueOfUint32(uint32(n)).MapKey(), nil
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return protoreflect.MapKey{}, fmt.Errorf("invalid uint64 map key %q", s)
+		}
+		return protoreflect.ValueOfUint64(n).MapKey(), nil
+	}
+	return protoreflect.MapKey{}, fmt.Errorf("unsupported map key kind %s", fd.Kind())
+}
+
+// mapFieldOf returns the map field that mem addresses as a whole in messages
+// of type desc, e.g. `reviews` or `detailed_reviews.alice.tags`, or nil if
+// mem addresses anything else.
+func mapFieldOf(desc protoreflect.MessageDescriptor, mem *Member) protoreflect.FieldDescriptor {
+	path := append([]string{mem.Value}, mem.Fields...)
+	for i := 0; i < len(path); i++ {
+		if desc == nil || isJSONMessage(desc) || desc.FullName() == anyName {
+			return nil
+		}
+		fd := desc.Fields().ByName(protoreflect.Name(path[i]))
+		switch {
+		case fd == nil:
+			return nil
+		case i == len(path)-1:
+			if fd.IsMap() {
+				return fd
+			}
+			return nil
+		case fd.IsMap():
+			// Skip the key.
+			i++
+			desc = fd.MapValue().Message()
+		default:
+			desc = fd.Message()
+		}
+	}
+	return nil
+}
+
+// evalMapKey evaluates r, a has restriction on the map field fd, e.g.
+// `reviews:alice` or `items:5`, which is true if the map has the key named
+// by its argument (AIP-160). The argument is parsed as a key of fd.
+func (ev *evaluator) evalMapKey(m protoreflect.Message, fd protoreflect.FieldDescriptor, r *Restriction) (bool, error) {
+	if r.Arg == nil || r.Arg.Comparable == nil || r.Arg.Comparable.Member == nil || len(r.Arg.Comparable.Member.Fields) > 0 {
+		return false, fmt.Errorf("the argument of a has restriction on map field %q must be a key", fd.Name())
+	}
+	key, err := parseMapKey(fd.MapKey(), r.Arg.Comparable.Member.Value)
+	if err != nil {
+		return false, err
+	}
+	lhs, err := resolveMemberValue(m, r.Comparable.Member)
+	if err != nil {
+		return false, err
+	}
+	return hasMapKey(ev.opts.normalizeValue(lhs), ev.opts.normalizeValue(key.Interface())), nil
+}
+
+// hasMapKey reports whether v, a resolved map or a slice of them for maps
+// beneath repeated fields, has the key.
+func hasMapKey(v, key any) bool {
+	switch v := v.(type) {
+	case map[any]any:
+		_, ok := v[key]
+		return ok
+	case []any:
+		for _, el := range v {
+			if hasMapKey(el, key) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolveMapWildcard resolves fields in every value of the map field fd,
+// e.g. `reviews.*` or `detailed_reviews.*.rating`, as a slice, so that a
+// comparison is true if it is true of any value.
+func resolveMapWildcard(fd protoreflect.FieldDescriptor, mp protoreflect.Map, fields []string) (any, error) {
+	md := fd.MapValue().Message()
+	if len(fields) > 0 {
+		if md == nil {
+			return nil, fmt.Errorf("cannot descend into non-message values of map field %q", fd.Name())
+		}
+		if mp.Len() == 0 {
+			return []any{}, checkSubfields(md, fields)
+		}
+	}
+	results := make([]any, 0, mp.Len())
+	var err error
+	mp.Range(func(_ protoreflect.MapKey, v protoreflect.Value) bool {
+		if len(fields) == 0 {
+			results = append(results, fieldValue(fd.MapValue(), v))
+			return true
+		}
+		var sub any
+		sub, err = resolveMemberValueFromMessage(v.Message(), fields)
+		results = append(results, sub)
+		return err == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// hasMapWildcardPath reports whether path is present in any value of the map
+// field fd, e.g. `detailed_reviews.*.text:*`. With no path, it reports
+// whether the map has any value.
+func hasMapWildcardPath(fd protoreflect.FieldDescriptor, mp protoreflect.Map, path []string) (bool, error) {
+	if len(path) == 0 {
+		return mp.Len() > 0, nil
+	}
+	md := fd.MapValue().Message()
+	if md == nil {
+		return false, fmt.Errorf("cannot descend into non-message values of map field %q", fd.Name())
+	}
+	if mp.Len() == 0 {
+		return false, checkSubfields(md, path)
+	}
+	var found bool
+	var err error
+	mp.Range(func(_ protoreflect.MapKey, v protoreflect.Value) bool {
+		found, err = hasFieldPath(v.Message(), path)
+		return err == nil && !found
+	})
+	return found, err
+}
@@ -0,0 +1,45 @@
+package query
+
+import (
+	"golang.org/x/text/unicode/norm"
+)
+
+// WithNormalization applies the Unicode normalization form to both operands
+// of string comparisons, substring matches and global restrictions, so that
+// equivalent strings match regardless of how they are encoded, e.g. "é" as
+// U+00E9 or as "e" followed by U+0301 (AIP-210).
+//
+// norm.NFC matches canonically equivalent strings; norm.NFKC also matches
+// compatibility equivalents, such as "ﬁ" and "fi". Strings are compared
+// unnormalized by default.
+func WithNormalization(form norm.Form) FilterOption {
+	return func(o *filterOptions) {
+		o.normalize = form.String
+	}
+}
+
+// normalizeValue returns v with every string it holds, including the
+// elements of slices and the keys and values of maps, normalized by o. v is
+// returned as is if o has no normalization.
+func (o *filterOptions) normalizeValue(v any) any {
+	if o.normalize == nil {
+		return v
+	}
+	switch v := v.(type) {
+	case string:
+		return o.normalize(v)
+	case []any:
+		out := make([]any, len(v))
+		for i, el := range v {
+			out[i] = o.normalizeValue(el)
+		}
+		return out
+	case map[any]any:
+		out := make(map[any]any, len(v))
+		for k, el := range v {
+			out[o.normalizeValue(k)] = o.normalizeValue(el)
+		}
+		return out
+	}
+	return v
+}
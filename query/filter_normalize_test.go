@@ -0,0 +1,65 @@
+package query_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+	aip "github.com/hxtk/aip/query"
+)
+
+func TestMatchesFilter_Normalization(t *testing.T) {
+	const (
+		composed   = "Caf\u00e9"  // "é" as a single code point.
+		decomposed = "Cafe\u0301" // "e" followed by a combining acute accent.
+	)
+	book := &testpb.Book{
+		Title:   decomposed + " Society",
+		Tags:    []string{decomposed},
+		Reviews: map[string]string{decomposed: decomposed},
+		Author:  &testpb.Author{GivenName: "\ufb01ona"}, // "fi" as a ligature.
+	}
+
+	tests := []struct {
+		filter string
+		form   norm.Form
+		want   bool
+	}{
+		{`title = "` + composed + ` Society"`, norm.NFC, true},
+		{`title:"` + composed + `"`, norm.NFC, true},
+		{`title > "` + composed + `"`, norm.NFC, true},
+		{`tags = "` + composed + `"`, norm.NFC, true},
+		{`reviews.` + decomposed + ` = "` + composed + `"`, norm.NFC, true},
+		{`reviews.*:"` + composed + `"`, norm.NFC, true},
+		{`reviews:"` + composed + `"`, norm.NFC, true},
+		{`"` + strings.ToLower(composed) + `"`, norm.NFC, true},
+		{`lower(title) = "` + strings.ToLower(composed) + ` society"`, norm.NFC, true},
+		{`author.given_name = fiona`, norm.NFC, false},
+		{`author.given_name = fiona`, norm.NFKC, true},
+		{`fiona`, norm.NFKC, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.filter, func(t *testing.T) {
+			f := mustParse(t, tc.filter)
+			filter, err := aip.ProtoFilter[testpb.Book](f, aip.WithNormalization(tc.form), aip.WithFunctions(testFunctions(t)))
+			require.NoError(t, err)
+			require.Equal(t, tc.want, filter(book))
+		})
+	}
+
+	// Without normalization, equivalent strings of different forms differ.
+	filter, err := aip.ProtoFilter[testpb.Book](mustParse(t, `title:"`+composed+`"`))
+	require.NoError(t, err)
+	require.False(t, filter(book))
+
+	// The search term of global restrictions is normalized too.
+	ctxFilter, err := aip.ProtoFilterCtx[testpb.Book](mustParse(t, composed), aip.WithNormalization(norm.NFD))
+	require.NoError(t, err)
+	ok, err := ctxFilter(context.Background(), &testpb.Book{Title: composed})
+	require.NoError(t, err)
+	require.True(t, ok)
+}
@@ -0,0 +1,61 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// ParseError is the error ParseFilter returns for invalid filters. It
+// locates the token at which parsing failed, so that callers can point at
+// it, e.g. in the description of an INVALID_ARGUMENT error or by
+// underlining it in a UI.
+type ParseError struct {
+	// Position is the byte offset of Token in the filter.
+	Position int
+	// RunePosition is the offset of Token in the filter in runes, as
+	// counted by UIs.
+	RunePosition int
+	// Token is the text of the token at which parsing failed, as written
+	// in the filter, or empty at the end of the filter.
+	Token string
+
+	err error
+}
+
+// Error implements error.
+func (e *ParseError) Error() string {
+	if e.Token == "" {
+		return fmt.Sprintf("%v (at end of filter)", e.err)
+	}
+	return fmt.Sprintf("%v (at position %d near %q)", e.err, e.Position, e.Token)
+}
+
+// Unwrap returns the underlying error.
+func (e *ParseError) Unwrap() error {
+	return e.err
+}
+
+// errorAt returns a *ParseError for err at the token tok at byte offset pos
+// of the filter.
+func (l *filterLexer) errorAt(pos int, tok string, err error) *ParseError {
+	return &ParseError{
+		Position:     pos,
+		RunePosition: utf8.RuneCountInString(l.src[:pos]),
+		Token:        tok,
+		err:          err,
+	}
+}
+
+// locate returns err as a *ParseError at the token lexed last, unless it
+// already is one.
+func (l *filterLexer) locate(err error) error {
+	var pe *ParseError
+	if errors.As(err, &pe) {
+		return err
+	}
+	if l.last == nil {
+		return l.errorAt(0, "", err)
+	}
+	return l.errorAt(l.last.pos, l.last.value, err)
+}
@@ -0,0 +1,53 @@
+package query_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	aip "github.com/hxtk/aip/query"
+)
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		filter       string
+		position     int
+		runePosition int
+		token        string
+		message      string
+	}{
+		{`title = `, 8, 8, "", "expected arg after = (at end of filter)"},
+		{`title = Dune)`, 12, 12, ")", `expected END but got RPAREN(")") (at position 12 near ")")`},
+		{`a AND `, 6, 6, "", "expected sequence after AND (at end of filter)"},
+		{`(a OR b`, 7, 7, "", ""},
+		{`title = "a\qb"`, 8, 8, `"a\qb"`, ""},
+		{`title = "é" AND rating = "x\q"`, 26, 25, `"x\q"`, ""},
+		{`title = "é" AND !x`, 17, 16, "!", ""},
+		{`a ! b`, 2, 2, "!", ""},
+		{`f(a, )`, 5, 5, ")", ""},
+		{`x = -y`, 5, 5, "y", ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.filter, func(t *testing.T) {
+			_, err := aip.ParseFilter(tc.filter)
+			var pe *aip.ParseError
+			require.ErrorAs(t, err, &pe)
+			require.Equal(t, tc.position, pe.Position)
+			require.Equal(t, tc.runePosition, pe.RunePosition)
+			require.Equal(t, tc.token, pe.Token)
+			if tc.message != "" {
+				require.EqualError(t, err, tc.message)
+			}
+
+			// Reused parsers locate errors too.
+			var p aip.Parser
+			_, err = p.Parse(tc.filter)
+			require.ErrorAs(t, err, &pe)
+			require.Equal(t, tc.position, pe.Position)
+		})
+	}
+
+	_, err := aip.ParseFilter(`title = "\q"`)
+	require.ErrorContains(t, errors.Unwrap(err), `invalid escape sequence \q`)
+}
@@ -19,7 +19,6 @@ package query
 
 // This file contains a lexer and parser for AIP-160 filter expressions.
 // The EBNF is at https://google.aip.dev/assets/misc/ebnf-filtering.txt
-// The function call syntax is not supported which simplifies the parser.
 //
 // Implemented EBNF (in terms of lexer tokens):
 // filter: [expression];
@@ -29,10 +28,14 @@ package query
 // term: [NEGATE] simple;
 // simple: restriction | composite;
 // restriction: comparable [COMPARATOR arg];
-// comparable: member;
+// comparable: member | function;
 // member: (TEXT | STRING) {DOT TEXT};
+// function: TEXT {DOT TEXT} LPAREN [arg {COMMA arg}] RPAREN;
 // composite: LPAREN expression RPAREN;
-// arg: comparable | composite;
+// arg: [MINUS] NUMBER | comparable | composite;
+//
+// The LPAREN of a function call must immediately follow its name: `f(x)` is
+// a call, while `f (x)` is the sequence of `f` and the composite `(x)`.
 //
 // TODO(mwarton): Redo whitespace handling.  There are still some cases (like "- 30")
 // 				  which are accepted as valid instead of being rejected.
@@ -41,6 +44,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 const (
@@ -58,20 +62,38 @@ const (
 )
 
 // lexerRegexp has one group for each kind of token that can be lexed, in the order of the kind consts above. There are two cases for kindNegate to handle whitespace correctly.
-var lexerRegexp = regexp.MustCompile(`^(<=|>=|!=|<|>|=|\:)|(NOT\s)|(-)|(AND\s)|(OR\s)|(\.)|(\()|(\))|(,)|("(?:[^"\\]|\\.)*")|([^\s\.,<>=!:\(\)]+)`)
+// All of the groups are anchored to the start of the input.
+var lexerRegexp = regexp.MustCompile(`^(?:(<=|>=|!=|<|>|=|\:)|(NOT\s)|(-)|(AND\s)|(OR\s)|(\.)|(\()|(\))|(,)|("(?:[^"\\]|\\.)*")|([^\s\.,<>=!:\(\)]+))`)
 
 type token struct {
 	kind  string
 	value string
+	// spaced reports whether the token was preceded by whitespace.
+	spaced bool
+	// pos is the byte offset of the token in the filter.
+	pos int
 }
 
 type filterLexer struct {
+	// src is the whole filter, and input the part of it not yet lexed.
+	src   string
 	input string
 	next  *token
+	// spaced reports whether whitespace preceded the token being lexed,
+	// and pos is its byte offset.
+	spaced bool
+	pos    int
+	// last is the token lexed last, which is the token the parser is
+	// looking at when it fails.
+	last *token
+
+	// slab, when non-nil, is reused to hold lexed tokens instead of
+	// allocating each one. See Parser.
+	slab *[]token
 }
 
 func NewLexer(input string) *filterLexer {
-	return &filterLexer{input: input}
+	return &filterLexer{src: input, input: input}
 }
 
 func (l *filterLexer) Peek() (*token, error) {
@@ -85,6 +107,22 @@ func (l *filterLexer) Peek() (*token, error) {
 	return l.next, nil
 }
 
+// newToken returns a token with the given kind and value, reusing storage
+// from the lexer's slab when one is configured.
+func (l *filterLexer) newToken(kind, value string) *token {
+	if l.slab == nil {
+		l.last = &token{kind: kind, value: value, spaced: l.spaced, pos: l.pos}
+		return l.last
+	}
+	if len(*l.slab) == cap(*l.slab) {
+		// Earlier tokens keep referencing the old backing array.
+		*l.slab = make([]token, 0, 2*cap(*l.slab)+16)
+	}
+	*l.slab = append(*l.slab, token{kind: kind, value: value, spaced: l.spaced, pos: l.pos})
+	l.last = &(*l.slab)[len(*l.slab)-1]
+	return l.last
+}
+
 func (l *filterLexer) Next() (*token, error) {
 	if l.next != nil {
 		next := l.next
@@ -92,75 +130,93 @@ func (l *filterLexer) Next() (*token, error) {
 		return next, nil
 	}
 	l.next = nil
-	l.input = strings.TrimLeft(l.input, " \t\r\n")
+	trimmed := strings.TrimLeft(l.input, " \t\r\n")
+	l.spaced = len(trimmed) < len(l.input)
+	l.input = trimmed
+	l.pos = len(l.src) - len(l.input)
 	if l.input == "" {
-		return &token{kind: kindEnd}, nil
+		return l.newToken(kindEnd, ""), nil
 	}
 	matches := lexerRegexp.FindStringSubmatch(l.input)
 	if matches == nil {
-		return nil, fmt.Errorf("error: unable to lex token from %q", l.input)
+		_, size := utf8.DecodeRuneInString(l.input)
+		return nil, l.errorAt(l.pos, l.input[:size], fmt.Errorf("error: unable to lex token from %q", l.input))
 	}
 	l.input = l.input[len(matches[0]):]
 	if matches[1] != "" {
-		return &token{kind: kindComparator, value: matches[1]}, nil
+		return l.newToken(kindComparator, matches[1]), nil
 	}
 	if matches[2] != "" {
 		// Needs to be fixed up to compensate for the trailing \s in the match which prevents
 		// matching "NOTother" as a negated "other".
 		length := len(matches[2])
-		return &token{kind: kindNegate, value: matches[2][:length-1]}, nil
+		return l.newToken(kindNegate, matches[2][:length-1]), nil
 	}
 	if matches[3] != "" {
-		return &token{kind: kindNegate, value: matches[3]}, nil
+		return l.newToken(kindNegate, matches[3]), nil
 	}
 	if matches[4] != "" {
 		// Needs to be fixed up to compensate for the trailing \s in the match which prevents
 		// matching "ANDother" as a "AND" "other".
 		length := len(matches[4])
-		return &token{kind: kindAnd, value: matches[4][:length-1]}, nil
+		return l.newToken(kindAnd, matches[4][:length-1]), nil
 	}
 	if matches[5] != "" {
 		// Needs to be fixed up to compensate for the trailing \s in the match which prevents
 		// matching "ORother" as a "OR" "other".
 		length := len(matches[5])
-		return &token{kind: kindOr, value: matches[5][:length-1]}, nil
+		return l.newToken(kindOr, matches[5][:length-1]), nil
 	}
 	if matches[6] != "" {
-		return &token{kind: kindDot, value: matches[6]}, nil
+		return l.newToken(kindDot, matches[6]), nil
 	}
 	if matches[7] != "" {
-		return &token{kind: kindLParen, value: matches[7]}, nil
+		return l.newToken(kindLParen, matches[7]), nil
 	}
 	if matches[8] != "" {
-		return &token{kind: kindRParen, value: matches[8]}, nil
+		return l.newToken(kindRParen, matches[8]), nil
 	}
 	if matches[9] != "" {
-		return &token{kind: kindComma, value: matches[9]}, nil
+		return l.newToken(kindComma, matches[9]), nil
 	}
 	if matches[10] != "" {
-		return &token{kind: kindString, value: matches[10]}, nil
+		return l.newToken(kindString, matches[10]), nil
 	}
 	if matches[11] != "" {
-		return &token{kind: kindText, value: matches[11]}, nil
+		return l.newToken(kindText, matches[11]), nil
 	}
 	return nil, fmt.Errorf("error: unhandled lexer regexp match %q", matches[0])
 }
 
 // AST Nodes.  These are based on the EBNF at https://google.aip.dev/assets/misc/ebnf-filtering.txt
-// Note that the syntax for functions is not currently supported.
 
 // Filter, possibly empty
 type Filter struct {
 	Expression *Expression // Optional, may be nil.
 }
 
+// String returns the filter as text in a canonical form, which ParseFilter
+// parses back to an equivalent filter with the same String(), e.g. for
+// logs, cache keys or binding page tokens to their filter. In the canonical
+// form:
+//   - tokens are separated by single spaces, other than around `.`, `:`
+//     and the parentheses and commas of function calls;
+//   - all factors are joined with an explicit AND, including the factors
+//     of sequences, e.g. `a b` is written `a AND b`;
+//   - negation is written NOT, e.g. `-a` is written `NOT a`;
+//   - text arguments of restrictions are quoted, e.g. `title = Dune` is
+//     written `title = "Dune"`, other than the `*` of presence tests.
+//
+// Unlike the String() methods of the other nodes, which describe the AST,
+// String does not tell these spellings apart. Use Canonicalize first for
+// filters that differ only in the order of their terms to have the same
+// text.
 func (v *Filter) String() string {
-	var s strings.Builder
-	s.WriteString("filter{")
-	if v.Expression != nil {
-		s.WriteString(v.Expression.String())
+	if v == nil || v.Expression == nil {
+		return ""
 	}
-	s.WriteString("}")
+	var s strings.Builder
+	writeFilter(&s, v.Expression)
 	return s.String()
 }
 
@@ -379,9 +435,11 @@ func (v *Arg) String() string {
 	return s.String()
 }
 
-// Comparable may either be a member or function.  As functions are not currently supported, it is always a member.
+// Comparable may either be a member or function.
 type Comparable struct {
 	Member *Member
+	// Function is a call of a function, see FunctionRegistry.
+	Function *Function
 }
 
 func (v *Comparable) String() string {
@@ -390,6 +448,30 @@ func (v *Comparable) String() string {
 	if v.Member != nil {
 		s.WriteString(v.Member.String())
 	}
+	if v.Function != nil {
+		s.WriteString(v.Function.String())
+	}
+	s.WriteString("}")
+	return s.String()
+}
+
+// Function calls are identified by their name, which may be qualified with
+// dots, and take zero or more arguments.
+//
+// Example: `regex(title, "^The")`
+type Function struct {
+	Name string
+	Args []*Arg
+}
+
+func (v *Function) String() string {
+	var s strings.Builder
+	s.WriteString("function{")
+	s.WriteString(strconv.Quote(v.Name))
+	for _, a := range v.Args {
+		s.WriteString(",")
+		s.WriteString(a.String())
+	}
 	s.WriteString("}")
 	return s.String()
 }
@@ -400,6 +482,14 @@ func (v *Comparable) String() string {
 type Member struct {
 	Value  string
 	Fields []string
+
+	// Kind is the kind of literal the member is written as. Only members
+	// of kind LiteralText have Fields. The Value of a float such as `3.5`
+	// holds all of its digits.
+	Kind LiteralKind
+	// Literal is the typed value of an integer, float or bool member, and
+	// nil for other kinds.
+	Literal any
 }
 
 func (v *Member) String() string {
@@ -420,18 +510,32 @@ func (v *Member) String() string {
 }
 
 // Parse an AIP-160 filter string into an AST.
+//
+// Errors are of type *ParseError, locating the problem in filter.
 func ParseFilter(filter string) (*Filter, error) {
-	return newParser(filter).filter()
+	return newParser(filter).parse()
 }
 
 type parser struct {
 	lexer filterLexer
+
+	// arena, when non-nil, supplies recycled AST nodes. See Parser.
+	arena *nodeArena
 }
 
 func newParser(input string) *parser {
 	return &parser{lexer: *NewLexer(input)}
 }
 
+// parse parses the whole input as a filter, returning errors as *ParseError.
+func (p *parser) parse() (*Filter, error) {
+	f, err := p.filter()
+	if err != nil {
+		return f, p.lexer.locate(err)
+	}
+	return f, nil
+}
+
 func (p *parser) expect(kind string) error {
 	t, err := p.lexer.Peek()
 	if err != nil {
@@ -461,13 +565,15 @@ func (p *parser) filter() (*Filter, error) {
 		return nil, err
 	}
 	if t != nil {
-		return &Filter{}, nil
+		return p.arena.filter(), nil
 	}
 	e, err := p.expression()
 	if err != nil {
 		return nil, err
 	}
-	return &Filter{Expression: e}, p.expect(kindEnd)
+	f := p.arena.filter()
+	f.Expression = e
+	return f, p.expect(kindEnd)
 }
 
 func (p *parser) expression() (*Expression, error) {
@@ -478,7 +584,7 @@ func (p *parser) expression() (*Expression, error) {
 	if s == nil {
 		return nil, nil
 	}
-	e := &Expression{}
+	e := p.arena.expression()
 	e.Sequences = append(e.Sequences, s)
 	for {
 		and, err := p.accept(kindAnd)
@@ -501,7 +607,7 @@ func (p *parser) expression() (*Expression, error) {
 }
 
 func (p *parser) sequence() (*Sequence, error) {
-	s := &Sequence{}
+	s := p.arena.sequence()
 	for {
 		f, err := p.factor()
 		if err != nil {
@@ -513,6 +619,7 @@ func (p *parser) sequence() (*Sequence, error) {
 		s.Factors = append(s.Factors, f)
 	}
 	if len(s.Factors) == 0 {
+		p.arena.releaseSequence(s)
 		return nil, nil
 	}
 	return s, nil
@@ -526,7 +633,7 @@ func (p *parser) factor() (*Factor, error) {
 	if t == nil {
 		return nil, nil
 	}
-	f := &Factor{}
+	f := p.arena.factor()
 	f.Terms = append(f.Terms, t)
 	for {
 		or, err := p.accept(kindOr)
@@ -563,7 +670,10 @@ func (p *parser) term() (*Term, error) {
 		}
 		return nil, nil
 	}
-	return &Term{Negated: n != nil, Simple: s}, nil
+	t := p.arena.term()
+	t.Negated = n != nil
+	t.Simple = s
+	return t, nil
 }
 
 func (p *parser) simple() (*Simple, error) {
@@ -572,14 +682,18 @@ func (p *parser) simple() (*Simple, error) {
 		return nil, err
 	}
 	if r != nil {
-		return &Simple{Restriction: r}, nil
+		s := p.arena.simple()
+		s.Restriction = r
+		return s, nil
 	}
 	c, err := p.composite()
 	if err != nil {
 		return nil, err
 	}
 	if c != nil {
-		return &Simple{Composite: c}, nil
+		s := p.arena.simple()
+		s.Composite = c
+		return s, nil
 	}
 	return nil, nil
 }
@@ -596,8 +710,10 @@ func (p *parser) restriction() (*Restriction, error) {
 	if err != nil {
 		return nil, err
 	}
+	r := p.arena.restriction()
+	r.Comparable = comparable
 	if comparator == nil {
-		return &Restriction{Comparable: comparable}, nil
+		return r, nil
 	}
 	arg, err := p.arg()
 	if err != nil {
@@ -606,10 +722,17 @@ func (p *parser) restriction() (*Restriction, error) {
 	if arg == nil {
 		return nil, fmt.Errorf("expected arg after %s", comparator.value)
 	}
-	return &Restriction{Comparable: comparable, Comparator: comparator.value, Arg: arg}, nil
+	r.Comparator = comparator.value
+	r.Arg = arg
+	return r, nil
 }
 
 func (p *parser) comparable() (*Comparable, error) {
+	t, err := p.lexer.Peek()
+	if err != nil {
+		return nil, err
+	}
+	quoted := t.kind == kindString
 	m, err := p.member()
 	if err != nil {
 		return nil, err
@@ -617,7 +740,53 @@ func (p *parser) comparable() (*Comparable, error) {
 	if m == nil {
 		return nil, nil
 	}
-	return &Comparable{Member: m}, nil
+	c := p.arena.comparable()
+	if t, err = p.lexer.Peek(); err != nil {
+		return nil, err
+	}
+	if quoted || t.kind != kindLParen || t.spaced {
+		c.Member = m
+		return c, nil
+	}
+	c.Function, err = p.function(m)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// function parses the arguments of a call of the function named by m,
+// starting at the LPAREN following the name.
+func (p *parser) function(m *Member) (*Function, error) {
+	fn := &Function{Name: strings.Join(append([]string{m.Value}, m.Fields...), ".")}
+	if err := p.expect(kindLParen); err != nil {
+		return nil, err
+	}
+	rparen, err := p.accept(kindRParen)
+	if err != nil {
+		return nil, err
+	}
+	if rparen != nil {
+		return fn, nil
+	}
+	for {
+		a, err := p.arg()
+		if err != nil {
+			return nil, err
+		}
+		if a == nil {
+			return nil, fmt.Errorf("expected argument of function %s", fn.Name)
+		}
+		fn.Args = append(fn.Args, a)
+		comma, err := p.accept(kindComma)
+		if err != nil {
+			return nil, err
+		}
+		if comma == nil {
+			break
+		}
+	}
+	return fn, p.expect(kindRParen)
 }
 
 func (p *parser) member() (*Member, error) {
@@ -626,11 +795,14 @@ func (p *parser) member() (*Member, error) {
 		return nil, err
 	}
 	if v != nil {
-		v.value, err = strconv.Unquote(v.value)
+		value, err := unquote(v.value)
 		if err != nil {
 			return nil, fmt.Errorf("error unquoting string: %w", err)
 		}
-		return &Member{Value: v.value}, nil
+		m := p.arena.member()
+		m.Value = value
+		m.Kind = LiteralString
+		return m, nil
 	}
 
 	v, err = p.accept(kindText)
@@ -640,7 +812,17 @@ func (p *parser) member() (*Member, error) {
 	if v == nil {
 		return nil, nil
 	}
-	m := &Member{Value: v.value}
+	m := p.arena.member()
+	m.Value = v.value
+	if intLiteral.MatchString(m.Value) {
+		if err := p.fraction(m); err != nil {
+			return nil, err
+		}
+	}
+	m.Kind, m.Literal = classifyLiteral(m.Value)
+	if m.Kind != LiteralText {
+		return m, nil
+	}
 	for {
 		dot, err := p.accept(kindDot)
 		if err != nil {
@@ -661,6 +843,61 @@ func (p *parser) member() (*Member, error) {
 	return m, nil
 }
 
+// negativeNumber parses a negative number, e.g. `-1` or `-1.5e3`, which the
+// lexer reads as a NEGATE followed by a number. It returns nil if the next
+// token is not a "-". In arguments, a "-" cannot negate, so it must be
+// immediately followed by a number.
+func (p *parser) negativeNumber() (*Member, error) {
+	t, err := p.lexer.Peek()
+	if err != nil {
+		return nil, err
+	}
+	if t.kind != kindNegate || t.value != "-" {
+		return nil, nil
+	}
+	p.lexer.Next()
+	t, err = p.lexer.Peek()
+	if err != nil {
+		return nil, err
+	}
+	if t.kind != kindText || t.spaced || !floatLiteral.MatchString(t.value) {
+		return nil, fmt.Errorf("expected a number after '-'")
+	}
+	p.lexer.Next()
+	m := p.arena.member()
+	m.Value = "-" + t.value
+	if intLiteral.MatchString(t.value) {
+		if err := p.fraction(m); err != nil {
+			return nil, err
+		}
+	}
+	m.Kind, m.Literal = classifyLiteral(m.Value)
+	return m, nil
+}
+
+// fraction appends the fractional part of a float literal, which the lexer
+// reads as a DOT and a field name, to the integer part in m.
+func (p *parser) fraction(m *Member) error {
+	dot, err := p.lexer.Peek()
+	if err != nil {
+		return err
+	}
+	if dot.kind != kindDot || dot.spaced {
+		return nil
+	}
+	p.lexer.Next()
+	f, err := p.lexer.Peek()
+	if err != nil {
+		return err
+	}
+	if f.kind != kindText || f.spaced || !fractionLiteral.MatchString(f.value) {
+		return fmt.Errorf("expected digits after '.' in number %s", m.Value)
+	}
+	p.lexer.Next()
+	m.Value += "." + f.value
+	return nil
+}
+
 func (p *parser) composite() (*Expression, error) {
 	lparen, err := p.accept(kindLParen)
 	if err != nil {
@@ -680,19 +917,33 @@ func (p *parser) composite() (*Expression, error) {
 }
 
 func (p *parser) arg() (*Arg, error) {
+	negative, err := p.negativeNumber()
+	if err != nil {
+		return nil, err
+	}
+	if negative != nil {
+		a := p.arena.arg()
+		a.Comparable = p.arena.comparable()
+		a.Comparable.Member = negative
+		return a, nil
+	}
 	comparable, err := p.comparable()
 	if err != nil {
 		return nil, err
 	}
 	if comparable != nil {
-		return &Arg{Comparable: comparable}, nil
+		a := p.arena.arg()
+		a.Comparable = comparable
+		return a, nil
 	}
 	composite, err := p.composite()
 	if err != nil {
 		return nil, err
 	}
 	if composite != nil {
-		return &Arg{Composite: composite}, nil
+		a := p.arena.arg()
+		a.Composite = composite
+		return a, nil
 	}
 	return nil, nil
 }
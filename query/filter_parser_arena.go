@@ -0,0 +1,243 @@
+package query
+
+// Parser parses AIP-160 filters while amortizing allocations across calls.
+//
+// Lexer tokens are stored in a slab that is reused by every call to Parse,
+// and AST nodes handed back via Release are kept on internal free-lists and
+// recycled by later calls. This is intended for gateways that parse many
+// distinct filters per second; for occasional parsing, use ParseFilter.
+//
+// A Parser is not safe for concurrent use. Use one Parser per goroutine, or
+// a sync.Pool of Parsers.
+type Parser struct {
+	slab  []token
+	arena nodeArena
+}
+
+// NewParser returns a new, empty Parser.
+func NewParser() *Parser {
+	return &Parser{}
+}
+
+// Parse parses an AIP-160 filter string into an AST, like ParseFilter.
+//
+// The returned Filter remains valid until it is passed to Release.
+func (p *Parser) Parse(filter string) (*Filter, error) {
+	p.slab = p.slab[:0]
+	ps := &parser{
+		lexer: filterLexer{src: filter, input: filter, slab: &p.slab},
+		arena: &p.arena,
+	}
+	f, err := ps.parse()
+	if err != nil {
+		p.Release(f)
+		return nil, err
+	}
+	return f, nil
+}
+
+// Release returns the nodes of f to the Parser for reuse by later calls to
+// Parse. The caller must not use f, or any node reachable from it, after
+// calling Release.
+//
+// Release accepts any Filter, including ones returned by ParseFilter, and is
+// a no-op for nil.
+func (p *Parser) Release(f *Filter) {
+	if f == nil {
+		return
+	}
+	p.arena.releaseExpression(f.Expression)
+	*f = Filter{}
+	p.arena.filters = append(p.arena.filters, f)
+}
+
+// nodeArena holds free-lists of AST nodes. A nil *nodeArena allocates fresh
+// nodes, which is the behavior of ParseFilter.
+type nodeArena struct {
+	filters      []*Filter
+	expressions  []*Expression
+	sequences    []*Sequence
+	factors      []*Factor
+	terms        []*Term
+	simples      []*Simple
+	restrictions []*Restriction
+	args         []*Arg
+	comparables  []*Comparable
+	members      []*Member
+}
+
+// take pops a node from the free-list, or allocates one if it is empty.
+func take[T any](free *[]*T) *T {
+	n := len(*free)
+	if n == 0 {
+		return new(T)
+	}
+	t := (*free)[n-1]
+	(*free)[n-1] = nil
+	*free = (*free)[:n-1]
+	return t
+}
+
+func (a *nodeArena) filter() *Filter {
+	if a == nil {
+		return &Filter{}
+	}
+	return take(&a.filters)
+}
+
+func (a *nodeArena) expression() *Expression {
+	if a == nil {
+		return &Expression{}
+	}
+	return take(&a.expressions)
+}
+
+func (a *nodeArena) sequence() *Sequence {
+	if a == nil {
+		return &Sequence{}
+	}
+	return take(&a.sequences)
+}
+
+func (a *nodeArena) factor() *Factor {
+	if a == nil {
+		return &Factor{}
+	}
+	return take(&a.factors)
+}
+
+func (a *nodeArena) term() *Term {
+	if a == nil {
+		return &Term{}
+	}
+	return take(&a.terms)
+}
+
+func (a *nodeArena) simple() *Simple {
+	if a == nil {
+		return &Simple{}
+	}
+	return take(&a.simples)
+}
+
+func (a *nodeArena) restriction() *Restriction {
+	if a == nil {
+		return &Restriction{}
+	}
+	return take(&a.restrictions)
+}
+
+func (a *nodeArena) arg() *Arg {
+	if a == nil {
+		return &Arg{}
+	}
+	return take(&a.args)
+}
+
+func (a *nodeArena) comparable() *Comparable {
+	if a == nil {
+		return &Comparable{}
+	}
+	return take(&a.comparables)
+}
+
+func (a *nodeArena) member() *Member {
+	if a == nil {
+		return &Member{}
+	}
+	return take(&a.members)
+}
+
+// The release methods reset each node, keeping the capacity of its child
+// slices, and push it onto the matching free-list. They are no-ops on a nil
+// arena.
+
+func (a *nodeArena) releaseExpression(e *Expression) {
+	if a == nil || e == nil {
+		return
+	}
+	for _, s := range e.Sequences {
+		a.releaseSequence(s)
+	}
+	clear(e.Sequences)
+	e.Sequences = e.Sequences[:0]
+	a.expressions = append(a.expressions, e)
+}
+
+func (a *nodeArena) releaseSequence(s *Sequence) {
+	if a == nil || s == nil {
+		return
+	}
+	for _, f := range s.Factors {
+		a.releaseFactor(f)
+	}
+	clear(s.Factors)
+	s.Factors = s.Factors[:0]
+	a.sequences = append(a.sequences, s)
+}
+
+func (a *nodeArena) releaseFactor(f *Factor) {
+	if f == nil {
+		return
+	}
+	for _, t := range f.Terms {
+		a.releaseTerm(t)
+	}
+	clear(f.Terms)
+	f.Terms = f.Terms[:0]
+	a.factors = append(a.factors, f)
+}
+
+func (a *nodeArena) releaseTerm(t *Term) {
+	if t == nil {
+		return
+	}
+	a.releaseSimple(t.Simple)
+	*t = Term{}
+	a.terms = append(a.terms, t)
+}
+
+func (a *nodeArena) releaseSimple(s *Simple) {
+	if s == nil {
+		return
+	}
+	a.releaseRestriction(s.Restriction)
+	a.releaseExpression(s.Composite)
+	*s = Simple{}
+	a.simples = append(a.simples, s)
+}
+
+func (a *nodeArena) releaseRestriction(r *Restriction) {
+	if r == nil {
+		return
+	}
+	a.releaseComparable(r.Comparable)
+	if r.Arg != nil {
+		a.releaseComparable(r.Arg.Comparable)
+		a.releaseExpression(r.Arg.Composite)
+		*r.Arg = Arg{}
+		a.args = append(a.args, r.Arg)
+	}
+	*r = Restriction{}
+	a.restrictions = append(a.restrictions, r)
+}
+
+func (a *nodeArena) releaseComparable(c *Comparable) {
+	if c == nil {
+		return
+	}
+	if m := c.Member; m != nil {
+		clear(m.Fields)
+		*m = Member{Fields: m.Fields[:0]}
+		a.members = append(a.members, m)
+	}
+	if fn := c.Function; fn != nil {
+		// Function nodes are rare, so only their arguments are recycled.
+		for _, arg := range fn.Args {
+			a.releaseComparable(arg.Comparable)
+			a.releaseExpression(arg.Composite)
+		}
+	}
+	*c = Comparable{}
+	a.comparables = append(a.comparables, c)
+}
@@ -0,0 +1,73 @@
+package query
+
+import (
+	"testing"
+)
+
+func TestParserReuse(t *testing.T) {
+	filters := []string{
+		``,
+		`title = "Dune"`,
+		`a b AND c AND d`,
+		`New York Giants OR Yankees`,
+		`NOT (a OR b) AND -file:".java"`,
+		`expr.type_map.1.type = x AND (b.c != "d" OR e <= 3)`,
+		`a = (b OR c)`,
+	}
+
+	p := NewParser()
+	for round := 0; round < 3; round++ {
+		for _, input := range filters {
+			want, err := ParseFilter(input)
+			if err != nil {
+				t.Fatalf("ParseFilter(%q) failed: %v", input, err)
+			}
+			got, err := p.Parse(input)
+			if err != nil {
+				t.Fatalf("Parser.Parse(%q) failed: %v", input, err)
+			}
+			if astString(got) != astString(want) {
+				t.Errorf("round %d: Parser.Parse(%q) = %s, want %s", round, input, got, want)
+			}
+			p.Release(got)
+		}
+	}
+}
+
+func TestParserReuseAfterError(t *testing.T) {
+	p := NewParser()
+	if _, err := p.Parse(`a = (b`); err == nil {
+		t.Fatalf("expected error for unbalanced parentheses")
+	}
+	f, err := p.Parse(`a = b`)
+	if err != nil {
+		t.Fatalf("Parse after error failed: %v", err)
+	}
+	want, _ := ParseFilter(`a = b`)
+	if astString(f) != astString(want) {
+		t.Errorf("got %s, want %s", f, want)
+	}
+}
+
+const benchFilter = `title = "The Pragmatic Programmer" AND (author.family_name = "Hunt" OR author.family_name = "Thomas") AND NOT reviews:"bad"`
+
+func BenchmarkParseFilter(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := ParseFilter(benchFilter); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParserReuse(b *testing.B) {
+	p := NewParser()
+	b.ReportAllocs()
+	for b.Loop() {
+		f, err := p.Parse(benchFilter)
+		if err != nil {
+			b.Fatal(err)
+		}
+		p.Release(f)
+	}
+}
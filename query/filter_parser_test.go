@@ -14,7 +14,10 @@
 
 package query
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestTokenKinds(t *testing.T) {
 	tests := []struct {
@@ -154,25 +157,145 @@ func TestFullParse(t *testing.T) {
 		{input: "member.field", ast: "filter{expression{sequence{factor{term{simple{restriction{comparable{member{\"member\", {\"field\"}}}}}}}}}}"},
 		{input: " member.field > 4 ", ast: "filter{expression{sequence{factor{term{simple{restriction{comparable{member{\"member\", {\"field\"}}},\">\",arg{comparable{member{\"4\"}}}}}}}}}}}"},
 		{input: "composite (expression)", ast: "filter{expression{sequence{factor{term{simple{restriction{comparable{member{\"composite\"}}}}}}},factor{term{simple{expression{sequence{factor{term{simple{restriction{comparable{member{\"expression\"}}}}}}}}}}}}}}}"},
-		// This should parse as a function, but function parsing is not implemented.
-		// {input: "function(expression)", ast: ""},
+		{input: "function(expression)", ast: "filter{expression{sequence{factor{term{simple{restriction{comparable{function{\"function\",arg{comparable{member{\"expression\"}}}}}}}}}}}}}"},
+		{input: "f()", ast: "filter{expression{sequence{factor{term{simple{restriction{comparable{function{\"f\"}}}}}}}}}"},
+		{input: `math.mod(a, "b c", g(d)) = 1`, ast: "filter{expression{sequence{factor{term{simple{restriction{comparable{function{\"math.mod\",arg{comparable{member{\"a\"}}}},arg{comparable{member{\"b c\"}}}},arg{comparable{function{\"g\",arg{comparable{member{\"d\"}}}}}}}}},\"=\",arg{comparable{member{\"1\"}}}}}}}}}}}"},
+		{input: "a = f(b)", ast: "filter{expression{sequence{factor{term{simple{restriction{comparable{member{\"a\"}}},\"=\",arg{comparable{function{\"f\",arg{comparable{member{\"b\"}}}}}}}}}}}}}}"},
+		{input: "NOT f(x)", ast: "filter{expression{sequence{factor{term{-simple{restriction{comparable{function{\"f\",arg{comparable{member{\"x\"}}}}}}}}}}}}}"},
+		{input: `"quoted"(x)`, ast: "filter{expression{sequence{factor{term{simple{restriction{comparable{member{\"quoted\"}}}}}}},factor{term{simple{expression{sequence{factor{term{simple{restriction{comparable{member{\"x\"}}}}}}}}}}}}}}}"},
+		{input: "f(-1, 2.5)", ast: "filter{expression{sequence{factor{term{simple{restriction{comparable{function{\"f\",arg{comparable{member{\"-1\"}}}},arg{comparable{member{\"2.5\"}}}}}}}}}}}}}"},
+		{input: "-1", ast: "filter{expression{sequence{factor{term{-simple{restriction{comparable{member{\"1\"}}}}}}}}}}"},
+		{input: "f(a,)", expectErr: true},
+		{input: "f(a", expectErr: true},
+		{input: "f(a b)", expectErr: true},
 	}
 	for _, test := range tests {
 		t.Run(test.input, func(t *testing.T) {
 			filter, err := ParseFilter(test.input)
 			if test.expectErr {
 				if err == nil {
-					t.Fatalf("expected error but no error produced from input: %q\nparsed as:%q", test.input, filter.String())
+					t.Fatalf("expected error but no error produced from input: %q\nparsed as:%q", test.input, astString(filter))
 				}
 				return
 			}
 			if err != nil {
 				t.Fatal(err)
 			}
-			ast := filter.String()
+			ast := astString(filter)
 			if ast != test.ast {
 				t.Errorf("incorrect AST parsed from input %q:\ngot %q\nwant %q", test.input, ast, test.ast)
 			}
 		})
 	}
 }
+
+// astString describes the AST of f, which Filter.String does not.
+func astString(f *Filter) string {
+	if f.Expression == nil {
+		return "filter{}"
+	}
+	return "filter{" + f.Expression.String() + "}"
+}
+
+func TestTypedLiterals(t *testing.T) {
+	tests := []struct {
+		input   string
+		value   string
+		kind    LiteralKind
+		literal any
+	}{
+		{input: "21", value: "21", kind: LiteralInt, literal: int64(21)},
+		{input: "007", value: "007", kind: LiteralInt, literal: int64(7)},
+		{input: "3.5", value: "3.5", kind: LiteralFloat, literal: 3.5},
+		{input: "1e3", value: "1e3", kind: LiteralFloat, literal: 1e3},
+		{input: "1.5e-3", value: "1.5e-3", kind: LiteralFloat, literal: 1.5e-3},
+		{input: "99999999999999999999", value: "99999999999999999999", kind: LiteralFloat, literal: 1e20},
+		{input: "-1", value: "-1", kind: LiteralInt, literal: int64(-1)},
+		{input: "-0.5", value: "-0.5", kind: LiteralFloat, literal: -0.5},
+		{input: "1.5e3", value: "1.5e3", kind: LiteralFloat, literal: 1.5e3},
+		{input: "-1.5E+3", value: "-1.5E+3", kind: LiteralFloat, literal: -1.5e3},
+		{input: "-2e-2", value: "-2e-2", kind: LiteralFloat, literal: -2e-2},
+		{input: "true", value: "true", kind: LiteralBool, literal: true},
+		{input: "false", value: "false", kind: LiteralBool, literal: false},
+		{input: "null", value: "null", kind: LiteralNull},
+		{input: `"21"`, value: "21", kind: LiteralString},
+		{input: `"true"`, value: "true", kind: LiteralString},
+		{input: "True", value: "True", kind: LiteralText},
+		{input: "1e", value: "1e", kind: LiteralText},
+		{input: "inf", value: "inf", kind: LiteralText},
+		{input: "0x10", value: "0x10", kind: LiteralText},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			filter, err := ParseFilter("value = " + test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := filter.Expression.Sequences[0].Factors[0].Terms[0].Simple.Restriction.Arg.Comparable.Member
+			if m.Value != test.value || len(m.Fields) > 0 {
+				t.Errorf("member = %v, want value %q", m, test.value)
+			}
+			if m.Kind != test.kind || m.Literal != test.literal {
+				t.Errorf("literal = %s %#v, want %s %#v", m.Kind, m.Literal, test.kind, test.literal)
+			}
+		})
+	}
+
+	for _, input := range []string{"value = 3.x", "value = 3.5.1", "value = 3. 5", "value = - 1", "value = -x", `value = -"1"`, "value = -1."} {
+		if filter, err := ParseFilter(input); err == nil {
+			t.Errorf("ParseFilter(%q) = %v, want an error", input, filter)
+		}
+	}
+}
+
+func TestStringEscapes(t *testing.T) {
+	tests := []struct {
+		input string
+		value string
+	}{
+		{`"say \"hi\""`, `say "hi"`},
+		{`"back\\slash"`, `back\slash`},
+		{`"trailing\\"`, `trailing\`},
+		{`"line\nbreak\ttab\r"`, "line\nbreak\ttab\r"},
+		{`"\a\b\f\v"`, "\a\b\f\v"},
+		{`"it\'s"`, "it's"},
+		{`"a\/b"`, "a/b"},
+		{`"caf\u00e9"`, "caf\u00e9"},
+		{`"cafe\u0301"`, "cafe\u0301"},
+		{`"\x41"`, "A"},
+		{`"\U0001F600"`, "\U0001F600"},
+		{`"\ud83d\ude00"`, "\U0001F600"},
+		{`"raw
+newline"`, "raw\nnewline"},
+		{`""`, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			filter, err := ParseFilter("value = " + test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := filter.Expression.Sequences[0].Factors[0].Terms[0].Simple.Restriction.Arg.Comparable.Member
+			if m.Value != test.value {
+				t.Errorf("value = %q, want %q", m.Value, test.value)
+			}
+		})
+	}
+
+	invalid := map[string]string{
+		`"\q"`:           `invalid escape sequence \q at offset 0`,
+		`"ab\u00"`:       "needs 4 hexadecimal digits",
+		`"\u00g9"`:       `invalid escape sequence \u00g9`,
+		`"\ud83d"`:       `unpaired surrogate \ud83d`,
+		`"\ud83dx"`:      `unpaired surrogate \ud83d`,
+		`"\ud83d\u0041"`: `invalid surrogate pair \ud83d\u0041`,
+		`"\U00110000"`:   `invalid code point \U00110000`,
+		`"\xZZ"`:         `invalid escape sequence \xZZ`,
+	}
+	for input, want := range invalid {
+		_, err := ParseFilter("value = " + input)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseFilter(%q) = %v, want an error containing %q", input, err, want)
+		}
+	}
+}
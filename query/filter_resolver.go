@@ -0,0 +1,107 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Resolver computes the value of a virtual field, such as a `display_name`
+// joined from the given and family names of an author, which is not a field
+// of the message but filters may restrict as if it were one.
+//
+// The value is a string, bool, int64, float64 or time.Time, or a []any of
+// them, which matches a restriction if any element does, as for repeated
+// fields. nil means the field is unset. ctx is the context passed to the
+// predicate returned by ProtoFilterCtx, or context.Background() for
+// ProtoFilter.
+type Resolver func(ctx context.Context, msg proto.Message) (any, error)
+
+// WithResolver registers fn to compute the virtual field with the given
+// name, e.g.
+//
+//	query.WithResolver("display_name", func(_ context.Context, msg proto.Message) (any, error) {
+//	    a := msg.(*pb.Author)
+//	    return a.GetGivenName() + " " + a.GetFamilyName(), nil
+//	})
+//
+// Unlike a Matcher, which decides restrictions itself, the resolved value
+// is compared as field values are, so every comparator applies, e.g.
+// `display_name:Frank` or `display_name:*`, and it may be passed to
+// functions, e.g. `lower(display_name) = "frank herbert"`. The field is
+// present unless its value is nil or the zero value of its type. A resolver
+// takes precedence over a message field with the same name, and a matcher
+// over a resolver. Resolvers are not called while a filter is compiled.
+func WithResolver(name string, fn Resolver) FilterOption {
+	return func(o *filterOptions) {
+		if o.resolvers == nil {
+			o.resolvers = make(map[string]Resolver)
+		}
+		o.resolvers[name] = fn
+	}
+}
+
+// resolver returns the resolver of the virtual field mem names, if any.
+func (ev *evaluator) resolver(mem *Member) (Resolver, bool) {
+	if mem == nil || mem.Kind != LiteralText || len(mem.Fields) > 0 {
+		return nil, false
+	}
+	fn, ok := ev.opts.resolvers[mem.Value]
+	return fn, ok
+}
+
+// resolveMember returns the value of mem in m: the value of the virtual
+// field it names, or else its value as resolveMemberValue returns it. The
+// value of a virtual field is nil while the filter is being validated.
+func (ev *evaluator) resolveMember(m protoreflect.Message, mem *Member) (any, error) {
+	fn, ok := ev.resolver(mem)
+	if !ok {
+		return resolveMemberValue(m, mem)
+	}
+	if ev.validating() {
+		return nil, nil
+	}
+	v, err := fn(ev.ctx, m.Interface())
+	if err != nil {
+		return nil, fmt.Errorf("resolving %s: %w", mem.Value, err)
+	}
+	return v, nil
+}
+
+// evalResolved evaluates r, whose comparable names a virtual field.
+func (ev *evaluator) evalResolved(m protoreflect.Message, r *Restriction) (bool, error) {
+	lhs, err := ev.resolveMember(m, r.Comparable.Member)
+	if err != nil {
+		return false, err
+	}
+	if isPresenceTest(r) {
+		return resolvedPresent(lhs), nil
+	}
+	if r.Arg == nil {
+		return false, fmt.Errorf("missing arg in restriction")
+	}
+	rhs, err := ev.resolveComparable(m, r.Arg.Comparable)
+	if err != nil {
+		return false, err
+	}
+	if lhs == nil {
+		// An unset field compares false, even with !=.
+		return false, nil
+	}
+	return compareAny(ev.opts.normalizeValue(lhs), ev.opts.normalizeValue(rhs), r.Comparator)
+}
+
+// resolvedPresent reports whether the resolved value v is present, i.e. it
+// is neither nil, the zero value of its type nor an empty slice.
+func resolvedPresent(v any) bool {
+	if v == nil {
+		return false
+	}
+	if isSlice(v) {
+		return len(toSlice(v)) > 0
+	}
+	return !reflect.ValueOf(v).IsZero()
+}
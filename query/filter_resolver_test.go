@@ -0,0 +1,79 @@
+package query_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+	aip "github.com/hxtk/aip/query"
+)
+
+// authorName resolves the display name of the author of a book.
+func authorName(_ context.Context, msg proto.Message) (any, error) {
+	a := msg.(*testpb.Book).GetAuthor()
+	if a == nil {
+		return nil, nil
+	}
+	return strings.TrimSpace(a.GetGivenName() + " " + a.GetFamilyName()), nil
+}
+
+func TestWithResolver(t *testing.T) {
+	dune := &testpb.Book{Title: "Dune", Author: &testpb.Author{GivenName: "Frank", FamilyName: "Herbert"}}
+	emma := &testpb.Book{Title: "Emma", Author: &testpb.Author{FamilyName: "Austen"}, Tags: []string{"classic"}}
+	anonymous := &testpb.Book{Title: "Beowulf"}
+	opts := []aip.FilterOption{
+		aip.WithResolver("author_name", authorName),
+		aip.WithResolver("labels", func(_ context.Context, msg proto.Message) (any, error) {
+			var labels []any
+			for _, tag := range msg.(*testpb.Book).GetTags() {
+				labels = append(labels, "tag:"+tag)
+			}
+			return labels, nil
+		}),
+		aip.WithFunctions(testFunctions(t)),
+		aip.WithStrictFields(),
+	}
+
+	tests := []struct {
+		filter string
+		want   []bool // dune, emma, anonymous
+	}{
+		{`author_name = "Frank Herbert"`, []bool{true, false, false}},
+		{`author_name != "Frank Herbert"`, []bool{false, true, false}},
+		{`author_name:Herb`, []bool{true, false, false}},
+		{`author_name > "B"`, []bool{true, false, false}},
+		{`author_name:*`, []bool{true, true, false}},
+		{`lower(author_name) = "austen"`, []bool{false, true, false}},
+		{`regex(author_name, "^Frank")`, []bool{true, false, false}},
+		{`labels:"tag:classic"`, []bool{false, true, false}},
+		{`labels:*`, []bool{false, true, false}},
+		{`NOT author_name:* OR title = Dune`, []bool{true, false, true}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.filter, func(t *testing.T) {
+			match, err := aip.ProtoFilter[testpb.Book](mustParse(t, tc.filter), opts...)
+			require.NoError(t, err)
+			for i, book := range []*testpb.Book{dune, emma, anonymous} {
+				require.Equal(t, tc.want[i], match(book), book.GetTitle())
+			}
+		})
+	}
+
+	// Without the resolver, the name is unknown.
+	_, err := aip.ProtoFilter[testpb.Book](mustParse(t, `author_name = "Frank Herbert"`), aip.WithStrictFields())
+	require.ErrorContains(t, err, `unknown field "author_name"`)
+
+	// Errors of the resolver are returned by the predicate.
+	errResolve := errors.New("resolve")
+	match, err := aip.ProtoFilterCtx[testpb.Book](mustParse(t, `author_name = x`), aip.WithResolver("author_name", func(context.Context, proto.Message) (any, error) {
+		return nil, errResolve
+	}))
+	require.NoError(t, err, "resolvers are not called while compiling")
+	_, err = match(t.Context(), dune)
+	require.ErrorIs(t, err, errResolve)
+}
@@ -0,0 +1,238 @@
+package query
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// FilterSchema declares the fields a filter may restrict and the comparators
+// it may restrict each with, e.g. so that a public API only accepts filters
+// it has indexes for:
+//
+//	schema := query.NewFilterSchema().
+//	    Allow("title", "=", ":").
+//	    Allow("create_time", ">", "<")
+//	...
+//	if err := schema.Validate(filter); err != nil {
+//	    return nil, connect.NewError(connect.CodeInvalidArgument, err)
+//	}
+//
+// Filters are checked as parsed, independently of any message or table, so
+// a schema may be shared by the evaluator and SQL generation alike.
+type FilterSchema struct {
+	// fields are the allowed field paths, in the order they were declared.
+	fields []FieldPath
+
+	// comparators are the comparators allowed for each canonical field path.
+	comparators map[string][]string
+
+	// search reports whether global restrictions, i.e. search terms, are
+	// allowed.
+	search bool
+
+	// functions are the names of the functions filters may call.
+	functions []string
+}
+
+// NewFilterSchema returns a schema allowing no fields, which only accepts
+// the empty filter.
+func NewFilterSchema() *FilterSchema {
+	return &FilterSchema{comparators: make(map[string][]string)}
+}
+
+// Allow declares that filters may restrict field, a path of field names
+// separated by dots, with the given comparators, or with any comparator if
+// none are given. Restrictions of the fields beneath field, such as keys of
+// a map or fields of a message, are allowed with the same comparators. ":*"
+// stands for presence tests, e.g. `author:*`, which ":" does not allow.
+// Allowing a field again adds to its comparators.
+//
+// Allow panics if field is not a valid field path or a comparator is not an
+// AIP-160 comparator.
+func (s *FilterSchema) Allow(field string, comparators ...string) *FilterSchema {
+	path, err := ParseFieldPath(field)
+	if err != nil {
+		panic(fmt.Sprintf("invalid filterable field %q: %v", field, err))
+	}
+	for _, op := range comparators {
+		if !slices.Contains(restrictionComparators, op) {
+			panic(fmt.Sprintf("invalid comparator %q of filterable field %s", op, field))
+		}
+	}
+	if len(comparators) == 0 {
+		comparators = restrictionComparators
+	}
+	key := path.String()
+	if _, ok := s.comparators[key]; !ok {
+		s.fields = append(s.fields, path)
+	}
+	for _, op := range comparators {
+		if !slices.Contains(s.comparators[key], op) {
+			s.comparators[key] = append(s.comparators[key], op)
+		}
+	}
+	return s
+}
+
+// AllowSearch declares that filters may contain global restrictions, e.g.
+// `Dune` or `"New York"`, which search the fields the server chooses.
+func (s *FilterSchema) AllowSearch() *FilterSchema {
+	s.search = true
+	return s
+}
+
+// AllowFunction declares that filters may call the function with the given
+// name, e.g. "regex". Functions called anywhere in a filter, including in
+// the arguments of restrictions and of other functions, must be allowed,
+// but the fields passed to them are not checked, so only allow functions
+// which may be applied to any field.
+func (s *FilterSchema) AllowFunction(name string) *FilterSchema {
+	if !slices.Contains(s.functions, name) {
+		s.functions = append(s.functions, name)
+	}
+	return s
+}
+
+// Fields returns the allowed field paths, in the order they were declared.
+func (s *FilterSchema) Fields() []FieldPath {
+	return slices.Clone(s.fields)
+}
+
+// Validate checks that filter only restricts allowed fields with their
+// allowed comparators, and only uses search terms and functions if they are
+// allowed. The error names the first restriction that is not allowed and
+// lists the filterable fields; it is a *FieldViolationError of FilterField
+// and matches ErrUnsupportedField. A nil or empty filter is always valid.
+func (s *FilterSchema) Validate(filter *Filter) error {
+	if filter == nil || filter.Expression == nil {
+		return nil
+	}
+	if err := s.validateExpression(filter.Expression); err != nil {
+		return newFieldViolation(FilterField, unsupportedFieldError{err})
+	}
+	return nil
+}
+
+// validateExpression checks the restrictions of e against s.
+func (s *FilterSchema) validateExpression(e *Expression) error {
+	for _, seq := range e.Sequences {
+		for _, f := range seq.Factors {
+			for _, term := range f.Terms {
+				if term.Simple.Composite != nil {
+					if err := s.validateExpression(term.Simple.Composite); err != nil {
+						return err
+					}
+					continue
+				}
+				if err := s.validateRestriction(term.Simple.Restriction); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validateRestriction checks r against s.
+func (s *FilterSchema) validateRestriction(r *Restriction) error {
+	if fn := r.Comparable.Function; fn != nil {
+		if err := s.validateFunction(fn); err != nil {
+			return err
+		}
+		return s.validateArg(r.Arg)
+	}
+	if r.Comparator == "" {
+		if !s.search {
+			return fmt.Errorf("search terms are not allowed in filters, %s", s.allowedFields())
+		}
+		return nil
+	}
+
+	member := r.Comparable.Member
+	segments := append([]string{member.Value}, member.Fields...)
+	comparators, ok := s.fieldComparators(segments)
+	if !ok {
+		return fmt.Errorf("field %q is not filterable, %s", NewFieldPath(segments...).String(), s.allowedFields())
+	}
+	comparator := r.Comparator
+	if isPresenceTest(r) {
+		comparator = ":*"
+	}
+	if !slices.Contains(comparators, comparator) {
+		return fmt.Errorf("field %q cannot be compared with %s, supported comparators are %s", NewFieldPath(segments...).String(), comparator, strings.Join(comparators, ", "))
+	}
+	return s.validateArg(r.Arg)
+}
+
+// validateArg checks the functions arg calls, e.g. `lower(author.name)` in
+// `title = lower(author.name)`, against s. Members of arg are values, not
+// restrictions, even within a composite such as `(Dune OR Emma)`.
+func (s *FilterSchema) validateArg(arg *Arg) error {
+	if arg == nil {
+		return nil
+	}
+	if arg.Comparable != nil && arg.Comparable.Function != nil {
+		return s.validateFunction(arg.Comparable.Function)
+	}
+	if arg.Composite == nil {
+		return nil
+	}
+	var err error
+	walkRestrictions(arg.Composite, func(r *Restriction) {
+		if err != nil {
+			return
+		}
+		if r.Comparable != nil && r.Comparable.Function != nil {
+			err = s.validateFunction(r.Comparable.Function)
+		} else if r.Arg != nil && r.Arg.Comparable != nil && r.Arg.Comparable.Function != nil {
+			err = s.validateFunction(r.Arg.Comparable.Function)
+		}
+	})
+	return err
+}
+
+// validateFunction checks that fn, and every function called in its
+// arguments, is allowed by s.
+func (s *FilterSchema) validateFunction(fn *Function) error {
+	if !slices.Contains(s.functions, fn.Name) {
+		return fmt.Errorf("function %q is not allowed in filters, %s", fn.Name, s.allowedFunctions())
+	}
+	for _, a := range fn.Args {
+		if err := s.validateArg(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldComparators returns the comparators allowed for the field addressed
+// by segments, i.e. those of the longest allowed path it begins with.
+func (s *FilterSchema) fieldComparators(segments []string) ([]string, bool) {
+	for i := len(segments); i > 0; i-- {
+		if comparators, ok := s.comparators[NewFieldPath(segments[:i]...).String()]; ok {
+			return comparators, true
+		}
+	}
+	return nil, false
+}
+
+// allowedFields describes the filterable fields for error messages.
+func (s *FilterSchema) allowedFields() string {
+	if len(s.fields) == 0 {
+		return "no fields are filterable"
+	}
+	names := make([]string, len(s.fields))
+	for i, f := range s.fields {
+		names[i] = f.String()
+	}
+	return "filterable fields are " + strings.Join(names, ", ")
+}
+
+// allowedFunctions describes the allowed functions for error messages.
+func (s *FilterSchema) allowedFunctions() string {
+	if len(s.functions) == 0 {
+		return "no functions are allowed"
+	}
+	return "allowed functions are " + strings.Join(s.functions, ", ")
+}
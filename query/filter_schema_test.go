@@ -0,0 +1,73 @@
+package query_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hxtk/aip/query"
+)
+
+func TestFilterSchema(t *testing.T) {
+	schema := query.NewFilterSchema().
+		Allow("title", "=", ":").
+		Allow("create_time", ">", "<").
+		Allow("reviews", "=", ":*").
+		Allow("author.given_name").
+		AllowFunction("regex")
+
+	valid := []string{
+		``,
+		`title = Dune`,
+		`title:Du* AND create_time > "2024-01-01T00:00:00Z"`,
+		`(title = Dune OR title = Emma) AND NOT create_time < "2000-01-01T00:00:00Z"`,
+		`reviews.alice = classic`,
+		`reviews:*`,
+		`author.given_name != Frank`,
+		`regex(title, "^The")`,
+		`title = (Dune OR Emma)`,
+		`title = regex(title, "^The")`,
+	}
+	for _, filter := range valid {
+		require.NoError(t, schema.Validate(mustParse(t, filter)), filter)
+	}
+	require.NoError(t, schema.Validate(nil))
+
+	invalid := []struct {
+		filter string
+		want   string
+	}{
+		{`page_count > 100`, `field "page_count" is not filterable, filterable fields are title, create_time, reviews, author.given_name`},
+		{`title = Dune AND (Emma OR author.family_name = Austen)`, `search terms are not allowed in filters, filterable fields are title, create_time, reviews, author.given_name`},
+		{`author:*`, `field "author" is not filterable`},
+		{`title != Dune`, `field "title" cannot be compared with !=, supported comparators are =, :`},
+		{`title:*`, `field "title" cannot be compared with :*, supported comparators are =, :`},
+		{`reviews.alice:classic`, `field "reviews.alice" cannot be compared with :, supported comparators are =, :*`},
+		{`year(create_time) = 2024`, `function "year" is not allowed in filters, allowed functions are regex`},
+		{`title = lower(author.family_name)`, `function "lower" is not allowed in filters`},
+		{`regex(lower(author.family_name), "^a")`, `function "lower" is not allowed in filters`},
+		{`title = (Dune OR lower(author.family_name))`, `function "lower" is not allowed in filters`},
+		{`title = (Dune OR (Emma AND lower(author.family_name)))`, `function "lower" is not allowed in filters`},
+	}
+	for _, tc := range invalid {
+		t.Run(tc.filter, func(t *testing.T) {
+			err := schema.Validate(mustParse(t, tc.filter))
+			require.ErrorContains(t, err, tc.want)
+			require.ErrorIs(t, err, query.ErrUnsupportedField)
+			var fv *query.FieldViolationError
+			require.True(t, errors.As(err, &fv))
+			require.Equal(t, query.FilterField, fv.FieldViolations()[0].GetField())
+		})
+	}
+
+	// Allowing search admits global restrictions, and allowing a field
+	// again adds to its comparators.
+	schema.AllowSearch().Allow("title", "!=")
+	require.NoError(t, schema.Validate(mustParse(t, `Dune OR title != Emma`)))
+	require.Equal(t, "title", schema.Fields()[0].String())
+
+	require.ErrorContains(t, query.NewFilterSchema().Validate(mustParse(t, `title = Dune`)), "no fields are filterable")
+	require.Panics(t, func() { query.NewFilterSchema().Allow("title", "~") })
+	require.Panics(t, func() { query.NewFilterSchema().Allow("title.") })
+}
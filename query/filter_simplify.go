@@ -0,0 +1,258 @@
+package query
+
+import (
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Simplify returns a simplified filter equivalent to f for messages of type
+// M, and whether any message can match it at all. Servers may skip querying
+// storage and return an empty page when satisfiable is false.
+//
+// Simplify treats the bare global restrictions true and false as boolean
+// constants and folds them away, e.g. "true AND x" becomes "x" and
+// "false OR x" becomes "x". It detects always-false conjunctions:
+//   - a restriction AND-ed with its negation, e.g. "a = 1 AND NOT a = 1";
+//   - different values for the same singular scalar field, e.g.
+//     "a = 1 AND a = 2", or "a = 1 AND a != 1".
+//
+// Repeated fields are not considered, since "tags = a AND tags = b" matches
+// any message with both tags. The result is in the canonical form of
+// Canonicalize; a filter that matches every message simplifies to an empty
+// filter.
+func Simplify[M proto.Message](f *Filter) (simplified *Filter, satisfiable bool) {
+	var zero M
+	desc := zero.ProtoReflect().Descriptor()
+
+	canonical := Canonicalize(f)
+	if canonical.Expression == nil {
+		return canonical, true
+	}
+	factors, value := simplifyConjuncts(desc, canonical.Expression)
+	switch value {
+	case constTrue:
+		return &Filter{}, true
+	case constFalse:
+		return &Filter{}, false
+	}
+	// Folding may leave groups that canonicalize further, e.g. duplicates.
+	return Canonicalize(factorFilter(factors)), true
+}
+
+// constValue is the result of folding a node: either a constant or unknown.
+type constValue int
+
+const (
+	constUnknown constValue = iota
+	constTrue
+	constFalse
+)
+
+// negate returns the constant value of NOT v.
+func (v constValue) negate() constValue {
+	switch v {
+	case constTrue:
+		return constFalse
+	case constFalse:
+		return constTrue
+	}
+	return constUnknown
+}
+
+// simplifyConjuncts simplifies the canonical conjunction e, returning the
+// remaining factors, or a constant if e folds to one.
+func simplifyConjuncts(desc protoreflect.MessageDescriptor, e *Expression) ([]*Factor, constValue) {
+	var out []*Factor
+	for _, seq := range e.Sequences {
+		for _, f := range seq.Factors {
+			terms, value := simplifyDisjuncts(desc, f)
+			switch value {
+			case constFalse:
+				return nil, constFalse
+			case constTrue:
+				continue
+			}
+			out = append(out, &Factor{Terms: terms})
+		}
+	}
+	if len(out) == 0 {
+		return nil, constTrue
+	}
+	if contradicts(desc, out) {
+		return nil, constFalse
+	}
+	return out, constUnknown
+}
+
+// simplifyDisjuncts simplifies the canonical disjunction f, returning the
+// remaining terms, or a constant if f folds to one.
+func simplifyDisjuncts(desc protoreflect.MessageDescriptor, f *Factor) ([]*Term, constValue) {
+	var out []*Term
+	for _, t := range f.Terms {
+		t, value := simplifyTerm(desc, t)
+		switch value {
+		case constTrue:
+			return nil, constTrue
+		case constFalse:
+			continue
+		}
+		out = append(out, t)
+	}
+	if len(out) == 0 {
+		return nil, constFalse
+	}
+	return out, constUnknown
+}
+
+// simplifyTerm simplifies the canonical term t, returning the simplified term,
+// or a constant if t folds to one.
+func simplifyTerm(desc protoreflect.MessageDescriptor, t *Term) (*Term, constValue) {
+	if r := t.Simple.Restriction; r != nil {
+		value := restrictionConst(r)
+		if t.Negated {
+			value = value.negate()
+		}
+		return t, value
+	}
+
+	factors, value := simplifyConjuncts(desc, t.Simple.Composite)
+	if t.Negated {
+		value = value.negate()
+	}
+	if value != constUnknown {
+		return nil, value
+	}
+	if len(factors) == 1 && len(factors[0].Terms) == 1 {
+		inner := factors[0].Terms[0]
+		return &Term{Negated: t.Negated != inner.Negated, Simple: inner.Simple}, constUnknown
+	}
+	return &Term{
+		Negated: t.Negated,
+		Simple: &Simple{Composite: &Expression{
+			Sequences: []*Sequence{{Factors: factors}},
+		}},
+	}, constUnknown
+}
+
+// restrictionConst returns the constant value of r if it is the bare global
+// restriction true or false.
+func restrictionConst(r *Restriction) constValue {
+	if r.Comparator != "" || r.Comparable == nil || r.Comparable.Member == nil || len(r.Comparable.Member.Fields) > 0 {
+		return constUnknown
+	}
+	switch r.Comparable.Member.Value {
+	case "true", "TRUE":
+		return constTrue
+	case "false", "FALSE":
+		return constFalse
+	}
+	return constUnknown
+}
+
+// contradicts reports whether the conjunction of factors can never hold.
+func contradicts(desc protoreflect.MessageDescriptor, factors []*Factor) bool {
+	// Restrictions holding in every match, by String(), and whether they are
+	// negated.
+	seen := map[string]bool{}
+	// The value a singular field must equal, by field path.
+	equal := map[string]string{}
+	// The values a singular field must not equal, by field path.
+	notEqual := map[string][]string{}
+
+	for _, f := range factors {
+		if len(f.Terms) != 1 || f.Terms[0].Simple.Restriction == nil {
+			continue
+		}
+		t := f.Terms[0]
+		key := t.Simple.Restriction.String()
+		if negated, ok := seen[key]; ok && negated != t.Negated {
+			return true
+		}
+		seen[key] = t.Negated
+
+		path, kind, value, ok := singularEquality(desc, t.Simple.Restriction)
+		if !ok {
+			continue
+		}
+		value, ok = normalizeLiteral(kind, value)
+		if !ok {
+			continue
+		}
+		if (t.Simple.Restriction.Comparator == "=") != t.Negated {
+			if prev, ok := equal[path]; ok && prev != value {
+				return true
+			}
+			equal[path] = value
+		} else {
+			notEqual[path] = append(notEqual[path], value)
+		}
+	}
+
+	for path, values := range notEqual {
+		v, ok := equal[path]
+		if !ok {
+			continue
+		}
+		for _, ne := range values {
+			if ne == v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// singularEquality decomposes r if it compares a singular string, numeric or
+// boolean field of desc with = or != to a literal.
+func singularEquality(desc protoreflect.MessageDescriptor, r *Restriction) (path string, kind protoreflect.Kind, value string, ok bool) {
+	if r.Comparator != "=" && r.Comparator != "!=" {
+		return "", 0, "", false
+	}
+	if r.Comparable == nil || r.Comparable.Member == nil || r.Arg == nil || r.Arg.Comparable == nil {
+		return "", 0, "", false
+	}
+	arg := r.Arg.Comparable.Member
+	if arg == nil || len(arg.Fields) > 0 {
+		return "", 0, "", false
+	}
+
+	segments := append([]string{r.Comparable.Member.Value}, r.Comparable.Member.Fields...)
+	if validateFieldPath(desc, segments) != nil {
+		return "", 0, "", false
+	}
+	fd := fieldPathDescriptor(desc, segments)
+	switch fd.Kind() {
+	case protoreflect.StringKind, protoreflect.BoolKind,
+		protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind,
+		protoreflect.FloatKind, protoreflect.DoubleKind:
+		return strings.Join(segments, "."), fd.Kind(), arg.Value, true
+	}
+	return "", 0, "", false
+}
+
+// normalizeLiteral returns a canonical spelling of the literal value compared
+// with a field of the given kind, so that equal values compare equal, e.g.
+// "1" and "01" for numbers or "true" and "t" for bools. It reports false for
+// a bool it cannot parse, as the evaluator cannot.
+func normalizeLiteral(kind protoreflect.Kind, value string) (string, bool) {
+	switch kind {
+	case protoreflect.StringKind:
+		return value, true
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", false
+		}
+		return strconv.FormatBool(b), true
+	}
+	if n, err := strconv.ParseFloat(value, 64); err == nil {
+		return strconv.FormatFloat(n, 'g', -1, 64), true
+	}
+	return value, true
+}
@@ -0,0 +1,85 @@
+package query_test
+
+import (
+	"testing"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/query"
+)
+
+func TestSimplify(t *testing.T) {
+	tests := []struct {
+		filter      string
+		want        string
+		satisfiable bool
+	}{
+		{filter: "", want: "", satisfiable: true},
+		{filter: "true AND title = Dune", want: "title = Dune", satisfiable: true},
+		{filter: "false OR title = Dune", want: "title = Dune", satisfiable: true},
+		{filter: "true OR title = Dune", want: "", satisfiable: true},
+		{filter: "NOT false", want: "", satisfiable: true},
+		{filter: "false AND title = Dune", satisfiable: false},
+		{filter: "NOT (true OR title = Dune)", satisfiable: false},
+		{filter: "title = Dune AND (false OR title = Dune)", want: "title = Dune", satisfiable: true},
+		{filter: "title = Dune AND title = Emma", satisfiable: false},
+		{filter: "title = Dune AND title != Dune", satisfiable: false},
+		{filter: "title = Dune AND NOT title = Dune", satisfiable: false},
+		{filter: "title = Dune AND title != Emma", want: "title != Emma AND title = Dune", satisfiable: true},
+		{filter: "page_count = 1 AND page_count = 01", want: "page_count = 01 AND page_count = 1", satisfiable: true},
+		{filter: "page_count = 1 AND page_count = 2", satisfiable: false},
+		{filter: "author.given_name = A AND author.given_name = B", satisfiable: false},
+		// Repeated fields match if any element matches.
+		{filter: "authors.given_name = A AND authors.given_name = B", want: "authors.given_name = A AND authors.given_name = B", satisfiable: true},
+		// Global restrictions are only constants when bare.
+		{filter: "prod AND NOT prod", satisfiable: false},
+		{filter: "prod", want: "prod", satisfiable: true},
+		{filter: "(title = Dune AND title = Emma) OR author = x", want: "author = x", satisfiable: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			f, err := query.ParseFilter(tt.filter)
+			if err != nil {
+				t.Fatalf("ParseFilter(%q) failed: %v", tt.filter, err)
+			}
+			got, satisfiable := query.Simplify[*testpb.Book](f)
+			if satisfiable != tt.satisfiable {
+				t.Fatalf("Simplify(%q) satisfiable = %v, want %v", tt.filter, satisfiable, tt.satisfiable)
+			}
+			want, err := query.ParseFilter(tt.want)
+			if err != nil {
+				t.Fatalf("ParseFilter(%q) failed: %v", tt.want, err)
+			}
+			if got.String() != query.Canonicalize(want).String() {
+				t.Errorf("Simplify(%q) = %s, want %s", tt.filter, got, query.Canonicalize(want))
+			}
+		})
+	}
+}
+
+func TestSimplify_Bool(t *testing.T) {
+	tests := []struct {
+		filter      string
+		satisfiable bool
+	}{
+		{filter: "force = true AND force = 1", satisfiable: true},
+		{filter: "force = true AND force = t", satisfiable: true},
+		{filter: "force = true AND force = TRUE", satisfiable: true},
+		{filter: "force = true AND force = 0", satisfiable: false},
+		{filter: "force = true AND force != T", satisfiable: false},
+		// Values the evaluator cannot parse are left alone.
+		{filter: "force = yes AND force = no", satisfiable: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			f, err := query.ParseFilter(tt.filter)
+			if err != nil {
+				t.Fatalf("ParseFilter(%q) failed: %v", tt.filter, err)
+			}
+			if _, satisfiable := query.Simplify[*testpb.DeleteBookRequest](f); satisfiable != tt.satisfiable {
+				t.Fatalf("Simplify(%q) satisfiable = %v, want %v", tt.filter, satisfiable, tt.satisfiable)
+			}
+		})
+	}
+}
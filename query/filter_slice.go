@@ -0,0 +1,96 @@
+package query
+
+import (
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// FilterSlice returns the elements of items that satisfy f, preserving their
+// relative order. The filter is compiled with opts, as by ProtoFilter, and
+// evaluated within the budget set with WithBudget, if any.
+//
+// A nil or empty filter returns every item.
+func FilterSlice[S any, M interface {
+	proto.Message
+	*S
+}](items []M, f *Filter, opts ...FilterOption) ([]M, error) {
+	pred, err := ProtoFilter[S, M](f, opts...)
+	if err != nil {
+		return nil, err
+	}
+	meter := newFilterOptions(opts).budget.start()
+
+	out := make([]M, 0, len(items))
+	for _, item := range items {
+		if err := meter.spend(); err != nil {
+			return nil, err
+		}
+		if pred(item) {
+			out = append(out, item)
+		}
+	}
+	return out, nil
+}
+
+// FilterSliceParallel is like FilterSlice, but evaluates the filter using up
+// to workers goroutines.
+//
+// The output order is deterministic: matching items appear in the same
+// relative order as in items regardless of the number of workers. If workers
+// is less than 2, the filter is evaluated on the calling goroutine. The
+// budget is shared by the workers.
+//
+// Items must not be mutated concurrently with a call to FilterSliceParallel.
+func FilterSliceParallel[S any, M interface {
+	proto.Message
+	*S
+}](items []M, f *Filter, workers int, opts ...FilterOption) ([]M, error) {
+	if workers > len(items) {
+		workers = len(items)
+	}
+	if workers < 2 {
+		return FilterSlice[S, M](items, f, opts...)
+	}
+
+	pred, err := ProtoFilter[S, M](f, opts...)
+	if err != nil {
+		return nil, err
+	}
+	meter := newFilterOptions(opts).budget.start()
+
+	// Each worker owns a contiguous chunk of items and records its results
+	// in a disjoint range of matches, so no further synchronization is needed.
+	matches := make([]bool, len(items))
+	chunk := (len(items) + workers - 1) / workers
+	errs := make([]error, workers)
+
+	var wg sync.WaitGroup
+	for w, start := 0, 0; start < len(items); w, start = w+1, start+chunk {
+		end := min(start+chunk, len(items))
+		wg.Add(1)
+		go func(w, start, end int) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				if errs[w] = meter.spend(); errs[w] != nil {
+					return
+				}
+				matches[i] = pred(items[i])
+			}
+		}(w, start, end)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	out := make([]M, 0, len(items))
+	for i, ok := range matches {
+		if ok {
+			out = append(out, items[i])
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,81 @@
+package query_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/stretchr/testify/require"
+
+	aip "github.com/hxtk/aip/query"
+)
+
+func makeBooks(n int) []*testpb.Book {
+	books := make([]*testpb.Book, n)
+	for i := range books {
+		family := "Hunt"
+		if i%3 == 0 {
+			family = "Thomas"
+		}
+		books[i] = &testpb.Book{
+			Title: fmt.Sprintf("Book %d", i),
+			Name:  fmt.Sprintf("books/%d", i),
+			Author: &testpb.Author{
+				GivenName:  "Andy",
+				FamilyName: family,
+			},
+		}
+	}
+	return books
+}
+
+func TestFilterSliceParallel(t *testing.T) {
+	books := makeBooks(1000)
+	f, err := aip.ParseFilter(`author.family_name = "Thomas"`)
+	require.NoError(t, err)
+
+	want, err := aip.FilterSlice(books, f)
+	require.NoError(t, err)
+	require.Len(t, want, 334)
+
+	for _, workers := range []int{-1, 0, 1, 2, 7, 16, 5000} {
+		t.Run(fmt.Sprintf("workers=%d", workers), func(t *testing.T) {
+			got, err := aip.FilterSliceParallel(books, f, workers)
+			require.NoError(t, err)
+			require.Equal(t, want, got)
+		})
+	}
+}
+
+func TestFilterSliceParallel_InvalidFilter(t *testing.T) {
+	f, err := aip.ParseFilter(`no_such_field.sub = "x"`)
+	require.NoError(t, err)
+
+	_, err = aip.FilterSliceParallel(makeBooks(10), f, 4)
+	require.Error(t, err)
+}
+
+func BenchmarkFilterSlice(b *testing.B) {
+	f, err := aip.ParseFilter(`author.family_name = "Thomas" OR title : "99"`)
+	require.NoError(b, err)
+
+	for _, n := range []int{1000, 10000, 50000} {
+		books := makeBooks(n)
+		b.Run(fmt.Sprintf("n=%d/sequential", n), func(b *testing.B) {
+			for b.Loop() {
+				if _, err := aip.FilterSlice(books, f); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		for _, workers := range []int{2, 4, 8} {
+			b.Run(fmt.Sprintf("n=%d/workers=%d", n, workers), func(b *testing.B) {
+				for b.Loop() {
+					if _, err := aip.FilterSliceParallel(books, f, workers); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package query
+
+// SplitFilter splits filter into a part the table can evaluate in SQL and a
+// residual part to be evaluated in memory, e.g. with ProtoFilter, such that a
+// resource matches filter iff it matches both parts. This lets a backend
+// honor filters on fields without a filterable column instead of rejecting
+// the request.
+//
+// The filter is split along its top-level conjunction: each AND-ed factor
+// (including those inside a parenthesized top-level group) goes to pushed if
+// WhereClause accepts it, and to residual otherwise. A disjunction that
+// references any unsupported field is kept whole in residual. Either result
+// is nil if it has no factors.
+//
+// Example:
+//
+//	pushed, residual := table.SplitFilter(filter)
+//	where, params, err := table.WhereClause(pushed, "p_")
+//	...
+//	match, err := query.ProtoFilter[pb.Book](residual)
+//	...
+//	for each row fetched with where {
+//	    if match(book) { ... }
+//	}
+//
+// With a residual filter, a page of database rows may yield fewer matching
+// resources than the requested page size; callers should keep fetching until
+// the page is full or the rows are exhausted.
+func (t *Table) SplitFilter(filter *Filter) (pushed *Filter, residual *Filter) {
+	if filter == nil || filter.Expression == nil {
+		return nil, nil
+	}
+
+	var pushedFactors, residualFactors []*Factor
+	for _, f := range conjuncts(filter.Expression) {
+		if _, _, err := t.WhereClause(factorFilter([]*Factor{f}), "p_"); err == nil {
+			pushedFactors = append(pushedFactors, f)
+		} else {
+			residualFactors = append(residualFactors, f)
+		}
+	}
+	return factorFilter(pushedFactors), factorFilter(residualFactors)
+}
+
+// conjuncts returns the factors AND-ed together at the top level of e,
+// flattening parenthesized groups that are themselves conjunctions.
+func conjuncts(e *Expression) []*Factor {
+	var out []*Factor
+	for _, seq := range e.Sequences {
+		for _, f := range seq.Factors {
+			if len(f.Terms) == 1 && !f.Terms[0].Negated && f.Terms[0].Simple.Composite != nil {
+				out = append(out, conjuncts(f.Terms[0].Simple.Composite)...)
+				continue
+			}
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// factorFilter returns the filter AND-ing factors, or nil if there are none.
+func factorFilter(factors []*Factor) *Filter {
+	if len(factors) == 0 {
+		return nil
+	}
+	return &Filter{
+		Expression: &Expression{
+			Sequences: []*Sequence{{Factors: factors}},
+		},
+	}
+}
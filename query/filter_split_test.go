@@ -0,0 +1,61 @@
+package query
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSplitFilter(t *testing.T) {
+	Convey("SplitFilter", t, func() {
+		table := NewTable().WithColumns(
+			NewColumn().WithFieldPath("foo").WithDatabaseName("db_foo").Filterable().Build(),
+			NewColumn().WithFieldPath("bar").WithDatabaseName("db_bar").Filterable().Build(),
+		).Build()
+
+		split := func(filter string) (string, *Filter) {
+			f, err := ParseFilter(filter)
+			So(err, ShouldBeNil)
+			pushed, residual := table.SplitFilter(f)
+			where, _, err := table.WhereClause(pushed, "p_")
+			So(err, ShouldBeNil)
+			return where, residual
+		}
+		parse := func(filter string) *Filter {
+			f, err := ParseFilter(filter)
+			So(err, ShouldBeNil)
+			return f
+		}
+
+		Convey("Empty filter", func() {
+			pushed, residual := table.SplitFilter(&Filter{})
+			So(pushed, ShouldBeNil)
+			So(residual, ShouldBeNil)
+		})
+		Convey("Fully supported", func() {
+			where, residual := split("foo = a AND bar = b")
+			So(where, ShouldEqual, "((db_foo = @p_0) AND (db_bar = @p_1))")
+			So(residual, ShouldBeNil)
+		})
+		Convey("Unsupported conjunct", func() {
+			where, residual := split("foo = a AND baz = c AND NOT bar = b")
+			So(where, ShouldEqual, "((db_foo = @p_0) AND (NOT (db_bar = @p_1)))")
+			So(residual, ShouldResemble, parse("baz = c"))
+		})
+		Convey("Disjunction with an unsupported field stays whole", func() {
+			where, residual := split("foo = a AND (bar = b OR baz = c)")
+			So(where, ShouldEqual, "(db_foo = @p_0)")
+			So(residual, ShouldResemble, parse("bar = b OR baz = c"))
+		})
+		Convey("Parenthesized conjunctions are flattened", func() {
+			where, residual := split("(foo = a AND baz = c) bar = b")
+			So(where, ShouldEqual, "((db_foo = @p_0) AND (db_bar = @p_1))")
+			So(residual, ShouldResemble, parse("baz = c"))
+		})
+		Convey("Nothing supported", func() {
+			where, residual := split("baz = c")
+			So(where, ShouldEqual, "(TRUE)")
+			So(residual, ShouldResemble, parse("baz = c"))
+		})
+	})
+}
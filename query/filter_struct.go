@@ -0,0 +1,127 @@
+package query
+
+import (
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// The full names of the well-known types holding JSON values.
+const (
+	structName    protoreflect.FullName = "google.protobuf.Struct"
+	valueName     protoreflect.FullName = "google.protobuf.Value"
+	listValueName protoreflect.FullName = "google.protobuf.ListValue"
+)
+
+// isJSONMessage reports whether desc is google.protobuf.Struct or
+// google.protobuf.Value, whose members are keys rather than fields.
+func isJSONMessage(desc protoreflect.MessageDescriptor) bool {
+	return desc.FullName() == structName || desc.FullName() == valueName
+}
+
+// isJSONValue reports whether desc is one of the well-known types holding
+// JSON values, which filters compare as converted by jsonValue.
+func isJSONValue(desc protoreflect.MessageDescriptor) bool {
+	return isJSONMessage(desc) || desc.FullName() == listValueName
+}
+
+// fieldValue returns the Go value of v, a value of the singular field fd, for
+// comparison: JSON values are converted by jsonValue.
+func fieldValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) any {
+	if fd.Message() != nil && isJSONValue(fd.Message()) {
+		return jsonValue(v.Message())
+	}
+	return v.Interface()
+}
+
+// resolveJSONPath resolves keys in m, a google.protobuf.Struct or
+// google.protobuf.Value, to the Go value of the JSON value they address, as
+// by jsonValue. Each key selects a member of a Struct; a missing member, or
+// a key into anything but a Struct, resolves to nil.
+func resolveJSONPath(m protoreflect.Message, keys []string) any {
+	for _, key := range keys {
+		if m.Descriptor().FullName() == valueName {
+			m = structOfValue(m)
+			if m == nil {
+				return nil
+			}
+		}
+		fields := m.Get(m.Descriptor().Fields().ByName("fields")).Map()
+		v := fields.Get(protoreflect.ValueOfString(key).MapKey())
+		if !v.IsValid() {
+			return nil
+		}
+		m = v.Message()
+	}
+	return jsonValue(m)
+}
+
+// hasJSONPath reports whether keys address a member of m, a
+// google.protobuf.Struct or google.protobuf.Value. A member set to null is
+// present.
+func hasJSONPath(m protoreflect.Message, keys []string) bool {
+	for _, key := range keys {
+		if m.Descriptor().FullName() == valueName {
+			m = structOfValue(m)
+			if m == nil {
+				return false
+			}
+		}
+		fields := m.Get(m.Descriptor().Fields().ByName("fields")).Map()
+		key := protoreflect.ValueOfString(key).MapKey()
+		if !fields.Has(key) {
+			return false
+		}
+		m = fields.Get(key).Message()
+	}
+	return true
+}
+
+// structOfValue returns the struct_value of the google.protobuf.Value m, or
+// nil if m holds another kind of value.
+func structOfValue(m protoreflect.Message) protoreflect.Message {
+	fd := m.Descriptor().Fields().ByName("struct_value")
+	if !m.Has(fd) {
+		return nil
+	}
+	return m.Get(fd).Message()
+}
+
+// jsonValue converts m, a google.protobuf.Struct, Value or ListValue, to the
+// Go value compareAny compares: nil for null, float64 for numbers, string,
+// bool, map[any]any for structs and []any for lists, whose elements compare
+// with "any element matches" semantics.
+func jsonValue(m protoreflect.Message) any {
+	desc := m.Descriptor()
+	switch desc.FullName() {
+	case structName:
+		out := make(map[any]any)
+		m.Get(desc.Fields().ByName("fields")).Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+			out[k.String()] = jsonValue(v.Message())
+			return true
+		})
+		return out
+	case listValueName:
+		l := m.Get(desc.Fields().ByName("values")).List()
+		out := make([]any, l.Len())
+		for i := range out {
+			out[i] = jsonValue(l.Get(i).Message())
+		}
+		return out
+	}
+
+	fd := m.WhichOneof(desc.Oneofs().ByName("kind"))
+	if fd == nil {
+		return nil
+	}
+	v := m.Get(fd)
+	switch fd.Name() {
+	case "number_value":
+		return v.Float()
+	case "string_value":
+		return v.String()
+	case "bool_value":
+		return v.Bool()
+	case "struct_value", "list_value":
+		return jsonValue(v.Message())
+	}
+	return nil
+}
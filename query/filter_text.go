@@ -0,0 +1,129 @@
+package query
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// plainText matches the values of text members that lex as a single text
+// token, and so may be written without quotes.
+var plainText = regexp.MustCompile(`^[^\s\.,<>=!:\(\)"\-][^\s\.,<>=!:\(\)]*$`)
+
+// writeFilter writes e to b in the canonical form of Filter.String.
+func writeFilter(b *strings.Builder, e *Expression) {
+	first := true
+	for _, seq := range e.Sequences {
+		for _, f := range seq.Factors {
+			if !first {
+				b.WriteString(" AND ")
+			}
+			first = false
+			for i, t := range f.Terms {
+				if i > 0 {
+					b.WriteString(" OR ")
+				}
+				writeTerm(b, t)
+			}
+		}
+	}
+}
+
+// writeTerm writes the term t, negated with NOT.
+func writeTerm(b *strings.Builder, t *Term) {
+	if t.Negated {
+		b.WriteString("NOT ")
+	}
+	if t.Simple.Composite != nil {
+		writeComposite(b, t.Simple.Composite)
+		return
+	}
+	writeRestriction(b, t.Simple.Restriction)
+}
+
+// writeComposite writes the parenthesized expression e.
+func writeComposite(b *strings.Builder, e *Expression) {
+	b.WriteString("(")
+	writeFilter(b, e)
+	b.WriteString(")")
+}
+
+// writeRestriction writes r, with spaces around its comparator unless it
+// is the has operator, e.g. `a = 1` but `a:b`.
+func writeRestriction(b *strings.Builder, r *Restriction) {
+	writeComparable(b, r.Comparable)
+	if r.Comparator == "" || r.Arg == nil {
+		return
+	}
+	if r.Comparator == ":" {
+		b.WriteString(":")
+	} else {
+		b.WriteString(" ")
+		b.WriteString(r.Comparator)
+		b.WriteString(" ")
+	}
+	if r.Arg.Composite != nil {
+		writeComposite(b, r.Arg.Composite)
+		return
+	}
+	// Text arguments are compared as strings, as quoted ones are, other
+	// than the * of presence tests.
+	if m := r.Arg.Comparable.Member; m != nil && m.Kind == LiteralText && len(m.Fields) == 0 && m.Value != "*" {
+		b.WriteString(strconv.Quote(m.Value))
+		return
+	}
+	writeComparable(b, r.Arg.Comparable)
+}
+
+// writeComparable writes the member or function call c.
+func writeComparable(b *strings.Builder, c *Comparable) {
+	if c.Function != nil {
+		b.WriteString(c.Function.Name)
+		b.WriteString("(")
+		for i, a := range c.Function.Args {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			if a.Composite != nil {
+				writeComposite(b, a.Composite)
+			} else {
+				writeComparable(b, a.Comparable)
+			}
+		}
+		b.WriteString(")")
+		return
+	}
+	if c.Member != nil {
+		writeMember(b, c.Member)
+	}
+}
+
+// writeMember writes m as it was written, quoting strings and text that
+// would not parse back as text.
+func writeMember(b *strings.Builder, m *Member) {
+	switch {
+	case m.Kind == LiteralString,
+		m.Kind == LiteralText && len(m.Fields) == 0 && !isPlainText(m.Value):
+		b.WriteString(strconv.Quote(m.Value))
+		return
+	}
+	b.WriteString(m.Value)
+	for _, f := range m.Fields {
+		b.WriteString(".")
+		b.WriteString(f)
+	}
+}
+
+// isPlainText reports whether s parses back as a text member with the value
+// s when written without quotes.
+func isPlainText(s string) bool {
+	switch s {
+	case "AND", "OR", "NOT":
+		return false
+	}
+	if !plainText.MatchString(s) {
+		return false
+	}
+	kind, _ := classifyLiteral(s)
+	return kind == LiteralText
+}
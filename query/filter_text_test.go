@@ -0,0 +1,101 @@
+package query_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+	aip "github.com/hxtk/aip/query"
+)
+
+func TestFilterString(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   string
+	}{
+		{``, ``},
+		{`  title  =  Dune  `, `title = "Dune"`},
+		{`title="Dune"`, `title = "Dune"`},
+		{`a b AND c`, `a AND b AND c`},
+		{`New York Giants OR Yankees`, `New AND York AND Giants OR Yankees`},
+		{`-title = Dune`, `NOT title = "Dune"`},
+		{`NOT (a OR b)`, `NOT (a OR b)`},
+		{`tags : "sci-fi"`, `tags:"sci-fi"`},
+		{`author:*`, `author:*`},
+		{`reviews.alice:*`, `reviews.alice:*`},
+		{`page_count >= 100 AND page_count < -5`, `page_count >= 100 AND page_count < -5`},
+		{`average_rating > 4.5`, `average_rating > 4.5`},
+		{`author = null OR format != PAPERBACK`, `author = null OR format != "PAPERBACK"`},
+		{`title = (Dune OR Emma)`, `title = (Dune OR Emma)`},
+		{`regex( title ,"^The" )`, `regex(title, "^The")`},
+		{`math.double(page_count) > 10`, `math.double(page_count) > 10`},
+		{`title = "say \"hi\"\n"`, `title = "say \"hi\"\n"`},
+		{`"AND" OR "12" OR "a b"`, `"AND" OR "12" OR "a b"`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.filter, func(t *testing.T) {
+			got := mustParse(t, tc.filter).String()
+			require.Equal(t, tc.want, got)
+			require.Equal(t, got, mustParse(t, got).String(), "the canonical form is stable")
+		})
+	}
+
+	var nilFilter *aip.Filter
+	require.Equal(t, "", nilFilter.String())
+}
+
+// TestFilterString_Quoting checks that text members built without the
+// parser, which may not parse back as text, are quoted.
+func TestFilterString_Quoting(t *testing.T) {
+	text := func(value string) *aip.Term {
+		return &aip.Term{Simple: &aip.Simple{Restriction: &aip.Restriction{
+			Comparable: &aip.Comparable{Member: &aip.Member{Value: value}},
+		}}}
+	}
+	f := &aip.Filter{Expression: &aip.Expression{Sequences: []*aip.Sequence{{
+		Factors: []*aip.Factor{{Terms: []*aip.Term{text("New York"), text("-1"), text("OR")}}},
+	}}}}
+	require.Equal(t, `"New York" OR "-1" OR "OR"`, f.String())
+}
+
+func TestFilterString_RoundTrip(t *testing.T) {
+	books := []*testpb.Book{
+		{},
+		{Title: "Dune", PageCount: proto.Int32(412), Format: testpb.Format_PAPERBACK, Tags: []string{"sci-fi"}},
+		{Title: "Emma", Author: &testpb.Author{GivenName: "Jane"}, Reviews: map[string]string{"alice": "classic"}},
+		{Title: "The Hobbit", Format: testpb.Format_HARDCOVER, Authors: []*testpb.Author{{GivenName: "J.R.R."}}},
+	}
+	filters := []string{
+		`title = Dune`,
+		`title:Du*`,
+		`Dune OR Emma`,
+		`-format = PAPERBACK`,
+		`format = HARDCOVER page_count > 0`,
+		`tags:sci-fi OR reviews:alice`,
+		`reviews.alice = classic`,
+		`reviews.*:classic`,
+		`author:* AND NOT author.given_name = Joe`,
+		`authors.given_name = "J.R.R."`,
+		`(title = Dune OR title = Emma) AND page_count:*`,
+		`regex(title, "^The")`,
+		`year(create_time) = 1970`,
+	}
+	for _, filter := range filters {
+		t.Run(filter, func(t *testing.T) {
+			f := mustParse(t, filter)
+			text := f.String()
+			roundTrip := mustParse(t, text)
+			require.Equal(t, text, roundTrip.String())
+
+			want, err := aip.ProtoFilter[testpb.Book](f, aip.WithFunctions(testFunctions(t)))
+			require.NoError(t, err)
+			got, err := aip.ProtoFilter[testpb.Book](roundTrip, aip.WithFunctions(testFunctions(t)))
+			require.NoError(t, err)
+			for _, book := range books {
+				require.Equal(t, want(book), got(book), "%s on %v", text, book)
+			}
+		})
+	}
+}
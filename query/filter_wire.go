@@ -0,0 +1,217 @@
+package query
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// WireFilter compiles a parsed AIP-160 Filter into a predicate over the
+// serialized (wire format) bytes of a message of type M.
+//
+// Only the fields referenced by the filter are decoded; every other field is
+// skipped at the wire level without being unmarshaled. This makes WireFilter
+// suitable for high-throughput streams of large messages where most of the
+// payload is irrelevant to the filter.
+//
+// Filters containing a global restriction (e.g. `Pragmatic`) must search every
+// string field in the message, so they fall back to a full decode, as do
+// filters compiled with a Matcher or Resolver.
+//
+// Example:
+//
+//	filter, err := query.ParseFilter(`author.family_name = "Hunt"`)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	f, err := query.WireFilter[testpb.Book](filter)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	ok, err := f(raw) // raw is a serialized testpb.Book
+//
+// The returned closure only returns an error if the bytes are malformed.
+func WireFilter[S any, M interface {
+	proto.Message
+	*S
+}](f *Filter, opts ...FilterOption) (func([]byte) (bool, error), error) {
+	if f == nil || f.Expression == nil {
+		// empty filter always true
+		return func([]byte) (bool, error) { return true, nil }, nil
+	}
+
+	var zeroRaw S
+	var zero M = &zeroRaw
+	o := newFilterOptions(opts)
+	f, err := o.bind(f)
+	if err != nil {
+		return nil, err
+	}
+
+	// Perform validation once; discard result.
+	if _, err := matchesFilter(nil, zero, f, o); err != nil {
+		return nil, err
+	}
+
+	refs, global := referencedFields(zero.ProtoReflect().Descriptor(), f.Expression)
+	// Matchers and resolvers may read any field of the message.
+	global = global || len(o.matchers) > 0 || len(o.resolvers) > 0
+
+	return func(b []byte) (bool, error) {
+		var raw S
+		var m M = &raw
+		if global {
+			if err := proto.Unmarshal(b, m); err != nil {
+				return false, err
+			}
+		} else {
+			partial, err := extractFields(b, refs)
+			if err != nil {
+				return false, err
+			}
+			if err := proto.Unmarshal(partial, m); err != nil {
+				return false, err
+			}
+		}
+		ok, _ := matchesFilter(context.Background(), m, f, o)
+		return ok, nil
+	}, nil
+}
+
+// fieldSet is a trie of field numbers referenced by a filter.
+//
+// A nil child means the whole field is needed; a non-nil child means only the
+// listed subfields of the (singular) message field are needed.
+type fieldSet map[protowire.Number]fieldSet
+
+// add records the member path rooted at desc in the set.
+func (s fieldSet) add(desc protoreflect.MessageDescriptor, path []string) {
+	fd := desc.Fields().ByName(protoreflect.Name(path[0]))
+	if fd == nil {
+		if od := desc.Oneofs().ByName(protoreflect.Name(path[0])); od != nil {
+			// The name of a oneof, e.g. `edition:*`, tests its members.
+			for i := 0; i < od.Fields().Len(); i++ {
+				s[od.Fields().Get(i).Number()] = nil
+			}
+		}
+		// Otherwise a literal value rather than a field reference.
+		return
+	}
+	num := fd.Number()
+	child, seen := s[num]
+	if seen && child == nil {
+		// Already decoding the whole field.
+		return
+	}
+	if len(path) == 1 || fd.IsList() || fd.IsMap() || fd.Message() == nil {
+		s[num] = nil
+		return
+	}
+	if isJSONValue(fd.Message()) || fd.Message().FullName() == anyName {
+		// The rest of the path names keys of a JSON object, or @type and
+		// fields of the packed message, rather than fields of desc's
+		// submessage, so the whole value is needed.
+		s[num] = nil
+		return
+	}
+	if child == nil {
+		child = fieldSet{}
+		s[num] = child
+	}
+	child.add(fd.Message(), path[1:])
+}
+
+// referencedFields walks the expression and returns the set of fields it
+// reads. The second return value reports whether the expression contains a
+// global restriction, which requires the whole message.
+func referencedFields(desc protoreflect.MessageDescriptor, e *Expression) (fieldSet, bool) {
+	refs := fieldSet{}
+	global := false
+
+	var addComparable func(c *Comparable)
+	addComparable = func(c *Comparable) {
+		if c == nil {
+			return
+		}
+		if c.Function != nil {
+			for _, a := range c.Function.Args {
+				addComparable(a.Comparable)
+			}
+			return
+		}
+		if c.Member != nil {
+			refs.add(desc, append([]string{c.Member.Value}, c.Member.Fields...))
+		}
+	}
+	var walkExpr func(e *Expression)
+	walkExpr = func(e *Expression) {
+		for _, seq := range e.Sequences {
+			for _, fac := range seq.Factors {
+				for _, t := range fac.Terms {
+					s := t.Simple
+					if s.Composite != nil {
+						walkExpr(s.Composite)
+						continue
+					}
+					r := s.Restriction
+					if r == nil {
+						continue
+					}
+					if r.Comparator == "" {
+						global = true
+						continue
+					}
+					addComparable(r.Comparable)
+					if r.Arg != nil {
+						addComparable(r.Arg.Comparable)
+					}
+					if r.Arg != nil && r.Arg.Composite != nil {
+						walkExpr(r.Arg.Composite)
+					}
+				}
+			}
+		}
+	}
+	walkExpr(e)
+	return refs, global
+}
+
+// extractFields scans b and returns a valid wire-format message containing
+// only the fields in refs. Submessages with a restricted child set are
+// rewritten recursively.
+func extractFields(b []byte, refs fieldSet) ([]byte, error) {
+	var out []byte
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, fmt.Errorf("parsing tag: %w", protowire.ParseError(n))
+		}
+		m := protowire.ConsumeFieldValue(num, typ, b[n:])
+		if m < 0 {
+			return nil, fmt.Errorf("parsing field %d: %w", num, protowire.ParseError(m))
+		}
+		field := b[:n+m]
+		b = b[n+m:]
+
+		child, ok := refs[num]
+		if !ok {
+			continue
+		}
+		if child == nil || typ != protowire.BytesType {
+			out = append(out, field...)
+			continue
+		}
+
+		sub, _ := protowire.ConsumeBytes(field[n:])
+		pruned, err := extractFields(sub, child)
+		if err != nil {
+			return nil, err
+		}
+		out = protowire.AppendTag(out, num, protowire.BytesType)
+		out = protowire.AppendBytes(out, pruned)
+	}
+	return out, nil
+}
@@ -0,0 +1,159 @@
+package query_test
+
+import (
+	"testing"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	aip "github.com/hxtk/aip/query"
+)
+
+func TestWireFilter(t *testing.T) {
+	book := &testpb.Book{
+		Title: "The Pragmatic Programmer",
+		Author: &testpb.Author{
+			GivenName:  "Andy",
+			FamilyName: "Hunt",
+		},
+		Authors: []*testpb.Author{
+			{GivenName: "Andy", FamilyName: "Hunt"},
+			{GivenName: "Dave", FamilyName: "Thomas"},
+		},
+		Reviews: map[string]string{
+			"review1": "Classic software engineering advice",
+		},
+		Name:      "books/123",
+		PageCount: proto.Int32(0),
+		Edition:   &testpb.Book_Volume{Volume: 3},
+	}
+	metadata, err := structpb.NewStruct(map[string]any{
+		"genre":  "scifi",
+		"series": map[string]any{"name": "Pragmatic Bookshelf"},
+		"tags":   []any{"craft", "career"},
+	})
+	require.NoError(t, err)
+	book.Metadata = metadata
+	details, err := anypb.New(&testpb.Author{GivenName: "Andy", FamilyName: "Hunt"})
+	require.NoError(t, err)
+	book.Details = details
+	raw, err := proto.Marshal(book)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		filter   string
+		expected bool
+	}{
+		{"empty filter", ``, true},
+		{"top-level equality", `title = "The Pragmatic Programmer"`, true},
+		{"top-level mismatch", `title = "Clean Code"`, false},
+		{"nested field", `author.family_name = "Hunt"`, true},
+		{"nested sibling not decoded", `author.given_name = "Hunt"`, false},
+		{"repeated message", `authors.family_name = "Thomas"`, true},
+		{"map has", `reviews.* : "Classic"`, true},
+		{"map has key", `reviews : "review1"`, true},
+		{"map has does not match values", `reviews : "Classic"`, false},
+		{"optional zero is present", `page_count:*`, true},
+		{"unset message is absent", `create_time:*`, false},
+		{"global restriction", `Thomas`, true},
+		{"AND across fields", `name = "books/123" AND author.given_name = "Andy"`, true},
+		{"negated composite", `NOT (title = "Clean Code" OR name = "books/456")`, true},
+		{"struct key", `metadata.genre = "scifi"`, true},
+		{"struct key mismatch", `metadata.genre = "fantasy"`, false},
+		{"nested struct key", `metadata.series.name = "Pragmatic Bookshelf"`, true},
+		{"struct list", `metadata.tags:career`, true},
+		{"struct key presence", `metadata.genre:*`, true},
+		{"struct missing key", `metadata.publisher:*`, false},
+		{"any type", `details.@type = "type.googleapis.com/test.Author"`, true},
+		{"any type mismatch", `details.@type = "type.googleapis.com/test.Review"`, false},
+		{"any packed field", `details.given_name = "Andy"`, true},
+		{"any packed field mismatch", `details.given_name = "Dave"`, false},
+		{"any packed field presence", `details.family_name:*`, true},
+		{"oneof presence", `edition:*`, true},
+		{"oneof member", `volume = 3`, true},
+		{"oneof wrong branch", `edition_code = ""`, false},
+		{"oneof wrong branch negated", `edition_code != "X"`, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := aip.ParseFilter(tc.filter)
+			require.NoError(t, err, "parse filter")
+
+			want, err := aip.ProtoFilter[testpb.Book](f)
+			require.NoError(t, err, "compile proto filter")
+
+			filter, err := aip.WireFilter[testpb.Book](f)
+			require.NoError(t, err, "compile wire filter")
+
+			ok, err := filter(raw)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, ok)
+			require.Equal(t, want(book), ok)
+		})
+	}
+}
+
+func TestWireFilter_Bindings(t *testing.T) {
+	book := &testpb.Book{Title: "me"}
+	raw, err := proto.Marshal(book)
+	require.NoError(t, err)
+	f, err := aip.ParseFilter(`title = @who`)
+	require.NoError(t, err)
+	bindings := aip.WithBindings(map[string]string{"who": "me"})
+
+	want, err := aip.ProtoFilter[testpb.Book](f, bindings)
+	require.NoError(t, err)
+	filter, err := aip.WireFilter[testpb.Book](f, bindings)
+	require.NoError(t, err)
+	ok, err := filter(raw)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, want(book), ok)
+
+	_, err = aip.WireFilter[testpb.Book](f, aip.WithBindings(map[string]string{}))
+	require.Error(t, err, "unbound placeholders are errors")
+}
+
+func TestWireFilter_NestedRepeated(t *testing.T) {
+	thread := &testpb.Comment{Replies: []*testpb.Comment{
+		{Text: "first"},
+		{Text: "second", Replies: []*testpb.Comment{
+			{Text: "shallow"},
+			{Text: "deep", Replies: []*testpb.Comment{{Text: "deepest"}}},
+		}},
+	}}
+	raw, err := proto.Marshal(thread)
+	require.NoError(t, err)
+
+	for filter, expected := range map[string]bool{
+		`replies.replies.text = deep`:             true,
+		`replies.replies.replies.text = deepest`:  true,
+		`replies.replies.replies.text = shallow`:  false,
+		`replies.replies.text:*`:                  true,
+		`NOT replies.replies.replies.text = deep`: true,
+	} {
+		f, err := aip.ParseFilter(filter)
+		require.NoError(t, err, filter)
+		wire, err := aip.WireFilter[testpb.Comment](f)
+		require.NoError(t, err, filter)
+		ok, err := wire(raw)
+		require.NoError(t, err, filter)
+		require.Equal(t, expected, ok, filter)
+	}
+}
+
+func TestWireFilter_MalformedBytes(t *testing.T) {
+	f, err := aip.ParseFilter(`title = "Dune"`)
+	require.NoError(t, err)
+
+	filter, err := aip.WireFilter[testpb.Book](f)
+	require.NoError(t, err)
+
+	_, err = filter([]byte{0x0a, 0xff})
+	require.Error(t, err)
+}
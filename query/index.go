@@ -0,0 +1,426 @@
+package query
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Index is an in-memory collection of resources, identified by their name
+// field, with sorted secondary indexes over declared orders. It lets
+// memstore-backed services answer filtered, ordered and paginated List
+// requests without scanning and sorting the whole collection:
+//
+//	books, err := query.NewIndex[pb.Book](
+//	    []query.OrderBy{{FieldPath: query.NewFieldPath("create_time")}},
+//	)
+//	...
+//	books.Put(book)
+//	...
+//	page, more, err := books.List(filter, order, cursor, pageSize)
+//
+// Resources are always indexed by name, and each declared order is extended
+// with name to make it total. An index serves a List whose order is a prefix
+// of its own, or the reverse of one; pages are then read from the index in
+// order, starting after the cursor and stopping once full. Restrictions of
+// the first field of the index with =, <, <=, > or >= to a literal, in the
+// top-level conjunction of the filter, narrow the range of the index read
+// by binary search, unless the field has explicit presence. Other
+// restrictions are evaluated on each resource read.
+//
+// Lists that no index serves sort the matching resources, which takes time
+// linear in the size of the collection.
+//
+// An Index is safe for concurrent use. Resources must not be modified after
+// they are added.
+type Index[S any, M interface {
+	proto.Message
+	*S
+}] struct {
+	name protoreflect.FieldDescriptor
+
+	mu      sync.RWMutex
+	byName  map[string]M
+	indexes []*sortedIndex[M]
+}
+
+// sortedIndex holds resources sorted by order.
+type sortedIndex[M proto.Message] struct {
+	order   []OrderBy
+	compare func(a, b M) int
+	items   []M
+}
+
+// NewIndex returns an empty Index with a sorted index for each of orders.
+// It returns an error if M has no string name field or an order is not
+// valid for M.
+func NewIndex[S any, M interface {
+	proto.Message
+	*S
+}](orders ...[]OrderBy) (*Index[S, M], error) {
+	var zero M = new(S)
+	name, err := stringField(zero.ProtoReflect().Descriptor(), "name")
+	if err != nil {
+		return nil, err
+	}
+
+	x := &Index[S, M]{
+		name:   name,
+		byName: make(map[string]M),
+	}
+	byName := []OrderBy{{FieldPath: NewFieldPath("name")}}
+	for _, order := range append([][]OrderBy{nil}, orders...) {
+		full := MergeWithDefaultOrder(byName, order)
+		compare, err := Comparer[M](full)
+		if err != nil {
+			return nil, err
+		}
+		x.indexes = append(x.indexes, &sortedIndex[M]{order: full, compare: compare})
+	}
+	return x, nil
+}
+
+// Len returns the number of resources in the index.
+func (x *Index[S, M]) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.byName)
+}
+
+// Get returns the resource with the given name.
+func (x *Index[S, M]) Get(name string) (M, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	m, ok := x.byName[name]
+	return m, ok
+}
+
+// Put adds m to the index, replacing any resource with the same name.
+func (x *Index[S, M]) Put(m M) {
+	name := m.ProtoReflect().Get(x.name).String()
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	old, replace := x.byName[name]
+	x.byName[name] = m
+	for _, idx := range x.indexes {
+		if replace {
+			idx.remove(old)
+		}
+		i, _ := slices.BinarySearchFunc(idx.items, m, idx.compare)
+		idx.items = slices.Insert(idx.items, i, m)
+	}
+}
+
+// Delete removes the resource with the given name, reporting whether there
+// was one.
+func (x *Index[S, M]) Delete(name string) bool {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	old, ok := x.byName[name]
+	if !ok {
+		return false
+	}
+	delete(x.byName, name)
+	for _, idx := range x.indexes {
+		idx.remove(old)
+	}
+	return true
+}
+
+// remove removes m from the index.
+func (idx *sortedIndex[M]) remove(m M) {
+	if i, ok := slices.BinarySearchFunc(idx.items, m, idx.compare); ok {
+		idx.items = slices.Delete(idx.items, i, i+1)
+	}
+}
+
+// List returns up to pageSize resources matching f, in the given order,
+// that come after cursor, and whether more follow them. A nil cursor starts
+// from the first resource, and a pageSize of zero or less returns every
+// match.
+//
+// An empty order lists resources by name. The cursor need only have the
+// fields of order set, as in cursors decoded with DecodeCursor. For pages to
+// be well-defined, order should end with a unique field, such as name.
+//
+// The filter is compiled with opts, as by ProtoFilter, and evaluated
+// within the budget set with WithBudget, if any. If f or order is not valid
+// for M, the error is of type *FieldViolationError.
+func (x *Index[S, M]) List(f *Filter, order []OrderBy, cursor M, pageSize int, opts ...FilterOption) ([]M, bool, error) {
+	if len(order) == 0 {
+		order = x.indexes[0].order
+	}
+	pred, err := ProtoFilter[S, M](f, opts...)
+	if err != nil {
+		return nil, false, err
+	}
+	o := newFilterOptions(opts)
+	// The range is narrowed on the filter as pred evaluates it.
+	bound, err := o.bind(f)
+	if err != nil {
+		return nil, false, err
+	}
+	meter := o.budget.start()
+	match := func(m M) (bool, error) {
+		if err := meter.spend(); err != nil {
+			return false, err
+		}
+		return pred(m), nil
+	}
+	compare, err := Comparer[M](order)
+	if err != nil {
+		return nil, false, err
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	idx, sorted, reverse, lo, hi := x.plan(bound, order, o)
+	var page []M
+	full := func() bool { return pageSize > 0 && len(page) > pageSize }
+
+	if !sorted {
+		for _, m := range idx.items[lo:hi] {
+			if cursor != nil && compare(m, cursor) <= 0 {
+				continue
+			}
+			ok, err := match(m)
+			if err != nil {
+				return nil, false, err
+			}
+			if ok {
+				page = append(page, m)
+			}
+		}
+		slices.SortStableFunc(page, compare)
+	} else if !reverse {
+		if cursor != nil {
+			lo = max(lo, sort.Search(len(idx.items), func(i int) bool { return compare(idx.items[i], cursor) > 0 }))
+		}
+		for i := lo; i < hi && !full(); i++ {
+			ok, err := match(idx.items[i])
+			if err != nil {
+				return nil, false, err
+			}
+			if ok {
+				page = append(page, idx.items[i])
+			}
+		}
+	} else {
+		if cursor != nil {
+			hi = min(hi, sort.Search(len(idx.items), func(i int) bool { return compare(idx.items[i], cursor) <= 0 }))
+		}
+		for i := hi - 1; i >= lo && !full(); i-- {
+			ok, err := match(idx.items[i])
+			if err != nil {
+				return nil, false, err
+			}
+			if ok {
+				page = append(page, idx.items[i])
+			}
+		}
+	}
+
+	if pageSize > 0 && len(page) > pageSize {
+		return page[:pageSize], true, nil
+	}
+	return page, false, nil
+}
+
+// plan chooses the index to read for a List with filter f and order, and
+// the range of it to read: of the indexes serving order, the one whose range
+// narrowed by f is smallest, which serves order read backwards if reverse.
+// If no index serves order, sorted is false and idx is the index whose range
+// is smallest.
+func (x *Index[S, M]) plan(f *Filter, order []OrderBy, o *filterOptions) (idx *sortedIndex[M], sorted, reverse bool, lo, hi int) {
+	for _, candidate := range x.indexes {
+		r, serves := candidate.serves(order)
+		l, h := candidate.bounds(f, o)
+		switch {
+		case idx == nil, serves && !sorted, serves == sorted && h-l < hi-lo:
+			idx, sorted, reverse, lo, hi = candidate, serves, r, l, h
+		}
+	}
+	return idx, sorted, reverse, lo, hi
+}
+
+// serves reports whether reading idx in some direction yields order, and
+// whether that direction is backwards.
+func (idx *sortedIndex[M]) serves(order []OrderBy) (reverse, ok bool) {
+	if len(order) > len(idx.order) {
+		return false, false
+	}
+	for i, o := range order {
+		if o.FieldPath.String() != idx.order[i].FieldPath.String() {
+			return false, false
+		}
+		flipped := o.Descending != idx.order[i].Descending
+		if i == 0 {
+			reverse = flipped
+		} else if flipped != reverse {
+			return false, false
+		}
+	}
+	return reverse, true
+}
+
+// bounds returns the range of idx holding the resources that may satisfy
+// the restrictions of its first field to literals in the top-level
+// conjunction of f, as compiled with o.
+//
+// Fields with explicit presence are not narrowed on: unset fields sort
+// first, but compare as their zero value in filters. Neither are fields
+// whose restrictions o evaluates otherwise than by comparing the stored
+// value: those of a matcher or resolver, and strings compared normalized.
+func (idx *sortedIndex[M]) bounds(f *Filter, o *filterOptions) (lo, hi int) {
+	lo, hi = 0, len(idx.items)
+	if f == nil || f.Expression == nil || len(idx.items) == 0 {
+		return lo, hi
+	}
+	key := idx.order[0]
+	desc := idx.items[0].ProtoReflect().Descriptor()
+	if pathHasPresence(desc, key.FieldPath.segments) {
+		return lo, hi
+	}
+	if segments := key.FieldPath.segments; len(segments) == 1 {
+		_, matcher := o.matchers[segments[0]]
+		_, resolver := o.resolvers[segments[0]]
+		if matcher || resolver {
+			return lo, hi
+		}
+	}
+	fd := fieldPathDescriptor(desc, key.FieldPath.segments)
+	if fd.Kind() == protoreflect.StringKind && o.normalize != nil {
+		return lo, hi
+	}
+	sign := 1
+	if key.Descending {
+		sign = -1
+	}
+	// at returns the position of the first resource whose key is >= v, or
+	// > v if strict, in the order of the index.
+	at := func(v protoreflect.Value, strict bool) int {
+		return sort.Search(len(idx.items), func(i int) bool {
+			k, _ := getFieldPathValue(idx.items[i].ProtoReflect(), key.FieldPath.segments)
+			c := sign * compareValues(k, v)
+			return c > 0 || (c == 0 && !strict)
+		})
+	}
+
+	for _, factor := range conjuncts(f.Expression) {
+		r := literalRestriction(desc, factor, key.FieldPath)
+		if r == nil {
+			continue
+		}
+		v, ok := literalValue(fd, r.Arg.Comparable.Member.Value)
+		if !ok {
+			continue
+		}
+		op := r.Comparator
+		if key.Descending {
+			op = flipComparator(op)
+		}
+		switch op {
+		case "=":
+			lo, hi = max(lo, at(v, false)), min(hi, at(v, true))
+		case ">":
+			lo = max(lo, at(v, true))
+		case ">=":
+			lo = max(lo, at(v, false))
+		case "<":
+			hi = min(hi, at(v, false))
+		case "<=":
+			hi = min(hi, at(v, true))
+		}
+	}
+	return lo, max(lo, hi)
+}
+
+// pathHasPresence reports whether any field along the path of segments in
+// desc has explicit presence.
+func pathHasPresence(desc protoreflect.MessageDescriptor, segments []string) bool {
+	for _, seg := range segments {
+		fd := desc.Fields().ByName(protoreflect.Name(seg))
+		if fd == nil || fd.HasPresence() {
+			return true
+		}
+		desc = fd.Message()
+	}
+	return false
+}
+
+// literalRestriction returns the restriction of factor if it compares the
+// field at path to a literal with =, <, <=, > or >=.
+func literalRestriction(desc protoreflect.MessageDescriptor, factor *Factor, path FieldPath) *Restriction {
+	if len(factor.Terms) != 1 || factor.Terms[0].Negated || factor.Terms[0].Simple.Restriction == nil {
+		return nil
+	}
+	r := factor.Terms[0].Simple.Restriction
+	switch r.Comparator {
+	case "=", "<", "<=", ">", ">=":
+	default:
+		return nil
+	}
+	if r.Comparable == nil || r.Comparable.Member == nil || r.Arg == nil || r.Arg.Comparable == nil {
+		return nil
+	}
+	lhs, arg := r.Comparable.Member, r.Arg.Comparable.Member
+	if arg == nil || len(arg.Fields) > 0 || desc.Fields().ByName(protoreflect.Name(arg.Value)) != nil {
+		return nil
+	}
+	if NewFieldPath(append([]string{lhs.Value}, lhs.Fields...)...).String() != path.String() {
+		return nil
+	}
+	return r
+}
+
+// flipComparator returns the comparator that holds for the operands of op
+// swapped.
+func flipComparator(op string) string {
+	switch op {
+	case "<":
+		return ">"
+	case "<=":
+		return ">="
+	case ">":
+		return "<"
+	case ">=":
+		return "<="
+	}
+	return op
+}
+
+// literalValue parses s as a value of fd, reporting whether a restriction of
+// fd to s compares the field with the parsed value, as ProtoFilter does.
+func literalValue(fd protoreflect.FieldDescriptor, s string) (protoreflect.Value, bool) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(s), true
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(s)
+		return protoreflect.ValueOfBool(b), err == nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, err := strconv.ParseInt(s, 10, 32)
+		return protoreflect.ValueOfInt32(int32(n)), err == nil
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := strconv.ParseInt(s, 10, 64)
+		return protoreflect.ValueOfInt64(n), err == nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		n, err := strconv.ParseUint(s, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(n)), err == nil
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := strconv.ParseUint(s, 10, 64)
+		return protoreflect.ValueOfUint64(n), err == nil
+	case protoreflect.DoubleKind:
+		n, err := strconv.ParseFloat(s, 64)
+		return protoreflect.ValueOfFloat64(n), err == nil
+	}
+	// ProtoFilter compares float fields as doubles, which rounding the
+	// literal to a float would not preserve.
+	return protoreflect.Value{}, false
+}
@@ -0,0 +1,171 @@
+package query
+
+import (
+	"maps"
+	"slices"
+)
+
+// IndexReport describes how the composite indexes declared on the columns
+// of a table, with ColumnBuilder.InIndex, serve a query.
+type IndexReport struct {
+	// Hint is the name of the index best serving the query, e.g. for an
+	// index hint in the generated SQL, or empty if no index serves it.
+	Hint string
+	// Covered reports whether Hint includes every column the query filters
+	// or sorts on, so the database can select rows from the index alone.
+	Covered bool
+	// Sorted reports whether reading Hint yields rows in the requested
+	// order, so the database need not sort them.
+	Sorted bool
+	// Unindexed holds the field paths of the columns the query filters or
+	// sorts on that are in no index.
+	Unindexed []string
+}
+
+// AnalyzeIndexes reports whether the query generated for filter and order
+// by WhereClause and OrderByClause is served by one of the table's indexes,
+// and which, so operators can catch unindexed filterable and sortable
+// fields before launch.
+//
+// An index serves a query if its leading key columns are restricted with =
+// in the top-level conjunction of the filter, or if the first key column
+// after those is filtered on or is the first column sorted on. Among the
+// indexes serving the query, those covering all its columns are preferred,
+// then those yielding the requested order, then those with the most leading
+// equality columns. Sort directions must be uniform for the order to be
+// yielded by an index, which databases scan forward or backward.
+//
+// Field paths without a column are ignored. A query filtering and sorting
+// on no column needs no index; its report is the zero value.
+func (t *Table) AnalyzeIndexes(filter *Filter, order []OrderBy) IndexReport {
+	equal := make(map[*Column]bool)
+	used := make(map[*Column]bool)
+	var usedOrder []*Column
+	if filter != nil && filter.Expression != nil {
+		for _, f := range conjuncts(filter.Expression) {
+			if c := t.equalityColumn(f); c != nil {
+				equal[c] = true
+			}
+		}
+		t.collectColumns(filter.Expression, used)
+	}
+	uniform := true
+	for _, o := range order {
+		c := t.columnByFieldPath[o.FieldPath.String()]
+		if c == nil {
+			continue
+		}
+		used[c] = true
+		uniform = uniform && o.Descending == order[0].Descending
+		if !equal[c] {
+			// Columns fixed by equality do not affect the order.
+			usedOrder = append(usedOrder, c)
+		}
+	}
+	if len(used) == 0 {
+		return IndexReport{}
+	}
+
+	report := IndexReport{}
+	for _, c := range t.columns {
+		if used[c] && len(c.indexes) == 0 {
+			report.Unindexed = append(report.Unindexed, c.fieldPath.String())
+		}
+	}
+
+	type candidate struct {
+		name            string
+		covered, sorted bool
+		prefix          int
+	}
+	var best *candidate
+	better := func(a, b *candidate) bool {
+		if a.covered != b.covered {
+			return a.covered
+		}
+		if a.sorted != b.sorted {
+			return a.sorted
+		}
+		if a.prefix != b.prefix {
+			return a.prefix > b.prefix
+		}
+		return a.name < b.name
+	}
+	for _, name := range slices.Sorted(maps.Keys(t.indexes)) {
+		keys := t.indexes[name]
+		prefix := 0
+		for prefix < len(keys) && equal[keys[prefix]] {
+			prefix++
+		}
+		rest := keys[prefix:]
+		sorted := uniform && len(usedOrder) <= len(rest)
+		for i, c := range usedOrder {
+			sorted = sorted && rest[i] == c
+		}
+		sorted = sorted && len(usedOrder) > 0
+		if prefix == 0 && !sorted && (len(rest) == 0 || !used[rest[0]]) {
+			continue
+		}
+		covered := true
+		for c := range used {
+			covered = covered && slices.Contains(keys, c)
+		}
+		cand := &candidate{name: name, covered: covered, sorted: sorted, prefix: prefix}
+		if best == nil || better(cand, best) {
+			best = cand
+		}
+	}
+	if best != nil {
+		report.Hint = best.name
+		report.Covered = best.covered
+		report.Sorted = best.sorted
+	}
+	return report
+}
+
+// equalityColumn returns the column f restricts with =, or nil if f is not
+// such a restriction.
+func (t *Table) equalityColumn(f *Factor) *Column {
+	if len(f.Terms) != 1 || f.Terms[0].Negated || f.Terms[0].Simple.Restriction == nil {
+		return nil
+	}
+	r := f.Terms[0].Simple.Restriction
+	if r.Comparator != "=" || r.Comparable == nil || r.Comparable.Member == nil || len(r.Comparable.Member.Fields) > 0 {
+		return nil
+	}
+	c := t.columnByFieldPath[NewFieldPath(r.Comparable.Member.Value).String()]
+	if c == nil || c.array || c.keyValue {
+		return nil
+	}
+	return c
+}
+
+// collectColumns adds the columns referenced by e to used. Restrictions
+// without a field reference the columns filtered implicitly.
+func (t *Table) collectColumns(e *Expression, used map[*Column]bool) {
+	for _, seq := range e.Sequences {
+		for _, f := range seq.Factors {
+			for _, term := range f.Terms {
+				if term.Simple.Composite != nil {
+					t.collectColumns(term.Simple.Composite, used)
+					continue
+				}
+				r := term.Simple.Restriction
+				if r == nil || r.Comparable == nil || r.Comparable.Member == nil {
+					continue
+				}
+				if r.Comparator == "" {
+					for _, c := range t.columns {
+						if c.implicitFilter {
+							used[c] = true
+						}
+					}
+					continue
+				}
+				if c := t.columnByFieldPath[NewFieldPath(r.Comparable.Member.Value).String()]; c != nil {
+					used[c] = true
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package query_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hxtk/aip/query"
+)
+
+func indexTable() *query.Table {
+	return query.NewTable().WithColumns(
+		query.NewColumn().WithFieldPath("name").WithDatabaseName("name").Filterable().Sortable().InIndex("pk", 0).Build(),
+		query.NewColumn().WithFieldPath("format").WithDatabaseName("format").Filterable().Sortable().InIndex("by_format_time", 0).Build(),
+		query.NewColumn().WithFieldPath("create_time").WithDatabaseName("create_time").Filterable().Sortable().InIndex("by_format_time", 1).InIndex("by_time", 0).Build(),
+		query.NewColumn().WithFieldPath("title").WithDatabaseName("title").Filterable().Sortable().FilterableImplicitly().Build(),
+	).Build()
+}
+
+func analyze(t *testing.T, table *query.Table, filter, orderBy string) query.IndexReport {
+	t.Helper()
+	f, err := query.ParseFilter(filter)
+	require.NoError(t, err)
+	order, err := query.ParseOrderBy(orderBy)
+	require.NoError(t, err)
+	return table.AnalyzeIndexes(f, order)
+}
+
+func TestAnalyzeIndexes(t *testing.T) {
+	table := indexTable()
+	cases := []struct {
+		filter, orderBy string
+		want            query.IndexReport
+	}{
+		{"", "", query.IndexReport{}},
+		{"name = books/1", "", query.IndexReport{Hint: "pk", Covered: true}},
+		{"format = EBOOK", "create_time desc", query.IndexReport{Hint: "by_format_time", Covered: true, Sorted: true}},
+		{"format = EBOOK", "format, create_time", query.IndexReport{Hint: "by_format_time", Covered: true, Sorted: true}},
+		{"format = EBOOK", "create_time, name desc", query.IndexReport{Hint: "by_format_time"}},
+		{"", "create_time", query.IndexReport{Hint: "by_time", Covered: true, Sorted: true}},
+		{`create_time > "2020-01-01T00:00:00Z"`, "", query.IndexReport{Hint: "by_time", Covered: true}},
+		{"format != EBOOK", "create_time", query.IndexReport{Hint: "by_format_time", Covered: true}},
+		{"format = EBOOK title = Dune", "", query.IndexReport{Hint: "by_format_time", Unindexed: []string{"title"}}},
+		{"title = Dune", "", query.IndexReport{Unindexed: []string{"title"}}},
+		{"Dune", "", query.IndexReport{Unindexed: []string{"title"}}},
+		{"", "title", query.IndexReport{Unindexed: []string{"title"}}},
+		{"name = books/1 OR title = Dune", "", query.IndexReport{Hint: "pk", Unindexed: []string{"title"}}},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.want, analyze(t, table, c.filter, c.orderBy), "filter %q, order_by %q", c.filter, c.orderBy)
+	}
+}
+
+func TestInIndexValidation(t *testing.T) {
+	require.PanicsWithValue(t, "no column at position 0 of index i", func() {
+		query.NewTable().WithColumns(
+			query.NewColumn().WithFieldPath("a").WithDatabaseName("a").InIndex("i", 1).Build(),
+		).Build()
+	})
+	require.PanicsWithValue(t, "multiple columns at position 0 of index i", func() {
+		query.NewTable().WithColumns(
+			query.NewColumn().WithFieldPath("a").WithDatabaseName("a").InIndex("i", 0).Build(),
+			query.NewColumn().WithFieldPath("b").WithDatabaseName("b").InIndex("i", 0).Build(),
+		).Build()
+	})
+}
@@ -0,0 +1,195 @@
+package query_test
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/unicode/norm"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/query"
+)
+
+func mustOrder(t *testing.T, text string) []query.OrderBy {
+	t.Helper()
+	order, err := query.ParseOrderBy(text)
+	require.NoError(t, err)
+	return order
+}
+
+func TestIndexList(t *testing.T) {
+	index, err := query.NewIndex[testpb.Book](
+		mustOrder(t, "page_count"),
+		mustOrder(t, "title desc, page_count"),
+		mustOrder(t, "author.family_name"),
+	)
+	require.NoError(t, err)
+
+	r := rand.New(rand.NewPCG(1, 2))
+	titles := []string{"Dune", "Emma", "Neuromancer", "Persuasion", "The Talisman"}
+	var books []*testpb.Book
+	for range 200 {
+		book := &testpb.Book{
+			Name:       fmt.Sprintf("books/%03d", r.IntN(1000)),
+			Title:      titles[r.IntN(len(titles))],
+			CreateTime: timestamppb.New(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, -r.IntN(30))),
+		}
+		if r.IntN(4) > 0 {
+			book.PageCount = proto.Int32(int32(r.IntN(50)))
+		}
+		if r.IntN(4) > 0 {
+			book.Author = &testpb.Author{FamilyName: titles[r.IntN(len(titles))]}
+		}
+		index.Put(book)
+		books = slices.DeleteFunc(books, func(b *testpb.Book) bool { return b.GetName() == book.GetName() })
+		books = append(books, book)
+	}
+	require.Equal(t, len(books), index.Len())
+
+	filters := []string{
+		"",
+		"page_count = 7",
+		"page_count >= 10 AND page_count < 20",
+		"page_count > 40 OR title = Dune",
+		"title = Emma",
+		`title < "N" AND page_count <= 25`,
+		"author.family_name = Dune",
+		"NOT page_count > 10",
+		`page_count = "3.5"`,
+		// Unset optional fields match as their zero value.
+		"page_count = 0",
+		"page_count < 2",
+		"page_count <= 0 OR title = Dune",
+		`author.family_name = ""`,
+	}
+	orders := []string{
+		"",
+		"name",
+		"name desc",
+		"page_count, name",
+		"page_count desc, name desc",
+		"title, page_count desc, name",
+		"create_time desc, name",
+	}
+	for _, filterText := range filters {
+		for _, orderText := range orders {
+			for _, pageSize := range []int{0, 1, 7} {
+				t.Run(fmt.Sprintf("%q by %q pages of %d", filterText, orderText, pageSize), func(t *testing.T) {
+					f, err := query.ParseFilter(filterText)
+					require.NoError(t, err)
+					order := mustOrder(t, orderText)
+					if orderText == "" {
+						order = mustOrder(t, "name")
+					}
+
+					want, err := query.FilterSlice(books, f)
+					require.NoError(t, err)
+					compare, err := query.Comparer[*testpb.Book](order)
+					require.NoError(t, err)
+					slices.SortStableFunc(want, compare)
+
+					var got []*testpb.Book
+					var cursor *testpb.Book
+					for {
+						page, more, err := index.List(f, mustOrder(t, orderText), cursor, pageSize)
+						require.NoError(t, err)
+						got = append(got, page...)
+						if !more {
+							break
+						}
+						require.Len(t, page, pageSize)
+						cursor = page[len(page)-1]
+					}
+					require.Equal(t, names(want), names(got))
+				})
+			}
+		}
+	}
+}
+
+func names(books []*testpb.Book) []string {
+	out := make([]string, len(books))
+	for i, b := range books {
+		out[i] = b.GetName()
+	}
+	return out
+}
+
+func TestIndexPutDelete(t *testing.T) {
+	index, err := query.NewIndex[testpb.Book](mustOrder(t, "title"))
+	require.NoError(t, err)
+
+	index.Put(&testpb.Book{Name: "books/1", Title: "Emma"})
+	index.Put(&testpb.Book{Name: "books/2", Title: "Dune"})
+	index.Put(&testpb.Book{Name: "books/1", Title: "Neuromancer"})
+	require.Equal(t, 2, index.Len())
+
+	book, ok := index.Get("books/1")
+	require.True(t, ok)
+	require.Equal(t, "Neuromancer", book.GetTitle())
+
+	page, more, err := index.List(nil, mustOrder(t, "title"), nil, 0)
+	require.NoError(t, err)
+	require.False(t, more)
+	require.Equal(t, []string{"books/2", "books/1"}, names(page))
+
+	require.True(t, index.Delete("books/2"))
+	require.False(t, index.Delete("books/2"))
+	page, _, err = index.List(mustParse(t, "title = Dune"), mustOrder(t, "title"), nil, 0)
+	require.NoError(t, err)
+	require.Empty(t, page)
+}
+
+// TestIndexList_Options checks that the range read from an index agrees
+// with the filter as compiled with options.
+func TestIndexList_Options(t *testing.T) {
+	index, err := query.NewIndex[testpb.Book](mustOrder(t, "title"))
+	require.NoError(t, err)
+	index.Put(&testpb.Book{Name: "books/1", Title: "Dune"})
+	index.Put(&testpb.Book{Name: "books/2", Title: "Emma"})
+
+	page, _, err := index.List(mustParse(t, "title = @t"), mustOrder(t, "title"), nil, 0,
+		query.WithBindings(map[string]string{"t": "Dune"}))
+	require.NoError(t, err)
+	require.Equal(t, []string{"books/1"}, names(page))
+
+	// "Amélie" stored decomposed, and filtered composed.
+	index.Put(&testpb.Book{Name: "books/3", Title: norm.NFD.String("Amélie")})
+	page, _, err = index.List(mustParse(t, `title = "`+norm.NFC.String("Amélie")+`"`), mustOrder(t, "title"), nil, 0,
+		query.WithNormalization(norm.NFC))
+	require.NoError(t, err)
+	require.Equal(t, []string{"books/3"}, names(page))
+
+	// A matcher or resolver decides restrictions of the name, not the field.
+	page, _, err = index.List(mustParse(t, "title = Zebra"), mustOrder(t, "title"), nil, 0,
+		query.WithMatcher("title", func(context.Context, proto.Message, string, string) (bool, error) { return true, nil }))
+	require.NoError(t, err)
+	require.Len(t, page, 3)
+	page, _, err = index.List(mustParse(t, "title = Zebra"), mustOrder(t, "title"), nil, 0,
+		query.WithResolver("title", func(context.Context, proto.Message) (any, error) { return "Zebra", nil }))
+	require.NoError(t, err)
+	require.Len(t, page, 3)
+}
+
+func TestIndexErrors(t *testing.T) {
+	_, err := query.NewIndex[testpb.Book](mustOrder(t, "authors"))
+	require.Error(t, err, "repeated fields cannot be indexed")
+
+	_, err = query.NewIndex[testpb.Author]()
+	require.ErrorContains(t, err, "no string field name")
+
+	index, err := query.NewIndex[testpb.Book]()
+	require.NoError(t, err)
+	_, _, err = index.List(mustParse(t, "nope.field = 1"), nil, nil, 0)
+	require.Error(t, err)
+	var fv *query.FieldViolationError
+	_, _, err = index.List(nil, mustOrder(t, "nope"), nil, 0)
+	require.ErrorAs(t, err, &fv)
+}
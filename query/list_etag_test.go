@@ -0,0 +1,54 @@
+package query_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hxtk/aip/aiptest"
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/query"
+)
+
+func listETag(t *testing.T, filter, orderBy, pageToken string, page []*testpb.Book) string {
+	t.Helper()
+	f, err := query.ParseFilter(filter)
+	require.NoError(t, err)
+	order, err := query.ParseOrderBy(orderBy)
+	require.NoError(t, err)
+	etag, err := query.ListETag(f, order, pageToken, page)
+	require.NoError(t, err)
+	return etag
+}
+
+func TestListETag(t *testing.T) {
+	books := aiptest.Books()
+	etag := listETag(t, "title = Dune AND format = PAPERBACK", "title", "", books[:2])
+	require.Regexp(t, `^W/"[A-Za-z0-9_-]+"$`, etag)
+
+	require.Equal(t, etag, listETag(t, "title = Dune AND format = PAPERBACK", "title", "", aiptest.Books()[:2]), "identical requests over unchanged data")
+	require.Equal(t, etag, listETag(t, `format = PAPERBACK title = "Dune"`, "title", "", books[:2]), "equivalent filters")
+
+	require.NotEqual(t, etag, listETag(t, "title = Emma", "title", "", books[:2]), "filter")
+	require.NotEqual(t, etag, listETag(t, "title = Dune AND format = PAPERBACK", "title desc", "", books[:2]), "order")
+	require.NotEqual(t, etag, listETag(t, "title = Dune AND format = PAPERBACK", "title", "token", books[:2]), "page token")
+	require.NotEqual(t, etag, listETag(t, "title = Dune AND format = PAPERBACK", "title", "", books[:1]), "page length")
+
+	changed := aiptest.Books()[:2]
+	changed[1].Title = "Emma (2nd edition)"
+	require.NotEqual(t, etag, listETag(t, "title = Dune AND format = PAPERBACK", "title", "", changed), "page contents")
+
+	empty, err := query.ListETag[*testpb.Book](nil, nil, "", nil)
+	require.NoError(t, err)
+	require.Equal(t, listETag(t, "", "", "", nil), empty, "a nil filter is empty")
+}
+
+func TestETagMatches(t *testing.T) {
+	etag := `W/"abc"`
+	require.True(t, query.ETagMatches(`W/"abc"`, etag))
+	require.True(t, query.ETagMatches(`"abc"`, etag), "weak comparison ignores the weak prefix")
+	require.True(t, query.ETagMatches(`"xyz", W/"abc"`, etag))
+	require.True(t, query.ETagMatches(`*`, etag))
+	require.False(t, query.ETagMatches(`W/"xyz"`, etag))
+	require.False(t, query.ETagMatches(``, etag))
+}
@@ -0,0 +1,125 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hxtk/aip/names"
+)
+
+// parentWildcard is the ID of a parent standing for every resource of its
+// collection, to list across parents (AIP-159).
+const parentWildcard = "-"
+
+// ParentClause creates a Standard SQL boolean expression selecting the rows
+// that are children of parent, the parent field of an AIP-132 List request,
+// according to the resource patterns of the table (see
+// TableBuilder.WithResourcePatterns).
+//
+// If the table has a column for the field path "parent", the clause compares
+// it with parent, e.g. (db_parent = @p_0). Otherwise it matches the prefix of
+// the column for the field path "name", e.g. (db_name LIKE @p_0) with
+// "publishers/acme/books/%". A parent ID of "-" matches every parent, e.g.
+// "publishers/-". The children of a top-level collection, whose parent is
+// empty, are selected with (TRUE).
+//
+// A parent matching none of the patterns is a *FieldViolationError of
+// ParentField, so a request can never fall back to an unscoped query.
+func (t *Table) ParentClause(parent, parameterPrefix string) (string, []QueryParameter, error) {
+	w := &whereClause{
+		table:      t,
+		namePrefix: parameterPrefix,
+	}
+	clause, err := w.parentQuery(parent)
+	if err != nil {
+		return "", []QueryParameter{}, err
+	}
+	return clause, w.parameters, nil
+}
+
+// ScopedWhereClause is like WhereClause, but also restricts the rows to the
+// children of parent, as ParentClause. Handlers of List methods of nested
+// collections should use it instead of WhereClause, so that the scope of
+// the request cannot be forgotten.
+func (t *Table) ScopedWhereClause(parent string, filter *Filter, parameterPrefix string) (string, []QueryParameter, error) {
+	w := &whereClause{
+		table:      t,
+		namePrefix: parameterPrefix,
+	}
+	clause, err := w.parentQuery(parent)
+	if err != nil {
+		return "", []QueryParameter{}, err
+	}
+	if filter == nil || filter.Expression == nil {
+		return clause, w.parameters, nil
+	}
+	filterClause, err := w.expressionQuery(filter.Expression)
+	if err != nil {
+		return "", []QueryParameter{}, err
+	}
+	clause = "(" + clause + " AND " + filterClause + ")"
+	if err := t.limits.Check(clause, w.parameters); err != nil {
+		return "", []QueryParameter{}, newFieldViolation(FilterField, fmt.Errorf("filter is too complex: %w", err))
+	}
+	t.reportWarnings(filter, nil)
+	return clause, w.parameters, nil
+}
+
+// parentQuery returns the SQL expression selecting the children of parent.
+// The returned string is an injection-safe SQL expression.
+func (w *whereClause) parentQuery(parent string) (string, error) {
+	if len(w.table.patterns) == 0 {
+		return "", fmt.Errorf("table has no resource patterns to scope queries by parent")
+	}
+	for _, pattern := range w.table.patterns {
+		parentPattern := names.ParentPattern(pattern)
+		if parentPattern == "" && parent == "" {
+			return "(TRUE)", nil
+		}
+		if !names.MatchPattern(parentPattern, parent) {
+			continue
+		}
+		if column := w.table.columnByFieldPath[NewFieldPath("parent").String()]; column != nil {
+			if !hasParentWildcard(parent) {
+				return fmt.Sprintf("(%s = %s)", column.databaseName, w.bind(parent)), nil
+			}
+			return fmt.Sprintf("(%s LIKE %s)", column.databaseName, w.bind(likeParent(parent))), nil
+		}
+		column := w.table.columnByFieldPath[NewFieldPath("name").String()]
+		if column == nil {
+			return "", fmt.Errorf("table has neither a parent nor a name column to scope queries by parent")
+		}
+		segments := strings.Split(pattern, "/")
+		collection := segments[len(segments)-2]
+		prefix := quoteLike(collection) + "/%"
+		if parent != "" {
+			prefix = likeParent(parent) + "/" + prefix
+		}
+		return fmt.Sprintf("(%s LIKE %s)", column.databaseName, w.bind(prefix)), nil
+	}
+	return "", newFieldViolation(ParentField, fmt.Errorf("%q is not a valid parent, expected a name matching the parent of %s", parent, strings.Join(w.table.patterns, " or ")))
+}
+
+// hasParentWildcard reports whether any ID of parent is the wildcard "-".
+func hasParentWildcard(parent string) bool {
+	for _, segment := range strings.Split(parent, "/") {
+		if segment == parentWildcard {
+			return true
+		}
+	}
+	return false
+}
+
+// likeParent returns the LIKE pattern matching parent, where each wildcard
+// ID matches any ID.
+func likeParent(parent string) string {
+	segments := strings.Split(parent, "/")
+	for i, segment := range segments {
+		if segment == parentWildcard {
+			segments[i] = "%"
+		} else {
+			segments[i] = quoteLike(segment)
+		}
+	}
+	return strings.Join(segments, "/")
+}
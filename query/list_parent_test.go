@@ -0,0 +1,78 @@
+package query_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	aip "github.com/hxtk/aip/query"
+)
+
+func TestParentClause(t *testing.T) {
+	byName := aip.NewTable().WithColumns(
+		aip.NewColumn().WithFieldPath("name").WithDatabaseName("db_name").Build(),
+		aip.NewColumn().WithFieldPath("title").WithDatabaseName("db_title").Filterable().Build(),
+	).WithResourcePatterns("publishers/{publisher}/books/{book}", "books/{book}").Build()
+	byParent := aip.NewTable().WithColumns(
+		aip.NewColumn().WithFieldPath("parent").WithDatabaseName("db_parent").Build(),
+	).WithResourcePatterns("publishers/{publisher}/books/{book}").Build()
+
+	tests := []struct {
+		table  *aip.Table
+		parent string
+		sql    string
+		value  string
+	}{
+		{byName, "publishers/acme", "(db_name LIKE @p_0)", "publishers/acme/books/%"},
+		{byName, "publishers/100%_off", "(db_name LIKE @p_0)", `publishers/100\%\_off/books/%`},
+		{byName, "publishers/-", "(db_name LIKE @p_0)", "publishers/%/books/%"},
+		{byName, "", "(TRUE)", ""},
+		{byParent, "publishers/acme", "(db_parent = @p_0)", "publishers/acme"},
+		{byParent, "publishers/-", "(db_parent LIKE @p_0)", "publishers/%"},
+	}
+	for _, tc := range tests {
+		sql, params, err := tc.table.ParentClause(tc.parent, "p_")
+		require.NoError(t, err, tc.parent)
+		require.Equal(t, tc.sql, sql, tc.parent)
+		if tc.value == "" {
+			require.Empty(t, params)
+		} else {
+			require.Equal(t, []aip.QueryParameter{{Name: "p_0", Value: tc.value}}, params)
+		}
+	}
+
+	for _, parent := range []string{"shelves/1", "publishers/acme/books/dune", "publishers/"} {
+		_, _, err := byName.ParentClause(parent, "p_")
+		var fv *aip.FieldViolationError
+		require.True(t, errors.As(err, &fv), "ParentClause(%q) = %v, want a field violation", parent, err)
+		require.Equal(t, aip.ParentField, fv.FieldViolations()[0].GetField())
+	}
+	_, _, err := byParent.ParentClause("", "p_")
+	require.Error(t, err, "an empty parent of a nested collection must not select every row")
+
+	_, _, err = aip.NewTable().Build().ParentClause("publishers/acme", "p_")
+	require.ErrorContains(t, err, "no resource patterns")
+}
+
+func TestScopedWhereClause(t *testing.T) {
+	table := aip.NewTable().WithColumns(
+		aip.NewColumn().WithFieldPath("name").WithDatabaseName("db_name").Build(),
+		aip.NewColumn().WithFieldPath("title").WithDatabaseName("db_title").Filterable().Build(),
+	).WithResourcePatterns("publishers/{publisher}/books/{book}").Build()
+
+	sql, params, err := table.ScopedWhereClause("publishers/acme", mustParse(t, "title = Dune"), "p_")
+	require.NoError(t, err)
+	require.Equal(t, "((db_name LIKE @p_0) AND (db_title = @p_1))", sql)
+	require.Equal(t, []aip.QueryParameter{
+		{Name: "p_0", Value: "publishers/acme/books/%"},
+		{Name: "p_1", Value: "Dune"},
+	}, params)
+
+	sql, _, err = table.ScopedWhereClause("publishers/acme", nil, "p_")
+	require.NoError(t, err)
+	require.Equal(t, "(db_name LIKE @p_0)", sql)
+
+	_, _, err = table.ScopedWhereClause("", mustParse(t, "title = Dune"), "p_")
+	require.Error(t, err)
+}
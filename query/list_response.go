@@ -0,0 +1,79 @@
+package query
+
+import (
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ListResponse returns a new AIP-132 List response holding items in its
+// repeated field of their message type and nextPageToken in its
+// next_page_token field. If totalSize is non-negative and Resp has an
+// integer total_size field, totalSize is set there too; pass a negative
+// totalSize when it is not known.
+//
+// Example:
+//
+//	return query.ListResponse[pb.ListBooksResponse](books, next, -1)
+//
+// ListResponse returns an error if Resp has no string next_page_token field,
+// or not exactly one repeated field of type M. The items are not copied.
+func ListResponse[S any, Resp interface {
+	proto.Message
+	*S
+}, I any, M interface {
+	proto.Message
+	*I
+}](items []M, nextPageToken string, totalSize int) (Resp, error) {
+	resp := Resp(new(S))
+	r := resp.ProtoReflect()
+	desc := r.Descriptor()
+
+	nextToken, err := stringField(desc, "next_page_token")
+	if err != nil {
+		return nil, err
+	}
+	var item M = new(I)
+	field, err := itemsField(desc, item.ProtoReflect().Descriptor())
+	if err != nil {
+		return nil, err
+	}
+
+	list := r.Mutable(field).List()
+	for _, item := range items {
+		list.Append(protoreflect.ValueOfMessage(item.ProtoReflect()))
+	}
+	r.Set(nextToken, protoreflect.ValueOfString(nextPageToken))
+	if fd := desc.Fields().ByName("total_size"); fd != nil && !fd.IsList() && totalSize >= 0 {
+		switch fd.Kind() {
+		case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+			r.Set(fd, protoreflect.ValueOfInt32(int32(min(totalSize, math.MaxInt32))))
+		case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+			r.Set(fd, protoreflect.ValueOfInt64(int64(totalSize)))
+		}
+	}
+	return resp, nil
+}
+
+// itemsField returns the only repeated field of desc whose elements are item
+// messages.
+func itemsField(desc, item protoreflect.MessageDescriptor) (protoreflect.FieldDescriptor, error) {
+	var found protoreflect.FieldDescriptor
+	fields := desc.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !fd.IsList() || fd.Message() == nil || fd.Message().FullName() != item.FullName() {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("message %s has more than one repeated %s field", desc.FullName(), item.FullName())
+		}
+		found = fd
+	}
+	if found == nil {
+		return nil, fmt.Errorf("message %s has no repeated %s field", desc.FullName(), item.FullName())
+	}
+	return found, nil
+}
@@ -0,0 +1,33 @@
+package query_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/query"
+)
+
+func TestListResponse(t *testing.T) {
+	books := makeBooks(3)
+
+	resp, err := query.ListResponse[testpb.ListBooksResponse](books, "next", 10)
+	require.NoError(t, err)
+	require.True(t, proto.Equal(&testpb.ListBooksResponse{
+		Books:         books,
+		NextPageToken: "next",
+		TotalSize:     10,
+	}, resp))
+
+	resp, err = query.ListResponse[testpb.ListBooksResponse](books[:0], "", -1)
+	require.NoError(t, err)
+	require.True(t, proto.Equal(&testpb.ListBooksResponse{}, resp))
+
+	_, err = query.ListResponse[testpb.ListBooksResponse]([]*testpb.Author{{}}, "", -1)
+	require.ErrorContains(t, err, "no repeated test.Author field")
+
+	_, err = query.ListResponse[testpb.ImportBooksResponse](books, "", -1)
+	require.ErrorContains(t, err, "no string field next_page_token")
+}
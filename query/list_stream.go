@@ -0,0 +1,65 @@
+package query
+
+import (
+	"context"
+	"iter"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// StreamList serves a server-streaming List method: it reads items from
+// storage one at a time and sends each that matches filter to send, after
+// prune has trimmed it to the fields the client asked for. A nil filter
+// matches every item and a nil prune sends items whole.
+//
+// Example, with a connect handler:
+//
+//	filter, err := query.ProtoFilterCtx[pb.Book](f)
+//	...
+//	return query.StreamList(ctx, store.Books(ctx, parent), stream.Send, filter, func(b *pb.Book) error {
+//	    return masks.PruneMessage(b, mask)
+//	})
+//
+// prune is passed a clone of each item, so items may be shared with other
+// readers, e.g. by an Index.
+//
+// StreamList does not read ahead of send, so a client reading slowly, whose
+// stream is blocked by flow control, slows reading from storage rather than
+// buffering items in memory. It stops at the first error of items, filter,
+// prune or send, or when ctx is done, returning that error; stopping the
+// iteration lets items release its storage cursor.
+func StreamList[M proto.Message](
+	ctx context.Context,
+	items iter.Seq2[M, error],
+	send func(M) error,
+	filter func(context.Context, M) (bool, error),
+	prune func(M) error,
+) error {
+	for item, err := range items {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if filter != nil {
+			ok, err := filter(ctx, item)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+		}
+		if prune != nil {
+			item = proto.CloneOf(item)
+			if err := prune(item); err != nil {
+				return err
+			}
+		}
+		if err := send(item); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
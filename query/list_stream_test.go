@@ -0,0 +1,108 @@
+package query_test
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/masks"
+	"github.com/hxtk/aip/query"
+)
+
+// bookStore returns an iterator over books that records how many it has
+// read and whether it was released, as a storage cursor would.
+func bookStore(books []*testpb.Book, read *int, released *bool) iter.Seq2[*testpb.Book, error] {
+	return func(yield func(*testpb.Book, error) bool) {
+		defer func() { *released = true }()
+		for _, b := range books {
+			*read++
+			if !yield(b, nil) {
+				return
+			}
+		}
+	}
+}
+
+func TestStreamList(t *testing.T) {
+	books := []*testpb.Book{
+		{Name: "books/1", Title: "Dune"},
+		{Name: "books/2", Title: "Emma"},
+		{Name: "books/3", Title: "Dune Messiah"},
+	}
+	filter, err := query.ProtoFilterCtx[testpb.Book](mustParse(t, `title:Dune`))
+	require.NoError(t, err)
+	mask, err := masks.New((&testpb.Book{}).ProtoReflect().Descriptor(), masks.ModeRead, "name")
+	require.NoError(t, err)
+
+	var read int
+	var released bool
+	var sent []string
+	err = query.StreamList(context.Background(), bookStore(books, &read, &released), func(b *testpb.Book) error {
+		// Items are read no further ahead than they are sent.
+		require.Equal(t, b.GetName(), books[read-1].GetName())
+		require.Empty(t, b.GetTitle(), "items are pruned to the mask")
+		sent = append(sent, b.GetName())
+		return nil
+	}, filter, func(b *testpb.Book) error {
+		return masks.PruneMessage(b, mask)
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"books/1", "books/3"}, sent)
+	require.True(t, released)
+	require.Equal(t, "Dune", books[0].GetTitle(), "stored items are not pruned")
+
+	// Without a filter or prune, every item is sent whole.
+	var all []*testpb.Book
+	err = query.StreamList(context.Background(), bookStore(books, &read, &released), func(b *testpb.Book) error {
+		all = append(all, b)
+		return nil
+	}, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	require.Equal(t, "Emma", all[1].GetTitle())
+	require.Same(t, books[1], all[1])
+}
+
+func TestStreamList_Stops(t *testing.T) {
+	books := []*testpb.Book{{Name: "books/1"}, {Name: "books/2"}, {Name: "books/3"}}
+
+	// Cancellation stops reading and releases the storage cursor.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var read int
+	var released bool
+	err := query.StreamList(ctx, bookStore(books, &read, &released), func(*testpb.Book) error {
+		cancel()
+		return nil
+	}, nil, nil)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, 2, read)
+	require.True(t, released)
+
+	// So does an error sending, e.g. when the client disconnects.
+	errSend := errors.New("send")
+	read, released = 0, false
+	err = query.StreamList(context.Background(), bookStore(books, &read, &released), func(*testpb.Book) error {
+		return errSend
+	}, nil, nil)
+	require.ErrorIs(t, err, errSend)
+	require.Equal(t, 1, read)
+	require.True(t, released)
+
+	// Errors of the storage iterator and of the filter are returned.
+	errRead := errors.New("read")
+	err = query.StreamList(context.Background(), func(yield func(*testpb.Book, error) bool) {
+		yield(nil, errRead)
+	}, func(*testpb.Book) error { return nil }, nil, nil)
+	require.ErrorIs(t, err, errRead)
+
+	errFilter := errors.New("filter")
+	err = query.StreamList(context.Background(), bookStore(books, &read, &released), func(*testpb.Book) error {
+		return nil
+	}, func(context.Context, *testpb.Book) (bool, error) { return false, errFilter }, nil)
+	require.ErrorIs(t, err, errFilter)
+}
@@ -0,0 +1,87 @@
+package query_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/query"
+)
+
+func nullZeroTable() *query.Table {
+	return query.NewTable().WithColumns(
+		query.NewColumn().WithFieldPath("name").WithDatabaseName("db_name").Filterable().Sortable().Build(),
+		query.NewColumn().WithFieldPath("title").WithDatabaseName("db_title").NullIsZero("''").Filterable().Sortable().Build(),
+		query.NewColumn().WithFieldPath("page_count").WithDatabaseName("db_page_count").NullIsZero("0").Nullable().Sortable().Build(),
+		query.NewColumn().WithFieldPath("create_time").WithDatabaseName("db_create_time").
+			NullIsZero("TIMESTAMP '1970-01-01 00:00:00+00'").Sortable().Build(),
+	).Build()
+}
+
+func TestNullIsZero_Filter(t *testing.T) {
+	table := nullZeroTable()
+	tests := []struct {
+		filter string
+		want   string
+	}{
+		{`title = ""`, "(COALESCE(db_title, '') = @p_0)"},
+		{`title != Dune`, "(COALESCE(db_title, '') <> @p_0)"},
+		{`title:Du*`, "(COALESCE(db_title, '') LIKE @p_0)"},
+		{`title:*`, "(COALESCE(db_title, '') <> '')"},
+		{`name:*`, "(db_name IS NOT NULL)"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.filter, func(t *testing.T) {
+			where, _, err := table.WhereClause(mustParse(t, tc.filter), "p_")
+			require.NoError(t, err)
+			require.Equal(t, tc.want, where)
+		})
+	}
+}
+
+func TestNullIsZero_Order(t *testing.T) {
+	table := nullZeroTable()
+	order, err := table.ParseOrder("page_count desc, title, name")
+	require.NoError(t, err)
+	clause, err := table.OrderByClause(order)
+	require.NoError(t, err)
+	// NULLs sort as zero, so they need not be placed first or last.
+	require.Equal(t, "ORDER BY COALESCE(db_page_count, 0) DESC, COALESCE(db_title, ''), db_name\n", clause)
+
+	doc, err := table.Document((&testpb.Book{}).ProtoReflect().Descriptor(), nil)
+	require.NoError(t, err)
+	require.False(t, doc.Fields[2].NullsFirst)
+}
+
+func TestNullIsZero_Seek(t *testing.T) {
+	table := nullZeroTable()
+
+	// An unset cursor field seeks from the zero value, as Comparer orders
+	// the unset field and NULL rows alike.
+	order, err := table.ParseOrder("page_count desc, name")
+	require.NoError(t, err)
+	where, params, err := table.SeekClause(&testpb.Book{Name: "books/1"}, order, "p_")
+	require.NoError(t, err)
+	require.Equal(t, "((COALESCE(db_page_count, 0) < @p_0) OR (COALESCE(db_page_count, 0) = @p_0 AND db_name > @p_1))", where)
+	require.Equal(t, []query.QueryParameter{{Name: "p_0", Value: int64(0)}, {Name: "p_1", Value: "books/1"}}, params)
+
+	_, params, err = table.SeekClause(&testpb.Book{Name: "books/1", PageCount: proto.Int32(12)}, order, "p_")
+	require.NoError(t, err)
+	require.Equal(t, int64(12), params[0].Value)
+
+	order, err = table.ParseOrder("create_time, name")
+	require.NoError(t, err)
+	where, params, err = table.SeekClause(&testpb.Book{Name: "books/1"}, order, "p_")
+	require.NoError(t, err)
+	require.Equal(t, "((COALESCE(db_create_time, TIMESTAMP '1970-01-01 00:00:00+00'), db_name) > (@p_0, @p_1))", where)
+	require.Equal(t, time.Unix(0, 0).UTC(), params[0].Value)
+
+	created := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	_, params, err = table.SeekClause(&testpb.Book{Name: "books/1", CreateTime: timestamppb.New(created)}, order, "p_")
+	require.NoError(t, err)
+	require.Equal(t, created, params[0].Value)
+}
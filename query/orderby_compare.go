@@ -1,7 +1,10 @@
 package query
 
 import (
+	"bytes"
+	"cmp"
 	"fmt"
+	"slices"
 	"strings"
 
 	"google.golang.org/protobuf/proto"
@@ -10,13 +13,62 @@ import (
 
 // Comparer returns a comparator function for proto messages based on orderBy.
 // The returned func(a, b) returns <0 if a < b, 0 if equal, >0 if a > b.
-func Comparer[M proto.Message](orderBy []OrderBy) (func(a, b M) int, error) {
+//
+// If orderBy is not valid for M, the error is of type *FieldViolationError.
+func Comparer[M proto.Message](orderBy []OrderBy, opts ...CompareOption) (func(a, b M) int, error) {
+	o := &compareOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
 	// Validate orderBy against M's descriptor (same as in Less).
 	var zero M
-	desc := zero.ProtoReflect().Descriptor()
-	for _, ob := range orderBy {
+	return comparer[M](zero.ProtoReflect().Descriptor(), orderBy, o)
+}
+
+// DynamicComparer is like Comparer, but for messages whose type is only
+// known at runtime from desc, e.g. dynamicpb messages built from a descriptor
+// set. The returned function must only be called with messages of type desc.
+func DynamicComparer(desc protoreflect.MessageDescriptor, orderBy []OrderBy, opts ...CompareOption) (func(a, b proto.Message) int, error) {
+	o := &compareOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return comparer[proto.Message](desc, orderBy, o)
+}
+
+// comparer returns a comparator function for messages of type desc based on
+// orderBy.
+func comparer[M proto.Message](desc protoreflect.MessageDescriptor, orderBy []OrderBy, o *compareOptions) (func(a, b M) int, error) {
+	// enumRanks[i] maps enum numbers to their sort rank when orderBy[i] is an
+	// enum field ordered by name.
+	enumRanks := make([]map[protoreflect.EnumNumber]int, len(orderBy))
+	// custom[i] is the custom comparator of orderBy[i], if any.
+	custom := make([]FieldComparator, len(orderBy))
+	for i, ob := range orderBy {
 		if err := validateFieldPath(desc, ob.FieldPath.segments); err != nil {
-			return nil, fmt.Errorf("invalid orderBy field %s: %w", ob.FieldPath.canonical, err)
+			return nil, newFieldViolation(OrderByField, unsupportedFieldError{fmt.Errorf("invalid orderBy field %s: %w", ob.FieldPath.canonical, err)})
+		}
+		enumOrder := o.enumOrder
+		if o.table != nil {
+			col, err := o.table.SortableColumnByFieldPath(ob.FieldPath)
+			if err != nil {
+				return nil, newFieldViolation(OrderByField, err)
+			}
+			if col.enumDesc != nil {
+				enumOrder = col.enumOrder
+			}
+			custom[i] = col.comparator
+		}
+		if fn, ok := o.comparators[ob.FieldPath.String()]; ok {
+			custom[i] = fn
+		}
+		if custom[i] != nil {
+			continue
+		}
+		fd := fieldPathDescriptor(desc, ob.FieldPath.segments)
+		if fd.Enum() != nil && enumOrder == EnumOrderByName {
+			enumRanks[i] = enumNameRanks(fd.Enum())
 		}
 	}
 
@@ -24,11 +76,18 @@ func Comparer[M proto.Message](orderBy []OrderBy) (func(a, b M) int, error) {
 		am := a.ProtoReflect()
 		bm := b.ProtoReflect()
 
-		for _, ob := range orderBy {
+		for i, ob := range orderBy {
 			av, _ := getFieldPathValue(am, ob.FieldPath.segments)
 			bv, _ := getFieldPathValue(bm, ob.FieldPath.segments)
 
-			cmp := compareValues(av, bv)
+			var cmp int
+			if fn := custom[i]; fn != nil && av.IsValid() && bv.IsValid() {
+				cmp = fn(av, bv)
+			} else if ranks := enumRanks[i]; ranks != nil {
+				cmp = compareEnumRanks(ranks, av, bv)
+			} else {
+				cmp = compareValues(av, bv)
+			}
 			if cmp == 0 {
 				continue
 			}
@@ -44,8 +103,10 @@ func Comparer[M proto.Message](orderBy []OrderBy) (func(a, b M) int, error) {
 
 // Less returns a comparator function for proto messages based on orderBy.
 // The returned func(a, b) reports whether a < b according to orderBy.
-func Less[M proto.Message](orderBy []OrderBy) (func(a, b M) bool, error) {
-	cmp, err := Comparer[M](orderBy)
+//
+// If orderBy is not valid for M, the error is of type *FieldViolationError.
+func Less[M proto.Message](orderBy []OrderBy, opts ...CompareOption) (func(a, b M) bool, error) {
+	cmp, err := Comparer[M](orderBy, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -55,10 +116,117 @@ func Less[M proto.Message](orderBy []OrderBy) (func(a, b M) bool, error) {
 	}, nil
 }
 
+// CompareOption configures the comparators returned by Comparer and Less.
+type CompareOption func(*compareOptions)
+
+type compareOptions struct {
+	table       *Table
+	enumOrder   EnumOrder
+	comparators map[string]FieldComparator
+}
+
+// FieldComparator compares two values of a field, returning <0 if a sorts
+// before b, 0 if they tie and >0 if a sorts after b. Both values are set:
+// unset fields sort first, as they do without a custom comparator.
+type FieldComparator func(a, b protoreflect.Value) int
+
+// WithSortableColumns additionally validates that every field in the order
+// is a sortable column of t, so in-memory sorting accepts exactly the orders
+// that t.OrderByClause would accept.
+func WithSortableColumns(t *Table) CompareOption {
+	return func(o *compareOptions) {
+		o.table = t
+	}
+}
+
+// WithEnumOrder sets how enum fields are ordered. The default is
+// EnumOrderByNumber.
+//
+// When combined with WithSortableColumns, a column declared with
+// ColumnBuilder.Enum uses the column's EnumOrder instead.
+func WithEnumOrder(order EnumOrder) CompareOption {
+	return func(o *compareOptions) {
+		o.enumOrder = order
+	}
+}
+
+// WithFieldComparator sorts the field at path with fn instead of its natural
+// order, e.g. to compare semantic versions or to collate display names for a
+// locale. It takes precedence over a comparator declared on the column with
+// ColumnBuilder.WithComparator.
+//
+// For SQL to sort the same way, the column must declare an equivalent
+// ordering with ColumnBuilder.WithComparator and
+// ColumnBuilder.WithOrderExpression.
+func WithFieldComparator(path FieldPath, fn FieldComparator) CompareOption {
+	return func(o *compareOptions) {
+		if o.comparators == nil {
+			o.comparators = make(map[string]FieldComparator)
+		}
+		o.comparators[path.String()] = fn
+	}
+}
+
+// enumNameRanks maps each value of the enum to the rank of its name in
+// lexicographic order.
+func enumNameRanks(desc protoreflect.EnumDescriptor) map[protoreflect.EnumNumber]int {
+	values := desc.Values()
+	names := make([]string, 0, values.Len())
+	for i := 0; i < values.Len(); i++ {
+		names = append(names, string(values.Get(i).Name()))
+	}
+	slices.Sort(names)
+
+	ranks := make(map[protoreflect.EnumNumber]int, len(names))
+	for i := 0; i < values.Len(); i++ {
+		v := values.Get(i)
+		rank, _ := slices.BinarySearch(names, string(v.Name()))
+		ranks[v.Number()] = rank
+	}
+	return ranks
+}
+
+// compareEnumRanks compares two enum values by rank. Unset values and numbers
+// without a rank sort after all ranked values, matching the ELSE branch of the
+// SQL expression generated for such columns.
+func compareEnumRanks(ranks map[protoreflect.EnumNumber]int, a, b protoreflect.Value) int {
+	rank := func(v protoreflect.Value) int {
+		if !v.IsValid() {
+			return len(ranks)
+		}
+		if r, ok := ranks[v.Enum()]; ok {
+			return r
+		}
+		return len(ranks)
+	}
+	ar, br := rank(a), rank(b)
+	switch {
+	case ar < br:
+		return -1
+	case ar > br:
+		return 1
+	}
+	return 0
+}
+
+// fieldPathDescriptor returns the descriptor of the field addressed by
+// segments, which must already be valid for desc.
+func fieldPathDescriptor(desc protoreflect.MessageDescriptor, segments []string) protoreflect.FieldDescriptor {
+	var fd protoreflect.FieldDescriptor
+	for _, seg := range segments {
+		fd = desc.Fields().ByName(protoreflect.Name(seg))
+		if fd.Message() != nil {
+			desc = fd.Message()
+		}
+	}
+	return fd
+}
+
 // validateFieldPath walks the descriptor to make sure segments are valid.
 func validateFieldPath(desc protoreflect.MessageDescriptor, segments []string) error {
+	var fd protoreflect.FieldDescriptor
 	for _, seg := range segments {
-		fd := desc.Fields().ByName(protoreflect.Name(seg))
+		fd = desc.Fields().ByName(protoreflect.Name(seg))
 		if fd == nil {
 			return fmt.Errorf("field %s not found on %s", seg, desc.FullName())
 		}
@@ -69,10 +237,18 @@ func validateFieldPath(desc protoreflect.MessageDescriptor, segments []string) e
 			desc = fd.Message()
 		}
 	}
+	// Message fields are only sortable if they have a well-defined order.
+	if fd != nil && fd.Message() != nil && fd.Message().FullName() != timestampName {
+		return fmt.Errorf("cannot sort on message field %s", fd.FullName())
+	}
 	return nil
 }
 
 // getFieldPathValue walks down nested fields along segments.
+//
+// A field with explicit presence that is not set, including one beneath a
+// missing message, yields the invalid Value, which compareValues treats as
+// null.
 func getFieldPathValue(m protoreflect.Message, segments []string) (protoreflect.Value, error) {
 	for i, seg := range segments {
 		fd := m.Descriptor().Fields().ByName(protoreflect.Name(seg))
@@ -81,6 +257,9 @@ func getFieldPathValue(m protoreflect.Message, segments []string) (protoreflect.
 		}
 		val := m.Get(fd)
 		if i == len(segments)-1 {
+			if fd.HasPresence() && !m.Has(fd) {
+				return protoreflect.Value{}, nil
+			}
 			return val, nil
 		}
 		if fd.Message() == nil || !m.Has(fd) {
@@ -93,7 +272,19 @@ func getFieldPathValue(m protoreflect.Message, segments []string) (protoreflect.
 
 // compareValues performs an ordering comparison between two protoreflect.Values.
 // Returns -1 if a < b, 0 if equal, +1 if a > b.
+//
+// Invalid values represent unset fields and sort before all set values,
+// matching NULLS FIRST for ascending order in Standard SQL.
+//
+// Floating point NaN sorts before all other numbers and equal to itself, as
+// in Standard SQL. Bytes compare lexicographically.
 func compareValues(a, b protoreflect.Value) int {
+	if !b.IsValid() {
+		if a.IsValid() {
+			return 1
+		}
+		return 0
+	}
 	switch av := a.Interface().(type) {
 	case int32:
 		bv := b.Interface().(int32)
@@ -131,9 +322,24 @@ func compareValues(a, b protoreflect.Value) int {
 			return 1
 		}
 		return 0
+	case protoreflect.EnumNumber:
+		bv := b.Interface().(protoreflect.EnumNumber)
+		switch {
+		case av < bv:
+			return -1
+		case av > bv:
+			return 1
+		}
+		return 0
+	case float32:
+		return cmp.Compare(av, b.Interface().(float32))
+	case float64:
+		return cmp.Compare(av, b.Interface().(float64))
 	case string:
 		bv := b.Interface().(string)
 		return strings.Compare(av, bv)
+	case []byte:
+		return bytes.Compare(av, b.Interface().([]byte))
 	case bool:
 		bv := b.Interface().(bool)
 		switch {
@@ -144,14 +350,45 @@ func compareValues(a, b protoreflect.Value) int {
 		default:
 			return 1
 		}
+	case protoreflect.Message:
+		if av.Descriptor().FullName() == timestampName {
+			return compareTimestamps(av, b.Message())
+		}
+		panic(fmt.Sprintf("unsupported message type %s in compareValues", av.Descriptor().FullName()))
 	case nil:
 		if b.Interface() != nil {
 			return -1
-		} else {
-			return 1
 		}
+		return 0
 	default:
-		// TODO: extend with other scalar types (enums, bytes, timestamps, etc.)
 		panic(fmt.Sprintf("unsupported type %T in compareValues", av))
 	}
 }
+
+// timestampName is the full name of google.protobuf.Timestamp.
+const timestampName protoreflect.FullName = "google.protobuf.Timestamp"
+
+// timestampParts returns the seconds and nanos of a google.protobuf.Timestamp.
+// An empty message reads as the Unix epoch.
+func timestampParts(m protoreflect.Message) (int64, int32) {
+	fields := m.Descriptor().Fields()
+	return m.Get(fields.ByName("seconds")).Int(), int32(m.Get(fields.ByName("nanos")).Int())
+}
+
+// compareTimestamps orders two google.protobuf.Timestamp messages by seconds,
+// then nanos.
+func compareTimestamps(a, b protoreflect.Message) int {
+	as, an := timestampParts(a)
+	bs, bn := timestampParts(b)
+	switch {
+	case as < bs:
+		return -1
+	case as > bs:
+		return 1
+	case an < bn:
+		return -1
+	case an > bn:
+		return 1
+	}
+	return 0
+}
@@ -1,9 +1,17 @@
 package query
 
 import (
+	"cmp"
+	"errors"
+	"strings"
 	"testing"
 
-	"github.com/hxtk/aip/internal/testpb"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/hxtk/aip/aiptest/testpb"
 )
 
 func TestLess(t *testing.T) {
@@ -101,3 +109,316 @@ func TestNonexistentFieldInSortKeys(t *testing.T) {
 		t.Logf("ParseOrderBy('no_such_field') rejected: %v", err)
 	}
 }
+
+func TestOrderByFieldViolations(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func() error
+	}{
+		{
+			name: "syntax error",
+			build: func() error {
+				_, err := ParseOrderBy("title,,")
+				return err
+			},
+		},
+		{
+			name: "duplicate field",
+			build: func() error {
+				_, err := ParseOrderBy("title, title desc")
+				return err
+			},
+		},
+		{
+			name: "unknown field",
+			build: func() error {
+				_, err := Comparer[*testpb.Book]([]OrderBy{{FieldPath: NewFieldPath("no_such_field")}})
+				return err
+			},
+		},
+		{
+			name: "repeated field",
+			build: func() error {
+				_, err := Less[*testpb.Book]([]OrderBy{{FieldPath: NewFieldPath("authors")}})
+				return err
+			},
+		},
+		{
+			name: "unsortable column",
+			build: func() error {
+				table := NewTable().WithColumns(
+					NewColumn().WithFieldPath("title").WithDatabaseName("title").Build(),
+				).Build()
+				_, err := table.OrderByClause([]OrderBy{{FieldPath: NewFieldPath("title")}})
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.build()
+			var fv *FieldViolationError
+			if !errors.As(err, &fv) {
+				t.Fatalf("got error %v (%T), want *FieldViolationError", err, err)
+			}
+			violations := fv.FieldViolations()
+			if len(violations) != 1 {
+				t.Fatalf("got %d violations, want 1", len(violations))
+			}
+			if got := violations[0].GetField(); got != OrderByField {
+				t.Errorf("violation field = %q, want %q", got, OrderByField)
+			}
+			if got := violations[0].GetDescription(); got != err.Error() {
+				t.Errorf("violation description = %q, want %q", got, err.Error())
+			}
+		})
+	}
+}
+
+func TestComparerWithSortableColumns(t *testing.T) {
+	table := NewTable().WithColumns(
+		NewColumn().WithFieldPath("title").WithDatabaseName("title").Sortable().Build(),
+		NewColumn().WithFieldPath("name").WithDatabaseName("name").Filterable().Build(),
+	).Build()
+
+	sortable, err := ParseOrderBy("title desc")
+	if err != nil {
+		t.Fatalf("ParseOrderBy failed: %v", err)
+	}
+	if _, err := Comparer[*testpb.Book](sortable, WithSortableColumns(table)); err != nil {
+		t.Errorf("Comparer rejected sortable column: %v", err)
+	}
+
+	unsortable, err := ParseOrderBy("title, name")
+	if err != nil {
+		t.Fatalf("ParseOrderBy failed: %v", err)
+	}
+	if _, err := Comparer[*testpb.Book](unsortable); err != nil {
+		t.Errorf("Comparer without table rejected valid message field: %v", err)
+	}
+	_, err = Less[*testpb.Book](unsortable, WithSortableColumns(table))
+	var fv *FieldViolationError
+	if !errors.As(err, &fv) {
+		t.Fatalf("got error %v, want *FieldViolationError for unsortable column", err)
+	}
+}
+
+func TestComparerEnumOrder(t *testing.T) {
+	paperback := &testpb.Book{Format: testpb.Format_PAPERBACK}
+	ebook := &testpb.Book{Format: testpb.Format_EBOOK}
+	unknown := &testpb.Book{Format: testpb.Format(99)}
+
+	order, err := ParseOrderBy("format")
+	if err != nil {
+		t.Fatalf("ParseOrderBy failed: %v", err)
+	}
+
+	byNumber, err := Less[*testpb.Book](order)
+	if err != nil {
+		t.Fatalf("Less failed: %v", err)
+	}
+	if !byNumber(paperback, ebook) {
+		t.Errorf("by number: expected PAPERBACK(1) < EBOOK(3)")
+	}
+
+	byName, err := Less[*testpb.Book](order, WithEnumOrder(EnumOrderByName))
+	if err != nil {
+		t.Fatalf("Less failed: %v", err)
+	}
+	if !byName(ebook, paperback) {
+		t.Errorf("by name: expected EBOOK < PAPERBACK")
+	}
+	if !byName(paperback, unknown) {
+		t.Errorf("by name: expected unknown values to sort last")
+	}
+
+	table := NewTable().WithColumns(
+		NewColumn().WithFieldPath("format").WithDatabaseName("format").
+			Enum(testpb.Format(0).Descriptor(), EnumOrderByName).Sortable().Build(),
+	).Build()
+	fromTable, err := Less[*testpb.Book](order, WithSortableColumns(table))
+	if err != nil {
+		t.Fatalf("Less failed: %v", err)
+	}
+	if !fromTable(ebook, paperback) {
+		t.Errorf("table column order: expected EBOOK < PAPERBACK")
+	}
+}
+
+func TestComparerFieldComparator(t *testing.T) {
+	// byLength sorts strings by length, then lexicographically.
+	byLength := func(a, b protoreflect.Value) int {
+		if c := cmp.Compare(len(a.String()), len(b.String())); c != 0 {
+			return c
+		}
+		return strings.Compare(a.String(), b.String())
+	}
+	short := &testpb.Book{Title: "Zen"}
+	long := &testpb.Book{Title: "Anathem"}
+
+	order, err := ParseOrderBy("title")
+	if err != nil {
+		t.Fatalf("ParseOrderBy failed: %v", err)
+	}
+	natural, err := Less[*testpb.Book](order)
+	if err != nil {
+		t.Fatalf("Less failed: %v", err)
+	}
+	if !natural(long, short) {
+		t.Errorf("natural order: expected Anathem < Zen")
+	}
+
+	custom, err := Less[*testpb.Book](order, WithFieldComparator(NewFieldPath("title"), byLength))
+	if err != nil {
+		t.Fatalf("Less failed: %v", err)
+	}
+	if !custom(short, long) {
+		t.Errorf("custom order: expected Zen < Anathem")
+	}
+
+	table := NewTable().WithColumns(
+		NewColumn().WithFieldPath("title").WithDatabaseName("title").
+			WithComparator(byLength).Sortable().Build(),
+	).Build()
+	fromTable, err := Less[*testpb.Book](order, WithSortableColumns(table))
+	if err != nil {
+		t.Fatalf("Less failed: %v", err)
+	}
+	if !fromTable(short, long) {
+		t.Errorf("column comparator: expected Zen < Anathem")
+	}
+	overridden, err := Less[*testpb.Book](order, WithSortableColumns(table),
+		WithFieldComparator(NewFieldPath("title"), func(a, b protoreflect.Value) int {
+			return strings.Compare(a.String(), b.String())
+		}))
+	if err != nil {
+		t.Fatalf("Less failed: %v", err)
+	}
+	if !overridden(long, short) {
+		t.Errorf("option comparator: expected Anathem < Zen")
+	}
+
+	// The comparator only sees set values; unset fields sort first.
+	order, err = ParseOrderBy("page_count")
+	if err != nil {
+		t.Fatalf("ParseOrderBy failed: %v", err)
+	}
+	reversed, err := Less[*testpb.Book](order, WithFieldComparator(NewFieldPath("page_count"), func(a, b protoreflect.Value) int {
+		return cmp.Compare(b.Int(), a.Int())
+	}))
+	if err != nil {
+		t.Fatalf("Less failed: %v", err)
+	}
+	few := &testpb.Book{PageCount: proto.Int32(10)}
+	many := &testpb.Book{PageCount: proto.Int32(500)}
+	if !reversed(many, few) {
+		t.Errorf("expected custom comparator to order 500 before 10")
+	}
+	if !reversed(&testpb.Book{}, many) {
+		t.Errorf("expected unset page_count to sort first")
+	}
+}
+
+func TestComparerTimestamp(t *testing.T) {
+	early := &testpb.Book{CreateTime: &timestamppb.Timestamp{Seconds: 100, Nanos: 5}}
+	late := &testpb.Book{CreateTime: &timestamppb.Timestamp{Seconds: 100, Nanos: 6}}
+	unset := &testpb.Book{}
+
+	order, err := ParseOrderBy("create_time desc")
+	if err != nil {
+		t.Fatalf("ParseOrderBy failed: %v", err)
+	}
+	less, err := Less[*testpb.Book](order)
+	if err != nil {
+		t.Fatalf("Less failed: %v", err)
+	}
+	if !less(late, early) {
+		t.Errorf("expected later timestamp first in descending order")
+	}
+	if !less(early, unset) {
+		t.Errorf("expected unset timestamp to sort last in descending order")
+	}
+	if less(early, early) {
+		t.Errorf("expected equal timestamps to be unordered")
+	}
+
+	order, err = ParseOrderBy("author")
+	if err != nil {
+		t.Fatalf("ParseOrderBy failed: %v", err)
+	}
+	if _, err := Less[*testpb.Book](order); err == nil {
+		t.Errorf("expected error sorting on a message field")
+	}
+}
+
+func TestComparerExplicitPresence(t *testing.T) {
+	zero, one := int32(0), int32(1)
+	unset := &testpb.Book{Name: "unset"}
+	setZero := &testpb.Book{Name: "zero", PageCount: &zero}
+	setOne := &testpb.Book{Name: "one", PageCount: &one}
+
+	order, err := ParseOrderBy("page_count")
+	if err != nil {
+		t.Fatalf("ParseOrderBy failed: %v", err)
+	}
+	cmp, err := Comparer[*testpb.Book](order)
+	if err != nil {
+		t.Fatalf("Comparer failed: %v", err)
+	}
+	if cmp(unset, setZero) >= 0 {
+		t.Errorf("expected unset optional field to sort before zero")
+	}
+	if cmp(setZero, setOne) >= 0 {
+		t.Errorf("expected 0 < 1")
+	}
+	if cmp(unset, &testpb.Book{}) != 0 {
+		t.Errorf("expected unset fields to compare equal")
+	}
+
+	order, err = ParseOrderBy("page_count desc")
+	if err != nil {
+		t.Fatalf("ParseOrderBy failed: %v", err)
+	}
+	less, err := Less[*testpb.Book](order)
+	if err != nil {
+		t.Fatalf("Less failed: %v", err)
+	}
+	if !less(setZero, unset) {
+		t.Errorf("expected unset optional field to sort last in descending order")
+	}
+}
+
+func TestDynamicComparer(t *testing.T) {
+	desc := (&testpb.Book{}).ProtoReflect().Descriptor()
+	order, err := ParseOrderBy("author.family_name, title desc")
+	if err != nil {
+		t.Fatalf("ParseOrderBy failed: %v", err)
+	}
+	cmp, err := DynamicComparer(desc, order)
+	if err != nil {
+		t.Fatalf("DynamicComparer failed: %v", err)
+	}
+
+	dynamic := func(b *testpb.Book) proto.Message {
+		m := dynamicpb.NewMessage(desc)
+		raw, _ := proto.Marshal(b)
+		if err := proto.Unmarshal(raw, m); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	austen := dynamic(&testpb.Book{Title: "Emma", Author: &testpb.Author{FamilyName: "Austen"}})
+	herbert := dynamic(&testpb.Book{Title: "Dune", Author: &testpb.Author{FamilyName: "Herbert"}})
+	if cmp(austen, herbert) >= 0 {
+		t.Errorf("expected Austen < Herbert")
+	}
+	if cmp(dynamic(&testpb.Book{}), austen) >= 0 {
+		t.Errorf("expected a missing author to sort first")
+	}
+
+	order, _ = ParseOrderBy("authors")
+	if _, err := DynamicComparer(desc, order); err == nil {
+		t.Errorf("expected an error sorting on a repeated field")
+	}
+}
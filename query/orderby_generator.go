@@ -16,6 +16,8 @@ package query
 
 import (
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
 )
 
@@ -39,12 +41,45 @@ func MergeWithDefaultOrder(defaultOrder []OrderBy, order []OrderBy) []OrderBy {
 	return result
 }
 
+// ParseOrder parses the AIP-132 order_by text of a request and merges it
+// with the default order of the table (see TableBuilder.WithDefaultOrder)
+// using MergeWithDefaultOrder. The result is the order to pass to
+// OrderByClause and SeekClause and to mint page tokens with.
+//
+// Errors are of type *FieldViolationError: the text may not be valid, name
+// fields that are not sortable, or name more fields than allowed (see
+// TableBuilder.WithMaxOrderFields).
+func (t *Table) ParseOrder(orderBy string) ([]OrderBy, error) {
+	order, err := ParseOrderBy(orderBy)
+	if err != nil {
+		return nil, err
+	}
+	if t.maxOrderFields > 0 && len(order) > t.maxOrderFields {
+		return nil, newFieldViolation(OrderByField, fmt.Errorf("order_by names %d fields, at most %d are allowed", len(order), t.maxOrderFields))
+	}
+	for _, o := range order {
+		if _, err := t.SortableColumnByFieldPath(o.FieldPath); err != nil {
+			return nil, newFieldViolation(OrderByField, err)
+		}
+	}
+	return MergeWithDefaultOrder(t.defaultOrder, order), nil
+}
+
+// DefaultOrder returns the default order of the table, or nil if it has
+// none.
+func (t *Table) DefaultOrder() []OrderBy {
+	return slices.Clone(t.defaultOrder)
+}
+
 // OrderByClause returns a Standard SQL Order by clause, including
 // "ORDER BY" and trailing new line (if an order is specified).
 // If no order is specified, returns "".
 //
 // The returned order clause is safe against SQL injection; only
 // strings appearing from Table appear in the output.
+//
+// If order is not valid for the table, the error is of type
+// *FieldViolationError.
 func (t *Table) OrderByClause(order []OrderBy) (string, error) {
 	if len(order) == 0 {
 		return "", nil
@@ -58,17 +93,82 @@ func (t *Table) OrderByClause(order []OrderBy) (string, error) {
 		}
 		column, err := t.SortableColumnByFieldPath(o.FieldPath)
 		if err != nil {
-			return "", err
+			return "", newFieldViolation(OrderByField, err)
 		}
 		if _, ok := seenColumns[column.databaseName]; ok {
-			return "", fmt.Errorf("field appears in order_by multiple times: %q", o.FieldPath.String())
+			return "", newFieldViolation(OrderByField, fmt.Errorf("field appears in order_by multiple times: %q", o.FieldPath.String()))
 		}
 		seenColumns[column.databaseName] = struct{}{}
-		result.WriteString(column.databaseName)
+		if column.comparator != nil && column.orderExpr == "" {
+			return "", newFieldViolation(OrderByField, fmt.Errorf("field %q is sorted with a custom comparator, which has no SQL order expression", o.FieldPath.String()))
+		}
+		result.WriteString(column.orderExpression())
 		if o.Descending {
 			result.WriteString(" DESC")
 		}
+		if column.nullable && column.nullZero == "" && !column.ranksEnum() {
+			if o.Descending {
+				result.WriteString(" NULLS LAST")
+			} else {
+				result.WriteString(" NULLS FIRST")
+			}
+		}
 	}
 	result.WriteString("\n")
+	if err := t.limits.Check(result.String(), nil); err != nil {
+		return "", newFieldViolation(OrderByField, fmt.Errorf("order_by is too complex: %w", err))
+	}
+	t.reportWarnings(nil, order)
 	return result.String(), nil
 }
+
+// ranksEnum reports whether the column is sorted on the rank of enum names.
+// The rank expression maps NULL to the rank of unknown values, so it is
+// never NULL.
+func (c *Column) ranksEnum() bool {
+	return c.enumDesc != nil && c.enumOrder == EnumOrderByName
+}
+
+// valueExpression returns the SQL expression of the value of the column's
+// field: the column itself, or, if NULL stands for the zero value of the
+// field, the column with NULL replaced by that zero value.
+//
+// The returned expression is safe against SQL injection; it is built only
+// from the database name and the declared zero value.
+func (c *Column) valueExpression() string {
+	if c.nullZero == "" {
+		return c.databaseName
+	}
+	return fmt.Sprintf("COALESCE(%s, %s)", c.databaseName, c.nullZero)
+}
+
+// orderExpression returns the SQL expression used to sort on the column.
+//
+// The returned expression is safe against SQL injection; it is built only
+// from the database name, the declared order expression and constants from
+// the enum descriptor.
+func (c *Column) orderExpression() string {
+	if c.orderExpr != "" {
+		return c.orderExpr
+	}
+	if !c.ranksEnum() {
+		return c.valueExpression()
+	}
+
+	ranks := enumNameRanks(c.enumDesc)
+	values := c.enumDesc.Values()
+	var b strings.Builder
+	b.WriteString("CASE ")
+	b.WriteString(c.valueExpression())
+	for i := 0; i < values.Len(); i++ {
+		num := values.Get(i).Number()
+		b.WriteString(" WHEN ")
+		b.WriteString(strconv.FormatInt(int64(num), 10))
+		b.WriteString(" THEN ")
+		b.WriteString(strconv.Itoa(ranks[num]))
+	}
+	b.WriteString(" ELSE ")
+	b.WriteString(strconv.Itoa(len(ranks)))
+	b.WriteString(" END")
+	return b.String()
+}
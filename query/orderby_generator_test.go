@@ -15,9 +15,14 @@
 package query
 
 import (
+	"errors"
+	"strings"
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/hxtk/aip/aiptest/testpb"
 
 	. "github.com/hxtk/aip/query/internal/assertions"
 )
@@ -31,6 +36,44 @@ func TestOrderByClause(t *testing.T) {
 			NewColumn().WithFieldPath("unsortable").WithDatabaseName("unsortable").Build(),
 		).Build()
 
+		Convey("Enum ordered by name", func() {
+			enumTable := NewTable().WithColumns(
+				NewColumn().WithFieldPath("format").WithDatabaseName("db_format").
+					Enum(testpb.Format(0).Descriptor(), EnumOrderByName).Sortable().Build(),
+				NewColumn().WithFieldPath("number").WithDatabaseName("db_number").
+					Enum(testpb.Format(0).Descriptor(), EnumOrderByNumber).Sortable().Build(),
+			).Build()
+			result, err := enumTable.OrderByClause([]OrderBy{
+				{
+					FieldPath:  NewFieldPath("format"),
+					Descending: true,
+				},
+				{
+					FieldPath: NewFieldPath("number"),
+				},
+			})
+			So(err, ShouldBeNil)
+			So(result, ShouldEqual, "ORDER BY CASE db_format WHEN 0 THEN 1 WHEN 1 THEN 3 WHEN 2 THEN 2 WHEN 3 THEN 0 ELSE 4 END DESC, db_number\n")
+		})
+		Convey("Custom comparator", func() {
+			byVersion := func(a, b protoreflect.Value) int { return strings.Compare(a.String(), b.String()) }
+			customTable := NewTable().WithColumns(
+				NewColumn().WithFieldPath("version").WithDatabaseName("db_version").
+					WithComparator(byVersion).Sortable().Build(),
+				NewColumn().WithFieldPath("display_name").WithDatabaseName("db_display_name").
+					WithComparator(byVersion).WithOrderExpression(`COLLATE(db_display_name, "und:ci")`).Sortable().Build(),
+			).Build()
+			_, err := customTable.OrderByClause([]OrderBy{
+				{FieldPath: NewFieldPath("version")},
+			})
+			So(err, ShouldErrLike, "field \"version\" is sorted with a custom comparator, which has no SQL order expression")
+
+			result, err := customTable.OrderByClause([]OrderBy{
+				{FieldPath: NewFieldPath("display_name"), Descending: true},
+			})
+			So(err, ShouldBeNil)
+			So(result, ShouldEqual, "ORDER BY COLLATE(db_display_name, \"und:ci\") DESC\n")
+		})
 		Convey("Empty order by", func() {
 			result, err := table.OrderByClause([]OrderBy{})
 			So(err, ShouldBeNil)
@@ -70,6 +113,7 @@ func TestOrderByClause(t *testing.T) {
 				},
 			})
 			So(err, ShouldErrLike, `no sortable field named "unsortable", valid fields are foo, bar, baz`)
+			So(errors.Is(err, ErrUnsupportedField), ShouldBeTrue)
 		})
 		Convey("Repeated field in order by", func() {
 			_, err := table.OrderByClause([]OrderBy{
@@ -82,6 +126,33 @@ func TestOrderByClause(t *testing.T) {
 			})
 			So(err, ShouldErrLike, `field appears in order_by multiple times: "foo"`)
 		})
+		Convey("Nullable columns", func() {
+			nullTable := NewTable().WithColumns(
+				NewColumn().WithFieldPath("author", "family_name").WithDatabaseName("a.family_name").Nullable().Sortable().Build(),
+				NewColumn().WithFieldPath("format").WithDatabaseName("db_format").
+					Enum(testpb.Format(0).Descriptor(), EnumOrderByName).Nullable().Sortable().Build(),
+				NewColumn().WithFieldPath("name").WithDatabaseName("db_name").Sortable().Build(),
+			).Build()
+			result, err := nullTable.OrderByClause([]OrderBy{
+				{FieldPath: NewFieldPath("author", "family_name")},
+				{FieldPath: NewFieldPath("name")},
+			})
+			So(err, ShouldBeNil)
+			So(result, ShouldEqual, "ORDER BY a.family_name NULLS FIRST, db_name\n")
+
+			result, err = nullTable.OrderByClause([]OrderBy{
+				{FieldPath: NewFieldPath("author", "family_name"), Descending: true},
+			})
+			So(err, ShouldBeNil)
+			So(result, ShouldEqual, "ORDER BY a.family_name DESC NULLS LAST\n")
+
+			// The rank of a NULL enum is that of unknown values.
+			result, err = nullTable.OrderByClause([]OrderBy{
+				{FieldPath: NewFieldPath("format")},
+			})
+			So(err, ShouldBeNil)
+			So(result, ShouldNotContainSubstring, "NULLS")
+		})
 	})
 }
 
@@ -131,3 +202,56 @@ func TestMergeWithDefaultOrder(t *testing.T) {
 		})
 	})
 }
+
+func TestParseOrder(t *testing.T) {
+	Convey("ParseOrder", t, func() {
+		table := NewTable().WithColumns(
+			NewColumn().WithFieldPath("create_time").WithDatabaseName("db_create_time").Sortable().Build(),
+			NewColumn().WithFieldPath("name").WithDatabaseName("db_name").Sortable().Build(),
+			NewColumn().WithFieldPath("title").WithDatabaseName("db_title").Sortable().Build(),
+			NewColumn().WithFieldPath("unsortable").WithDatabaseName("unsortable").Build(),
+		).WithDefaultOrder("create_time desc, name").WithMaxOrderFields(2).Build()
+
+		Convey("Empty order", func() {
+			result, err := table.ParseOrder("")
+			So(err, ShouldBeNil)
+			So(result, ShouldResemble, table.DefaultOrder())
+
+			sql, err := table.OrderByClause(result)
+			So(err, ShouldBeNil)
+			So(sql, ShouldEqual, "ORDER BY db_create_time DESC, db_name\n")
+		})
+		Convey("Order completed by the default", func() {
+			result, err := table.ParseOrder("title, create_time")
+			So(err, ShouldBeNil)
+			So(result, ShouldResemble, []OrderBy{
+				{FieldPath: NewFieldPath("title")},
+				{FieldPath: NewFieldPath("create_time")},
+				{FieldPath: NewFieldPath("name")},
+			})
+		})
+		Convey("Too many fields", func() {
+			_, err := table.ParseOrder("title, name, create_time")
+			So(err, ShouldErrLike, "at most 2 are allowed")
+			var fv *FieldViolationError
+			So(errors.As(err, &fv), ShouldBeTrue)
+			So(fv.FieldViolations()[0].GetField(), ShouldEqual, OrderByField)
+		})
+		Convey("Unsortable field", func() {
+			_, err := table.ParseOrder("unsortable")
+			So(err, ShouldErrLike, `no sortable field named "unsortable"`)
+		})
+		Convey("Syntax error", func() {
+			_, err := table.ParseOrder("title ascending")
+			So(err, ShouldErrLike, "syntax error")
+		})
+		Convey("No default order", func() {
+			result, err := NewTable().Build().ParseOrder("")
+			So(err, ShouldBeNil)
+			So(result, ShouldBeEmpty)
+		})
+		Convey("Invalid default order", func() {
+			So(func() { NewTable().WithDefaultOrder("unsortable").Build() }, ShouldPanic)
+		})
+	})
+}
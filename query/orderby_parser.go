@@ -82,6 +82,9 @@ type OrderBy struct {
 //
 // Some valid paths would be: foo, bar.foobar and
 // named_bars.`bar-key`.foobar.
+//
+// Field paths are made from their segments with NewFieldPath, or parsed
+// with ParseFieldPath, FieldPathsFromMask or FieldPathFromJSON.
 type FieldPath struct {
 	// The field path as its segments.
 	segments []string
@@ -126,6 +129,8 @@ func (f FieldPath) String() string {
 // ParseOrderBy parses an AIP-132 order_by list. The method validates the
 // syntax is correct and each identifier appears at most once, but
 // it does not validate the identifiers themselves are valid.
+//
+// Errors returned by ParseOrderBy are of type *FieldViolationError.
 func ParseOrderBy(text string) ([]OrderBy, error) {
 	// Empty order_by list.
 	if strings.Trim(text, " ") == "" {
@@ -134,7 +139,7 @@ func ParseOrderBy(text string) ([]OrderBy, error) {
 
 	expr, err := orderByParser.ParseString("", text)
 	if err != nil {
-		return nil, errors.Annotate(err, "syntax error").Err()
+		return nil, newFieldViolation(OrderByField, errors.Annotate(err, "syntax error").Err())
 	}
 
 	var result []OrderBy
@@ -148,7 +153,7 @@ func ParseOrderBy(text string) ([]OrderBy, error) {
 	uniqueFieldPaths := make(map[string]struct{})
 	for _, orderBy := range result {
 		if _, ok := uniqueFieldPaths[orderBy.FieldPath.String()]; ok {
-			return nil, errors.Reason("field appears multiple times: %q", orderBy.FieldPath).Err()
+			return nil, newFieldViolation(OrderByField, errors.Reason("field appears multiple times: %q", orderBy.FieldPath).Err())
 		}
 		uniqueFieldPaths[orderBy.FieldPath.String()] = struct{}{}
 	}
@@ -0,0 +1,58 @@
+package query
+
+import (
+	"slices"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// OrderBySplit is an order split between the database and memory by
+// Table.SplitOrderBy.
+type OrderBySplit struct {
+	// Pushed is the longest prefix of the order that the table can sort on,
+	// to be passed to OrderByClause.
+	Pushed []OrderBy
+	// Residual is the remainder of the order, applied to each page in memory
+	// by SortPage.
+	Residual []OrderBy
+}
+
+// Exact reports whether the database ordering alone yields the requested
+// order, i.e. whether there is no residual.
+func (s OrderBySplit) Exact() bool {
+	return len(s.Residual) == 0
+}
+
+// SplitOrderBy splits order into the longest prefix the table can sort on
+// and a residual suffix, which starts at the first field without a sortable
+// column.
+//
+// Sorting in memory is only correct within a page. Rows tied on every Pushed
+// field are ordered by Residual only if they are all on the same page, so
+// unless Exact reports true, the overall order is approximate across page
+// boundaries. Callers needing an exact order should reject such requests, or
+// make sure pages never split a group of rows tied on the Pushed fields.
+func (t *Table) SplitOrderBy(order []OrderBy) OrderBySplit {
+	for i, o := range order {
+		if _, err := t.SortableColumnByFieldPath(o.FieldPath); err != nil {
+			return OrderBySplit{Pushed: order[:i:i], Residual: order[i:]}
+		}
+	}
+	return OrderBySplit{Pushed: order}
+}
+
+// SortPage stably sorts a page of results fetched in the split's Pushed
+// order into the full order. It does nothing if the split is exact.
+//
+// See Table.SplitOrderBy for the limits of sorting in memory.
+func SortPage[M proto.Message](page []M, split OrderBySplit, opts ...CompareOption) error {
+	if split.Exact() {
+		return nil
+	}
+	cmp, err := Comparer[M](append(slices.Clip(split.Pushed), split.Residual...), opts...)
+	if err != nil {
+		return err
+	}
+	slices.SortStableFunc(page, cmp)
+	return nil
+}
@@ -0,0 +1,49 @@
+package query_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/query"
+)
+
+func TestSplitOrderBy(t *testing.T) {
+	table := query.NewTable().WithColumns(
+		query.NewColumn().WithFieldPath("format").WithDatabaseName("format").Sortable().Build(),
+		query.NewColumn().WithFieldPath("name").WithDatabaseName("name").Sortable().Build(),
+		query.NewColumn().WithFieldPath("title").WithDatabaseName("title").Filterable().Build(),
+	).Build()
+
+	order, err := query.ParseOrderBy("format desc, name")
+	require.NoError(t, err)
+	split := table.SplitOrderBy(order)
+	require.True(t, split.Exact())
+	require.Equal(t, order, split.Pushed)
+
+	order, err = query.ParseOrderBy("format desc, title, name")
+	require.NoError(t, err)
+	split = table.SplitOrderBy(order)
+	require.False(t, split.Exact())
+	require.Equal(t, order[:1], split.Pushed)
+	require.Equal(t, order[1:], split.Residual, "fields after the first unsortable one stay residual")
+
+	clause, err := table.OrderByClause(split.Pushed)
+	require.NoError(t, err)
+	require.Equal(t, "ORDER BY format DESC\n", clause)
+
+	// A page as returned by the database: sorted by format only.
+	page := []*testpb.Book{
+		{Name: "books/1", Format: testpb.Format_EBOOK, Title: "b"},
+		{Name: "books/2", Format: testpb.Format_EBOOK, Title: "a"},
+		{Name: "books/3", Format: testpb.Format_PAPERBACK, Title: "a"},
+		{Name: "books/4", Format: testpb.Format_PAPERBACK, Title: "a"},
+	}
+	require.NoError(t, query.SortPage(page, split))
+	var names []string
+	for _, b := range page {
+		names = append(names, b.GetName())
+	}
+	require.Equal(t, []string{"books/2", "books/1", "books/3", "books/4"}, names)
+}
@@ -1,47 +1,403 @@
 package query
 
 import (
+	"bytes"
+	"context"
 	"encoding/base64"
 	"errors"
 	"fmt"
-	"slices"
+	"net/url"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/tink-crypto/tink-go/v2/tink"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
+// tokenBufPool holds scratch buffers used while encoding and decoding page
+// tokens. Buffers are only used for intermediate values (wire bytes, AAD and
+// base64 text) and never escape the function that borrowed them.
+var tokenBufPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, 256)
+		return &b
+	},
+}
+
+func getTokenBuf() *[]byte {
+	return tokenBufPool.Get().(*[]byte)
+}
+
+func putTokenBuf(b *[]byte) {
+	*b = (*b)[:0]
+	tokenBufPool.Put(b)
+}
+
 var (
 	ErrInvalidPageToken = errors.New("invalid page token")
 	ErrInvalidOrder     = errors.New("invalid order for message type")
+	ErrNoTokenKey       = errors.New("no page token key for request")
+	ErrPageTokenExpired = errors.New("page token expired")
+)
+
+// KeyProvider selects the AEAD primitive and associated data used to protect
+// page tokens for the request carried by ctx.
+//
+// Multi-tenant servers may use a KeyProvider to derive tenant-specific keys
+// and associated data from the request context, so that tokens minted for one
+// tenant cannot be decrypted for another. Returning a nil AEAD rejects the
+// request with ErrNoTokenKey.
+type KeyProvider func(ctx context.Context) (tink.AEAD, []byte)
+
+// StaticKeys returns a KeyProvider that uses the same AEAD and associated data
+// for every request.
+func StaticKeys(aead tink.AEAD, aad []byte) KeyProvider {
+	return func(context.Context) (tink.AEAD, []byte) {
+		return aead, aad
+	}
+}
+
+// BindIdentity returns a KeyProvider that mixes the caller identity that
+// identity extracts from the request context, such as the authenticated
+// principal, into the associated data selected by keys. A token minted for
+// one caller then fails to decode with ErrInvalidPageToken for any other,
+// so that it cannot be replayed by another principal.
+//
+// Tokens minted before binding do not decode once it is enabled. Callers
+// with an empty identity, such as anonymous ones, share their tokens.
+func BindIdentity(keys KeyProvider, identity func(ctx context.Context) string) KeyProvider {
+	return func(ctx context.Context) (tink.AEAD, []byte) {
+		aead, aad := keys(ctx)
+		if aead == nil {
+			return nil, nil
+		}
+		// Both parts are length-prefixed so that no other pair of
+		// associated data and identity yields the same bytes.
+		bound := protowire.AppendBytes(nil, aad)
+		bound = protowire.AppendBytes(bound, []byte(identity(ctx)))
+		return aead, bound
+	}
+}
+
+// TokenOption configures how page tokens are minted and decoded.
+type TokenOption func(*tokenOptions)
+
+type tokenOptions struct {
+	compress bool
+	encoding *base64.Encoding
+	lenient  bool
+	maxAge   time.Duration
+	format   CursorFormat
+}
+
+func newTokenOptions(opts []TokenOption) *tokenOptions {
+	o := &tokenOptions{encoding: base64.RawURLEncoding}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithCompression compresses the cursor of page tokens with DEFLATE before
+// encryption, when that makes it smaller, to keep tokens short for orders
+// including long string fields such as resource names and titles. Tokens
+// are decompressed when decoded regardless of this option.
+func WithCompression() TokenOption {
+	return func(o *tokenOptions) {
+		o.compress = true
+	}
+}
+
+// WithEncoding sets the text encoding of page tokens, e.g.
+// base64.URLEncoding for clients or gateways that expect padding. The
+// default is base64.RawURLEncoding. Tokens must be decoded with the
+// encoding they were minted with, unless decoded WithLenientDecoding.
+func WithEncoding(enc *base64.Encoding) TokenOption {
+	return func(o *tokenOptions) {
+		o.encoding = enc
+	}
+}
+
+// WithLenientDecoding decodes page tokens that intermediaries have
+// re-encoded: tokens in the standard or URL-safe base64 alphabet, with or
+// without padding, and tokens percent-encoded, once or repeatedly, as query
+// parameters, including those whose '+' became a space. Tokens remain
+// authenticated, so leniency does not let clients forge them.
+func WithLenientDecoding() TokenOption {
+	return func(o *tokenOptions) {
+		o.lenient = true
+	}
+}
+
+// WithMaxAge rejects page tokens minted more than d ago, with an error
+// wrapping both ErrInvalidPageToken and ErrPageTokenExpired, so that
+// iteration over a collection does not go on indefinitely. It applies to
+// the decoding functions, but not to InspectToken. Consistency tokens are
+// rejected likewise, with ErrInvalidConsistencyToken.
+func WithMaxAge(d time.Duration) TokenOption {
+	return func(o *tokenOptions) {
+		o.maxAge = d
+	}
+}
+
+// CursorFormat is the serialization of the cursor message in page tokens.
+type CursorFormat int
+
+const (
+	// CursorBinary serializes cursors in the protobuf wire format, which
+	// makes the shortest tokens.
+	CursorBinary CursorFormat = iota
+
+	// CursorJSON serializes cursors in the protobuf JSON format, so that
+	// the cursors of tokens decrypted by InspectToken can be read, e.g. by
+	// the operators of internal tools. Tokens are longer.
+	CursorJSON
+)
+
+func (f CursorFormat) String() string {
+	switch f {
+	case CursorBinary:
+		return "BINARY"
+	case CursorJSON:
+		return "JSON"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// WithCursorFormat sets the serialization of the cursor of minted page
+// tokens. The default is CursorBinary. Tokens record their format, so they
+// are decoded regardless of this option, and servers may change formats
+// without invalidating outstanding tokens.
+func WithCursorFormat(f CursorFormat) TokenOption {
+	return func(o *tokenOptions) {
+		o.format = f
+	}
+}
+
+// appendDecode appends the ciphertext of token to dst.
+func (o *tokenOptions) appendDecode(dst []byte, token string) ([]byte, error) {
+	n := len(dst)
+	cipher, err := o.encoding.AppendDecode(dst, []byte(token))
+	if err == nil || !o.lenient {
+		return cipher, err
+	}
+	for i := 0; i < 3 && strings.Contains(token, "%"); i++ {
+		unescaped, err := url.PathUnescape(token)
+		if err != nil {
+			break
+		}
+		token = unescaped
+	}
+	token = strings.TrimRight(token, "=")
+	token = strings.NewReplacer(" ", "-", "+", "-", "/", "_").Replace(token)
+	if lenient, lerr := base64.RawURLEncoding.AppendDecode(cipher[:n], []byte(token)); lerr == nil {
+		return lenient, nil
+	}
+	return cipher, err
+}
+
+// NewCursorContext is like NewCursor, but uses the AEAD and associated data
+// selected by keys for ctx. If ctx carries a snapshot (see PinSnapshot), the
+// token is bound to it so that the following page is read at the same
+// snapshot.
+func NewCursorContext(ctx context.Context, m proto.Message, order []OrderBy, keys KeyProvider, opts ...TokenOption) (string, error) {
+	aead, aad := keys(ctx)
+	if aead == nil {
+		return "", ErrNoTokenKey
+	}
+	snapshot, _ := SnapshotFromContext(ctx)
+	return newToken(m, DirectionNext, snapshot, order, aead, aad, opts)
+}
+
+// DecodeCursorContext is like DecodeCursor, but uses the AEAD and associated
+// data selected by keys for ctx.
+func DecodeCursorContext[S any, M interface {
+	proto.Message
+	*S
+}](ctx context.Context, token string, order []OrderBy, keys KeyProvider, opts ...TokenOption) (M, error) {
+	aead, aad := keys(ctx)
+	if aead == nil {
+		return nil, ErrNoTokenKey
+	}
+	return DecodeCursor[S, M](token, order, aead, aad, opts...)
+}
+
+// Direction is the direction in which a page token continues iteration.
+type Direction int
+
+const (
+	// DirectionNext continues iteration after the cursor, in the requested
+	// order.
+	DirectionNext Direction = iota
+
+	// DirectionPrevious continues iteration before the cursor, i.e., it
+	// returns the page preceding the page the cursor was taken from.
+	DirectionPrevious
 )
 
+func (d Direction) String() string {
+	switch d {
+	case DirectionNext:
+		return "NEXT"
+	case DirectionPrevious:
+		return "PREVIOUS"
+	default:
+		return "UNKNOWN"
+	}
+}
+
 // DecodeCursor parses a Page Token string
+//
+// The direction of the token is ignored; use DecodeDirectionalCursor for
+// APIs that support previous-page tokens.
 func DecodeCursor[S any, M interface {
 	proto.Message
 	*S
-}](token string, order []OrderBy, aead tink.AEAD, aad []byte) (M, error) {
-	cipher, err := base64.RawURLEncoding.DecodeString(token)
-	if err != nil {
-		return nil, fmt.Errorf("%w: %w", ErrInvalidPageToken, err)
-	}
+}](token string, order []OrderBy, aead tink.AEAD, aad []byte, opts ...TokenOption) (M, error) {
+	msg, _, err := DecodeDirectionalCursor[S, M](token, order, aead, aad, opts...)
+	return msg, err
+}
 
-	aad = slices.Concat(aad, []byte{0}, serializeOrderByText(order))
-	data, err := aead.Decrypt(cipher, aad)
+// DecodeDirectionalCursor parses a page token minted by NewCursor,
+// NewDirectionalCursor or NewPageTokens, returning the cursor message and the
+// direction in which the server should seek from it.
+//
+// For DirectionNext, the next page holds the items after the cursor (see
+// CursorFilter). For DirectionPrevious, it holds the items immediately before
+// the cursor (see DirectionalCursorFilter and ReverseOrder).
+//
+// Tokens minted before a field of the order was removed, renumbered or
+// changed type fail with an error wrapping ErrStaleCursorSchema.
+func DecodeDirectionalCursor[S any, M interface {
+	proto.Message
+	*S
+}](token string, order []OrderBy, aead tink.AEAD, aad []byte, opts ...TokenOption) (M, Direction, error) {
+	env, err := openToken(token, order, aead, aad, newTokenOptions(opts))
 	if err != nil {
-		return nil, fmt.Errorf("%w: %w", ErrInvalidPageToken, err)
+		return nil, DirectionNext, err
 	}
 
 	var zero S
 	var msg M = &zero
 
-	err = proto.Unmarshal(data, msg)
+	if env.format == CursorJSON {
+		// Fields since removed from the schema are reported by
+		// checkSchema.
+		err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(env.cursor, msg)
+	} else {
+		err = proto.Unmarshal(env.cursor, msg)
+	}
+	if err != nil {
+		return nil, DirectionNext, fmt.Errorf("%w: %w", ErrInvalidPageToken, err)
+	}
+	if err := checkSchema(&env, msg.ProtoReflect(), order); err != nil {
+		return nil, DirectionNext, err
+	}
+
+	return msg, env.direction, nil
+}
+
+// openToken decodes, authenticates and decrypts a page token, and checks
+// that it was minted for order.
+func openToken(token string, order []OrderBy, aead tink.AEAD, aad []byte, o *tokenOptions) (tokenEnvelope, error) {
+	env, err := decryptToken(token, aead, aad, o)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %w", ErrInvalidPageToken, err)
+		return env, err
+	}
+	if o.maxAge > 0 && time.Since(env.issued()) > o.maxAge {
+		return env, fmt.Errorf("%w: %w", ErrInvalidPageToken, ErrPageTokenExpired)
 	}
 
-	return msg, nil
+	orderBuf := getTokenBuf()
+	defer putTokenBuf(orderBuf)
+	*orderBuf = appendOrderByText(*orderBuf, order)
+	if !bytes.Equal(env.order, *orderBuf) {
+		return env, fmt.Errorf("%w: %w", ErrInvalidPageToken, ErrInvalidOrder)
+	}
+	return env, nil
+}
+
+// decryptToken decodes, authenticates and decrypts a page token.
+func decryptToken(token string, aead tink.AEAD, aad []byte, o *tokenOptions) (tokenEnvelope, error) {
+	env, err := openEnvelope(token, aead, aad, o)
+	if err != nil {
+		return env, fmt.Errorf("%w: %w", ErrInvalidPageToken, err)
+	}
+	if len(env.minRead) > 0 {
+		return env, fmt.Errorf("%w: consistency token used as a page token", ErrInvalidPageToken)
+	}
+	return env, nil
+}
+
+// openEnvelope decodes, authenticates and decrypts the envelope of a token.
+func openEnvelope(token string, aead tink.AEAD, aad []byte, o *tokenOptions) (tokenEnvelope, error) {
+	var env tokenEnvelope
+
+	cipherBuf := getTokenBuf()
+	defer putTokenBuf(cipherBuf)
+	cipher, err := o.appendDecode(*cipherBuf, token)
+	*cipherBuf = cipher
+	if err != nil {
+		return env, err
+	}
+
+	data, err := aead.Decrypt(cipher, aad)
+	if err != nil {
+		return env, err
+	}
+
+	// The decrypted data is not pooled, so the envelope may alias it.
+	if err := env.unmarshal(data); err != nil {
+		return env, err
+	}
+	return env, nil
+}
+
+// TokenInfo describes the authenticated contents of a page token.
+type TokenInfo struct {
+	// Version is the version of the token format.
+	Version int
+	// Direction is the direction in which the token continues iteration.
+	Direction Direction
+	// IssueTime is when the token was minted.
+	IssueTime time.Time
+	// Order is the canonical text of the order the token is bound to, e.g.
+	// "author.family_name:asc|title:desc".
+	Order string
+	// Cursor is the serialized cursor message, decompressed if it was
+	// minted WithCompression.
+	Cursor []byte
+	// CursorFormat is the serialization of Cursor.
+	CursorFormat CursorFormat
+	// Snapshot is the storage snapshot the token is pinned to, or empty.
+	Snapshot string
+}
+
+// InspectToken decrypts a page token and returns its contents without
+// checking it against an order or decoding the cursor message.
+//
+// InspectToken is intended for debugging tools. Servers should use
+// DecodeCursor or DecodeDirectionalCursor, which validate the token against
+// the order of the request.
+func InspectToken(token string, aead tink.AEAD, aad []byte, opts ...TokenOption) (*TokenInfo, error) {
+	env, err := decryptToken(token, aead, aad, newTokenOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	return &TokenInfo{
+		Version:      int(env.version),
+		Direction:    env.direction,
+		IssueTime:    env.issued(),
+		Order:        string(env.order),
+		Cursor:       env.cursor,
+		CursorFormat: env.format,
+		Snapshot:     string(env.snapshot),
+	}, nil
 }
 
 // CursorFilter generates a filter from a proto.Message and an iteration order.
@@ -60,36 +416,180 @@ func CursorFilter[M proto.Message](cursor M, order []OrderBy) (func(M) bool, err
 	}, nil
 }
 
-func NewCursor(m proto.Message, order []OrderBy, aead tink.AEAD, aad []byte) (string, error) {
+// DirectionalCursorFilter is like CursorFilter, but for DirectionPrevious the
+// returned function reports whether the parameter comes before the cursor
+// message in the sort order.
+//
+// To serve a previous page, select the items matching this filter, sort them
+// by ReverseOrder(order), take the first page_size items and reverse them.
+func DirectionalCursorFilter[M proto.Message](cursor M, order []OrderBy, dir Direction) (func(M) bool, error) {
+	if dir == DirectionNext {
+		return CursorFilter(cursor, order)
+	}
+
+	less, err := Less[M](order)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(msg M) bool {
+		return less(msg, cursor)
+	}, nil
+}
+
+// ReverseOrder returns a copy of order with every sort direction flipped.
+func ReverseOrder(order []OrderBy) []OrderBy {
+	out := make([]OrderBy, len(order))
+	for i, ob := range order {
+		out[i] = OrderBy{FieldPath: ob.FieldPath, Descending: !ob.Descending}
+	}
+	return out
+}
+
+// CombinedPredicate returns a single predicate that reports whether a message
+// both satisfies filter and comes after cursor in the given order. This is
+// the usual predicate for serving one page of an in-memory List.
+//
+// A nil cursor (as when serving the first page) only applies the filter, and a
+// nil filter only applies the cursor.
+func CombinedPredicate[S any, M interface {
+	proto.Message
+	*S
+}](filter *Filter, cursor M, order []OrderBy) (func(M) bool, error) {
+	match, err := ProtoFilter[S, M](filter)
+	if err != nil {
+		return nil, err
+	}
+	if cursor == nil {
+		return match, nil
+	}
+
+	after, err := CursorFilter(cursor, order)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(msg M) bool {
+		// The cursor check is cheaper than most filters, so it goes first.
+		return after(msg) && match(msg)
+	}, nil
+}
+
+// NewCursor mints a page token continuing iteration after m in the given
+// order.
+func NewCursor(m proto.Message, order []OrderBy, aead tink.AEAD, aad []byte, opts ...TokenOption) (string, error) {
+	return NewDirectionalCursor(m, DirectionNext, order, aead, aad, opts...)
+}
+
+// NewPageTokens mints the next and previous page tokens for a page whose
+// first and last items (in the requested order) are first and last.
+func NewPageTokens(first, last proto.Message, order []OrderBy, aead tink.AEAD, aad []byte, opts ...TokenOption) (next, prev string, err error) {
+	next, err = NewDirectionalCursor(last, DirectionNext, order, aead, aad, opts...)
+	if err != nil {
+		return "", "", err
+	}
+	prev, err = NewDirectionalCursor(first, DirectionPrevious, order, aead, aad, opts...)
+	if err != nil {
+		return "", "", err
+	}
+	return next, prev, nil
+}
+
+// NewDirectionalCursor mints a page token continuing iteration from m in the
+// given direction.
+func NewDirectionalCursor(m proto.Message, dir Direction, order []OrderBy, aead tink.AEAD, aad []byte, opts ...TokenOption) (string, error) {
+	return newToken(m, dir, "", order, aead, aad, opts)
+}
+
+// NewSnapshotCursor is like NewDirectionalCursor, but binds the token to a
+// storage snapshot, such as a Spanner read timestamp or an exported Postgres
+// snapshot ID. PinSnapshot recovers the snapshot when the token is
+// presented, so that every page of the iteration is read at the same
+// snapshot.
+func NewSnapshotCursor(m proto.Message, dir Direction, snapshot string, order []OrderBy, aead tink.AEAD, aad []byte, opts ...TokenOption) (string, error) {
+	return newToken(m, dir, snapshot, order, aead, aad, opts)
+}
+
+// newToken mints a page token continuing iteration from m in the given
+// direction, pinned to snapshot if it is not empty.
+func newToken(m proto.Message, dir Direction, snapshot string, order []OrderBy, aead tink.AEAD, aad []byte, opts []TokenOption) (string, error) {
+	o := newTokenOptions(opts)
+
 	pruned, err := pruneMessage(m, order)
 	if err != nil {
 		return "", fmt.Errorf("pruning message: %w", err)
 	}
 
-	raw, err := proto.Marshal(pruned)
+	rawBuf := getTokenBuf()
+	defer putTokenBuf(rawBuf)
+	var raw []byte
+	switch o.format {
+	case CursorBinary:
+		raw, err = proto.MarshalOptions{}.MarshalAppend(*rawBuf, pruned)
+	case CursorJSON:
+		raw, err = protojson.MarshalOptions{}.MarshalAppend(*rawBuf, pruned)
+	default:
+		return "", fmt.Errorf("unknown cursor format %s", o.format)
+	}
 	if err != nil {
 		return "", fmt.Errorf("marshaling pruned message: %w", err)
 	}
+	*rawBuf = raw
+
+	orderBuf := getTokenBuf()
+	defer putTokenBuf(orderBuf)
+	*orderBuf = appendOrderByText(*orderBuf, order)
+
+	// The paths of order resolve, since the message was pruned to them.
+	schemaBuf := getTokenBuf()
+	defer putTokenBuf(schemaBuf)
+	*schemaBuf, _ = appendSchema(*schemaBuf, m.ProtoReflect().Descriptor(), order)
+
+	env := tokenEnvelope{
+		version:   tokenVersion,
+		direction: dir,
+		cursor:    raw,
+		issueTime: time.Now().UnixNano(),
+		order:     *orderBuf,
+		snapshot:  []byte(snapshot),
+		schema:    *schemaBuf,
+		format:    o.format,
+	}
+	if o.compress {
+		if err := env.compress(); err != nil {
+			return "", fmt.Errorf("compressing cursor: %w", err)
+		}
+	}
+	envBuf := getTokenBuf()
+	defer putTokenBuf(envBuf)
+	*envBuf = env.appendTo(*envBuf)
 
-	aad = slices.Concat(aad, []byte{0}, serializeOrderByText(order))
-	ciphertext, err := aead.Encrypt(raw, aad)
+	ciphertext, err := aead.Encrypt(*envBuf, aad)
 	if err != nil {
 		return "", fmt.Errorf("encrypting token: %w", err)
 	}
 
-	return base64.RawURLEncoding.EncodeToString(ciphertext), nil
+	textBuf := getTokenBuf()
+	defer putTokenBuf(textBuf)
+	*textBuf = o.encoding.AppendEncode(*textBuf, ciphertext)
+	return string(*textBuf), nil
 }
 
-func serializeOrderByText(order []OrderBy) []byte {
-	parts := make([]string, len(order))
+// appendOrderByText appends the canonical text of order, which binds a page
+// token to the order it was minted for.
+func appendOrderByText(dst []byte, order []OrderBy) []byte {
 	for i, ob := range order {
-		dir := "asc"
+		if i > 0 {
+			dst = append(dst, '|')
+		}
+		dst = append(dst, ob.FieldPath.canonical...)
 		if ob.Descending {
-			dir = "desc"
+			dst = append(dst, ":desc"...)
+		} else {
+			dst = append(dst, ":asc"...)
 		}
-		parts[i] = ob.FieldPath.canonical + ":" + dir
 	}
-	return []byte(strings.Join(parts, "|"))
+	return dst
 }
 
 // pruneMessage returns a new proto.Message containing only the fields needed for the sort order.
@@ -121,6 +621,10 @@ func copyFieldPath(src, dst protoreflect.Message, segments []string) error {
 		return fmt.Errorf("cannot sort on repeated field %s in message %s", segments[0], fieldDesc.FullName())
 	}
 
+	if fieldDesc.HasPresence() && !src.Has(fieldDesc) {
+		// Leave unset fields unset so the cursor keeps their presence.
+		return nil
+	}
 	val := src.Get(fieldDesc)
 	if len(segments) == 1 {
 		dst.Set(fieldDesc, val)
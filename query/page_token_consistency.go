@@ -0,0 +1,141 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ErrInvalidConsistencyToken is wrapped by the errors of consistency tokens
+// that cannot be decoded.
+var ErrInvalidConsistencyToken = errors.New("invalid consistency token")
+
+// consistencyDomain separates the associated data of consistency tokens from
+// that of page tokens, so that neither kind decodes as the other.
+const consistencyDomain = "aip.consistency"
+
+// ConsistencyReader makes the reads of a List request observe a write that a
+// client has already seen, e.g. one whose response carried a consistency
+// token.
+//
+// For example, a Spanner implementation may return the commit timestamp of
+// a read-write transaction from Version and perform a read bounded by that
+// minimum timestamp in ReadAtLeast; a Postgres implementation may return
+// the WAL position of the commit and wait for a replica to replay it.
+type ConsistencyReader interface {
+	// Version returns an identifier for the state of storage after the
+	// writes of the request carried by ctx, such as the commit timestamp of
+	// its transaction. An empty identifier mints no token.
+	Version(ctx context.Context) (string, error)
+
+	// ReadAtLeast returns a context whose reads observe every write up to
+	// version. It returns an error if that cannot be guaranteed, e.g.
+	// because no replica has caught up before the deadline of ctx.
+	ReadAtLeast(ctx context.Context, version string) (context.Context, error)
+}
+
+// NewConsistencyToken mints a consistency token, protected by the AEAD and
+// associated data selected by keys for ctx, for the storage version that
+// reader reports after a write. Create and Update handlers return it to
+// clients, which pass it to later List requests to read their own writes
+// (see RequireConsistency).
+//
+// Consistency tokens are distinct from page tokens: neither decodes as the
+// other. An empty version mints an empty token.
+func NewConsistencyToken(ctx context.Context, reader ConsistencyReader, keys KeyProvider, opts ...TokenOption) (string, error) {
+	version, err := reader.Version(ctx)
+	if err != nil {
+		return "", fmt.Errorf("reading storage version: %w", err)
+	}
+	if version == "" {
+		return "", nil
+	}
+	aead, aad := keys(ctx)
+	if aead == nil {
+		return "", ErrNoTokenKey
+	}
+	o := newTokenOptions(opts)
+
+	env := tokenEnvelope{
+		version:   tokenVersion,
+		issueTime: time.Now().UnixNano(),
+		minRead:   []byte(version),
+	}
+	envBuf := getTokenBuf()
+	defer putTokenBuf(envBuf)
+	*envBuf = env.appendTo(*envBuf)
+
+	aadBuf := getTokenBuf()
+	defer putTokenBuf(aadBuf)
+	*aadBuf = appendConsistencyAAD(*aadBuf, aad)
+
+	ciphertext, err := aead.Encrypt(*envBuf, *aadBuf)
+	if err != nil {
+		return "", fmt.Errorf("encrypting token: %w", err)
+	}
+
+	textBuf := getTokenBuf()
+	defer putTokenBuf(textBuf)
+	*textBuf = o.encoding.AppendEncode(*textBuf, ciphertext)
+	return string(*textBuf), nil
+}
+
+// DecodeConsistencyToken returns the storage version of a token minted by
+// NewConsistencyToken, using the AEAD and associated data selected by keys
+// for ctx. The error of a token that does not decode wraps
+// ErrInvalidConsistencyToken.
+func DecodeConsistencyToken(ctx context.Context, token string, keys KeyProvider, opts ...TokenOption) (string, error) {
+	aead, aad := keys(ctx)
+	if aead == nil {
+		return "", ErrNoTokenKey
+	}
+	o := newTokenOptions(opts)
+
+	aadBuf := getTokenBuf()
+	defer putTokenBuf(aadBuf)
+	*aadBuf = appendConsistencyAAD(*aadBuf, aad)
+
+	env, err := openEnvelope(token, aead, *aadBuf, o)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidConsistencyToken, err)
+	}
+	if len(env.minRead) == 0 {
+		return "", fmt.Errorf("%w: token has no storage version", ErrInvalidConsistencyToken)
+	}
+	if o.maxAge > 0 && time.Since(env.issued()) > o.maxAge {
+		return "", fmt.Errorf("%w: %w", ErrInvalidConsistencyToken, ErrPageTokenExpired)
+	}
+	return string(env.minRead), nil
+}
+
+// RequireConsistency makes the reads of a List request observe the writes
+// whose consistency token the client presented.
+//
+// If token is empty, ctx is returned unchanged and reads have the default
+// consistency of reader. Otherwise the result is that of reader.ReadAtLeast
+// for the version of the token. opts configure how the token is decoded.
+func RequireConsistency(ctx context.Context, token string, keys KeyProvider, reader ConsistencyReader, opts ...TokenOption) (context.Context, error) {
+	if token == "" {
+		return ctx, nil
+	}
+	version, err := DecodeConsistencyToken(ctx, token, keys, opts...)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = reader.ReadAtLeast(ctx, version)
+	if err != nil {
+		return nil, fmt.Errorf("reading at version %s: %w", version, err)
+	}
+	return ctx, nil
+}
+
+// appendConsistencyAAD appends the associated data of consistency tokens
+// derived from aad to dst.
+func appendConsistencyAAD(dst, aad []byte) []byte {
+	// Both parts are length-prefixed, as in BindIdentity.
+	dst = protowire.AppendString(dst, consistencyDomain)
+	return protowire.AppendBytes(dst, aad)
+}
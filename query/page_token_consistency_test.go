@@ -0,0 +1,105 @@
+package query_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/query"
+	"github.com/tink-crypto/tink-go/v2/testing/fakekms"
+	"github.com/tink-crypto/tink-go/v2/tink"
+)
+
+type readAtKey struct{}
+
+// fakeConsistencyReader reports a fixed commit version and records the
+// minimum version of reads in the context.
+type fakeConsistencyReader struct {
+	version string
+	behind  bool
+}
+
+func (r *fakeConsistencyReader) Version(context.Context) (string, error) {
+	return r.version, nil
+}
+
+func (r *fakeConsistencyReader) ReadAtLeast(ctx context.Context, version string) (context.Context, error) {
+	if r.behind {
+		return nil, errors.New("replica is behind")
+	}
+	return context.WithValue(ctx, readAtKey{}, version), nil
+}
+
+func TestConsistencyToken(t *testing.T) {
+	aead, err := fakekms.NewAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("error creating AEAD")
+	}
+	keys := query.StaticKeys(aead, []byte("ctx"))
+	reader := &fakeConsistencyReader{version: "1700000000.000000001"}
+
+	tok, err := query.NewConsistencyToken(context.Background(), reader, keys)
+	if err != nil {
+		t.Fatalf("NewConsistencyToken failed: %v", err)
+	}
+	ctx, err := query.RequireConsistency(context.Background(), tok, keys, reader)
+	if err != nil {
+		t.Fatalf("RequireConsistency failed: %v", err)
+	}
+	if got := ctx.Value(readAtKey{}); got != "1700000000.000000001" {
+		t.Fatalf("read at %v, want 1700000000.000000001", got)
+	}
+
+	ctx, err = query.RequireConsistency(context.Background(), "", keys, reader)
+	if err != nil || ctx.Value(readAtKey{}) != nil {
+		t.Errorf("RequireConsistency without a token = %v, %v; want the context unchanged", ctx.Value(readAtKey{}), err)
+	}
+
+	reader.behind = true
+	if _, err := query.RequireConsistency(context.Background(), tok, keys, reader); err == nil {
+		t.Error("RequireConsistency with a lagging reader: got nil error")
+	}
+
+	otherKeys := query.StaticKeys(aead, []byte("other"))
+	if _, err := query.DecodeConsistencyToken(context.Background(), tok, otherKeys); !errors.Is(err, query.ErrInvalidConsistencyToken) {
+		t.Errorf("DecodeConsistencyToken with other associated data: got %v, want ErrInvalidConsistencyToken", err)
+	}
+	if _, err := query.DecodeConsistencyToken(context.Background(), tok, keys, query.WithMaxAge(time.Nanosecond)); !errors.Is(err, query.ErrPageTokenExpired) {
+		t.Errorf("DecodeConsistencyToken of an old token: got %v, want ErrPageTokenExpired", err)
+	}
+	if _, err := query.NewConsistencyToken(context.Background(), reader, func(context.Context) (tink.AEAD, []byte) { return nil, nil }); !errors.Is(err, query.ErrNoTokenKey) {
+		t.Errorf("NewConsistencyToken without a key: got %v, want ErrNoTokenKey", err)
+	}
+
+	reader.version = ""
+	if tok, err := query.NewConsistencyToken(context.Background(), reader, keys); tok != "" || err != nil {
+		t.Errorf("NewConsistencyToken without a version = %q, %v; want an empty token", tok, err)
+	}
+}
+
+func TestConsistencyToken_NotAPageToken(t *testing.T) {
+	aead, err := fakekms.NewAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("error creating AEAD")
+	}
+	keys := query.StaticKeys(aead, []byte("ctx"))
+	order, _ := query.ParseOrderBy("")
+
+	consistency, err := query.NewConsistencyToken(context.Background(), &fakeConsistencyReader{version: "42"}, keys)
+	if err != nil {
+		t.Fatalf("NewConsistencyToken failed: %v", err)
+	}
+	if _, err := query.DecodeCursor[testpb.Book](consistency, order, aead, []byte("ctx")); !errors.Is(err, query.ErrInvalidPageToken) {
+		t.Errorf("DecodeCursor of a consistency token: got %v, want ErrInvalidPageToken", err)
+	}
+
+	page, err := query.NewCursor(&testpb.Book{}, order, aead, []byte("ctx"))
+	if err != nil {
+		t.Fatalf("NewCursor failed: %v", err)
+	}
+	if _, err := query.DecodeConsistencyToken(context.Background(), page, keys); !errors.Is(err, query.ErrInvalidConsistencyToken) {
+		t.Errorf("DecodeConsistencyToken of a page token: got %v, want ErrInvalidConsistencyToken", err)
+	}
+}
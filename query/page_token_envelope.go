@@ -0,0 +1,206 @@
+package query
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// tokenVersion is the version of the page token envelope format written by
+// this package.
+const tokenVersion = 1
+
+// Field numbers of the page token envelope. The envelope is encoded in the
+// protobuf wire format so that fields can be added compatibly.
+const (
+	envelopeVersionField   protowire.Number = 1
+	envelopeDirectionField protowire.Number = 2
+	envelopeCursorField    protowire.Number = 3
+	envelopeIssueTimeField protowire.Number = 4
+	envelopeOrderField     protowire.Number = 5
+	envelopeSnapshotField  protowire.Number = 6
+	envelopeCompressField  protowire.Number = 7
+	envelopeSchemaField    protowire.Number = 8
+	envelopeMinReadField   protowire.Number = 9
+	envelopeFormatField    protowire.Number = 10
+)
+
+// maxCursorSize bounds the size of a decompressed cursor.
+const maxCursorSize = 1 << 20
+
+// tokenEnvelope is the plaintext of a page token.
+type tokenEnvelope struct {
+	version   uint64
+	direction Direction
+
+	// cursor is the serialized, pruned cursor message.
+	cursor []byte
+
+	// issueTime is when the token was minted, in nanoseconds since the Unix
+	// epoch.
+	issueTime int64
+
+	// order is the canonical text of the iteration order the token is bound
+	// to; see appendOrderByText.
+	order []byte
+
+	// snapshot identifies the storage snapshot that pages of the iteration
+	// are read at, or is empty if reads are not pinned; see SnapshotPinner.
+	snapshot []byte
+
+	// compressed is whether cursor is compressed with DEFLATE; see
+	// WithCompression.
+	compressed bool
+
+	// schema fingerprints the fields of the order in the schema the token
+	// was minted under, or is empty for tokens minted before it was
+	// recorded; see appendSchema.
+	schema []byte
+
+	// format is the serialization of cursor; see WithCursorFormat.
+	format CursorFormat
+
+	// minRead is the storage version, such as a commit timestamp, that
+	// reads must observe; it is only set in consistency tokens; see
+	// NewConsistencyToken.
+	minRead []byte
+}
+
+// appendTo appends the wire encoding of e to b.
+func (e *tokenEnvelope) appendTo(b []byte) []byte {
+	b = protowire.AppendTag(b, envelopeVersionField, protowire.VarintType)
+	b = protowire.AppendVarint(b, e.version)
+	if e.direction != DirectionNext {
+		b = protowire.AppendTag(b, envelopeDirectionField, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(e.direction))
+	}
+	b = protowire.AppendTag(b, envelopeCursorField, protowire.BytesType)
+	b = protowire.AppendBytes(b, e.cursor)
+	b = protowire.AppendTag(b, envelopeIssueTimeField, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(e.issueTime))
+	b = protowire.AppendTag(b, envelopeOrderField, protowire.BytesType)
+	b = protowire.AppendBytes(b, e.order)
+	if len(e.snapshot) > 0 {
+		b = protowire.AppendTag(b, envelopeSnapshotField, protowire.BytesType)
+		b = protowire.AppendBytes(b, e.snapshot)
+	}
+	if e.compressed {
+		b = protowire.AppendTag(b, envelopeCompressField, protowire.VarintType)
+		b = protowire.AppendVarint(b, 1)
+	}
+	if len(e.schema) > 0 {
+		b = protowire.AppendTag(b, envelopeSchemaField, protowire.BytesType)
+		b = protowire.AppendBytes(b, e.schema)
+	}
+	if e.format != CursorBinary {
+		b = protowire.AppendTag(b, envelopeFormatField, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(e.format))
+	}
+	if len(e.minRead) > 0 {
+		b = protowire.AppendTag(b, envelopeMinReadField, protowire.BytesType)
+		b = protowire.AppendBytes(b, e.minRead)
+	}
+	return b
+}
+
+// unmarshal decodes b into e, decompressing the cursor. The cursor field
+// aliases b if it is not compressed.
+func (e *tokenEnvelope) unmarshal(b []byte) error {
+	*e = tokenEnvelope{}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		switch {
+		case num == envelopeVersionField && typ == protowire.VarintType:
+			e.version, n = protowire.ConsumeVarint(b)
+		case num == envelopeDirectionField && typ == protowire.VarintType:
+			var v uint64
+			v, n = protowire.ConsumeVarint(b)
+			e.direction = Direction(v)
+		case num == envelopeCursorField && typ == protowire.BytesType:
+			e.cursor, n = protowire.ConsumeBytes(b)
+		case num == envelopeIssueTimeField && typ == protowire.VarintType:
+			var v uint64
+			v, n = protowire.ConsumeVarint(b)
+			e.issueTime = int64(v)
+		case num == envelopeOrderField && typ == protowire.BytesType:
+			e.order, n = protowire.ConsumeBytes(b)
+		case num == envelopeSnapshotField && typ == protowire.BytesType:
+			e.snapshot, n = protowire.ConsumeBytes(b)
+		case num == envelopeCompressField && typ == protowire.VarintType:
+			var v uint64
+			v, n = protowire.ConsumeVarint(b)
+			e.compressed = v != 0
+		case num == envelopeSchemaField && typ == protowire.BytesType:
+			e.schema, n = protowire.ConsumeBytes(b)
+		case num == envelopeFormatField && typ == protowire.VarintType:
+			var v uint64
+			v, n = protowire.ConsumeVarint(b)
+			e.format = CursorFormat(v)
+		case num == envelopeMinReadField && typ == protowire.BytesType:
+			e.minRead, n = protowire.ConsumeBytes(b)
+		default:
+			// Unknown fields are skipped for forward compatibility.
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+
+	if e.version != tokenVersion {
+		return fmt.Errorf("unsupported token version %d", e.version)
+	}
+	if e.direction != DirectionNext && e.direction != DirectionPrevious {
+		return errors.New("unknown token direction")
+	}
+	if e.format != CursorBinary && e.format != CursorJSON {
+		return errors.New("unknown cursor format")
+	}
+	if e.compressed {
+		cursor, err := io.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(e.cursor)), maxCursorSize+1))
+		if err != nil {
+			return fmt.Errorf("decompressing cursor: %w", err)
+		}
+		if len(cursor) > maxCursorSize {
+			return fmt.Errorf("decompressed cursor exceeds %d bytes", maxCursorSize)
+		}
+		e.cursor, e.compressed = cursor, false
+	}
+	return nil
+}
+
+// compress compresses the cursor of e with DEFLATE if that makes it
+// smaller.
+func (e *tokenEnvelope) compress() error {
+	var b bytes.Buffer
+	w, err := flate.NewWriter(&b, flate.BestCompression)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(e.cursor); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if b.Len() < len(e.cursor) {
+		e.cursor, e.compressed = b.Bytes(), true
+	}
+	return nil
+}
+
+// issued returns the time the token was minted.
+func (e *tokenEnvelope) issued() time.Time {
+	return time.Unix(0, e.issueTime)
+}
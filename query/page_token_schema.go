@@ -0,0 +1,79 @@
+package query
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ErrStaleCursorSchema is wrapped, along with ErrInvalidPageToken, by the
+// errors of decoding page tokens minted under an older schema, in which the
+// fields of their order had other numbers or types, or which had fields
+// since removed. Such a cursor cannot be resumed reliably; servers should ask
+// the client to restart pagination from the first page.
+var ErrStaleCursorSchema = errors.New("page token was minted for an older schema")
+
+// appendSchema appends a fingerprint of the fields along each path of order
+// in desc to dst: the number and kind of each field, with paths separated by
+// zero, which is not a valid field number. It reports false if a path does
+// not resolve in desc.
+func appendSchema(dst []byte, desc protoreflect.MessageDescriptor, order []OrderBy) ([]byte, bool) {
+	for _, ob := range order {
+		md := desc
+		for _, seg := range ob.FieldPath.segments {
+			if md == nil {
+				return dst, false
+			}
+			fd := md.Fields().ByName(protoreflect.Name(seg))
+			if fd == nil {
+				return dst, false
+			}
+			dst = protowire.AppendVarint(dst, uint64(fd.Number()))
+			dst = protowire.AppendVarint(dst, uint64(fd.Kind()))
+			md = fd.Message()
+		}
+		dst = protowire.AppendVarint(dst, 0)
+	}
+	return dst, true
+}
+
+// checkSchema returns an error wrapping ErrStaleCursorSchema if the token
+// env, whose cursor was decoded into cursor, was minted under a schema of
+// the fields of order other than the current one.
+func checkSchema(env *tokenEnvelope, cursor protoreflect.Message, order []OrderBy) error {
+	stale := fmt.Errorf("%w: %w", ErrInvalidPageToken, ErrStaleCursorSchema)
+	if hasUnknownFields(cursor) {
+		// The cursor only holds fields of the order, so unknown ones were
+		// removed from the schema.
+		return stale
+	}
+	if len(env.schema) == 0 {
+		return nil
+	}
+	buf := getTokenBuf()
+	defer putTokenBuf(buf)
+	schema, ok := appendSchema(*buf, cursor.Descriptor(), order)
+	*buf = schema
+	if !ok || !bytes.Equal(schema, env.schema) {
+		return stale
+	}
+	return nil
+}
+
+// hasUnknownFields reports whether m or a message in it has unknown fields.
+func hasUnknownFields(m protoreflect.Message) bool {
+	if len(m.GetUnknown()) > 0 {
+		return true
+	}
+	found := false
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.Message() != nil && !fd.IsList() && !fd.IsMap() {
+			found = hasUnknownFields(v.Message())
+		}
+		return !found
+	})
+	return found
+}
@@ -0,0 +1,80 @@
+package query
+
+import (
+	"context"
+	"fmt"
+)
+
+// SnapshotPinner pins the reads of a paginated List to a consistent snapshot
+// of the storage layer, so that its pages are mutually consistent even as
+// data changes between requests.
+//
+// For example, a Spanner implementation may return the read timestamp of a
+// strong read from Snapshot and perform a stale read at that timestamp in
+// Pin; a Postgres implementation may export the snapshot of a REPEATABLE READ
+// transaction and import it into the transaction opened by Pin.
+type SnapshotPinner interface {
+	// Snapshot returns an identifier for the current state of storage. It is
+	// called for the first page of an iteration. An empty identifier
+	// disables pinning for the iteration.
+	Snapshot(ctx context.Context) (string, error)
+
+	// Pin returns a context whose reads observe the given snapshot. It
+	// returns an error if the snapshot is no longer available, e.g. because
+	// it is older than the retention period of the database.
+	Pin(ctx context.Context, snapshot string) (context.Context, error)
+}
+
+type snapshotKey struct{}
+
+// WithSnapshot returns a copy of ctx carrying the storage snapshot that reads
+// for the request are pinned to.
+func WithSnapshot(ctx context.Context, snapshot string) context.Context {
+	return context.WithValue(ctx, snapshotKey{}, snapshot)
+}
+
+// SnapshotFromContext returns the storage snapshot carried by ctx, if any.
+func SnapshotFromContext(ctx context.Context) (string, bool) {
+	snapshot, ok := ctx.Value(snapshotKey{}).(string)
+	return snapshot, ok && snapshot != ""
+}
+
+// PinSnapshot pins the reads of a List request to a storage snapshot.
+//
+// If token is empty, a new snapshot is taken from pinner; otherwise the
+// snapshot embedded in the token is used, so tokens must be minted by
+// NewCursorContext or NewSnapshotCursor. The returned context is the result
+// of pinner.Pin and carries the snapshot, so that NewCursorContext binds the
+// next page token to it.
+//
+// Tokens minted without a snapshot are served unpinned. opts configure how
+// the token is decoded.
+func PinSnapshot(ctx context.Context, token string, order []OrderBy, keys KeyProvider, pinner SnapshotPinner, opts ...TokenOption) (context.Context, error) {
+	var snapshot string
+	if token == "" {
+		var err error
+		snapshot, err = pinner.Snapshot(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("taking snapshot: %w", err)
+		}
+	} else {
+		aead, aad := keys(ctx)
+		if aead == nil {
+			return nil, ErrNoTokenKey
+		}
+		env, err := openToken(token, order, aead, aad, newTokenOptions(opts))
+		if err != nil {
+			return nil, err
+		}
+		snapshot = string(env.snapshot)
+	}
+	if snapshot == "" {
+		return ctx, nil
+	}
+
+	ctx, err := pinner.Pin(ctx, snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("pinning snapshot: %w", err)
+	}
+	return WithSnapshot(ctx, snapshot), nil
+}
@@ -0,0 +1,115 @@
+package query_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/query"
+	"github.com/tink-crypto/tink-go/v2/testing/fakekms"
+)
+
+type pinnedKey struct{}
+
+// fakePinner hands out sequential snapshots and records the pinned snapshot
+// in the context.
+type fakePinner struct {
+	next    string
+	expired string
+}
+
+func (p *fakePinner) Snapshot(context.Context) (string, error) {
+	return p.next, nil
+}
+
+func (p *fakePinner) Pin(ctx context.Context, snapshot string) (context.Context, error) {
+	if snapshot == p.expired {
+		return nil, errors.New("snapshot expired")
+	}
+	return context.WithValue(ctx, pinnedKey{}, snapshot), nil
+}
+
+func TestPinSnapshot(t *testing.T) {
+	aead, err := fakekms.NewAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("error creating AEAD")
+	}
+	keys := query.StaticKeys(aead, []byte("ctx"))
+	order, _ := query.ParseOrderBy("title")
+	pinner := &fakePinner{next: "1700000000.000000001"}
+
+	// The first page takes a new snapshot.
+	ctx, err := query.PinSnapshot(context.Background(), "", order, keys, pinner)
+	if err != nil {
+		t.Fatalf("PinSnapshot failed: %v", err)
+	}
+	if got := ctx.Value(pinnedKey{}); got != "1700000000.000000001" {
+		t.Fatalf("first page pinned to %v, want 1700000000.000000001", got)
+	}
+	tok, err := query.NewCursorContext(ctx, &testpb.Book{Title: "Dune"}, order, keys)
+	if err != nil {
+		t.Fatalf("NewCursorContext failed: %v", err)
+	}
+
+	// Later pages reuse the snapshot of the token, not a new one.
+	pinner.next = "1800000000"
+	ctx, err = query.PinSnapshot(context.Background(), tok, order, keys, pinner)
+	if err != nil {
+		t.Fatalf("PinSnapshot failed: %v", err)
+	}
+	if got := ctx.Value(pinnedKey{}); got != "1700000000.000000001" {
+		t.Fatalf("second page pinned to %v, want 1700000000.000000001", got)
+	}
+	if got, ok := query.SnapshotFromContext(ctx); !ok || got != "1700000000.000000001" {
+		t.Fatalf("SnapshotFromContext = %q, %v", got, ok)
+	}
+
+	info, err := query.InspectToken(tok, aead, []byte("ctx"))
+	if err != nil {
+		t.Fatalf("InspectToken failed: %v", err)
+	}
+	if info.Snapshot != "1700000000.000000001" {
+		t.Errorf("TokenInfo.Snapshot = %q, want 1700000000.000000001", info.Snapshot)
+	}
+
+	pinner.expired = "1700000000.000000001"
+	if _, err := query.PinSnapshot(context.Background(), tok, order, keys, pinner); err == nil {
+		t.Error("PinSnapshot with an expired snapshot: got nil error")
+	}
+	other, _ := query.ParseOrderBy("title desc")
+	if _, err := query.PinSnapshot(context.Background(), tok, other, keys, pinner); !errors.Is(err, query.ErrInvalidPageToken) {
+		t.Errorf("PinSnapshot with another order: got %v, want ErrInvalidPageToken", err)
+	}
+}
+
+func TestPinSnapshot_Unpinned(t *testing.T) {
+	aead, err := fakekms.NewAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("error creating AEAD")
+	}
+	keys := query.StaticKeys(aead, []byte("ctx"))
+	order, _ := query.ParseOrderBy("title")
+	pinner := &fakePinner{next: "1"}
+
+	tok, err := query.NewCursor(&testpb.Book{Title: "Dune"}, order, aead, []byte("ctx"))
+	if err != nil {
+		t.Fatalf("NewCursor failed: %v", err)
+	}
+	ctx, err := query.PinSnapshot(context.Background(), tok, order, keys, pinner)
+	if err != nil {
+		t.Fatalf("PinSnapshot failed: %v", err)
+	}
+	if _, ok := query.SnapshotFromContext(ctx); ok {
+		t.Error("token without a snapshot was pinned")
+	}
+
+	tok, err = query.NewSnapshotCursor(&testpb.Book{Title: "Dune"}, query.DirectionPrevious, "42", order, aead, []byte("ctx"))
+	if err != nil {
+		t.Fatalf("NewSnapshotCursor failed: %v", err)
+	}
+	decoded, dir, err := query.DecodeDirectionalCursor[testpb.Book](tok, order, aead, []byte("ctx"))
+	if err != nil || decoded.GetTitle() != "Dune" || dir != query.DirectionPrevious {
+		t.Fatalf("DecodeDirectionalCursor = %v, %v, %v", decoded, dir, err)
+	}
+}
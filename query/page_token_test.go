@@ -1,11 +1,22 @@
 package query_test
 
 import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"math"
+	"net/url"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
-	"github.com/hxtk/aip/internal/testpb"
+	"github.com/hxtk/aip/aiptest/testpb"
 	"github.com/hxtk/aip/query"
 	"github.com/tink-crypto/tink-go/v2/testing/fakekms"
+	"github.com/tink-crypto/tink-go/v2/tink"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // The fake KMS should only be used in tests. It is not secure.
@@ -43,6 +54,137 @@ func TestCursorRoundtrip_Scalar(t *testing.T) {
 	}
 }
 
+func TestCursorRoundtrip_Timestamp(t *testing.T) {
+	aead, err := fakekms.NewAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("error creating AEAD")
+	}
+	aad := []byte("ctx")
+
+	order, err := query.ParseOrderBy("create_time desc, name")
+	if err != nil {
+		t.Fatalf("ParseOrderBy failed: %v", err)
+	}
+
+	for _, book := range []*testpb.Book{
+		{Name: "books/1", CreateTime: &timestamppb.Timestamp{Seconds: 1700000000, Nanos: 42}},
+		{Name: "books/2"},
+	} {
+		tok, err := query.NewCursor(book, order, aead, aad)
+		if err != nil {
+			t.Fatalf("NewCursor failed: %v", err)
+		}
+		decoded, err := query.DecodeCursor[testpb.Book](tok, order, aead, aad)
+		if err != nil {
+			t.Fatalf("DecodeCursor failed: %v", err)
+		}
+		if !proto.Equal(decoded.GetCreateTime(), book.GetCreateTime()) {
+			t.Errorf("got create_time %v, want %v", decoded.GetCreateTime(), book.GetCreateTime())
+		}
+
+		next, err := query.CursorFilter(decoded, order)
+		if err != nil {
+			t.Fatalf("CursorFilter failed: %v", err)
+		}
+		if next(book) {
+			t.Errorf("cursor %s should not select itself", book.GetName())
+		}
+	}
+}
+
+func TestCursorRoundtrip_FloatBytesEnum(t *testing.T) {
+	aead, err := fakekms.NewAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("error creating AEAD")
+	}
+	aad := []byte("ctx")
+
+	order, err := query.ParseOrderBy("average_rating desc, weight_kg, checksum, format, name")
+	if err != nil {
+		t.Fatalf("ParseOrderBy failed: %v", err)
+	}
+	less, err := query.Less[*testpb.Book](order)
+	if err != nil {
+		t.Fatalf("Less failed: %v", err)
+	}
+
+	books := []*testpb.Book{
+		{Name: "books/1", AverageRating: 4.5, WeightKg: 0.25, Checksum: []byte{0x01}},
+		{Name: "books/2", AverageRating: 4.5, WeightKg: 0.25, Checksum: []byte{0x01, 0x00}, Format: testpb.Format_HARDCOVER},
+		{Name: "books/3", AverageRating: 4.5, WeightKg: 0.5, Format: testpb.Format_PAPERBACK},
+		{Name: "books/4", AverageRating: 4.5, WeightKg: 0.5, Format: testpb.Format_EBOOK},
+		{Name: "books/5", AverageRating: -1},
+		{Name: "books/6", AverageRating: math.NaN(), Checksum: []byte{0xff}},
+	}
+	for i := 1; i < len(books); i++ {
+		if !less(books[i-1], books[i]) {
+			t.Fatalf("%s should sort before %s", books[i-1].GetName(), books[i].GetName())
+		}
+	}
+
+	for i, book := range books {
+		tok, err := query.NewCursor(book, order, aead, aad)
+		if err != nil {
+			t.Fatalf("NewCursor failed: %v", err)
+		}
+		decoded, err := query.DecodeCursor[testpb.Book](tok, order, aead, aad)
+		if err != nil {
+			t.Fatalf("DecodeCursor failed: %v", err)
+		}
+		pruned := &testpb.Book{
+			Name:          book.GetName(),
+			AverageRating: book.GetAverageRating(),
+			WeightKg:      book.GetWeightKg(),
+			Checksum:      book.GetChecksum(),
+			Format:        book.GetFormat(),
+		}
+		if !proto.Equal(decoded, pruned) {
+			t.Errorf("got cursor %v, want %v", decoded, pruned)
+		}
+
+		next, err := query.CursorFilter(decoded, order)
+		if err != nil {
+			t.Fatalf("CursorFilter failed: %v", err)
+		}
+		for j, other := range books {
+			if got, want := next(other), j > i; got != want {
+				t.Errorf("cursor %s selects %s = %v, want %v", book.GetName(), other.GetName(), got, want)
+			}
+		}
+	}
+}
+
+func TestCursorRoundtrip_ExplicitPresence(t *testing.T) {
+	aead, err := fakekms.NewAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("error creating AEAD")
+	}
+	aad := []byte("ctx")
+
+	order, err := query.ParseOrderBy("page_count, name")
+	if err != nil {
+		t.Fatalf("ParseOrderBy failed: %v", err)
+	}
+
+	zero := int32(0)
+	for _, book := range []*testpb.Book{
+		{Name: "books/1", PageCount: &zero},
+		{Name: "books/2"},
+	} {
+		tok, err := query.NewCursor(book, order, aead, aad)
+		if err != nil {
+			t.Fatalf("NewCursor failed: %v", err)
+		}
+		decoded, err := query.DecodeCursor[testpb.Book](tok, order, aead, aad)
+		if err != nil {
+			t.Fatalf("DecodeCursor failed: %v", err)
+		}
+		if (decoded.PageCount == nil) != (book.PageCount == nil) {
+			t.Errorf("%s: page_count presence not preserved", book.GetName())
+		}
+	}
+}
+
 func TestCursorRoundtrip_NestedPresent(t *testing.T) {
 	aead, err := fakekms.NewAEAD(keyURI)
 	if err != nil {
@@ -155,6 +297,170 @@ func TestTamperedTokenFails(t *testing.T) {
 	}
 }
 
+func TestCursorCompression(t *testing.T) {
+	aead, err := fakekms.NewAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("error creating AEAD")
+	}
+	aad := []byte("ctx")
+	order, err := query.ParseOrderBy("title, name")
+	if err != nil {
+		t.Fatalf("ParseOrderBy failed: %v", err)
+	}
+
+	for _, tc := range []struct {
+		book    *testpb.Book
+		shorter bool
+	}{
+		{&testpb.Book{
+			Name:  "publishers/pragmatic-bookshelf/books/the-pragmatic-programmer-your-journey-to-mastery",
+			Title: "The Pragmatic Programmer: Your Journey to Mastery, 20th Anniversary Edition (The Pragmatic Programmer)",
+		}, true},
+		// Cursors that do not compress are left as they are.
+		{&testpb.Book{Name: "books/1", Title: "Dune"}, false},
+	} {
+		book := tc.book
+		plain, err := query.NewCursor(book, order, aead, aad)
+		if err != nil {
+			t.Fatalf("NewCursor failed: %v", err)
+		}
+		compressed, err := query.NewCursor(book, order, aead, aad, query.WithCompression())
+		if err != nil {
+			t.Fatalf("NewCursor failed: %v", err)
+		}
+		if got := len(compressed) < len(plain); got != tc.shorter || len(compressed) > len(plain) {
+			t.Errorf("got compressed token of %d bytes and plain token of %d bytes", len(compressed), len(plain))
+		}
+
+		decoded, err := query.DecodeCursor[testpb.Book](compressed, order, aead, aad)
+		if err != nil {
+			t.Fatalf("DecodeCursor failed: %v", err)
+		}
+		if !proto.Equal(decoded, book) {
+			t.Errorf("got %v, want %v", decoded, book)
+		}
+		info, err := query.InspectToken(compressed, aead, aad)
+		if err != nil {
+			t.Fatalf("InspectToken failed: %v", err)
+		}
+		if raw, _ := proto.Marshal(book); !slices.Equal(info.Cursor, raw) {
+			t.Errorf("InspectToken returned cursor %x, want %x", info.Cursor, raw)
+		}
+	}
+}
+
+func TestCursorFormat(t *testing.T) {
+	aead, err := fakekms.NewAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("error creating AEAD")
+	}
+	aad := []byte("ctx")
+	order, err := query.ParseOrderBy("title, create_time desc")
+	if err != nil {
+		t.Fatalf("ParseOrderBy failed: %v", err)
+	}
+	book := &testpb.Book{Title: "Dune", CreateTime: timestamppb.New(time.Unix(1700000000, 0))}
+
+	for _, opts := range [][]query.TokenOption{
+		{query.WithCursorFormat(query.CursorJSON)},
+		{query.WithCursorFormat(query.CursorJSON), query.WithCompression()},
+	} {
+		tok, err := query.NewCursor(book, order, aead, aad, opts...)
+		if err != nil {
+			t.Fatalf("NewCursor failed: %v", err)
+		}
+		// Tokens are decoded in the format they were minted in.
+		decoded, err := query.DecodeCursor[testpb.Book](tok, order, aead, aad)
+		if err != nil {
+			t.Fatalf("DecodeCursor failed: %v", err)
+		}
+		if !proto.Equal(decoded, book) {
+			t.Errorf("got %v, want %v", decoded, book)
+		}
+
+		info, err := query.InspectToken(tok, aead, aad)
+		if err != nil {
+			t.Fatalf("InspectToken failed: %v", err)
+		}
+		if info.CursorFormat != query.CursorJSON {
+			t.Errorf("TokenInfo.CursorFormat = %s, want JSON", info.CursorFormat)
+		}
+		if want := `{"title":"Dune","createTime":"2023-11-14T22:13:20Z"}`; strings.ReplaceAll(string(info.Cursor), " ", "") != want {
+			t.Errorf("TokenInfo.Cursor = %s, want %s", info.Cursor, want)
+		}
+	}
+
+	tok, err := query.NewCursor(book, order, aead, aad)
+	if err != nil {
+		t.Fatalf("NewCursor failed: %v", err)
+	}
+	info, err := query.InspectToken(tok, aead, aad)
+	if err != nil {
+		t.Fatalf("InspectToken failed: %v", err)
+	}
+	if info.CursorFormat != query.CursorBinary {
+		t.Errorf("TokenInfo.CursorFormat = %s, want BINARY by default", info.CursorFormat)
+	}
+}
+
+func TestTokenEncoding(t *testing.T) {
+	aead, err := fakekms.NewAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("error creating AEAD")
+	}
+	aad := []byte("ctx")
+	order, err := query.ParseOrderBy("title")
+	if err != nil {
+		t.Fatalf("ParseOrderBy failed: %v", err)
+	}
+	book := &testpb.Book{Title: "Dune"}
+
+	// The ciphertext is random, so mint until the token differs from one in
+	// the default encoding, i.e. it has padding or a '+' or '/'.
+	var padded string
+	for !strings.ContainsAny(padded, "+/=") {
+		padded, err = query.NewCursor(book, order, aead, aad, query.WithEncoding(base64.StdEncoding))
+		if err != nil {
+			t.Fatalf("NewCursor failed: %v", err)
+		}
+	}
+	if _, err := query.DecodeCursor[testpb.Book](padded, order, aead, aad, query.WithEncoding(base64.StdEncoding)); err != nil {
+		t.Errorf("DecodeCursor with the minting encoding failed: %v", err)
+	}
+
+	token, err := query.NewCursor(book, order, aead, aad)
+	if err != nil {
+		t.Fatalf("NewCursor failed: %v", err)
+	}
+	cipher, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		t.Fatalf("token is not raw URL-safe base64: %v", err)
+	}
+	std := base64.StdEncoding.EncodeToString(cipher)
+	for name, mangled := range map[string]string{
+		"standard":        std,
+		"padded":          base64.URLEncoding.EncodeToString(cipher),
+		"query escaped":   url.QueryEscape(std),
+		"escaped twice":   url.QueryEscape(url.QueryEscape(std)),
+		"plus as space":   strings.ReplaceAll(std, "+", " "),
+		"minted standard": padded,
+	} {
+		t.Run(name, func(t *testing.T) {
+			decoded, err := query.DecodeCursor[testpb.Book](mangled, order, aead, aad, query.WithLenientDecoding())
+			if err != nil {
+				t.Fatalf("DecodeCursor failed: %v", err)
+			}
+			if decoded.GetTitle() != "Dune" {
+				t.Errorf("got %q, want %q", decoded.GetTitle(), "Dune")
+			}
+		})
+	}
+
+	if _, err := query.DecodeCursor[testpb.Book](padded, order, aead, aad); !errors.Is(err, query.ErrInvalidPageToken) {
+		t.Errorf("DecodeCursor of a padded token without leniency: got %v, want ErrInvalidPageToken", err)
+	}
+}
+
 func TestLessAndCursorFilter(t *testing.T) {
 	orderAsc, err := query.ParseOrderBy("title")
 	if err != nil {
@@ -194,3 +500,354 @@ func TestRejectMapFieldsInSortKeys(t *testing.T) {
 	}
 }
 
+func BenchmarkNewCursor(b *testing.B) {
+	aead, err := fakekms.NewAEAD(keyURI)
+	if err != nil {
+		b.Fatalf("error creating AEAD")
+	}
+	aad := []byte("ctx")
+	book := &testpb.Book{
+		Title:  "Dune",
+		Author: &testpb.Author{GivenName: "Frank", FamilyName: "Herbert"},
+	}
+	order, _ := query.ParseOrderBy("author.family_name, title desc")
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := query.NewCursor(book, order, aead, aad); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeCursor(b *testing.B) {
+	aead, err := fakekms.NewAEAD(keyURI)
+	if err != nil {
+		b.Fatalf("error creating AEAD")
+	}
+	aad := []byte("ctx")
+	book := &testpb.Book{
+		Title:  "Dune",
+		Author: &testpb.Author{GivenName: "Frank", FamilyName: "Herbert"},
+	}
+	order, _ := query.ParseOrderBy("author.family_name, title desc")
+	tok, err := query.NewCursor(book, order, aead, aad)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := query.DecodeCursor[testpb.Book](tok, order, aead, aad); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestCombinedPredicate(t *testing.T) {
+	order, err := query.ParseOrderBy("title")
+	if err != nil {
+		t.Fatalf("ParseOrderBy failed: %v", err)
+	}
+	filter, err := query.ParseFilter(`author.family_name = "Hunt"`)
+	if err != nil {
+		t.Fatalf("ParseFilter failed: %v", err)
+	}
+
+	hunt := &testpb.Author{FamilyName: "Hunt"}
+	books := []*testpb.Book{
+		{Title: "A", Author: hunt},
+		{Title: "B", Author: hunt},
+		{Title: "C", Author: hunt},
+		{Title: "D", Author: &testpb.Author{FamilyName: "Thomas"}},
+	}
+
+	tests := []struct {
+		name   string
+		filter *query.Filter
+		cursor *testpb.Book
+		want   []string
+	}{
+		{"first page", filter, nil, []string{"A", "B", "C"}},
+		{"after cursor", filter, &testpb.Book{Title: "A"}, []string{"B", "C"}},
+		{"cursor only", nil, &testpb.Book{Title: "B"}, []string{"C", "D"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pred, err := query.CombinedPredicate(tc.filter, tc.cursor, order)
+			if err != nil {
+				t.Fatalf("CombinedPredicate failed: %v", err)
+			}
+			var got []string
+			for _, b := range books {
+				if pred(b) {
+					got = append(got, b.GetTitle())
+				}
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	invalid, _ := query.ParseOrderBy("authors")
+	if _, err := query.CombinedPredicate(filter, books[0], invalid); err == nil {
+		t.Errorf("expected error for invalid order")
+	}
+}
+
+func TestPageTokensBidirectional(t *testing.T) {
+	aead, err := fakekms.NewAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("error creating AEAD")
+	}
+	aad := []byte("ctx")
+	order, err := query.ParseOrderBy("title")
+	if err != nil {
+		t.Fatalf("ParseOrderBy failed: %v", err)
+	}
+
+	var books []*testpb.Book
+	for _, title := range []string{"A", "B", "C", "D", "E", "F"} {
+		books = append(books, &testpb.Book{Title: title, Name: "books/" + title})
+	}
+	less, err := query.Less[*testpb.Book](order)
+	if err != nil {
+		t.Fatalf("Less failed: %v", err)
+	}
+	reverseLess, err := query.Less[*testpb.Book](query.ReverseOrder(order))
+	if err != nil {
+		t.Fatalf("Less failed: %v", err)
+	}
+
+	// Serve the page of size 2 continuing from the given token.
+	page := func(token string) []*testpb.Book {
+		if token == "" {
+			return books[:2]
+		}
+		cursor, dir, err := query.DecodeDirectionalCursor[testpb.Book](token, order, aead, aad)
+		if err != nil {
+			t.Fatalf("DecodeDirectionalCursor failed: %v", err)
+		}
+		filter, err := query.DirectionalCursorFilter(cursor, order, dir)
+		if err != nil {
+			t.Fatalf("DirectionalCursorFilter failed: %v", err)
+		}
+		var matched []*testpb.Book
+		for _, b := range books {
+			if filter(b) {
+				matched = append(matched, b)
+			}
+		}
+		if dir == query.DirectionNext {
+			slices.SortFunc(matched, func(a, b *testpb.Book) int {
+				if less(a, b) {
+					return -1
+				}
+				return 1
+			})
+			return matched[:min(2, len(matched))]
+		}
+		slices.SortFunc(matched, func(a, b *testpb.Book) int {
+			if reverseLess(a, b) {
+				return -1
+			}
+			return 1
+		})
+		matched = matched[:min(2, len(matched))]
+		slices.Reverse(matched)
+		return matched
+	}
+	titles := func(bs []*testpb.Book) []string {
+		var out []string
+		for _, b := range bs {
+			out = append(out, b.GetTitle())
+		}
+		return out
+	}
+
+	first := page("")
+	next, _, err := query.NewPageTokens(first[0], first[len(first)-1], order, aead, aad)
+	if err != nil {
+		t.Fatalf("NewPageTokens failed: %v", err)
+	}
+	second := page(next)
+	if got, want := titles(second), []string{"C", "D"}; !slices.Equal(got, want) {
+		t.Fatalf("second page = %v, want %v", got, want)
+	}
+
+	_, prev, err := query.NewPageTokens(second[0], second[len(second)-1], order, aead, aad)
+	if err != nil {
+		t.Fatalf("NewPageTokens failed: %v", err)
+	}
+	if got, want := titles(page(prev)), titles(first); !slices.Equal(got, want) {
+		t.Fatalf("previous page = %v, want %v", got, want)
+	}
+
+	// Forward-only decoding still accepts previous-page tokens.
+	if _, err := query.DecodeCursor[testpb.Book](prev, order, aead, aad); err != nil {
+		t.Fatalf("DecodeCursor failed on previous-page token: %v", err)
+	}
+}
+
+type tenantKey struct{}
+
+func TestCursorContextPerTenant(t *testing.T) {
+	aead, err := fakekms.NewAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("error creating AEAD")
+	}
+	keys := func(ctx context.Context) (tink.AEAD, []byte) {
+		tenant, ok := ctx.Value(tenantKey{}).(string)
+		if !ok {
+			return nil, nil
+		}
+		return aead, []byte("tenant=" + tenant)
+	}
+
+	book := &testpb.Book{Title: "Dune"}
+	order, _ := query.ParseOrderBy("title")
+	ctxA := context.WithValue(context.Background(), tenantKey{}, "a")
+	ctxB := context.WithValue(context.Background(), tenantKey{}, "b")
+
+	tok, err := query.NewCursorContext(ctxA, book, order, keys)
+	if err != nil {
+		t.Fatalf("NewCursorContext failed: %v", err)
+	}
+
+	decoded, err := query.DecodeCursorContext[testpb.Book](ctxA, tok, order, keys)
+	if err != nil {
+		t.Fatalf("DecodeCursorContext failed: %v", err)
+	}
+	if decoded.GetTitle() != "Dune" {
+		t.Fatalf("got %q, want %q", decoded.GetTitle(), "Dune")
+	}
+
+	if _, err := query.DecodeCursorContext[testpb.Book](ctxB, tok, order, keys); !errors.Is(err, query.ErrInvalidPageToken) {
+		t.Fatalf("decoding with another tenant's keys: got %v, want ErrInvalidPageToken", err)
+	}
+	if _, err := query.NewCursorContext(context.Background(), book, order, keys); !errors.Is(err, query.ErrNoTokenKey) {
+		t.Fatalf("minting without a tenant: got %v, want ErrNoTokenKey", err)
+	}
+
+	static, err := query.DecodeCursorContext[testpb.Book](ctxB, tok, order, query.StaticKeys(aead, []byte("tenant=a")))
+	if err != nil || static.GetTitle() != "Dune" {
+		t.Fatalf("DecodeCursorContext with StaticKeys = %v, %v", static, err)
+	}
+}
+
+func TestBindIdentity(t *testing.T) {
+	aead, err := fakekms.NewAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("error creating AEAD")
+	}
+	keys := query.BindIdentity(query.StaticKeys(aead, []byte("ctx")), func(ctx context.Context) string {
+		caller, _ := ctx.Value(callerKey{}).(string)
+		return caller
+	})
+
+	book := &testpb.Book{Title: "Dune"}
+	order, _ := query.ParseOrderBy("title")
+	alice := context.WithValue(context.Background(), callerKey{}, "users/alice")
+	bob := context.WithValue(context.Background(), callerKey{}, "users/bob")
+
+	tok, err := query.NewCursorContext(alice, book, order, keys)
+	if err != nil {
+		t.Fatalf("NewCursorContext failed: %v", err)
+	}
+	decoded, err := query.DecodeCursorContext[testpb.Book](alice, tok, order, keys)
+	if err != nil {
+		t.Fatalf("DecodeCursorContext failed: %v", err)
+	}
+	if decoded.GetTitle() != "Dune" {
+		t.Fatalf("got %q, want %q", decoded.GetTitle(), "Dune")
+	}
+
+	for name, ctx := range map[string]context.Context{"other caller": bob, "anonymous": context.Background()} {
+		if _, err := query.DecodeCursorContext[testpb.Book](ctx, tok, order, keys); !errors.Is(err, query.ErrInvalidPageToken) {
+			t.Errorf("decoding as %s: got %v, want ErrInvalidPageToken", name, err)
+		}
+	}
+	if _, err := query.DecodeCursor[testpb.Book](tok, order, aead, []byte("ctx")); !errors.Is(err, query.ErrInvalidPageToken) {
+		t.Errorf("decoding without binding: got %v, want ErrInvalidPageToken", err)
+	}
+}
+
+func TestTokenMaxAge(t *testing.T) {
+	aead, err := fakekms.NewAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("error creating AEAD")
+	}
+	aad := []byte("ctx")
+	order, err := query.ParseOrderBy("title")
+	if err != nil {
+		t.Fatalf("ParseOrderBy failed: %v", err)
+	}
+	token, err := query.NewCursor(&testpb.Book{Title: "Dune"}, order, aead, aad)
+	if err != nil {
+		t.Fatalf("NewCursor failed: %v", err)
+	}
+
+	if _, err := query.DecodeCursor[testpb.Book](token, order, aead, aad, query.WithMaxAge(time.Hour)); err != nil {
+		t.Errorf("DecodeCursor of a fresh token failed: %v", err)
+	}
+
+	time.Sleep(time.Millisecond)
+	_, err = query.DecodeCursor[testpb.Book](token, order, aead, aad, query.WithMaxAge(time.Microsecond))
+	if !errors.Is(err, query.ErrPageTokenExpired) || !errors.Is(err, query.ErrInvalidPageToken) {
+		t.Errorf("DecodeCursor of an expired token = %v, want ErrPageTokenExpired", err)
+	}
+	if _, err := query.InspectToken(token, aead, aad, query.WithMaxAge(time.Microsecond)); err != nil {
+		t.Errorf("InspectToken of an expired token failed: %v", err)
+	}
+}
+
+func TestStaleCursorSchema(t *testing.T) {
+	aead, err := fakekms.NewAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("error creating AEAD")
+	}
+	aad := []byte("ctx")
+	mint := func(m proto.Message, orderBy string) (string, []query.OrderBy) {
+		t.Helper()
+		order, err := query.ParseOrderBy(orderBy)
+		if err != nil {
+			t.Fatalf("ParseOrderBy failed: %v", err)
+		}
+		token, err := query.NewCursor(m, order, aead, aad)
+		if err != nil {
+			t.Fatalf("NewCursor failed: %v", err)
+		}
+		return token, order
+	}
+
+	// Comment.text has the number and type Review.text had, so tokens
+	// survive the change.
+	token, order := mint(&testpb.Review{Text: "great"}, "text")
+	comment, err := query.DecodeCursor[testpb.Comment](token, order, aead, aad)
+	if err != nil || comment.GetText() != "great" {
+		t.Errorf("DecodeCursor of a compatible schema = %v, %v", comment, err)
+	}
+
+	for name, decode := range map[string]func() error{
+		// Book.name has another number than Comment.name, though the
+		// cursor bytes are a valid Book.
+		"renumbered": func() error {
+			token, order := mint(&testpb.Comment{Name: "comments/1"}, "name")
+			_, err := query.DecodeCursor[testpb.Book](token, order, aead, aad)
+			return err
+		},
+		"removed": func() error {
+			token, order := mint(&testpb.Book{PageCount: proto.Int32(3)}, "page_count")
+			_, err := query.DecodeCursor[testpb.Author](token, order, aead, aad)
+			return err
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := decode()
+			if !errors.Is(err, query.ErrStaleCursorSchema) || !errors.Is(err, query.ErrInvalidPageToken) {
+				t.Errorf("DecodeCursor = %v, want ErrStaleCursorSchema", err)
+			}
+		})
+	}
+}
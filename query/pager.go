@@ -0,0 +1,154 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ErrRepeatedPageToken is reported by Pager when a List RPC returns the page
+// token it was called with, which would otherwise page forever.
+var ErrRepeatedPageToken = errors.New("server returned the same page token twice")
+
+// Pager iterates over every item of an AIP-132 List RPC, following the
+// AIP-158 page_token and next_page_token fields from page to page.
+//
+// Example, with a connect client:
+//
+//	pager, err := query.NewPager(ctx,
+//	    func(ctx context.Context, req *pb.ListBooksRequest) (*pb.ListBooksResponse, error) {
+//	        resp, err := client.ListBooks(ctx, connect.NewRequest(req))
+//	        if err != nil {
+//	            return nil, err
+//	        }
+//	        return resp.Msg, nil
+//	    },
+//	    &pb.ListBooksRequest{Parent: "shelves/1"},
+//	    (*pb.ListBooksResponse).GetBooks,
+//	)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	for book := range pager.All() {
+//	    ...
+//	}
+//	if err := pager.Err(); err != nil {
+//	    log.Fatal(err)
+//	}
+//
+// A Pager is not safe for concurrent use.
+type Pager[Req, Resp proto.Message, Item any] struct {
+	ctx   context.Context
+	list  func(context.Context, Req) (Resp, error)
+	items func(Resp) []Item
+
+	req       Req
+	pageToken protoreflect.FieldDescriptor
+	nextToken protoreflect.FieldDescriptor
+
+	page []Item
+	// token is the page token of the next page, or empty after the last.
+	token   string
+	started bool
+	err     error
+}
+
+// NewPager returns a Pager calling list with req, then with copies of req
+// carrying each next_page_token in turn, until a response has an empty
+// next_page_token. items returns the items of a page.
+//
+// req is not modified. NewPager returns an error if Req has no string
+// page_token field or Resp no string next_page_token field.
+func NewPager[Req, Resp proto.Message, Item any](
+	ctx context.Context,
+	list func(context.Context, Req) (Resp, error),
+	req Req,
+	items func(Resp) []Item,
+) (*Pager[Req, Resp, Item], error) {
+	pageToken, err := stringField(req.ProtoReflect().Descriptor(), "page_token")
+	if err != nil {
+		return nil, err
+	}
+	var resp Resp
+	nextToken, err := stringField(resp.ProtoReflect().Descriptor(), "next_page_token")
+	if err != nil {
+		return nil, err
+	}
+	return &Pager[Req, Resp, Item]{
+		ctx:       ctx,
+		list:      list,
+		items:     items,
+		req:       proto.Clone(req).(Req),
+		pageToken: pageToken,
+		nextToken: nextToken,
+		token:     req.ProtoReflect().Get(pageToken).String(),
+	}, nil
+}
+
+// stringField returns the singular string field of desc with the given name.
+func stringField(desc protoreflect.MessageDescriptor, name protoreflect.Name) (protoreflect.FieldDescriptor, error) {
+	fd := desc.Fields().ByName(name)
+	if fd == nil || fd.Kind() != protoreflect.StringKind || fd.IsList() {
+		return nil, fmt.Errorf("message %s has no string field %s", desc.FullName(), name)
+	}
+	return fd, nil
+}
+
+// Next returns the next item. It returns false when there are no more items
+// or a call failed; Err distinguishes the two.
+func (p *Pager[Req, Resp, Item]) Next() (Item, bool) {
+	for len(p.page) == 0 {
+		if p.err != nil || (p.started && p.token == "") {
+			var zero Item
+			return zero, false
+		}
+		p.fetch()
+	}
+	item := p.page[0]
+	p.page = p.page[1:]
+	return item, true
+}
+
+// fetch loads the next page.
+func (p *Pager[Req, Resp, Item]) fetch() {
+	if err := p.ctx.Err(); err != nil {
+		p.err = err
+		return
+	}
+	p.req.ProtoReflect().Set(p.pageToken, protoreflect.ValueOfString(p.token))
+	resp, err := p.list(p.ctx, p.req)
+	if err != nil {
+		p.err = err
+		return
+	}
+	next := resp.ProtoReflect().Get(p.nextToken).String()
+	if next != "" && next == p.token {
+		p.err = ErrRepeatedPageToken
+		return
+	}
+	p.started = true
+	p.token = next
+	p.page = p.items(resp)
+}
+
+// All returns an iterator over the remaining items. Iteration stops early
+// if a call fails; check Err afterwards.
+func (p *Pager[Req, Resp, Item]) All() iter.Seq[Item] {
+	return func(yield func(Item) bool) {
+		for {
+			item, ok := p.Next()
+			if !ok || !yield(item) {
+				return
+			}
+		}
+	}
+}
+
+// Err returns the error that stopped iteration, if any.
+func (p *Pager[Req, Resp, Item]) Err() error {
+	return p.err
+}
@@ -0,0 +1,122 @@
+package query_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/query"
+)
+
+// listBooks returns a fake List RPC serving books with offset page tokens.
+func listBooks(books []*testpb.Book, calls *int) func(context.Context, *testpb.ListBooksRequest) (*testpb.ListBooksResponse, error) {
+	return func(_ context.Context, req *testpb.ListBooksRequest) (*testpb.ListBooksResponse, error) {
+		*calls++
+		start := 0
+		if req.GetPageToken() != "" {
+			var err error
+			start, err = strconv.Atoi(req.GetPageToken())
+			if err != nil {
+				return nil, fmt.Errorf("bad page token %q", req.GetPageToken())
+			}
+		}
+		end := min(start+int(req.GetPageSize()), len(books))
+		resp := &testpb.ListBooksResponse{Books: books[start:end]}
+		if end < len(books) {
+			resp.NextPageToken = strconv.Itoa(end)
+		}
+		return resp, nil
+	}
+}
+
+func TestPager(t *testing.T) {
+	books := makeBooks(7)
+	var calls int
+	req := &testpb.ListBooksRequest{PageSize: 3}
+	pager, err := query.NewPager(context.Background(), listBooks(books, &calls), req, (*testpb.ListBooksResponse).GetBooks)
+	require.NoError(t, err)
+
+	var got []*testpb.Book
+	for book := range pager.All() {
+		got = append(got, book)
+	}
+	require.NoError(t, pager.Err())
+	require.Equal(t, books, got)
+	require.Equal(t, 3, calls)
+	require.Empty(t, req.GetPageToken(), "request should not be modified")
+
+	_, ok := pager.Next()
+	require.False(t, ok)
+	require.Equal(t, 3, calls, "exhausted pager should not call the RPC again")
+}
+
+func TestPagerNextAndEmptyPages(t *testing.T) {
+	pages := []*testpb.ListBooksResponse{
+		{NextPageToken: "a"},
+		{Books: []*testpb.Book{{Name: "books/1"}}, NextPageToken: "b"},
+		{},
+	}
+	var calls int
+	list := func(_ context.Context, req *testpb.ListBooksRequest) (*testpb.ListBooksResponse, error) {
+		calls++
+		return pages[calls-1], nil
+	}
+	pager, err := query.NewPager(context.Background(), list, &testpb.ListBooksRequest{}, (*testpb.ListBooksResponse).GetBooks)
+	require.NoError(t, err)
+
+	book, ok := pager.Next()
+	require.True(t, ok)
+	require.Equal(t, "books/1", book.GetName())
+	_, ok = pager.Next()
+	require.False(t, ok)
+	require.NoError(t, pager.Err())
+	require.Equal(t, 3, calls)
+}
+
+func TestPagerErrors(t *testing.T) {
+	rpcErr := errors.New("unavailable")
+	list := func(_ context.Context, req *testpb.ListBooksRequest) (*testpb.ListBooksResponse, error) {
+		if req.GetPageToken() == "" {
+			return &testpb.ListBooksResponse{Books: []*testpb.Book{{}}, NextPageToken: "next"}, nil
+		}
+		return nil, rpcErr
+	}
+	pager, err := query.NewPager(context.Background(), list, &testpb.ListBooksRequest{}, (*testpb.ListBooksResponse).GetBooks)
+	require.NoError(t, err)
+	var n int
+	for range pager.All() {
+		n++
+	}
+	require.Equal(t, 1, n)
+	require.ErrorIs(t, pager.Err(), rpcErr)
+
+	repeat := func(_ context.Context, req *testpb.ListBooksRequest) (*testpb.ListBooksResponse, error) {
+		return &testpb.ListBooksResponse{NextPageToken: "same"}, nil
+	}
+	pager, err = query.NewPager(context.Background(), repeat, &testpb.ListBooksRequest{}, (*testpb.ListBooksResponse).GetBooks)
+	require.NoError(t, err)
+	_, ok := pager.Next()
+	require.False(t, ok)
+	require.ErrorIs(t, pager.Err(), query.ErrRepeatedPageToken)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	pager, err = query.NewPager(ctx, repeat, &testpb.ListBooksRequest{}, (*testpb.ListBooksResponse).GetBooks)
+	require.NoError(t, err)
+	_, ok = pager.Next()
+	require.False(t, ok)
+	require.ErrorIs(t, pager.Err(), context.Canceled)
+}
+
+func TestNewPagerRequiresTokenFields(t *testing.T) {
+	list := func(context.Context, *testpb.GetBookRequest) (*testpb.ListBooksResponse, error) {
+		return nil, nil
+	}
+	_, err := query.NewPager(context.Background(), list, &testpb.GetBookRequest{}, (*testpb.ListBooksResponse).GetBooks)
+	require.ErrorContains(t, err, "no string field page_token")
+}
@@ -0,0 +1,289 @@
+// Package querytest runs the SQL generated by a query.Table against a real
+// database seeded from proto fixtures. A DB serves the queries sent by
+// aiptest.Differential, which checks that WhereClause, SeekClause and
+// OrderByClause select the same resources, in the same order, as in-memory
+// evaluation:
+//
+//	db, err := querytest.OpenSQLite(ctx, table, books)
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer db.Close()
+//	divergences, err := aiptest.Differential(ctx, table, books, db.Exec, workloads...)
+//
+// OpenSQLite needs no external database. To test against the dialect used
+// in production, pass Open a connection to e.g. a PostgreSQL container
+// started with dockertest, along with the Postgres dialect.
+//
+// Each resource is stored in one row, holding the value of each column of
+// the table, as returned by Table.RowValues, and the serialized resource.
+// Tables with array or key-value columns are therefore not supported.
+package querytest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/hxtk/aip/aiptest"
+	"github.com/hxtk/aip/query"
+
+	// Registers the "sqlite" driver used by OpenSQLite.
+	_ "modernc.org/sqlite"
+)
+
+// TableName is the name of the database table holding the resources.
+const TableName = "querytest_resources"
+
+// messageColumn is the column holding the serialized resource.
+const messageColumn = "querytest_message"
+
+// Dialect describes how to create and query a table in a SQL database.
+type Dialect struct {
+	// Placeholder returns the placeholder of the ith parameter of a
+	// statement, counting from 1, e.g. "$1". If nil, the @name parameters
+	// of generated SQL are kept and bound by name with sql.Named.
+	Placeholder func(i int) string
+
+	// The column types of Go values of each type bound by Table.RowValues.
+	Bool, Int, Float, String, Bytes, Timestamp string
+}
+
+// SQLite is the dialect of SQLite.
+var SQLite = Dialect{
+	Bool:      "BOOLEAN",
+	Int:       "INTEGER",
+	Float:     "REAL",
+	String:    "TEXT",
+	Bytes:     "BLOB",
+	Timestamp: "TIMESTAMP",
+}
+
+// Postgres is the dialect of PostgreSQL.
+var Postgres = Dialect{
+	Placeholder: func(i int) string { return "$" + strconv.Itoa(i) },
+	Bool:        "BOOLEAN",
+	Int:         "BIGINT",
+	Float:       "DOUBLE PRECISION",
+	String:      "TEXT",
+	Bytes:       "BYTEA",
+	Timestamp:   "TIMESTAMPTZ",
+}
+
+// DB is a database table holding resources of type M.
+type DB[M proto.Message] struct {
+	db      *sql.DB
+	dialect Dialect
+	// owned reports whether the DB opened db, and closes it when closed.
+	owned bool
+	// newMessage returns a new, empty resource.
+	newMessage func() M
+}
+
+// OpenSQLite returns a DB holding items in a new in-memory SQLite database,
+// with a column for each column of table. LIKE is made case-sensitive, as
+// in Standard SQL and in-memory evaluation.
+func OpenSQLite[S any, M interface {
+	proto.Message
+	*S
+}](ctx context.Context, table *query.Table, items []M) (*DB[M], error) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		return nil, err
+	}
+	// Each connection to :memory: opens a distinct database.
+	db.SetMaxOpenConns(1)
+	if _, err := db.ExecContext(ctx, "PRAGMA case_sensitive_like = ON"); err != nil {
+		db.Close()
+		return nil, err
+	}
+	d, err := Open[S, M](ctx, db, SQLite, table, items)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	d.owned = true
+	return d, nil
+}
+
+// Open creates the table TableName in db, with a column for each column of
+// table, and inserts a row for each of items. The table must not exist;
+// Close drops it.
+func Open[S any, M interface {
+	proto.Message
+	*S
+}](ctx context.Context, db *sql.DB, dialect Dialect, table *query.Table, items []M) (*DB[M], error) {
+	rows := make([][]query.QueryParameter, len(items))
+	for i, item := range items {
+		row, err := table.RowValues(item)
+		if err != nil {
+			return nil, fmt.Errorf("item %d: %w", i, err)
+		}
+		rows[i] = row
+	}
+	d := &DB[M]{
+		db:         db,
+		dialect:    dialect,
+		newMessage: func() M { return M(new(S)) },
+	}
+	if err := d.create(ctx, rows); err != nil {
+		return nil, err
+	}
+	for i, row := range rows {
+		if err := d.insert(ctx, row, items[i]); err != nil {
+			return nil, fmt.Errorf("item %d: %w", i, err)
+		}
+	}
+	return d, nil
+}
+
+// create creates the table holding rows.
+func (d *DB[M]) create(ctx context.Context, rows [][]query.QueryParameter) error {
+	var columns []string
+	if len(rows) > 0 {
+		for i, p := range rows[0] {
+			if slices.ContainsFunc(rows[0][:i], func(q query.QueryParameter) bool { return q.Name == p.Name }) {
+				continue
+			}
+			columns = append(columns, p.Name+" "+d.columnType(rows, i))
+		}
+	}
+	columns = append(columns, messageColumn+" "+d.dialect.Bytes)
+	stmt := fmt.Sprintf("CREATE TABLE %s (%s)", TableName, strings.Join(columns, ", "))
+	if _, err := d.db.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("creating table: %w", err)
+	}
+	return nil
+}
+
+// columnType returns the type of the ith column of rows, from its first
+// non-NULL value.
+func (d *DB[M]) columnType(rows [][]query.QueryParameter, i int) string {
+	for _, row := range rows {
+		switch row[i].Value.(type) {
+		case bool:
+			return d.dialect.Bool
+		case int64, uint64:
+			return d.dialect.Int
+		case float64:
+			return d.dialect.Float
+		case []byte:
+			return d.dialect.Bytes
+		case time.Time:
+			return d.dialect.Timestamp
+		case string:
+			return d.dialect.String
+		}
+	}
+	return d.dialect.String
+}
+
+// insert inserts the row holding item.
+func (d *DB[M]) insert(ctx context.Context, row []query.QueryParameter, item M) error {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(item)
+	if err != nil {
+		return err
+	}
+	var columns, values []string
+	var params []query.QueryParameter
+	for i, p := range row {
+		if slices.ContainsFunc(row[:i], func(q query.QueryParameter) bool { return q.Name == p.Name }) {
+			continue
+		}
+		name := "c_" + strconv.Itoa(i)
+		columns = append(columns, p.Name)
+		values = append(values, "@"+name)
+		params = append(params, query.QueryParameter{Name: name, Value: p.Value})
+	}
+	columns = append(columns, messageColumn)
+	values = append(values, "@message")
+	params = append(params, query.QueryParameter{Name: "message", Value: data})
+
+	stmt, args := d.bind(fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", TableName, strings.Join(columns, ", "), strings.Join(values, ", ")), params)
+	_, err = d.db.ExecContext(ctx, stmt, args...)
+	return err
+}
+
+// Exec returns the resources selected by q, in order. It is an
+// aiptest.Executor.
+func (d *DB[M]) Exec(ctx context.Context, q aiptest.SQLQuery) ([]M, error) {
+	where := q.Where
+	if where == "" {
+		where = "TRUE"
+	}
+	stmt := fmt.Sprintf("SELECT %s FROM %s WHERE %s %s", messageColumn, TableName, where, q.OrderBy)
+	if q.Limit > 0 {
+		stmt += " LIMIT " + strconv.Itoa(q.Limit)
+	}
+	stmt, args := d.bind(stmt, q.Params)
+	rows, err := d.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []M
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		m := d.newMessage()
+		if err := proto.Unmarshal(data, m); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// Close drops the table, and closes the database if the DB opened it.
+func (d *DB[M]) Close() error {
+	if d.owned {
+		return d.db.Close()
+	}
+	_, err := d.db.Exec("DROP TABLE " + TableName)
+	return err
+}
+
+// parameterPattern matches the @name parameters of generated SQL.
+var parameterPattern = regexp.MustCompile(`@[A-Za-z_][A-Za-z0-9_]*`)
+
+// bind returns stmt with its parameters in the placeholders of the dialect,
+// and the arguments binding them to params.
+func (d *DB[M]) bind(stmt string, params []query.QueryParameter) (string, []any) {
+	if d.dialect.Placeholder == nil {
+		args := make([]any, len(params))
+		for i, p := range params {
+			args[i] = sql.Named(p.Name, p.Value)
+		}
+		return stmt, args
+	}
+
+	values := make(map[string]any, len(params))
+	for _, p := range params {
+		values[p.Name] = p.Value
+	}
+	positions := make(map[string]int)
+	var args []any
+	stmt = parameterPattern.ReplaceAllStringFunc(stmt, func(match string) string {
+		name := match[1:]
+		value, ok := values[name]
+		if !ok {
+			return match
+		}
+		if _, ok := positions[name]; !ok {
+			args = append(args, value)
+			positions[name] = len(args)
+		}
+		return d.dialect.Placeholder(positions[name])
+	})
+	return stmt, args
+}
@@ -0,0 +1,129 @@
+package querytest_test
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"testing"
+
+	"github.com/hxtk/aip/aiptest"
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/query"
+	"github.com/hxtk/aip/query/querytest"
+)
+
+func booksTable() *query.Table {
+	return query.NewTable().WithColumns(
+		query.NewColumn().WithFieldPath("name").WithDatabaseName("db_name").Filterable().Sortable().Build(),
+		query.NewColumn().WithFieldPath("title").WithDatabaseName("db_title").Filterable().Sortable().FilterableImplicitly().Build(),
+		query.NewColumn().WithFieldPath("author", "family_name").WithDatabaseName("db_family_name").Sortable().Build(),
+		query.NewColumn().WithFieldPath("create_time").WithDatabaseName("db_create_time").Sortable().Build(),
+		query.NewColumn().WithFieldPath("page_count").WithDatabaseName("db_page_count").Filterable().Sortable().Build(),
+		query.NewColumn().WithFieldPath("format").WithDatabaseName("db_format").
+			Enum(testpb.Format(0).Descriptor(), query.EnumOrderByName).Sortable().Build(),
+	).Build()
+}
+
+func TestSQLite_Differential(t *testing.T) {
+	ctx := context.Background()
+	table := booksTable()
+	books := aiptest.Books()
+	db, err := querytest.OpenSQLite(ctx, table, books)
+	if err != nil {
+		t.Fatalf("OpenSQLite failed: %v", err)
+	}
+	defer db.Close()
+
+	workloads := []aiptest.Workload{
+		{OrderBy: "name"},
+		{OrderBy: "title desc, name", PageSize: 2},
+		{Filter: "title = Emma OR title = Persuasion", OrderBy: "create_time desc, name", PageSize: 1},
+		{Filter: "title:an OR page_count = 412", OrderBy: "name"},
+		{Filter: "NOT title = Dune AND page_count:*", OrderBy: "page_count, name"},
+		{Filter: "Talisman", OrderBy: "name"},
+		{OrderBy: "author.family_name, name", PageSize: 2},
+		{OrderBy: "create_time, name", PageSize: 3},
+		{OrderBy: "format desc, name", PageSize: 2},
+	}
+	divergences, err := aiptest.Differential(ctx, table, books, db.Exec, workloads...)
+	if err != nil {
+		t.Fatalf("Differential failed: %v", err)
+	}
+	for _, d := range divergences {
+		t.Error(d)
+	}
+}
+
+func TestSQLite_Exec(t *testing.T) {
+	ctx := context.Background()
+	table := booksTable()
+	db, err := querytest.OpenSQLite(ctx, table, aiptest.Books())
+	if err != nil {
+		t.Fatalf("OpenSQLite failed: %v", err)
+	}
+	defer db.Close()
+
+	f, err := query.ParseFilter(`title = Emma OR title = Persuasion`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	where, params, err := table.WhereClause(f, "p_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := db.Exec(ctx, aiptest.SQLQuery{Where: where, OrderBy: "ORDER BY db_name DESC", Limit: 1, Params: params})
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if len(got) != 1 || got[0].GetName() != "books/4" {
+		t.Errorf("Exec returned %v, want books/4", got)
+	}
+}
+
+func TestOpen_PositionalPlaceholders(t *testing.T) {
+	ctx := context.Background()
+	conn, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetMaxOpenConns(1)
+	if _, err := conn.Exec("PRAGMA case_sensitive_like = ON"); err != nil {
+		t.Fatal(err)
+	}
+
+	// SQLite also accepts numbered placeholders, like those of Postgres.
+	dialect := querytest.SQLite
+	dialect.Placeholder = func(i int) string { return "?" + strconv.Itoa(i) }
+	table := booksTable()
+	books := aiptest.Books()
+	db, err := querytest.Open(ctx, conn, dialect, table, books)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	workload := aiptest.Workload{Filter: "title:e", OrderBy: "page_count desc, name", PageSize: 2}
+	divergences, err := aiptest.Differential(ctx, table, books, db.Exec, workload)
+	if err != nil {
+		t.Fatalf("Differential failed: %v", err)
+	}
+	for _, d := range divergences {
+		t.Error(d)
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, err := conn.Exec("SELECT * FROM " + querytest.TableName); err == nil {
+		t.Error("Close did not drop the table")
+	}
+}
+
+func TestOpen_ArrayColumn(t *testing.T) {
+	table := query.NewTable().WithColumns(
+		query.NewColumn().WithFieldPath("tags").WithDatabaseName("db_tags").Array().Filterable().Build(),
+	).Build()
+	if _, err := querytest.OpenSQLite(context.Background(), table, aiptest.Books()); err == nil {
+		t.Error("OpenSQLite with an array column: got nil error, want error")
+	}
+}
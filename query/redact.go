@@ -0,0 +1,124 @@
+package query
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Redacted replaces the values removed by RedactValues.
+const Redacted = "<redacted>"
+
+// FilterValues returns the literal values of an AIP-160 filter, such as the
+// arguments of restrictions and bare search terms, which may hold personal
+// data that should not be logged. Field paths, function names, operators
+// and keywords are not values. The filter need not be valid: every token
+// that is not evidently part of a field path is returned, as is the input
+// left unlexed by a syntax error. String literals are returned both quoted
+// and unquoted.
+func FilterValues(filter string) []string {
+	var tokens []*token
+	lexer := NewLexer(filter)
+	var values []string
+	for {
+		t, err := lexer.Next()
+		if err != nil {
+			values = append(values, strings.TrimSpace(lexer.input))
+			break
+		}
+		if t.kind == kindEnd {
+			break
+		}
+		tokens = append(tokens, t)
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		switch t := tokens[i]; t.kind {
+		case kindString:
+			values = append(values, t.value)
+			if s, err := unquote(t.value); err == nil && s != "" {
+				values = append(values, s)
+			}
+		case kindText:
+			// A dotted sequence of text is a field path if a comparator
+			// follows it, or a function name if an argument list does.
+			j := i
+			for j+2 < len(tokens) && tokens[j+1].kind == kindDot && tokens[j+2].kind == kindText {
+				j += 2
+			}
+			next := ""
+			if j+1 < len(tokens) {
+				next = tokens[j+1].kind
+			}
+			if next != kindComparator && next != kindLParen {
+				// Values such as e-mail addresses are lexed as dotted
+				// sequences, whose parts errors may name separately.
+				if j > i {
+					var value strings.Builder
+					for _, t := range tokens[i : j+1] {
+						value.WriteString(t.value)
+					}
+					values = append(values, value.String())
+				}
+				for k := i; k <= j; k += 2 {
+					values = append(values, tokens[k].value)
+				}
+			}
+			i = j
+		}
+	}
+	return values
+}
+
+// RedactValues returns text with each occurrence of values replaced by
+// Redacted, e.g. to remove the values returned by FilterValues from the
+// message of an error parsing or translating the filter. Values are
+// replaced where they appear quoted, or unquoted as whole words; longer
+// values are replaced first. A value that is also a word of the message
+// itself is redacted there too, which obscures the message but never
+// leaks the value.
+func RedactValues(text string, values ...string) string {
+	values = slices.Clone(values)
+	slices.SortFunc(values, func(a, b string) int {
+		return cmp.Compare(len(b), len(a))
+	})
+	quoted := strconv.Quote(Redacted)
+	for _, v := range values {
+		if v == "" || v == Redacted {
+			continue
+		}
+		text = strings.ReplaceAll(text, strconv.Quote(v), quoted)
+		text = replaceWord(text, v, Redacted)
+	}
+	return text
+}
+
+// replaceWord replaces the occurrences of old in s that are not adjacent to
+// letters, digits or underscores with new.
+func replaceWord(s, old, new string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, old)
+		if i < 0 {
+			break
+		}
+		before, _ := utf8.DecodeLastRuneInString(s[:i])
+		after, _ := utf8.DecodeRuneInString(s[i+len(old):])
+		b.WriteString(s[:i])
+		if i > 0 && isWordRune(before) || i+len(old) < len(s) && isWordRune(after) {
+			b.WriteString(old)
+		} else {
+			b.WriteString(new)
+		}
+		s = s[i+len(old):]
+	}
+	b.WriteString(s)
+	return b.String()
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
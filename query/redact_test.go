@@ -0,0 +1,61 @@
+package query_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hxtk/aip/query"
+)
+
+func TestFilterValues(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   []string
+	}{
+		{``, nil},
+		{`title = "John Smith"`, []string{`"John Smith"`, "John Smith"}},
+		{`author.email:alice@example.com AND rating > 3`, []string{"alice@example.com", "alice@example", "com", "3"}},
+		{`reviews.smith = bob`, []string{"bob"}},
+		{`alice -bob NOT carol`, []string{"alice", "bob", "carol"}},
+		{`has(labels, secret)`, []string{"labels", "secret"}},
+		{`title = (`, nil},
+		{`title = "unclosed`, []string{`"unclosed`}},
+		{`a.b`, []string{"a.b", "a", "b"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.filter, func(t *testing.T) {
+			require.Equal(t, tc.want, query.FilterValues(tc.filter))
+		})
+	}
+}
+
+func TestRedactValues(t *testing.T) {
+	filter := `email = "alice@example.com" AND name = al`
+	values := query.FilterValues(filter)
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{
+			`expected END but got STRING("\"alice@example.com\"")`,
+			`expected END but got STRING("<redacted>")`,
+		},
+		{
+			`argument for field email: invalid value alice@example.com`,
+			`argument for field email: invalid value <redacted>`,
+		},
+		{
+			`no value al in "alice" or "al", but "al.ice" redacts al`,
+			`no value <redacted> in "alice" or "<redacted>", but "<redacted>.ice" redacts <redacted>`,
+		},
+		{
+			`no filterable field "email", valid fields are email, name`,
+			`no filterable field "email", valid fields are email, name`,
+		},
+	}
+	for _, tc := range tests {
+		require.Equal(t, tc.want, query.RedactValues(tc.text, values...))
+	}
+}
@@ -0,0 +1,111 @@
+package query
+
+import (
+	"fmt"
+	"slices"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// SavedQuery is a named filter and order that a server publishes as a
+// stable, documented shortcut, e.g. "recently_updated", for clients to
+// invoke by name instead of spelling out the query.
+type SavedQuery struct {
+	// Name is the name clients invoke the query by.
+	Name string
+
+	// Description documents the query for clients.
+	Description string
+
+	// Filter is an AIP-160 filter, which may be empty. It may contain
+	// placeholders to bind per request; see BindFilter.
+	Filter string
+
+	// OrderBy is an AIP-132 order_by clause, which may be empty.
+	OrderBy string
+}
+
+// QueryRegistry holds saved queries, parsed and validated against a message
+// type once when the registry is built, typically at startup.
+type QueryRegistry struct {
+	queries []SavedQuery
+	byName  map[string]*compiledQuery
+}
+
+// compiledQuery is a parsed SavedQuery.
+type compiledQuery struct {
+	filter *Filter
+	order  []OrderBy
+}
+
+// NewQueryRegistry returns a registry of queries over messages of type desc.
+// It returns an error if a query has no name or a duplicate name, or if its
+// filter or order is not valid for desc.
+func NewQueryRegistry(desc protoreflect.MessageDescriptor, queries ...SavedQuery) (*QueryRegistry, error) {
+	r := &QueryRegistry{
+		queries: slices.Clone(queries),
+		byName:  make(map[string]*compiledQuery, len(queries)),
+	}
+	for _, q := range queries {
+		if q.Name == "" {
+			return nil, fmt.Errorf("saved query has no name")
+		}
+		if _, ok := r.byName[q.Name]; ok {
+			return nil, fmt.Errorf("duplicate saved query %q", q.Name)
+		}
+		filter, err := ParseFilter(q.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("saved query %q: parsing filter: %w", q.Name, err)
+		}
+		if _, err := DynamicFilter(desc, filter); err != nil {
+			return nil, fmt.Errorf("saved query %q: invalid filter: %w", q.Name, err)
+		}
+		order, err := ParseOrderBy(q.OrderBy)
+		if err != nil {
+			return nil, fmt.Errorf("saved query %q: parsing order: %w", q.Name, err)
+		}
+		if _, err := DynamicComparer(desc, order); err != nil {
+			return nil, fmt.Errorf("saved query %q: invalid order: %w", q.Name, err)
+		}
+		r.byName[q.Name] = &compiledQuery{filter: filter, order: order}
+	}
+	return r, nil
+}
+
+// Queries returns the saved queries of r in the order they were registered,
+// e.g. to document them.
+func (r *QueryRegistry) Queries() []SavedQuery {
+	return slices.Clone(r.queries)
+}
+
+// Apply combines the saved query named name with the filter and order of a
+// request. The result matches only what both filters match, and is ordered
+// by order, then by the saved order for fields order does not mention, as by
+// MergeWithDefaultOrder. An empty name returns filter and order unchanged.
+//
+// If no query is named name, the error is a *FieldViolationError of
+// ViewField.
+func (r *QueryRegistry) Apply(name string, filter *Filter, order []OrderBy) (*Filter, []OrderBy, error) {
+	if name == "" {
+		return filter, order, nil
+	}
+	q, ok := r.byName[name]
+	if !ok {
+		return nil, nil, newFieldViolation(ViewField, fmt.Errorf("unknown saved query %q", name))
+	}
+	return andFilters(q.filter, filter), MergeWithDefaultOrder(q.order, order), nil
+}
+
+// andFilters returns a filter matching what both a and b match. The result
+// shares nodes with a and b.
+func andFilters(a, b *Filter) *Filter {
+	switch {
+	case a == nil || a.Expression == nil:
+		return b
+	case b == nil || b.Expression == nil:
+		return a
+	}
+	return &Filter{Expression: &Expression{
+		Sequences: slices.Concat(a.Expression.Sequences, b.Expression.Sequences),
+	}}
+}
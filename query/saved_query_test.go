@@ -0,0 +1,68 @@
+package query_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/query"
+)
+
+func TestQueryRegistry(t *testing.T) {
+	desc := (&testpb.Book{}).ProtoReflect().Descriptor()
+	saved := []query.SavedQuery{
+		{
+			Name:        "hunt_by_title",
+			Description: "Books by Hunt, by title.",
+			Filter:      `author.family_name = "Hunt"`,
+			OrderBy:     "title desc, name",
+		},
+		{Name: "everything"},
+	}
+	registry, err := query.NewQueryRegistry(desc, saved...)
+	require.NoError(t, err)
+	require.Equal(t, saved, registry.Queries())
+
+	books := makeBooks(6)
+	apply := func(name, filter, orderBy string) []string {
+		t.Helper()
+		order, err := query.ParseOrderBy(orderBy)
+		require.NoError(t, err)
+		f, order, err := registry.Apply(name, mustParse(t, filter), order)
+		require.NoError(t, err)
+		matched, err := query.FilterSlice(books, f)
+		require.NoError(t, err)
+		compare, err := query.Comparer[*testpb.Book](order)
+		require.NoError(t, err)
+		slices.SortStableFunc(matched, compare)
+		var names []string
+		for _, b := range matched {
+			names = append(names, b.GetName())
+		}
+		return names
+	}
+
+	require.Equal(t, []string{"books/5", "books/4", "books/2", "books/1"}, apply("hunt_by_title", "", ""))
+	require.Equal(t, []string{"books/1", "books/2"}, apply("hunt_by_title", `title < "Book 3"`, "title"))
+	require.Len(t, apply("everything", "", ""), 6)
+	require.Equal(t, []string{"books/0"}, apply("", `name = "books/0"`, ""))
+
+	_, _, err = registry.Apply("nope", nil, nil)
+	var fv *query.FieldViolationError
+	require.True(t, errors.As(err, &fv))
+	require.Equal(t, query.ViewField, fv.FieldViolations()[0].GetField())
+
+	for _, bad := range [][]query.SavedQuery{
+		{{Filter: "title = x"}},
+		{{Name: "a"}, {Name: "a"}},
+		{{Name: "a", Filter: "title = ("}},
+		{{Name: "a", Filter: "author.nickname = x"}},
+		{{Name: "a", OrderBy: "nickname"}},
+	} {
+		_, err := query.NewQueryRegistry(desc, bad...)
+		require.Error(t, err, "%+v", bad)
+	}
+}
@@ -0,0 +1,272 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// SeekClause creates a Standard SQL boolean expression selecting the rows
+// that come strictly after cursor in the given order, for keyset ("seek")
+// pagination. Combine it with WhereClause using AND and with
+// OrderByClause(order) to serve the next page.
+//
+// The fragment is enclosed in parentheses and does not include the "WHERE"
+// keyword, e.g. ((db_a, db_b) > (@p_0, @p_1)) when all fields sort in the
+// same direction. Mixed directions expand to the equivalent disjunction, e.g.
+// ((db_a > @p_0) OR (db_a = @p_0 AND db_b < @p_1)). The cursor's values are passed
+// via query parameters with types matching the field: google.protobuf.Timestamp
+// fields are bound as time.Time, integers and enums as int64, and so on.
+//
+// Fields with explicit presence (messages, proto3 optional fields) map to
+// nullable columns. An unset cursor field seeks from NULL, and NULLs are
+// placed first in ascending and last in descending order, as Comparer does
+// and as is the default in Standard SQL. Columns declared NullIsZero instead
+// seek as if NULL were the zero value of the field, so an unset cursor field
+// seeks from the zero value.
+//
+// Every field in order must be a sortable column of the table. To make the
+// result well-defined, order should end with a unique column. If the clause
+// exceeds the limits of the table, the error wraps ErrSQLTooLarge.
+func (t *Table) SeekClause(cursor proto.Message, order []OrderBy, parameterPrefix string) (string, []QueryParameter, error) {
+	if len(order) == 0 {
+		return "(TRUE)", []QueryParameter{}, nil
+	}
+
+	for _, o := range order {
+		if column, err := t.SortableColumnByFieldPath(o.FieldPath); err == nil && column.synthetic {
+			return "", []QueryParameter{}, newFieldViolation(OrderByField, fmt.Errorf("cannot seek on synthetic field %q, which has no value in the cursor", o.FieldPath.String()))
+		}
+	}
+	m := cursor.ProtoReflect()
+	if err := validateOrder(m.Descriptor(), order); err != nil {
+		return "", []QueryParameter{}, err
+	}
+
+	w := &whereClause{
+		table:      t,
+		namePrefix: parameterPrefix,
+	}
+	keys := make([]seekKey, len(order))
+	uniform, nullable := true, false
+	for i, o := range order {
+		uniform = uniform && o.Descending == order[0].Descending
+		column, err := t.SortableColumnByFieldPath(o.FieldPath)
+		if err != nil {
+			return "", []QueryParameter{}, newFieldViolation(OrderByField, err)
+		}
+		if column.comparator != nil {
+			return "", []QueryParameter{}, newFieldViolation(OrderByField, fmt.Errorf("cannot seek on field %q, which is sorted with a custom comparator", o.FieldPath.String()))
+		}
+		value, err := seekValue(column, m, o.FieldPath.segments)
+		if err != nil {
+			return "", []QueryParameter{}, fmt.Errorf("cursor field %s: %w", o.FieldPath.String(), err)
+		}
+		keys[i] = seekKey{
+			column:     column.orderExpression(),
+			descending: o.Descending,
+			nullable:   seekNullable(column, m.Descriptor(), o.FieldPath.segments),
+		}
+		if value != nil {
+			keys[i].value = w.bind(value)
+		}
+		// NULLs sort first, so a row-value comparison only excludes them
+		// correctly when seeking forwards from non-NULL values.
+		nullable = nullable || keys[i].value == "" || (keys[i].nullable && o.Descending)
+	}
+
+	if len(keys) == 1 && !nullable {
+		return t.checkSeek(fmt.Sprintf("(%s %s %s)", keys[0].column, seekOperator(order[0]), keys[0].value), w.parameters)
+	}
+	if uniform && !nullable {
+		columns := make([]string, len(keys))
+		values := make([]string, len(keys))
+		for i, k := range keys {
+			columns[i], values[i] = k.column, k.value
+		}
+		return t.checkSeek(fmt.Sprintf("((%s) %s (%s))", strings.Join(columns, ", "), seekOperator(order[0]), strings.Join(values, ", ")), w.parameters)
+	}
+
+	// A row-value comparison cannot express mixed directions or NULLs, so
+	// expand it:
+	// (a > @a) OR (a = @a AND b < @b) OR (a = @a AND b = @b AND c > @c) ...
+	var disjuncts []string
+	for i, k := range keys {
+		after := k.after()
+		if after == "" {
+			continue
+		}
+		var conjuncts []string
+		for _, prev := range keys[:i] {
+			conjuncts = append(conjuncts, prev.equal())
+		}
+		if len(conjuncts) == 0 && strings.HasPrefix(after, "(") {
+			disjuncts = append(disjuncts, after)
+			continue
+		}
+		conjuncts = append(conjuncts, after)
+		disjuncts = append(disjuncts, "("+strings.Join(conjuncts, " AND ")+")")
+	}
+	if len(disjuncts) == 0 {
+		return "(FALSE)", w.parameters, nil
+	}
+	return t.checkSeek("("+strings.Join(disjuncts, " OR ")+")", w.parameters)
+}
+
+// checkSeek returns the seek clause and its parameters, or an error if they
+// exceed the limits of t.
+func (t *Table) checkSeek(clause string, params []QueryParameter) (string, []QueryParameter, error) {
+	if err := t.limits.Check(clause, params); err != nil {
+		return "", []QueryParameter{}, newFieldViolation(OrderByField, fmt.Errorf("order_by is too complex: %w", err))
+	}
+	return clause, params, nil
+}
+
+// seekKey is one sort key of a seek clause.
+type seekKey struct {
+	column     string
+	descending bool
+	// nullable reports whether the column may hold NULL.
+	nullable bool
+	// value is the bound cursor value, or empty if the cursor value is NULL.
+	value string
+}
+
+// equal returns the condition that a row ties with the cursor on k.
+func (k seekKey) equal() string {
+	if k.value == "" {
+		return k.column + " IS NULL"
+	}
+	return fmt.Sprintf("%s = %s", k.column, k.value)
+}
+
+// after returns the condition that a row sorts strictly after the cursor on
+// k, or the empty string if no row can. NULLs sort first in ascending order
+// and last in descending order.
+func (k seekKey) after() string {
+	switch {
+	case k.value == "" && k.descending:
+		return ""
+	case k.value == "":
+		return k.column + " IS NOT NULL"
+	case k.descending && k.nullable:
+		return fmt.Sprintf("(%s < %s OR %s IS NULL)", k.column, k.value, k.column)
+	case k.descending:
+		return fmt.Sprintf("%s < %s", k.column, k.value)
+	}
+	return fmt.Sprintf("%s > %s", k.column, k.value)
+}
+
+// seekOperator returns the comparison operator selecting rows after a
+// non-NULL cursor value in the direction of o.
+func seekOperator(o OrderBy) string {
+	if o.Descending {
+		return "<"
+	}
+	return ">"
+}
+
+// validateOrder checks that order is a valid sort order for desc.
+func validateOrder(desc protoreflect.MessageDescriptor, order []OrderBy) error {
+	for _, ob := range order {
+		if err := validateFieldPath(desc, ob.FieldPath.segments); err != nil {
+			return newFieldViolation(OrderByField, unsupportedFieldError{fmt.Errorf("invalid orderBy field %s: %w", ob.FieldPath.canonical, err)})
+		}
+	}
+	return nil
+}
+
+// seekValue returns the query parameter value of the field of m addressed by
+// segments, as stored in column, or nil if the field is unset and has explicit
+// presence.
+func seekValue(column *Column, m protoreflect.Message, segments []string) (any, error) {
+	fd := fieldPathDescriptor(m.Descriptor(), segments)
+	if fd.Enum() != nil && column.enumDesc != nil && column.enumOrder == EnumOrderByName {
+		// The CASE expression maps NULL to the same rank as unknown numbers.
+		ranks := enumNameRanks(column.enumDesc)
+		v, err := getFieldPathValue(m, segments)
+		if err != nil || !v.IsValid() {
+			return int64(len(ranks)), nil
+		}
+		if rank, ok := ranks[v.Enum()]; ok {
+			return int64(rank), nil
+		}
+		return int64(len(ranks)), nil
+	}
+	value, err := columnValue(m, segments)
+	if err != nil || value != nil || column.nullZero == "" {
+		return value, err
+	}
+	// The column compares NULL as the zero value, so the cursor must too.
+	if fd.Message() != nil && fd.Message().FullName() == timestampName {
+		return time.Unix(0, 0).UTC(), nil
+	}
+	return sqlValue(fd, fd.Default())
+}
+
+// columnValue returns the query parameter value of the field of m addressed
+// by segments, or nil if the field, or a message along its path, is unset.
+func columnValue(m protoreflect.Message, segments []string) (any, error) {
+	v, err := getFieldPathValue(m, segments)
+	if err != nil {
+		// A missing intermediate message.
+		v = protoreflect.Value{}
+	}
+	if !v.IsValid() {
+		return nil, nil
+	}
+	return sqlValue(fieldPathDescriptor(m.Descriptor(), segments), v)
+}
+
+// seekNullable reports whether the column addressed by segments may be NULL,
+// i.e. whether it is declared Nullable or any field along the path has
+// explicit presence, unless NULL is compared as the zero value.
+func seekNullable(column *Column, desc protoreflect.MessageDescriptor, segments []string) bool {
+	if column.ranksEnum() || column.nullZero != "" {
+		return false
+	}
+	if column.nullable {
+		return true
+	}
+	for _, seg := range segments {
+		fd := desc.Fields().ByName(protoreflect.Name(seg))
+		if fd.HasPresence() {
+			return true
+		}
+		desc = fd.Message()
+	}
+	return false
+}
+
+// sqlValue converts a singular protobuf value into the Go value bound for it
+// as a query parameter.
+func sqlValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) (any, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return v.Bool(), nil
+	case protoreflect.EnumKind:
+		return int64(v.Enum()), nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return v.Int(), nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return int64(v.Uint()), nil
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return v.Uint(), nil
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return v.Float(), nil
+	case protoreflect.StringKind:
+		return v.String(), nil
+	case protoreflect.BytesKind:
+		return v.Bytes(), nil
+	case protoreflect.MessageKind:
+		if fd.Message().FullName() == timestampName {
+			seconds, nanos := timestampParts(v.Message())
+			return time.Unix(seconds, int64(nanos)).UTC(), nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported field type %s", fd.Kind())
+}
@@ -0,0 +1,189 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+
+	. "github.com/hxtk/aip/query/internal/assertions"
+)
+
+func TestSeekClause(t *testing.T) {
+	Convey("SeekClause", t, func() {
+		table := NewTable().WithColumns(
+			NewColumn().WithFieldPath("create_time").WithDatabaseName("db_create_time").Sortable().Build(),
+			NewColumn().WithFieldPath("name").WithDatabaseName("db_name").Sortable().Build(),
+			NewColumn().WithFieldPath("format").WithDatabaseName("db_format").
+				Enum(testpb.Format(0).Descriptor(), EnumOrderByName).Sortable().Build(),
+			NewColumn().WithFieldPath("author", "family_name").WithDatabaseName("db_family_name").Sortable().Build(),
+			NewColumn().WithFieldPath("title").WithDatabaseName("db_title").Build(),
+		).Build()
+		created := time.Date(2024, 5, 6, 7, 8, 9, 10, time.UTC)
+		cursor := &testpb.Book{
+			Name:       "books/1",
+			CreateTime: timestamppb.New(created),
+			Format:     testpb.Format_EBOOK,
+		}
+
+		Convey("Empty order", func() {
+			result, pars, err := table.SeekClause(cursor, nil, "p_")
+			So(err, ShouldBeNil)
+			So(pars, ShouldBeEmpty)
+			So(result, ShouldEqual, "(TRUE)")
+		})
+		Convey("Single timestamp column", func() {
+			result, pars, err := table.SeekClause(cursor, []OrderBy{
+				{FieldPath: NewFieldPath("create_time")},
+			}, "p_")
+			So(err, ShouldBeNil)
+			So(pars, ShouldResemble, []QueryParameter{
+				{Name: "p_0", Value: created},
+			})
+			So(result, ShouldEqual, "(db_create_time > @p_0)")
+		})
+		Convey("Descending row comparison", func() {
+			result, pars, err := table.SeekClause(cursor, []OrderBy{
+				{FieldPath: NewFieldPath("format"), Descending: true},
+				{FieldPath: NewFieldPath("name"), Descending: true},
+			}, "p_")
+			So(err, ShouldBeNil)
+			So(pars, ShouldResemble, []QueryParameter{
+				{Name: "p_0", Value: int64(0)},
+				{Name: "p_1", Value: "books/1"},
+			})
+			So(result, ShouldEqual, "((CASE db_format WHEN 0 THEN 1 WHEN 1 THEN 3 WHEN 2 THEN 2 WHEN 3 THEN 0 ELSE 4 END, db_name) < (@p_0, @p_1))")
+		})
+		Convey("Ascending row comparison over nullable columns", func() {
+			result, _, err := table.SeekClause(cursor, []OrderBy{
+				{FieldPath: NewFieldPath("create_time")},
+				{FieldPath: NewFieldPath("name")},
+			}, "p_")
+			So(err, ShouldBeNil)
+			So(result, ShouldEqual, "((db_create_time, db_name) > (@p_0, @p_1))")
+		})
+		Convey("Mixed directions", func() {
+			result, pars, err := table.SeekClause(cursor, []OrderBy{
+				{FieldPath: NewFieldPath("name"), Descending: true},
+				{FieldPath: NewFieldPath("create_time")},
+				{FieldPath: NewFieldPath("format"), Descending: true},
+			}, "p_")
+			So(err, ShouldBeNil)
+			So(pars, ShouldResemble, []QueryParameter{
+				{Name: "p_0", Value: "books/1"},
+				{Name: "p_1", Value: created},
+				{Name: "p_2", Value: int64(0)},
+			})
+			format := "CASE db_format WHEN 0 THEN 1 WHEN 1 THEN 3 WHEN 2 THEN 2 WHEN 3 THEN 0 ELSE 4 END"
+			So(result, ShouldEqual, "((db_name < @p_0)"+
+				" OR (db_name = @p_0 AND db_create_time > @p_1)"+
+				" OR (db_name = @p_0 AND db_create_time = @p_1 AND "+format+" < @p_2))")
+		})
+		Convey("Mixed directions ending ascending", func() {
+			result, _, err := table.SeekClause(cursor, []OrderBy{
+				{FieldPath: NewFieldPath("name"), Descending: true},
+				{FieldPath: NewFieldPath("create_time")},
+			}, "p_")
+			So(err, ShouldBeNil)
+			So(result, ShouldEqual, "((db_name < @p_0) OR (db_name = @p_0 AND db_create_time > @p_1))")
+		})
+		Convey("Descending over a nullable column includes NULLs", func() {
+			result, _, err := table.SeekClause(cursor, []OrderBy{
+				{FieldPath: NewFieldPath("create_time"), Descending: true},
+				{FieldPath: NewFieldPath("name"), Descending: true},
+			}, "p_")
+			So(err, ShouldBeNil)
+			So(result, ShouldEqual, "((db_create_time < @p_0 OR db_create_time IS NULL)"+
+				" OR (db_create_time = @p_0 AND db_name < @p_1))")
+		})
+		Convey("Unset fields seek from NULL", func() {
+			result, pars, err := table.SeekClause(&testpb.Book{Name: "books/2"}, []OrderBy{
+				{FieldPath: NewFieldPath("create_time")},
+				{FieldPath: NewFieldPath("author", "family_name")},
+				{FieldPath: NewFieldPath("name")},
+			}, "p_")
+			So(err, ShouldBeNil)
+			So(pars, ShouldResemble, []QueryParameter{
+				{Name: "p_0", Value: "books/2"},
+			})
+			So(result, ShouldEqual, "((db_create_time IS NOT NULL)"+
+				" OR (db_create_time IS NULL AND db_family_name IS NOT NULL)"+
+				" OR (db_create_time IS NULL AND db_family_name IS NULL AND db_name > @p_0))")
+		})
+		Convey("Nothing follows NULL in descending order but ties", func() {
+			result, _, err := table.SeekClause(&testpb.Book{Name: "books/2"}, []OrderBy{
+				{FieldPath: NewFieldPath("create_time"), Descending: true},
+			}, "p_")
+			So(err, ShouldBeNil)
+			So(result, ShouldEqual, "(FALSE)")
+		})
+		Convey("Enum ordered by name binds rank", func() {
+			result, pars, err := table.SeekClause(cursor, []OrderBy{
+				{FieldPath: NewFieldPath("format")},
+			}, "p_")
+			So(err, ShouldBeNil)
+			So(pars, ShouldResemble, []QueryParameter{
+				{Name: "p_0", Value: int64(0)},
+			})
+			So(result, ShouldEqual, "(CASE db_format WHEN 0 THEN 1 WHEN 1 THEN 3 WHEN 2 THEN 2 WHEN 3 THEN 0 ELSE 4 END > @p_0)")
+		})
+		Convey("Nullable columns include NULLs in descending order", func() {
+			nullTable := NewTable().WithColumns(
+				NewColumn().WithFieldPath("title").WithDatabaseName("db_title").Nullable().Sortable().Build(),
+			).Build()
+			result, _, err := nullTable.SeekClause(&testpb.Book{Title: "Dune"}, []OrderBy{
+				{FieldPath: NewFieldPath("title"), Descending: true},
+			}, "p_")
+			So(err, ShouldBeNil)
+			So(result, ShouldEqual, "((db_title < @p_0 OR db_title IS NULL))")
+		})
+		Convey("Float, bytes and enum keys bind their values", func() {
+			keyTable := NewTable().WithColumns(
+				NewColumn().WithFieldPath("average_rating").WithDatabaseName("db_average_rating").Sortable().Build(),
+				NewColumn().WithFieldPath("weight_kg").WithDatabaseName("db_weight_kg").Sortable().Build(),
+				NewColumn().WithFieldPath("checksum").WithDatabaseName("db_checksum").Sortable().Build(),
+				NewColumn().WithFieldPath("format").WithDatabaseName("db_format").Sortable().Build(),
+			).Build()
+			result, pars, err := keyTable.SeekClause(&testpb.Book{
+				AverageRating: 4.5,
+				WeightKg:      0.25,
+				Checksum:      []byte{0x01, 0x02},
+				Format:        testpb.Format_PAPERBACK,
+			}, []OrderBy{
+				{FieldPath: NewFieldPath("average_rating"), Descending: true},
+				{FieldPath: NewFieldPath("weight_kg"), Descending: true},
+				{FieldPath: NewFieldPath("checksum"), Descending: true},
+				{FieldPath: NewFieldPath("format"), Descending: true},
+			}, "p_")
+			So(err, ShouldBeNil)
+			So(pars, ShouldResemble, []QueryParameter{
+				{Name: "p_0", Value: 4.5},
+				{Name: "p_1", Value: 0.25},
+				{Name: "p_2", Value: []byte{0x01, 0x02}},
+				{Name: "p_3", Value: int64(testpb.Format_PAPERBACK)},
+			})
+			So(result, ShouldEqual, "((db_average_rating, db_weight_kg, db_checksum, db_format) < (@p_0, @p_1, @p_2, @p_3))")
+		})
+		Convey("Custom comparator", func() {
+			customTable := NewTable().WithColumns(
+				NewColumn().WithFieldPath("title").WithDatabaseName("db_title").
+					WithComparator(func(a, b protoreflect.Value) int { return 0 }).
+					WithOrderExpression("db_title_key").Sortable().Build(),
+			).Build()
+			_, _, err := customTable.SeekClause(cursor, []OrderBy{
+				{FieldPath: NewFieldPath("title")},
+			}, "p_")
+			So(err, ShouldErrLike, "cannot seek on field \"title\", which is sorted with a custom comparator")
+		})
+		Convey("Unsortable column", func() {
+			_, _, err := table.SeekClause(cursor, []OrderBy{
+				{FieldPath: NewFieldPath("title")},
+			}, "p_")
+			So(err, ShouldErrLike, "no sortable field named \"title\"")
+		})
+	})
+}
@@ -0,0 +1,43 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+)
+
+// PostgresMaxParameters is the maximum number of parameters of a Postgres
+// statement, imposed by the 16-bit parameter count of its wire protocol.
+const PostgresMaxParameters = 65535
+
+// ErrSQLTooLarge is returned when a generated SQL clause exceeds the limits
+// configured with TableBuilder.WithLimits.
+var ErrSQLTooLarge = errors.New("generated SQL exceeds limits")
+
+// SQLLimits bounds the size of the SQL clauses generated for a table, so that
+// overly complex requests fail with a clear error instead of producing a
+// statement the database driver rejects.
+//
+// The limits apply to each generated clause separately. Servers combining
+// several clauses into one statement may use Check on the combined text and
+// parameters.
+type SQLLimits struct {
+	// MaxLength is the maximum length of a clause in bytes, or zero for no
+	// limit.
+	MaxLength int
+
+	// MaxParameters is the maximum number of query parameters of a clause,
+	// or zero for no limit, e.g. PostgresMaxParameters.
+	MaxParameters int
+}
+
+// Check returns an error wrapping ErrSQLTooLarge if sql or params exceed the
+// limits.
+func (l SQLLimits) Check(sql string, params []QueryParameter) error {
+	if l.MaxParameters > 0 && len(params) > l.MaxParameters {
+		return fmt.Errorf("%w: %d query parameters, the maximum is %d", ErrSQLTooLarge, len(params), l.MaxParameters)
+	}
+	if l.MaxLength > 0 && len(sql) > l.MaxLength {
+		return fmt.Errorf("%w: %d bytes, the maximum is %d", ErrSQLTooLarge, len(sql), l.MaxLength)
+	}
+	return nil
+}
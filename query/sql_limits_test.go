@@ -0,0 +1,63 @@
+package query
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+
+	. "github.com/hxtk/aip/query/internal/assertions"
+)
+
+func TestSQLLimits(t *testing.T) {
+	Convey("SQLLimits", t, func() {
+		columns := []*Column{
+			NewColumn().WithFieldPath("title").WithDatabaseName("db_title").Filterable().Sortable().Build(),
+			NewColumn().WithFieldPath("name").WithDatabaseName("db_name").Filterable().Sortable().Build(),
+		}
+
+		Convey("Check", func() {
+			limits := SQLLimits{MaxLength: 10, MaxParameters: 1}
+			So(limits.Check("(TRUE)", nil), ShouldBeNil)
+			So(limits.Check("(a = @p_0)", []QueryParameter{{Name: "p_0"}}), ShouldBeNil)
+			So(limits.Check("(a = @p_0 OR b = @p_1)", nil), ShouldErrLike, "22 bytes, the maximum is 10")
+			So(limits.Check("", make([]QueryParameter, 2)), ShouldErrLike, "2 query parameters, the maximum is 1")
+			So(SQLLimits{}.Check("(a = @p_0 OR b = @p_1)", make([]QueryParameter, PostgresMaxParameters+1)), ShouldBeNil)
+		})
+		Convey("WhereClause", func() {
+			table := NewTable().WithColumns(columns...).WithLimits(SQLLimits{MaxParameters: 2}).Build()
+			filter, err := ParseFilter("title = a OR name = b")
+			So(err, ShouldBeNil)
+			_, pars, err := table.WhereClause(filter, "p_")
+			So(err, ShouldBeNil)
+			So(pars, ShouldHaveLength, 2)
+
+			filter, err = ParseFilter("title = a OR name = b OR title = c")
+			So(err, ShouldBeNil)
+			_, _, err = table.WhereClause(filter, "p_")
+			So(errors.Is(err, ErrSQLTooLarge), ShouldBeTrue)
+			So(err, ShouldErrLike, "filter is too complex: generated SQL exceeds limits: 3 query parameters, the maximum is 2")
+			var fv *FieldViolationError
+			So(errors.As(err, &fv), ShouldBeTrue)
+			So(fv.FieldViolations()[0].Field, ShouldEqual, "filter")
+		})
+		Convey("OrderByClause", func() {
+			table := NewTable().WithColumns(columns...).WithLimits(SQLLimits{MaxLength: 20}).Build()
+			_, err := table.OrderByClause([]OrderBy{{FieldPath: NewFieldPath("title")}})
+			So(err, ShouldBeNil)
+			_, err = table.OrderByClause([]OrderBy{{FieldPath: NewFieldPath("title")}, {FieldPath: NewFieldPath("name")}})
+			So(errors.Is(err, ErrSQLTooLarge), ShouldBeTrue)
+			So(err, ShouldErrLike, "order_by is too complex")
+		})
+		Convey("SeekClause", func() {
+			table := NewTable().WithColumns(columns...).WithLimits(SQLLimits{MaxParameters: 1}).Build()
+			cursor := &testpb.Book{Title: "Dune", Name: "books/1"}
+			_, _, err := table.SeekClause(cursor, []OrderBy{{FieldPath: NewFieldPath("title")}}, "p_")
+			So(err, ShouldBeNil)
+			_, _, err = table.SeekClause(cursor, []OrderBy{{FieldPath: NewFieldPath("title")}, {FieldPath: NewFieldPath("name")}}, "p_")
+			So(errors.Is(err, ErrSQLTooLarge), ShouldBeTrue)
+		})
+	})
+}
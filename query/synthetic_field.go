@@ -0,0 +1,52 @@
+package query
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.chromium.org/luci/common/errors"
+)
+
+// restrictionComparators are the comparators synthetic fields and filter
+// schemas may declare, with ":*" standing for presence tests.
+var restrictionComparators = []string{"=", "!=", "<", "<=", ">", ">=", ":", ":*"}
+
+// syntheticQuery returns the SQL expression equivalent to the given
+// restriction on a synthetic column, which must use one of the comparators
+// declared for it.
+// The returned string is an injection-safe SQL expression.
+func (w *whereClause) syntheticQuery(column *Column, restriction *Restriction) (string, error) {
+	comparator := restriction.Comparator
+	if isPresenceTest(restriction) {
+		comparator = ":*"
+	}
+	if !slices.Contains(column.comparators, comparator) {
+		return "", fmt.Errorf("synthetic field %q cannot be compared with %s, supported comparators are %s", column.fieldPath.String(), comparator, strings.Join(column.comparators, ", "))
+	}
+	if len(restriction.Comparable.Member.Fields) > 0 {
+		return "", fmt.Errorf("synthetic field %q has no fields", column.fieldPath.String())
+	}
+	switch comparator {
+	case ":*":
+		return fmt.Sprintf("(%s IS NOT NULL)", column.databaseName), nil
+	case ":":
+		arg, err := w.likeArgValue(restriction.Arg, column)
+		if err != nil {
+			return "", errors.Annotate(err, "argument for field %s", column.fieldPath.String()).Err()
+		}
+		return fmt.Sprintf("(%s LIKE %s)", column.databaseName, arg), nil
+	case "!=":
+		comparator = "<>"
+	}
+	var arg string
+	if c := restriction.Arg.Comparable; c != nil && c.Member != nil && (c.Member.Kind == LiteralInt || c.Member.Kind == LiteralFloat) {
+		arg = w.bind(c.Member.Literal)
+	} else {
+		var err error
+		if arg, err = w.argValue(restriction.Arg, column); err != nil {
+			return "", errors.Annotate(err, "argument for field %s", column.fieldPath.String()).Err()
+		}
+	}
+	return fmt.Sprintf("(%s %s %s)", column.databaseName, comparator, arg), nil
+}
@@ -0,0 +1,105 @@
+package query_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/query"
+)
+
+func syntheticTable() *query.Table {
+	return query.NewTable().WithColumns(
+		query.NewColumn().WithFieldPath("title").WithDatabaseName("db_title").Filterable().Sortable().Build(),
+		query.NewColumn().WithFieldPath("relevance").Synthetic("ts_rank(search, query)", ">", ">=", ":*").Filterable().Sortable().Build(),
+		query.NewColumn().WithFieldPath("random").Synthetic("RANDOM()").Sortable().Build(),
+	).Build()
+}
+
+func TestSyntheticFields_Filter(t *testing.T) {
+	table := syntheticTable()
+	tests := []struct {
+		filter string
+		want   string
+		params []query.QueryParameter
+	}{
+		{
+			filter: `relevance > 0.5`,
+			want:   "(ts_rank(search, query) > @p_0)",
+			params: []query.QueryParameter{{Name: "p_0", Value: 0.5}},
+		},
+		{
+			filter: `relevance >= 1 AND title = Dune`,
+			want:   "((ts_rank(search, query) >= @p_0) AND (db_title = @p_1))",
+			params: []query.QueryParameter{{Name: "p_0", Value: int64(1)}, {Name: "p_1", Value: "Dune"}},
+		},
+		{
+			filter: `relevance:*`,
+			want:   "(ts_rank(search, query) IS NOT NULL)",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.filter, func(t *testing.T) {
+			where, params, err := table.WhereClause(mustParse(t, tc.filter), "p_")
+			require.NoError(t, err)
+			require.Equal(t, tc.want, where)
+			require.Equal(t, tc.params, params)
+		})
+	}
+
+	for _, filter := range []string{`relevance = 1`, `relevance < 1`, `relevance:high`, `relevance.x > 1`, `random > 0.5`} {
+		_, _, err := table.WhereClause(mustParse(t, filter), "p_")
+		require.Error(t, err, filter)
+	}
+	_, _, err := table.WhereClause(mustParse(t, `relevance = 1`), "p_")
+	require.ErrorContains(t, err, "supported comparators are >, >=, :*")
+}
+
+func TestSyntheticFields_Order(t *testing.T) {
+	table := syntheticTable()
+
+	order, err := table.ParseOrder("relevance desc, title")
+	require.NoError(t, err)
+	clause, err := table.OrderByClause(order)
+	require.NoError(t, err)
+	require.Equal(t, "ORDER BY ts_rank(search, query) DESC, db_title\n", clause)
+
+	order, err = table.ParseOrder("random")
+	require.NoError(t, err)
+	clause, err = table.OrderByClause(order)
+	require.NoError(t, err)
+	require.Equal(t, "ORDER BY RANDOM()\n", clause)
+
+	// The cursor has no value to seek from.
+	_, _, err = table.SeekClause(&testpb.Book{Title: "Dune"}, order, "p_")
+	require.ErrorContains(t, err, "synthetic field")
+}
+
+func TestSyntheticFields_Schema(t *testing.T) {
+	table := syntheticTable()
+	desc := (&testpb.Book{}).ProtoReflect().Descriptor()
+
+	require.NoError(t, table.CheckAgainst(desc))
+
+	row, err := table.RowValues(&testpb.Book{Title: "Dune"})
+	require.NoError(t, err)
+	require.Equal(t, []query.QueryParameter{{Name: "db_title", Value: "Dune"}}, row)
+
+	doc, err := table.Document(desc, nil)
+	require.NoError(t, err)
+	require.Len(t, doc.Fields, 3)
+	require.Equal(t, query.FieldDoc{
+		Path:      "relevance",
+		Operators: []string{">", ">=", ":*"},
+		Sortable:  true,
+		Synthetic: true,
+	}, doc.Fields[1])
+	require.Equal(t, query.FieldDoc{Path: "random", Sortable: true, Synthetic: true}, doc.Fields[2])
+
+	require.Panics(t, func() {
+		query.NewTable().WithColumns(
+			query.NewColumn().WithFieldPath("relevance").Synthetic("score", "~").Filterable().Build(),
+		).Build()
+	})
+}
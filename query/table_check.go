@@ -0,0 +1,62 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// CheckAgainst verifies that the field path of every column of the table,
+// other than synthetic fields (see ColumnBuilder.Synthetic), names a field of the resource described by desc, of a kind compatible with
+// the column:
+//
+//   - KeyValue columns must be map<string, string> fields;
+//   - Array columns must be repeated fields;
+//   - other columns must be singular fields;
+//   - Bool columns must be bool fields;
+//   - Enum columns must be fields of the same enum.
+//
+// It is meant to run at startup or in tests, so that renaming or retyping a
+// field of the resource is caught before filters and orders on it fail in
+// production. The error joins those of every incompatible column.
+func (t *Table) CheckAgainst(desc protoreflect.MessageDescriptor) error {
+	var errs []error
+	for _, column := range t.columns {
+		if column.synthetic {
+			continue
+		}
+		fd, err := fieldByPath(desc, column.fieldPath.segments)
+		if err == nil {
+			err = column.checkField(fd)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("column %s: %w", column.fieldPath.String(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkField returns an error if fd is not of a kind compatible with c.
+func (c *Column) checkField(fd protoreflect.FieldDescriptor) error {
+	switch {
+	case c.keyValue:
+		if !fd.IsMap() || fd.MapKey().Kind() != protoreflect.StringKind || fd.MapValue().Kind() != protoreflect.StringKind {
+			return fmt.Errorf("key-value column requires a map<string, string> field, got %s", fieldType(fd))
+		}
+		return nil
+	case c.array:
+		if !fd.IsList() {
+			return fmt.Errorf("array column requires a repeated field, got %s", fieldType(fd))
+		}
+	case fd.IsList() || fd.IsMap():
+		return fmt.Errorf("column requires a singular field, got %s", fieldType(fd))
+	}
+	switch {
+	case c.columnType == ColumnTypeBool && fd.Kind() != protoreflect.BoolKind:
+		return fmt.Errorf("bool column requires a bool field, got %s", fieldType(fd))
+	case c.enumDesc != nil && (fd.Enum() == nil || fd.Enum().FullName() != c.enumDesc.FullName()):
+		return fmt.Errorf("enum column requires a field of enum %s, got %s", c.enumDesc.FullName(), fieldType(fd))
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package query_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/query"
+)
+
+func TestTableCheckAgainst(t *testing.T) {
+	desc := (&testpb.Book{}).ProtoReflect().Descriptor()
+
+	table := query.NewTable().WithColumns(
+		query.NewColumn().WithFieldPath("name").WithDatabaseName("name").Sortable().Filterable().Build(),
+		query.NewColumn().WithFieldPath("author", "family_name").WithDatabaseName("author_family_name").Filterable().Build(),
+		query.NewColumn().WithFieldPath("reviews").WithDatabaseName("reviews").KeyValue().Filterable().Build(),
+		query.NewColumn().WithFieldPath("tags").WithDatabaseName("tags").Array().Filterable().Build(),
+		query.NewColumn().WithFieldPath("format").WithDatabaseName("format").Enum(testpb.Format(0).Descriptor(), query.EnumOrderByNumber).Sortable().Build(),
+		query.NewColumn().WithFieldPath("create_time").WithDatabaseName("create_time").Sortable().Build(),
+	).Build()
+	require.NoError(t, table.CheckAgainst(desc))
+
+	drifted := query.NewTable().WithColumns(
+		query.NewColumn().WithFieldPath("name").WithDatabaseName("name").Filterable().Build(),
+		query.NewColumn().WithFieldPath("isbn").WithDatabaseName("isbn").Filterable().Build(),
+		query.NewColumn().WithFieldPath("authors", "family_name").WithDatabaseName("a").Filterable().Build(),
+		query.NewColumn().WithFieldPath("items").WithDatabaseName("items").KeyValue().Filterable().Build(),
+		query.NewColumn().WithFieldPath("title").WithDatabaseName("title").Array().Filterable().Build(),
+		query.NewColumn().WithFieldPath("tags").WithDatabaseName("tags").Filterable().Build(),
+		query.NewColumn().WithFieldPath("page_count").WithDatabaseName("page_count").Bool().Filterable().Build(),
+		query.NewColumn().WithFieldPath("language_code").WithDatabaseName("language_code").Enum(testpb.Format(0).Descriptor(), query.EnumOrderByNumber).Sortable().Build(),
+	).Build()
+	err := drifted.CheckAgainst(desc)
+	require.Error(t, err)
+	for _, want := range []string{
+		"column isbn: field isbn not found on test.Book",
+		"column authors.family_name: field authors is not a singular message",
+		"column items: key-value column requires a map<string, string> field, got map<int32, string>",
+		"column title: array column requires a repeated field, got string",
+		"column tags: column requires a singular field, got repeated string",
+		"column page_count: bool column requires a bool field, got int32",
+		"column language_code: enum column requires a field of enum test.Format, got string",
+	} {
+		require.ErrorContains(t, err, want)
+	}
+	require.NotContains(t, err.Error(), "column name:")
+}
@@ -0,0 +1,314 @@
+package query
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// TableDoc documents the query surface of a Table: the fields that may be
+// referenced in AIP-160 filters and AIP-132 orders of a List method, and
+// its default order.
+//
+// A TableDoc is derived from the same column definitions that WhereClause
+// and OrderByClause enforce, so published API documentation built from it
+// stays in sync with the server's behaviour.
+type TableDoc struct {
+	// Resource is the full name of the resource message.
+	Resource string `json:"resource"`
+	// Fields documents the filterable and sortable fields, in the order of
+	// the table's columns.
+	Fields []FieldDoc `json:"fields"`
+	// Implicit holds the paths of the fields searched by filter terms
+	// without a field, e.g. `filter: "Austen"`.
+	Implicit []string `json:"implicit,omitempty"`
+	// DefaultOrder is the order_by text applied when a request specifies
+	// none, or empty if the order is unspecified.
+	DefaultOrder string `json:"defaultOrder,omitempty"`
+}
+
+// FieldDoc documents how a field may be referenced in queries.
+type FieldDoc struct {
+	// Path is the field path, as written in filters and orders.
+	Path string `json:"path"`
+	// Type is the protobuf type of the field, e.g. "string",
+	// "repeated string" or "map<string, string>", and empty for synthetic
+	// fields.
+	Type string `json:"type"`
+	// Description holds the leading comments of the field, if the
+	// descriptor retains source information.
+	Description string `json:"description,omitempty"`
+	// Operators are the supported filter comparators. For key-value fields
+	// they apply to a key of the field, e.g. `labels.env = prod`. They are
+	// empty if the field is not filterable.
+	Operators []string `json:"operators,omitempty"`
+	// KeyValue reports whether Operators apply to keys of the field.
+	KeyValue bool `json:"keyValue,omitempty"`
+	// Sortable reports whether the field may be referenced in order_by.
+	Sortable bool `json:"sortable,omitempty"`
+	// EnumValues holds the names of the values of an enum field, in the
+	// order they sort in.
+	EnumValues []string `json:"enumValues,omitempty"`
+	// NullsFirst reports whether unset values of the field sort first in
+	// ascending order and last in descending order.
+	NullsFirst bool `json:"nullsFirst,omitempty"`
+	// Synthetic reports whether the field is computed by the server rather
+	// than a field of the resource, e.g. a relevance score.
+	Synthetic bool `json:"synthetic,omitempty"`
+}
+
+// Document returns the documentation of the query surface of the table for
+// the resource described by desc, with the given default order, or the
+// default order of the table if it is nil.
+//
+// An error is returned if a filterable or sortable column does not name a
+// field of desc, or if defaultOrder references a field that is not
+// sortable.
+func (t *Table) Document(desc protoreflect.MessageDescriptor, defaultOrder []OrderBy) (*TableDoc, error) {
+	doc := &TableDoc{Resource: string(desc.FullName())}
+	for _, column := range t.columns {
+		if !column.filterable && !column.sortable && !column.implicitFilter {
+			continue
+		}
+		if column.synthetic {
+			doc.Fields = append(doc.Fields, syntheticFieldDoc(column))
+			continue
+		}
+		fd, err := fieldByPath(desc, column.fieldPath.segments)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", column.fieldPath.String(), err)
+		}
+		if column.implicitFilter {
+			doc.Implicit = append(doc.Implicit, column.fieldPath.String())
+		}
+		if !column.filterable && !column.sortable {
+			continue
+		}
+		field := FieldDoc{
+			Path:       column.fieldPath.String(),
+			Type:       fieldType(fd),
+			KeyValue:   column.filterable && column.keyValue,
+			Sortable:   column.sortable,
+			NullsFirst: column.sortable && column.nullable && column.nullZero == "" && !column.ranksEnum(),
+		}
+		if loc := fd.ParentFile().SourceLocations().ByDescriptor(fd); loc.LeadingComments != "" {
+			field.Description = strings.TrimSpace(loc.LeadingComments)
+		}
+		if column.filterable {
+			field.Operators = column.operators()
+		}
+		if column.sortable && column.enumDesc != nil {
+			field.EnumValues = enumValueNames(column)
+		}
+		doc.Fields = append(doc.Fields, field)
+	}
+
+	if defaultOrder == nil {
+		defaultOrder = t.defaultOrder
+	}
+	for _, o := range defaultOrder {
+		if _, err := t.SortableColumnByFieldPath(o.FieldPath); err != nil {
+			return nil, fmt.Errorf("default order: %w", err)
+		}
+	}
+	doc.DefaultOrder = orderByText(defaultOrder)
+	return doc, nil
+}
+
+// syntheticFieldDoc documents the synthetic field of the column.
+func syntheticFieldDoc(c *Column) FieldDoc {
+	field := FieldDoc{
+		Path:      c.fieldPath.String(),
+		Sortable:  c.sortable,
+		Synthetic: true,
+	}
+	if c.filterable {
+		field.Operators = c.operators()
+	}
+	return field
+}
+
+// operators returns the filter comparators WhereClause supports on the
+// column.
+func (c *Column) operators() []string {
+	switch {
+	case c.synthetic:
+		return slices.Clone(c.comparators)
+	case c.keyValue:
+		return []string{"=", "!=", ":", ":*"}
+	case c.array:
+		return []string{":", ":*"}
+	case c.columnType == ColumnTypeString && c.argSubstitute == nil:
+		return []string{"=", "!=", ":", ":*"}
+	default:
+		return []string{"=", "!=", ":*"}
+	}
+}
+
+// enumValueNames returns the names of the values of the column's enum, in
+// the order they sort in.
+func enumValueNames(c *Column) []string {
+	values := c.enumDesc.Values()
+	names := make([]string, values.Len())
+	if !c.ranksEnum() {
+		for i := range names {
+			names[i] = string(values.Get(i).Name())
+		}
+		return names
+	}
+	ranks := enumNameRanks(c.enumDesc)
+	for i := 0; i < values.Len(); i++ {
+		v := values.Get(i)
+		names[ranks[v.Number()]] = string(v.Name())
+	}
+	return names
+}
+
+// fieldByPath returns the field of desc at the path given by segments.
+func fieldByPath(desc protoreflect.MessageDescriptor, segments []string) (protoreflect.FieldDescriptor, error) {
+	var fd protoreflect.FieldDescriptor
+	for i, seg := range segments {
+		if i > 0 {
+			if fd.Message() == nil || fd.IsList() || fd.IsMap() {
+				return nil, fmt.Errorf("field %s is not a singular message", fd.Name())
+			}
+			desc = fd.Message()
+		}
+		fd = desc.Fields().ByName(protoreflect.Name(seg))
+		if fd == nil {
+			return nil, fmt.Errorf("field %s not found on %s", seg, desc.FullName())
+		}
+	}
+	if fd == nil {
+		return nil, fmt.Errorf("empty field path")
+	}
+	return fd, nil
+}
+
+// fieldType returns the protobuf type of fd as written in a .proto file.
+func fieldType(fd protoreflect.FieldDescriptor) string {
+	if fd.IsMap() {
+		return fmt.Sprintf("map<%s, %s>", fieldType(fd.MapKey()), fieldType(fd.MapValue()))
+	}
+	var name string
+	switch {
+	case fd.Message() != nil:
+		name = string(fd.Message().FullName())
+	case fd.Enum() != nil:
+		name = string(fd.Enum().FullName())
+	default:
+		name = fd.Kind().String()
+	}
+	if fd.IsList() {
+		return "repeated " + name
+	}
+	return name
+}
+
+// orderByText returns the order_by text of order.
+func orderByText(order []OrderBy) string {
+	parts := make([]string, len(order))
+	for i, o := range order {
+		parts[i] = o.FieldPath.String()
+		if o.Descending {
+			parts[i] += " desc"
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Markdown renders the documentation as a Markdown section suitable for
+// inclusion in the description of a List method.
+func (d *TableDoc) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "### Filtering and ordering `%s`\n\n", d.Resource)
+	b.WriteString("| Field | Type | Filter operators | Sortable |\n")
+	b.WriteString("|---|---|---|---|\n")
+	for _, f := range d.Fields {
+		ops := make([]string, len(f.Operators))
+		for i, op := range f.Operators {
+			switch {
+			case f.KeyValue && op == ":*":
+				ops[i] = "`" + f.Path + ":*`, `" + f.Path + ".KEY:*`"
+			case f.KeyValue:
+				ops[i] = "`" + f.Path + ".KEY " + op + "`"
+			default:
+				ops[i] = "`" + op + "`"
+			}
+		}
+		sortable := "no"
+		if f.Sortable {
+			sortable = "yes"
+		}
+		fmt.Fprintf(&b, "| `%s` | `%s` | %s | %s |\n", f.Path, f.Type, strings.Join(ops, ", "), sortable)
+	}
+
+	var notes []string
+	if len(d.Implicit) > 0 {
+		notes = append(notes, fmt.Sprintf("Filter terms without a field search %s.", codeList(d.Implicit)))
+	}
+	for _, f := range d.Fields {
+		if len(f.EnumValues) > 0 {
+			notes = append(notes, fmt.Sprintf("`%s` sorts in the order %s.", f.Path, codeList(f.EnumValues)))
+		}
+		if f.NullsFirst {
+			notes = append(notes, fmt.Sprintf("Unset values of `%s` sort first in ascending order and last in descending order.", f.Path))
+		}
+	}
+	if d.DefaultOrder != "" {
+		notes = append(notes, fmt.Sprintf("The default order is `%s`.", d.DefaultOrder))
+	}
+	if len(notes) > 0 {
+		b.WriteString("\n")
+		for _, n := range notes {
+			b.WriteString("- ")
+			b.WriteString(n)
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// codeList formats items as a comma-separated list of code spans.
+func codeList(items []string) string {
+	return "`" + strings.Join(items, "`, `") + "`"
+}
+
+// OpenAPIExtensions returns the documentation as OpenAPI specification
+// extensions, to be merged into the operation object of a List method:
+//
+//	"x-aip-filter": the filterable fields with their operators, and the
+//	                fields searched implicitly;
+//	"x-aip-order-by": the sortable fields and the default order.
+//
+// The values marshal to JSON with encoding/json.
+func (d *TableDoc) OpenAPIExtensions() map[string]any {
+	type filterExt struct {
+		Fields   []FieldDoc `json:"fields"`
+		Implicit []string   `json:"implicit,omitempty"`
+	}
+	type orderByExt struct {
+		Fields  []FieldDoc `json:"fields"`
+		Default string     `json:"default,omitempty"`
+	}
+	filter := filterExt{Fields: []FieldDoc{}, Implicit: d.Implicit}
+	orderBy := orderByExt{Fields: []FieldDoc{}, Default: d.DefaultOrder}
+	for _, f := range d.Fields {
+		if len(f.Operators) > 0 {
+			g := f
+			g.Sortable, g.EnumValues, g.NullsFirst = false, nil, false
+			filter.Fields = append(filter.Fields, g)
+		}
+		if f.Sortable {
+			g := f
+			g.Operators, g.KeyValue = nil, false
+			orderBy.Fields = append(orderBy.Fields, g)
+		}
+	}
+	return map[string]any{
+		"x-aip-filter":   filter,
+		"x-aip-order-by": orderBy,
+	}
+}
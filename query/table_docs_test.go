@@ -0,0 +1,120 @@
+package query_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/query"
+)
+
+func docTable() *query.Table {
+	return query.NewTable().WithColumns(
+		query.NewColumn().WithFieldPath("name").WithDatabaseName("name").Sortable().Filterable().Build(),
+		query.NewColumn().WithFieldPath("title").WithDatabaseName("title").Filterable().FilterableImplicitly().Build(),
+		query.NewColumn().WithFieldPath("author", "family_name").WithDatabaseName("author_family_name").Filterable().Sortable().Nullable().FilterableImplicitly().Build(),
+		query.NewColumn().WithFieldPath("reviews").WithDatabaseName("reviews").KeyValue().Filterable().Build(),
+		query.NewColumn().WithFieldPath("format").WithDatabaseName("format").Enum(testpb.Format(0).Descriptor(), query.EnumOrderByName).Sortable().Build(),
+		query.NewColumn().WithFieldPath("create_time").WithDatabaseName("secret_column").Build(),
+	).Build()
+}
+
+func TestTableDocument(t *testing.T) {
+	desc := (&testpb.Book{}).ProtoReflect().Descriptor()
+	order, err := query.ParseOrderBy("name desc")
+	require.NoError(t, err)
+
+	doc, err := docTable().Document(desc, order)
+	require.NoError(t, err)
+	require.Equal(t, &query.TableDoc{
+		Resource: "test.Book",
+		Fields: []query.FieldDoc{
+			{Path: "name", Type: "string", Operators: []string{"=", "!=", ":", ":*"}, Sortable: true},
+			{Path: "title", Type: "string", Operators: []string{"=", "!=", ":", ":*"}},
+			{Path: "author.family_name", Type: "string", Operators: []string{"=", "!=", ":", ":*"}, Sortable: true, NullsFirst: true},
+			{Path: "reviews", Type: "map<string, string>", Operators: []string{"=", "!=", ":", ":*"}, KeyValue: true},
+			{Path: "format", Type: "test.Format", Sortable: true, EnumValues: []string{"EBOOK", "FORMAT_UNSPECIFIED", "HARDCOVER", "PAPERBACK"}},
+		},
+		Implicit:     []string{"title", "author.family_name"},
+		DefaultOrder: "name desc",
+	}, doc)
+
+	md := doc.Markdown()
+	require.Contains(t, md, "| `name` | `string` | `=`, `!=`, `:`, `:*` | yes |\n")
+	require.Contains(t, md, "| `reviews` | `map<string, string>` | `reviews.KEY =`, `reviews.KEY !=`, `reviews.KEY :`, `reviews:*`, `reviews.KEY:*` | no |\n")
+	require.Contains(t, md, "| `format` | `test.Format` |  | yes |\n")
+	require.Contains(t, md, "- Filter terms without a field search `title`, `author.family_name`.\n")
+	require.Contains(t, md, "- `format` sorts in the order `EBOOK`, `FORMAT_UNSPECIFIED`, `HARDCOVER`, `PAPERBACK`.\n")
+	require.Contains(t, md, "- The default order is `name desc`.\n")
+	require.NotContains(t, md, "secret_column", "database names must not leak into documentation")
+	require.NotContains(t, md, "create_time", "columns that cannot be queried are not documented")
+
+	b, err := json.Marshal(doc.OpenAPIExtensions())
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"x-aip-filter": {
+			"fields": [
+				{"path": "name", "type": "string", "operators": ["=", "!=", ":", ":*"]},
+				{"path": "title", "type": "string", "operators": ["=", "!=", ":", ":*"]},
+				{"path": "author.family_name", "type": "string", "operators": ["=", "!=", ":", ":*"]},
+				{"path": "reviews", "type": "map<string, string>", "operators": ["=", "!=", ":", ":*"], "keyValue": true}
+			],
+			"implicit": ["title", "author.family_name"]
+		},
+		"x-aip-order-by": {
+			"fields": [
+				{"path": "name", "type": "string", "sortable": true},
+				{"path": "author.family_name", "type": "string", "sortable": true, "nullsFirst": true},
+				{"path": "format", "type": "test.Format", "sortable": true, "enumValues": ["EBOOK", "FORMAT_UNSPECIFIED", "HARDCOVER", "PAPERBACK"]}
+			],
+			"default": "name desc"
+		}
+	}`, string(b))
+}
+
+func TestTableDocumentOperators(t *testing.T) {
+	desc := (&testpb.Book{}).ProtoReflect().Descriptor()
+	table := query.NewTable().WithColumns(
+		query.NewColumn().WithFieldPath("authors").WithDatabaseName("authors").Array().Filterable().Build(),
+		query.NewColumn().WithFieldPath("name").WithDatabaseName("name").Filterable().WithArgumentSubstitutor(func(s string) string { return "books/" + s }).Build(),
+		query.NewColumn().WithFieldPath("page_count").WithDatabaseName("page_count").Bool().Filterable().Build(),
+	).Build()
+
+	doc, err := table.Document(desc, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{":", ":*"}, doc.Fields[0].Operators)
+	require.Equal(t, "repeated test.Author", doc.Fields[0].Type)
+	require.Equal(t, []string{"=", "!=", ":*"}, doc.Fields[1].Operators, "has is rejected on substituted arguments")
+	require.Equal(t, []string{"=", "!=", ":*"}, doc.Fields[2].Operators)
+	require.Empty(t, doc.DefaultOrder)
+	require.NotContains(t, doc.Markdown(), "default order")
+	table = query.NewTable().WithColumns(
+		query.NewColumn().WithFieldPath("title").WithDatabaseName("title").Sortable().Build(),
+	).WithDefaultOrder("title desc").Build()
+	doc, err = table.Document(desc, nil)
+	require.NoError(t, err)
+	require.Equal(t, "title desc", doc.DefaultOrder, "the default order of the table is documented")
+}
+
+func TestTableDocumentErrors(t *testing.T) {
+	desc := (&testpb.Book{}).ProtoReflect().Descriptor()
+
+	table := query.NewTable().WithColumns(
+		query.NewColumn().WithFieldPath("isbn").WithDatabaseName("isbn").Filterable().Build(),
+	).Build()
+	_, err := table.Document(desc, nil)
+	require.ErrorContains(t, err, "column isbn: field isbn not found on test.Book")
+
+	table = query.NewTable().WithColumns(
+		query.NewColumn().WithFieldPath("authors", "family_name").WithDatabaseName("a").Filterable().Build(),
+	).Build()
+	_, err = table.Document(desc, nil)
+	require.ErrorContains(t, err, "is not a singular message")
+
+	order, err := query.ParseOrderBy("title")
+	require.NoError(t, err)
+	_, err = docTable().Document(desc, order)
+	require.ErrorContains(t, err, `default order: no sortable field named "title"`)
+}
@@ -0,0 +1,41 @@
+package query
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// RowValues returns the values m stores in the columns of the table, as
+// query parameters named by the columns' database names, in the order of
+// the columns. Synthetic fields, which the database computes, are skipped.
+// Values have the Go types SeekClause binds cursor values
+// with, e.g. time.Time for google.protobuf.Timestamp fields, and are nil
+// where a field with explicit presence, or a message along its path, is
+// unset.
+//
+// RowValues lets tests and tools write the rows a table reads, e.g. to seed
+// a database from proto fixtures. It returns an error if m has a field
+// stored in an array or key-value column, which have no scalar value, or if
+// a column does not name a singular scalar or timestamp field of m.
+func (t *Table) RowValues(m proto.Message) ([]QueryParameter, error) {
+	r := m.ProtoReflect()
+	row := make([]QueryParameter, 0, len(t.columns))
+	for _, column := range t.columns {
+		if column.synthetic {
+			continue
+		}
+		if column.array || column.keyValue {
+			return nil, fmt.Errorf("column %s: array and key-value columns have no scalar value", column.databaseName)
+		}
+		if err := validateFieldPath(r.Descriptor(), column.fieldPath.segments); err != nil {
+			return nil, fmt.Errorf("column %s: %w", column.databaseName, err)
+		}
+		value, err := columnValue(r, column.fieldPath.segments)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", column.databaseName, err)
+		}
+		row = append(row, QueryParameter{Name: column.databaseName, Value: value})
+	}
+	return row, nil
+}
@@ -0,0 +1,63 @@
+package query_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/query"
+)
+
+func TestTableRowValues(t *testing.T) {
+	table := query.NewTable().WithColumns(
+		query.NewColumn().WithFieldPath("name").WithDatabaseName("db_name").Filterable().Build(),
+		query.NewColumn().WithFieldPath("author", "family_name").WithDatabaseName("db_family_name").Sortable().Build(),
+		query.NewColumn().WithFieldPath("page_count").WithDatabaseName("db_page_count").Sortable().Build(),
+		query.NewColumn().WithFieldPath("format").WithDatabaseName("db_format").Enum(testpb.Format(0).Descriptor(), query.EnumOrderByName).Sortable().Build(),
+		query.NewColumn().WithFieldPath("create_time").WithDatabaseName("db_create_time").Sortable().Build(),
+	).Build()
+	created := time.Date(1965, 8, 1, 0, 0, 0, 0, time.UTC)
+
+	row, err := table.RowValues(&testpb.Book{
+		Name:       "books/1",
+		Author:     &testpb.Author{FamilyName: "Herbert"},
+		PageCount:  proto.Int32(412),
+		Format:     testpb.Format_PAPERBACK,
+		CreateTime: timestamppb.New(created),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []query.QueryParameter{
+		{Name: "db_name", Value: "books/1"},
+		{Name: "db_family_name", Value: "Herbert"},
+		{Name: "db_page_count", Value: int64(412)},
+		{Name: "db_format", Value: int64(testpb.Format_PAPERBACK)},
+		{Name: "db_create_time", Value: created},
+	}, row)
+
+	// Unset fields with presence, and fields beneath unset messages, are NULL.
+	row, err = table.RowValues(&testpb.Book{Name: "books/2"})
+	require.NoError(t, err)
+	require.Equal(t, []query.QueryParameter{
+		{Name: "db_name", Value: "books/2"},
+		{Name: "db_family_name", Value: nil},
+		{Name: "db_page_count", Value: nil},
+		{Name: "db_format", Value: int64(0)},
+		{Name: "db_create_time", Value: nil},
+	}, row)
+}
+
+func TestTableRowValues_Unsupported(t *testing.T) {
+	for _, column := range []*query.Column{
+		query.NewColumn().WithFieldPath("tags").WithDatabaseName("db_tags").Array().Filterable().Build(),
+		query.NewColumn().WithFieldPath("reviews").WithDatabaseName("db_reviews").KeyValue().Filterable().Build(),
+		query.NewColumn().WithFieldPath("missing").WithDatabaseName("db_missing").Filterable().Build(),
+	} {
+		table := query.NewTable().WithColumns(column).Build()
+		_, err := table.RowValues(&testpb.Book{})
+		require.Error(t, err)
+	}
+}
@@ -0,0 +1,102 @@
+package query
+
+import "fmt"
+
+// WarningKind is the kind of a Warning.
+type WarningKind int
+
+const (
+	// WarningDeprecatedField reports a query referencing a deprecated
+	// field.
+	WarningDeprecatedField WarningKind = iota
+	// WarningUnknownField reports a filter comparing a name that is not a
+	// field, e.g. `tittle = "Dune"`, which is compared as a string literal
+	// unless WithStrictFields is used.
+	WarningUnknownField
+)
+
+// Warning reports a query that is accepted but may not do what its author
+// intended, e.g. because it references a deprecated field.
+type Warning struct {
+	// Kind is the kind of the warning.
+	Kind WarningKind
+	// FieldPath is the path of the field, or of the unknown name for
+	// WarningUnknownField.
+	FieldPath string
+	// Replacement is the path of the field to use instead of a deprecated
+	// field, or empty if there is none.
+	Replacement string
+}
+
+func (w Warning) String() string {
+	if w.Kind == WarningUnknownField {
+		return fmt.Sprintf("%q is not a field and was compared as a literal", w.FieldPath)
+	}
+	if w.Replacement == "" {
+		return fmt.Sprintf("field %q is deprecated", w.FieldPath)
+	}
+	return fmt.Sprintf("field %q is deprecated, use %q instead", w.FieldPath, w.Replacement)
+}
+
+// Warnings returns a Warning for each deprecated field that filter or order
+// references, in the order of the table's columns. Fields searched
+// implicitly are not reported, since the query does not name them.
+func (t *Table) Warnings(filter *Filter, order []OrderBy) []Warning {
+	used := make(map[*Column]bool)
+	if filter != nil && filter.Expression != nil {
+		t.collectNamedColumns(filter.Expression, used)
+	}
+	for _, o := range order {
+		if c := t.columnByFieldPath[o.FieldPath.String()]; c != nil {
+			used[c] = true
+		}
+	}
+	var warnings []Warning
+	for _, c := range t.columns {
+		if used[c] && c.deprecated {
+			warnings = append(warnings, Warning{FieldPath: c.fieldPath.String(), Replacement: c.replacement})
+		}
+	}
+	return warnings
+}
+
+// collectNamedColumns adds the columns named by restrictions in e to used.
+func (t *Table) collectNamedColumns(e *Expression, used map[*Column]bool) {
+	for _, seq := range e.Sequences {
+		for _, f := range seq.Factors {
+			for _, term := range f.Terms {
+				if term.Simple.Composite != nil {
+					t.collectNamedColumns(term.Simple.Composite, used)
+					continue
+				}
+				r := term.Simple.Restriction
+				if r == nil || r.Comparator == "" || r.Comparable == nil || r.Comparable.Member == nil {
+					continue
+				}
+				if c := t.columnByFieldPath[NewFieldPath(r.Comparable.Member.Value).String()]; c != nil {
+					used[c] = true
+				}
+			}
+		}
+	}
+}
+
+// WithWarningHandler specifies a function called with a Warning when a filter
+// is compiled for each name its restrictions compare that is not a field,
+// e.g. to log or count filters that WithStrictFields would reject.
+func WithWarningHandler(h func(Warning)) FilterOption {
+	return func(o *filterOptions) {
+		o.warn = h
+	}
+}
+
+// reportWarnings passes the warnings about a query with filter and order to
+// the table's warning handler, if any.
+func (t *Table) reportWarnings(filter *Filter, order []OrderBy) {
+	if t.warn == nil {
+		return
+	}
+	for _, w := range t.Warnings(filter, order) {
+		t.warn(w)
+	}
+}
@@ -0,0 +1,70 @@
+package query_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/query"
+)
+
+func TestWarnings(t *testing.T) {
+	var reported []query.Warning
+	table := query.NewTable().WithColumns(
+		query.NewColumn().WithFieldPath("name").WithDatabaseName("name").Filterable().Sortable().Build(),
+		query.NewColumn().WithFieldPath("author").WithDatabaseName("author").Filterable().Sortable().Deprecated("author_name").Build(),
+		query.NewColumn().WithFieldPath("author_name").WithDatabaseName("author_name").Filterable().Sortable().Build(),
+		query.NewColumn().WithFieldPath("blurb").WithDatabaseName("blurb").FilterableImplicitly().Deprecated("").Build(),
+	).WithWarningHandler(func(w query.Warning) {
+		reported = append(reported, w)
+	}).Build()
+
+	author := query.Warning{FieldPath: "author", Replacement: "author_name"}
+	require.Equal(t, `field "author" is deprecated, use "author_name" instead`, author.String())
+	require.Equal(t, `field "blurb" is deprecated`, query.Warning{FieldPath: "blurb"}.String())
+
+	f := mustParse(t, `name = "books/1" OR (author = Austen AND author = Austen) OR Emma`)
+	require.Equal(t, []query.Warning{author}, table.Warnings(f, nil))
+	require.Equal(t, []query.Warning{author}, table.Warnings(nil, mustOrder(t, "name, author desc")))
+	require.Empty(t, table.Warnings(mustParse(t, "author_name = Austen Emma"), mustOrder(t, "author_name")))
+
+	_, _, err := table.WhereClause(f, "p")
+	require.NoError(t, err)
+	require.Equal(t, []query.Warning{author}, reported)
+
+	reported = nil
+	_, err = table.OrderByClause(mustOrder(t, "author"))
+	require.NoError(t, err)
+	require.Equal(t, []query.Warning{author}, reported)
+
+	reported = nil
+	_, _, err = table.WhereClause(mustParse(t, "author = Austen AND nope = 1"), "p")
+	require.Error(t, err)
+	require.Empty(t, reported, "invalid queries report no warnings")
+}
+
+func TestFilterWarnings(t *testing.T) {
+	var reported []query.Warning
+	handler := query.WithWarningHandler(func(w query.Warning) {
+		reported = append(reported, w)
+	})
+
+	f := mustParse(t, `tittle = "Dune" OR (title = Dune AND NOT pages > 3) OR Dune`)
+	filter, err := query.ProtoFilter[testpb.Book](f, handler)
+	require.NoError(t, err)
+	require.Equal(t, []query.Warning{
+		{Kind: query.WarningUnknownField, FieldPath: "tittle"},
+		{Kind: query.WarningUnknownField, FieldPath: "pages"},
+	}, reported)
+	require.Equal(t, `"tittle" is not a field and was compared as a literal`, reported[0].String())
+
+	// Warnings are reported when the filter is compiled, not per message.
+	reported = nil
+	require.True(t, filter(&testpb.Book{Title: "Dune"}))
+	require.Empty(t, reported)
+
+	_, err = query.ProtoFilter[testpb.Book](mustParse(t, `title = Dune AND "Dune" = title`), handler)
+	require.NoError(t, err)
+	require.Empty(t, reported, "literal arguments and quoted strings are not reported")
+}
@@ -0,0 +1,49 @@
+package aip
+
+import (
+	"google.golang.org/protobuf/proto"
+
+	"github.com/hxtk/aip/masks"
+	"github.com/hxtk/aip/query"
+)
+
+// WithRedactedValues redacts the literal values of the filter, and the map
+// keys of the update_mask, of requests from the descriptions of the field
+// violations they cause, keeping only field paths and operators, so that
+// personal data embedded in user queries does not leak into logs or error
+// responses. See query.FilterValues and masks.MapKeys.
+func WithRedactedValues() Option {
+	return func(o *options) {
+		o.redactValues = true
+	}
+}
+
+// redact redacts the values of the request msg from the descriptions of
+// violations, if enabled.
+func (o *options) redact(msg proto.Message, violations []Violation) {
+	if !o.redactValues || msg == nil {
+		return
+	}
+	values := requestValues(msg)
+	if len(values) == 0 {
+		return
+	}
+	for i, v := range violations {
+		violations[i].Description = query.RedactValues(v.Description, values...)
+	}
+}
+
+// requestValues returns the values of msg that violations must not reveal.
+func requestValues(msg proto.Message) []string {
+	m := msg.ProtoReflect()
+	var values []string
+	if text, ok := stringValue(m, query.FilterField); ok {
+		values = append(values, query.FilterValues(text)...)
+	}
+	if resource, _ := updateTarget(m); resource != nil {
+		for _, p := range maskPaths(m, m.Descriptor().Fields().ByName("update_mask")) {
+			values = append(values, masks.MapKeys(resource.Message(), p)...)
+		}
+	}
+	return values
+}
@@ -0,0 +1,175 @@
+package aip
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"connectrpc.com/connect"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/hxtk/aip/masks"
+)
+
+// Updater holds the storage operations of an AIP-134 Update method, for
+// Update to apply the standard semantics to.
+type Updater[M proto.Message] struct {
+	// Get returns the resource named name. If it does not exist, Get must
+	// return an error with code connect.CodeNotFound.
+	Get func(ctx context.Context, name string) (M, error)
+
+	// Update stores resource, the stored resource with the update applied,
+	// and returns the resource as stored.
+	Update func(ctx context.Context, resource M) (M, error)
+
+	// Create stores resource, a new resource built from an Update request
+	// with allow_missing set, and returns the resource as stored. If nil,
+	// allow_missing is ignored.
+	Create func(ctx context.Context, resource M) (M, error)
+}
+
+// Update implements an AIP-134 Update method for the resource in req, named
+// by its name field, using the storage operations of u, and returns the
+// stored resource. The update_mask of req is applied to the stored resource
+// with masks.ApplyUpdateMask, and the request fields below are honored if
+// req or the resource has them:
+//
+//   - allow_missing: if set and the resource does not exist, it is created
+//     from the fields of the request resource the update mask covers. The
+//     mask must then cover every REQUIRED field of the resource, or the
+//     request fails with connect.CodeInvalidArgument. Otherwise a missing
+//     resource fails with connect.CodeNotFound.
+//   - etag (AIP-154) of the resource: if set and not equal to the etag of
+//     the stored resource, the request fails with connect.CodeAborted.
+//
+// Updates changing an IMMUTABLE field of the stored resource fail with
+// connect.CodeInvalidArgument. OUTPUT_ONLY fields are never changed.
+//
+// Example:
+//
+//	func (s *server) UpdateBook(ctx context.Context, req *connect.Request[pb.UpdateBookRequest]) (*connect.Response[pb.Book], error) {
+//		book, err := aip.Update(ctx, req.Msg, s.books)
+//		if err != nil {
+//			return nil, err
+//		}
+//		return connect.NewResponse(book), nil
+//	}
+func Update[S any, M interface {
+	proto.Message
+	*S
+}](ctx context.Context, req proto.Message, u Updater[M]) (M, error) {
+	r := req.ProtoReflect()
+	field, _ := updateTarget(r)
+	if field == nil || field.Message().FullName() != M(new(S)).ProtoReflect().Descriptor().FullName() {
+		return nil, fmt.Errorf("%s is not an Update request for %T", r.Descriptor().FullName(), M(nil))
+	}
+	if !r.Has(field) {
+		return nil, violationError(ctx, []Violation{{Field: string(field.Name()), Reason: ReasonFieldRequired, Description: "required field is not set"}})
+	}
+	resource := r.Get(field).Message().Interface().(M)
+	name, ok := stringValue(resource.ProtoReflect(), "name")
+	if !ok {
+		return nil, violationError(ctx, []Violation{{Field: string(field.Name()) + ".name", Reason: ReasonFieldRequired, Description: "required field is not set"}})
+	}
+	paths := maskPaths(r, r.Descriptor().Fields().ByName("update_mask"))
+
+	stored, err := u.Get(ctx, name)
+	if connect.CodeOf(err) == connect.CodeNotFound {
+		if u.Create == nil || !boolField(r, "allow_missing") {
+			return nil, err
+		}
+		return create[S](ctx, u, field, resource, name, paths)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if etag, ok := stringValue(resource.ProtoReflect(), "etag"); ok {
+		if current, _ := stringValue(stored.ProtoReflect(), "etag"); etag != current {
+			return nil, connect.NewError(connect.CodeAborted, fmt.Errorf("etag %q does not match the current etag of %s", etag, name))
+		}
+	}
+	updated := proto.Clone(stored).(M)
+	if err := masks.ApplyUpdateMask(updated, resource, paths...); err != nil {
+		return nil, violationError(ctx, []Violation{{Field: "update_mask", Reason: ReasonFieldMaskInvalid, Description: err.Error()}})
+	}
+	if violations := appendImmutableViolations(nil, stored.ProtoReflect(), updated.ProtoReflect(), string(field.Name())+"."); len(violations) > 0 {
+		return nil, violationError(ctx, violations)
+	}
+	return u.Update(ctx, updated)
+}
+
+// create creates the resource named name from the fields of resource that
+// paths cover, for an Update request with allow_missing.
+func create[S any, M interface {
+	proto.Message
+	*S
+}](ctx context.Context, u Updater[M], field protoreflect.FieldDescriptor, resource M, name string, paths []string) (M, error) {
+	created := M(new(S))
+	if err := masks.ApplyUpdateMask(created, resource, paths...); err != nil {
+		return nil, violationError(ctx, []Violation{{Field: "update_mask", Reason: ReasonFieldMaskInvalid, Description: err.Error()}})
+	}
+	m := created.ProtoReflect()
+	m.Set(m.Descriptor().Fields().ByName("name"), protoreflect.ValueOfString(name))
+
+	var violations []Violation
+	for _, v := range appendRequiredViolations(nil, m, string(field.Name())+".", nil, false) {
+		v.Description = "required field of a new resource is not covered by update_mask"
+		violations = append(violations, v)
+	}
+	if len(violations) > 0 {
+		return nil, violationError(ctx, violations)
+	}
+	return u.Create(ctx, created)
+}
+
+// appendImmutableViolations appends a violation for each field annotated
+// IMMUTABLE whose value differs between before and after, recursing into
+// singular message fields set in both.
+func appendImmutableViolations(violations []Violation, before, after protoreflect.Message, prefix string) []Violation {
+	fields := before.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		path := prefix + string(fd.Name())
+		if hasBehavior(fd, annotations.FieldBehavior_IMMUTABLE) {
+			if !fieldEqual(before, after, fd) {
+				violations = append(violations, Violation{
+					Field:       path,
+					Reason:      ReasonFieldImmutable,
+					Description: "immutable field cannot be changed",
+				})
+			}
+			continue
+		}
+		if fd.Message() != nil && !fd.IsList() && !fd.IsMap() && before.Has(fd) && after.Has(fd) {
+			violations = appendImmutableViolations(violations, before.Get(fd).Message(), after.Get(fd).Message(), path+".")
+		}
+	}
+	return violations
+}
+
+// fieldEqual reports whether fd has the same value in a and b.
+func fieldEqual(a, b protoreflect.Message, fd protoreflect.FieldDescriptor) bool {
+	x, y := a.New(), b.New()
+	if a.Has(fd) {
+		x.Set(fd, a.Get(fd))
+	}
+	if b.Has(fd) {
+		y.Set(fd, b.Get(fd))
+	}
+	return proto.Equal(x.Interface(), y.Interface())
+}
+
+// violationError returns a CodeInvalidArgument error describing violations,
+// with the details the interceptor attaches to validation errors, for the
+// call of the handler context ctx.
+func violationError(ctx context.Context, violations []Violation) error {
+	var spec connect.Spec
+	var header http.Header
+	if call, ok := connect.CallInfoForHandlerContext(ctx); ok {
+		spec, header = call.Spec(), call.RequestHeader()
+	}
+	return (&options{}).invalidArgument(spec, header, violations)
+}
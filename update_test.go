@@ -0,0 +1,170 @@
+package aip_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/hxtk/aip"
+	"github.com/hxtk/aip/aiptest/testpb"
+)
+
+// shelfStore is an in-memory store of shelves, by name.
+type shelfStore struct {
+	shelves map[string]*testpb.Shelf
+	created []string
+}
+
+func newShelfStore() *shelfStore {
+	return &shelfStore{shelves: map[string]*testpb.Shelf{
+		"shelves/1": {
+			Name:       "shelves/1",
+			Theme:      "Science fiction",
+			Location:   "Aisle 3",
+			Etag:       "v1",
+			CreateTime: timestamppb.New(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)),
+		},
+	}}
+}
+
+func (s *shelfStore) updater() aip.Updater[*testpb.Shelf] {
+	return aip.Updater[*testpb.Shelf]{
+		Get: func(_ context.Context, name string) (*testpb.Shelf, error) {
+			shelf, ok := s.shelves[name]
+			if !ok {
+				return nil, connect.NewError(connect.CodeNotFound, errors.New(name+" not found"))
+			}
+			return shelf, nil
+		},
+		Update: func(_ context.Context, shelf *testpb.Shelf) (*testpb.Shelf, error) {
+			shelf.Etag += "+"
+			s.shelves[shelf.GetName()] = shelf
+			return shelf, nil
+		},
+		Create: func(_ context.Context, shelf *testpb.Shelf) (*testpb.Shelf, error) {
+			s.shelves[shelf.GetName()] = shelf
+			s.created = append(s.created, shelf.GetName())
+			return shelf, nil
+		},
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+	store := newShelfStore()
+	stored := proto.Clone(store.shelves["shelves/1"]).(*testpb.Shelf)
+
+	shelf, err := aip.Update(ctx, &testpb.UpdateShelfRequest{
+		Shelf:      &testpb.Shelf{Name: "shelves/1", Description: "Rockets", Theme: "ignored", Etag: "v1", CreateTime: timestamppb.Now()},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"description", "create_time"}},
+	}, store.updater())
+	require.NoError(t, err)
+
+	stored.Description = "Rockets"
+	stored.Etag = "v1+"
+	require.True(t, proto.Equal(stored, shelf), "got %v, want %v", shelf, stored)
+	require.Empty(t, store.created)
+}
+
+func TestUpdate_Errors(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		req  *testpb.UpdateShelfRequest
+		code connect.Code
+	}{
+		{
+			name: "missing",
+			req:  &testpb.UpdateShelfRequest{Shelf: &testpb.Shelf{Name: "shelves/2", Theme: "Poetry"}},
+			code: connect.CodeNotFound,
+		},
+		{
+			name: "stale etag",
+			req: &testpb.UpdateShelfRequest{
+				Shelf:      &testpb.Shelf{Name: "shelves/1", Description: "Rockets", Etag: "v0"},
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"description"}},
+			},
+			code: connect.CodeAborted,
+		},
+		{
+			name: "immutable",
+			req: &testpb.UpdateShelfRequest{
+				Shelf:      &testpb.Shelf{Name: "shelves/1", Location: "Aisle 4"},
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"location"}},
+			},
+			code: connect.CodeInvalidArgument,
+		},
+		{
+			name: "invalid mask",
+			req: &testpb.UpdateShelfRequest{
+				Shelf:      &testpb.Shelf{Name: "shelves/1"},
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"genre"}},
+			},
+			code: connect.CodeInvalidArgument,
+		},
+		{
+			name: "no name",
+			req:  &testpb.UpdateShelfRequest{Shelf: &testpb.Shelf{Theme: "Poetry"}},
+			code: connect.CodeInvalidArgument,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := newShelfStore()
+			_, err := aip.Update(ctx, tc.req, store.updater())
+			require.Equal(t, tc.code, connect.CodeOf(err), "error: %v", err)
+			require.Equal(t, "v1", store.shelves["shelves/1"].GetEtag())
+		})
+	}
+
+	// An unchanged immutable field may be written.
+	store := newShelfStore()
+	_, err := aip.Update(ctx, &testpb.UpdateShelfRequest{
+		Shelf: &testpb.Shelf{Name: "shelves/1", Theme: "Science fiction", Location: "Aisle 3"},
+	}, store.updater())
+	require.NoError(t, err)
+}
+
+func TestUpdate_AllowMissing(t *testing.T) {
+	ctx := context.Background()
+	store := newShelfStore()
+
+	shelf, err := aip.Update(ctx, &testpb.UpdateShelfRequest{
+		Shelf:        &testpb.Shelf{Name: "shelves/2", Theme: "Poetry", Location: "Aisle 1", Description: "Not covered"},
+		UpdateMask:   &fieldmaskpb.FieldMask{Paths: []string{"theme", "location"}},
+		AllowMissing: true,
+	}, store.updater())
+	require.NoError(t, err)
+	require.True(t, proto.Equal(&testpb.Shelf{Name: "shelves/2", Theme: "Poetry", Location: "Aisle 1"}, shelf), "got %v", shelf)
+	require.Equal(t, []string{"shelves/2"}, store.created)
+
+	// The mask must cover the required fields of the new resource.
+	_, err = aip.Update(ctx, &testpb.UpdateShelfRequest{
+		Shelf:        &testpb.Shelf{Name: "shelves/3", Theme: "Poetry", Location: "Aisle 1"},
+		UpdateMask:   &fieldmaskpb.FieldMask{Paths: []string{"location"}},
+		AllowMissing: true,
+	}, store.updater())
+	require.Equal(t, connect.CodeInvalidArgument, connect.CodeOf(err))
+	var connectErr *connect.Error
+	require.ErrorAs(t, err, &connectErr)
+	var fields []string
+	for _, detail := range connectErr.Details() {
+		value, err := detail.Value()
+		require.NoError(t, err)
+		if br, ok := value.(*errdetails.BadRequest); ok {
+			for _, v := range br.GetFieldViolations() {
+				fields = append(fields, v.GetField())
+			}
+		}
+	}
+	require.Equal(t, []string{"shelf.theme"}, fields)
+	require.Equal(t, []string{"shelves/2"}, store.created)
+}
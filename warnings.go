@@ -0,0 +1,110 @@
+package aip
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"connectrpc.com/connect"
+
+	"github.com/hxtk/aip/masks"
+)
+
+// Reasons of warnings. Like the reasons of violations, they are stable and
+// machine-readable.
+const (
+	// WarningPageSizeClamped is the reason of page sizes larger than the
+	// max_page_size of the policy, which are lowered to it.
+	WarningPageSizeClamped = "PAGE_SIZE_CLAMPED"
+	// WarningReadMaskPathIgnored is the reason of read mask paths naming
+	// fields that do not exist, which select nothing.
+	WarningReadMaskPathIgnored = "READ_MASK_PATH_IGNORED"
+)
+
+// Warning reports a request the interceptor accepted leniently rather than
+// rejecting, so that API owners can measure how often it happens before
+// tightening validation.
+type Warning struct {
+	// Field is the path of the field handled leniently, e.g. "page_size",
+	// or the read mask path.
+	Field string
+	// Reason is one of the Warning reason constants.
+	Reason string
+	// Description is the description of the warning in English.
+	Description string
+}
+
+// WithWarningHandler sets a function called with each Warning about a
+// request, e.g. to log or count them. It is called with the context and
+// spec of the call. Unlike AddWarning, it does not inform the client.
+func WithWarningHandler(h func(ctx context.Context, spec connect.Spec, w Warning)) Option {
+	return func(o *options) {
+		o.warn = h
+	}
+}
+
+// readMaskWarning returns the Warning about the read mask path of w.
+func readMaskWarning(w masks.Warning) Warning {
+	return Warning{
+		Field:       w.Path,
+		Reason:      WarningReadMaskPathIgnored,
+		Description: w.String(),
+	}
+}
+
+type warningsCtxKey struct{}
+
+// warnings collects the warnings added to a call.
+type warnings struct {
+	mu sync.Mutex
+	// header receives warnings as they are added, for streams; otherwise
+	// they are held in texts until the response is ready.
+	header http.Header
+	texts  []string
+}
+
+// AddWarning adds a warning to the response of the call with context ctx,
+// e.g. the text of a query.Warning about a deprecated field the request
+// filters or sorts on. If the interceptor was created WithWarningHeaders,
+// warnings are sent in Warning response headers, with code 299; otherwise
+// they are discarded. Warnings added to a stream after its first message is
+// sent are discarded.
+func AddWarning(ctx context.Context, text string) {
+	w, ok := ctx.Value(warningsCtxKey{}).(*warnings)
+	if !ok {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.header != nil {
+		w.header.Add("Warning", warningValue(text))
+		return
+	}
+	w.texts = append(w.texts, text)
+}
+
+// WithWarningHeaders sends the warnings added with AddWarning in Warning
+// response headers.
+func WithWarningHeaders() Option {
+	return func(o *options) {
+		o.warningHeaders = true
+	}
+}
+
+// setHeaders adds the warnings held to header.
+func (w *warnings) setHeaders(header http.Header) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, text := range w.texts {
+		header.Add("Warning", warningValue(text))
+	}
+	w.texts = nil
+}
+
+// warningValue returns the value of a Warning header with code 299
+// (miscellaneous persistent warning) and the given text.
+func warningValue(text string) string {
+	text = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\r", " ", "\n", " ").Replace(text)
+	return `299 - "` + text + `"`
+}
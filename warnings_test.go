@@ -0,0 +1,102 @@
+package aip_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hxtk/aip"
+	"github.com/hxtk/aip/aiptest/testpb"
+	"github.com/hxtk/aip/aiptest/testpb/testpbconnect"
+)
+
+// warningBookService adds a warning to each call.
+type warningBookService struct {
+	fakeBookService
+}
+
+func (s *warningBookService) GetBook(ctx context.Context, req *connect.Request[testpb.GetBookRequest]) (*connect.Response[testpb.Book], error) {
+	aip.AddWarning(ctx, `field "name" is deprecated`)
+	return nil, connect.NewError(connect.CodeNotFound, errors.New("not found"))
+}
+
+func (s *warningBookService) ListBooksPage(ctx context.Context, req *connect.Request[testpb.ListBooksRequest]) (*connect.Response[testpb.ListBooksResponse], error) {
+	aip.AddWarning(ctx, `field "author" is deprecated, use "author_name" instead`)
+	aip.AddWarning(ctx, "second")
+	return s.fakeBookService.ListBooksPage(ctx, req)
+}
+
+func (s *warningBookService) ListBooks(ctx context.Context, req *connect.Request[testpb.ListBooksRequest], stream *connect.ServerStream[testpb.Book]) error {
+	aip.AddWarning(ctx, "streamed")
+	return s.fakeBookService.ListBooks(ctx, req, stream)
+}
+
+func TestWarningHeaders(t *testing.T) {
+	client := newClient(t, &warningBookService{}, aip.WithWarningHeaders())
+
+	rsp, err := client.ListBooksPage(context.Background(), connect.NewRequest(&testpb.ListBooksRequest{}))
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		`299 - "field \"author\" is deprecated, use \"author_name\" instead"`,
+		`299 - "second"`,
+	}, rsp.Header().Values("Warning"))
+
+	stream, err := client.ListBooks(context.Background(), connect.NewRequest(&testpb.ListBooksRequest{}))
+	require.NoError(t, err)
+	require.True(t, stream.Receive())
+	require.Equal(t, []string{`299 - "streamed"`}, stream.ResponseHeader().Values("Warning"))
+	require.NoError(t, stream.Close())
+
+	_, err = client.GetBook(context.Background(), connect.NewRequest(&testpb.GetBookRequest{Name: "books/1"}))
+	var connectErr *connect.Error
+	require.ErrorAs(t, err, &connectErr)
+	require.Equal(t, []string{`299 - "field \"name\" is deprecated"`}, connectErr.Meta().Values("Warning"))
+
+	client = newClient(t, &warningBookService{})
+	rsp, err = client.ListBooksPage(context.Background(), connect.NewRequest(&testpb.ListBooksRequest{}))
+	require.NoError(t, err)
+	require.Empty(t, rsp.Header().Values("Warning"), "warnings are discarded by default")
+}
+
+func TestWarningHandler(t *testing.T) {
+	policy, err := aip.ParsePolicy([]byte(testPolicy))
+	require.NoError(t, err)
+	var reported []aip.Warning
+	var procedures []string
+	client := newClient(t, &fakeBookService{},
+		aip.WithPolicy(policy),
+		aip.WithReadMaskHeader("X-Read-Mask"),
+		aip.WithWarningHandler(func(ctx context.Context, spec connect.Spec, w aip.Warning) {
+			reported = append(reported, w)
+			procedures = append(procedures, spec.Procedure)
+		}),
+	)
+
+	_, err = client.ListBooksPage(context.Background(), connect.NewRequest(&testpb.ListBooksRequest{PageSize: 500}))
+	require.NoError(t, err)
+	require.Equal(t, []aip.Warning{{
+		Field:       "page_size",
+		Reason:      aip.WarningPageSizeClamped,
+		Description: "page_size 500 exceeds the maximum of 10 and was lowered to it",
+	}}, reported)
+	require.Equal(t, []string{testpbconnect.BookServiceListBooksPageProcedure}, procedures)
+
+	reported = nil
+	_, err = client.ListBooksPage(context.Background(), connect.NewRequest(&testpb.ListBooksRequest{PageSize: 10}))
+	require.NoError(t, err)
+	require.Empty(t, reported, "page sizes within the maximum are not reported")
+
+	req := connect.NewRequest(&testpb.GetBookRequest{Name: "books/1"})
+	req.Header().Set("X-Read-Mask", "title,author.nickname")
+	rsp, err := client.GetBook(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, "The Pragmatic Programmer", rsp.Msg.GetTitle())
+	require.Equal(t, []aip.Warning{{
+		Field:       "author.nickname",
+		Reason:      aip.WarningReadMaskPathIgnored,
+		Description: `field "nickname" of read mask path "author.nickname" does not exist and was ignored`,
+	}}, reported)
+}